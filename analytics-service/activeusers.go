@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/grpc/codes"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// GetActiveUsers counts distinct users with at least one event per bucket
+// (DAU, WAU or MAU by granularity), plus the rolling 7 and 30 day actives up
+// to the end of the range.
+func (s *server) GetActiveUsers(ctx context.Context, req *pb.GetActiveUsersRequest) (*pb.GetActiveUsersResponse, error) {
+	granularity := req.Granularity
+	if granularity == "" {
+		granularity = "day"
+	}
+	if granularity != "day" && granularity != "week" && granularity != "month" {
+		return nil, statusError(codes.InvalidArgument, "INVALID_GRANULARITY", map[string]string{"granularity": req.Granularity}, "granularity must be day, week or month, got %q", req.Granularity)
+	}
+
+	timezone := req.Timezone
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	loc, err := parseTimezone(timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	dates, err := parseDateRange(req.StartDate, req.EndDate, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	// Count each user once per bucket: group by bucket and user first, then
+	// count the users in each bucket
+	at := toDate("$created_at")
+	pipeline := []bson.M{
+		{"$match": eventsIn(dates)},
+		{"$group": bson.M{"_id": bson.M{
+			"bucket": bson.M{"$dateTrunc": bson.M{
+				"date":        at,
+				"unit":        granularity,
+				"timezone":    timezone,
+				"startOfWeek": "monday",
+			}},
+			"user_id": "$user_id",
+		}}},
+		{"$group": bson.M{"_id": "$_id.bucket", "active": bson.M{"$sum": 1}}},
+	}
+
+	cursor, err := s.collection.Aggregate(ctx, pipeline, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	counts := make(map[int64]int32)
+	for cursor.Next(ctx) {
+		var row struct {
+			Start  time.Time `bson:"_id"`
+			Active int32     `bson:"active"`
+		}
+		if err := cursor.Decode(&row); err != nil {
+			return nil, err
+		}
+		counts[row.Start.Unix()] = row.Active
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	resp := &pb.GetActiveUsersResponse{}
+	for start := truncateTo(dates.start, granularity, loc); !start.After(dates.end); start = nextBucket(start, granularity) {
+		resp.Buckets = append(resp.Buckets, &pb.ActiveUsersBucket{
+			Start:       start.Format(time.RFC3339),
+			ActiveUsers: counts[start.Unix()],
+		})
+	}
+
+	resp.WeeklyActiveUsers, resp.MonthlyActiveUsers, err = s.rollingActives(ctx, dates.end)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// rollingActives counts the distinct users active in the 7 and 30 days up to
+// end, from one pass over the last 30 days of events.
+func (s *server) rollingActives(ctx context.Context, end time.Time) (week, month int32, err error) {
+	window := dateRange{start: end.AddDate(0, 0, -30), end: end}
+	weekStart := end.AddDate(0, 0, -7)
+
+	pipeline := []bson.M{
+		{"$match": eventsIn(window)},
+		{"$group": bson.M{"_id": "$user_id", "last": bson.M{"$max": toDate("$created_at")}}},
+		{"$group": bson.M{
+			"_id":   nil,
+			"month": bson.M{"$sum": 1},
+			"week":  bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$gt": bson.A{"$last", weekStart}}, 1, 0}}},
+		}},
+	}
+
+	cursor, err := s.collection.Aggregate(ctx, pipeline, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return 0, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var totals struct {
+		Week  int32 `bson:"week"`
+		Month int32 `bson:"month"`
+	}
+	if cursor.Next(ctx) {
+		if err := cursor.Decode(&totals); err != nil {
+			return 0, 0, err
+		}
+	}
+	return totals.Week, totals.Month, cursor.Err()
+}
+
+// eventsIn matches the events that happened in r. created_at is always
+// written as an RFC3339 string in the server's timezone, so string order is
+// time order and a range on it can use the {created_at, user_id} index.
+func eventsIn(r dateRange) bson.M {
+	return bson.M{"created_at": bson.M{
+		"$gte": r.start.In(time.Local).Format(time.RFC3339),
+		"$lte": r.end.In(time.Local).Format(time.RFC3339),
+	}}
+}
@@ -0,0 +1,103 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+func TestMongoActiveUsers(t *testing.T) {
+	stats := newMongoService(t, &fakeTaskClient{})
+	repo := stats.stats.(*mongoRepository)
+	s := &server{statsService: stats, collection: repo.events}
+	ctx := context.Background()
+
+	at := func(month time.Month, day, hour, min, sec int) time.Time {
+		return time.Date(2026, month, day, hour, min, sec, 0, time.UTC)
+	}
+	activity := map[string][]time.Time{
+		// First and last second of 1 March, then the first of 2 March
+		"u1": {at(3, 1, 0, 0, 0), at(3, 1, 23, 59, 59), at(3, 2, 0, 0, 0)},
+		// Includes the last second of the week of 2 March
+		"u2": {at(3, 1, 12, 0, 0), at(3, 8, 23, 59, 59), at(3, 10, 10, 0, 0)},
+		// The first second of the week of 9 March
+		"u3": {at(3, 9, 0, 0, 0)},
+		// Before the range, but within 30 days of its end
+		"u4": {at(2, 20, 12, 0, 0)},
+		// More than 30 days before the end
+		"u5": {at(2, 1, 12, 0, 0)},
+		// After the range
+		"u6": {at(3, 11, 0, 0, 0)},
+		// Either side of 7 days before the end of the range
+		"u7": {at(3, 3, 23, 59, 59)},
+		"u8": {at(3, 4, 0, 0, 0)},
+	}
+	for userID, times := range activity {
+		for i, when := range times {
+			_, err := repo.InsertEvent(ctx, Event{
+				UserID: userID, EventType: "app_opened", ResourceID: userID + string(rune('a'+i)),
+				CreatedAt: when.In(time.Local).Format(time.RFC3339),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	tests := []struct {
+		name       string
+		req        *pb.GetActiveUsersRequest
+		wantStarts []string
+		wantActive []int32
+		// The rolling actives up to the end of the range
+		wantWeekly, wantMonthly int32
+	}{
+		{"daily",
+			&pb.GetActiveUsersRequest{StartDate: "2026-03-01", EndDate: "2026-03-10"},
+			[]string{"2026-03-01T00:00:00Z", "2026-03-02T00:00:00Z", "2026-03-03T00:00:00Z", "2026-03-04T00:00:00Z", "2026-03-05T00:00:00Z",
+				"2026-03-06T00:00:00Z", "2026-03-07T00:00:00Z", "2026-03-08T00:00:00Z", "2026-03-09T00:00:00Z", "2026-03-10T00:00:00Z"},
+			[]int32{2, 1, 1, 1, 0, 0, 0, 1, 1, 1},
+			3, 6},
+		{"weekly",
+			&pb.GetActiveUsersRequest{StartDate: "2026-03-01", EndDate: "2026-03-10", Granularity: "week"},
+			[]string{"2026-02-23T00:00:00Z", "2026-03-02T00:00:00Z", "2026-03-09T00:00:00Z"},
+			[]int32{2, 4, 2},
+			3, 6},
+		{"monthly",
+			&pb.GetActiveUsersRequest{StartDate: "2026-02-01", EndDate: "2026-03-10", Granularity: "month"},
+			[]string{"2026-02-01T00:00:00Z", "2026-03-01T00:00:00Z"},
+			[]int32{2, 5},
+			3, 6},
+		// u1's last second of 1 March in UTC is already 2 March in Dhaka
+		{"days in the caller's timezone",
+			&pb.GetActiveUsersRequest{StartDate: "2026-03-01", EndDate: "2026-03-02", Timezone: "Asia/Dhaka"},
+			[]string{"2026-03-01T00:00:00+06:00", "2026-03-02T00:00:00+06:00"},
+			[]int32{2, 1},
+			2, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := s.GetActiveUsers(ctx, tt.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var starts []string
+			var active []int32
+			for _, bucket := range resp.Buckets {
+				starts = append(starts, bucket.Start)
+				active = append(active, bucket.ActiveUsers)
+			}
+			if !slices.Equal(starts, tt.wantStarts) || !slices.Equal(active, tt.wantActive) {
+				t.Errorf("buckets %v with %v active users, want %v with %v", starts, active, tt.wantStarts, tt.wantActive)
+			}
+			if resp.WeeklyActiveUsers != tt.wantWeekly || resp.MonthlyActiveUsers != tt.wantMonthly {
+				t.Errorf("%d weekly and %d monthly actives, want %d and %d", resp.WeeklyActiveUsers, resp.MonthlyActiveUsers, tt.wantWeekly, tt.wantMonthly)
+			}
+		})
+	}
+}
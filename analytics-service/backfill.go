@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+	"github.com/technonext/todo-app/proto/publicid"
+	"github.com/technonext/todo-app/proto/schema"
+	"github.com/technonext/todo-app/proto/tenant"
+)
+
+// backfillSource marks the events the backfill generated, which a unique
+// index keeps to one per task and event type.
+const backfillSource = "backfill"
+
+// backfillTask is the part of a task document the backfill needs.
+type backfillTask struct {
+	ID          primitive.ObjectID `bson:"_id"`
+	PublicID    string             `bson:"public_id"`
+	UserID      string             `bson:"user_id"`
+	Completed   bool               `bson:"completed"`
+	CreatedAt   string             `bson:"created_at"`
+	UpdatedAt   string             `bson:"updated_at"`
+	CompletedAt string             `bson:"completed_at"`
+	Priority    string             `bson:"priority"`
+	Labels      []string           `bson:"labels"`
+	TenantID    string             `bson:"tenant_id"`
+}
+
+// BackfillAnalytics generates the task.created and task.completed events for
+// tasks that predate event tracking, then rebuilds the stats counters and the
+// daily rollups. Events are upserted by task and type, so running it again
+// only fills in what is still missing. Events that retention would purge
+// straight away are skipped.
+func (s *server) BackfillAnalytics(ctx context.Context, req *pb.BackfillRequest) (*pb.BackfillResponse, error) {
+	// Even a failed backfill may have stored events for any user
+	defer s.cache.invalidateAll()
+
+	cursor, err := s.taskCollection.Find(ctx, bson.M{}, options.Find().SetBatchSize(500))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	now := time.Now()
+	resp := &pb.BackfillResponse{}
+	openTasks := make(map[string]openCount)
+	for cursor.Next(ctx) {
+		var task backfillTask
+		if err := cursor.Decode(&task); err != nil {
+			return nil, err
+		}
+		resp.TasksProcessed++
+		if !task.Completed {
+			open := openTasks[task.UserID]
+			open.tenantID = task.TenantID
+			open.count++
+			openTasks[task.UserID] = open
+		}
+
+		if !s.retention.expired(task.CreatedAt, now) {
+			created, err := s.backfillEvent(ctx, task, "task.created", task.CreatedAt)
+			if err != nil {
+				return nil, err
+			}
+			resp.EventsCreated += created
+		}
+
+		if task.Completed {
+			completedAt := task.CompletedAt
+			if completedAt == "" {
+				completedAt = task.UpdatedAt
+			}
+			if !s.retention.expired(completedAt, now) {
+				created, err := s.backfillEvent(ctx, task, "task.completed", completedAt)
+				if err != nil {
+					return nil, err
+				}
+				resp.EventsCreated += created
+			}
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := s.rebuildDailyStats(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.rollupStats(ctx, true); err != nil {
+		return nil, err
+	}
+	if err := s.resetOpenTasks(ctx, openTasks); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Analytics backfill processed %d tasks, created %d events", resp.TasksProcessed, resp.EventsCreated)
+	return resp, nil
+}
+
+// backfillEvent inserts an event unless one already exists for the task,
+// returning how many were created. When a concurrent backfill inserts the same
+// event first, the unique index rejects this one. Events name the task by its
+// public id, but those tracked before it had one name it by ObjectID.
+func (s *server) backfillEvent(ctx context.Context, task backfillTask, eventType, createdAt string) (int64, error) {
+	resourceID := publicid.Or(task.PublicID, task.ID)
+	filter := bson.M{"resource_id": bson.M{"$in": bson.A{task.ID.Hex(), resourceID}}, "event_type": eventType}
+	metadata := bson.M{"source": backfillSource}
+	if task.Priority != "" {
+		metadata["priority"] = strings.TrimPrefix(task.Priority, "TASK_PRIORITY_")
+	}
+	if len(task.Labels) > 0 {
+		metadata["labels"] = task.Labels
+	}
+	insert := bson.M{
+		"resource_id": resourceID,
+		"user_id":     task.UserID,
+		"metadata":    metadata,
+		"created_at":  createdAt,
+		schema.Field:  eventSchema.Version(),
+	}
+	// A backfill for every tenant files each event under its task's
+	if task.TenantID != "" {
+		insert[tenant.Field] = task.TenantID
+	}
+	update := bson.M{"$setOnInsert": insert}
+
+	result, err := s.collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return result.UpsertedCount, nil
+}
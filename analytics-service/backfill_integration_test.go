@@ -0,0 +1,160 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+	"github.com/technonext/todo-app/proto/publicid"
+	"github.com/technonext/todo-app/proto/tenant"
+)
+
+// newBackfillServer returns a server backfilling from a tasks collection next
+// to the events, keeping events for retention.
+func newBackfillServer(t *testing.T, retention time.Duration) *server {
+	stats := newMongoService(t, &fakeTaskClient{})
+	repo := stats.stats.(*mongoRepository)
+	return &server{
+		statsService:   stats,
+		collection:     repo.events,
+		dailyStats:     repo.dailyStats,
+		userCounters:   repo.userCounters,
+		statsDaily:     repo.statsDaily,
+		jobs:           repo.jobs,
+		retention:      retentionPolicy{age: retention},
+		taskCollection: tenant.Scoped(repo.events.Database().Collection("tasks")),
+	}
+}
+
+// documents returns every document of collection, by id.
+func documents(t *testing.T, collection *tenant.Collection) []bson.M {
+	t.Helper()
+	ctx := context.Background()
+	cursor, err := collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var docs []bson.M
+	if err := cursor.All(ctx, &docs); err != nil {
+		t.Fatal(err)
+	}
+	return docs
+}
+
+func TestMongoBackfillAnalytics(t *testing.T) {
+	s := newBackfillServer(t, 90*24*time.Hour)
+	ctx := context.Background()
+	now := time.Now().UTC()
+	recent := now.Add(-48 * time.Hour).Format(time.RFC3339)
+	done := now.Add(-24 * time.Hour).Format(time.RFC3339)
+	expired := now.Add(-400 * 24 * time.Hour).Format(time.RFC3339)
+
+	completed, legacy, old := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	completedID, legacyID := publicid.New(publicid.Task), publicid.New(publicid.Task)
+	_, err := s.taskCollection.InsertMany(ctx, []interface{}{
+		bson.M{"_id": completed, publicid.Field: completedID, "user_id": "u1", "completed": true,
+			"created_at": recent, "updated_at": done, "completed_at": done, "priority": "TASK_PRIORITY_HIGH"},
+		bson.M{"_id": legacy, publicid.Field: legacyID, "user_id": "u1", "created_at": recent, "updated_at": recent},
+		bson.M{"_id": old, "user_id": "u1", "created_at": expired, "updated_at": expired},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Tracked before tasks had public ids, so naming the task by ObjectID
+	_, err = s.collection.InsertOne(ctx, bson.M{
+		"user_id": "u1", "event_type": "task.created", "resource_id": legacy.Hex(), "created_at": recent,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := s.BackfillAnalytics(ctx, &pb.BackfillRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.TasksProcessed != 3 || resp.EventsCreated != 2 {
+		t.Fatalf("first run processed %d tasks, created %d events; want 3 and 2", resp.TasksProcessed, resp.EventsCreated)
+	}
+	for _, tt := range []struct {
+		name   string
+		filter bson.M
+		want   int64
+	}{
+		{"completed task", bson.M{"resource_id": completedID, "metadata.source": backfillSource, "metadata.priority": "HIGH"}, 2},
+		{"legacy task", bson.M{"resource_id": bson.M{"$in": bson.A{legacy.Hex(), legacyID}}}, 1},
+		{"expired task", bson.M{"resource_id": old.Hex()}, 0},
+	} {
+		if n, err := s.collection.CountDocuments(ctx, tt.filter); err != nil || n != tt.want {
+			t.Errorf("%s: %d events (%v), want %d", tt.name, n, err, tt.want)
+		}
+	}
+
+	repo := s.stats.(*mongoRepository)
+	open, err := repo.OpenTasks(ctx, "u1")
+	if err != nil || open != 2 {
+		t.Errorf("open tasks = %d (%v), want 2", open, err)
+	}
+	created, completedCount, err := repo.RangeTotals(ctx, "u1", dateRange{start: now.Add(-7 * 24 * time.Hour), end: now})
+	if err != nil || created != 2 || completedCount != 1 {
+		t.Errorf("last week created %d, completed %d (%v); want 2 and 1", created, completedCount, err)
+	}
+
+	daily, counters := documents(t, s.dailyStats), documents(t, s.userCounters)
+	resp, err = s.BackfillAnalytics(ctx, &pb.BackfillRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.TasksProcessed != 3 || resp.EventsCreated != 0 {
+		t.Errorf("second run processed %d tasks, created %d events; want 3 and 0", resp.TasksProcessed, resp.EventsCreated)
+	}
+	if n, err := s.collection.CountDocuments(ctx, bson.M{}); err != nil || n != 3 {
+		t.Errorf("%d events after the second run (%v), want 3", n, err)
+	}
+	if got := documents(t, s.dailyStats); !reflect.DeepEqual(got, daily) {
+		t.Errorf("second run changed the daily stats from %v to %v", daily, got)
+	}
+	if got := documents(t, s.userCounters); !reflect.DeepEqual(got, counters) {
+		t.Errorf("second run changed the counters from %v to %v", counters, got)
+	}
+}
+
+func TestMongoBackfillEvent(t *testing.T) {
+	s := newBackfillServer(t, 0)
+	ctx := context.Background()
+	task := backfillTask{ID: primitive.NewObjectID(), PublicID: publicid.New(publicid.Task), UserID: "u1"}
+	_, err := s.collection.InsertOne(ctx, bson.M{
+		"user_id": "u1", "event_type": "task.created", "resource_id": task.ID.Hex(), "created_at": "2026-03-01T09:00:00Z",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		eventType string
+		want      int64
+	}{
+		{"tracked under its ObjectID", "task.created", 0},
+		{"missing", "task.completed", 1},
+		{"backfilled already", "task.completed", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			created, err := s.backfillEvent(ctx, task, tt.eventType, "2026-03-02T09:00:00Z")
+			if err != nil || created != tt.want {
+				t.Errorf("created %d (%v), want %d", created, err, tt.want)
+			}
+		})
+	}
+	if n, err := s.collection.CountDocuments(ctx, bson.M{"resource_id": task.PublicID, "event_type": "task.completed"}); err != nil || n != 1 {
+		t.Errorf("%d task.completed events under the public id (%v), want 1", n, err)
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+const (
+	maxBatchEvents = 1000
+	// Client clocks running ahead by up to this much are clamped to now
+	maxClockSkew = 5 * time.Minute
+	// Queued events older than this are rejected rather than backdated
+	maxEventAge = 7 * 24 * time.Hour
+)
+
+// duplicateKeyCode is the Mongo error code of a unique index violation.
+const duplicateKeyCode = 11000
+
+const (
+	eventAccepted   = "accepted"
+	eventDuplicate  = "duplicate"
+	eventSampledOut = "sampled_out"
+	eventRejected   = "rejected"
+)
+
+// eventFromRequest validates a tracked event and resolves its timestamp:
+// the server time, unless the client supplied a plausible one.
+func (s *statsService) eventFromRequest(req *pb.TrackEventRequest, now time.Time) (Event, error) {
+	if req.UserId == "" || req.EventType == "" {
+		return Event{}, statusError(codes.InvalidArgument, "MISSING_EVENT_FIELDS", nil, "user_id and event_type are required")
+	}
+	if err := s.eventTypes.validate(req.EventType); err != nil {
+		return Event{}, err
+	}
+
+	createdAt := now
+	if req.ClientTimestamp != "" {
+		t, err := time.Parse(time.RFC3339, req.ClientTimestamp)
+		if err != nil {
+			return Event{}, statusError(codes.InvalidArgument, "INVALID_CLIENT_TIMESTAMP", map[string]string{"client_timestamp": req.ClientTimestamp}, "client_timestamp must be an RFC3339 timestamp: %v", err)
+		}
+		switch {
+		case t.After(now.Add(maxClockSkew)):
+			return Event{}, statusError(codes.InvalidArgument, "CLIENT_TIMESTAMP_IN_FUTURE", map[string]string{"client_timestamp": req.ClientTimestamp}, "client_timestamp %s is more than %s in the future", req.ClientTimestamp, maxClockSkew)
+		case now.Sub(t) > maxEventAge:
+			return Event{}, statusError(codes.InvalidArgument, "CLIENT_TIMESTAMP_TOO_OLD", map[string]string{"client_timestamp": req.ClientTimestamp}, "client_timestamp %s is older than %s", req.ClientTimestamp, maxEventAge)
+		case t.Before(now):
+			createdAt = t
+		}
+	}
+
+	return Event{
+		UserID:        req.UserId,
+		EventType:     req.EventType,
+		ResourceID:    req.ResourceId,
+		Metadata:      req.Metadata.AsMap(),
+		ClientEventID: req.ClientEventId,
+		CreatedAt:     createdAt.In(time.Local).Format(time.RFC3339),
+	}, nil
+}
+
+// TrackEvents stores a batch of events in one round-trip. Each event gets its
+// own result, so one bad event does not fail the batch.
+func (s *server) TrackEvents(ctx context.Context, req *pb.TrackEventsRequest) (*pb.TrackEventsResponse, error) {
+	if len(req.Events) == 0 {
+		return nil, statusError(codes.InvalidArgument, "EMPTY_BATCH", nil, "events must not be empty")
+	}
+	if len(req.Events) > maxBatchEvents {
+		return nil, statusError(codes.InvalidArgument, "BATCH_TOO_LARGE", map[string]string{"max_events": strconv.Itoa(maxBatchEvents)}, "at most %d events can be tracked per batch", maxBatchEvents)
+	}
+
+	now := time.Now()
+	resp := &pb.TrackEventsResponse{Results: make([]*pb.TrackEventResult, len(req.Events))}
+	var events []Event
+	var positions []int
+	seen := make(map[string]bool)
+
+	for i, item := range req.Events {
+		result := &pb.TrackEventResult{Index: int32(i)}
+		resp.Results[i] = result
+
+		event, err := s.eventFromRequest(item, now)
+		if err != nil {
+			result.Status = eventRejected
+			result.Error = status.Convert(err).Message()
+			continue
+		}
+		if event.ClientEventID != "" {
+			key := event.UserID + "\x00" + event.ClientEventID
+			if seen[key] {
+				result.Status = eventDuplicate
+				continue
+			}
+			seen[key] = true
+		}
+
+		event.ID = primitive.NewObjectID()
+		events = append(events, event)
+		positions = append(positions, i)
+	}
+
+	admitted, err := s.limits.admit(ctx, events)
+	if err != nil {
+		return nil, err
+	}
+	kept, keptPositions := events[:0], positions[:0]
+	for i, err := range admitted {
+		result := resp.Results[positions[i]]
+		switch {
+		case err == errSampledOut:
+			result.Status = eventSampledOut
+		case err != nil:
+			result.Status = eventRejected
+			result.Error = status.Convert(err).Message()
+		default:
+			kept = append(kept, events[i])
+			keptPositions = append(keptPositions, positions[i])
+		}
+	}
+	events, positions = kept, keptPositions
+
+	if len(events) > 0 {
+		failed, err := s.storeEvents(ctx, events)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, event := range events {
+			result := resp.Results[positions[i]]
+			if writeErr, ok := failed[i]; ok {
+				if writeErr.HasErrorCode(duplicateKeyCode) {
+					result.Status = eventDuplicate
+				} else {
+					result.Status = eventRejected
+					result.Error = writeErr.Message
+				}
+				continue
+			}
+
+			result.Status = eventAccepted
+			result.Event = event.toProto()
+		}
+	}
+
+	for _, result := range resp.Results {
+		switch result.Status {
+		case eventAccepted:
+			resp.Accepted++
+		case eventDuplicate:
+			resp.Duplicates++
+		case eventSampledOut:
+			resp.SampledOut++
+		default:
+			resp.Rejected++
+		}
+	}
+
+	return resp, nil
+}
+
+// storeEvents inserts events, which must already have ids, in one round-trip
+// and applies the stored ones to the counters. It returns the write error of
+// each event that was not stored, by index; duplicates have duplicateKeyCode.
+func (s *server) storeEvents(ctx context.Context, events []Event) (map[int]mongo.WriteError, error) {
+	docs := make([]interface{}, len(events))
+	for i, event := range events {
+		event.SchemaVersion = eventSchema.Version()
+		docs[i] = event
+	}
+
+	// Unordered so the rest of the batch is stored when an event was
+	// already tracked by an earlier flush
+	failed := make(map[int]mongo.WriteError)
+	_, err := s.collection.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+	var bulkErr mongo.BulkWriteException
+	if errors.As(err, &bulkErr) && bulkErr.WriteConcernError == nil {
+		for _, writeErr := range bulkErr.WriteErrors {
+			failed[writeErr.Index] = writeErr.WriteError
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	for i, event := range events {
+		if _, ok := failed[i]; ok {
+			continue
+		}
+		if err := s.stats.ApplyEvent(ctx, event); err != nil {
+			log.Printf("Failed to update counters for %s event: %v", event.EventType, err)
+		}
+		s.cache.invalidateUser(event.UserID)
+		s.bus.publish(ctx, event)
+	}
+	return failed, nil
+}
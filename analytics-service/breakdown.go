@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"google.golang.org/grpc/codes"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+const (
+	defaultTopLabels = 10
+	maxTopLabels     = 50
+)
+
+// priorityOrder is the order priorities are reported in; tasks without one
+// come last.
+var priorityOrder = []string{"LOW", "MEDIUM", "HIGH", "URGENT", "UNSPECIFIED"}
+
+// GetTaskBreakdown slices the user's tasks created in the range by label and
+// by priority. Both come from the metadata of each task's latest event, so
+// the task service is not read.
+func (s *server) GetTaskBreakdown(ctx context.Context, req *pb.GetTaskBreakdownRequest) (*pb.GetTaskBreakdownResponse, error) {
+	if req.UserId == "" {
+		return nil, statusError(codes.InvalidArgument, "USER_ID_REQUIRED", nil, "user_id is required")
+	}
+	topLabels := req.TopLabels
+	if topLabels == 0 {
+		topLabels = defaultTopLabels
+	}
+	if topLabels < 0 || topLabels > maxTopLabels {
+		return nil, statusError(codes.InvalidArgument, "INVALID_TOP_LABELS", nil, "top_labels must be between 1 and %d", maxTopLabels)
+	}
+
+	dates, err := parseDateRange(req.StartDate, req.EndDate, time.UTC)
+	if err != nil {
+		return nil, err
+	}
+
+	match := eventsIn(dates)
+	match["user_id"] = req.UserId
+	match["event_type"] = bson.M{"$in": bson.A{"task.created", "task.completed", "task.reopened"}}
+
+	lastIDOf := func(eventType string) bson.M {
+		return bson.M{"$max": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$event_type", eventType}}, "$_id", nil}}}
+	}
+	bucket := func(key interface{}) bson.M {
+		return bson.M{
+			"_id":       key,
+			"total":     bson.M{"$sum": "$weight"},
+			"completed": bson.M{"$sum": bson.M{"$cond": bson.A{"$completed", "$weight", 0}}},
+		}
+	}
+
+	pipeline := []bson.M{
+		{"$match": match},
+		{"$sort": bson.M{"_id": 1}},
+		// One document per task: whether it was created in the range, whether
+		// its last completion outlasts its last reopen, and its latest
+		// priority and labels
+		{"$group": bson.M{
+			"_id":           "$resource_id",
+			"created":       lastIDOf("task.created"),
+			"last_complete": lastIDOf("task.completed"),
+			"last_reopen":   lastIDOf("task.reopened"),
+			"priority":      bson.M{"$last": "$metadata.priority"},
+			"labels":        bson.M{"$last": "$metadata.labels"},
+			"weight":        bson.M{"$max": eventWeight},
+		}},
+		{"$match": bson.M{"created": bson.M{"$ne": nil}}},
+		{"$project": bson.M{
+			"priority":  bson.M{"$ifNull": bson.A{"$priority", "UNSPECIFIED"}},
+			"labels":    1,
+			"weight":    1,
+			"completed": bson.M{"$gt": bson.A{"$last_complete", "$last_reopen"}},
+		}},
+		{"$facet": bson.M{
+			"labels": bson.A{
+				bson.M{"$unwind": "$labels"},
+				bson.M{"$group": bucket("$labels")},
+				bson.M{"$sort": bson.D{{Key: "total", Value: -1}, {Key: "_id", Value: 1}}},
+				bson.M{"$limit": topLabels},
+			},
+			"priorities": bson.A{
+				bson.M{"$group": bucket("$priority")},
+			},
+		}},
+	}
+
+	cursor, err := s.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	type row struct {
+		Key       string `bson:"_id"`
+		Total     int32  `bson:"total"`
+		Completed int32  `bson:"completed"`
+	}
+	var result struct {
+		Labels     []row `bson:"labels"`
+		Priorities []row `bson:"priorities"`
+	}
+	if cursor.Next(ctx) {
+		if err := cursor.Decode(&result); err != nil {
+			return nil, err
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	toBucket := func(r row) *pb.BreakdownBucket {
+		b := &pb.BreakdownBucket{Key: r.Key, Total: r.Total, Completed: r.Completed}
+		if r.Total > 0 {
+			b.CompletionRate = float64(r.Completed) / float64(r.Total)
+		}
+		return b
+	}
+
+	resp := &pb.GetTaskBreakdownResponse{}
+	for _, r := range result.Labels {
+		resp.Labels = append(resp.Labels, toBucket(r))
+	}
+
+	rank := func(priority string) int {
+		for i, p := range priorityOrder {
+			if p == priority {
+				return i
+			}
+		}
+		return len(priorityOrder)
+	}
+	sort.SliceStable(result.Priorities, func(i, j int) bool {
+		return rank(result.Priorities[i].Key) < rank(result.Priorities[j].Key)
+	})
+	for _, r := range result.Priorities {
+		resp.Priorities = append(resp.Priorities, toBucket(r))
+	}
+	return resp, nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"math"
+	"time"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// burndownLines returns the ideal and actual burndowns of total tasks over
+// days, the sprint's days oldest first, from the completions on each. Every
+// point is the number of tasks left at the end of its day: the ideal line
+// reaches none at the end of the last day, and the actual line takes the
+// running sum of completions off the total. The actual line stops after
+// through, the last day that has happened; days is never empty.
+func burndownLines(days []string, through string, total int32, completions map[string]int32) (ideal, actual []*pb.BurndownPoint) {
+	n := float64(len(days))
+	var done int32
+	for i, day := range days {
+		ideal = append(ideal, &pb.BurndownPoint{
+			Date:           day,
+			RemainingTasks: float64(total) * (n - 1 - float64(i)) / n,
+		})
+		if day > through {
+			continue
+		}
+		done += completions[day]
+		actual = append(actual, &pb.BurndownPoint{
+			Date:           day,
+			RemainingTasks: math.Max(0, float64(total-done)),
+		})
+	}
+	return ideal, actual
+}
+
+// getBurndown counts the sprint's completions from the user's daily
+// counters, which the task.completed events update, rather than from the
+// tasks: a task completed in the sprint and reopened since still burned down
+// on the day it was completed.
+func (s *statsService) getBurndown(ctx context.Context, req *pb.GetBurndownRequest) (*pb.GetBurndownResponse, error) {
+	r, err := parseDateRange(req.SprintStart, req.SprintEnd, time.UTC)
+	if err != nil {
+		return nil, err
+	}
+	r.start = r.start.UTC().Truncate(24 * time.Hour)
+
+	completions, err := s.stats.DailyCompletions(ctx, req.UserId, r)
+	if err != nil {
+		return nil, err
+	}
+
+	var days []string
+	for day := r.start; !day.After(r.end); day = day.AddDate(0, 0, 1) {
+		days = append(days, day.Format(dayFormat))
+	}
+	ideal, actual := burndownLines(days, time.Now().UTC().Format(dayFormat), req.TotalTasks, completions)
+	return &pb.GetBurndownResponse{Ideal: ideal, Actual: actual}, nil
+}
+
+func (s *server) GetBurndown(ctx context.Context, req *pb.GetBurndownRequest) (*pb.GetBurndownResponse, error) {
+	return s.getBurndown(ctx, req)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// remaining returns the remaining_tasks of points.
+func remaining(points []*pb.BurndownPoint) []float64 {
+	var values []float64
+	for _, p := range points {
+		values = append(values, p.RemainingTasks)
+	}
+	return values
+}
+
+func TestBurndownLines(t *testing.T) {
+	days := []string{"2026-03-02", "2026-03-03", "2026-03-04", "2026-03-05"}
+	tests := []struct {
+		name        string
+		through     string
+		total       int32
+		completions map[string]int32
+		wantIdeal   []float64
+		wantActual  []float64
+	}{
+		{"on track", "2026-03-05", 8,
+			map[string]int32{"2026-03-02": 2, "2026-03-03": 2, "2026-03-04": 2, "2026-03-05": 2},
+			[]float64{6, 4, 2, 0}, []float64{6, 4, 2, 0}},
+		{"idle days", "2026-03-05", 8,
+			map[string]int32{"2026-03-03": 5},
+			[]float64{6, 4, 2, 0}, []float64{8, 3, 3, 3}},
+		{"completions outnumbering the total", "2026-03-05", 2,
+			map[string]int32{"2026-03-02": 1, "2026-03-04": 3},
+			[]float64{1.5, 1, 0.5, 0}, []float64{1, 1, 0, 0}},
+		{"sprint still running", "2026-03-03", 4,
+			map[string]int32{"2026-03-02": 1},
+			[]float64{3, 2, 1, 0}, []float64{3, 3}},
+		{"sprint not started", "2026-03-01", 4, nil,
+			[]float64{3, 2, 1, 0}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ideal, actual := burndownLines(days, tt.through, tt.total, tt.completions)
+			if got := remaining(ideal); !reflect.DeepEqual(got, tt.wantIdeal) {
+				t.Errorf("ideal = %v, want %v", got, tt.wantIdeal)
+			}
+			if got := remaining(actual); !reflect.DeepEqual(got, tt.wantActual) {
+				t.Errorf("actual = %v, want %v", got, tt.wantActual)
+			}
+			for i, p := range ideal {
+				if p.Date != days[i] || i < len(actual) && actual[i].Date != days[i] {
+					t.Errorf("point %d dated %s, want %s", i, p.Date, days[i])
+				}
+			}
+		})
+	}
+}
+
+func TestGetBurndown(t *testing.T) {
+	repo := newFakeRepository()
+	s := newTestService(repo, &fakeTaskClient{})
+	repo.daily["u1"] = map[string][2]int32{
+		// Before the sprint
+		"2026-02-28": {0, 9},
+		"2026-03-02": {3, 1},
+		"2026-03-04": {0, 4},
+		"2026-03-06": {1, 2},
+		// After it
+		"2026-03-07": {0, 9},
+	}
+
+	resp, err := s.getBurndown(context.Background(), &pb.GetBurndownRequest{
+		UserId: "u1", SprintStart: "2026-03-02", SprintEnd: "2026-03-06T12:00:00Z", TotalTasks: 10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := remaining(resp.Ideal), []float64{8, 6, 4, 2, 0}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ideal = %v, want %v", got, want)
+	}
+	if got, want := remaining(resp.Actual), []float64{9, 9, 5, 5, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("actual = %v, want %v", got, want)
+	}
+	if first, last := resp.Actual[0].Date, resp.Actual[len(resp.Actual)-1].Date; first != "2026-03-02" || last != "2026-03-06" {
+		t.Errorf("actual runs %s to %s, want 2026-03-02 to 2026-03-06", first, last)
+	}
+
+	// A sprint ending next week has no actual points after today
+	today := time.Now().UTC()
+	resp, err = s.getBurndown(context.Background(), &pb.GetBurndownRequest{
+		UserId: "u1", SprintStart: today.AddDate(0, 0, -2).Format(dayFormat), SprintEnd: today.AddDate(0, 0, 7).Format(dayFormat), TotalTasks: 5,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Ideal) != 10 || len(resp.Actual) != 3 {
+		t.Errorf("%d ideal and %d actual points, want 10 and 3", len(resp.Ideal), len(resp.Actual))
+	}
+
+	for _, tt := range []struct {
+		req    *pb.GetBurndownRequest
+		reason string
+	}{
+		{&pb.GetBurndownRequest{UserId: "u1", SprintStart: "March", SprintEnd: "2026-03-06"}, "INVALID_START_DATE"},
+		{&pb.GetBurndownRequest{UserId: "u1", SprintStart: "2026-03-06", SprintEnd: "2026-03-02"}, "INVALID_DATE_RANGE"},
+		{&pb.GetBurndownRequest{UserId: "u1", SprintStart: "2025-01-01", SprintEnd: "2026-03-02"}, "DATE_RANGE_TOO_LONG"},
+	} {
+		_, err := s.getBurndown(context.Background(), tt.req)
+		if status.Code(err) != codes.InvalidArgument || errorReason(err) != tt.reason {
+			t.Errorf("%s to %s: %v, want InvalidArgument %s", tt.req.SprintStart, tt.req.SprintEnd, err, tt.reason)
+		}
+	}
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+	"github.com/technonext/todo-app/proto/tenant"
+)
+
+// Per-user read RPCs are served from an in-process cache for a short TTL.
+// Entries are keyed by the method and the normalized request, and every entry
+// of a user is dropped as soon as an event is tracked for them, so reads never
+// trail that user's own writes. Responses are shared between callers and must
+// not be modified.
+//
+// Invalidation only reaches the replica that tracked the event: with several
+// replicas, another one keeps serving its cached stats until they expire, so
+// ANALYTICS_CACHE_TTL bounds how stale stats read elsewhere can be.
+
+// cacheLoadTimeout bounds a load shared by concurrent misses. The load runs
+// apart from the caller that started it, so that caller going away does not
+// fail the others waiting on it.
+const cacheLoadTimeout = 30 * time.Second
+
+// cachedMethods are the read RPCs whose responses are cached. They are only
+// cached for requests naming a user, since invalidation is per user.
+var cachedMethods = map[string]bool{
+	pb.AnalyticsService_GetUserStats_FullMethodName:          true,
+	pb.AnalyticsService_GetPeakHours_FullMethodName:          true,
+	pb.AnalyticsService_GetCompletionTrend_FullMethodName:    true,
+	pb.AnalyticsService_GetUserStreak_FullMethodName:         true,
+	pb.AnalyticsService_GetActivityHeatmap_FullMethodName:    true,
+	pb.AnalyticsService_GetCompletionLatency_FullMethodName:  true,
+	pb.AnalyticsService_GetTaskBreakdown_FullMethodName:      true,
+	pb.AnalyticsService_GetCompletionForecast_FullMethodName: true,
+	pb.AnalyticsService_GetBurndown_FullMethodName:           true,
+}
+
+type readCache struct {
+	ttl      time.Duration
+	capacity int
+
+	mu sync.Mutex
+	// lru holds *cacheEntry, most recently used first
+	lru     *list.List
+	entries map[string]*list.Element
+	byUser  map[string]map[string]bool
+	// loading counts the loads in flight per user. While a user has any,
+	// generations counts their invalidations, and epoch counts invalidations
+	// of everyone, so a load that started before one is not cached after it
+	loading     map[string]int
+	generations map[string]uint64
+	epoch       uint64
+
+	group singleflight.Group
+
+	hits          uint64
+	misses        uint64
+	evictions     uint64
+	invalidations uint64
+}
+
+type cacheEntry struct {
+	key     string
+	userID  string
+	value   interface{}
+	expires time.Time
+}
+
+// loadReadCache reads ANALYTICS_CACHE_TTL and ANALYTICS_CACHE_SIZE; a zero
+// TTL turns caching off.
+func loadReadCache() (*readCache, error) {
+	ttl, err := time.ParseDuration(getEnv("ANALYTICS_CACHE_TTL", "60s"))
+	if err != nil || ttl < 0 {
+		return nil, fmt.Errorf("invalid ANALYTICS_CACHE_TTL %q", os.Getenv("ANALYTICS_CACHE_TTL"))
+	}
+	capacity, err := strconv.Atoi(getEnv("ANALYTICS_CACHE_SIZE", "10000"))
+	if err != nil || capacity <= 0 {
+		return nil, fmt.Errorf("invalid ANALYTICS_CACHE_SIZE %q: must be a positive number of entries", os.Getenv("ANALYTICS_CACHE_SIZE"))
+	}
+	return newReadCache(ttl, capacity), nil
+}
+
+func newReadCache(ttl time.Duration, capacity int) *readCache {
+	return &readCache{
+		ttl:         ttl,
+		capacity:    capacity,
+		lru:         list.New(),
+		entries:     make(map[string]*list.Element),
+		byUser:      make(map[string]map[string]bool),
+		loading:     make(map[string]int),
+		generations: make(map[string]uint64),
+	}
+}
+
+// unaryInterceptor serves cachedMethods from the cache. Concurrent misses on
+// the same key share a single call to the handler.
+func (c *readCache) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if c.ttl == 0 || !cachedMethods[info.FullMethod] {
+		return handler(ctx, req)
+	}
+	msg, ok := req.(interface {
+		proto.Message
+		GetUserId() string
+	})
+	if !ok || msg.GetUserId() == "" {
+		return handler(ctx, req)
+	}
+	// A fresh read asks to skip snapshots, so it skips the cache too
+	if fresh, ok := req.(interface{ GetFresh() bool }); ok && fresh.GetFresh() {
+		return handler(ctx, req)
+	}
+
+	key, err := cacheKey(info.FullMethod, msg)
+	if err != nil {
+		return handler(ctx, req)
+	}
+	// Another tenant asking about the same user id sees its own data
+	if id, ok := tenant.FromContext(ctx); ok {
+		key = id + "\x00" + key
+	}
+	userID := msg.GetUserId()
+
+	if value, ok := c.get(key, time.Now()); ok {
+		atomic.AddUint64(&c.hits, 1)
+		return value, nil
+	}
+	atomic.AddUint64(&c.misses, 1)
+
+	loaded := c.group.DoChan(key, func() (interface{}, error) {
+		loadCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cacheLoadTimeout)
+		defer cancel()
+		generation := c.beginLoad(userID)
+		value, err := handler(loadCtx, req)
+		c.endLoad(userID, key, value, err, generation, time.Now())
+		return value, err
+	})
+	select {
+	case <-ctx.Done():
+		return nil, status.FromContextError(ctx.Err()).Err()
+	case result := <-loaded:
+		return result.Val, result.Err
+	}
+}
+
+// cacheKey identifies a request by method and content. Timestamps in the
+// date range are normalized to UTC so the same instant written in different
+// offsets shares an entry; plain dates are kept, since what they mean
+// depends on the request's timezone, which is part of the key.
+func cacheKey(method string, req proto.Message) (string, error) {
+	req = proto.Clone(req)
+	fields := req.ProtoReflect().Descriptor().Fields()
+	for _, name := range []protoreflect.Name{"start_date", "end_date"} {
+		field := fields.ByName(name)
+		if field == nil || field.Kind() != protoreflect.StringKind {
+			continue
+		}
+		value := req.ProtoReflect().Get(field).String()
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			req.ProtoReflect().Set(field, protoreflect.ValueOfString(t.UTC().Format(time.RFC3339Nano)))
+		}
+	}
+
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+	return method + "\x00" + string(data), nil
+}
+
+func (c *readCache) get(key string, now time.Time) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*cacheEntry)
+	if now.After(entry.expires) {
+		c.remove(elem)
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	return entry.value, true
+}
+
+func (c *readCache) beginLoad(userID string) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.loading[userID]++
+	// Both only grow while the load runs, so the sum changes if either does
+	return c.epoch + c.generations[userID]
+}
+
+// endLoad stores a successful load unless the user was invalidated since it
+// began.
+func (c *readCache) endLoad(userID, key string, value interface{}, err error, generation uint64, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stale := c.epoch+c.generations[userID] != generation
+	if c.loading[userID]--; c.loading[userID] == 0 {
+		delete(c.loading, userID)
+		delete(c.generations, userID)
+	}
+	if err != nil || stale {
+		return
+	}
+	c.store(userID, key, value, now)
+}
+
+// put caches value under key for the user.
+func (c *readCache) put(userID, key string, value interface{}, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.store(userID, key, value, now)
+}
+
+// store adds an entry, evicting the least recently used ones past capacity;
+// c.mu must be held.
+func (c *readCache) store(userID, key string, value interface{}, now time.Time) {
+	if elem, ok := c.entries[key]; ok {
+		c.remove(elem)
+	}
+	c.entries[key] = c.lru.PushFront(&cacheEntry{key: key, userID: userID, value: value, expires: now.Add(c.ttl)})
+	if c.byUser[userID] == nil {
+		c.byUser[userID] = make(map[string]bool)
+	}
+	c.byUser[userID][key] = true
+
+	for c.lru.Len() > c.capacity {
+		c.remove(c.lru.Back())
+		c.evictions++
+	}
+}
+
+// remove drops one entry; c.mu must be held.
+func (c *readCache) remove(elem *list.Element) {
+	entry := c.lru.Remove(elem).(*cacheEntry)
+	delete(c.entries, entry.key)
+	keys := c.byUser[entry.userID]
+	delete(keys, entry.key)
+	if len(keys) == 0 {
+		delete(c.byUser, entry.userID)
+	}
+}
+
+// invalidateUser drops every cached response for the user.
+func (c *readCache) invalidateUser(userID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.loading[userID] > 0 {
+		c.generations[userID]++
+	}
+	for key := range c.byUser[userID] {
+		c.remove(c.entries[key])
+	}
+	c.invalidations++
+}
+
+// invalidateAll empties the cache, for writes that touch many users.
+func (c *readCache) invalidateAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.epoch++
+	c.lru.Init()
+	c.entries = make(map[string]*list.Element)
+	c.byUser = make(map[string]map[string]bool)
+	c.invalidations++
+}
+
+func (c *readCache) stats() *pb.CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return &pb.CacheStats{
+		Enabled:       c.ttl > 0,
+		TtlSeconds:    int32(c.ttl / time.Second),
+		Capacity:      int32(c.capacity),
+		Entries:       int32(c.lru.Len()),
+		Hits:          atomic.LoadUint64(&c.hits),
+		Misses:        atomic.LoadUint64(&c.misses),
+		Evictions:     c.evictions,
+		Invalidations: c.invalidations,
+	}
+}
+
+// GetCacheStats reports how well the read cache is doing.
+func (s *server) GetCacheStats(ctx context.Context, req *pb.GetCacheStatsRequest) (*pb.GetCacheStatsResponse, error) {
+	return &pb.GetCacheStatsResponse{Stats: s.cache.stats()}, nil
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+var userStatsInfo = &grpc.UnaryServerInfo{FullMethod: pb.AnalyticsService_GetUserStats_FullMethodName}
+
+func TestReadCacheServesHitsAndInvalidates(t *testing.T) {
+	c := newReadCache(time.Minute, 10)
+	var calls int32
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return &pb.GetUserStatsResponse{Stats: &pb.UserStats{TotalTasks: atomic.AddInt32(&calls, 1)}}, nil
+	}
+	get := func(req *pb.GetUserStatsRequest) int32 {
+		t.Helper()
+		resp, err := c.unaryInterceptor(context.Background(), req, userStatsInfo, handler)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.(*pb.GetUserStatsResponse).Stats.TotalTasks
+	}
+
+	tests := []struct {
+		name   string
+		before func()
+		req    *pb.GetUserStatsRequest
+		want   int32
+	}{
+		{"miss", nil, &pb.GetUserStatsRequest{UserId: "u1"}, 1},
+		{"hit", nil, &pb.GetUserStatsRequest{UserId: "u1"}, 1},
+		{"same instant in another offset", nil, &pb.GetUserStatsRequest{UserId: "u1", StartDate: "2026-01-01T06:00:00+06:00"}, 2},
+		{"normalized hit", nil, &pb.GetUserStatsRequest{UserId: "u1", StartDate: "2026-01-01T00:00:00Z"}, 2},
+		{"fresh bypasses", nil, &pb.GetUserStatsRequest{UserId: "u1", Fresh: true}, 3},
+		{"other user", nil, &pb.GetUserStatsRequest{UserId: "u2"}, 4},
+		{"invalidated user", func() { c.invalidateUser("u1") }, &pb.GetUserStatsRequest{UserId: "u1"}, 5},
+		{"other user kept", nil, &pb.GetUserStatsRequest{UserId: "u2"}, 4},
+		{"invalidated everyone", func() { c.invalidateAll() }, &pb.GetUserStatsRequest{UserId: "u2"}, 6},
+	}
+	for _, tt := range tests {
+		if tt.before != nil {
+			tt.before()
+		}
+		if got := get(tt.req); got != tt.want {
+			t.Errorf("%s: got response %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestReadCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := newReadCache(time.Minute, 2)
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return &pb.GetUserStatsResponse{}, nil
+	}
+	for _, userID := range []string{"u1", "u2", "u1", "u3"} {
+		if _, err := c.unaryInterceptor(context.Background(), &pb.GetUserStatsRequest{UserId: userID}, userStatsInfo, handler); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stats := c.stats()
+	if stats.Evictions != 1 || c.byUser["u2"] != nil || c.byUser["u1"] == nil {
+		t.Errorf("evictions = %d, cached users = %v; want u2 evicted", stats.Evictions, c.byUser)
+	}
+}
+
+func TestReadCacheSharedLoadOutlivesCanceledCaller(t *testing.T) {
+	c := newReadCache(time.Minute, 10)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		close(started)
+		select {
+		case <-release:
+			return &pb.GetUserStatsResponse{Stats: &pb.UserStats{TotalTasks: 7}}, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	req := &pb.GetUserStatsRequest{UserId: "u1"}
+
+	firstCtx, cancelFirst := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := c.unaryInterceptor(firstCtx, req, userStatsInfo, handler)
+		first <- err
+	}()
+	<-started
+
+	second := make(chan interface{}, 1)
+	go func() {
+		resp, err := c.unaryInterceptor(context.Background(), req, userStatsInfo, handler)
+		if err != nil {
+			second <- err
+			return
+		}
+		second <- resp
+	}()
+
+	cancelFirst()
+	if err := <-first; status.Code(err) != codes.Canceled {
+		t.Errorf("canceled caller: code = %v, want Canceled", status.Code(err))
+	}
+	close(release)
+
+	switch got := (<-second).(type) {
+	case *pb.GetUserStatsResponse:
+		if got.Stats.TotalTasks != 7 {
+			t.Errorf("waiting caller got %d, want 7", got.Stats.TotalTasks)
+		}
+	default:
+		t.Errorf("waiting caller failed: %v", got)
+	}
+}
+
+func TestReadCachePut(t *testing.T) {
+	c := newReadCache(time.Minute, 2)
+	now := time.Now()
+	c.put("u1", "u1", 1, now)
+	c.put("u2", "u2", 2, now)
+	c.put("u3", "u3", 3, now)
+
+	tests := []struct {
+		key  string
+		at   time.Time
+		want interface{}
+	}{
+		{"u1", now, nil},
+		{"u2", now, 2},
+		{"u3", now, 3},
+		{"u3", now.Add(2 * time.Minute), nil},
+	}
+	for _, tt := range tests {
+		got, _ := c.get(tt.key, tt.at)
+		if got != tt.want {
+			t.Errorf("get(%q) at +%v = %v, want %v", tt.key, tt.at.Sub(now), got, tt.want)
+		}
+	}
+	if entries := c.stats().Entries; entries != 1 {
+		t.Errorf("entries = %d, want 1 after the expired one is dropped", entries)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+const (
+	// maxCompareUsers bounds how many users one comparison fetches stats for
+	maxCompareUsers = 10
+	// compareUserTimeout bounds each user's stats, so a slow one only costs
+	// its own slot.
+	compareUserTimeout = 3 * time.Second
+)
+
+// CompareUsersProductivity fetches the stats of each user over the same
+// range concurrently and ranks them by completion rate. A user whose stats
+// fail or time out keeps a slot with the error, after the others.
+func (s *server) CompareUsersProductivity(ctx context.Context, req *pb.CompareUsersRequest) (*pb.CompareUsersResponse, error) {
+	return s.compareUsersProductivity(ctx, req)
+}
+
+func (s *statsService) compareUsersProductivity(ctx context.Context, req *pb.CompareUsersRequest) (*pb.CompareUsersResponse, error) {
+	if len(req.UserIds) == 0 {
+		return nil, statusError(codes.InvalidArgument, "USER_IDS_REQUIRED", nil, "user_ids is required")
+	}
+	if len(req.UserIds) > maxCompareUsers {
+		return nil, statusError(codes.InvalidArgument, "TOO_MANY_USERS", map[string]string{"max_users": strconv.Itoa(maxCompareUsers)}, "at most %d users can be compared", maxCompareUsers)
+	}
+	for _, id := range req.UserIds {
+		if id == "" {
+			return nil, statusError(codes.InvalidArgument, "USER_ID_REQUIRED", nil, "user_ids must not contain empty ids")
+		}
+	}
+	dates, err := parseDateRange(req.StartDate, req.EndDate, time.UTC)
+	if err != nil {
+		return nil, err
+	}
+	days := math.Max(1, math.Ceil(dates.end.Sub(dates.start).Hours()/24))
+
+	users := make([]*pb.UserStatsSummary, len(req.UserIds))
+	var wg sync.WaitGroup
+	for i, id := range req.UserIds {
+		wg.Add(1)
+		go func(i int, id string) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, compareUserTimeout)
+			defer cancel()
+			users[i] = &pb.UserStatsSummary{UserId: id}
+			resp, err := s.getUserStats(ctx, &pb.GetUserStatsRequest{UserId: id, StartDate: req.StartDate, EndDate: req.EndDate})
+			if err != nil {
+				users[i].Error = compareError(ctx, err)
+				return
+			}
+			stats := resp.Stats
+			if stats.TotalTasks > 0 {
+				users[i].CompletionRate = float64(stats.CompletedTasks) / float64(stats.TotalTasks)
+			}
+			users[i].AvgDailyCompletions = float64(stats.CompletedTasks) / days
+			users[i].Streak = stats.CurrentStreak
+		}(i, id)
+	}
+	wg.Wait()
+
+	sort.SliceStable(users, func(i, j int) bool {
+		if (users[i].Error == "") != (users[j].Error == "") {
+			return users[i].Error == ""
+		}
+		return users[i].CompletionRate > users[j].CompletionRate
+	})
+	return &pb.CompareUsersResponse{Users: users}, nil
+}
+
+// compareError describes why a user's stats are missing from a comparison.
+func compareError(ctx context.Context, err error) string {
+	if ctx.Err() == context.DeadlineExceeded {
+		return "timeout"
+	}
+	return status.Convert(err).Message()
+}
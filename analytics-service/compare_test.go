@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+func TestCompareUsersProductivity(t *testing.T) {
+	repo := newFakeRepository()
+	s := newTestService(repo, &fakeTaskClient{})
+
+	at2, _ := daysAgo(2)
+	// low completes 1 of 4, high 3 of 3 and mid 1 of 2
+	for user, counts := range map[string][2]int{"low": {4, 1}, "high": {3, 3}, "mid": {2, 1}} {
+		for i := 0; i < counts[0]; i++ {
+			id := fmt.Sprintf("%s-%d", user, i)
+			track(t, s, &pb.TrackEventRequest{UserId: user, EventType: "task.created", ResourceId: id, ClientTimestamp: at2})
+			if i < counts[1] {
+				track(t, s, &pb.TrackEventRequest{UserId: user, EventType: "task.completed", ResourceId: id, ClientTimestamp: at2})
+			}
+		}
+	}
+
+	resp, err := s.compareUsersProductivity(context.Background(), &pb.CompareUsersRequest{UserIds: []string{"low", "high", "mid"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		user string
+		rate float64
+	}{{"high", 1}, {"mid", 0.5}, {"low", 0.25}}
+	if len(resp.Users) != len(want) {
+		t.Fatalf("got %d users, want %d", len(resp.Users), len(want))
+	}
+	for i, w := range want {
+		got := resp.Users[i]
+		if got.UserId != w.user || got.CompletionRate != w.rate || got.Error != "" {
+			t.Errorf("users[%d] = %s at %v (%q), want %s at %v", i, got.UserId, got.CompletionRate, got.Error, w.user, w.rate)
+		}
+		if got.AvgDailyCompletions <= 0 {
+			t.Errorf("%s avg daily completions = %v, want above 0", got.UserId, got.AvgDailyCompletions)
+		}
+	}
+}
+
+func TestCompareUsersProductivityValidation(t *testing.T) {
+	s := newTestService(newFakeRepository(), &fakeTaskClient{})
+	tooMany := make([]string, maxCompareUsers+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprint("u", i)
+	}
+	tests := []struct {
+		name string
+		req  *pb.CompareUsersRequest
+	}{
+		{"no users", &pb.CompareUsersRequest{}},
+		{"too many users", &pb.CompareUsersRequest{UserIds: tooMany}},
+		{"empty id", &pb.CompareUsersRequest{UserIds: []string{"u1", ""}}},
+		{"invalid range", &pb.CompareUsersRequest{UserIds: []string{"u1"}, StartDate: "last week"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.compareUsersProductivity(context.Background(), tt.req)
+			if code := status.Code(err); code != codes.InvalidArgument {
+				t.Errorf("code = %v, want InvalidArgument (%v)", code, err)
+			}
+		})
+	}
+}
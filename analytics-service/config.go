@@ -0,0 +1,38 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/technonext/todo-app/proto/mongoutil"
+	"github.com/technonext/todo-app/proto/settings"
+)
+
+// Storage drivers, chosen with STORAGE_DRIVER
+const (
+	storageMongo = settings.StorageMongo
+	// Events and counters held in memory, for local development
+	storageMemory = "memory"
+)
+
+// Config holds the settings validateConfig checks at startup; analytics reads
+// none beyond those every service shares.
+type Config struct {
+	settings.Server
+}
+
+func loadConfig(mongo mongoutil.Config) Config {
+	return Config{Server: settings.LoadServer("50054", mongo)}
+}
+
+// validateConfig reports every invalid setting in cfg, each named by its
+// environment variable.
+func validateConfig(cfg Config) error {
+	errs := cfg.Errors()
+	switch cfg.StorageDriver {
+	case storageMongo, storageMemory:
+	default:
+		errs = append(errs, fmt.Errorf("STORAGE_DRIVER: %q is not %s or %s", cfg.StorageDriver, storageMongo, storageMemory))
+	}
+	return errors.Join(errs...)
+}
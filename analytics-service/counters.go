@@ -0,0 +1,360 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/technonext/todo-app/proto/tenant"
+)
+
+// Stats are served from counters maintained on TrackEvent instead of reading
+// the task service's collection:
+//
+//   - user_daily_stats holds tasks created and completed per user and UTC day,
+//     so a date range is answered from at most one document per day.
+//   - user_counters holds each user's current number of open tasks.
+//
+// Both are updated with atomic $inc upserts and can be rebuilt by the backfill.
+// A task.ownership_migrated event moves the counters of the user whose tasks
+// were migrated onto the user who now has them.
+
+const dayFormat = "2006-01-02"
+
+// counterDeltas returns the changes an event makes to the counters.
+func counterDeltas(event Event) (created, completed, open int) {
+	switch event.EventType {
+	case "task.created":
+		return 1, 0, 1
+	case "task.completed":
+		return 0, 1, -1
+	case "task.reopened":
+		return 0, 0, 1
+	case "task.deleted":
+		// Deleting a completed task leaves the open count alone
+		if completed, _ := event.Metadata["completed"].(bool); completed {
+			return 0, 0, 0
+		}
+		return 0, 0, -1
+	}
+	return 0, 0, 0
+}
+
+// ApplyEvent applies a tracked event, weighted by its sample rate, to the
+// user's counters.
+func (m *mongoRepository) ApplyEvent(ctx context.Context, event Event) error {
+	if event.EventType == "task.ownership_migrated" {
+		return m.moveCounters(ctx, event.ResourceID, event.UserID)
+	}
+	created, completed, open := counterDeltas(event)
+	if created == 0 && completed == 0 && open == 0 {
+		return nil
+	}
+	weight := event.weight()
+	created, completed, open = created*weight, completed*weight, open*weight
+
+	at, err := time.Parse(time.RFC3339, event.CreatedAt)
+	if err != nil {
+		return err
+	}
+	day := at.UTC().Format(dayFormat)
+
+	if created != 0 || completed != 0 {
+		err := upsertInc(ctx, m.dailyStats,
+			bson.M{"_id": fmt.Sprintf("%s:%s", event.UserID, day)},
+			bson.M{
+				"$inc":         bson.M{"created": created, "completed": completed},
+				"$setOnInsert": bson.M{"user_id": event.UserID, "day": day},
+			})
+		if err != nil {
+			return err
+		}
+	}
+
+	if open != 0 {
+		return upsertInc(ctx, m.userCounters,
+			bson.M{"_id": event.UserID},
+			bson.M{"$inc": bson.M{"open_tasks": open}})
+	}
+	return nil
+}
+
+// upsertInc runs an upserting update, retrying once when a concurrent upsert
+// created the document first.
+func upsertInc(ctx context.Context, collection *tenant.Collection, filter, update bson.M) error {
+	opts := options.Update().SetUpsert(true)
+	_, err := collection.UpdateOne(ctx, filter, update, opts)
+	if mongo.IsDuplicateKeyError(err) {
+		_, err = collection.UpdateOne(ctx, filter, update, opts)
+	}
+	return err
+}
+
+// RangeTotals sums the user's daily counters over the days in r.
+func (m *mongoRepository) RangeTotals(ctx context.Context, userID string, r dateRange) (created, completed int32, err error) {
+	filter := bson.M{
+		"user_id": userID,
+		"day": bson.M{
+			"$gte": r.start.UTC().Format(dayFormat),
+			"$lte": r.end.UTC().Format(dayFormat),
+		},
+	}
+
+	cursor, err := m.dailyStats.Find(ctx, filter)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var day struct {
+			Created   int32 `bson:"created"`
+			Completed int32 `bson:"completed"`
+		}
+		if err := cursor.Decode(&day); err != nil {
+			return 0, 0, err
+		}
+		created += day.Created
+		completed += day.Completed
+	}
+	return created, completed, cursor.Err()
+}
+
+// DailyCompletions returns the user's completions on each day in r that has
+// counters, keyed by day; days with none had no activity.
+func (m *mongoRepository) DailyCompletions(ctx context.Context, userID string, r dateRange) (map[string]int32, error) {
+	filter := bson.M{
+		"user_id": userID,
+		"day": bson.M{
+			"$gte": r.start.UTC().Format(dayFormat),
+			"$lte": r.end.UTC().Format(dayFormat),
+		},
+	}
+
+	cursor, err := m.dailyStats.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	completions := make(map[string]int32)
+	for cursor.Next(ctx) {
+		var day struct {
+			Day       string `bson:"day"`
+			Completed int32  `bson:"completed"`
+		}
+		if err := cursor.Decode(&day); err != nil {
+			return nil, err
+		}
+		completions[day.Day] = day.Completed
+	}
+	return completions, cursor.Err()
+}
+
+// OpenTasks returns the user's current number of incomplete tasks.
+func (m *mongoRepository) OpenTasks(ctx context.Context, userID string) (int32, error) {
+	var counters struct {
+		OpenTasks int32 `bson:"open_tasks"`
+	}
+	err := m.userCounters.FindOne(ctx, bson.M{"_id": userID}).Decode(&counters)
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if counters.OpenTasks < 0 {
+		// Events for tasks that predate tracking can push the count below zero
+		// until the backfill has run
+		return 0, nil
+	}
+	return counters.OpenTasks, nil
+}
+
+// rebuildDailyStats recomputes the daily counters from the stored events.
+func (s *server) rebuildDailyStats(ctx context.Context) error {
+	countOf := func(eventType string) bson.M {
+		return bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$event_type", eventType}}, eventWeight, 0}}}
+	}
+
+	pipeline := []bson.M{
+		{"$match": bson.M{"event_type": bson.M{"$in": bson.A{"task.created", "task.completed"}}}},
+		{"$group": bson.M{
+			"_id": bson.M{
+				"tenant_id": tenantKey,
+				"user_id":   "$user_id",
+				"day": bson.M{"$dateToString": bson.M{
+					"format": "%Y-%m-%d",
+					"date":   toDate("$created_at"),
+				}},
+			},
+			"created":   countOf("task.created"),
+			"completed": countOf("task.completed"),
+		}},
+		{"$match": bson.M{"_id.day": bson.M{"$ne": nil}}},
+		{"$project": bson.M{
+			"_id":        bson.M{"$concat": bson.A{"$_id.user_id", ":", "$_id.day"}},
+			tenant.Field: tenantOf("$_id.tenant_id"),
+			"user_id":    "$_id.user_id",
+			"day":        "$_id.day",
+			"created":    1,
+			"completed":  1,
+		}},
+		{"$merge": bson.M{
+			"into":           s.dailyStats.Name(),
+			"whenMatched":    "replace",
+			"whenNotMatched": "insert",
+		}},
+	}
+
+	cursor, err := s.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	return cursor.Close(ctx)
+}
+
+// openCount is a user's number of open tasks and the tenant they belong to.
+type openCount struct {
+	tenantID string
+	count    int32
+}
+
+// resetOpenTasks replaces every user's open task count.
+func (s *server) resetOpenTasks(ctx context.Context, open map[string]openCount) error {
+	if _, err := s.userCounters.UpdateMany(ctx, bson.M{}, bson.M{"$set": bson.M{"open_tasks": 0}}); err != nil {
+		return err
+	}
+	for userID, open := range open {
+		// A backfill across tenants files each count under its user's
+		_, err := s.userCounters.UpdateOne(tenant.WithID(ctx, open.tenantID),
+			bson.M{"_id": userID},
+			bson.M{"$set": bson.M{"open_tasks": open.count}},
+			options.Update().SetUpsert(true))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// counterMove marks a counter document on its way to another user's, so the
+// move can be finished after a failure without counting it twice.
+type counterMove struct {
+	// A random id the target records once it has the counts
+	ID string `bson:"id"`
+	To string `bson:"to"`
+}
+
+// markMove marks the counter document id as moving to toUserID, unless an
+// earlier move marked it already, and returns the move it is marked with.
+// It returns nil when the document is gone, as a finished move leaves it.
+func markMove(ctx context.Context, collection *tenant.Collection, id, toUserID string) (*counterMove, error) {
+	move := counterMove{ID: primitive.NewObjectID().Hex(), To: toUserID}
+	_, err := collection.UpdateOne(ctx,
+		bson.M{"_id": id, "move": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"move": move}})
+	if err != nil {
+		return nil, err
+	}
+	var marked struct {
+		Move *counterMove `bson:"move"`
+	}
+	err = collection.FindOne(ctx, bson.M{"_id": id}).Decode(&marked)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	return marked.Move, err
+}
+
+// applyMove makes update, which adds a moved document's counts, to the
+// counter document targetID unless it has had move's counts already.
+func applyMove(ctx context.Context, collection *tenant.Collection, targetID string, move *counterMove, update bson.M) error {
+	filter := bson.M{"_id": targetID, "moves": bson.M{"$ne": move.ID}}
+	update["$push"] = bson.M{"moves": move.ID}
+	_, err := collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		// The target exists: it has the counts already, which the filter
+		// leaves alone, or another writer has just created it
+		_, err = collection.UpdateOne(ctx, filter, update)
+	}
+	return err
+}
+
+// moveDailyStats adds userID's daily counters to toUserID's and deletes
+// them. Each day is marked with where it is going before it is added there,
+// and the target records the mark, so a move that fails partway is finished
+// by the next, to the same target, rather than counted again, even if the
+// next names another.
+func (m *mongoRepository) moveDailyStats(ctx context.Context, userID, toUserID string) error {
+	cursor, err := m.dailyStats.Find(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return err
+	}
+	var days []struct {
+		ID        string `bson:"_id"`
+		Day       string `bson:"day"`
+		Created   int32  `bson:"created"`
+		Completed int32  `bson:"completed"`
+	}
+	if err := cursor.All(ctx, &days); err != nil {
+		return err
+	}
+	for _, day := range days {
+		move, err := markMove(ctx, m.dailyStats, day.ID, toUserID)
+		if err != nil {
+			return err
+		}
+		if move == nil {
+			continue
+		}
+		err = applyMove(ctx, m.dailyStats, fmt.Sprintf("%s:%s", move.To, day.Day), move, bson.M{
+			"$inc":         bson.M{"created": day.Created, "completed": day.Completed},
+			"$setOnInsert": bson.M{"user_id": move.To, "day": day.Day},
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := m.dailyStats.DeleteOne(ctx, bson.M{"_id": day.ID}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// moveCounters moves userID's counters onto toUserID's, as the task service
+// moves every task of userID when it migrates their ownership. Like
+// moveDailyStats, it is safe to repeat after a failure.
+func (m *mongoRepository) moveCounters(ctx context.Context, userID, toUserID string) error {
+	if userID == "" || userID == toUserID {
+		return nil
+	}
+	if err := m.moveDailyStats(ctx, userID, toUserID); err != nil {
+		return err
+	}
+
+	var counter struct {
+		OpenTasks int32 `bson:"open_tasks"`
+	}
+	err := m.userCounters.FindOne(ctx, bson.M{"_id": userID}).Decode(&counter)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	move, err := markMove(ctx, m.userCounters, userID, toUserID)
+	if err != nil || move == nil {
+		return err
+	}
+	if err := applyMove(ctx, m.userCounters, move.To, move, bson.M{"$inc": bson.M{"open_tasks": counter.OpenTasks}}); err != nil {
+		return err
+	}
+	_, err = m.userCounters.DeleteOne(ctx, bson.M{"_id": userID})
+	return err
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"google.golang.org/grpc/codes"
+)
+
+// maxStatsRange bounds how much history a single stats query may scan.
+const maxStatsRange = 366 * 24 * time.Hour
+
+// dateRange is an inclusive window used to filter stats queries.
+type dateRange struct {
+	start time.Time
+	end   time.Time
+}
+
+// parseDateRange validates the start_date/end_date of a stats request. Both
+// accept RFC3339 timestamps or plain dates in loc; a plain end date covers the
+// whole day. Missing bounds default to the last month.
+func parseDateRange(startDate, endDate string, loc *time.Location) (dateRange, error) {
+	now := time.Now()
+	r := dateRange{start: now.AddDate(0, -1, 0), end: now}
+
+	var err error
+	if startDate != "" {
+		if r.start, err = parseDate(startDate, false, loc); err != nil {
+			return r, statusError(codes.InvalidArgument, "INVALID_START_DATE", map[string]string{"start_date": startDate}, "invalid start_date %q: use RFC3339 or YYYY-MM-DD", startDate)
+		}
+	}
+	if endDate != "" {
+		if r.end, err = parseDate(endDate, true, loc); err != nil {
+			return r, statusError(codes.InvalidArgument, "INVALID_END_DATE", map[string]string{"end_date": endDate}, "invalid end_date %q: use RFC3339 or YYYY-MM-DD", endDate)
+		}
+	}
+
+	if r.start.After(r.end) {
+		return r, statusError(codes.InvalidArgument, "INVALID_DATE_RANGE", nil, "start_date must not be after end_date")
+	}
+	if r.end.Sub(r.start) > maxStatsRange {
+		return r, statusError(codes.InvalidArgument, "DATE_RANGE_TOO_LONG", map[string]string{"max_days": strconv.Itoa(int(maxStatsRange.Hours() / 24))}, "date range must not exceed %d days", int(maxStatsRange.Hours()/24))
+	}
+	return r, nil
+}
+
+// parseTimezone loads the IANA timezone stats are bucketed in. "Local" and
+// the empty name, which time.LoadLocation accepts, are rejected as MongoDB
+// knows neither; requests default a missing timezone to UTC before this.
+func parseTimezone(name string) (*time.Location, error) {
+	if name == "" || name == "Local" {
+		return nil, statusError(codes.InvalidArgument, "INVALID_TIMEZONE", map[string]string{"timezone": name}, "invalid timezone %q: use an IANA name such as UTC", name)
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, statusError(codes.InvalidArgument, "INVALID_TIMEZONE", map[string]string{"timezone": name}, "invalid timezone %q", name)
+	}
+	return loc, nil
+}
+
+func parseDate(value string, endOfDay bool, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, loc)
+	if err != nil {
+		return t, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+	return t, nil
+}
+
+// toDate converts a stored timestamp to a date inside an aggregation
+// expression. Tasks store RFC3339 strings, but real BSON dates are accepted
+// too; values that cannot be converted become null and never match.
+func toDate(field interface{}) bson.M {
+	return bson.M{"$convert": bson.M{
+		"input":   field,
+		"to":      "date",
+		"onError": nil,
+		"onNull":  nil,
+	}}
+}
+
+// contains matches documents whose date expression falls inside the range.
+func (r dateRange) contains(date interface{}) bson.M {
+	return bson.M{"$and": bson.A{
+		bson.M{"$gte": bson.A{date, r.start}},
+		bson.M{"$lte": bson.A{date, r.end}},
+	}}
+}
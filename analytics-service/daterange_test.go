@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+func TestParseTimezone(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{"UTC", false},
+		{"Asia/Dhaka", false},
+		{"America/New_York", false},
+		{"Local", true},
+		{"", true},
+		{"Mars/Olympus", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc, err := parseTimezone(tt.name)
+			if tt.wantErr {
+				if status.Code(err) != codes.InvalidArgument || errorReason(err) != "INVALID_TIMEZONE" {
+					t.Fatalf("err = %v, want INVALID_TIMEZONE", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if loc.String() != tt.name {
+				t.Errorf("location = %q, want %q", loc, tt.name)
+			}
+		})
+	}
+}
+
+func TestLocalTimezoneRejected(t *testing.T) {
+	s := &server{statsService: newTestService(newFakeRepository(), &fakeTaskClient{})}
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{"GetUserStreak", func() error {
+			_, err := s.GetUserStreak(ctx, &pb.GetUserStreakRequest{UserId: "u1", Timezone: "Local"})
+			return err
+		}},
+		{"GetUserStats", func() error {
+			_, err := s.GetUserStats(ctx, &pb.GetUserStatsRequest{UserId: "u1", Timezone: "Local"})
+			return err
+		}},
+		{"GetPeakHours", func() error {
+			_, err := s.GetPeakHours(ctx, &pb.GetPeakHoursRequest{UserId: "u1", Timezone: "Local"})
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := status.Code(tt.call()); code != codes.InvalidArgument {
+				t.Errorf("code = %v, want InvalidArgument", code)
+			}
+		})
+	}
+}
+
+func TestEventsIn(t *testing.T) {
+	dhaka, _ := time.LoadLocation("Asia/Dhaka")
+	tests := []struct {
+		name    string
+		r       dateRange
+		inside  []time.Time
+		outside []time.Time
+	}{
+		{
+			name:    "utc day",
+			r:       dateRange{start: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), end: time.Date(2026, 3, 1, 23, 59, 59, 999999999, time.UTC)},
+			inside:  []time.Time{time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 1, 23, 59, 59, 0, time.UTC)},
+			outside: []time.Time{time.Date(2026, 2, 28, 23, 59, 59, 0, time.UTC), time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:    "bounds in another zone",
+			r:       dateRange{start: time.Date(2026, 3, 1, 0, 0, 0, 0, dhaka), end: time.Date(2026, 3, 1, 12, 0, 0, 0, dhaka)},
+			inside:  []time.Time{time.Date(2026, 2, 28, 18, 0, 0, 0, time.UTC), time.Date(2026, 3, 1, 6, 0, 0, 0, time.UTC)},
+			outside: []time.Time{time.Date(2026, 2, 28, 17, 59, 59, 0, time.UTC), time.Date(2026, 3, 1, 6, 0, 1, 0, time.UTC)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match := eventsIn(tt.r)
+			if len(match) != 1 {
+				t.Fatalf("match = %v, want only a created_at range an index can serve", match)
+			}
+			bounds := match["created_at"].(bson.M)
+			in := func(at time.Time) bool {
+				// created_at as eventFromRequest writes it
+				createdAt := at.In(time.Local).Format(time.RFC3339)
+				return createdAt >= bounds["$gte"].(string) && createdAt <= bounds["$lte"].(string)
+			}
+			for _, at := range tt.inside {
+				if !in(at) {
+					t.Errorf("%v is outside %v", at, bounds)
+				}
+			}
+			for _, at := range tt.outside {
+				if in(at) {
+					t.Errorf("%v is inside %v", at, bounds)
+				}
+			}
+		})
+	}
+}
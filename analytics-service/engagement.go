@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"google.golang.org/grpc/codes"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+const (
+	engagementWindowDays = 30
+	// Computed scores are kept this long, up to engagementCacheSize users,
+	// since each one costs calls to the user and notification services
+	engagementCacheTTL  = 10 * time.Minute
+	engagementCacheSize = 10000
+	// Creating this many tasks a day on average counts as full task activity
+	targetTasksPerDay = 1.0
+)
+
+// Weights of the engagement components; they sum to 1.
+const (
+	taskActivityWeight           = 0.4
+	notificationEngagementWeight = 0.3
+	sessionFrequencyWeight       = 0.3
+)
+
+// GetEngagementScore rates how actively the user uses the app over the last
+// 30 days, from tasks created per day, the share of notifications they opened
+// and how many days they logged in.
+func (s *server) GetEngagementScore(ctx context.Context, req *pb.GetEngagementScoreRequest) (*pb.GetEngagementScoreResponse, error) {
+	if req.UserId == "" {
+		return nil, statusError(codes.InvalidArgument, "USER_ID_REQUIRED", nil, "user_id is required")
+	}
+
+	now := time.Now()
+	if resp, ok := s.engagement.get(req.UserId, now); ok {
+		return resp.(*pb.GetEngagementScoreResponse), nil
+	}
+
+	window := dateRange{start: now.AddDate(0, 0, -engagementWindowDays), end: now}
+	created, _, err := s.stats.RangeTotals(ctx, req.UserId, window)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the totals are needed, so fetch a single notification each
+	all, err := s.notifications.GetNotifications(ctx, &pb.GetNotificationsRequest{UserId: req.UserId, Limit: 1})
+	if err != nil {
+		return nil, err
+	}
+	unread, err := s.notifications.GetNotifications(ctx, &pb.GetNotificationsRequest{UserId: req.UserId, UnreadOnly: true, Limit: 1})
+	if err != nil {
+		return nil, err
+	}
+
+	activity, err := s.users.GetUserActivityStats(ctx, &pb.GetUserActivityStatsRequest{UserId: req.UserId, Days: engagementWindowDays})
+	if err != nil {
+		return nil, err
+	}
+
+	breakdown := &pb.EngagementBreakdown{
+		TaskActivity:     clampUnit(float64(created) / engagementWindowDays / targetTasksPerDay),
+		SessionFrequency: clampUnit(float64(activity.ActiveDays) / engagementWindowDays),
+	}
+	if all.Total > 0 {
+		breakdown.NotificationEngagement = clampUnit(float64(all.Total-unread.Total) / float64(all.Total))
+	}
+
+	score := taskActivityWeight*breakdown.TaskActivity +
+		notificationEngagementWeight*breakdown.NotificationEngagement +
+		sessionFrequencyWeight*breakdown.SessionFrequency
+
+	resp := &pb.GetEngagementScoreResponse{
+		Score:     math.Round(score*1000) / 10,
+		Breakdown: breakdown,
+	}
+	s.engagement.put(req.UserId, req.UserId, resp, now)
+	return resp, nil
+}
+
+func clampUnit(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/grpc/codes"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// DeleteUserEvents erases a user from analytics: their events and everything
+// derived from them for that user alone, such as counters, snapshots,
+// streaks and weekly summaries. Global rollups keep their totals, which no
+// longer identify anyone. Events already exported by the retention job stay
+// in the archive files.
+func (s *server) DeleteUserEvents(ctx context.Context, req *pb.DeleteUserEventsRequest) (*pb.DeleteUserEventsResponse, error) {
+	if req.UserId == "" {
+		return nil, statusError(codes.InvalidArgument, "USER_ID_REQUIRED", nil, "user_id is required")
+	}
+
+	deleted, err := s.events.DeleteUserEvents(ctx, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.stats.DeleteUserStats(ctx, req.UserId); err != nil {
+		return nil, err
+	}
+	if _, err := s.weeklySummaries.DeleteMany(ctx, bson.M{"user_id": req.UserId}); err != nil {
+		return nil, err
+	}
+	if err := s.limits.windows.deleteUser(ctx, req.UserId); err != nil {
+		return nil, err
+	}
+	return &pb.DeleteUserEventsResponse{DeletedCount: int32(deleted)}, nil
+}
+
+func (m *mongoRepository) DeleteUserEvents(ctx context.Context, userID string) (int64, error) {
+	result, err := m.events.DeleteMany(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+func (m *mongoRepository) DeleteUserStats(ctx context.Context, userID string) error {
+	if _, err := m.dailyStats.DeleteMany(ctx, bson.M{"user_id": userID}); err != nil {
+		return err
+	}
+	if _, err := m.userCounters.DeleteOne(ctx, bson.M{"_id": userID}); err != nil {
+		return err
+	}
+	if _, err := m.snapshots.DeleteMany(ctx, bson.M{"user_id": userID}); err != nil {
+		return err
+	}
+	_, err := m.streaks.DeleteMany(ctx, bson.M{"user_id": userID})
+	return err
+}
+
+// anonymizeBatchSize bounds the events rewritten by one update, so
+// anonymizing a prolific user never holds a long write.
+const anonymizeBatchSize = 500
+
+// anonymousMetadata are the metadata fields kept on anonymized events. None
+// identify anyone; labels and fields sent by clients could.
+var anonymousMetadata = bson.A{"completed", "on_time", "priority", "source"}
+
+// newPseudonym returns an identifier for userID's anonymized data. It is
+// derived with a random salt that is never stored, so it cannot be traced
+// back to the user, nor recomputed to link data anonymized later.
+func newPseudonym(userID string) (string, error) {
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, salt)
+	mac.Write([]byte(userID))
+	return "anon-" + hex.EncodeToString(mac.Sum(nil))[:32], nil
+}
+
+// AnonymizeUserEvents erases a user from analytics like DeleteUserEvents, but
+// keeps their events and daily counters under a pseudonym, so global stats
+// keep their history. Running it again finds nothing left to anonymize; a run
+// that fails partway leaves the rest to be anonymized under another
+// pseudonym, which only counts matter for.
+func (s *server) AnonymizeUserEvents(ctx context.Context, req *pb.AnonymizeUserEventsRequest) (*pb.AnonymizeUserEventsResponse, error) {
+	if req.UserId == "" {
+		return nil, statusError(codes.InvalidArgument, "USER_ID_REQUIRED", nil, "user_id is required")
+	}
+	pseudonym, err := newPseudonym(req.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	anonymized, err := s.events.AnonymizeUserEvents(ctx, req.UserId, pseudonym)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.stats.AnonymizeUserStats(ctx, req.UserId, pseudonym); err != nil {
+		return nil, err
+	}
+	if _, err := s.weeklySummaries.DeleteMany(ctx, bson.M{"user_id": req.UserId}); err != nil {
+		return nil, err
+	}
+	if err := s.limits.windows.deleteUser(ctx, req.UserId); err != nil {
+		return nil, err
+	}
+	return &pb.AnonymizeUserEventsResponse{AnonymizedCount: int32(anonymized)}, nil
+}
+
+func (m *mongoRepository) AnonymizeUserEvents(ctx context.Context, userID, pseudonym string) (int64, error) {
+	// Client event ids are dropped with the metadata: clients choose them
+	update := bson.A{
+		bson.M{"$set": bson.M{
+			"user_id": pseudonym,
+			"metadata": bson.M{"$cond": bson.A{
+				bson.M{"$eq": bson.A{bson.M{"$type": "$metadata"}, "object"}},
+				bson.M{"$arrayToObject": bson.M{"$filter": bson.M{
+					"input": bson.M{"$objectToArray": "$metadata"},
+					"cond":  bson.M{"$in": bson.A{"$$this.k", anonymousMetadata}},
+				}}},
+				"$$REMOVE",
+			}},
+		}},
+		bson.M{"$unset": "client_event_id"},
+	}
+
+	var anonymized int64
+	for {
+		cursor, err := m.events.Find(ctx, bson.M{"user_id": userID},
+			options.Find().SetProjection(bson.M{"_id": 1}).SetLimit(anonymizeBatchSize))
+		if err != nil {
+			return anonymized, err
+		}
+		var batch []struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		if err := cursor.All(ctx, &batch); err != nil {
+			return anonymized, err
+		}
+		if len(batch) == 0 {
+			return anonymized, nil
+		}
+		ids := make([]primitive.ObjectID, len(batch))
+		for i, event := range batch {
+			ids[i] = event.ID
+		}
+		result, err := m.events.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": ids}, "user_id": userID}, update)
+		if err != nil {
+			return anonymized, err
+		}
+		anonymized += result.ModifiedCount
+	}
+}
+
+// AnonymizeUserStats moves the user's daily counters to pseudonym, which the
+// global stats sum, and deletes the aggregates kept for the user alone. Days
+// a failed run had started moving are finished under that run's pseudonym.
+func (m *mongoRepository) AnonymizeUserStats(ctx context.Context, userID, pseudonym string) error {
+	if err := m.moveDailyStats(ctx, userID, pseudonym); err != nil {
+		return err
+	}
+	return m.DeleteUserStats(ctx, userID)
+}
@@ -0,0 +1,182 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+	"github.com/technonext/todo-app/proto/tenant"
+)
+
+func TestMongoAnonymizeUserEvents(t *testing.T) {
+	stats := newMongoService(t, &fakeTaskClient{})
+	repo := stats.stats.(*mongoRepository)
+	db := repo.events.Database()
+	s := &server{statsService: stats, weeklySummaries: tenant.Scoped(db.Collection("weekly_summaries"))}
+	ctx := context.Background()
+
+	at3, _ := daysAgo(3)
+	at1, _ := daysAgo(1)
+	metadata, err := structpb.NewStruct(map[string]interface{}{"priority": "HIGH", "labels": []interface{}{"alice-private"}, "completed": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	track(t, stats, &pb.TrackEventRequest{UserId: "alice", EventType: "task.created", ResourceId: "a", ClientTimestamp: at3, ClientEventId: "alice-phone-1"})
+	track(t, stats, &pb.TrackEventRequest{UserId: "alice", EventType: "task.created", ResourceId: "b", ClientTimestamp: at1})
+	track(t, stats, &pb.TrackEventRequest{UserId: "alice", EventType: "task.completed", ResourceId: "b", ClientTimestamp: at1, Metadata: metadata})
+	track(t, stats, &pb.TrackEventRequest{UserId: "bob", EventType: "task.created", ResourceId: "c", ClientTimestamp: at1})
+
+	// Days before the lookback are served from the rollup, later ones live
+	jobs := &server{dailyStats: repo.dailyStats, statsDaily: repo.statsDaily, jobs: repo.jobs}
+	if err := jobs.rollupStats(ctx, true); err != nil {
+		t.Fatal(err)
+	}
+	r := dateRange{start: time.Now().AddDate(0, 0, -7), end: time.Now()}
+	before, err := repo.GlobalTotals(ctx, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := s.AnonymizeUserEvents(ctx, &pb.AnonymizeUserEventsRequest{UserId: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.AnonymizedCount != 3 {
+		t.Errorf("anonymized %d events, want 3", resp.AnonymizedCount)
+	}
+
+	if n, err := repo.events.CountDocuments(ctx, bson.M{"user_id": "alice"}); err != nil || n != 0 {
+		t.Errorf("%d events of alice left (%v), want none", n, err)
+	}
+	if n, err := repo.dailyStats.CountDocuments(ctx, bson.M{"user_id": "alice"}); err != nil || n != 0 {
+		t.Errorf("%d daily counters of alice left (%v), want none", n, err)
+	}
+	if n, _ := repo.userCounters.CountDocuments(ctx, bson.M{"_id": "alice"}); n != 0 {
+		t.Error("alice's open task counter is left")
+	}
+
+	var events []Event
+	cursor, err := repo.events.Find(ctx, bson.M{"user_id": bson.M{"$ne": "bob"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cursor.All(ctx, &events); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("%d anonymized events kept, want 3", len(events))
+	}
+	for _, e := range events {
+		if e.UserID != events[0].UserID || !strings.HasPrefix(e.UserID, "anon-") {
+			t.Errorf("event %s belongs to %q, want one pseudonym for all", e.ID.Hex(), e.UserID)
+		}
+		if e.ClientEventID != "" || e.Metadata["labels"] != nil {
+			t.Errorf("event %s keeps %q and %v", e.ID.Hex(), e.ClientEventID, e.Metadata)
+		}
+		if e.EventType == "task.completed" && (e.Metadata["priority"] != "HIGH" || e.Metadata["completed"] != true) {
+			t.Errorf("completion metadata = %v, want priority and completed kept", e.Metadata)
+		}
+	}
+
+	assertTotals := func(when string) {
+		t.Helper()
+		after, err := repo.GlobalTotals(ctx, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if after != before {
+			t.Errorf("%s: global totals = %+v, want %+v as before", when, after, before)
+		}
+	}
+	assertTotals("after anonymizing")
+	if err := jobs.rollupStats(ctx, true); err != nil {
+		t.Fatal(err)
+	}
+	assertTotals("rolled up again")
+
+	again, err := s.AnonymizeUserEvents(ctx, &pb.AnonymizeUserEventsRequest{UserId: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.AnonymizedCount != 0 {
+		t.Errorf("re-run anonymized %d events, want none", again.AnonymizedCount)
+	}
+	assertTotals("re-run")
+}
+
+// A run that fails after adding a day to the pseudonym's counters, but
+// before removing it from the user's, is finished by the next run rather
+// than counted twice, though the next run picks another pseudonym.
+func TestMongoAnonymizeUserStatsRetry(t *testing.T) {
+	stats := newMongoService(t, &fakeTaskClient{})
+	repo := stats.stats.(*mongoRepository)
+	ctx := context.Background()
+
+	at3, _ := daysAgo(3)
+	at1, _ := daysAgo(1)
+	track(t, stats, &pb.TrackEventRequest{UserId: "alice", EventType: "task.created", ResourceId: "a", ClientTimestamp: at3})
+	track(t, stats, &pb.TrackEventRequest{UserId: "alice", EventType: "task.created", ResourceId: "b", ClientTimestamp: at1})
+	track(t, stats, &pb.TrackEventRequest{UserId: "alice", EventType: "task.completed", ResourceId: "b", ClientTimestamp: at1})
+	track(t, stats, &pb.TrackEventRequest{UserId: "bob", EventType: "task.created", ResourceId: "c", ClientTimestamp: at1})
+	r := dateRange{start: time.Now().AddDate(0, 0, -7), end: time.Now()}
+	before, err := repo.GlobalTotals(ctx, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first run fails between the two writes of one day
+	var first struct {
+		ID        string `bson:"_id"`
+		Day       string `bson:"day"`
+		Created   int32  `bson:"created"`
+		Completed int32  `bson:"completed"`
+	}
+	if err := repo.dailyStats.FindOne(ctx, bson.M{"user_id": "alice"}).Decode(&first); err != nil {
+		t.Fatal(err)
+	}
+	move, err := markMove(ctx, repo.dailyStats, first.ID, "anon-first")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = applyMove(ctx, repo.dailyStats, "anon-first:"+first.Day, move, bson.M{
+		"$inc":         bson.M{"created": first.Created, "completed": first.Completed},
+		"$setOnInsert": bson.M{"user_id": "anon-first", "day": first.Day},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, pseudonym := range []string{"anon-second", "anon-third"} {
+		if err := repo.AnonymizeUserStats(ctx, "alice", pseudonym); err != nil {
+			t.Fatal(err)
+		}
+		after, err := repo.GlobalTotals(ctx, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if after != before {
+			t.Errorf("after anonymizing as %s: global totals = %+v, want %+v as before", pseudonym, after, before)
+		}
+	}
+	if n, err := repo.dailyStats.CountDocuments(ctx, bson.M{"user_id": "alice"}); err != nil || n != 0 {
+		t.Errorf("%d daily counters of alice left (%v), want none", n, err)
+	}
+	var moved struct {
+		Created   int32 `bson:"created"`
+		Completed int32 `bson:"completed"`
+	}
+	if err := repo.dailyStats.FindOne(ctx, bson.M{"_id": "anon-first:" + first.Day}).Decode(&moved); err != nil {
+		t.Fatal(err)
+	}
+	if moved.Created != first.Created || moved.Completed != first.Completed {
+		t.Errorf("the day the failed run started moving has %d created, %d completed; want %d and %d",
+			moved.Created, moved.Completed, first.Created, first.Completed)
+	}
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPseudonymsCannotBeLinked(t *testing.T) {
+	first, err := newPseudonym("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := newPseudonym("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second {
+		t.Errorf("both runs pseudonymize alice as %s", first)
+	}
+	if strings.Contains(first, "alice") || !strings.HasPrefix(first, "anon-") {
+		t.Errorf("pseudonym = %s", first)
+	}
+}
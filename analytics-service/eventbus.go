@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+	"github.com/technonext/todo-app/proto/tenant"
+)
+
+// EventBus hands the events this instance tracks to its live subscribers.
+// Unlike StreamEvents it needs no replica set, but a subscriber only hears
+// about events tracked by the instance it is connected to.
+//
+// Subscribers are kept per tenant and user, as a slice that is replaced
+// rather than changed, so publishing never waits on a lock.
+type EventBus struct {
+	subscribers sync.Map // busKey -> *[]*subscription
+}
+
+type busKey struct {
+	tenant, user string
+}
+
+// subscription receives the events of its key with one of types, or of any
+// type when types is empty. Events are dropped once events is full, so a
+// slow subscriber never holds up tracking.
+type subscription struct {
+	types  []string
+	events chan *pb.Event
+}
+
+func newEventBus() *EventBus {
+	return &EventBus{}
+}
+
+func busKeyFor(ctx context.Context, userID string) busKey {
+	id, _ := tenant.Scope(ctx)
+	return busKey{tenant: id, user: userID}
+}
+
+// subscribe registers a subscription to the events of userID in ctx's
+// tenant. Call the returned func to remove it.
+func (b *EventBus) subscribe(ctx context.Context, userID string, types []string) (*subscription, func()) {
+	key := busKeyFor(ctx, userID)
+	sub := &subscription{types: types, events: make(chan *pb.Event, streamBufferSize)}
+	b.update(key, func(subs []*subscription) []*subscription {
+		return append(slices.Clip(subs), sub)
+	})
+	return sub, func() {
+		b.update(key, func(subs []*subscription) []*subscription {
+			return slices.DeleteFunc(slices.Clone(subs), func(s *subscription) bool { return s == sub })
+		})
+	}
+}
+
+// update replaces the subscriptions of key with change's result, dropping
+// the key once none are left.
+func (b *EventBus) update(key busKey, change func([]*subscription) []*subscription) {
+	for {
+		old, loaded := b.subscribers.Load(key)
+		var subs []*subscription
+		if loaded {
+			subs = *old.(*[]*subscription)
+		}
+		next := change(subs)
+		switch {
+		case !loaded && len(next) == 0:
+			return
+		case !loaded:
+			if _, raced := b.subscribers.LoadOrStore(key, &next); !raced {
+				return
+			}
+		case len(next) == 0:
+			if b.subscribers.CompareAndDelete(key, old) {
+				return
+			}
+		default:
+			if b.subscribers.CompareAndSwap(key, old, &next) {
+				return
+			}
+		}
+	}
+}
+
+// publish hands events, tracked in ctx's tenant, to their subscribers. A
+// nil bus has none.
+func (b *EventBus) publish(ctx context.Context, events ...Event) {
+	if b == nil {
+		return
+	}
+	for _, event := range events {
+		subs, ok := b.subscribers.Load(busKeyFor(ctx, event.UserID))
+		if !ok {
+			continue
+		}
+		var msg *pb.Event
+		for _, sub := range *subs.(*[]*subscription) {
+			if len(sub.types) > 0 && !slices.Contains(sub.types, event.EventType) {
+				continue
+			}
+			if msg == nil {
+				msg = event.toProto()
+			}
+			select {
+			case sub.events <- msg:
+			default:
+			}
+		}
+	}
+}
+
+// SubscribeToEvents sends the events of a user as this instance tracks them,
+// until the client disconnects. Headers are sent once the subscription is
+// in place, so an event tracked after they arrive is never missed.
+func (s *server) SubscribeToEvents(req *pb.SubscribeRequest, stream pb.AnalyticsService_SubscribeToEventsServer) error {
+	if req.UserId == "" {
+		return statusError(codes.InvalidArgument, "USER_ID_REQUIRED", nil, "user_id is required")
+	}
+	ctx := stream.Context()
+	sub, unsubscribe := s.bus.subscribe(ctx, req.UserId, req.EventTypes)
+	defer unsubscribe()
+
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event := <-sub.events:
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// fakeSubscribeStream passes on the events of a subscription, and closes
+// subscribed once headers are sent.
+type fakeSubscribeStream struct {
+	grpc.ServerStream
+	ctx        context.Context
+	subscribed chan struct{}
+	events     chan *pb.Event
+}
+
+func (f *fakeSubscribeStream) Context() context.Context { return f.ctx }
+
+func (f *fakeSubscribeStream) SendHeader(metadata.MD) error {
+	close(f.subscribed)
+	return nil
+}
+
+func (f *fakeSubscribeStream) Send(event *pb.Event) error {
+	f.events <- event
+	return nil
+}
+
+func TestSubscribeToEvents(t *testing.T) {
+	s := &server{statsService: newTestService(newFakeRepository(), &fakeTaskClient{})}
+	s.bus = newEventBus()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream := &fakeSubscribeStream{ctx: ctx, subscribed: make(chan struct{}), events: make(chan *pb.Event, 10)}
+	done := make(chan error, 1)
+	go func() {
+		done <- s.SubscribeToEvents(&pb.SubscribeRequest{UserId: "u1", EventTypes: []string{"task.completed"}}, stream)
+	}()
+	<-stream.subscribed
+
+	// Neither another type nor another user's event reaches the subscriber
+	track(t, s.statsService, &pb.TrackEventRequest{UserId: "u1", EventType: "task.created", ResourceId: "t1"})
+	track(t, s.statsService, &pb.TrackEventRequest{UserId: "u2", EventType: "task.completed", ResourceId: "t2"})
+	track(t, s.statsService, &pb.TrackEventRequest{UserId: "u1", EventType: "task.completed", ResourceId: "t1"})
+
+	select {
+	case event := <-stream.events:
+		if event.UserId != "u1" || event.EventType != "task.completed" || event.Id == "" {
+			t.Errorf("received %+v, want u1's task.completed", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("tracked event never arrived")
+	}
+	if len(stream.events) > 0 {
+		t.Errorf("received %+v, which does not match the subscription", <-stream.events)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("SubscribeToEvents() = %v after disconnecting", err)
+	}
+	s.bus.subscribers.Range(func(key, _ interface{}) bool {
+		t.Errorf("subscription of %v left behind after disconnecting", key)
+		return true
+	})
+}
+
+func TestSubscribeToEventsRequiresUser(t *testing.T) {
+	s := &server{statsService: &statsService{bus: newEventBus()}}
+	err := s.SubscribeToEvents(&pb.SubscribeRequest{}, nil)
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("code = %v, want InvalidArgument", status.Code(err))
+	}
+}
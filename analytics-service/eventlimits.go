@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/grpc/codes"
+)
+
+// Event types can be rate limited per user and sampled, so one noisy client
+// cannot flood the events collection. Controls are read from
+// ANALYTICS_EVENT_CONTROLS (comma-separated) and the file named by
+// ANALYTICS_EVENT_CONTROLS_FILE (one per line, # starts a comment), which is
+// reloaded when it changes and overrides the env var per event type:
+//
+//	app_opened limit=100/1h sample=10
+//
+// limits user to 100 app_opened events an hour and stores 1 in 10 of those.
+// A stored sampled event records its sample rate, and stats weigh it by that.
+// Task lifecycle events cannot be sampled: counters and latency pair each
+// completion with its task's creation, which sampling would break.
+
+// errSampledOut marks an event left unstored by sampling.
+var errSampledOut = errors.New("event sampled out")
+
+// eventWeight is the number of tracked events a stored event stands for.
+var eventWeight = bson.M{"$ifNull": bson.A{"$sample_rate", 1}}
+
+func (e Event) weight() int {
+	if e.SampleRate > 1 {
+		return int(e.SampleRate)
+	}
+	return 1
+}
+
+type eventControl struct {
+	// Events per user per window, unlimited when 0
+	limit  int
+	window time.Duration
+	// Store 1 in sampleRate events, all of them when 0 or 1
+	sampleRate int32
+}
+
+type eventControls map[string]eventControl
+
+// rateWindows stores the per-user rate limit windows.
+type rateWindows interface {
+	// add adds n events to the window with id, kept until expires, and
+	// returns the window's total.
+	add(ctx context.Context, id string, expires time.Time, n int) (int, error)
+	// deleteUser deletes all of the user's windows.
+	deleteUser(ctx context.Context, userID string) error
+}
+
+// eventLimiter enforces the current controls. Rate limit windows live in
+// MongoDB so the limits hold across service replicas.
+type eventLimiter struct {
+	controls atomic.Pointer[eventControls]
+	windows  rateWindows
+	// Controls from the env var, which the file is layered over
+	base eventControls
+	path string
+	// Last file contents loaded, to skip reloading an unchanged file
+	loaded []byte
+}
+
+// loadEventLimiter reads the controls, checking their event types against
+// registry.
+func loadEventLimiter(windows rateWindows, registry eventTypeRegistry) (*eventLimiter, error) {
+	base := make(eventControls)
+	for _, spec := range strings.Split(os.Getenv("ANALYTICS_EVENT_CONTROLS"), ",") {
+		if err := base.parse(spec, registry); err != nil {
+			return nil, fmt.Errorf("invalid ANALYTICS_EVENT_CONTROLS: %w", err)
+		}
+	}
+
+	l := &eventLimiter{windows: windows, base: base, path: os.Getenv("ANALYTICS_EVENT_CONTROLS_FILE")}
+	if _, err := l.reload(registry); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (c eventControls) parse(spec string, registry eventTypeRegistry) error {
+	fields := strings.Fields(spec)
+	if len(fields) == 0 {
+		return nil
+	}
+	eventType := fields[0]
+	if err := registry.validate(eventType); err != nil {
+		return fmt.Errorf("unknown event type %q", eventType)
+	}
+
+	lifecycle := false
+	for _, t := range builtinEventTypes {
+		lifecycle = lifecycle || t == eventType
+	}
+
+	var control eventControl
+	for _, field := range fields[1:] {
+		key, value, _ := strings.Cut(field, "=")
+		switch key {
+		case "limit":
+			count, window, ok := strings.Cut(value, "/")
+			n, err := strconv.Atoi(count)
+			if !ok || err != nil || n <= 0 {
+				return fmt.Errorf("%s: limit must look like 100/1h", eventType)
+			}
+			d, err := time.ParseDuration(window)
+			if err != nil || d < time.Second {
+				return fmt.Errorf("%s: invalid limit window %q", eventType, window)
+			}
+			control.limit, control.window = n, d
+		case "sample":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return fmt.Errorf("%s: sample must be a positive integer", eventType)
+			}
+			if lifecycle && n > 1 {
+				return fmt.Errorf("%s: task lifecycle events cannot be sampled", eventType)
+			}
+			control.sampleRate = int32(n)
+		default:
+			return fmt.Errorf("%s: unknown setting %q", eventType, field)
+		}
+	}
+	c[eventType] = control
+	return nil
+}
+
+// reload rereads the controls file, reporting whether it changed. The
+// controls in force are only replaced by a file that parses.
+func (l *eventLimiter) reload(registry eventTypeRegistry) (bool, error) {
+	var data []byte
+	if l.path != "" {
+		var err error
+		if data, err = os.ReadFile(l.path); err != nil {
+			return false, err
+		}
+	}
+	if l.controls.Load() != nil && bytes.Equal(data, l.loaded) {
+		return false, nil
+	}
+
+	controls := make(eventControls, len(l.base))
+	for eventType, control := range l.base {
+		controls[eventType] = control
+	}
+	for i, line := range strings.Split(string(data), "\n") {
+		line, _, _ = strings.Cut(line, "#")
+		if err := controls.parse(line, registry); err != nil {
+			return false, fmt.Errorf("%s:%d: %w", l.path, i+1, err)
+		}
+	}
+
+	l.controls.Store(&controls)
+	l.loaded = data
+	return true, nil
+}
+
+// startReloading checks the controls file for changes every interval until
+// ctx ends.
+func (l *eventLimiter) startReloading(ctx context.Context, interval time.Duration, registry eventTypeRegistry) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		changed, err := l.reload(registry)
+		if err != nil {
+			log.Printf("Failed to reload event controls, keeping the current ones: %v", err)
+		} else if changed {
+			log.Printf("Reloaded event controls from %s", l.path)
+		}
+	}
+}
+
+// admit applies the rate limits, then sampling, to events in order, setting
+// the sample rate of the sampled events it keeps. For each event it returns
+// nil to store it, errSampledOut, or a ResourceExhausted error.
+func (l *eventLimiter) admit(ctx context.Context, events []Event) ([]error, error) {
+	controls := *l.controls.Load()
+	results := make([]error, len(events))
+
+	// Events of one user and type share a counter, bumped once per call
+	type counterKey struct{ userID, eventType string }
+	groups := make(map[counterKey][]int)
+	for i, event := range events {
+		if controls[event.EventType].limit > 0 {
+			key := counterKey{event.UserID, event.EventType}
+			groups[key] = append(groups[key], i)
+		}
+	}
+	for key, positions := range groups {
+		control := controls[key.eventType]
+		count, err := l.count(ctx, key.userID, key.eventType, control.window, len(positions))
+		if err != nil {
+			return nil, err
+		}
+		// count includes this call's events, which come last in the window
+		allowed := control.limit - (count - len(positions))
+		for i, pos := range positions {
+			if i >= allowed {
+				results[pos] = statusError(codes.ResourceExhausted, "EVENT_RATE_LIMITED",
+					map[string]string{"user_id": key.userID, "event_type": key.eventType, "limit": strconv.Itoa(control.limit), "window": control.window.String()},
+					"rate limit of %d %s events per %s exceeded for user %s", control.limit, key.eventType, control.window, key.userID)
+			}
+		}
+	}
+
+	for i := range events {
+		rate := controls[events[i].EventType].sampleRate
+		if results[i] != nil || rate <= 1 {
+			continue
+		}
+		if rand.Int31n(rate) != 0 {
+			results[i] = errSampledOut
+			continue
+		}
+		events[i].SampleRate = rate
+	}
+	return results, nil
+}
+
+// count adds n events to the user's fixed window for the event type and
+// returns the window's total.
+func (l *eventLimiter) count(ctx context.Context, userID, eventType string, window time.Duration, n int) (int, error) {
+	start := time.Now().UTC().Truncate(window)
+	id := fmt.Sprintf("%s:%s:%d:%d", userID, eventType, int64(window.Seconds()), start.Unix())
+	return l.windows.add(ctx, id, start.Add(window+time.Minute), n)
+}
+
+// mongoRateWindows keeps each window as a document whose _id starts with the
+// user id, removed by a TTL index once it expires.
+type mongoRateWindows struct {
+	collection *mongo.Collection
+}
+
+func (m mongoRateWindows) add(ctx context.Context, id string, expires time.Time, n int) (int, error) {
+	filter := bson.M{"_id": id}
+	update := bson.M{
+		"$inc":         bson.M{"count": n},
+		"$setOnInsert": bson.M{"expires_at": expires},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var counter struct {
+		Count int `bson:"count"`
+	}
+	err := m.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&counter)
+	if mongo.IsDuplicateKeyError(err) {
+		// A concurrent upsert created the window first; the retry will match it
+		err = m.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&counter)
+	}
+	return counter.Count, err
+}
+
+func (m mongoRateWindows) deleteUser(ctx context.Context, userID string) error {
+	windows := primitive.Regex{Pattern: "^" + regexp.QuoteMeta(userID+":")}
+	_, err := m.collection.DeleteMany(ctx, bson.M{"_id": windows})
+	return err
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func testRegistry() eventTypeRegistry {
+	registry := make(eventTypeRegistry)
+	for _, t := range builtinEventTypes {
+		registry[t] = true
+	}
+	registry.add("app_opened")
+	return registry
+}
+
+func TestEventControlsParse(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    eventControl
+		wantErr string
+	}{
+		{"", eventControl{}, ""},
+		{"app_opened limit=100/1h sample=10", eventControl{limit: 100, window: time.Hour, sampleRate: 10}, ""},
+		{"task.created limit=50/1m", eventControl{limit: 50, window: time.Minute}, ""},
+		{"task.completed sample=1", eventControl{sampleRate: 1}, ""},
+		{"task.completed sample=10", eventControl{}, "cannot be sampled"},
+		{"task.created sample=2", eventControl{}, "cannot be sampled"},
+		{"app_closed limit=1/1h", eventControl{}, "unknown event type"},
+		{"app_opened limit=100", eventControl{}, "limit must look like"},
+		{"app_opened limit=100/1ms", eventControl{}, "invalid limit window"},
+		{"app_opened sample=0", eventControl{}, "positive integer"},
+		{"app_opened burst=5", eventControl{}, "unknown setting"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			controls := make(eventControls)
+			err := controls.parse(tt.spec, testRegistry())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fields := strings.Fields(tt.spec); len(fields) > 0 && controls[fields[0]] != tt.want {
+				t.Errorf("control = %+v, want %+v", controls[fields[0]], tt.want)
+			}
+		})
+	}
+}
+
+func newTestLimiter(t *testing.T, controls string) *eventLimiter {
+	t.Helper()
+	t.Setenv("ANALYTICS_EVENT_CONTROLS", controls)
+	t.Setenv("ANALYTICS_EVENT_CONTROLS_FILE", "")
+	l, err := loadEventLimiter(&memoryWindows{}, testRegistry())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func TestAdmitRateLimit(t *testing.T) {
+	l := newTestLimiter(t, "app_opened limit=3/1h")
+	batch := func(userID string, n int) []Event {
+		events := make([]Event, n)
+		for i := range events {
+			events[i] = Event{UserID: userID, EventType: "app_opened"}
+		}
+		return events
+	}
+	limited := func(results []error) int {
+		n := 0
+		for _, err := range results {
+			if status.Code(err) == codes.ResourceExhausted {
+				n++
+			}
+		}
+		return n
+	}
+
+	tests := []struct {
+		name        string
+		events      []Event
+		wantLimited int
+	}{
+		{"within the limit", batch("u1", 2), 0},
+		{"crosses the limit", batch("u1", 3), 2},
+		{"over the limit", batch("u1", 1), 1},
+		{"other user has their own window", batch("u2", 3), 0},
+		{"unlimited type", []Event{{UserID: "u1", EventType: "task.created"}}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := l.admit(context.Background(), tt.events)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := limited(results); got != tt.wantLimited {
+				t.Errorf("limited %d events, want %d", got, tt.wantLimited)
+			}
+		})
+	}
+
+	if err := l.windows.deleteUser(context.Background(), "u1"); err != nil {
+		t.Fatal(err)
+	}
+	results, _ := l.admit(context.Background(), batch("u1", 1))
+	if results[0] != nil {
+		t.Errorf("after deleting the user's windows: %v", results[0])
+	}
+}
+
+func TestAdmitSampling(t *testing.T) {
+	l := newTestLimiter(t, "app_opened sample=10")
+	events := make([]Event, 2000)
+	for i := range events {
+		events[i] = Event{UserID: "u1", EventType: "app_opened"}
+	}
+	results, err := l.admit(context.Background(), events)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kept, weight := 0, 0
+	for i, err := range results {
+		switch err {
+		case nil:
+			kept++
+			weight += events[i].weight()
+			if events[i].SampleRate != 10 {
+				t.Fatalf("kept event has sample rate %d, want 10", events[i].SampleRate)
+			}
+		case errSampledOut:
+		default:
+			t.Fatalf("unexpected result %v", err)
+		}
+	}
+	// 1 in 10 kept, each standing for 10, so the weight estimates the total
+	if kept < 100 || kept > 300 {
+		t.Errorf("kept %d of 2000 events, want about 200", kept)
+	}
+	if weight != kept*10 {
+		t.Errorf("weight = %d, want %d", weight, kept*10)
+	}
+}
+
+func TestEventWeight(t *testing.T) {
+	tests := []struct {
+		rate int32
+		want int
+	}{{0, 1}, {1, 1}, {10, 10}}
+	for _, tt := range tests {
+		if got := (Event{SampleRate: tt.rate}).weight(); got != tt.want {
+			t.Errorf("weight of rate %d = %d, want %d", tt.rate, got, tt.want)
+		}
+	}
+}
+
+func TestEventLimiterReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "controls")
+	write := func(contents string) {
+		if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("app_opened limit=5/1h # noisy clients\n")
+	t.Setenv("ANALYTICS_EVENT_CONTROLS", "app_opened limit=100/1h,task.created limit=10/1m")
+	t.Setenv("ANALYTICS_EVENT_CONTROLS_FILE", path)
+	registry := testRegistry()
+	l, err := loadEventLimiter(&memoryWindows{}, registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	controls := *l.controls.Load()
+	if controls["app_opened"].limit != 5 || controls["task.created"].limit != 10 {
+		t.Fatalf("file should override the env var per type: %+v", controls)
+	}
+
+	if changed, err := l.reload(registry); changed || err != nil {
+		t.Errorf("unchanged file: changed = %v, err = %v", changed, err)
+	}
+
+	write("app_opened limit=7/1h\n")
+	if changed, err := l.reload(registry); !changed || err != nil {
+		t.Fatalf("changed file: changed = %v, err = %v", changed, err)
+	}
+	if got := (*l.controls.Load())["app_opened"].limit; got != 7 {
+		t.Errorf("limit after reload = %d, want 7", got)
+	}
+
+	write("task.completed sample=5\n")
+	if _, err := l.reload(registry); err == nil {
+		t.Fatal("reload accepted sampling a lifecycle event")
+	}
+	if got := (*l.controls.Load())["app_opened"].limit; got != 7 {
+		t.Errorf("a bad file replaced the controls: limit = %d, want 7", got)
+	}
+}
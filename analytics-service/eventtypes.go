@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+)
+
+// builtinEventTypes are the events the services themselves emit.
+var builtinEventTypes = []string{
+	"task.created",
+	"task.completed",
+	"task.reopened",
+	"task.deleted",
+	"task.due_date_extended",
+	"task.ownership_migrated",
+}
+
+// eventTypeRegistry is the set of event types TrackEvent accepts, so typos do
+// not quietly create event types no stats ever read.
+type eventTypeRegistry map[string]bool
+
+// loadEventTypes returns the built-in event types plus any listed in
+// ANALYTICS_EVENT_TYPES (comma-separated) or in the file named by
+// ANALYTICS_EVENT_TYPES_FILE (one per line, # starts a comment).
+func loadEventTypes() (eventTypeRegistry, error) {
+	registry := make(eventTypeRegistry)
+	for _, t := range builtinEventTypes {
+		registry[t] = true
+	}
+
+	for _, t := range strings.Split(os.Getenv("ANALYTICS_EVENT_TYPES"), ",") {
+		registry.add(t)
+	}
+
+	if path := os.Getenv("ANALYTICS_EVENT_TYPES_FILE"); path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line, _, _ := strings.Cut(scanner.Text(), "#")
+			registry.add(line)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	return registry, nil
+}
+
+func (r eventTypeRegistry) add(eventType string) {
+	if eventType = strings.TrimSpace(eventType); eventType != "" {
+		r[eventType] = true
+	}
+}
+
+func (r eventTypeRegistry) validate(eventType string) error {
+	if !r[eventType] {
+		return statusError(codes.InvalidArgument, "UNKNOWN_EVENT_TYPE", map[string]string{"event_type": eventType}, "unknown event_type %q", eventType)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestLoadEventTypes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "event-types")
+	if err := os.WriteFile(path, []byte("# Mobile events\nwidget_viewed\n  app_opened  # from the launcher\n\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ANALYTICS_EVENT_TYPES", "search_run, ,filter_saved")
+	t.Setenv("ANALYTICS_EVENT_TYPES_FILE", path)
+
+	registry, err := loadEventTypes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, eventType := range []string{"task.created", "task.completed", "search_run", "filter_saved", "widget_viewed", "app_opened"} {
+		if err := registry.validate(eventType); err != nil {
+			t.Errorf("validate(%q) = %v, want it accepted", eventType, err)
+		}
+	}
+	for _, eventType := range []string{"task.craeted", "Task.Created", "", " ", "# Mobile events", "app_opened  "} {
+		err := registry.validate(eventType)
+		if status.Code(err) != codes.InvalidArgument || errorReason(err) != "UNKNOWN_EVENT_TYPE" {
+			t.Errorf("validate(%q) = %v, want UNKNOWN_EVENT_TYPE", eventType, err)
+		}
+	}
+
+	t.Setenv("ANALYTICS_EVENT_TYPES_FILE", filepath.Join(t.TempDir(), "missing"))
+	if _, err := loadEventTypes(); err == nil {
+		t.Error("loaded event types from a missing file")
+	}
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"google.golang.org/grpc/codes"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+	"github.com/technonext/todo-app/proto/tenant"
+)
+
+const (
+	// exportBatch is how many buckets are read per round of queries, which
+	// bounds what an export holds in memory whatever its range
+	exportBatch = 31
+	// exportChunkSize is the most CSV sent in one message
+	exportChunkSize = 32 << 10
+)
+
+var exportHeader = []string{"date", "created", "completed", "active_users", "overdue"}
+
+type exportRow struct {
+	created     int32
+	completed   int32
+	activeUsers int32
+	overdue     int32
+}
+
+// ExportStats streams task stats per day, week or month as CSV, in chunks
+// of at most exportChunkSize. Created and completed come from the global
+// rollup for days it covers and from the per-user counters otherwise.
+// Active users are those who created a task in the bucket, and overdue
+// counts the tasks overdue at its end.
+func (s *server) ExportStats(req *pb.ExportStatsRequest, stream pb.AnalyticsService_ExportStatsServer) error {
+	ctx := stream.Context()
+
+	granularity := req.Granularity
+	if granularity == "" {
+		granularity = "day"
+	}
+	if granularity != "day" && granularity != "week" && granularity != "month" {
+		return statusError(codes.InvalidArgument, "INVALID_GRANULARITY", map[string]string{"granularity": req.Granularity}, "granularity must be day, week or month, got %q", req.Granularity)
+	}
+	switch req.Scope {
+	case "", "global":
+		if req.UserId != "" {
+			return statusError(codes.InvalidArgument, "UNEXPECTED_USER_ID", nil, "user_id is only accepted with the user scope")
+		}
+	case "user":
+		if req.UserId == "" {
+			return statusError(codes.InvalidArgument, "USER_ID_REQUIRED", nil, "user_id is required for the user scope")
+		}
+	default:
+		return statusError(codes.InvalidArgument, "INVALID_SCOPE", map[string]string{"scope": req.Scope}, "scope must be global or user, got %q", req.Scope)
+	}
+
+	dates, err := parseDateRange(req.StartDate, req.EndDate, time.UTC)
+	if err != nil {
+		return err
+	}
+
+	out := newExportWriter(stream)
+	if err := out.Write(exportHeader); err != nil {
+		return err
+	}
+
+	batch := make([]time.Time, 0, exportBatch)
+	for start := truncateTo(dates.start, granularity, time.UTC); !start.After(dates.end); start = nextBucket(start, granularity) {
+		batch = append(batch, start)
+		if len(batch) == exportBatch {
+			if err := s.exportBuckets(ctx, out, req.UserId, dates, granularity, batch); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+	}
+	if len(batch) > 0 {
+		if err := s.exportBuckets(ctx, out, req.UserId, dates, granularity, batch); err != nil {
+			return err
+		}
+	}
+
+	out.Flush()
+	return out.Error()
+}
+
+// exportBuckets writes the rows of consecutive buckets, clipped to dates.
+func (s *server) exportBuckets(ctx context.Context, out *csv.Writer, userID string, dates dateRange, granularity string, starts []time.Time) error {
+	r := dateRange{start: starts[0], end: nextBucket(starts[len(starts)-1], granularity).Add(-time.Nanosecond)}
+	if r.start.Before(dates.start) {
+		r.start = dates.start
+	}
+	if r.end.After(dates.end) {
+		r.end = dates.end
+	}
+
+	rows := make(map[int64]*exportRow, len(starts))
+	for _, start := range starts {
+		rows[start.Unix()] = &exportRow{}
+	}
+	if err := s.exportCounts(ctx, rows, userID, r, granularity); err != nil {
+		return err
+	}
+	if err := s.exportActiveUsers(ctx, rows, userID, r, granularity); err != nil {
+		return err
+	}
+	if err := s.exportOverdue(ctx, rows, userID, dates, granularity, starts); err != nil {
+		return err
+	}
+
+	for _, start := range starts {
+		row := rows[start.Unix()]
+		err := out.Write([]string{
+			start.Format(dayFormat),
+			strconv.Itoa(int(row.created)),
+			strconv.Itoa(int(row.completed)),
+			strconv.Itoa(int(row.activeUsers)),
+			strconv.Itoa(int(row.overdue)),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportCounts adds up tasks created and completed per bucket over the days
+// in r: for all users from stats_daily up to the last rolled-up day and the
+// per-user counters after it, for one user from their counters.
+func (s *server) exportCounts(ctx context.Context, rows map[int64]*exportRow, userID string, r dateRange, granularity string) error {
+	start := r.start.UTC().Format(dayFormat)
+	end := r.end.UTC().Format(dayFormat)
+
+	if userID != "" {
+		return addBucketCounts(ctx, rows, s.dailyStats, bson.M{"user_id": userID, "day": bson.M{"$gte": start, "$lte": end}}, granularity)
+	}
+
+	through, err := rolledThrough(ctx, s.jobs)
+	if err != nil {
+		return err
+	}
+	liveStart := start
+	if through != "" && start <= through {
+		rolledEnd := end
+		if through < rolledEnd {
+			rolledEnd = through
+		}
+		if err := addBucketCounts(ctx, rows, s.statsDaily, bson.M{"day": bson.M{"$gte": start, "$lte": rolledEnd}}, granularity); err != nil {
+			return err
+		}
+		next, err := time.Parse(dayFormat, through)
+		if err != nil {
+			return err
+		}
+		liveStart = next.AddDate(0, 0, 1).Format(dayFormat)
+	}
+	if liveStart > end {
+		return nil
+	}
+	return addBucketCounts(ctx, rows, s.dailyStats, bson.M{"day": bson.M{"$gte": liveStart, "$lte": end}}, granularity)
+}
+
+// addBucketCounts sums the created and completed counts of the matching
+// daily documents into their buckets.
+func addBucketCounts(ctx context.Context, rows map[int64]*exportRow, collection *tenant.Collection, filter bson.M, granularity string) error {
+	cursor, err := collection.Aggregate(ctx, []bson.M{
+		{"$match": filter},
+		{"$group": bson.M{
+			"_id":       dayBucket("$day", granularity),
+			"created":   bson.M{"$sum": "$created"},
+			"completed": bson.M{"$sum": "$completed"},
+		}},
+	})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var bucket struct {
+			Start     time.Time `bson:"_id"`
+			Created   int32     `bson:"created"`
+			Completed int32     `bson:"completed"`
+		}
+		if err := cursor.Decode(&bucket); err != nil {
+			return err
+		}
+		if row, ok := rows[bucket.Start.Unix()]; ok {
+			row.created += bucket.Created
+			row.completed += bucket.Completed
+		}
+	}
+	return cursor.Err()
+}
+
+// exportActiveUsers counts the users who created a task in each bucket.
+// Distinct users cannot be summed across daily rollups, so this reads the
+// per-user counters.
+func (s *server) exportActiveUsers(ctx context.Context, rows map[int64]*exportRow, userID string, r dateRange, granularity string) error {
+	match := bson.M{
+		"day": bson.M{
+			"$gte": r.start.UTC().Format(dayFormat),
+			"$lte": r.end.UTC().Format(dayFormat),
+		},
+		"created": bson.M{"$gt": 0},
+	}
+	if userID != "" {
+		match["user_id"] = userID
+	}
+
+	cursor, err := s.dailyStats.Aggregate(ctx, []bson.M{
+		{"$match": match},
+		{"$group": bson.M{"_id": bson.M{"bucket": dayBucket("$day", granularity), "user_id": "$user_id"}}},
+		{"$group": bson.M{"_id": "$_id.bucket", "active": bson.M{"$sum": 1}}},
+	})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var bucket struct {
+			Start  time.Time `bson:"_id"`
+			Active int32     `bson:"active"`
+		}
+		if err := cursor.Decode(&bucket); err != nil {
+			return err
+		}
+		if row, ok := rows[bucket.Start.Unix()]; ok {
+			row.activeUsers = bucket.Active
+		}
+	}
+	return cursor.Err()
+}
+
+// exportOverdue counts the tasks overdue at the end of each bucket, or at the
+// end of the range or now if those come first, in one pass over the tasks.
+func (s *server) exportOverdue(ctx context.Context, rows map[int64]*exportRow, userID string, dates dateRange, granularity string, starts []time.Time) error {
+	match := bson.M{"due_date": bson.M{"$nin": bson.A{nil, ""}}}
+	if userID != "" {
+		match["user_id"] = userID
+	}
+
+	now := time.Now()
+	sums := bson.M{"_id": nil}
+	for i, start := range starts {
+		at := nextBucket(start, granularity).Add(-time.Nanosecond)
+		if at.After(dates.end) {
+			at = dates.end
+		}
+		if at.After(now) {
+			at = now
+		}
+		sums[bucketKey(i)] = bson.M{"$sum": bson.M{"$cond": bson.A{overdueExpr(at), 1, 0}}}
+	}
+
+	cursor, err := s.taskCollection.Aggregate(ctx, []bson.M{
+		{"$match": match},
+		{"$group": sums},
+		{"$project": bson.M{"_id": 0}},
+	})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	counts := map[string]int32{}
+	if cursor.Next(ctx) {
+		if err := cursor.Decode(&counts); err != nil {
+			return err
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+	for i, start := range starts {
+		rows[start.Unix()].overdue = counts[bucketKey(i)]
+	}
+	return nil
+}
+
+// dayBucket truncates a YYYY-MM-DD field to the start of its UTC bucket.
+func dayBucket(day string, granularity string) bson.M {
+	return bson.M{"$dateTrunc": bson.M{
+		"date":        toDate(day),
+		"unit":        granularity,
+		"startOfWeek": "monday",
+	}}
+}
+
+func bucketKey(i int) string {
+	return fmt.Sprintf("bucket%d", i)
+}
+
+// newExportWriter writes CSV to stream in chunks of at most
+// exportChunkSize. csv.Writer reuses a large enough bufio.Writer, so each
+// flush of it sends one chunk.
+func newExportWriter(stream pb.AnalyticsService_ExportStatsServer) *csv.Writer {
+	return csv.NewWriter(bufio.NewWriterSize(chunkSender{stream}, exportChunkSize))
+}
+
+// chunkSender sends each write as one chunk of the export.
+type chunkSender struct {
+	stream pb.AnalyticsService_ExportStatsServer
+}
+
+func (c chunkSender) Write(p []byte) (int, error) {
+	if err := c.stream.Send(&pb.ExportStatsChunk{Data: p}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,82 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+	"github.com/technonext/todo-app/proto/tenant"
+)
+
+func TestMongoExportMatchesRollup(t *testing.T) {
+	repo := newMongoRepository(t)
+	db := repo.events.Database()
+	s := &server{
+		collection:     repo.events,
+		dailyStats:     repo.dailyStats,
+		statsDaily:     repo.statsDaily,
+		jobs:           repo.jobs,
+		taskCollection: tenant.Scoped(db.Collection("tasks")),
+	}
+	ctx := context.Background()
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	day := func(n int) string { return today.AddDate(0, 0, -n).Format(dayFormat) }
+	var docs []interface{}
+	for _, c := range []struct {
+		user               string
+		ago                int
+		created, completed int32
+	}{
+		{"u1", 3, 4, 1}, {"u2", 3, 2, 2}, {"u1", 2, 0, 3}, {"u2", 1, 5, 0}, {"u1", 0, 1, 1},
+	} {
+		docs = append(docs, bson.M{"_id": c.user + ":" + day(c.ago), "user_id": c.user, "day": day(c.ago), "created": c.created, "completed": c.completed})
+	}
+	if _, err := repo.dailyStats.InsertMany(ctx, docs); err != nil {
+		t.Fatal(err)
+	}
+	// Rolls up through yesterday; today is read from the counters
+	if err := s.rollupStats(ctx, true); err != nil {
+		t.Fatal(err)
+	}
+
+	stream := &fakeExportStream{}
+	err := s.ExportStats(&pb.ExportStatsRequest{StartDate: day(3), EndDate: day(0)}, stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(strings.NewReader(stream.csv())).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 5 || strings.Join(records[0], ",") != strings.Join(exportHeader, ",") {
+		t.Fatalf("export = %q, want the header and 4 days", records)
+	}
+
+	for _, record := range records[1:] {
+		var want struct {
+			Created     int32 `bson:"created"`
+			Completed   int32 `bson:"completed"`
+			ActiveUsers int32 `bson:"active_users"`
+		}
+		if record[0] == day(0) {
+			// Not rolled up yet: the sum of the live counters
+			want.Created, want.Completed, want.ActiveUsers = 1, 1, 1
+		} else if err := repo.statsDaily.FindOne(ctx, bson.M{"day": record[0]}).Decode(&want); err != nil {
+			t.Fatalf("rollup for %s: %v", record[0], err)
+		}
+		got := []string{record[1], record[2], record[3]}
+		wantRow := []string{strconv.Itoa(int(want.Created)), strconv.Itoa(int(want.Completed)), strconv.Itoa(int(want.ActiveUsers))}
+		if strings.Join(got, ",") != strings.Join(wantRow, ",") {
+			t.Errorf("%s: created, completed, active = %v, want %v from the rollup", record[0], got, wantRow)
+		}
+	}
+}
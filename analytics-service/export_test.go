@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// fakeExportStream collects the chunks of an export.
+type fakeExportStream struct {
+	grpc.ServerStream
+	chunks [][]byte
+}
+
+func (f *fakeExportStream) Context() context.Context { return context.Background() }
+
+func (f *fakeExportStream) Send(chunk *pb.ExportStatsChunk) error {
+	f.chunks = append(f.chunks, append([]byte(nil), chunk.Data...))
+	return nil
+}
+
+func (f *fakeExportStream) csv() string {
+	return string(bytes.Join(f.chunks, nil))
+}
+
+func TestExportWriterEscaping(t *testing.T) {
+	tests := []struct {
+		name   string
+		record []string
+		want   string
+	}{
+		{"plain", []string{"2026-03-01", "4", "2"}, "2026-03-01,4,2\n"},
+		{"comma", []string{"a,b", "1"}, "\"a,b\",1\n"},
+		{"quote", []string{`say "hi"`, "1"}, "\"say \"\"hi\"\"\",1\n"},
+		{"newline", []string{"two\nlines", "1"}, "\"two\nlines\",1\n"},
+		{"leading space", []string{" padded", "1"}, "\" padded\",1\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream := &fakeExportStream{}
+			out := newExportWriter(stream)
+			if err := out.Write(tt.record); err != nil {
+				t.Fatal(err)
+			}
+			out.Flush()
+			if err := out.Error(); err != nil {
+				t.Fatal(err)
+			}
+			if got := stream.csv(); got != tt.want {
+				t.Errorf("csv = %q, want %q", got, tt.want)
+			}
+
+			// And it reads back as the same record
+			back, err := csv.NewReader(strings.NewReader(stream.csv())).Read()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(back, "|") != strings.Join(tt.record, "|") {
+				t.Errorf("read back %q, want %q", back, tt.record)
+			}
+		})
+	}
+}
+
+func TestExportWriterChunks(t *testing.T) {
+	stream := &fakeExportStream{}
+	out := newExportWriter(stream)
+	row := []string{"2026-03-01", "12345", "678", "90", "1"}
+	const rows = 5000
+	for i := 0; i < rows; i++ {
+		if err := out.Write(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out.Flush()
+
+	if len(stream.chunks) < 2 {
+		t.Fatalf("sent %d chunks, want the export split", len(stream.chunks))
+	}
+	for i, chunk := range stream.chunks {
+		if len(chunk) > exportChunkSize {
+			t.Errorf("chunk %d has %d bytes, more than %d", i, len(chunk), exportChunkSize)
+		}
+	}
+	records, err := csv.NewReader(strings.NewReader(stream.csv())).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != rows {
+		t.Errorf("read back %d rows, want %d", len(records), rows)
+	}
+}
+
+func TestExportStatsValidation(t *testing.T) {
+	s := &server{}
+	tests := []struct {
+		name   string
+		req    *pb.ExportStatsRequest
+		reason string
+	}{
+		{"granularity", &pb.ExportStatsRequest{Granularity: "hour"}, "INVALID_GRANULARITY"},
+		{"scope", &pb.ExportStatsRequest{Scope: "team"}, "INVALID_SCOPE"},
+		{"user scope without user", &pb.ExportStatsRequest{Scope: "user"}, "USER_ID_REQUIRED"},
+		{"global scope with user", &pb.ExportStatsRequest{UserId: "u1"}, "UNEXPECTED_USER_ID"},
+		{"range", &pb.ExportStatsRequest{StartDate: "2026-03-02", EndDate: "2026-03-01"}, "INVALID_DATE_RANGE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream := &fakeExportStream{}
+			err := s.ExportStats(tt.req, stream)
+			if status.Code(err) != codes.InvalidArgument || errorReason(err) != tt.reason {
+				t.Errorf("err = %v, want %s", err, tt.reason)
+			}
+			if len(stream.chunks) > 0 {
+				t.Errorf("sent %d chunks before rejecting the request", len(stream.chunks))
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// avgExtensionsPerTask is the due date extensions made in a range per task
+// created in it. Extensions of tasks created earlier count too, so a user
+// who mostly pushes back old tasks can average more than any one task was
+// extended.
+func avgExtensionsPerTask(extensions, tasks int32) float64 {
+	if tasks == 0 {
+		return 0
+	}
+	return float64(extensions) / float64(tasks)
+}
+
+// DueDateExtensions counts the task service's task.due_date_extended events
+// for the user in r.
+func (m *mongoRepository) DueDateExtensions(ctx context.Context, userID string, r dateRange) (int32, error) {
+	match := eventsIn(r)
+	match["user_id"] = userID
+	match["event_type"] = "task.due_date_extended"
+	pipeline := []bson.M{
+		{"$match": match},
+		{"$group": bson.M{"_id": nil, "extensions": bson.M{"$sum": eventWeight}}},
+	}
+	cursor, err := m.events.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var totals struct {
+		Extensions int32 `bson:"extensions"`
+	}
+	if cursor.Next(ctx) {
+		if err := cursor.Decode(&totals); err != nil {
+			return 0, err
+		}
+	}
+	return totals.Extensions, cursor.Err()
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+
+	"github.com/technonext/todo-app/proto/pagination"
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// fakeRepository is a memoryRepository whose methods named in err return
+// that error instead.
+type fakeRepository struct {
+	*memoryRepository
+	err map[string]error
+}
+
+func newFakeRepository() *fakeRepository {
+	return &fakeRepository{memoryRepository: newMemoryRepository(), err: make(map[string]error)}
+}
+
+func (f *fakeRepository) InsertEvent(ctx context.Context, event Event) (primitive.ObjectID, error) {
+	if err := f.err["InsertEvent"]; err != nil {
+		return primitive.NilObjectID, err
+	}
+	return f.memoryRepository.InsertEvent(ctx, event)
+}
+
+func (f *fakeRepository) ApplyEvent(ctx context.Context, event Event) error {
+	if err := f.err["ApplyEvent"]; err != nil {
+		return err
+	}
+	return f.memoryRepository.ApplyEvent(ctx, event)
+}
+
+func (f *fakeRepository) RangeTotals(ctx context.Context, userID string, r dateRange) (created, completed int32, err error) {
+	if err := f.err["RangeTotals"]; err != nil {
+		return 0, 0, err
+	}
+	return f.memoryRepository.RangeTotals(ctx, userID, r)
+}
+
+func (f *fakeRepository) SaveSnapshot(ctx context.Context, snapshot UserStatsSnapshot) error {
+	if err := f.err["SaveSnapshot"]; err != nil {
+		return err
+	}
+	return f.memoryRepository.SaveSnapshot(ctx, snapshot)
+}
+
+func (f *fakeRepository) GlobalTotals(ctx context.Context, r dateRange) (globalTotals, error) {
+	if err := f.err["GlobalTotals"]; err != nil {
+		return globalTotals{}, err
+	}
+	return f.memoryRepository.GlobalTotals(ctx, r)
+}
+
+// fakeTaskClient answers GetPendingTaskCount with overdue, or err.
+type fakeTaskClient struct {
+	pb.TaskServiceClient
+	overdue int32
+	err     error
+}
+
+func (c *fakeTaskClient) GetPendingTaskCount(ctx context.Context, req *pb.GetPendingTaskCountRequest, opts ...grpc.CallOption) (*pb.GetPendingTaskCountResponse, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &pb.GetPendingTaskCountResponse{Overdue: c.overdue}, nil
+}
+
+// newTestService returns a statsService over repo with no event controls and
+// caching off.
+func newTestService(repo *fakeRepository, tasks pb.TaskServiceClient) *statsService {
+	limits := &eventLimiter{windows: &memoryWindows{}}
+	limits.controls.Store(&eventControls{})
+	registry := make(eventTypeRegistry)
+	for _, t := range builtinEventTypes {
+		registry[t] = true
+	}
+	registry.add("app_opened")
+	return &statsService{
+		events:     repo,
+		stats:      repo,
+		tasks:      tasks,
+		eventTypes: registry,
+		limits:     limits,
+		cache:      newReadCache(0, 1),
+	}
+}
+
+// fakeNotificationClient lists templates, records the notifications sent
+// and answers GetNotificationCounts with counts, or err. A hanging client
+// answers nothing until the call's context ends.
+type fakeNotificationClient struct {
+	pb.NotificationServiceClient
+	mu        sync.Mutex
+	templates []*pb.Template
+	sent      []*pb.NotificationRequest
+	counts    *pb.GetNotificationCountsResponse
+	err       error
+	hang      bool
+}
+
+func (c *fakeNotificationClient) GetNotificationCounts(ctx context.Context, req *pb.GetNotificationCountsRequest, opts ...grpc.CallOption) (*pb.GetNotificationCountsResponse, error) {
+	if c.hang {
+		<-ctx.Done()
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+	if c.err != nil {
+		return nil, c.err
+	}
+	return c.counts, nil
+}
+
+// fakeUserClient answers GetUserCounts with counts, or err, or hangs like
+// fakeNotificationClient.
+type fakeUserClient struct {
+	pb.UserServiceClient
+	counts *pb.GetUserCountsResponse
+	err    error
+	hang   bool
+}
+
+func (c *fakeUserClient) GetUserCounts(ctx context.Context, req *pb.GetUserCountsRequest, opts ...grpc.CallOption) (*pb.GetUserCountsResponse, error) {
+	if c.hang {
+		<-ctx.Done()
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+	if c.err != nil {
+		return nil, c.err
+	}
+	return c.counts, nil
+}
+
+func (c *fakeNotificationClient) ListTemplates(ctx context.Context, req *pb.ListTemplatesRequest, opts ...grpc.CallOption) (*pb.ListTemplatesResponse, error) {
+	page, err := pagination.Resolve(req.Pagination, req.Page, req.Limit, pagination.DefaultLimits)
+	if err != nil {
+		return nil, err
+	}
+	start := min(int(page.Offset), len(c.templates))
+	end := len(c.templates)
+	if page.Limit > 0 {
+		end = min(start+int(page.Limit), end)
+	}
+	templates := c.templates[start:end]
+	return &pb.ListTemplatesResponse{Templates: templates, Total: int32(len(c.templates)), Pagination: page.Response(int64(len(c.templates)), len(templates))}, nil
+}
+
+func (c *fakeNotificationClient) SendNotification(ctx context.Context, req *pb.NotificationRequest, opts ...grpc.CallOption) (*pb.NotificationResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sent = append(c.sent, req)
+	return &pb.NotificationResponse{Notification: &pb.Notification{UserId: req.UserId}}, nil
+}
+
+// errorReason returns the ErrorInfo reason attached to err.
+func errorReason(err error) string {
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(interface{ GetReason() string }); ok {
+			return info.GetReason()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc/codes"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+const (
+	// forecastHistoryDays is how many whole days before today a forecast
+	// is fitted to
+	forecastHistoryDays = 30
+	// minForecastDays is the fewest days of history a forecast is made from
+	minForecastDays = 7
+	// A trend is stable while the fitted line moves by less than this
+	// fraction of the mean daily completions over the history
+	stableTrendFraction = 0.1
+)
+
+// completionForecast is a least-squares line through daily completions.
+type completionForecast struct {
+	tomorrow   int32
+	week       int32
+	trend      string
+	confidence float64
+}
+
+// forecastCompletions fits a line through daily completions, oldest first and
+// ending yesterday, and extends it: today is one day past the last count.
+// Predictions below zero count as zero.
+func forecastCompletions(daily []int32) completionForecast {
+	n := float64(len(daily))
+	meanX := (n - 1) / 2
+	var meanY float64
+	for _, y := range daily {
+		meanY += float64(y)
+	}
+	meanY /= n
+
+	var sxy, sxx float64
+	for x, y := range daily {
+		dx := float64(x) - meanX
+		sxy += dx * (float64(y) - meanY)
+		sxx += dx * dx
+	}
+	var slope float64
+	if sxx > 0 {
+		slope = sxy / sxx
+	}
+	intercept := meanY - slope*meanX
+	predict := func(x float64) float64 {
+		return math.Max(0, intercept+slope*x)
+	}
+
+	var squares float64
+	for x, y := range daily {
+		residual := float64(y) - (intercept + slope*float64(x))
+		squares += residual * residual
+	}
+	rmse := math.Sqrt(squares / n)
+	confidence := 1.0
+	if rmse > 0 {
+		confidence = math.Max(0, 1-rmse/meanY)
+	}
+
+	var week float64
+	for x := n; x < n+7; x++ {
+		week += predict(x)
+	}
+
+	trend := "stable"
+	if change := slope * (n - 1); math.Abs(change) >= stableTrendFraction*meanY && change != 0 {
+		trend = "improving"
+		if change < 0 {
+			trend = "declining"
+		}
+	}
+
+	return completionForecast{
+		tomorrow:   int32(math.Round(predict(n + 1))),
+		week:       int32(math.Round(week)),
+		trend:      trend,
+		confidence: confidence,
+	}
+}
+
+// getCompletionForecast forecasts from the user's daily counters over the
+// last forecastHistoryDays whole UTC days. The history starts on the first of
+// those days the user was active, so new users are not forecast from days
+// before they joined.
+func (s *statsService) getCompletionForecast(ctx context.Context, req *pb.GetCompletionForecastRequest) (*pb.GetCompletionForecastResponse, error) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	dates := dateRange{start: today.AddDate(0, 0, -forecastHistoryDays), end: today.Add(-time.Nanosecond)}
+	completions, err := s.stats.DailyCompletions(ctx, req.UserId, dates)
+	if err != nil {
+		return nil, err
+	}
+
+	var daily []int32
+	for day := dates.start; day.Before(today); day = day.AddDate(0, 0, 1) {
+		count, active := completions[day.Format(dayFormat)]
+		if active || len(daily) > 0 {
+			daily = append(daily, count)
+		}
+	}
+	if len(daily) < minForecastDays {
+		return nil, statusError(codes.FailedPrecondition, "INSUFFICIENT_HISTORY",
+			map[string]string{"days": strconv.Itoa(len(daily)), "required_days": strconv.Itoa(minForecastDays)},
+			"user %s has %d days of history, a forecast needs %d", req.UserId, len(daily), minForecastDays)
+	}
+
+	forecast := forecastCompletions(daily)
+	return &pb.GetCompletionForecastResponse{
+		PredictedCompletionsTomorrow: forecast.tomorrow,
+		PredictedCompletionsThisWeek: forecast.week,
+		Trend:                        forecast.trend,
+		Confidence:                   forecast.confidence,
+	}, nil
+}
+
+func (s *server) GetCompletionForecast(ctx context.Context, req *pb.GetCompletionForecastRequest) (*pb.GetCompletionForecastResponse, error) {
+	return s.getCompletionForecast(ctx, req)
+}
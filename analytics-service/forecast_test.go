@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// linear returns n daily counts on the line a + b*day.
+func linear(n int, a, b int32) []int32 {
+	daily := make([]int32, n)
+	for x := range daily {
+		daily[x] = a + b*int32(x)
+	}
+	return daily
+}
+
+func TestForecastCompletions(t *testing.T) {
+	tests := []struct {
+		name           string
+		daily          []int32
+		wantTomorrow   int32
+		wantWeek       int32
+		wantTrend      string
+		wantConfidence float64
+	}{
+		// Yesterday was day 29 with 59; today is day 30
+		{"rising line", linear(30, 1, 2), 63, 469, "improving", 1},
+		{"falling line", linear(30, 40, -1), 9, 49, "declining", 1},
+		{"flat line", linear(30, 5, 0), 5, 35, "stable", 1},
+		{"line falling below zero", linear(10, 10, -1), 0, 0, "declining", 1},
+		{"no completions", linear(7, 0, 0), 0, 0, "stable", 1},
+		{"noise around a flat line", []int32{4, 6, 4, 6, 4, 6, 4, 6, 4, 6, 4, 6, 4, 6, 4, 6, 4, 6, 4, 6}, 5, 36, "stable", 0.8008},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := forecastCompletions(tt.daily)
+			if got.tomorrow != tt.wantTomorrow || got.week != tt.wantWeek {
+				t.Errorf("tomorrow, week = %d, %d, want %d, %d", got.tomorrow, got.week, tt.wantTomorrow, tt.wantWeek)
+			}
+			if got.trend != tt.wantTrend {
+				t.Errorf("trend = %q, want %q", got.trend, tt.wantTrend)
+			}
+			if math.Abs(got.confidence-tt.wantConfidence) > 1e-3 {
+				t.Errorf("confidence = %.4f, want %.4f", got.confidence, tt.wantConfidence)
+			}
+		})
+	}
+}
+
+func TestGetCompletionForecast(t *testing.T) {
+	repo := newFakeRepository()
+	s := newTestService(repo, &fakeTaskClient{})
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	setDaily := func(userID string, daysAgo int, completed int32) {
+		if repo.daily[userID] == nil {
+			repo.daily[userID] = make(map[string][2]int32)
+		}
+		repo.daily[userID][today.AddDate(0, 0, -daysAgo).Format(dayFormat)] = [2]int32{1, completed}
+	}
+
+	// u1 has been active for 40 days, completing one more task each day; only
+	// the last 30 count, and today's are ignored
+	for ago := 40; ago >= 1; ago-- {
+		setDaily("u1", ago, int32(40-ago))
+	}
+	setDaily("u1", 0, 100)
+	// u2 joined 10 days ago and has been inactive since
+	setDaily("u2", 10, 3)
+	// u3 joined 6 days ago
+	setDaily("u3", 6, 3)
+
+	resp, err := s.getCompletionForecast(context.Background(), &pb.GetCompletionForecastRequest{UserId: "u1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Yesterday had 39; tomorrow is two days on, and the week runs 40 to 46
+	if resp.PredictedCompletionsTomorrow != 41 || resp.PredictedCompletionsThisWeek != 301 || resp.Trend != "improving" || resp.Confidence != 1 {
+		t.Errorf("forecast = %v, want 41 tomorrow, 301 this week, improving with confidence 1", resp)
+	}
+
+	resp, err = s.getCompletionForecast(context.Background(), &pb.GetCompletionForecastRequest{UserId: "u2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Trend != "declining" || resp.PredictedCompletionsTomorrow != 0 {
+		t.Errorf("forecast after going quiet = %v, want declining to 0", resp)
+	}
+
+	for _, userID := range []string{"u3", "unknown"} {
+		_, err := s.getCompletionForecast(context.Background(), &pb.GetCompletionForecastRequest{UserId: userID})
+		if status.Code(err) != codes.FailedPrecondition || errorReason(err) != "INSUFFICIENT_HISTORY" {
+			t.Errorf("%s: %v, want FailedPrecondition INSUFFICIENT_HISTORY", userID, err)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"google.golang.org/grpc/codes"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// GetActivityHeatmap counts the user's created and completed tasks by day of
+// the week and hour in the caller's timezone. Both matrices are 7x24 with
+// empty cells as zeros, so the dashboard can draw them directly.
+func (s *server) GetActivityHeatmap(ctx context.Context, req *pb.GetActivityHeatmapRequest) (*pb.GetActivityHeatmapResponse, error) {
+	if req.UserId == "" {
+		return nil, statusError(codes.InvalidArgument, "USER_ID_REQUIRED", nil, "user_id is required")
+	}
+	timezone := req.Timezone
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	loc, err := parseTimezone(timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	dates, err := parseDateRange(req.StartDate, req.EndDate, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	// ISO parts number the days Monday (1) to Sunday (7)
+	createdAt := toDate("$created_at")
+	match := eventsIn(dates)
+	match["user_id"] = req.UserId
+	match["event_type"] = bson.M{"$in": bson.A{"task.created", "task.completed"}}
+	pipeline := []bson.M{
+		{"$match": match},
+		{"$project": bson.M{
+			"event_type": 1,
+			"weight":     eventWeight,
+			"parts": bson.M{"$dateToParts": bson.M{
+				"date":     createdAt,
+				"timezone": timezone,
+				"iso8601":  true,
+			}},
+		}},
+		{"$group": bson.M{
+			"_id": bson.M{
+				"event_type": "$event_type",
+				"day":        "$parts.isoDayOfWeek",
+				"hour":       "$parts.hour",
+			},
+			"count": bson.M{"$sum": "$weight"},
+		}},
+	}
+
+	cursor, err := s.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	resp := &pb.GetActivityHeatmapResponse{
+		Created:   emptyHeatmap(),
+		Completed: emptyHeatmap(),
+	}
+	for cursor.Next(ctx) {
+		var row struct {
+			ID struct {
+				EventType string `bson:"event_type"`
+				Day       int    `bson:"day"`
+				Hour      int    `bson:"hour"`
+			} `bson:"_id"`
+			Count int32 `bson:"count"`
+		}
+		if err := cursor.Decode(&row); err != nil {
+			return nil, err
+		}
+		if row.ID.Day < 1 || row.ID.Day > 7 || row.ID.Hour < 0 || row.ID.Hour > 23 {
+			continue
+		}
+
+		matrix := resp.Created
+		if row.ID.EventType == "task.completed" {
+			matrix = resp.Completed
+		}
+		matrix[row.ID.Day-1].Hours[row.ID.Hour] = row.Count
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func emptyHeatmap() []*pb.HeatmapRow {
+	rows := make([]*pb.HeatmapRow, 7)
+	for i := range rows {
+		rows[i] = &pb.HeatmapRow{Hours: make([]int32, 24)}
+	}
+	return rows
+}
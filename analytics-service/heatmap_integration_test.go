@@ -0,0 +1,93 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+func TestMongoActivityHeatmap(t *testing.T) {
+	stats := newMongoService(t, &fakeTaskClient{})
+	repo := stats.stats.(*mongoRepository)
+	s := &server{statsService: stats, collection: repo.events}
+	ctx := context.Background()
+
+	// Dhaka is UTC+6 all year, so these instants fall on known cells there
+	events := []struct {
+		userID, eventType string
+		at                time.Time
+		sampleRate        int32
+	}{
+		// Monday 09:30
+		{"u1", "task.created", time.Date(2026, 3, 2, 3, 30, 0, 0, time.UTC), 0},
+		// Sunday in UTC, but Monday 00:15
+		{"u1", "task.created", time.Date(2026, 3, 1, 18, 15, 0, 0, time.UTC), 0},
+		// Sunday 23:00 and 23:59, the last cell of the week
+		{"u1", "task.created", time.Date(2026, 3, 8, 17, 0, 0, 0, time.UTC), 0},
+		{"u1", "task.created", time.Date(2026, 3, 8, 17, 59, 0, 0, time.UTC), 0},
+		// Wednesday 18:00
+		{"u1", "task.completed", time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC), 0},
+		// Friday 12:00, sampled one in four
+		{"u1", "task.completed", time.Date(2026, 3, 6, 6, 0, 0, 0, time.UTC), 4},
+		// Not counted: other types, other users and Monday 00:30 after the range
+		{"u1", "task.deleted", time.Date(2026, 3, 2, 3, 30, 0, 0, time.UTC), 0},
+		{"u2", "task.created", time.Date(2026, 3, 2, 3, 30, 0, 0, time.UTC), 0},
+		{"u1", "task.created", time.Date(2026, 3, 8, 18, 30, 0, 0, time.UTC), 0},
+	}
+	for i, e := range events {
+		_, err := repo.InsertEvent(ctx, Event{
+			UserID: e.userID, EventType: e.eventType, ResourceID: string(rune('a' + i)), SampleRate: e.sampleRate,
+			CreatedAt: e.at.In(time.Local).Format(time.RFC3339),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	resp, err := s.GetActivityHeatmap(ctx, &pb.GetActivityHeatmapRequest{UserId: "u1", StartDate: "2026-03-01", EndDate: "2026-03-08", Timezone: "Asia/Dhaka"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Rows run Monday to Sunday
+	wantCreated, wantCompleted := emptyHeatmap(), emptyHeatmap()
+	wantCreated[0].Hours[9] = 1
+	wantCreated[0].Hours[0] = 1
+	wantCreated[6].Hours[23] = 2
+	wantCompleted[2].Hours[18] = 1
+	wantCompleted[4].Hours[12] = 4
+	for _, matrix := range []struct {
+		name      string
+		got, want []*pb.HeatmapRow
+	}{
+		{"created", resp.Created, wantCreated},
+		{"completed", resp.Completed, wantCompleted},
+	} {
+		if len(matrix.got) != 7 {
+			t.Fatalf("%s has %d rows, want 7", matrix.name, len(matrix.got))
+		}
+		for day, row := range matrix.got {
+			if len(row.Hours) != 24 {
+				t.Fatalf("%s day %d has %d hours, want 24", matrix.name, day+1, len(row.Hours))
+			}
+			for hour, count := range row.Hours {
+				if want := matrix.want[day].Hours[hour]; count != want {
+					t.Errorf("%s day %d hour %d = %d, want %d", matrix.name, day+1, hour, count, want)
+				}
+			}
+		}
+	}
+
+	// In UTC the same events land elsewhere
+	resp, err = s.GetActivityHeatmap(ctx, &pb.GetActivityHeatmapRequest{UserId: "u1", StartDate: "2026-03-01", EndDate: "2026-03-08"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sunday, monday, midnight := resp.Created[6].Hours[17], resp.Created[0].Hours[3], resp.Created[0].Hours[0]; sunday != 2 || monday != 1 || midnight != 0 {
+		t.Errorf("in UTC, Sunday 17:00 has %d, Monday 03:00 %d and Monday 00:00 %d created; want 2, 1 and 0", sunday, monday, midnight)
+	}
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+	"github.com/technonext/todo-app/proto/tenant"
+)
+
+// With EVENT_BROKER=nats, events are also ingested from a JetStream subject,
+// so busy publishers need not wait on TrackEvent. Each message is a
+// TrackEventRequest in protobuf JSON and is validated like one. Messages are
+// fetched in batches and acknowledged only once stored, so delivery is at
+// least once; the message id (Nats-Msg-Id, or else the stream sequence) is
+// stored on the event under a unique index, so a redelivered message is not
+// stored twice. Messages that can never be stored are copied to the dead
+// letter subject, with the reason in the Analytics-Error header, and dropped.
+
+const (
+	ingestConsumer    = "analytics"
+	ingestErrorHeader = "Analytics-Error"
+	// Wait between attempts to connect to the broker
+	ingestRetryDelay = 5 * time.Second
+)
+
+type ingestConfig struct {
+	url               string
+	stream            string
+	subject           string
+	deadLetterSubject string
+	batchSize         int
+	flushInterval     time.Duration
+}
+
+// loadIngestConfig reads the broker settings, returning nil when
+// EVENT_BROKER is unset.
+func loadIngestConfig() (*ingestConfig, error) {
+	switch broker := os.Getenv("EVENT_BROKER"); broker {
+	case "":
+		return nil, nil
+	case "nats":
+	default:
+		return nil, fmt.Errorf("unsupported EVENT_BROKER %q: only nats is supported", broker)
+	}
+
+	batchSize, err := strconv.Atoi(getEnv("EVENT_BATCH_SIZE", "100"))
+	if err != nil || batchSize <= 0 || batchSize > maxBatchEvents {
+		return nil, fmt.Errorf("invalid EVENT_BATCH_SIZE %q: must be from 1 to %d", os.Getenv("EVENT_BATCH_SIZE"), maxBatchEvents)
+	}
+	flushInterval, err := time.ParseDuration(getEnv("EVENT_FLUSH_INTERVAL", "1s"))
+	if err != nil || flushInterval <= 0 {
+		return nil, fmt.Errorf("invalid EVENT_FLUSH_INTERVAL %q", os.Getenv("EVENT_FLUSH_INTERVAL"))
+	}
+
+	cfg := &ingestConfig{
+		url:               getEnv("NATS_URL", nats.DefaultURL),
+		stream:            getEnv("EVENT_STREAM", "ANALYTICS_EVENTS"),
+		subject:           getEnv("EVENT_SUBJECT", "analytics.events"),
+		deadLetterSubject: getEnv("EVENT_DEAD_LETTER_SUBJECT", "analytics.events.dead"),
+		batchSize:         batchSize,
+		flushInterval:     flushInterval,
+	}
+	if cfg.deadLetterSubject == cfg.subject {
+		return nil, fmt.Errorf("EVENT_DEAD_LETTER_SUBJECT must differ from EVENT_SUBJECT")
+	}
+	return cfg, nil
+}
+
+// startIngest consumes events until ctx ends, connecting to the broker
+// again whenever it cannot.
+func (s *server) startIngest(ctx context.Context, cfg *ingestConfig) {
+	for {
+		err := s.ingest(ctx, cfg)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("Event ingestion stopped, retrying in %s: %v", ingestRetryDelay, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(ingestRetryDelay):
+		}
+	}
+}
+
+func (s *server) ingest(ctx context.Context, cfg *ingestConfig) error {
+	nc, err := nats.Connect(cfg.url, nats.MaxReconnects(-1))
+	if err != nil {
+		return err
+	}
+	defer nc.Close()
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return err
+	}
+	stream, err := js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:     cfg.stream,
+		Subjects: []string{cfg.subject},
+	})
+	if err != nil {
+		return err
+	}
+	// Dead letters are kept in a stream of their own so they can be inspected
+	// and republished
+	_, err = js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:     cfg.stream + "_DEAD",
+		Subjects: []string{cfg.deadLetterSubject},
+	})
+	if err != nil {
+		return err
+	}
+	consumer, err := stream.CreateOrUpdateConsumer(ctx, jetstream.ConsumerConfig{
+		Durable:       ingestConsumer,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		FilterSubject: cfg.subject,
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("Ingesting events from %s on %s", cfg.subject, cfg.url)
+
+	for ctx.Err() == nil {
+		batch, err := consumer.Fetch(cfg.batchSize, jetstream.FetchMaxWait(cfg.flushInterval))
+		if err != nil {
+			return err
+		}
+		var msgs []jetstream.Msg
+		for msg := range batch.Messages() {
+			msgs = append(msgs, msg)
+		}
+		if err := batch.Error(); err != nil && err != nats.ErrTimeout {
+			// Messages fetched but not acknowledged are redelivered
+			return err
+		}
+		if len(msgs) > 0 {
+			s.ingestBatch(ctx, js, cfg, msgs)
+		}
+	}
+	return nil
+}
+
+// ingestBatch stores the valid messages of a batch in one insert and
+// acknowledges each message once its fate is settled.
+func (s *server) ingestBatch(ctx context.Context, js jetstream.JetStream, cfg *ingestConfig, msgs []jetstream.Msg) {
+	now := time.Now()
+	var events []Event
+	var pending []jetstream.Msg
+
+	for _, msg := range msgs {
+		var req pb.TrackEventRequest
+		if err := protojson.Unmarshal(msg.Data(), &req); err != nil {
+			s.deadLetter(ctx, js, cfg, msg, "malformed event: "+err.Error())
+			continue
+		}
+		event, err := s.eventFromRequest(&req, now)
+		if err != nil {
+			s.deadLetter(ctx, js, cfg, msg, status.Convert(err).Message())
+			continue
+		}
+
+		event.ID = primitive.NewObjectID()
+		event.MessageID = messageID(msg)
+		event.TenantID = msg.Headers().Get(tenant.Header)
+		events = append(events, event)
+		pending = append(pending, msg)
+	}
+	if len(events) == 0 {
+		return
+	}
+
+	// Events the controls turn away were handled as configured, so they are
+	// acknowledged rather than dead-lettered
+	admitted, err := s.limits.admit(ctx, events)
+	if err != nil {
+		log.Printf("Failed to apply event controls to %d ingested events: %v", len(events), err)
+		for _, msg := range pending {
+			msg.Nak()
+		}
+		return
+	}
+	kept, keptPending := events[:0], pending[:0]
+	for i, err := range admitted {
+		if err != nil {
+			pending[i].Ack()
+			continue
+		}
+		kept = append(kept, events[i])
+		keptPending = append(keptPending, pending[i])
+	}
+	events, pending = kept, keptPending
+	if len(events) == 0 {
+		return
+	}
+
+	failed, err := s.storeEvents(ctx, events)
+	if err != nil {
+		log.Printf("Failed to store %d ingested events: %v", len(events), err)
+		for _, msg := range pending {
+			msg.Nak()
+		}
+		return
+	}
+
+	for i, msg := range pending {
+		writeErr, ok := failed[i]
+		switch {
+		case !ok, writeErr.HasErrorCode(duplicateKeyCode):
+			// Stored now or by an earlier delivery
+			msg.Ack()
+		default:
+			s.deadLetter(ctx, js, cfg, msg, writeErr.Message)
+		}
+	}
+}
+
+// messageID identifies a message across redeliveries.
+func messageID(msg jetstream.Msg) string {
+	if id := msg.Headers().Get(jetstream.MsgIDHeader); id != "" {
+		return id
+	}
+	if meta, err := msg.Metadata(); err == nil {
+		return fmt.Sprintf("%s:%d", meta.Stream, meta.Sequence.Stream)
+	}
+	return ""
+}
+
+// deadLetter copies msg to the dead letter subject and drops it. If the copy
+// cannot be published, msg is left for redelivery instead.
+func (s *server) deadLetter(ctx context.Context, js jetstream.JetStream, cfg *ingestConfig, msg jetstream.Msg, reason string) {
+	dead := nats.NewMsg(cfg.deadLetterSubject)
+	dead.Data = msg.Data()
+	for key, values := range msg.Headers() {
+		dead.Header[key] = values
+	}
+	dead.Header.Set(ingestErrorHeader, reason)
+
+	if _, err := js.PublishMsg(ctx, dead); err != nil {
+		log.Printf("Failed to dead-letter event message: %v", err)
+		msg.Nak()
+		return
+	}
+	log.Printf("Dead-lettered event message: %s", reason)
+	msg.Term()
+}
@@ -0,0 +1,218 @@
+//go:build integration
+
+package main
+
+// Besides MongoDB, the broker tests need a JetStream-enabled NATS server:
+//
+//	docker run --rm -d -p 4222:4222 nats:2.10 -js
+//
+// NATS_TEST_URL overrides the default nats://localhost:4222. Each test uses
+// streams of its own, deleted afterwards.
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// newIngestTest returns a server storing to MongoDB and a config for fresh
+// streams on the test broker, along with a JetStream client for publishing.
+func newIngestTest(t *testing.T) (*server, *ingestConfig, jetstream.JetStream) {
+	t.Helper()
+	url := os.Getenv("NATS_TEST_URL")
+	if url == "" {
+		url = nats.DefaultURL
+	}
+	nc, err := nats.Connect(url, nats.Timeout(2*time.Second))
+	if err != nil {
+		t.Skipf("NATS not reachable at %s: %v", url, err)
+	}
+	t.Cleanup(nc.Close)
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id := time.Now().UnixNano()
+	cfg := &ingestConfig{
+		url:               url,
+		stream:            fmt.Sprintf("ANALYTICS_TEST_%d", id),
+		subject:           fmt.Sprintf("analytics_test.%d.events", id),
+		deadLetterSubject: fmt.Sprintf("analytics_test.%d.dead", id),
+		batchSize:         2,
+		flushInterval:     100 * time.Millisecond,
+	}
+	t.Cleanup(func() {
+		js.DeleteStream(context.Background(), cfg.stream)
+		js.DeleteStream(context.Background(), cfg.stream+"_DEAD")
+	})
+
+	return newIngestServer(t), cfg, js
+}
+
+// newIngestServer returns a server storing ingested events to MongoDB.
+func newIngestServer(t *testing.T) *server {
+	stats := newMongoService(t, nil)
+	return &server{statsService: stats, collection: stats.events.(*mongoRepository).events}
+}
+
+// startTestIngest consumes events until the test ends.
+func startTestIngest(t *testing.T, s *server, cfg *ingestConfig) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.ingest(ctx, cfg)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+// waitFor polls cond until it holds or a few seconds pass.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func publishEvent(t *testing.T, js jetstream.JetStream, subject, id, data string) {
+	t.Helper()
+	msg := nats.NewMsg(subject)
+	msg.Data = []byte(data)
+	if id != "" {
+		msg.Header.Set(jetstream.MsgIDHeader, id)
+	}
+	if _, err := js.PublishMsg(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIngestBatches(t *testing.T) {
+	s, cfg, js := newIngestTest(t)
+	startTestIngest(t, s, cfg)
+	waitFor(t, "the stream", func() bool {
+		_, err := js.Stream(context.Background(), cfg.stream)
+		return err == nil
+	})
+
+	// More events than fit in one batch, some without a publisher id
+	for i := 0; i < 5; i++ {
+		id := ""
+		if i%2 == 0 {
+			id = fmt.Sprintf("m%d", i)
+		}
+		publishEvent(t, js, cfg.subject, id, fmt.Sprintf(`{"user_id": "u1", "event_type": "task.created", "resource_id": "t%d"}`, i))
+	}
+
+	ctx := context.Background()
+	waitFor(t, "5 stored events", func() bool {
+		n, _ := s.collection.CountDocuments(ctx, bson.M{"user_id": "u1"})
+		return n == 5
+	})
+	ids, err := s.collection.Distinct(ctx, "message_id", bson.M{"user_id": "u1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 5 {
+		t.Errorf("stored %d distinct message ids, want 5: %v", len(ids), ids)
+	}
+	for _, id := range []string{"m0", "m2", "m4"} {
+		if n, _ := s.collection.CountDocuments(ctx, bson.M{"message_id": id}); n != 1 {
+			t.Errorf("%d events have message id %s, want 1", n, id)
+		}
+	}
+}
+
+func TestIngestRedeliveryIsIdempotent(t *testing.T) {
+	s := newIngestServer(t)
+	cfg := &ingestConfig{deadLetterSubject: "events.dead"}
+	ctx := context.Background()
+	data := `{"user_id": "u1", "event_type": "task.created", "resource_id": "t1"}`
+
+	// The first delivery is stored but its ack is lost, so the broker
+	// delivers it again, alone and then alongside a new event
+	first := newFakeMsg(data, "m1")
+	s.ingestBatch(ctx, nil, cfg, []jetstream.Msg{first})
+	again := newFakeMsg(data, "m1")
+	s.ingestBatch(ctx, nil, cfg, []jetstream.Msg{again})
+	mixed := []*fakeMsg{newFakeMsg(data, "m1"), newFakeMsg(`{"user_id": "u1", "event_type": "task.created", "resource_id": "t2"}`, "m2")}
+	s.ingestBatch(ctx, nil, cfg, []jetstream.Msg{mixed[0], mixed[1]})
+
+	for i, msg := range []*fakeMsg{first, again, mixed[0], mixed[1]} {
+		if msg.settled != "ack" {
+			t.Errorf("delivery %d was settled with %q, want ack", i, msg.settled)
+		}
+	}
+	if n, _ := s.collection.CountDocuments(ctx, bson.M{"user_id": "u1"}); n != 2 {
+		t.Errorf("stored %d events, want 2", n)
+	}
+	created, _, err := s.stats.RangeTotals(ctx, "u1", dateRange{start: time.Now().AddDate(0, 0, -1), end: time.Now().AddDate(0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created != 2 {
+		t.Errorf("counted %d created tasks, want 2", created)
+	}
+}
+
+func TestIngestDeadLetters(t *testing.T) {
+	s, cfg, js := newIngestTest(t)
+	startTestIngest(t, s, cfg)
+	waitFor(t, "the streams", func() bool {
+		_, err := js.Stream(context.Background(), cfg.stream+"_DEAD")
+		return err == nil
+	})
+
+	publishEvent(t, js, cfg.subject, "bad", "not json")
+	publishEvent(t, js, cfg.subject, "unknown", `{"user_id": "u1", "event_type": "nope"}`)
+	publishEvent(t, js, cfg.subject, "good", `{"user_id": "u1", "event_type": "task.created", "resource_id": "t1"}`)
+
+	ctx := context.Background()
+	consumer, err := js.CreateOrUpdateConsumer(ctx, cfg.stream+"_DEAD", jetstream.ConsumerConfig{AckPolicy: jetstream.AckNonePolicy})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reasons := make(map[string]string)
+	waitFor(t, "2 dead letters", func() bool {
+		batch, err := consumer.Fetch(2, jetstream.FetchMaxWait(100*time.Millisecond))
+		if err != nil {
+			return false
+		}
+		for msg := range batch.Messages() {
+			reasons[msg.Headers().Get(jetstream.MsgIDHeader)] = msg.Headers().Get(ingestErrorHeader)
+		}
+		return len(reasons) == 2
+	})
+
+	if !strings.HasPrefix(reasons["bad"], "malformed event: ") {
+		t.Errorf("malformed message reason = %q", reasons["bad"])
+	}
+	if !strings.Contains(reasons["unknown"], "nope") {
+		t.Errorf("unknown event type reason = %q", reasons["unknown"])
+	}
+	waitFor(t, "the valid event", func() bool {
+		n, _ := s.collection.CountDocuments(ctx, bson.M{"message_id": "good"})
+		return n == 1
+	})
+
+	// Dead letters are terminated, so nothing is left pending redelivery
+	waitFor(t, "all messages settled", func() bool {
+		info, err := js.Consumer(ctx, cfg.stream, ingestConsumer)
+		return err == nil && info.CachedInfo().NumAckPending == 0 && info.CachedInfo().NumPending == 0
+	})
+}
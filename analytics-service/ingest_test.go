@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// fakeMsg is a fetched message that records how it was settled.
+type fakeMsg struct {
+	jetstream.Msg
+	data    []byte
+	headers nats.Header
+	meta    *jetstream.MsgMetadata
+	settled string
+}
+
+func newFakeMsg(data string, id string) *fakeMsg {
+	msg := &fakeMsg{data: []byte(data), headers: nats.Header{}}
+	if id != "" {
+		msg.headers.Set(jetstream.MsgIDHeader, id)
+	}
+	return msg
+}
+
+func (m *fakeMsg) Data() []byte         { return m.data }
+func (m *fakeMsg) Headers() nats.Header { return m.headers }
+func (m *fakeMsg) Ack() error           { m.settled = "ack"; return nil }
+func (m *fakeMsg) Nak() error           { m.settled = "nak"; return nil }
+func (m *fakeMsg) Term() error          { m.settled = "term"; return nil }
+
+func (m *fakeMsg) Metadata() (*jetstream.MsgMetadata, error) {
+	if m.meta == nil {
+		return nil, errors.New("not a JetStream message")
+	}
+	return m.meta, nil
+}
+
+// fakeJetStream records the messages published, or fails with err.
+type fakeJetStream struct {
+	jetstream.JetStream
+	published []*nats.Msg
+	err       error
+}
+
+func (js *fakeJetStream) PublishMsg(ctx context.Context, msg *nats.Msg, opts ...jetstream.PublishOpt) (*jetstream.PubAck, error) {
+	if js.err != nil {
+		return nil, js.err
+	}
+	js.published = append(js.published, msg)
+	return &jetstream.PubAck{}, nil
+}
+
+func TestLoadIngestConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    *ingestConfig
+		wantErr bool
+	}{
+		{name: "disabled", env: map[string]string{}},
+		{
+			name: "defaults",
+			env:  map[string]string{"EVENT_BROKER": "nats"},
+			want: &ingestConfig{
+				url:               nats.DefaultURL,
+				stream:            "ANALYTICS_EVENTS",
+				subject:           "analytics.events",
+				deadLetterSubject: "analytics.events.dead",
+				batchSize:         100,
+				flushInterval:     time.Second,
+			},
+		},
+		{
+			name: "overridden",
+			env: map[string]string{
+				"EVENT_BROKER":              "nats",
+				"NATS_URL":                  "nats://broker:4222",
+				"EVENT_STREAM":              "EVENTS",
+				"EVENT_SUBJECT":             "events",
+				"EVENT_DEAD_LETTER_SUBJECT": "events.dead",
+				"EVENT_BATCH_SIZE":          "10",
+				"EVENT_FLUSH_INTERVAL":      "250ms",
+			},
+			want: &ingestConfig{
+				url:               "nats://broker:4222",
+				stream:            "EVENTS",
+				subject:           "events",
+				deadLetterSubject: "events.dead",
+				batchSize:         10,
+				flushInterval:     250 * time.Millisecond,
+			},
+		},
+		{name: "unknown broker", env: map[string]string{"EVENT_BROKER": "kafka"}, wantErr: true},
+		{name: "zero batch", env: map[string]string{"EVENT_BROKER": "nats", "EVENT_BATCH_SIZE": "0"}, wantErr: true},
+		{name: "batch too large", env: map[string]string{"EVENT_BROKER": "nats", "EVENT_BATCH_SIZE": "100000"}, wantErr: true},
+		{name: "bad interval", env: map[string]string{"EVENT_BROKER": "nats", "EVENT_FLUSH_INTERVAL": "soon"}, wantErr: true},
+		{name: "negative interval", env: map[string]string{"EVENT_BROKER": "nats", "EVENT_FLUSH_INTERVAL": "-1s"}, wantErr: true},
+		{
+			name:    "dead letters on the event subject",
+			env:     map[string]string{"EVENT_BROKER": "nats", "EVENT_SUBJECT": "events", "EVENT_DEAD_LETTER_SUBJECT": "events"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"EVENT_BROKER", "NATS_URL", "EVENT_STREAM", "EVENT_SUBJECT", "EVENT_DEAD_LETTER_SUBJECT", "EVENT_BATCH_SIZE", "EVENT_FLUSH_INTERVAL"} {
+				// Setenv restores the variable afterwards; getEnv tells unset from empty
+				t.Setenv(key, tt.env[key])
+				if _, ok := tt.env[key]; !ok {
+					os.Unsetenv(key)
+				}
+			}
+			got, err := loadIngestConfig()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadIngestConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			switch {
+			case tt.want == nil && got != nil:
+				t.Errorf("loadIngestConfig() = %+v, want nil", got)
+			case tt.want != nil && (got == nil || *got != *tt.want):
+				t.Errorf("loadIngestConfig() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMessageID(t *testing.T) {
+	sequenced := newFakeMsg("{}", "")
+	sequenced.meta = &jetstream.MsgMetadata{Stream: "EVENTS", Sequence: jetstream.SequencePair{Stream: 42}}
+	both := newFakeMsg("{}", "client-1")
+	both.meta = sequenced.meta
+
+	tests := []struct {
+		name string
+		msg  *fakeMsg
+		want string
+	}{
+		{"publisher id", newFakeMsg("{}", "client-1"), "client-1"},
+		{"publisher id over sequence", both, "client-1"},
+		{"stream sequence", sequenced, "EVENTS:42"},
+		{"neither", newFakeMsg("{}", ""), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := messageID(tt.msg); got != tt.want {
+				t.Errorf("messageID() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIngestBatchDeadLetters(t *testing.T) {
+	cfg := &ingestConfig{subject: "events", deadLetterSubject: "events.dead"}
+	tests := []struct {
+		name   string
+		data   string
+		reason string
+	}{
+		{"not json", "not json", "malformed event: "},
+		{"wrong field type", `{"user_id": 7}`, "malformed event: "},
+		{"missing fields", `{"event_type": "app_opened"}`, "user_id and event_type are required"},
+		{"unknown type", `{"user_id": "u1", "event_type": "nope"}`, ""},
+		{"bad timestamp", `{"user_id": "u1", "event_type": "app_opened", "client_timestamp": "yesterday"}`, "client_timestamp must be an RFC3339 timestamp"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &server{statsService: newTestService(newFakeRepository(), nil)}
+			js := &fakeJetStream{}
+			msg := newFakeMsg(tt.data, "m1")
+			s.ingestBatch(context.Background(), js, cfg, []jetstream.Msg{msg})
+
+			if msg.settled != "term" {
+				t.Errorf("message was settled with %q, want term", msg.settled)
+			}
+			if len(js.published) != 1 {
+				t.Fatalf("published %d dead letters, want 1", len(js.published))
+			}
+			dead := js.published[0]
+			if dead.Subject != cfg.deadLetterSubject || string(dead.Data) != tt.data {
+				t.Errorf("dead letter = %s %q, want %s %q", dead.Subject, dead.Data, cfg.deadLetterSubject, tt.data)
+			}
+			if dead.Header.Get(jetstream.MsgIDHeader) != "m1" {
+				t.Errorf("dead letter lost its headers: %v", dead.Header)
+			}
+			if reason := dead.Header.Get(ingestErrorHeader); reason == "" || !strings.HasPrefix(reason, tt.reason) {
+				t.Errorf("%s = %q, want it to start with %q", ingestErrorHeader, reason, tt.reason)
+			}
+		})
+	}
+}
+
+func TestIngestBatchDeadLetterUnpublished(t *testing.T) {
+	s := &server{statsService: newTestService(newFakeRepository(), nil)}
+	js := &fakeJetStream{err: errors.New("broker gone")}
+	msg := newFakeMsg("not json", "")
+	s.ingestBatch(context.Background(), js, &ingestConfig{deadLetterSubject: "events.dead"}, []jetstream.Msg{msg})
+
+	// Dropping it now would lose it, so it waits for redelivery
+	if msg.settled != "nak" {
+		t.Errorf("message was settled with %q, want nak", msg.settled)
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// Tasks completed more than a year after creation are left out of latency
+// stats, so long-forgotten tasks do not swamp the averages.
+const maxCompletionLatency = 365 * 24 * time.Hour
+
+// completionLatency summarises how long tasks took to complete.
+type completionLatency struct {
+	Count int32   `bson:"count"`
+	Avg   float64 `bson:"avg_seconds"`
+	P50   float64 `bson:"p50_seconds"`
+	P90   float64 `bson:"p90_seconds"`
+}
+
+func (c completionLatency) toProto() *pb.CompletionLatency {
+	return &pb.CompletionLatency{
+		Count:      c.Count,
+		AvgSeconds: c.Avg,
+		P50Seconds: c.P50,
+		P90Seconds: c.P90,
+	}
+}
+
+func (s *server) GetCompletionLatency(ctx context.Context, req *pb.GetCompletionLatencyRequest) (*pb.GetCompletionLatencyResponse, error) {
+	dates, err := parseDateRange(req.StartDate, req.EndDate, time.UTC)
+	if err != nil {
+		return nil, err
+	}
+
+	latency, err := s.stats.CompletionLatency(ctx, req.UserId, dates)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.GetCompletionLatencyResponse{Latency: latency.toProto()}, nil
+}
+
+// CompletionLatency measures tasks completed in r by pairing each
+// task.completed event with its task's task.created event. userID is
+// optional.
+func (m *mongoRepository) CompletionLatency(ctx context.Context, userID string, r dateRange) (completionLatency, error) {
+	if m.percentiles {
+		return m.aggregateLatency(ctx, userID, r)
+	}
+	return m.sortedLatency(ctx, userID, r)
+}
+
+// supportsPercentile reports whether the server has the $percentile
+// accumulator, added in MongoDB 7. Without it percentiles are computed here.
+func supportsPercentile(ctx context.Context, client *mongo.Client) (bool, error) {
+	var info struct {
+		VersionArray []int32 `bson:"versionArray"`
+	}
+	if err := client.Database("admin").RunCommand(ctx, bson.M{"buildInfo": 1}).Decode(&info); err != nil {
+		return false, err
+	}
+	return percentileVersion(info.VersionArray), nil
+}
+
+// percentileVersion reports whether a server version has $percentile.
+func percentileVersion(version []int32) bool {
+	return len(version) > 0 && version[0] >= 7
+}
+
+// latencyPipeline yields one {seconds, weight} document per measured
+// completion, weight being the sample rate of the completion event.
+func latencyPipeline(events, userID string, r dateRange) []bson.M {
+	match := eventsIn(r)
+	match["event_type"] = "task.completed"
+	if userID != "" {
+		match["user_id"] = userID
+	}
+
+	return []bson.M{
+		{"$match": match},
+		{"$lookup": bson.M{
+			"from":         events,
+			"localField":   "resource_id",
+			"foreignField": "resource_id",
+			"pipeline": bson.A{
+				bson.M{"$match": bson.M{"event_type": "task.created"}},
+				bson.M{"$project": bson.M{"created_at": 1}},
+				bson.M{"$limit": 1},
+			},
+			"as": "created",
+		}},
+		{"$unwind": "$created"},
+		{"$project": bson.M{
+			"_id":    0,
+			"weight": eventWeight,
+			"seconds": bson.M{"$divide": bson.A{
+				bson.M{"$subtract": bson.A{toDate("$created_at"), toDate("$created.created_at")}},
+				1000,
+			}},
+		}},
+		{"$match": bson.M{"seconds": bson.M{"$gte": 0, "$lte": maxCompletionLatency.Seconds()}}},
+	}
+}
+
+func (m *mongoRepository) aggregateLatency(ctx context.Context, userID string, r dateRange) (completionLatency, error) {
+	pipeline := append(latencyPipeline(m.events.Name(), userID, r), bson.M{"$group": bson.M{
+		"_id":   nil,
+		"count": bson.M{"$sum": "$weight"},
+		"avg":   bson.M{"$avg": "$seconds"},
+		"percentiles": bson.M{"$percentile": bson.M{
+			"input":  "$seconds",
+			"p":      bson.A{0.5, 0.9},
+			"method": "approximate",
+		}},
+	}})
+
+	cursor, err := m.events.Aggregate(ctx, pipeline)
+	if err != nil {
+		return completionLatency{}, err
+	}
+	defer cursor.Close(ctx)
+
+	var result completionLatency
+	if cursor.Next(ctx) {
+		var row struct {
+			Count       int32     `bson:"count"`
+			Avg         float64   `bson:"avg"`
+			Percentiles []float64 `bson:"percentiles"`
+		}
+		if err := cursor.Decode(&row); err != nil {
+			return completionLatency{}, err
+		}
+		result = completionLatency{Count: row.Count, Avg: row.Avg}
+		if len(row.Percentiles) == 2 {
+			result.P50, result.P90 = row.Percentiles[0], row.Percentiles[1]
+		}
+	}
+	return result, cursor.Err()
+}
+
+// sortedLatency computes the stats in Go from every measured completion.
+func (m *mongoRepository) sortedLatency(ctx context.Context, userID string, r dateRange) (completionLatency, error) {
+	cursor, err := m.events.Aggregate(ctx, latencyPipeline(m.events.Name(), userID, r))
+	if err != nil {
+		return completionLatency{}, err
+	}
+	defer cursor.Close(ctx)
+
+	var seconds []float64
+	var count int32
+	for cursor.Next(ctx) {
+		var row struct {
+			Seconds float64 `bson:"seconds"`
+			Weight  int32   `bson:"weight"`
+		}
+		if err := cursor.Decode(&row); err != nil {
+			return completionLatency{}, err
+		}
+		seconds = append(seconds, row.Seconds)
+		count += row.Weight
+	}
+	if err := cursor.Err(); err != nil {
+		return completionLatency{}, err
+	}
+	// Sampled completions stand for several, but their latencies are
+	// representative as they are
+	latency := summarizeLatency(seconds)
+	latency.Count = count
+	return latency, nil
+}
+
+// summarizeLatency returns the average and nearest-rank percentiles.
+func summarizeLatency(seconds []float64) completionLatency {
+	if len(seconds) == 0 {
+		return completionLatency{}
+	}
+	sort.Float64s(seconds)
+
+	var sum float64
+	for _, v := range seconds {
+		sum += v
+	}
+	percentile := func(p float64) float64 {
+		rank := int(math.Ceil(p*float64(len(seconds)))) - 1
+		if rank < 0 {
+			rank = 0
+		}
+		return seconds[rank]
+	}
+
+	return completionLatency{
+		Count: int32(len(seconds)),
+		Avg:   sum / float64(len(seconds)),
+		P50:   percentile(0.5),
+		P90:   percentile(0.9),
+	}
+}
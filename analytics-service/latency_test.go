@@ -0,0 +1,58 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestPercentileVersion(t *testing.T) {
+	tests := []struct {
+		version []int32
+		want    bool
+	}{
+		{nil, false},
+		{[]int32{6, 0, 14, 0}, false},
+		{[]int32{7, 0, 0, 0}, true},
+		{[]int32{8, 0, 4, 0}, true},
+	}
+	for _, tt := range tests {
+		if got := percentileVersion(tt.version); got != tt.want {
+			t.Errorf("percentileVersion(%v) = %v, want %v", tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestLatencyPipelineMatch(t *testing.T) {
+	r := dateRange{
+		start: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		end:   time.Date(2024, 3, 31, 23, 59, 59, 0, time.UTC),
+	}
+
+	tests := []struct {
+		name   string
+		userID string
+	}{
+		{"all users", ""},
+		{"one user", "u1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := eventsIn(r)
+			want["event_type"] = "task.completed"
+			if tt.userID != "" {
+				want["user_id"] = tt.userID
+			}
+
+			got := latencyPipeline("events", tt.userID, r)[0]["$match"]
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("$match = %v, want %v", got, want)
+			}
+			if _, ok := got.(bson.M)["created_at"]; !ok {
+				t.Error("$match does not bound created_at, so it cannot use an index")
+			}
+		})
+	}
+}
@@ -2,228 +2,436 @@ package main
 
 import (
 	"context"
-	"fmt"
-	"log"
+	"flag"
+	"log/slog"
 	"net"
+	"net/http"
 	"os"
+	"strconv"
 	"time"
+	_ "time/tzdata" // timezone-aware stats must not depend on the image shipping tzdata
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
-	"go.mongodb.org/mongo-driver/v2/bson"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/reflection"
 
+	"github.com/technonext/todo-app/proto/backup"
+	"github.com/technonext/todo-app/proto/buildinfo"
+	"github.com/technonext/todo-app/proto/diagnostics"
+	"github.com/technonext/todo-app/proto/faults"
+	"github.com/technonext/todo-app/proto/grpcmiddleware"
+	"github.com/technonext/todo-app/proto/logging"
+	"github.com/technonext/todo-app/proto/mongoutil"
 	pb "github.com/technonext/todo-app/proto/proto"
+	"github.com/technonext/todo-app/proto/serviceauth"
+	"github.com/technonext/todo-app/proto/tenant"
 )
 
 type server struct {
 	pb.UnimplementedAnalyticsServiceServer
-	collection     *mongo.Collection
-	taskCollection *mongo.Collection
+	*statsService
+	collection      *tenant.Collection
+	dailyStats      *tenant.Collection
+	userCounters    *tenant.Collection
+	statsDaily      *tenant.Collection
+	jobs            *tenant.Collection
+	snapshots       *tenant.Collection
+	weeklySummaries *tenant.Collection
+	// digestSchedules is only read for users' timezones, which the
+	// notification service keeps on their digest schedules
+	digestSchedules *tenant.Collection
+	users           pb.UserServiceClient
+	notifications   pb.NotificationServiceClient
+	engagement      *readCache
+	retention       retentionPolicy
+	// changeStreams enables StreamEvents
+	changeStreams bool
+	// taskCollection is only read by the backfill, to import tasks that
+	// predate event tracking, and for the reports on fields events do not
+	// carry, such as due dates and current priorities
+	taskCollection *tenant.Collection
 }
 
 type Event struct {
-	ID         primitive.ObjectID `bson:"_id,omitempty"`
-	UserID     string             `bson:"user_id"`
-	EventType  string             `bson:"event_type"`
-	ResourceID string             `bson:"resource_id"`
-	Metadata   string             `bson:"metadata"`
-	CreatedAt  string             `bson:"created_at"`
+	ID            primitive.ObjectID `bson:"_id,omitempty"`
+	UserID        string             `bson:"user_id"`
+	EventType     string             `bson:"event_type"`
+	ResourceID    string             `bson:"resource_id"`
+	Metadata      eventMetadata      `bson:"metadata,omitempty"`
+	ClientEventID string             `bson:"client_event_id,omitempty"`
+	MessageID     string             `bson:"message_id,omitempty"`
+	SampleRate    int32              `bson:"sample_rate,omitempty"`
+	CreatedAt     string             `bson:"created_at"`
+	// The tenant the event belongs to, empty for the default tenant
+	TenantID string `bson:"tenant_id,omitempty"`
+	// The version of the document's layout; see eventSchema
+	SchemaVersion int `bson:"schema_version,omitempty"`
 }
 
-func (s *server) TrackEvent(ctx context.Context, req *pb.TrackEventRequest) (*pb.TrackEventResponse, error) {
-	now := time.Now().Format(time.RFC3339)
-	event := Event{
-		UserID:     req.UserId,
-		EventType:  req.EventType,
-		ResourceID: req.ResourceId,
-		Metadata:   req.Metadata,
-		CreatedAt:  now,
+func (e Event) toProto() *pb.Event {
+	return &pb.Event{
+		Id:            e.ID.Hex(),
+		UserId:        e.UserID,
+		EventType:     e.EventType,
+		ResourceId:    e.ResourceID,
+		Metadata:      e.Metadata.toProto(),
+		CreatedAt:     e.CreatedAt,
+		ClientEventId: e.ClientEventID,
+		SampleRate:    e.SampleRate,
 	}
+}
+
+// eventIndexes are the indexes of the events collection. Backfill looks
+// events up by the task they describe, and stores at most one event per task
+// and type even when backfills run concurrently;
+// active-user counts scan a time range; client event ids make batched
+// retries idempotent, and broker message ids make redelivered messages
+// idempotent. Indexes serving calls lead with the tenant, which every call's
+// queries match on; the backfill and message id indexes keep events unique
+// across tenants
+var eventIndexes = []mongo.IndexModel{
+	{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "resource_id", Value: 1}, {Key: "event_type", Value: 1}}},
+	{
+		Keys: bson.D{{Key: "resource_id", Value: 1}, {Key: "event_type", Value: 1}, {Key: "metadata.source", Value: 1}},
+		Options: options.Index().
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{"metadata.source": backfillSource}),
+	},
+	{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "created_at", Value: 1}, {Key: "user_id", Value: 1}}},
+	{
+		Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "user_id", Value: 1}, {Key: "client_event_id", Value: 1}},
+		Options: options.Index().
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{"client_event_id": bson.M{"$exists": true}}),
+	},
+	{
+		Keys:    bson.D{{Key: "message_id", Value: 1}},
+		Options: options.Index().SetUnique(true).SetSparse(true),
+	},
+}
+
+func main() {
+	migrateOnly := flag.Bool("migrate", false, "apply pending data migrations and exit")
+	dryRun := flag.Bool("dry-run", false, "with -migrate, report what the pending migrations would change without applying them")
+	flag.Parse()
+	logging.Setup("analytics-service")
 
-	result, err := s.collection.InsertOne(ctx, event)
+	mongoConfig, err := mongoutil.ConfigFromEnv()
 	if err != nil {
-		log.Printf("Failed to track event: %v", err)
-		return nil, err
+		logging.Fatal("Invalid MongoDB settings", "error", err)
 	}
-
-	oid, ok := result.InsertedID.(primitive.ObjectID)
-	if !ok {
-		log.Printf("Failed to convert ObjectID")
-		return nil, err
+	cfg := loadConfig(mongoConfig)
+	if err := validateConfig(cfg); err != nil {
+		logging.Fatal("Invalid configuration", "error", err)
+	}
+	backupStore, err := backup.FromEnv(context.Background())
+	if err != nil {
+		logging.Fatal("Invalid configuration", "error", err)
+	}
+	analytics := &server{}
+	var repo interface {
+		EventsRepository
+		StatsRepository
 	}
+	var windows rateWindows
+	var diagnosticsHandler http.Handler
+	var backups *backup.Server
+	if cfg.StorageDriver == storageMemory {
+		if *migrateOnly {
+			return
+		}
+		slog.Warn("Events are kept in memory and lost when the service stops")
+		repo, windows = newMemoryRepository(), &memoryWindows{}
+	} else {
+		slog.Info("Connecting to MongoDB")
+		client, err := mongoutil.Connect(context.Background(), mongoConfig)
+		if err != nil {
+			logging.Fatal("Failed to connect to MongoDB", "error", err)
+		}
+		defer client.Disconnect(context.Background())
 
-	return &pb.TrackEventResponse{
-		Event: &pb.Event{
-			Id:         oid.Hex(),
-			UserId:     event.UserID,
-			EventType:  event.EventType,
-			ResourceId: event.ResourceID,
-			Metadata:   event.Metadata,
-			CreatedAt:  event.CreatedAt,
-		},
-	}, nil
-}
+		if *migrateOnly {
+			if err := runMigrations(context.Background(), client.Database(mongoConfig.DatabaseName()), *dryRun, os.Stdout); err != nil {
+				logging.Fatal("Migration failed", "error", err)
+			}
+			return
+		}
+		warnPendingMigrations(context.Background(), client.Database(mongoConfig.DatabaseName()))
+		if backupStore != nil {
+			backups = backup.NewServer(client.Database(mongoConfig.DatabaseName()), backupStore)
+		}
 
-func (s *server) GetUserStats(ctx context.Context, req *pb.GetUserStatsRequest) (*pb.GetUserStatsResponse, error) {
-	// Parse date range
-	startDate := req.StartDate
-	endDate := req.EndDate
-	if startDate == "" {
-		startDate = time.Now().AddDate(0, -1, 0).Format(time.RFC3339) // Default to 1 month ago
-	}
-	if endDate == "" {
-		endDate = time.Now().Format(time.RFC3339) // Default to now
+		collection := client.Database(mongoConfig.DatabaseName()).Collection("events", eventSchema.Decoding(nil, Event{}))
+		if err := eventSchema.Check(context.Background(), collection); err != nil {
+			logging.Fatal("Database is newer than this release", "error", err)
+		}
+		// The task service may have migrated the tasks' dates from strings
+		taskCollection := client.Database(mongoConfig.DatabaseName()).Collection("tasks", mongoutil.DatesAsStrings())
+		dailyStats := client.Database(mongoConfig.DatabaseName()).Collection("user_daily_stats")
+		userCounters := client.Database(mongoConfig.DatabaseName()).Collection("user_counters")
+		statsDaily := client.Database(mongoConfig.DatabaseName()).Collection("stats_daily")
+		jobs := client.Database(mongoConfig.DatabaseName()).Collection("jobs")
+		snapshots := client.Database(mongoConfig.DatabaseName()).Collection("daily_user_stats")
+		streaks := client.Database(mongoConfig.DatabaseName()).Collection("user_streaks")
+		weeklySummaries := client.Database(mongoConfig.DatabaseName()).Collection("weekly_summaries")
+		digestSchedules := client.Database(mongoConfig.DatabaseName()).Collection("digest_schedules")
+		eventRateLimits := client.Database(mongoConfig.DatabaseName()).Collection("event_rate_limits")
+
+		err = mongoutil.EnsureIndexes(context.Background(), collection, eventIndexes...)
+		if err != nil {
+			logging.Fatal("Failed to create event indexes", "error", err)
+		}
+
+		err = mongoutil.EnsureIndexes(context.Background(), dailyStats, []mongo.IndexModel{
+			{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "day", Value: 1}}},
+			{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "day", Value: 1}}},
+		}...)
+		if err != nil {
+			logging.Fatal("Failed to create stats indexes", "error", err)
+		}
+
+		err = mongoutil.EnsureIndexes(context.Background(), statsDaily, mongo.IndexModel{
+			Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "day", Value: 1}},
+		})
+		if err != nil {
+			logging.Fatal("Failed to create rollup indexes", "error", err)
+		}
+
+		err = mongoutil.EnsureIndexes(context.Background(), snapshots, mongo.IndexModel{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "date", Value: -1}},
+			Options: options.Index().SetUnique(true),
+		})
+		if err != nil {
+			logging.Fatal("Failed to create snapshot indexes", "error", err)
+		}
+
+		err = mongoutil.EnsureIndexes(context.Background(), weeklySummaries, mongo.IndexModel{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "week", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		})
+		if err != nil {
+			logging.Fatal("Failed to create weekly summary indexes", "error", err)
+		}
+
+		// Expired rate limit windows are removed by MongoDB
+		err = mongoutil.EnsureIndexes(context.Background(), eventRateLimits, mongo.IndexModel{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		})
+		if err != nil {
+			logging.Fatal("Failed to create event rate limit indexes", "error", err)
+		}
+
+		changeStreams, err := loadChangeStreams(context.Background(), client)
+		if err != nil {
+			logging.Fatal("Invalid configuration", "error", err)
+		}
+
+		percentiles, err := supportsPercentile(context.Background(), client)
+		if err != nil {
+			logging.Fatal("Failed to read MongoDB version", "error", err)
+		}
+
+		stats := &mongoRepository{
+			events:       tenant.Scoped(collection),
+			dailyStats:   tenant.Scoped(dailyStats),
+			userCounters: tenant.Scoped(userCounters),
+			statsDaily:   tenant.Scoped(statsDaily),
+			snapshots:    tenant.Scoped(snapshots),
+			streaks:      tenant.Scoped(streaks),
+			jobs:         tenant.Scoped(jobs),
+			percentiles:  percentiles,
+		}
+		repo = stats
+		windows = mongoRateWindows{collection: eventRateLimits}
+		diagnosticsHandler = diagnostics.NewServer(collection)
+
+		analytics.collection = tenant.Scoped(collection)
+		analytics.dailyStats = stats.dailyStats
+		analytics.userCounters = stats.userCounters
+		analytics.statsDaily = stats.statsDaily
+		analytics.jobs = stats.jobs
+		analytics.snapshots = stats.snapshots
+		analytics.weeklySummaries = tenant.Scoped(weeklySummaries)
+		analytics.digestSchedules = tenant.Scoped(digestSchedules)
+		analytics.taskCollection = tenant.Scoped(taskCollection)
+		analytics.changeStreams = changeStreams
 	}
 
-	// Count total tasks
-	totalTasksFilter := bson.M{"user_id": req.UserId}
-	totalTasks, err := s.taskCollection.CountDocuments(ctx, totalTasksFilter)
+	// Overdue counts come from the task service
+	taskConn, err := grpcmiddleware.Dial(getEnv("TASK_SERVICE_ADDR", "localhost:50051"), "TASK_SERVICE_HMAC_SECRET")
 	if err != nil {
-		return nil, err
+		logging.Fatal("Failed to connect to task service", "error", err)
 	}
+	defer taskConn.Close()
 
-	// Count completed tasks
-	completedTasksFilter := bson.M{"user_id": req.UserId, "completed": true}
-	completedTasks, err := s.taskCollection.CountDocuments(ctx, completedTasksFilter)
+	// Engagement scores also read login and notification activity
+	userConn, err := grpcmiddleware.Dial(getEnv("USER_SERVICE_ADDR", "localhost:50052"), "USER_SERVICE_HMAC_SECRET")
 	if err != nil {
-		return nil, err
+		logging.Fatal("Failed to connect to user service", "error", err)
 	}
+	defer userConn.Close()
 
-	// Count pending tasks
-	pendingTasksFilter := bson.M{"user_id": req.UserId, "completed": false}
-	pendingTasks, err := s.taskCollection.CountDocuments(ctx, pendingTasksFilter)
+	notificationConn, err := grpcmiddleware.Dial(getEnv("NOTIFICATION_SERVICE_ADDR", "localhost:50053"), "NOTIFICATION_SERVICE_HMAC_SECRET")
 	if err != nil {
-		return nil, err
+		logging.Fatal("Failed to connect to notification service", "error", err)
 	}
+	defer notificationConn.Close()
 
-	// Count overdue tasks
-	now := time.Now().Format(time.RFC3339)
-	overdueTasksFilter := bson.M{
-		"user_id":   req.UserId,
-		"completed": false,
-		"due_date":  bson.M{"$lt": now},
+	eventTypes, err := loadEventTypes()
+	if err != nil {
+		logging.Fatal("Failed to load event types", "error", err)
 	}
-	overdueTasks, err := s.taskCollection.CountDocuments(ctx, overdueTasksFilter)
+
+	limits, err := loadEventLimiter(windows, eventTypes)
 	if err != nil {
-		return nil, err
+		logging.Fatal("Failed to load event controls", "error", err)
+	}
+	controlsReload, err := time.ParseDuration(getEnv("ANALYTICS_EVENT_CONTROLS_RELOAD", "30s"))
+	if err != nil || controlsReload <= 0 {
+		logging.Fatal("Invalid ANALYTICS_EVENT_CONTROLS_RELOAD", "value", os.Getenv("ANALYTICS_EVENT_CONTROLS_RELOAD"))
 	}
 
-	return &pb.GetUserStatsResponse{
-		Stats: &pb.UserStats{
-			TotalTasks:     int32(totalTasks),
-			CompletedTasks: int32(completedTasks),
-			PendingTasks:   int32(pendingTasks),
-			OverdueTasks:   int32(overdueTasks),
-		},
-	}, nil
-}
+	port := cfg.Port
+
+	lis, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		logging.Fatal("Failed to listen", "error", err)
+	}
 
-func (s *server) GetTaskStats(ctx context.Context, req *pb.GetTaskStatsRequest) (*pb.GetTaskStatsResponse, error) {
-	// Parse date range
-	startDate := req.StartDate
-	endDate := req.EndDate
-	if startDate == "" {
-		startDate = time.Now().AddDate(0, -1, 0).Format(time.RFC3339) // Default to 1 month ago
+	rollupInterval, err := time.ParseDuration(getEnv("STATS_ROLLUP_INTERVAL", "1h"))
+	if err != nil || rollupInterval <= 0 {
+		logging.Fatal("Invalid STATS_ROLLUP_INTERVAL", "value", os.Getenv("STATS_ROLLUP_INTERVAL"))
 	}
-	if endDate == "" {
-		endDate = time.Now().Format(time.RFC3339) // Default to now
+
+	snapshotHour, err := strconv.Atoi(getEnv("STATS_SNAPSHOT_HOUR", "0"))
+	if err != nil || snapshotHour < 0 || snapshotHour > 23 {
+		logging.Fatal("Invalid STATS_SNAPSHOT_HOUR: must be an hour from 0 to 23", "value", os.Getenv("STATS_SNAPSHOT_HOUR"))
 	}
 
-	// Count total tasks
-	totalTasksFilter := bson.M{}
-	totalTasks, err := s.taskCollection.CountDocuments(ctx, totalTasksFilter)
+	summaryHour, err := strconv.Atoi(getEnv("WEEKLY_SUMMARY_HOUR", "8"))
+	if err != nil || summaryHour < 0 || summaryHour > 23 {
+		logging.Fatal("Invalid WEEKLY_SUMMARY_HOUR: must be an hour from 0 to 23", "value", os.Getenv("WEEKLY_SUMMARY_HOUR"))
+	}
+	summaryLoc, err := parseTimezone(getEnv("WEEKLY_SUMMARY_TIMEZONE", "UTC"))
 	if err != nil {
-		return nil, err
+		logging.Fatal("Invalid WEEKLY_SUMMARY_TIMEZONE", "value", os.Getenv("WEEKLY_SUMMARY_TIMEZONE"))
 	}
 
-	// Count completed tasks
-	completedTasksFilter := bson.M{"completed": true}
-	completedTasks, err := s.taskCollection.CountDocuments(ctx, completedTasksFilter)
+	retention, err := loadRetentionPolicy()
 	if err != nil {
-		return nil, err
+		logging.Fatal("Invalid event retention settings", "error", err)
 	}
 
-	// Count active users (users with at least one task)
-	pipeline := mongo.Pipeline{
-		{{"$group", bson.D{{"_id", "$user_id"}}}},
-		{{"$count", "count"}},
+	cache, err := loadReadCache()
+	if err != nil {
+		logging.Fatal("Invalid configuration", "error", err)
 	}
-	cursor, err := s.taskCollection.Aggregate(ctx, pipeline)
+
+	ingest, err := loadIngestConfig()
 	if err != nil {
-		return nil, err
+		logging.Fatal("Invalid configuration", "error", err)
 	}
-	defer cursor.Close(ctx)
 
-	var result struct {
-		Count int32 `bson:"count"`
+	analytics.statsService = &statsService{
+		events:     repo,
+		stats:      repo,
+		tasks:      pb.NewTaskServiceClient(taskConn),
+		eventTypes: eventTypes,
+		limits:     limits,
+		cache:      cache,
+		bus:        newEventBus(),
 	}
-	activeUsers := int32(0)
-	if cursor.Next(ctx) {
-		if err := cursor.Decode(&result); err == nil {
-			activeUsers = result.Count
+	analytics.users = pb.NewUserServiceClient(userConn)
+	analytics.notifications = pb.NewNotificationServiceClient(notificationConn)
+	analytics.engagement = newReadCache(engagementCacheTTL, engagementCacheSize)
+	analytics.retention = retention
+	// The jobs work on the collections, so they only run on MongoDB
+	if cfg.StorageDriver == storageMongo {
+		go analytics.startRollups(context.Background(), rollupInterval)
+		go analytics.startStatsConsolidator(context.Background(), snapshotHour)
+		go analytics.startWeeklySummaries(context.Background(), weeklySummaryConfig{
+			hour:     summaryHour,
+			loc:      summaryLoc,
+			template: getEnv("WEEKLY_SUMMARY_TEMPLATE", "weekly_summary"),
+		})
+		if retention.age > 0 {
+			go analytics.startRetention(context.Background())
 		}
+		if ingest != nil {
+			go analytics.startIngest(context.Background(), ingest)
+		}
+	} else if ingest != nil {
+		slog.Warn("Events are only consumed from the broker with STORAGE_DRIVER=mongo")
 	}
-
-	return &pb.GetTaskStatsResponse{
-		Stats: &pb.TaskStats{
-			TotalTasks:     int32(totalTasks),
-			CompletedTasks: int32(completedTasks),
-			ActiveUsers:    activeUsers,
-		},
-	}, nil
-}
-
-func main() {
-	// Read the environment variables
-	mongoUser := os.Getenv("MONGO_USERNAME")
-	mongoPass := os.Getenv("MONGO_PASSWORD")
-	mongoHost := os.Getenv("MONGO_HOST")
-	if mongoUser == "" || mongoPass == "" || mongoHost == "" {
-		log.Fatal("Error: MONGO_USERNAME, MONGO_PASSWORD, and MONGO_HOST must be set")
+	if limits.path != "" {
+		go limits.startReloading(context.Background(), controlsReload, eventTypes)
 	}
-	// Build the connection string
-	mongoURI := fmt.Sprintf("mongodb://%s:%s@%s/todo_app?authSource=admin", mongoUser, mongoPass, mongoHost)
 
-	log.Printf("Connecting to MongoDB at %s...", mongoHost)
-	// Connect to MongoDB
-	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI(mongoURI))
+	auth, err := serviceauth.FromEnv("ANALYTICS_SERVICE_HMAC_SECRET")
 	if err != nil {
-		log.Fatalf("Failed to connect to MongoDB: %v", err)
+		logging.Fatal("Invalid configuration", "error", err)
 	}
-	defer client.Disconnect(context.Background())
-
-	// Check the connection
-	err = client.Ping(context.Background(), nil)
+	reflection, err := grpcmiddleware.ReflectionFromEnv()
 	if err != nil {
-		log.Fatalf("Failed to ping MongoDB: %v", err)
+		logging.Fatal("Invalid configuration", "error", err)
 	}
 
-	collection := client.Database("todo_app").Collection("events")
-	taskCollection := client.Database("todo_app").Collection("tasks")
-
-	// Get port from environment variable
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "50054"
+	requireTenant, err := tenant.RequiredFromEnv()
+	if err != nil {
+		logging.Fatal("Invalid configuration", "error", err)
 	}
-
-	lis, err := net.Listen("tcp", ":"+port)
+	injector, err := faults.FromEnv()
 	if err != nil {
-		log.Fatalf("Failed to listen: %v", err)
+		logging.Fatal("Invalid configuration", "error", err)
+	}
+	pprof, err := grpcmiddleware.PprofFromEnv()
+	if err != nil {
+		logging.Fatal("Invalid configuration", "error", err)
+	}
+	info := buildinfo.NewServer("analytics-service", cfg.Info())
+
+	var unary []grpc.UnaryServerInterceptor
+	opts := []grpc.ServerOption{grpc.ChainUnaryInterceptor(cache.unaryInterceptor)}
+	if cfg.StorageDriver == storageMemory {
+		unary = append(unary, memoryUnaryInterceptor)
+		opts = append(opts, grpc.ChainStreamInterceptor(memoryStreamInterceptor))
 	}
 
-	s := grpc.NewServer()
-	pb.RegisterAnalyticsServiceServer(s, &server{
-		collection:     collection,
-		taskCollection: taskCollection,
+	// Authentication runs first, so cached responses are only served to
+	// authenticated callers, and the tenant is known before the cache is read
+	s := grpcmiddleware.NewServer(grpcmiddleware.ServerConfig{
+		Auth:                 auth,
+		Reflection:           reflection,
+		RequireTenant:        requireTenant,
+		Faults:               injector,
+		Info:                 info,
+		MaxRecvMsgSize:       cfg.RecvMsgSize(),
+		MaxConcurrentStreams: cfg.StreamLimit(),
+		MaxInFlight:          cfg.InFlightLimit(),
+		Unary:                unary,
+	}, opts...)
+	pb.RegisterAnalyticsServiceServer(s, analytics)
+	backups.Register(s)
+
+	grpcmiddleware.ServeMetrics(grpcmiddleware.MetricsConfig{
+		Port:        os.Getenv("METRICS_PORT"),
+		Diagnostics: diagnosticsHandler,
+		Info:        info,
+		Pprof:       pprof,
 	})
-	reflection.Register(s)
 
-	log.Printf("Analytics service listening on port %s", port)
-	if err := s.Serve(lis); err != nil {
-		log.Fatalf("Failed to serve: %v", err)
+	slog.Info("Analytics service listening", "port", port, "version", buildinfo.Version, "storage", cfg.StorageDriver)
+	if err := s.Serve(injector.Listen(lis)); err != nil {
+		logging.Fatal("Failed to serve", "error", err)
+	}
+}
+
+func getEnv(key, fallback string) string {
+	if value, exists := os.LookupEnv(key); exists {
+		return value
 	}
+	return fallback
 }
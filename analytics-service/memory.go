@@ -0,0 +1,381 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+	"github.com/technonext/todo-app/proto/tenant"
+)
+
+// memoryRepository keeps events and counters in memory, answering the same
+// questions as mongoRepository by scanning them. It backs STORAGE_DRIVER=memory
+// for local development, where everything is lost when the service stops,
+// and the tests of statsService.
+type memoryRepository struct {
+	mu        sync.Mutex
+	events    []Event
+	daily     map[string]map[string][2]int32 // user, day: created, completed
+	open      map[string]int32
+	streaks   map[string]streak
+	snapshots map[string]*UserStatsSnapshot
+}
+
+func newMemoryRepository() *memoryRepository {
+	return &memoryRepository{
+		daily:     make(map[string]map[string][2]int32),
+		open:      make(map[string]int32),
+		streaks:   make(map[string]streak),
+		snapshots: make(map[string]*UserStatsSnapshot),
+	}
+}
+
+func (f *memoryRepository) InsertEvent(ctx context.Context, event Event) (primitive.ObjectID, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if id, ok := tenant.Scope(ctx); ok {
+		event.TenantID = id
+	}
+	for _, e := range f.events {
+		if event.ClientEventID != "" && e.UserID == event.UserID && e.ClientEventID == event.ClientEventID {
+			return primitive.NilObjectID, errEventExists
+		}
+	}
+	event.ID = primitive.NewObjectID()
+	f.events = append(f.events, event)
+	return event.ID, nil
+}
+
+func (f *memoryRepository) DeleteUserEvents(ctx context.Context, userID string) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var kept []Event
+	for _, e := range f.events {
+		if e.UserID != userID {
+			kept = append(kept, e)
+		}
+	}
+	deleted := int64(len(f.events) - len(kept))
+	f.events = kept
+	return deleted, nil
+}
+
+func (f *memoryRepository) AnonymizeUserEvents(ctx context.Context, userID, pseudonym string) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var anonymized int64
+	for i, e := range f.events {
+		if e.UserID == userID {
+			f.events[i].UserID, f.events[i].ClientEventID = pseudonym, ""
+			anonymized++
+		}
+	}
+	return anonymized, nil
+}
+
+func (f *memoryRepository) ApplyEvent(ctx context.Context, event Event) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if from, to := event.ResourceID, event.UserID; event.EventType == "task.ownership_migrated" && from != "" && from != to {
+		for day, counts := range f.daily[from] {
+			if f.daily[to] == nil {
+				f.daily[to] = make(map[string][2]int32)
+			}
+			moved := f.daily[to][day]
+			moved[0] += counts[0]
+			moved[1] += counts[1]
+			f.daily[to][day] = moved
+		}
+		f.open[to] += f.open[from]
+		delete(f.daily, from)
+		delete(f.open, from)
+		return nil
+	}
+	created, completed, open := counterDeltas(event)
+	weight := int32(event.weight())
+	at, err := time.Parse(time.RFC3339, event.CreatedAt)
+	if err != nil {
+		return err
+	}
+	day := at.UTC().Format(dayFormat)
+	if f.daily[event.UserID] == nil {
+		f.daily[event.UserID] = make(map[string][2]int32)
+	}
+	counts := f.daily[event.UserID][day]
+	counts[0] += int32(created) * weight
+	counts[1] += int32(completed) * weight
+	f.daily[event.UserID][day] = counts
+	f.open[event.UserID] += int32(open) * weight
+	return nil
+}
+
+func (f *memoryRepository) RangeTotals(ctx context.Context, userID string, r dateRange) (created, completed int32, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	start, end := r.start.UTC().Format(dayFormat), r.end.UTC().Format(dayFormat)
+	for day, counts := range f.daily[userID] {
+		if day >= start && day <= end {
+			created += counts[0]
+			completed += counts[1]
+		}
+	}
+	return created, completed, nil
+}
+
+func (f *memoryRepository) DailyCompletions(ctx context.Context, userID string, r dateRange) (map[string]int32, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	start, end := r.start.UTC().Format(dayFormat), r.end.UTC().Format(dayFormat)
+	completions := make(map[string]int32)
+	for day, counts := range f.daily[userID] {
+		if day >= start && day <= end {
+			completions[day] = counts[1]
+		}
+	}
+	return completions, nil
+}
+
+func (f *memoryRepository) OpenTasks(ctx context.Context, userID string) (int32, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if open := f.open[userID]; open > 0 {
+		return open, nil
+	}
+	return 0, nil
+}
+
+// inRange reports whether the event happened within r.
+func inRange(e Event, r dateRange) bool {
+	at, err := time.Parse(time.RFC3339, e.CreatedAt)
+	return err == nil && !at.Before(r.start) && !at.After(r.end)
+}
+
+func (f *memoryRepository) OnTimeCompletions(ctx context.Context, userID string, r dateRange) (onTime, dueDated int32, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, e := range f.events {
+		if e.UserID != userID || e.EventType != "task.completed" || !inRange(e, r) {
+			continue
+		}
+		if v, ok := e.Metadata["on_time"].(bool); ok {
+			dueDated += int32(e.weight())
+			if v {
+				onTime += int32(e.weight())
+			}
+		}
+	}
+	return onTime, dueDated, nil
+}
+
+func (f *memoryRepository) DueDateExtensions(ctx context.Context, userID string, r dateRange) (int32, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var extensions int32
+	for _, e := range f.events {
+		if e.UserID == userID && e.EventType == "task.due_date_extended" && inRange(e, r) {
+			extensions += int32(e.weight())
+		}
+	}
+	return extensions, nil
+}
+
+func (f *memoryRepository) Streak(ctx context.Context, userID string, loc *time.Location) (streak, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	seen := make(map[string]bool)
+	var days []string
+	for _, e := range f.events {
+		if e.UserID != userID || e.EventType != "task.completed" {
+			continue
+		}
+		at, err := time.Parse(time.RFC3339, e.CreatedAt)
+		if err != nil {
+			continue
+		}
+		if day := at.In(loc).Format(dayFormat); !seen[day] {
+			seen[day] = true
+			days = append(days, day)
+		}
+	}
+	sort.Strings(days)
+	return streakOf(days, time.Now().In(loc).Format(dayFormat)), nil
+}
+
+func (f *memoryRepository) SavedStreak(ctx context.Context, userID string, loc *time.Location) (*streak, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	st, ok := f.streaks[streakID(userID, loc)]
+	if !ok {
+		return nil, nil
+	}
+	return &st, nil
+}
+
+func (f *memoryRepository) SaveStreak(ctx context.Context, userID string, loc *time.Location, st streak) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.streaks[streakID(userID, loc)] = st
+	return nil
+}
+
+func (f *memoryRepository) CompletionLatency(ctx context.Context, userID string, r dateRange) (completionLatency, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	createdAt := make(map[string]time.Time)
+	for _, e := range f.events {
+		if e.EventType == "task.created" {
+			createdAt[e.ResourceID], _ = time.Parse(time.RFC3339, e.CreatedAt)
+		}
+	}
+	var seconds []float64
+	for _, e := range f.events {
+		if e.EventType != "task.completed" || (userID != "" && e.UserID != userID) || !inRange(e, r) {
+			continue
+		}
+		created, ok := createdAt[e.ResourceID]
+		if !ok {
+			continue
+		}
+		completed, _ := time.Parse(time.RFC3339, e.CreatedAt)
+		if d := completed.Sub(created); d >= 0 && d <= maxCompletionLatency {
+			seconds = append(seconds, d.Seconds())
+		}
+	}
+	return summarizeLatency(seconds), nil
+}
+
+func (f *memoryRepository) LatestSnapshot(ctx context.Context, userID string) (*UserStatsSnapshot, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.snapshots[userID], nil
+}
+
+func (f *memoryRepository) SaveSnapshot(ctx context.Context, snapshot UserStatsSnapshot) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.snapshots[snapshot.UserID] = &snapshot
+	return nil
+}
+
+func (f *memoryRepository) GlobalTotals(ctx context.Context, r dateRange) (globalTotals, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	start, end := r.start.UTC().Format(dayFormat), r.end.UTC().Format(dayFormat)
+	var totals globalTotals
+	for _, days := range f.daily {
+		for day, counts := range days {
+			if day < start || day > end {
+				continue
+			}
+			totals.Created += counts[0]
+			totals.Completed += counts[1]
+			if counts[0] > 0 {
+				totals.ActiveUsers++
+			}
+		}
+	}
+	return totals, nil
+}
+
+func (f *memoryRepository) DeleteUserStats(ctx context.Context, userID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.daily, userID)
+	delete(f.open, userID)
+	delete(f.snapshots, userID)
+	return nil
+}
+
+func (f *memoryRepository) AnonymizeUserStats(ctx context.Context, userID, pseudonym string) error {
+	f.mu.Lock()
+	if daily, ok := f.daily[userID]; ok {
+		f.daily[pseudonym] = daily
+	}
+	f.mu.Unlock()
+	return f.DeleteUserStats(ctx, userID)
+}
+
+// memoryWindows keeps rate limit windows in a map, dropping expired ones as
+// new events are counted, as MongoDB's TTL index does.
+type memoryWindows struct {
+	mu      sync.Mutex
+	counts  map[string]int
+	expires map[string]time.Time
+}
+
+func (m *memoryWindows) add(ctx context.Context, id string, expires time.Time, n int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.counts == nil {
+		m.counts, m.expires = make(map[string]int), make(map[string]time.Time)
+	}
+	now := time.Now()
+	for other, at := range m.expires {
+		if at.Before(now) {
+			delete(m.counts, other)
+			delete(m.expires, other)
+		}
+	}
+	m.counts[id] += n
+	m.expires[id] = expires
+	return m.counts[id], nil
+}
+
+func (m *memoryWindows) deleteUser(ctx context.Context, userID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id := range m.counts {
+		if strings.HasPrefix(id, userID+":") {
+			delete(m.counts, id)
+			delete(m.expires, id)
+		}
+	}
+	return nil
+}
+
+// memoryMethods are the AnalyticsService methods served with
+// STORAGE_DRIVER=memory, those statsService covers. The rest, and the
+// background jobs, work on the MongoDB collections.
+var memoryMethods = map[string]bool{
+	pb.AnalyticsService_TrackEvent_FullMethodName:               true,
+	pb.AnalyticsService_GetUserStats_FullMethodName:             true,
+	pb.AnalyticsService_GetTaskStats_FullMethodName:             true,
+	pb.AnalyticsService_GetUserStreak_FullMethodName:            true,
+	pb.AnalyticsService_GetBurndown_FullMethodName:              true,
+	pb.AnalyticsService_CompareUsersProductivity_FullMethodName: true,
+	pb.AnalyticsService_GetCompletionForecast_FullMethodName:    true,
+	pb.AnalyticsService_GetCompletionLatency_FullMethodName:     true,
+	pb.AnalyticsService_GetCacheStats_FullMethodName:            true,
+	pb.AnalyticsService_SubscribeToEvents_FullMethodName:        true,
+}
+
+// checkMemoryMethod refuses the AnalyticsService methods not in
+// memoryMethods.
+func checkMemoryMethod(method string) error {
+	if !strings.HasPrefix(method, "/todo.AnalyticsService/") || memoryMethods[method] {
+		return nil
+	}
+	return statusError(codes.Unimplemented, "UNSUPPORTED_BY_STORAGE", map[string]string{"method": method, "storage_driver": storageMemory},
+		"%s needs STORAGE_DRIVER=%s", method, storageMongo)
+}
+
+func memoryUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := checkMemoryMethod(info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func memoryStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := checkMemoryMethod(info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// eventMetadata is stored as a sub-document so aggregations can group by its
+// fields. Events tracked before that stored metadata as a JSON string; those
+// still decode, as the parsed object or as {"value": <string>}.
+type eventMetadata map[string]interface{}
+
+func (m *eventMetadata) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	switch t {
+	case bsontype.Null, bsontype.Undefined:
+		*m = nil
+		return nil
+	case bsontype.String:
+		var legacy string
+		if err := bson.UnmarshalValue(t, data, &legacy); err != nil {
+			return err
+		}
+		return m.fromLegacy(legacy)
+	case bsontype.EmbeddedDocument:
+		// Round-trip through relaxed extended JSON so nested documents become
+		// plain maps that structpb accepts
+		ext, err := bson.MarshalExtJSON(bson.Raw(data), false, false)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(ext, (*map[string]interface{})(m))
+	}
+	return fmt.Errorf("cannot decode %s into event metadata", t)
+}
+
+func (m *eventMetadata) fromLegacy(s string) error {
+	*m = nil
+	if s == "" {
+		return nil
+	}
+	var parsed map[string]interface{}
+	if json.Unmarshal([]byte(s), &parsed) == nil {
+		*m = parsed
+		return nil
+	}
+	*m = eventMetadata{"value": s}
+	return nil
+}
+
+func (m eventMetadata) toProto() *structpb.Struct {
+	if len(m) == 0 {
+		return nil
+	}
+	s, err := structpb.NewStruct(m)
+	if err != nil {
+		return nil
+	}
+	return s
+}
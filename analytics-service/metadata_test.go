@@ -0,0 +1,94 @@
+package main
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestEventMetadataRoundTrip(t *testing.T) {
+	metadata, err := structpb.NewStruct(map[string]interface{}{
+		"priority": "HIGH",
+		"labels":   []interface{}{"work", "urgent"},
+		"client": map[string]interface{}{
+			"app":     "ios",
+			"version": 2.5,
+			"beta":    true,
+			"screen":  map[string]interface{}{"width": 390.0, "dark": false},
+		},
+		"note": nil,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := bson.Marshal(Event{UserID: "u1", EventType: "app_opened", Metadata: metadata.AsMap()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Stored as documents, so aggregations can group by nested fields
+	raw := bson.Raw(data)
+	if got := raw.Lookup("metadata").Type; got != bsontype.EmbeddedDocument {
+		t.Errorf("metadata stored as %v, want a document", got)
+	}
+	if app, ok := raw.Lookup("metadata", "client", "app").StringValueOK(); !ok || app != "ios" {
+		t.Errorf("metadata.client.app = %v, want ios", raw.Lookup("metadata", "client", "app"))
+	}
+
+	var event Event
+	if err := bson.Unmarshal(data, &event); err != nil {
+		t.Fatal(err)
+	}
+	if got := event.Metadata.toProto(); !proto.Equal(got, metadata) {
+		t.Errorf("metadata read back as %v, want %v", got, metadata)
+	}
+}
+
+func TestEventMetadataLegacy(t *testing.T) {
+	tests := []struct {
+		name     string
+		metadata interface{}
+		want     map[string]interface{}
+	}{
+		{"JSON object string", `{"priority":"HIGH","source":{"app":"web"}}`,
+			map[string]interface{}{"priority": "HIGH", "source": map[string]interface{}{"app": "web"}}},
+		{"plain string", "opened from the widget", map[string]interface{}{"value": "opened from the widget"}},
+		{"JSON array string", `["a","b"]`, map[string]interface{}{"value": `["a","b"]`}},
+		{"empty string", "", nil},
+		{"null", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := bson.Marshal(bson.M{"user_id": "u1", "event_type": "app_opened", "metadata": tt.metadata})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var event Event
+			if err := bson.Unmarshal(data, &event); err != nil {
+				t.Fatal(err)
+			}
+			want, err := structpb.NewStruct(tt.want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == nil {
+				want = nil
+			}
+			if got := event.Metadata.toProto(); !proto.Equal(got, want) {
+				t.Errorf("metadata = %v, want %v", got, want)
+			}
+		})
+	}
+
+	data, err := bson.Marshal(bson.M{"user_id": "u1", "event_type": "app_opened", "metadata": int32(7)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var event Event
+	if err := bson.Unmarshal(data, &event); err == nil {
+		t.Errorf("decoded metadata stored as a number into %v, want an error", event.Metadata)
+	}
+}
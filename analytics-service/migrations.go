@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"text/tabwriter"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/technonext/todo-app/proto/logging"
+	"github.com/technonext/todo-app/proto/migrate"
+	"github.com/technonext/todo-app/proto/publicid"
+	"github.com/technonext/todo-app/proto/schema"
+	"github.com/technonext/todo-app/proto/tenant"
+)
+
+// analyticsMigrations are the analytics service's data migrations over db,
+// applied with -migrate. Versions are never reused or reordered once
+// released.
+func analyticsMigrations(db *mongo.Database) []migrate.Migration {
+	return []migrate.Migration{
+		{
+			Version:     1,
+			Description: "task events name tasks by public_id",
+			Collection:  "events",
+			Filter: bson.M{
+				"event_type":  bson.M{"$regex": `^task\.`},
+				"resource_id": bson.M{"$regex": `^[0-9a-f]{24}$`},
+			},
+			Update:        taskPublicIDs(db.Collection("tasks")),
+			SchemaVersion: 1,
+		},
+		// Per-user documents stored before they were kept per tenant
+		{
+			Version:     2,
+			Description: "daily stats carry their user's tenant",
+			Collection:  "user_daily_stats",
+			Filter:      untenanted,
+			Update:      userTenants(db.Collection("events"), "user_id"),
+		},
+		{
+			Version:     3,
+			Description: "open task counters carry their user's tenant",
+			Collection:  "user_counters",
+			Filter:      untenanted,
+			Update:      userTenants(db.Collection("events"), "_id"),
+		},
+		{
+			Version:     4,
+			Description: "stats snapshots carry their user's tenant",
+			Collection:  "daily_user_stats",
+			Filter:      untenanted,
+			Update:      userTenants(db.Collection("events"), "user_id"),
+		},
+		{
+			Version:     5,
+			Description: "streaks carry their user's tenant",
+			Collection:  "user_streaks",
+			Filter:      untenanted,
+			Update:      userTenants(db.Collection("events"), "user_id"),
+		},
+		{
+			Version:     6,
+			Description: "weekly summary markers carry their user's tenant",
+			Collection:  "weekly_summaries",
+			Filter:      untenanted,
+			Update:      userTenants(db.Collection("events"), "user_id"),
+		},
+	}
+}
+
+// untenanted matches the documents stored without a tenant_id.
+var untenanted = bson.M{tenant.Field: bson.M{"$exists": false}}
+
+// eventSchema is the history of the layout of events, a version per
+// migration changing it; see package schema.
+var eventSchema = schema.Schema{
+	Collection: "events",
+	Upgrades: []schema.Upgrade{
+		// Task events by public id; those of tasks without one keep the
+		// ObjectID hex, which readers accept either way
+		nil,
+	},
+}
+
+// taskPublicIDs replaces the ObjectID hex in an event's resource_id with
+// the public id of the task in tasks. Events of tasks since deleted, or not
+// yet given a public id, are left alone: run the task service's backfill
+// first, and this one again to pick up what it missed.
+func taskPublicIDs(tasks *mongo.Collection) func(bson.Raw) (bson.M, error) {
+	// A task has several events, looked up once; ObjectID hex to public id,
+	// empty for tasks without one
+	known := make(map[string]string)
+	return func(doc bson.Raw) (bson.M, error) {
+		value := doc.Lookup("resource_id")
+		if value.Type != bsontype.String {
+			return nil, nil
+		}
+		hex := value.StringValue()
+		publicID, ok := known[hex]
+		if !ok {
+			oid, err := primitive.ObjectIDFromHex(hex)
+			if err != nil {
+				return nil, nil
+			}
+			var task struct {
+				PublicID string `bson:"public_id"`
+			}
+			opts := options.FindOne().SetProjection(bson.M{publicid.Field: 1})
+			err = tasks.FindOne(context.Background(), bson.M{"_id": oid}, opts).Decode(&task)
+			if err != nil && err != mongo.ErrNoDocuments {
+				return nil, err
+			}
+			publicID = task.PublicID
+			known[hex] = publicID
+		}
+		if publicID == "" {
+			return nil, nil
+		}
+		return bson.M{"$set": bson.M{"resource_id": publicID}}, nil
+	}
+}
+
+// userTenants sets a document's tenant_id to the tenant of the user in its
+// userField, taken from the user's latest event in events. Documents of the
+// default tenant's users, and of users without events, are left alone.
+func userTenants(events *mongo.Collection, userField string) func(bson.Raw) (bson.M, error) {
+	known := make(map[string]string)
+	return func(doc bson.Raw) (bson.M, error) {
+		userID, ok := doc.Lookup(userField).StringValueOK()
+		if !ok {
+			return nil, nil
+		}
+		tenantID, ok := known[userID]
+		if !ok {
+			var event Event
+			opts := options.FindOne().SetSort(bson.D{{Key: "_id", Value: -1}}).SetProjection(bson.M{tenant.Field: 1})
+			err := events.FindOne(context.Background(), bson.M{"user_id": userID}, opts).Decode(&event)
+			if err != nil && err != mongo.ErrNoDocuments {
+				return nil, err
+			}
+			tenantID = event.TenantID
+			known[userID] = tenantID
+		}
+		if tenantID == "" {
+			return nil, nil
+		}
+		return bson.M{"$set": bson.M{tenant.Field: tenantID}}, nil
+	}
+}
+
+// runMigrations applies the pending migrations, or with dryRun reports what
+// they would change, and writes a line per migration to w.
+func runMigrations(ctx context.Context, db *mongo.Database, dryRun bool, w io.Writer) error {
+	runner, err := migrate.New(db, "analytics-service", analyticsMigrations(db)...)
+	if err != nil {
+		return err
+	}
+	run := runner.Run
+	if dryRun {
+		run = runner.DryRun
+	}
+	results, err := run(ctx)
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tDESCRIPTION\tSCANNED\tCHANGED")
+	for _, r := range results {
+		fmt.Fprintf(tw, "%d\t%s\t%d\t%d\n", r.Version, r.Description, r.Scanned, r.Changed)
+	}
+	tw.Flush()
+	if len(results) == 0 && err == nil {
+		fmt.Fprintln(w, "No pending migrations")
+	}
+	return err
+}
+
+// warnPendingMigrations logs the migrations the database still needs.
+func warnPendingMigrations(ctx context.Context, db *mongo.Database) {
+	runner, err := migrate.New(db, "analytics-service", analyticsMigrations(db)...)
+	if err != nil {
+		logging.Fatal("Invalid migrations", "error", err)
+	}
+	pending, err := runner.Pending(ctx)
+	if err != nil {
+		slog.Warn("Failed to check for pending migrations", "error", err)
+		return
+	}
+	for _, m := range pending {
+		slog.Warn("Data migration pending, apply it with -migrate", "version", m.Version, "description", m.Description)
+	}
+}
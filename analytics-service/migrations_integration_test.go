@@ -0,0 +1,113 @@
+//go:build integration
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/technonext/todo-app/proto/publicid"
+	"github.com/technonext/todo-app/proto/tenant"
+)
+
+func TestResourceIDMigration(t *testing.T) {
+	stats := newMongoService(t, &fakeTaskClient{})
+	repo := stats.stats.(*mongoRepository)
+	db := repo.events.Database()
+	ctx := context.Background()
+
+	backfilled, pending := primitive.NewObjectID(), primitive.NewObjectID()
+	publicID := publicid.New(publicid.Task)
+	_, err := db.Collection("tasks").InsertMany(ctx, []interface{}{
+		bson.M{"_id": backfilled, publicid.Field: publicID, "user_id": "u1"},
+		bson.M{"_id": pending, "user_id": "u1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	deleted := primitive.NewObjectID().Hex()
+	_, err = repo.events.InsertMany(ctx, []interface{}{
+		bson.M{"user_id": "u1", "event_type": "task.created", "resource_id": backfilled.Hex()},
+		bson.M{"user_id": "u1", "event_type": "task.completed", "resource_id": backfilled.Hex()},
+		bson.M{"user_id": "u1", "event_type": "task.created", "resource_id": pending.Hex()},
+		bson.M{"user_id": "u1", "event_type": "task.created", "resource_id": deleted},
+		bson.M{"user_id": "u1", "event_type": "user.login", "resource_id": backfilled.Hex()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := runMigrations(ctx, db, false, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		filter bson.M
+		want   int64
+	}{
+		{bson.M{"resource_id": publicID, "event_type": bson.M{"$in": bson.A{"task.created", "task.completed"}}}, 2},
+		// Only task events name tasks
+		{bson.M{"resource_id": backfilled.Hex()}, 1},
+		// Tasks without a public id, or gone, keep their ObjectIDs
+		{bson.M{"resource_id": bson.M{"$in": bson.A{pending.Hex(), deleted}}}, 2},
+	} {
+		if n, err := repo.events.CountDocuments(ctx, tt.filter); err != nil || n != tt.want {
+			t.Errorf("%v: %d events (%v), want %d", tt.filter, n, err, tt.want)
+		}
+	}
+}
+
+func TestUserTenantMigration(t *testing.T) {
+	stats := newMongoService(t, &fakeTaskClient{})
+	repo := stats.stats.(*mongoRepository)
+	db := repo.events.Database()
+	ctx := context.Background()
+
+	_, err := db.Collection("events").InsertMany(ctx, []interface{}{
+		bson.M{"user_id": "a1", "event_type": "task.created", "resource_id": "t1", tenant.Field: "acme"},
+		bson.M{"user_id": "d1", "event_type": "task.created", "resource_id": "t2"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Collection("user_counters").InsertMany(ctx, []interface{}{
+		bson.M{"_id": "a1", "open_tasks": 1},
+		bson.M{"_id": "d1", "open_tasks": 1},
+		bson.M{"_id": "gone", "open_tasks": 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := runMigrations(ctx, db, false, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		tenant string
+		want   []string
+	}{
+		{"acme", []string{"a1"}},
+		{"", []string{"d1", "gone"}},
+	} {
+		ids, err := repo.userCounters.Distinct(tenant.WithID(ctx, tt.tenant), "_id", bson.M{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, id := range ids {
+			got = append(got, id.(string))
+		}
+		slices.Sort(got)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("tenant %q counters = %v, want %v", tt.tenant, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestDecodeEventVersions(t *testing.T) {
+	oid := primitive.NewObjectID()
+	// An event as each release stored it
+	tests := []struct {
+		name       string
+		doc        bson.M
+		resourceID string
+		version    int
+	}{
+		{"version 0, metadata as JSON text, tasks by ObjectID",
+			bson.M{"_id": oid, "user_id": "u1", "event_type": "task.created", "resource_id": "65f1c0ffee0123456789abcd", "metadata": `{"priority":"HIGH"}`},
+			"65f1c0ffee0123456789abcd", 1},
+		{"version 1, tasks by public id",
+			bson.M{"_id": oid, "user_id": "u1", "event_type": "task.created", "resource_id": "task_01HV4Z3K9Q8W5N2M7R6T1Y0XCB", "metadata": bson.M{"priority": "HIGH"}, "schema_version": 1},
+			"task_01HV4Z3K9Q8W5N2M7R6T1Y0XCB", 1},
+		{"version 2, from the next release",
+			bson.M{"_id": oid, "user_id": "u1", "event_type": "task.created", "resource_id": "task_01HV4Z3K9Q8W5N2M7R6T1Y0XCB", "metadata": bson.M{"priority": "HIGH"}, "session_id": "s1", "schema_version": 2},
+			"task_01HV4Z3K9Q8W5N2M7R6T1Y0XCB", 2},
+	}
+	registry := eventSchema.Decoding(nil, Event{}).Registry
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cursor, err := mongo.NewCursorFromDocuments([]interface{}{tt.doc}, nil, registry)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var events []Event
+			if err := cursor.All(context.Background(), &events); err != nil {
+				t.Fatal(err)
+			}
+			e := events[0]
+			if e.ID != oid || e.UserID != "u1" || e.EventType != "task.created" || e.ResourceID != tt.resourceID {
+				t.Errorf("event = %+v, want task.created of %s by u1", e, tt.resourceID)
+			}
+			if e.Metadata["priority"] != "HIGH" {
+				t.Errorf("metadata = %v, want priority HIGH", e.Metadata)
+			}
+			if e.SchemaVersion != tt.version {
+				t.Errorf("schema version = %d, want %d", e.SchemaVersion, tt.version)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"google.golang.org/grpc/codes"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+const (
+	defaultOverdueWeeks = 8
+	maxOverdueWeeks     = 52
+)
+
+// overdueBuckets are the ranges of whole days overdue GetOverdueAging
+// reports, both ends inclusive; the last is open-ended.
+var overdueBuckets = []*pb.OverdueBucket{
+	{Label: "1-3", MinDays: 1, MaxDays: 3},
+	{Label: "4-7", MinDays: 4, MaxDays: 7},
+	{Label: "8-30", MinDays: 8, MaxDays: 30},
+	{Label: "30+", MinDays: 31},
+}
+
+// overdueExpr matches tasks that existed and were due by at, but were
+// neither completed nor deleted by then. Tasks completed before completion
+// times were recorded fall back to updated_at.
+func overdueExpr(at time.Time) bson.M {
+	before := dateRange{start: time.Unix(0, 0), end: at}
+	completedAt := toDate(bson.M{"$ifNull": bson.A{"$completed_at", "$updated_at"}})
+
+	return bson.M{"$and": bson.A{
+		before.contains(toDate("$created_at")),
+		before.contains(toDate("$due_date")),
+		bson.M{"$not": bson.A{bson.M{"$and": bson.A{
+			"$completed",
+			before.contains(completedAt),
+		}}}},
+		bson.M{"$not": bson.A{bson.M{"$and": bson.A{
+			bson.M{"$eq": bson.A{"$is_deleted", true}},
+			before.contains(toDate("$deleted_at")),
+		}}}},
+	}}
+}
+
+// GetOverdueAging buckets the tasks overdue at as_of by how many days they
+// are overdue, and counts those overdue at the end of each of the weeks
+// before. It reads due dates from the task service's collection, which
+// events do not carry.
+func (s *server) GetOverdueAging(ctx context.Context, req *pb.GetOverdueAgingRequest) (*pb.GetOverdueAgingResponse, error) {
+	asOf := time.Now().UTC()
+	if req.AsOf != "" {
+		t, err := parseDate(req.AsOf, true, time.UTC)
+		if err != nil {
+			return nil, statusError(codes.InvalidArgument, "INVALID_AS_OF", map[string]string{"as_of": req.AsOf}, "invalid as_of %q: use RFC3339 or YYYY-MM-DD", req.AsOf)
+		}
+		asOf = t
+	}
+	weeks := req.Weeks
+	if weeks == 0 {
+		weeks = defaultOverdueWeeks
+	}
+	if weeks < 0 || weeks > maxOverdueWeeks {
+		return nil, statusError(codes.InvalidArgument, "INVALID_WEEKS", nil, "weeks must be between 1 and %d", maxOverdueWeeks)
+	}
+
+	match := bson.M{"due_date": bson.M{"$nin": bson.A{nil, ""}}}
+	if req.UserId != "" {
+		match["user_id"] = req.UserId
+	}
+
+	const day = float64(24 * time.Hour / time.Millisecond)
+	daysOverdue := bson.M{"$max": bson.A{1, bson.M{"$ceil": bson.M{"$divide": bson.A{
+		bson.M{"$subtract": bson.A{asOf, toDate("$due_date")}},
+		day,
+	}}}}}
+	var branches bson.A
+	for _, bucket := range overdueBuckets[:len(overdueBuckets)-1] {
+		branches = append(branches, bson.M{"case": bson.M{"$lte": bson.A{"$days", bucket.MaxDays}}, "then": bucket.Label})
+	}
+
+	facets := bson.M{
+		"buckets": bson.A{
+			bson.M{"$match": bson.M{"$expr": overdueExpr(asOf)}},
+			bson.M{"$project": bson.M{"days": daysOverdue}},
+			bson.M{"$group": bson.M{
+				"_id":   bson.M{"$switch": bson.M{"branches": branches, "default": overdueBuckets[len(overdueBuckets)-1].Label}},
+				"count": bson.M{"$sum": 1},
+			}},
+		},
+	}
+
+	// One sum per week in a single pass, oldest week first and the last
+	// ending at as_of
+	weekEnds := make([]time.Time, weeks)
+	weekly := bson.M{"_id": nil}
+	for i := range weekEnds {
+		weekEnds[i] = asOf.AddDate(0, 0, -7*(int(weeks)-1-i))
+		weekly[weekKey(i)] = bson.M{"$sum": bson.M{"$cond": bson.A{overdueExpr(weekEnds[i]), 1, 0}}}
+	}
+	facets["trend"] = bson.A{
+		bson.M{"$group": weekly},
+		bson.M{"$project": bson.M{"_id": 0}},
+	}
+
+	cursor, err := s.taskCollection.Aggregate(ctx, []bson.M{
+		{"$match": match},
+		{"$facet": facets},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var result struct {
+		Buckets []struct {
+			Label string `bson:"_id"`
+			Count int32  `bson:"count"`
+		} `bson:"buckets"`
+		Trend []map[string]int32 `bson:"trend"`
+	}
+	if cursor.Next(ctx) {
+		if err := cursor.Decode(&result); err != nil {
+			return nil, err
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int32)
+	for _, row := range result.Buckets {
+		counts[row.Label] = row.Count
+	}
+	resp := &pb.GetOverdueAgingResponse{AsOf: asOf.Format(time.RFC3339)}
+	for _, bucket := range overdueBuckets {
+		resp.Buckets = append(resp.Buckets, &pb.OverdueBucket{
+			Label:   bucket.Label,
+			MinDays: bucket.MinDays,
+			MaxDays: bucket.MaxDays,
+			Count:   counts[bucket.Label],
+		})
+		resp.Total += counts[bucket.Label]
+	}
+
+	trend := map[string]int32{}
+	if len(result.Trend) > 0 {
+		trend = result.Trend[0]
+	}
+	for i, end := range weekEnds {
+		resp.Trend = append(resp.Trend, &pb.OverdueTrendPoint{WeekEnd: end.Format(time.RFC3339), Overdue: trend[weekKey(i)]})
+	}
+	return resp, nil
+}
+
+func weekKey(i int) string {
+	return fmt.Sprintf("week%d", i)
+}
@@ -0,0 +1,80 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+	"github.com/technonext/todo-app/proto/tenant"
+)
+
+func TestMongoOverdueAgingBuckets(t *testing.T) {
+	repo := newMongoRepository(t)
+	tasks := repo.events.Database().Collection("tasks")
+	s := &server{taskCollection: tenant.Scoped(tasks)}
+	ctx := context.Background()
+
+	// as_of is pinned, so each task sits a known distance from a boundary
+	asOf := time.Date(2026, 3, 31, 23, 59, 59, 0, time.UTC)
+	created := asOf.AddDate(0, -3, 0).Format(time.RFC3339)
+	task := func(user string, overdue time.Duration, extra bson.M) bson.M {
+		doc := bson.M{"user_id": user, "created_at": created, "due_date": asOf.Add(-overdue).Format(time.RFC3339)}
+		for k, v := range extra {
+			doc[k] = v
+		}
+		return doc
+	}
+	day := 24 * time.Hour
+	docs := []interface{}{
+		task("u1", time.Minute, nil),        // 1-3: part of a day rounds up to 1
+		task("u1", 3*day, nil),              // 1-3
+		task("u1", 3*day+time.Minute, nil),  // 4-7
+		task("u1", 7*day, nil),              // 4-7
+		task("u1", 7*day+time.Minute, nil),  // 8-30
+		task("u1", 30*day, nil),             // 8-30
+		task("u1", 30*day+time.Minute, nil), // 30+
+		task("u1", -time.Minute, nil),       // due after as_of
+		task("u1", 2*day, bson.M{"completed": true, "completed_at": asOf.Add(-day).Format(time.RFC3339)}),
+		task("u1", 2*day, bson.M{"completed": true, "completed_at": asOf.Add(day).Format(time.RFC3339)}), // 1-3: still open at as_of
+		task("u1", 2*day, bson.M{"is_deleted": true, "deleted_at": asOf.Add(-day).Format(time.RFC3339)}),
+		task("u2", 2*day, nil), // 1-3 for all users only
+	}
+	if _, err := tasks.InsertMany(ctx, docs); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		userID     string
+		wantCounts map[string]int32
+	}{
+		{"one user", "u1", map[string]int32{"1-3": 3, "4-7": 2, "8-30": 2, "30+": 1}},
+		{"all users", "", map[string]int32{"1-3": 4, "4-7": 2, "8-30": 2, "30+": 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := s.GetOverdueAging(ctx, &pb.GetOverdueAgingRequest{UserId: tt.userID, AsOf: asOf.Format(time.RFC3339), Weeks: 2})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var total int32
+			for _, bucket := range resp.Buckets {
+				if bucket.Count != tt.wantCounts[bucket.Label] {
+					t.Errorf("bucket %s = %d, want %d", bucket.Label, bucket.Count, tt.wantCounts[bucket.Label])
+				}
+				total += tt.wantCounts[bucket.Label]
+			}
+			if resp.Total != total {
+				t.Errorf("total = %d, want %d", resp.Total, total)
+			}
+			if len(resp.Trend) != 2 || resp.Trend[1].WeekEnd != asOf.Format(time.RFC3339) || resp.Trend[1].Overdue != total {
+				t.Errorf("trend = %v, want 2 weeks ending at as_of with %d overdue", resp.Trend, total)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+func TestGetOverdueAgingValidation(t *testing.T) {
+	s := &server{}
+	tests := []struct {
+		name   string
+		req    *pb.GetOverdueAgingRequest
+		reason string
+	}{
+		{"invalid as_of", &pb.GetOverdueAgingRequest{AsOf: "tomorrow"}, "INVALID_AS_OF"},
+		{"negative weeks", &pb.GetOverdueAgingRequest{Weeks: -1}, "INVALID_WEEKS"},
+		{"too many weeks", &pb.GetOverdueAgingRequest{Weeks: maxOverdueWeeks + 1}, "INVALID_WEEKS"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.GetOverdueAging(context.Background(), tt.req)
+			if status.Code(err) != codes.InvalidArgument || errorReason(err) != tt.reason {
+				t.Errorf("err = %v, want %s", err, tt.reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/status"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// overviewSectionTimeout bounds each section of the system overview, so a
+// slow service only costs its own section.
+const overviewSectionTimeout = 3 * time.Second
+
+// GetSystemOverview summarises users, today's tasks and today's
+// notifications. Sections are gathered concurrently; one that fails or times
+// out is marked unavailable instead of failing the response.
+func (s *server) GetSystemOverview(ctx context.Context, req *pb.GetSystemOverviewRequest) (*pb.GetSystemOverviewResponse, error) {
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	monday := today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+
+	resp := &pb.GetSystemOverviewResponse{GeneratedAt: now.Format(time.RFC3339)}
+	var wg sync.WaitGroup
+	section := func(fill func(ctx context.Context) error, fail func(reason string)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, overviewSectionTimeout)
+			defer cancel()
+			if err := fill(ctx); err != nil {
+				fail(overviewError(ctx, err))
+			}
+		}()
+	}
+
+	section(func(ctx context.Context) error {
+		counts, err := s.users.GetUserCounts(ctx, &pb.GetUserCountsRequest{Since: monday.Format(time.RFC3339)})
+		if err != nil {
+			return err
+		}
+		resp.Users = &pb.OverviewUsers{Available: true, Total: counts.Total, SignupsThisWeek: counts.Signups}
+		return nil
+	}, func(reason string) { resp.Users = &pb.OverviewUsers{Error: reason} })
+
+	section(func(ctx context.Context) error {
+		totals, err := s.stats.GlobalTotals(ctx, dateRange{start: today, end: now})
+		if err != nil {
+			return err
+		}
+		resp.Tasks = &pb.OverviewTasks{Available: true, CreatedToday: totals.Created, CompletedToday: totals.Completed}
+		return nil
+	}, func(reason string) { resp.Tasks = &pb.OverviewTasks{Error: reason} })
+
+	section(func(ctx context.Context) error {
+		counts, err := s.notifications.GetNotificationCounts(ctx, &pb.GetNotificationCountsRequest{Since: today.Format(time.RFC3339)})
+		if err != nil {
+			return err
+		}
+		resp.Notifications = &pb.OverviewNotifications{Available: true, SentToday: counts.Sent, FailedWebhookDeliveries: counts.FailedWebhookDeliveries}
+		return nil
+	}, func(reason string) { resp.Notifications = &pb.OverviewNotifications{Error: reason} })
+
+	wg.Wait()
+	return resp, nil
+}
+
+// overviewError describes why a section is unavailable.
+func overviewError(ctx context.Context, err error) string {
+	if ctx.Err() == context.DeadlineExceeded {
+		return "timed out"
+	}
+	return status.Convert(err).Message()
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+func TestGetSystemOverview(t *testing.T) {
+	userCounts := &pb.GetUserCountsResponse{Total: 40, Signups: 3}
+	notificationCounts := &pb.GetNotificationCountsResponse{Sent: 12, FailedWebhookDeliveries: 2}
+	tests := []struct {
+		name              string
+		users             *fakeUserClient
+		notifications     *fakeNotificationClient
+		repoErr           error
+		wantUsers         string
+		wantTasks         string
+		wantNotifications string
+	}{
+		{"all available", &fakeUserClient{counts: userCounts}, &fakeNotificationClient{counts: notificationCounts}, nil, "", "", ""},
+		{"user service down", &fakeUserClient{err: status.Error(codes.Unavailable, "connection refused")}, &fakeNotificationClient{counts: notificationCounts}, nil, "connection refused", "", ""},
+		{"notification service hangs", &fakeUserClient{counts: userCounts}, &fakeNotificationClient{hang: true}, nil, "", "", "timed out"},
+		{"rollups fail", &fakeUserClient{counts: userCounts}, &fakeNotificationClient{counts: notificationCounts}, status.Error(codes.Internal, "rollup failed"), "", "rollup failed", ""},
+		{"everything down", &fakeUserClient{hang: true}, &fakeNotificationClient{err: status.Error(codes.Unavailable, "down")}, status.Error(codes.Internal, "rollup failed"), "timed out", "rollup failed", "down"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeRepository()
+			s := &server{statsService: newTestService(repo, &fakeTaskClient{}), users: tt.users, notifications: tt.notifications}
+			track(t, s.statsService, &pb.TrackEventRequest{UserId: "u1", EventType: "task.created", ResourceId: "a"})
+			if tt.repoErr != nil {
+				repo.err["GlobalTotals"] = tt.repoErr
+			}
+
+			// The hanging clients give up with the request, well before a
+			// section's own timeout
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			start := time.Now()
+			resp, err := s.GetSystemOverview(ctx, &pb.GetSystemOverviewRequest{})
+			if err != nil {
+				t.Fatalf("a failing section failed the overview: %v", err)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("took %s, want the hanging section cut off", elapsed)
+			}
+
+			check := func(section string, available bool, gotErr, wantErr string) {
+				if available != (wantErr == "") || gotErr != wantErr {
+					t.Errorf("%s: available, error = %v, %q, want %v, %q", section, available, gotErr, wantErr == "", wantErr)
+				}
+			}
+			check("users", resp.Users.Available, resp.Users.Error, tt.wantUsers)
+			check("tasks", resp.Tasks.Available, resp.Tasks.Error, tt.wantTasks)
+			check("notifications", resp.Notifications.Available, resp.Notifications.Error, tt.wantNotifications)
+
+			if tt.wantUsers == "" && (resp.Users.Total != 40 || resp.Users.SignupsThisWeek != 3) {
+				t.Errorf("users = %+v", resp.Users)
+			}
+			if tt.wantTasks == "" && resp.Tasks.CreatedToday != 1 {
+				t.Errorf("tasks created today = %d, want 1", resp.Tasks.CreatedToday)
+			}
+			if tt.wantNotifications == "" && (resp.Notifications.SentToday != 12 || resp.Notifications.FailedWebhookDeliveries != 2) {
+				t.Errorf("notifications = %+v", resp.Notifications)
+			}
+		})
+	}
+}
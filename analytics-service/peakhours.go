@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// GetPeakHours counts the user's completions by hour of the day in their
+// timezone over the date range, busiest hours first.
+func (s *server) GetPeakHours(ctx context.Context, req *pb.GetPeakHoursRequest) (*pb.GetPeakHoursResponse, error) {
+	timezone := req.Timezone
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	loc, err := parseTimezone(timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	dates, err := parseDateRange(req.StartDate, req.EndDate, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	match := eventsIn(dates)
+	match["user_id"] = req.UserId
+	match["event_type"] = "task.completed"
+
+	// created_at is stored as an RFC3339 string, so parse it before bucketing
+	// by the hour in the caller's timezone
+	pipeline := []bson.M{
+		{"$match": match},
+		{"$group": bson.M{
+			"_id": bson.M{"$hour": bson.M{
+				"date":     bson.M{"$dateFromString": bson.M{"dateString": "$created_at"}},
+				"timezone": timezone,
+			}},
+			"completions": bson.M{"$sum": eventWeight},
+		}},
+	}
+
+	cursor, err := s.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var hours []*pb.HourBucket
+	for cursor.Next(ctx) {
+		var row struct {
+			Hour        int32 `bson:"_id"`
+			Completions int32 `bson:"completions"`
+		}
+		if err := cursor.Decode(&row); err != nil {
+			return nil, err
+		}
+		hours = append(hours, &pb.HourBucket{Hour: row.Hour, Completions: row.Completions})
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(hours, func(i, j int) bool {
+		if hours[i].Completions != hours[j].Completions {
+			return hours[i].Completions > hours[j].Completions
+		}
+		return hours[i].Hour < hours[j].Hour
+	})
+
+	return &pb.GetPeakHoursResponse{Hours: hours}, nil
+}
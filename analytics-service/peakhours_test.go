@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+func TestGetPeakHoursValidation(t *testing.T) {
+	s := &server{}
+	tests := []struct {
+		name   string
+		req    *pb.GetPeakHoursRequest
+		reason string
+	}{
+		{"invalid timezone", &pb.GetPeakHoursRequest{UserId: "u1", Timezone: "Mars/Olympus"}, "INVALID_TIMEZONE"},
+		{"invalid start", &pb.GetPeakHoursRequest{UserId: "u1", StartDate: "last week"}, "INVALID_START_DATE"},
+		{"start after end", &pb.GetPeakHoursRequest{UserId: "u1", StartDate: "2026-02-01", EndDate: "2026-01-01"}, "INVALID_DATE_RANGE"},
+		{"range too long", &pb.GetPeakHoursRequest{UserId: "u1", StartDate: "2020-01-01", EndDate: "2022-01-01"}, "DATE_RANGE_TOO_LONG"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.GetPeakHours(context.Background(), tt.req)
+			if code := status.Code(err); code != codes.InvalidArgument {
+				t.Fatalf("code = %v, want InvalidArgument", code)
+			}
+			if got := errorReason(err); got != tt.reason {
+				t.Errorf("reason = %q, want %q", got, tt.reason)
+			}
+		})
+	}
+}
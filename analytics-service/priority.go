@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// priorityWeights are how much a task at each priority counts towards the
+// weighted completion score. Tasks without a priority count for nothing.
+var priorityWeights = map[string]float64{
+	"LOW":    1,
+	"MEDIUM": 2,
+	"HIGH":   3,
+	"URGENT": 4,
+}
+
+// priorityCount is the number of the user's tasks at one priority that are,
+// or are not, completed.
+type priorityCount struct {
+	Priority  string `bson:"priority"`
+	Completed bool   `bson:"completed"`
+	Count     int32  `bson:"count"`
+}
+
+// GetPriorityBreakdown counts the user's tasks and their completions by
+// priority, reading the task service's collection: events only carry the
+// priority a task had when they were recorded.
+func (s *server) GetPriorityBreakdown(ctx context.Context, req *pb.GetPriorityBreakdownRequest) (*pb.GetPriorityBreakdownResponse, error) {
+	cursor, err := s.taskCollection.Aggregate(ctx, []bson.M{
+		{"$match": bson.M{"user_id": req.UserId, "is_deleted": bson.M{"$ne": true}}},
+		{"$group": bson.M{
+			"_id":   bson.M{"priority": "$priority", "completed": "$completed"},
+			"count": bson.M{"$sum": 1},
+		}},
+		{"$project": bson.M{
+			"_id":       0,
+			"priority":  bson.M{"$ifNull": bson.A{"$_id.priority", ""}},
+			"completed": bson.M{"$eq": bson.A{"$_id.completed", true}},
+			"count":     1,
+		}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var counts []priorityCount
+	if err := cursor.All(ctx, &counts); err != nil {
+		return nil, err
+	}
+	return priorityBreakdown(counts), nil
+}
+
+// priorityBreakdown folds counts into a bucket per priority, in
+// priorityOrder, and scores them.
+func priorityBreakdown(counts []priorityCount) *pb.GetPriorityBreakdownResponse {
+	buckets := make(map[string]*pb.PriorityBucket)
+	for _, priority := range priorityOrder {
+		buckets[priority] = &pb.PriorityBucket{Priority: priority}
+	}
+	for _, c := range counts {
+		// Tasks store the TaskPriority name
+		priority := strings.TrimPrefix(c.Priority, "TASK_PRIORITY_")
+		bucket, ok := buckets[priority]
+		if !ok {
+			bucket = buckets["UNSPECIFIED"]
+		}
+		bucket.Total += c.Count
+		if c.Completed {
+			bucket.Completed += c.Count
+		}
+	}
+
+	resp := &pb.GetPriorityBreakdownResponse{}
+	var weighted, weightedCompleted float64
+	for _, priority := range priorityOrder {
+		bucket := buckets[priority]
+		if priority == "UNSPECIFIED" && bucket.Total == 0 {
+			continue
+		}
+		if bucket.Total > 0 {
+			bucket.CompletionRate = float64(bucket.Completed) / float64(bucket.Total)
+		}
+		resp.Buckets = append(resp.Buckets, bucket)
+		weighted += priorityWeights[priority] * float64(bucket.Total)
+		weightedCompleted += priorityWeights[priority] * float64(bucket.Completed)
+	}
+	if weighted > 0 {
+		resp.WeightedCompletionScore = weightedCompleted / weighted
+	}
+	return resp
+}
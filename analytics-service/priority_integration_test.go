@@ -0,0 +1,66 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+	"github.com/technonext/todo-app/proto/tenant"
+)
+
+func TestMongoPriorityBreakdown(t *testing.T) {
+	repo := newMongoRepository(t)
+	tasks := repo.events.Database().Collection("tasks")
+	s := &server{taskCollection: tenant.Scoped(tasks)}
+	ctx := context.Background()
+
+	task := func(user, priority string, completed bool) bson.M {
+		doc := bson.M{"user_id": user, "completed": completed}
+		if priority != "" {
+			doc["priority"] = priority
+		}
+		return doc
+	}
+	docs := []interface{}{
+		task("u1", "TASK_PRIORITY_LOW", true),
+		task("u1", "TASK_PRIORITY_LOW", false),
+		task("u1", "TASK_PRIORITY_MEDIUM", false),
+		task("u1", "TASK_PRIORITY_HIGH", true),
+		task("u1", "TASK_PRIORITY_URGENT", true),
+		task("u1", "TASK_PRIORITY_URGENT", false),
+		task("u1", "", true),
+		bson.M{"user_id": "u1", "priority": "TASK_PRIORITY_URGENT", "is_deleted": true},
+		task("u2", "TASK_PRIORITY_URGENT", true),
+	}
+	if _, err := tasks.InsertMany(ctx, docs); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := s.GetPriorityBreakdown(ctx, &pb.GetPriorityBreakdownRequest{UserId: "u1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string][2]int32)
+	var order []string
+	for _, b := range resp.Buckets {
+		got[b.Priority] = [2]int32{b.Total, b.Completed}
+		order = append(order, b.Priority)
+	}
+	want := map[string][2]int32{"LOW": {2, 1}, "MEDIUM": {1, 0}, "HIGH": {1, 1}, "URGENT": {2, 1}, "UNSPECIFIED": {1, 1}}
+	for priority, counts := range want {
+		if got[priority] != counts {
+			t.Errorf("%s = %v total and completed, want %v", priority, got[priority], counts)
+		}
+	}
+	if len(order) != 5 || order[0] != "LOW" || order[3] != "URGENT" || order[4] != "UNSPECIFIED" {
+		t.Errorf("order = %v, want LOW to URGENT, then UNSPECIFIED", order)
+	}
+	// (1*1 + 3*1 + 4*1) / (1*2 + 2*1 + 3*1 + 4*2)
+	if want := 8.0 / 15; resp.WeightedCompletionScore != want {
+		t.Errorf("weighted completion score = %v, want %v", resp.WeightedCompletionScore, want)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPriorityBreakdown(t *testing.T) {
+	resp := priorityBreakdown([]priorityCount{
+		{Priority: "TASK_PRIORITY_LOW", Completed: true, Count: 2},
+		{Priority: "TASK_PRIORITY_LOW", Count: 2},
+		{Priority: "TASK_PRIORITY_MEDIUM", Completed: true, Count: 1},
+		{Priority: "TASK_PRIORITY_MEDIUM", Count: 3},
+		{Priority: "TASK_PRIORITY_HIGH", Completed: true, Count: 3},
+		{Priority: "TASK_PRIORITY_HIGH", Count: 1},
+		{Priority: "TASK_PRIORITY_URGENT", Completed: true, Count: 1},
+		{Priority: "TASK_PRIORITY_URGENT", Count: 1},
+		{Priority: "", Completed: true, Count: 5},
+	})
+
+	want := []struct {
+		priority         string
+		total, completed int32
+		rate             float64
+	}{
+		{"LOW", 4, 2, 0.5},
+		{"MEDIUM", 4, 1, 0.25},
+		{"HIGH", 4, 3, 0.75},
+		{"URGENT", 2, 1, 0.5},
+		{"UNSPECIFIED", 5, 5, 1},
+	}
+	if len(resp.Buckets) != len(want) {
+		t.Fatalf("buckets = %v, want %d", resp.Buckets, len(want))
+	}
+	for i, w := range want {
+		b := resp.Buckets[i]
+		if b.Priority != w.priority || b.Total != w.total || b.Completed != w.completed || b.CompletionRate != w.rate {
+			t.Errorf("bucket %d = %v, want %s with %d of %d completed (%v)", i, b, w.priority, w.completed, w.total, w.rate)
+		}
+	}
+
+	// Completions weighted 1 to 4 over tasks weighted the same, leaving out
+	// the tasks without a priority:
+	// (1*2 + 2*1 + 3*3 + 4*1) / (1*4 + 2*4 + 3*4 + 4*2) = 17/32
+	if got, want := resp.WeightedCompletionScore, 17.0/32; math.Abs(got-want) > 1e-9 {
+		t.Errorf("weighted completion score = %v, want %v", got, want)
+	}
+}
+
+func TestPriorityBreakdownWithoutTasks(t *testing.T) {
+	resp := priorityBreakdown(nil)
+	var priorities []string
+	for _, b := range resp.Buckets {
+		priorities = append(priorities, b.Priority)
+		if b.Total != 0 || b.CompletionRate != 0 {
+			t.Errorf("bucket %v, want it empty", b)
+		}
+	}
+	if len(priorities) != 4 || priorities[0] != "LOW" || priorities[3] != "URGENT" {
+		t.Errorf("priorities = %v, want LOW to URGENT without UNSPECIFIED", priorities)
+	}
+	if resp.WeightedCompletionScore != 0 {
+		t.Errorf("weighted completion score = %v, want 0", resp.WeightedCompletionScore)
+	}
+}
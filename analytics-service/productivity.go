@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"math"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// The productivity score is a weighted sum, scaled to 0-100, of:
+//
+//   - completion rate: tasks completed / tasks created in the range, capped at 1
+//   - on-time rate: completions by their due date / completions that had a due
+//     date. Users who never set due dates are not penalised, so it is 1 when
+//     they completed tasks but none had a due date, and 0 without completions.
+//   - streak: the current streak over streakTarget days, capped at 1
+//
+// so a user who finishes everything they create, on time, every day for a
+// week scores 100.
+const (
+	completionRateWeight = 0.5
+	onTimeRateWeight     = 0.3
+	streakWeight         = 0.2
+	streakTarget         = 7
+)
+
+func productivityScore(created, completed, onTime, dueDated, currentStreak int32) float64 {
+	var completionRate, onTimeRate float64
+	if created > 0 {
+		completionRate = math.Min(1, float64(completed)/float64(created))
+	}
+	switch {
+	case dueDated > 0:
+		onTimeRate = float64(onTime) / float64(dueDated)
+	case completed > 0:
+		onTimeRate = 1
+	}
+	streakRate := math.Min(1, float64(currentStreak)/streakTarget)
+
+	score := completionRateWeight*completionRate + onTimeRateWeight*onTimeRate + streakWeight*streakRate
+	return math.Round(score*1000) / 10
+}
+
+// OnTimeCompletions counts the user's completions in r that had a due date,
+// and how many of them were on time. The gateway records this as on_time in
+// the completion event's metadata.
+func (m *mongoRepository) OnTimeCompletions(ctx context.Context, userID string, r dateRange) (onTime, dueDated int32, err error) {
+	match := eventsIn(r)
+	match["user_id"] = userID
+	match["event_type"] = "task.completed"
+	match["metadata.on_time"] = bson.M{"$type": "bool"}
+	pipeline := []bson.M{
+		{"$match": match},
+		{"$group": bson.M{
+			"_id":       nil,
+			"due_dated": bson.M{"$sum": eventWeight},
+			"on_time":   bson.M{"$sum": bson.M{"$cond": bson.A{"$metadata.on_time", eventWeight, 0}}},
+		}},
+	}
+	cursor, err := m.events.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var totals struct {
+		OnTime   int32 `bson:"on_time"`
+		DueDated int32 `bson:"due_dated"`
+	}
+	if cursor.Next(ctx) {
+		if err := cursor.Decode(&totals); err != nil {
+			return 0, 0, err
+		}
+	}
+	return totals.OnTime, totals.DueDated, cursor.Err()
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// These pin the documents the service sends MongoDB, so changing the bson
+// package or the driver cannot quietly change what a query matches.
+
+// sameDocument reports whether got marshals to the document written as
+// extended JSON in want. Both are decoded the same way before comparing, as
+// maps do not keep their key order.
+func sameDocument(t *testing.T, got interface{}, want string) {
+	t.Helper()
+	raw, err := bson.Marshal(bson.M{"doc": got})
+	if err != nil {
+		t.Fatalf("marshal %v: %v", got, err)
+	}
+	var gotDoc, wantDoc bson.M
+	if err := bson.Unmarshal(raw, &gotDoc); err != nil {
+		t.Fatal(err)
+	}
+	if err := bson.UnmarshalExtJSON([]byte(`{"doc": `+want+`}`), false, &wantDoc); err != nil {
+		t.Fatalf("bad expected document %s: %v", want, err)
+	}
+	if !reflect.DeepEqual(gotDoc, wantDoc) {
+		gotJSON, _ := bson.MarshalExtJSON(gotDoc, false, false)
+		t.Errorf("document = %s\nwant %s", gotJSON, want)
+	}
+}
+
+func TestQueryDocuments(t *testing.T) {
+	local := time.Local
+	time.Local = time.UTC
+	t.Cleanup(func() { time.Local = local })
+
+	r := dateRange{
+		start: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+		end:   time.Date(2026, 3, 31, 23, 59, 59, 0, time.UTC),
+	}
+	asOf := time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		got  interface{}
+		want string
+	}{
+		{
+			name: "events in range",
+			got:  eventsIn(r),
+			want: `{"created_at": {"$gte": "2026-03-01T00:00:00Z", "$lte": "2026-03-31T23:59:59Z"}}`,
+		},
+		{
+			name: "range contains",
+			got:  r.contains("$day"),
+			want: `{"$and": [
+				{"$gte": ["$day", {"$date": "2026-03-01T00:00:00Z"}]},
+				{"$lte": ["$day", {"$date": "2026-03-31T23:59:59Z"}]}
+			]}`,
+		},
+		{
+			name: "to date",
+			got:  toDate("$created_at"),
+			want: `{"$convert": {"input": "$created_at", "to": "date", "onError": null, "onNull": null}}`,
+		},
+		{
+			name: "older than",
+			got:  olderThan(asOf, bson.M{"$in": bson.A{"app_opened"}}),
+			want: `{
+				"created_at": {"$gte": "1970-01-01T00:00:00Z", "$lte": "2026-03-15T12:00:00Z"},
+				"event_type": {"$in": ["app_opened"]}
+			}`,
+		},
+		{
+			name: "day bucket",
+			got:  dayBucket("$day", "week"),
+			want: `{"$dateTrunc": {
+				"date": {"$convert": {"input": "$day", "to": "date", "onError": null, "onNull": null}},
+				"unit": "week",
+				"startOfWeek": "monday"
+			}}`,
+		},
+		{
+			name: "overdue",
+			got:  overdueExpr(asOf),
+			want: `{"$and": [
+				{"$and": [
+					{"$gte": [{"$convert": {"input": "$created_at", "to": "date", "onError": null, "onNull": null}}, {"$date": "1970-01-01T00:00:00Z"}]},
+					{"$lte": [{"$convert": {"input": "$created_at", "to": "date", "onError": null, "onNull": null}}, {"$date": "2026-03-15T12:00:00Z"}]}
+				]},
+				{"$and": [
+					{"$gte": [{"$convert": {"input": "$due_date", "to": "date", "onError": null, "onNull": null}}, {"$date": "1970-01-01T00:00:00Z"}]},
+					{"$lte": [{"$convert": {"input": "$due_date", "to": "date", "onError": null, "onNull": null}}, {"$date": "2026-03-15T12:00:00Z"}]}
+				]},
+				{"$not": [{"$and": [
+					"$completed",
+					{"$and": [
+						{"$gte": [{"$convert": {"input": {"$ifNull": ["$completed_at", "$updated_at"]}, "to": "date", "onError": null, "onNull": null}}, {"$date": "1970-01-01T00:00:00Z"}]},
+						{"$lte": [{"$convert": {"input": {"$ifNull": ["$completed_at", "$updated_at"]}, "to": "date", "onError": null, "onNull": null}}, {"$date": "2026-03-15T12:00:00Z"}]}
+					]}
+				]}]},
+				{"$not": [{"$and": [
+					{"$eq": ["$is_deleted", true]},
+					{"$and": [
+						{"$gte": [{"$convert": {"input": "$deleted_at", "to": "date", "onError": null, "onNull": null}}, {"$date": "1970-01-01T00:00:00Z"}]},
+						{"$lte": [{"$convert": {"input": "$deleted_at", "to": "date", "onError": null, "onNull": null}}, {"$date": "2026-03-15T12:00:00Z"}]}
+					]}
+				]}]}
+			]}`,
+		},
+		{
+			name: "latency pipeline",
+			got:  latencyPipeline("events", "u1", r),
+			want: `[
+				{"$match": {
+					"created_at": {"$gte": "2026-03-01T00:00:00Z", "$lte": "2026-03-31T23:59:59Z"},
+					"event_type": "task.completed",
+					"user_id": "u1"
+				}},
+				{"$lookup": {
+					"from": "events",
+					"localField": "resource_id",
+					"foreignField": "resource_id",
+					"pipeline": [
+						{"$match": {"event_type": "task.created"}},
+						{"$project": {"created_at": 1}},
+						{"$limit": 1}
+					],
+					"as": "created"
+				}},
+				{"$unwind": "$created"},
+				{"$project": {
+					"_id": 0,
+					"weight": {"$ifNull": ["$sample_rate", 1]},
+					"seconds": {"$divide": [
+						{"$subtract": [
+							{"$convert": {"input": "$created_at", "to": "date", "onError": null, "onNull": null}},
+							{"$convert": {"input": "$created.created_at", "to": "date", "onError": null, "onNull": null}}
+						]},
+						1000
+					]}
+				}},
+				{"$match": {"seconds": {"$gte": 0, "$lte": 31536000.0}}}
+			]`,
+		},
+		{
+			name: "latency pipeline for everyone",
+			got:  latencyPipeline("events", "", r)[0],
+			want: `{"$match": {
+				"created_at": {"$gte": "2026-03-01T00:00:00Z", "$lte": "2026-03-31T23:59:59Z"},
+				"event_type": "task.completed"
+			}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sameDocument(t, tt.got, tt.want)
+		})
+	}
+}
+
+func TestEventIndexKeys(t *testing.T) {
+	// Index keys are ordered; a map here would build a different index
+	want := []string{
+		`{"tenant_id": 1, "resource_id": 1, "event_type": 1}`,
+		`{"resource_id": 1, "event_type": 1, "metadata.source": 1}`,
+		`{"tenant_id": 1, "created_at": 1, "user_id": 1}`,
+		`{"tenant_id": 1, "user_id": 1, "client_event_id": 1}`,
+		`{"message_id": 1}`,
+	}
+	if len(eventIndexes) != len(want) {
+		t.Fatalf("%d event indexes, want %d", len(eventIndexes), len(want))
+	}
+	for i, index := range eventIndexes {
+		got, err := bson.MarshalExtJSON(index.Keys, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var compact bson.D
+		if err := bson.UnmarshalExtJSON([]byte(want[i]), false, &compact); err != nil {
+			t.Fatal(err)
+		}
+		wantJSON, _ := bson.MarshalExtJSON(compact, false, false)
+		if string(got) != string(wantJSON) {
+			t.Errorf("index %d keys = %s, want %s", i, got, wantJSON)
+		}
+	}
+}
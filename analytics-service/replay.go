@@ -0,0 +1,54 @@
+package main
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/grpc/codes"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// ReplayEvents streams stored events in insertion order so an external
+// processor can recompute stats after the analytics logic changes. Replaying
+// is read-only, so it is safe to repeat; a consumer that drops out resumes by
+// passing the last id it saw as from_event_id.
+func (s *server) ReplayEvents(req *pb.ReplayEventsRequest, stream pb.AnalyticsService_ReplayEventsServer) error {
+	ctx := stream.Context()
+
+	filter := bson.M{}
+	if req.UserId != "" {
+		filter["user_id"] = req.UserId
+	}
+	if req.FromEventId != "" {
+		from, err := primitive.ObjectIDFromHex(req.FromEventId)
+		if err != nil {
+			return statusError(codes.InvalidArgument, "INVALID_EVENT_ID", map[string]string{"event_id": req.FromEventId}, "invalid from_event_id")
+		}
+		filter["_id"] = bson.M{"$gt": from}
+	}
+	if len(req.EventTypes) > 0 {
+		filter["event_type"] = bson.M{"$in": req.EventTypes}
+	}
+
+	// ObjectIDs grow with insertion time, so sorting by _id replays in order
+	findOptions := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetBatchSize(500)
+	cursor, err := s.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var event Event
+		if err := cursor.Decode(&event); err != nil {
+			return err
+		}
+
+		if err := stream.Send(event.toProto()); err != nil {
+			return err
+		}
+	}
+
+	return cursor.Err()
+}
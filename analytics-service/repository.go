@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/technonext/todo-app/proto/tenant"
+)
+
+// The stats RPCs reach storage through EventsRepository and StatsRepository
+// rather than the collections, so their logic does not depend on how events
+// and counters are kept. mongoRepository implements both; background jobs
+// such as the rollup and retention still work on the collections directly.
+
+// errEventExists is returned for an event whose client_event_id was already
+// tracked for the user.
+var errEventExists = errors.New("event already tracked")
+
+// EventsRepository stores tracked events.
+type EventsRepository interface {
+	// InsertEvent stores event and returns its id, or errEventExists.
+	InsertEvent(ctx context.Context, event Event) (primitive.ObjectID, error)
+	// DeleteUserEvents deletes all of the user's events, returning how many.
+	DeleteUserEvents(ctx context.Context, userID string) (int64, error)
+	// AnonymizeUserEvents moves all of the user's events to pseudonym,
+	// dropping what could identify them, and returns how many it moved.
+	AnonymizeUserEvents(ctx context.Context, userID, pseudonym string) (int64, error)
+}
+
+// StatsRepository maintains and reads the aggregates stats are served from.
+// Date ranges are inclusive.
+type StatsRepository interface {
+	// ApplyEvent updates the counters for a newly tracked event.
+	ApplyEvent(ctx context.Context, event Event) error
+	// RangeTotals sums tasks created and completed by the user over the days
+	// in r.
+	RangeTotals(ctx context.Context, userID string, r dateRange) (created, completed int32, err error)
+	// DailyCompletions returns the user's completions per day in r, keyed by
+	// day, for the days they were active.
+	DailyCompletions(ctx context.Context, userID string, r dateRange) (map[string]int32, error)
+	OpenTasks(ctx context.Context, userID string) (int32, error)
+	OnTimeCompletions(ctx context.Context, userID string, r dateRange) (onTime, dueDated int32, err error)
+	Streak(ctx context.Context, userID string, loc *time.Location) (streak, error)
+	// SavedStreak returns nil when no streak is saved for the user in loc.
+	SavedStreak(ctx context.Context, userID string, loc *time.Location) (*streak, error)
+	SaveStreak(ctx context.Context, userID string, loc *time.Location, st streak) error
+	CompletionLatency(ctx context.Context, userID string, r dateRange) (completionLatency, error)
+	// DueDateExtensions counts the user's due date extensions in r.
+	DueDateExtensions(ctx context.Context, userID string, r dateRange) (int32, error)
+	// LatestSnapshot returns nil when the user has no snapshot yet.
+	LatestSnapshot(ctx context.Context, userID string) (*UserStatsSnapshot, error)
+	SaveSnapshot(ctx context.Context, snapshot UserStatsSnapshot) error
+	GlobalTotals(ctx context.Context, r dateRange) (globalTotals, error)
+	// DeleteUserStats deletes the aggregates kept for the user alone.
+	DeleteUserStats(ctx context.Context, userID string) error
+	// AnonymizeUserStats moves the user's contribution to global stats to
+	// pseudonym and deletes the rest of their aggregates.
+	AnonymizeUserStats(ctx context.Context, userID, pseudonym string) error
+}
+
+type mongoRepository struct {
+	events       *tenant.Collection
+	dailyStats   *tenant.Collection
+	userCounters *tenant.Collection
+	statsDaily   *tenant.Collection
+	snapshots    *tenant.Collection
+	streaks      *tenant.Collection
+	jobs         *tenant.Collection
+	// percentiles is whether the server can compute percentiles itself
+	percentiles bool
+}
+
+func (m *mongoRepository) InsertEvent(ctx context.Context, event Event) (primitive.ObjectID, error) {
+	event.SchemaVersion = eventSchema.Version()
+	result, err := m.events.InsertOne(ctx, event)
+	if mongo.IsDuplicateKeyError(err) {
+		return primitive.NilObjectID, errEventExists
+	}
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	oid, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return primitive.NilObjectID, errors.New("inserted event id is not an ObjectID")
+	}
+	return oid, nil
+}
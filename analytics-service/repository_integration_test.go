@@ -0,0 +1,383 @@
+//go:build integration
+
+package main
+
+// These tests run the real pipelines against MongoDB. Start one with
+//
+//	docker run --rm -d -p 27017:27017 mongo:5.0
+//
+// and run go test -tags integration ./... ; MONGO_TEST_URI overrides the
+// default mongodb://localhost:27017. Each test works in its own database,
+// dropped afterwards.
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/technonext/todo-app/proto/mongoutil"
+	pb "github.com/technonext/todo-app/proto/proto"
+	"github.com/technonext/todo-app/proto/tenant"
+)
+
+func newMongoRepository(t *testing.T) *mongoRepository {
+	t.Helper()
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		uri = "mongodb://localhost:27017"
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Ping(ctx, nil); err != nil {
+		t.Skipf("MongoDB not reachable at %s: %v", uri, err)
+	}
+
+	percentiles, err := supportsPercentile(ctx, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db := client.Database(fmt.Sprintf("analytics_test_%d", time.Now().UnixNano()))
+	t.Cleanup(func() {
+		db.Drop(context.Background())
+		client.Disconnect(context.Background())
+	})
+	if err := mongoutil.EnsureIndexes(ctx, db.Collection("events"), eventIndexes...); err != nil {
+		t.Fatal(err)
+	}
+	return &mongoRepository{
+		events:       tenant.Scoped(db.Collection("events")),
+		dailyStats:   tenant.Scoped(db.Collection("user_daily_stats")),
+		userCounters: tenant.Scoped(db.Collection("user_counters")),
+		statsDaily:   tenant.Scoped(db.Collection("stats_daily")),
+		snapshots:    tenant.Scoped(db.Collection("daily_user_stats")),
+		streaks:      tenant.Scoped(db.Collection("user_streaks")),
+		jobs:         tenant.Scoped(db.Collection("jobs")),
+		percentiles:  percentiles,
+	}
+}
+
+func newMongoService(t *testing.T, tasks pb.TaskServiceClient) *statsService {
+	s := newTestService(newFakeRepository(), tasks)
+	repo := newMongoRepository(t)
+	s.events, s.stats = repo, repo
+	return s
+}
+
+func TestMongoGetUserStats(t *testing.T) {
+	s := newMongoService(t, &fakeTaskClient{overdue: 1})
+	at3, day3 := daysAgo(3)
+	at2, _ := daysAgo(2)
+	at1, day1 := daysAgo(1)
+	onTime, _ := structpb.NewStruct(map[string]interface{}{"on_time": true})
+	late, _ := structpb.NewStruct(map[string]interface{}{"on_time": false})
+
+	track(t, s, &pb.TrackEventRequest{UserId: "u1", EventType: "task.created", ResourceId: "a", ClientTimestamp: at3})
+	track(t, s, &pb.TrackEventRequest{UserId: "u1", EventType: "task.created", ResourceId: "b", ClientTimestamp: at3})
+	track(t, s, &pb.TrackEventRequest{UserId: "u1", EventType: "task.completed", ResourceId: "a", ClientTimestamp: at2, Metadata: onTime})
+	track(t, s, &pb.TrackEventRequest{UserId: "u1", EventType: "task.completed", ResourceId: "b", ClientTimestamp: at1, Metadata: late})
+
+	tests := []struct {
+		name                       string
+		req                        *pb.GetUserStatsRequest
+		wantCode                   codes.Code
+		wantCreated, wantCompleted int32
+		wantLatency                int32
+	}{
+		{"default range", &pb.GetUserStatsRequest{UserId: "u1", Fresh: true}, codes.OK, 2, 2, 2},
+		{"first day", &pb.GetUserStatsRequest{UserId: "u1", StartDate: day3, EndDate: day3}, codes.OK, 2, 0, 0},
+		{"last day", &pb.GetUserStatsRequest{UserId: "u1", StartDate: day1, EndDate: day1}, codes.OK, 0, 1, 1},
+		{"invalid range", &pb.GetUserStatsRequest{UserId: "u1", StartDate: day1, EndDate: day3}, codes.InvalidArgument, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := s.getUserStats(context.Background(), tt.req)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("code = %v, want %v (%v)", code, tt.wantCode, err)
+			}
+			if err != nil {
+				return
+			}
+			got := resp.Stats
+			if got.TotalTasks != tt.wantCreated || got.CompletedTasks != tt.wantCompleted || got.CompletionLatency.Count != tt.wantLatency {
+				t.Errorf("created, completed, latency count = %d, %d, %d, want %d, %d, %d",
+					got.TotalTasks, got.CompletedTasks, got.CompletionLatency.Count, tt.wantCreated, tt.wantCompleted, tt.wantLatency)
+			}
+		})
+	}
+
+	resp, err := s.getUserStats(context.Background(), &pb.GetUserStatsRequest{UserId: "u1", Fresh: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.Stats; got.PendingTasks != 0 || got.OverdueTasks != 1 || got.CurrentStreak != 2 {
+		t.Errorf("pending, overdue, streak = %d, %d, %d, want 0, 1, 2", got.PendingTasks, got.OverdueTasks, got.CurrentStreak)
+	}
+}
+
+func TestMongoTrackEventDuplicate(t *testing.T) {
+	s := newMongoService(t, &fakeTaskClient{})
+	req := &pb.TrackEventRequest{UserId: "u1", EventType: "task.created", ClientEventId: "c1"}
+	track(t, s, req)
+	resp, err := s.trackEvent(context.Background(), req)
+	if err != nil {
+		t.Fatalf("retry failed: %v", err)
+	}
+	if !resp.Duplicate {
+		t.Error("retry was not reported as a duplicate")
+	}
+}
+
+func TestMongoTrackEventsDuplicates(t *testing.T) {
+	s := newMongoService(t, &fakeTaskClient{})
+	srv := &server{statsService: s, collection: s.events.(*mongoRepository).events}
+	event := func(clientID string) *pb.TrackEventRequest {
+		return &pb.TrackEventRequest{UserId: "u1", EventType: "task.created", ClientEventId: clientID}
+	}
+
+	tests := []struct {
+		name           string
+		events         []*pb.TrackEventRequest
+		wantAccepted   int32
+		wantDuplicates int32
+	}{
+		{"first flush", []*pb.TrackEventRequest{event("c1"), event("c2")}, 2, 0},
+		{"retried flush", []*pb.TrackEventRequest{event("c1"), event("c2"), event("c3")}, 1, 2},
+		{"repeated within a batch", []*pb.TrackEventRequest{event("c4"), event("c4")}, 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := srv.TrackEvents(context.Background(), &pb.TrackEventsRequest{Events: tt.events})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.Accepted != tt.wantAccepted || resp.Duplicates != tt.wantDuplicates || resp.Rejected != 0 {
+				t.Errorf("accepted, duplicates, rejected = %d, %d, %d, want %d, %d, 0", resp.Accepted, resp.Duplicates, resp.Rejected, tt.wantAccepted, tt.wantDuplicates)
+			}
+		})
+	}
+}
+
+func TestMongoGetTaskStats(t *testing.T) {
+	s := newMongoService(t, &fakeTaskClient{})
+	at3, day3 := daysAgo(3)
+	at1, _ := daysAgo(1)
+	track(t, s, &pb.TrackEventRequest{UserId: "u1", EventType: "task.created", ResourceId: "a", ClientTimestamp: at3})
+	track(t, s, &pb.TrackEventRequest{UserId: "u2", EventType: "task.created", ResourceId: "b", ClientTimestamp: at1})
+	track(t, s, &pb.TrackEventRequest{UserId: "u2", EventType: "task.completed", ResourceId: "b", ClientTimestamp: at1})
+
+	resp, err := s.getTaskStats(context.Background(), &pb.GetTaskStatsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.Stats; got.TotalTasks != 2 || got.CompletedTasks != 1 || got.ActiveUsers != 2 {
+		t.Errorf("stats = %+v", got)
+	}
+
+	resp, err = s.getTaskStats(context.Background(), &pb.GetTaskStatsRequest{StartDate: day3, EndDate: day3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.Stats; got.TotalTasks != 1 || got.CompletedTasks != 0 || got.ActiveUsers != 1 {
+		t.Errorf("stats for %s = %+v", day3, got)
+	}
+}
+
+func TestMongoGlobalTotalsRolledUp(t *testing.T) {
+	s := newMongoService(t, &fakeTaskClient{})
+	repo := s.stats.(*mongoRepository)
+	at3, _ := daysAgo(3)
+	at1, _ := daysAgo(1)
+	track(t, s, &pb.TrackEventRequest{UserId: "u1", EventType: "task.created", ResourceId: "a", ClientTimestamp: at3})
+	track(t, s, &pb.TrackEventRequest{UserId: "u1", EventType: "task.created", ResourceId: "b", ClientTimestamp: at1})
+	track(t, s, &pb.TrackEventRequest{UserId: "u2", EventType: "task.created", ResourceId: "c", ClientTimestamp: at1})
+	track(t, s, &pb.TrackEventRequest{UserId: "u2", EventType: "task.completed", ResourceId: "c", ClientTimestamp: at1})
+
+	r := dateRange{start: time.Now().AddDate(0, 0, -7), end: time.Now()}
+	live, err := repo.GlobalTotals(context.Background(), r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (globalTotals{Created: 3, Completed: 1, ActiveUsers: 3}); live != want {
+		t.Errorf("live totals = %+v, want %+v", live, want)
+	}
+
+	jobs := &server{dailyStats: repo.dailyStats, statsDaily: repo.statsDaily, jobs: repo.jobs}
+	if err := jobs.rollupStats(context.Background(), true); err != nil {
+		t.Fatal(err)
+	}
+	rolled, err := repo.GlobalTotals(context.Background(), r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rolled != live {
+		t.Errorf("rolled-up totals = %+v, want %+v", rolled, live)
+	}
+}
+
+func TestMongoGlobalTotalsPerTenant(t *testing.T) {
+	s := newMongoService(t, &fakeTaskClient{})
+	repo := s.stats.(*mongoRepository)
+	acme := tenant.WithID(context.Background(), "acme")
+	at2, _ := daysAgo(2)
+	for _, tt := range []struct {
+		ctx  context.Context
+		user string
+	}{{acme, "a1"}, {acme, "a2"}, {context.Background(), "d1"}} {
+		req := &pb.TrackEventRequest{UserId: tt.user, EventType: "task.created", ResourceId: tt.user, ClientTimestamp: at2}
+		if _, err := s.trackEvent(tt.ctx, req); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	jobs := &server{dailyStats: repo.dailyStats, statsDaily: repo.statsDaily, jobs: repo.jobs}
+	if err := jobs.rollupStats(context.Background(), true); err != nil {
+		t.Fatal(err)
+	}
+
+	r := dateRange{start: time.Now().AddDate(0, 0, -7), end: time.Now()}
+	for _, tt := range []struct {
+		name string
+		ctx  context.Context
+		want globalTotals
+	}{
+		{"acme", acme, globalTotals{Created: 2, ActiveUsers: 2}},
+		{"default tenant", context.Background(), globalTotals{Created: 1, ActiveUsers: 1}},
+	} {
+		got, err := repo.GlobalTotals(tt.ctx, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("%s totals = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// replayStream collects the events ReplayEvents sends.
+type replayStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	sent []*pb.Event
+}
+
+func (r *replayStream) Context() context.Context { return r.ctx }
+
+func (r *replayStream) Send(event *pb.Event) error {
+	r.sent = append(r.sent, event)
+	return nil
+}
+
+func TestMongoReplayEventsReadOnly(t *testing.T) {
+	repo := newMongoRepository(t)
+	s := &server{collection: repo.events}
+	for _, id := range []string{"a", "b"} {
+		if _, err := repo.InsertEvent(context.Background(), Event{UserID: "u1", EventType: "task.created", ResourceID: id, CreatedAt: time.Now().Format(time.RFC3339)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		stream := &replayStream{ctx: context.Background()}
+		if err := s.ReplayEvents(&pb.ReplayEventsRequest{UserId: "u1"}, stream); err != nil {
+			t.Fatal(err)
+		}
+		if len(stream.sent) != 2 {
+			t.Fatalf("replay %d sent %d events, want 2", i, len(stream.sent))
+		}
+	}
+
+	stamped, err := repo.events.CountDocuments(context.Background(), bson.M{"processed_at": bson.M{"$exists": true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stamped != 0 {
+		t.Errorf("%d events were modified by the replay", stamped)
+	}
+}
+
+func TestMongoWeeklySummariesIdempotent(t *testing.T) {
+	repo := newMongoRepository(t)
+	db := repo.events.Database()
+	ctx := context.Background()
+	notifications := &fakeNotificationClient{templates: []*pb.Template{
+		{Id: "t1", Name: "weekly_summary", Status: pb.TemplateStatus_TEMPLATE_STATUS_ACTIVE},
+	}}
+	s := &server{
+		collection:      repo.events,
+		jobs:            repo.jobs,
+		weeklySummaries: tenant.Scoped(db.Collection("weekly_summaries")),
+		digestSchedules: tenant.Scoped(db.Collection("digest_schedules")),
+		taskCollection:  tenant.Scoped(db.Collection("tasks")),
+		notifications:   notifications,
+	}
+	_, err := s.weeklySummaries.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "week", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Both users completed a task on Wednesday of 2026-W10; "west" lives in
+	// Los Angeles, where Monday 10:00 UTC is still before 08:00
+	for _, userID := range []string{"utc", "west"} {
+		if _, err := repo.InsertEvent(ctx, Event{UserID: userID, EventType: "task.completed", ResourceID: userID, CreatedAt: "2026-03-04T12:00:00Z"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.digestSchedules.InsertOne(ctx, bson.M{"user_id": "west", "timezone": "America/Los_Angeles"}); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := weeklySummaryConfig{hour: 8, loc: time.UTC, template: "weekly_summary"}
+	monday := time.Date(2026, 3, 9, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		now      time.Time
+		wantSent int
+		wantTo   string
+	}{
+		{"first run", monday, 1, "utc"},
+		{"re-run", monday.Add(time.Minute), 0, ""},
+		{"due in Los Angeles", monday.Add(7 * time.Hour), 1, "west"},
+		{"re-run later that week", monday.Add(48 * time.Hour), 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := len(notifications.sent)
+			sent, failed, err := s.sendWeeklySummaries(ctx, cfg, tt.now)
+			if err != nil || failed != 0 {
+				t.Fatalf("sent %d, failed %d, err %v", sent, failed, err)
+			}
+			if sent != tt.wantSent || len(notifications.sent)-before != tt.wantSent {
+				t.Fatalf("sent %d summaries, want %d", sent, tt.wantSent)
+			}
+			if tt.wantSent == 0 {
+				return
+			}
+			req := notifications.sent[len(notifications.sent)-1]
+			if req.UserId != tt.wantTo || req.TemplateId != "t1" || req.Variables["week"] != "2026-W10" || req.Variables["completed"] != "1" {
+				t.Errorf("sent %+v", req)
+			}
+		})
+	}
+}
+
+func TestMongoOwnershipMigrationMovesCounters(t *testing.T) {
+	runOwnershipMigrationTest(t, newMongoService(t, &fakeTaskClient{}))
+}
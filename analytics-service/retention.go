@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// Events older than the retention age are purged in batches by one replica
+// at a time, optionally after being exported to gzip'd JSON-lines files.
+// Audit events (event types starting with "audit.") are kept for their own,
+// longer retention. Stats already folded into the per-user counters are not
+// affected, but queries that read raw events only see what is retained.
+
+const (
+	retentionJob       = "event_retention"
+	retentionInterval  = time.Hour
+	retentionBatchSize = 1000
+	// Each batch renews the lease, so it only has to outlive one batch
+	retentionLeaseTTL = 10 * time.Minute
+)
+
+var auditEventTypes = primitive.Regex{Pattern: `^audit\.`}
+
+// retentionPolicy is how long events are kept. A zero age keeps them forever.
+type retentionPolicy struct {
+	age        time.Duration
+	auditAge   time.Duration
+	archiveDir string
+}
+
+// expired reports whether a regular event created at createdAt would already
+// be past retention at now. Timestamps that do not parse are never expired.
+func (p retentionPolicy) expired(createdAt string, now time.Time) bool {
+	if p.age == 0 {
+		return false
+	}
+	t, err := time.Parse(time.RFC3339, createdAt)
+	return err == nil && t.Before(now.Add(-p.age))
+}
+
+// loadRetentionPolicy reads EVENT_RETENTION_DAYS, AUDIT_EVENT_RETENTION_DAYS
+// and EVENT_ARCHIVE_DIR. Events are kept forever unless EVENT_RETENTION_DAYS
+// is set.
+func loadRetentionPolicy() (retentionPolicy, error) {
+	var policy retentionPolicy
+
+	days, err := strconv.Atoi(getEnv("EVENT_RETENTION_DAYS", "0"))
+	if err != nil || days < 0 {
+		return policy, fmt.Errorf("EVENT_RETENTION_DAYS %q must be a number of days", os.Getenv("EVENT_RETENTION_DAYS"))
+	}
+	auditDays, err := strconv.Atoi(getEnv("AUDIT_EVENT_RETENTION_DAYS", "0"))
+	if err != nil || auditDays < 0 {
+		return policy, fmt.Errorf("AUDIT_EVENT_RETENTION_DAYS %q must be a number of days", os.Getenv("AUDIT_EVENT_RETENTION_DAYS"))
+	}
+	if auditDays > 0 && auditDays < days {
+		return policy, fmt.Errorf("AUDIT_EVENT_RETENTION_DAYS (%d) must not be shorter than EVENT_RETENTION_DAYS (%d)", auditDays, days)
+	}
+	policy.age = time.Duration(days) * 24 * time.Hour
+	policy.auditAge = time.Duration(auditDays) * 24 * time.Hour
+
+	if dir := os.Getenv("EVENT_ARCHIVE_DIR"); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return policy, fmt.Errorf("EVENT_ARCHIVE_DIR: %w", err)
+		}
+		policy.archiveDir = dir
+	}
+	return policy, nil
+}
+
+// startRetention purges expired events every retentionInterval until ctx is
+// done.
+func (s *server) startRetention(ctx context.Context) {
+	ticker := time.NewTicker(retentionInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.purgeExpiredEvents(ctx); err != nil {
+			log.Printf("Event retention failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// purgeExpiredEvents deletes regular and then audit events past their
+// retention, recording the run on the job's document.
+func (s *server) purgeExpiredEvents(ctx context.Context) error {
+	now := time.Now()
+	filters := []bson.M{
+		olderThan(now.Add(-s.retention.age), bson.M{"$not": auditEventTypes}),
+	}
+	if s.retention.auditAge > 0 {
+		filters = append(filters, olderThan(now.Add(-s.retention.auditAge), auditEventTypes))
+	}
+
+	var purged, archived int64
+	for _, filter := range filters {
+		for {
+			leader, err := s.acquireLease(ctx, retentionJob, retentionLeaseTTL)
+			if err != nil {
+				return err
+			}
+			if !leader {
+				return nil
+			}
+
+			deleted, exported, err := s.purgeBatch(ctx, filter)
+			purged += deleted
+			archived += exported
+			if err != nil {
+				if err := s.recordPurge(ctx, now, purged, archived); err != nil {
+					log.Printf("Failed to record event purge: %v", err)
+				}
+				return err
+			}
+			if deleted < retentionBatchSize {
+				break
+			}
+		}
+	}
+
+	if purged > 0 {
+		s.cache.invalidateAll()
+		log.Printf("Event retention purged %d events", purged)
+	}
+	return s.recordPurge(ctx, now, purged, archived)
+}
+
+// olderThan matches events of the given types created before cutoff.
+func olderThan(cutoff time.Time, eventTypes interface{}) bson.M {
+	filter := eventsIn(dateRange{start: time.Unix(0, 0), end: cutoff})
+	filter["event_type"] = eventTypes
+	return filter
+}
+
+// purgeBatch deletes up to retentionBatchSize events matching filter,
+// exporting them first when an archive directory is configured. Events are
+// only deleted once their archive file is safely written.
+func (s *server) purgeBatch(ctx context.Context, filter bson.M) (deleted, archived int64, err error) {
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(retentionBatchSize)
+	cursor, err := s.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return 0, 0, err
+	}
+	var events []bson.Raw
+	if err := cursor.All(ctx, &events); err != nil {
+		return 0, 0, err
+	}
+	if len(events) == 0 {
+		return 0, 0, nil
+	}
+
+	ids := make(bson.A, 0, len(events))
+	for _, event := range events {
+		ids = append(ids, event.Lookup("_id"))
+	}
+
+	if s.retention.archiveDir != "" {
+		if err := archiveEvents(s.retention.archiveDir, events); err != nil {
+			return 0, 0, fmt.Errorf("archive events: %w", err)
+		}
+		archived = int64(len(events))
+	}
+
+	result, err := s.collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return 0, archived, err
+	}
+	return result.DeletedCount, archived, nil
+}
+
+// archiveEvents writes events as canonical extended JSON, one per line, to a
+// new gzip file in dir. The file is written under a temporary name and
+// renamed once synced, so a crash never leaves a partial archive behind.
+func archiveEvents(dir string, events []bson.Raw) error {
+	id, _ := events[0].Lookup("_id").ObjectIDOK()
+	name := fmt.Sprintf("events-%s-%s.jsonl.gz", time.Now().UTC().Format("20060102T150405Z"), id.Hex())
+	path := filepath.Join(dir, name)
+
+	f, err := os.CreateTemp(dir, name+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	w := bufio.NewWriter(gz)
+	for _, event := range events {
+		line, err := bson.MarshalExtJSON(event, true, false)
+		if err != nil {
+			return err
+		}
+		w.Write(line)
+		w.WriteByte('\n')
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+func (s *server) recordPurge(ctx context.Context, at time.Time, purged, archived int64) error {
+	_, err := s.jobs.UpdateOne(ctx,
+		bson.M{"_id": retentionJob},
+		bson.M{
+			"$set": bson.M{"last_purge_at": at.Format(time.RFC3339), "last_purged": purged},
+			"$inc": bson.M{"total_purged": purged, "total_archived": archived},
+		},
+		options.Update().SetUpsert(true))
+	return err
+}
+
+// GetRetentionStatus reports the retention policy, the oldest retained event
+// and what the purges have removed so far.
+func (s *server) GetRetentionStatus(ctx context.Context, req *pb.GetRetentionStatusRequest) (*pb.GetRetentionStatusResponse, error) {
+	resp := &pb.RetentionStatus{
+		RetentionDays:      int32(s.retention.age.Hours() / 24),
+		AuditRetentionDays: int32(s.retention.auditAge.Hours() / 24),
+		ArchiveDir:         s.retention.archiveDir,
+	}
+
+	var oldest Event
+	opts := options.FindOne().
+		SetSort(bson.D{{Key: "created_at", Value: 1}}).
+		SetProjection(bson.M{"created_at": 1})
+	err := s.collection.FindOne(ctx, bson.M{}, opts).Decode(&oldest)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+	resp.OldestEventAt = oldest.CreatedAt
+
+	var job struct {
+		LastPurgeAt   string `bson:"last_purge_at"`
+		LastPurged    int64  `bson:"last_purged"`
+		TotalPurged   int64  `bson:"total_purged"`
+		TotalArchived int64  `bson:"total_archived"`
+	}
+	err = s.jobs.FindOne(ctx, bson.M{"_id": retentionJob}).Decode(&job)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+	resp.LastPurgeAt = job.LastPurgeAt
+	resp.LastPurged = job.LastPurged
+	resp.TotalPurged = job.TotalPurged
+	resp.TotalArchived = job.TotalArchived
+
+	return &pb.GetRetentionStatusResponse{Status: resp}, nil
+}
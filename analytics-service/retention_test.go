@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetentionPolicyExpired(t *testing.T) {
+	now := time.Date(2024, 3, 31, 12, 0, 0, 0, time.UTC)
+	policy := retentionPolicy{age: 30 * 24 * time.Hour}
+
+	tests := []struct {
+		name      string
+		policy    retentionPolicy
+		createdAt string
+		want      bool
+	}{
+		{"kept forever", retentionPolicy{}, "2020-01-01T00:00:00Z", false},
+		{"past retention", policy, "2024-02-29T11:59:59Z", true},
+		{"at cutoff", policy, "2024-03-01T12:00:00Z", false},
+		{"recent", policy, "2024-03-30T00:00:00Z", false},
+		{"other zone", policy, "2024-03-01T13:00:00+02:00", true},
+		{"unparsable", policy, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.expired(tt.createdAt, now); got != tt.want {
+				t.Errorf("expired(%q) = %v, want %v", tt.createdAt, got, tt.want)
+			}
+		})
+	}
+}
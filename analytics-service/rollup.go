@@ -0,0 +1,285 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/technonext/todo-app/proto/tenant"
+)
+
+// Global task stats are served from stats_daily, one document per tenant and
+// UTC day summing the tenant's user_daily_stats across users. A job rolls up
+// every complete day; days after the last rolled-up one are read live, so
+// GetTaskStats touches per-user counters only for the current day or so.
+//
+// The job runs on one replica at a time: each run takes a lease on the job's
+// document in the jobs collection, which also records how far it has rolled.
+// Job documents are the service's own rather than a tenant's, so they are
+// read and written across tenants.
+
+const (
+	rollupJob = "stats_rollup"
+	// Events may be tracked up to maxEventAge late, so recent days are rolled
+	// up again on every run to pick up their changes
+	rollupLookback = maxEventAge + 24*time.Hour
+	// rollupLayout is the layout of stats_daily the job writes. Before 2 a
+	// day's document summed every tenant's counters; until a rollup in the
+	// current layout has run, days are read live and the next run rolls up
+	// all of them, under the tenants the migrations gave the counters.
+	rollupLayout = 2
+)
+
+// instanceID identifies this replica as a lease holder.
+var instanceID = fmt.Sprintf("%s-%d", hostname(), os.Getpid())
+
+func hostname() string {
+	name, err := os.Hostname()
+	if err != nil {
+		return "analytics"
+	}
+	return name
+}
+
+// startRollups runs the rollup job every interval until ctx is done.
+func (s *server) startRollups(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		// The lease outlives one interval so the leader renews it before
+		// another replica can take over
+		leader, err := s.acquireLease(ctx, rollupJob, 2*interval)
+		if err != nil {
+			log.Printf("Failed to acquire %s lease: %v", rollupJob, err)
+		} else if leader {
+			if err := s.rollupStats(ctx, false); err != nil {
+				log.Printf("Stats rollup failed: %v", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// acquireLease takes or renews the job's lease for ttl, reporting whether this
+// replica holds it.
+func (s *server) acquireLease(ctx context.Context, job string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	filter := bson.M{
+		"_id": job,
+		"$or": bson.A{
+			bson.M{"lease_holder": instanceID},
+			bson.M{"lease_expires_at": bson.M{"$lt": now}},
+			bson.M{"lease_expires_at": bson.M{"$exists": false}},
+		},
+	}
+	update := bson.M{"$set": bson.M{"lease_holder": instanceID, "lease_expires_at": now.Add(ttl)}}
+
+	_, err := s.jobs.UpdateOne(jobContext(ctx), filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		// Another replica holds an unexpired lease
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// jobContext returns ctx for the jobs collection, whose documents every
+// tenant shares.
+func jobContext(ctx context.Context) context.Context {
+	return tenant.WithID(ctx, tenant.All)
+}
+
+// rolledThrough returns the last day the rollup covers, or "" before the
+// first run in the current layout.
+func rolledThrough(ctx context.Context, jobs *tenant.Collection) (string, error) {
+	var job struct {
+		RolledThrough string `bson:"rolled_through"`
+		Layout        int    `bson:"layout"`
+	}
+	err := jobs.FindOne(jobContext(ctx), bson.M{"_id": rollupJob}).Decode(&job)
+	if err == mongo.ErrNoDocuments {
+		return "", nil
+	}
+	if err != nil || job.Layout < rollupLayout {
+		return "", err
+	}
+	return job.RolledThrough, nil
+}
+
+// tenantKey groups documents by tenant, the default tenant's under "".
+var tenantKey = bson.M{"$ifNull": bson.A{"$" + tenant.Field, ""}}
+
+// tenantOf is the tenant_id a pipeline stores for the tenantKey at path,
+// none for the default tenant.
+func tenantOf(path string) bson.M {
+	return bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{path, ""}}, "$$REMOVE", path}}
+}
+
+// rollupStats recomputes the daily summaries of complete days: all of them
+// when full is set or nothing was rolled up yet, otherwise the days since the
+// last run plus the lookback window. Every tenant's days are rolled up, as
+// far as the one day the job records.
+func (s *server) rollupStats(ctx context.Context, full bool) error {
+	ctx = tenant.WithID(ctx, tenant.All)
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	through := today.AddDate(0, 0, -1).Format(dayFormat)
+
+	from := ""
+	if !full {
+		last, err := rolledThrough(ctx, s.jobs)
+		if err != nil {
+			return err
+		}
+		if last != "" {
+			from = today.Add(-rollupLookback).Format(dayFormat)
+			if last < from {
+				from = last
+			}
+		}
+	}
+
+	days := bson.M{"$lte": through}
+	if from != "" {
+		days["$gte"] = from
+	} else {
+		// Days of older layouts are rolled up again under their tenants
+		if _, err := s.statsDaily.DeleteMany(ctx, bson.M{"day": bson.M{"$exists": false}}); err != nil {
+			return err
+		}
+	}
+
+	pipeline := []bson.M{
+		{"$match": bson.M{"day": days}},
+		{"$group": bson.M{
+			"_id":          bson.M{"tenant_id": tenantKey, "day": "$day"},
+			"created":      bson.M{"$sum": "$created"},
+			"completed":    bson.M{"$sum": "$completed"},
+			"active_users": bson.M{"$sum": activeUserDay},
+		}},
+		{"$project": bson.M{
+			"_id":          bson.M{"$concat": bson.A{"$_id.tenant_id", ":", "$_id.day"}},
+			tenant.Field:   tenantOf("$_id.tenant_id"),
+			"day":          "$_id.day",
+			"created":      1,
+			"completed":    1,
+			"active_users": 1,
+		}},
+		{"$merge": bson.M{
+			"into":           s.statsDaily.Name(),
+			"whenMatched":    "replace",
+			"whenNotMatched": "insert",
+		}},
+	}
+	cursor, err := s.dailyStats.Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	if err := cursor.Close(ctx); err != nil {
+		return err
+	}
+
+	_, err = s.jobs.UpdateOne(ctx,
+		bson.M{"_id": rollupJob},
+		bson.M{"$set": bson.M{"rolled_through": through, "rolled_up_at": time.Now(), "layout": rollupLayout}},
+		options.Update().SetUpsert(true))
+	return err
+}
+
+// globalTotals are the task counts summed across users over some days.
+// ActiveUsers sums each day's active users, so a user active on three of the
+// days counts three times.
+type globalTotals struct {
+	Created     int32 `bson:"created"`
+	Completed   int32 `bson:"completed"`
+	ActiveUsers int32 `bson:"active_users"`
+}
+
+func (t *globalTotals) add(o globalTotals) {
+	t.Created += o.Created
+	t.Completed += o.Completed
+	t.ActiveUsers += o.ActiveUsers
+}
+
+// GlobalTotals sums tasks created and completed and daily active users over
+// the days in r: rolled-up days from stats_daily, later ones from the live
+// counters.
+func (m *mongoRepository) GlobalTotals(ctx context.Context, r dateRange) (globalTotals, error) {
+	start := r.start.UTC().Format(dayFormat)
+	end := r.end.UTC().Format(dayFormat)
+
+	var totals globalTotals
+	through, err := rolledThrough(ctx, m.jobs)
+	if err != nil {
+		return totals, err
+	}
+
+	liveStart := start
+	if through != "" && start <= through {
+		rolledEnd := end
+		if through < rolledEnd {
+			rolledEnd = through
+		}
+		rolled, err := sumDays(ctx, m.statsDaily, bson.M{"day": bson.M{"$gte": start, "$lte": rolledEnd}}, "$active_users")
+		if err != nil {
+			return totals, err
+		}
+		totals.add(rolled)
+
+		next, err := time.Parse(dayFormat, through)
+		if err != nil {
+			return totals, err
+		}
+		liveStart = next.AddDate(0, 0, 1).Format(dayFormat)
+	}
+
+	if liveStart <= end {
+		// Each counter is one user's day, so the users active on a day are
+		// the counters with tasks created, as in the rollup
+		live, err := sumDays(ctx, m.dailyStats, bson.M{"day": bson.M{"$gte": liveStart, "$lte": end}}, activeUserDay)
+		if err != nil {
+			return totals, err
+		}
+		totals.add(live)
+	}
+	return totals, nil
+}
+
+// activeUserDay is 1 for a per-user daily counter of an active user, else 0.
+var activeUserDay = bson.M{"$cond": bson.A{bson.M{"$gt": bson.A{"$created", 0}}, 1, 0}}
+
+// sumDays totals the created and completed counts of the matching documents,
+// and the active users given by the active expression.
+func sumDays(ctx context.Context, collection *tenant.Collection, filter bson.M, active interface{}) (globalTotals, error) {
+	var totals globalTotals
+	cursor, err := collection.Aggregate(ctx, []bson.M{
+		{"$match": filter},
+		{"$group": bson.M{
+			"_id":          nil,
+			"created":      bson.M{"$sum": "$created"},
+			"completed":    bson.M{"$sum": "$completed"},
+			"active_users": bson.M{"$sum": active},
+		}},
+	})
+	if err != nil {
+		return totals, err
+	}
+	defer cursor.Close(ctx)
+
+	if cursor.Next(ctx) {
+		if err := cursor.Decode(&totals); err != nil {
+			return totals, err
+		}
+	}
+	return totals, cursor.Err()
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// statsService holds the logic of the core stats RPCs: tracking events and
+// reading user and task stats. It reaches storage only through the
+// repositories, so it is tested against an in-memory fake; server embeds it
+// and adds the RPCs that still work on the collections.
+type statsService struct {
+	events EventsRepository
+	stats  StatsRepository
+	// Overdue counts come from the task service
+	tasks      pb.TaskServiceClient
+	eventTypes eventTypeRegistry
+	limits     *eventLimiter
+	cache      *readCache
+	// Live subscribers to the events tracked
+	bus *EventBus
+}
+
+func (s *statsService) trackEvent(ctx context.Context, req *pb.TrackEventRequest) (*pb.TrackEventResponse, error) {
+	event, err := s.eventFromRequest(req, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	events := []Event{event}
+	admitted, err := s.limits.admit(ctx, events)
+	if err != nil {
+		return nil, err
+	}
+	if admitted[0] == errSampledOut {
+		return &pb.TrackEventResponse{SampledOut: true}, nil
+	}
+	if admitted[0] != nil {
+		return nil, admitted[0]
+	}
+	event = events[0]
+
+	oid, err := s.events.InsertEvent(ctx, event)
+	if err == errEventExists {
+		// A client retrying an event it already tracked is not an error
+		return &pb.TrackEventResponse{Duplicate: true}, nil
+	}
+	if err != nil {
+		log.Printf("Failed to track event: %v", err)
+		return nil, err
+	}
+
+	// The event is already stored, so a failed counter update is only logged;
+	// the backfill rebuilds the counters from events
+	if err := s.stats.ApplyEvent(ctx, event); err != nil {
+		log.Printf("Failed to update counters for %s event: %v", event.EventType, err)
+	}
+	// Only once the counters are updated, or a read in between would cache
+	// them stale
+	s.cache.invalidateUser(event.UserID)
+
+	event.ID = oid
+	s.bus.publish(ctx, event)
+
+	return &pb.TrackEventResponse{Event: event.toProto()}, nil
+}
+
+// getUserStats returns the latest daily snapshot of the user's stats for
+// default requests, and otherwise computes them live.
+func (s *statsService) getUserStats(ctx context.Context, req *pb.GetUserStatsRequest) (*pb.GetUserStatsResponse, error) {
+	timezone := req.Timezone
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	loc, err := parseTimezone(timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse date range
+	dates, err := parseDateRange(req.StartDate, req.EndDate, time.UTC)
+	if err != nil {
+		return nil, err
+	}
+
+	// Snapshots cover the default range in UTC, so only they can use one
+	if !req.Fresh && req.StartDate == "" && req.EndDate == "" && timezone == "UTC" {
+		snapshot, err := s.stats.LatestSnapshot(ctx, req.UserId)
+		if err != nil {
+			return nil, err
+		}
+		if snapshot != nil {
+			return &pb.GetUserStatsResponse{Stats: snapshot.toProto(), SnapshotAt: snapshot.SnapshotAt}, nil
+		}
+	}
+
+	stats, err := s.computeUserStats(ctx, req.UserId, dates, loc, req.Fresh)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.GetUserStatsResponse{Stats: stats}, nil
+}
+
+// computeUserStats reads the user's counters: tasks created and completed in
+// the date range (resolved to whole UTC days), and the current pending and
+// overdue counts. Overdue depends on due dates, which events do not carry, so
+// it is asked of the task service, and listed as unavailable when that fails. The streaks and productivity score use
+// days in loc; the streak is recomputed when fresh is set, and read from the
+// saved one otherwise.
+func (s *statsService) computeUserStats(ctx context.Context, userID string, dates dateRange, loc *time.Location, fresh bool) (*pb.UserStats, error) {
+	totalTasks, completedTasks, err := s.stats.RangeTotals(ctx, userID, dates)
+	if err != nil {
+		return nil, err
+	}
+
+	pendingTasks, err := s.stats.OpenTasks(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The rest is local, so the stats go without overdue rather than fail
+	var unavailable []string
+	pending, err := s.tasks.GetPendingTaskCount(ctx, &pb.GetPendingTaskCountRequest{UserId: userID})
+	if err != nil {
+		log.Printf("Failed to get overdue tasks for user %s: %v", userID, err)
+		pending = &pb.GetPendingTaskCountResponse{}
+		unavailable = append(unavailable, "overdue_tasks")
+	}
+
+	onTime, dueDated, err := s.stats.OnTimeCompletions(ctx, userID, dates)
+	if err != nil {
+		return nil, err
+	}
+
+	st, err := s.userStreak(ctx, userID, loc, fresh)
+	if err != nil {
+		return nil, err
+	}
+
+	latency, err := s.stats.CompletionLatency(ctx, userID, dates)
+	if err != nil {
+		return nil, err
+	}
+
+	extensions, err := s.stats.DueDateExtensions(ctx, userID, dates)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.UserStats{
+		TotalTasks:           totalTasks,
+		CompletedTasks:       completedTasks,
+		PendingTasks:         pendingTasks,
+		OverdueTasks:         pending.Overdue,
+		ProductivityScore:    productivityScore(totalTasks, completedTasks, onTime, dueDated, st.current),
+		CurrentStreak:        st.current,
+		LongestStreak:        st.longest,
+		CompletionLatency:    latency.toProto(),
+		StreakStartDate:      st.startDay,
+		Unavailable:          unavailable,
+		AvgExtensionsPerTask: avgExtensionsPerTask(extensions, totalTasks),
+	}, nil
+}
+
+// getTaskStats sums the daily rollups over the date range, reading the days
+// not rolled up yet from the per-user counters. Active users are summed per
+// day too, so they count user-days rather than distinct users.
+func (s *statsService) getTaskStats(ctx context.Context, req *pb.GetTaskStatsRequest) (*pb.GetTaskStatsResponse, error) {
+	// Parse date range
+	dates, err := parseDateRange(req.StartDate, req.EndDate, time.UTC)
+	if err != nil {
+		return nil, err
+	}
+
+	totals, err := s.stats.GlobalTotals(ctx, dates)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.GetTaskStatsResponse{
+		Stats: &pb.TaskStats{
+			TotalTasks:     totals.Created,
+			CompletedTasks: totals.Completed,
+			ActiveUsers:    totals.ActiveUsers,
+		},
+	}, nil
+}
+
+// The stats RPCs are served by the embedded statsService.
+
+func (s *server) TrackEvent(ctx context.Context, req *pb.TrackEventRequest) (*pb.TrackEventResponse, error) {
+	return s.trackEvent(ctx, req)
+}
+
+func (s *server) GetUserStats(ctx context.Context, req *pb.GetUserStatsRequest) (*pb.GetUserStatsResponse, error) {
+	return s.getUserStats(ctx, req)
+}
+
+func (s *server) GetTaskStats(ctx context.Context, req *pb.GetTaskStatsRequest) (*pb.GetTaskStatsResponse, error) {
+	return s.getTaskStats(ctx, req)
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// daysAgo returns noon UTC n days ago, as an RFC3339 timestamp and a date.
+func daysAgo(n int) (string, string) {
+	t := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -n).Add(12 * time.Hour)
+	return t.Format(time.RFC3339), t.Format(dayFormat)
+}
+
+func track(t *testing.T, s *statsService, req *pb.TrackEventRequest) {
+	t.Helper()
+	if _, err := s.trackEvent(context.Background(), req); err != nil {
+		t.Fatalf("tracking %s: %v", req.EventType, err)
+	}
+}
+
+func TestTrackEvent(t *testing.T) {
+	future := time.Now().Add(time.Hour).Format(time.RFC3339)
+	old := time.Now().Add(-maxEventAge - time.Hour).Format(time.RFC3339)
+
+	tests := []struct {
+		name     string
+		req      *pb.TrackEventRequest
+		repoErr  error
+		wantCode codes.Code
+	}{
+		{"stored", &pb.TrackEventRequest{UserId: "u1", EventType: "task.created", ResourceId: "t1"}, nil, codes.OK},
+		{"missing user", &pb.TrackEventRequest{EventType: "task.created"}, nil, codes.InvalidArgument},
+		{"missing type", &pb.TrackEventRequest{UserId: "u1"}, nil, codes.InvalidArgument},
+		{"unknown type", &pb.TrackEventRequest{UserId: "u1", EventType: "task.craeted"}, nil, codes.InvalidArgument},
+		{"bad timestamp", &pb.TrackEventRequest{UserId: "u1", EventType: "app_opened", ClientTimestamp: "yesterday"}, nil, codes.InvalidArgument},
+		{"timestamp in future", &pb.TrackEventRequest{UserId: "u1", EventType: "app_opened", ClientTimestamp: future}, nil, codes.InvalidArgument},
+		{"timestamp too old", &pb.TrackEventRequest{UserId: "u1", EventType: "app_opened", ClientTimestamp: old}, nil, codes.InvalidArgument},
+		{"storage failure", &pb.TrackEventRequest{UserId: "u1", EventType: "app_opened"}, errors.New("disk full"), codes.Unknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeRepository()
+			repo.err["InsertEvent"] = tt.repoErr
+			resp, err := newTestService(repo, &fakeTaskClient{}).trackEvent(context.Background(), tt.req)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("code = %v, want %v (%v)", code, tt.wantCode, err)
+			}
+			if tt.wantCode != codes.OK {
+				return
+			}
+			if resp.Event.Id == "" || len(repo.events) != 1 {
+				t.Errorf("event not stored: %+v", resp.Event)
+			}
+			if repo.open["u1"] != 1 {
+				t.Errorf("open tasks = %d, want 1", repo.open["u1"])
+			}
+		})
+	}
+}
+
+func TestTrackEventDuplicateClientEventID(t *testing.T) {
+	repo := newFakeRepository()
+	s := newTestService(repo, &fakeTaskClient{})
+	req := &pb.TrackEventRequest{UserId: "u1", EventType: "task.created", ClientEventId: "c1"}
+	track(t, s, req)
+
+	resp, err := s.trackEvent(context.Background(), req)
+	if err != nil {
+		t.Fatalf("retry failed: %v", err)
+	}
+	if !resp.Duplicate || resp.Event != nil {
+		t.Errorf("duplicate, event = %v, %v, want true, nil", resp.Duplicate, resp.Event)
+	}
+	if repo.open["u1"] != 1 {
+		t.Errorf("duplicate was counted: open tasks = %d", repo.open["u1"])
+	}
+}
+
+func TestTrackEventCounterFailureStillStores(t *testing.T) {
+	repo := newFakeRepository()
+	repo.err["ApplyEvent"] = errors.New("counters unavailable")
+	s := newTestService(repo, &fakeTaskClient{})
+	track(t, s, &pb.TrackEventRequest{UserId: "u1", EventType: "task.created"})
+	if len(repo.events) != 1 {
+		t.Errorf("stored %d events, want 1", len(repo.events))
+	}
+}
+
+func TestGetUserStatsDateRange(t *testing.T) {
+	repo := newFakeRepository()
+	s := newTestService(repo, &fakeTaskClient{overdue: 2})
+
+	at5, _ := daysAgo(5)
+	at3, day3 := daysAgo(3)
+	at1, _ := daysAgo(1)
+	_, day4 := daysAgo(4)
+	_, day2 := daysAgo(2)
+	onTime, _ := structpb.NewStruct(map[string]interface{}{"on_time": true})
+	for i, at := range []string{at5, at3, at3, at1} {
+		id := string(rune('a' + i))
+		track(t, s, &pb.TrackEventRequest{UserId: "u1", EventType: "task.created", ResourceId: id, ClientTimestamp: at})
+	}
+	track(t, s, &pb.TrackEventRequest{UserId: "u1", EventType: "task.completed", ResourceId: "b", ClientTimestamp: at3, Metadata: onTime})
+	track(t, s, &pb.TrackEventRequest{UserId: "u2", EventType: "task.created", ResourceId: "x", ClientTimestamp: at3})
+
+	tests := []struct {
+		name                       string
+		req                        *pb.GetUserStatsRequest
+		wantCode                   codes.Code
+		wantCreated, wantCompleted int32
+	}{
+		{"default range", &pb.GetUserStatsRequest{UserId: "u1"}, codes.OK, 4, 1},
+		{"plain dates", &pb.GetUserStatsRequest{UserId: "u1", StartDate: day4, EndDate: day2}, codes.OK, 2, 1},
+		{"single day", &pb.GetUserStatsRequest{UserId: "u1", StartDate: day3, EndDate: day3}, codes.OK, 2, 1},
+		{"other user", &pb.GetUserStatsRequest{UserId: "u2", StartDate: day3, EndDate: day3}, codes.OK, 1, 0},
+		{"invalid start", &pb.GetUserStatsRequest{UserId: "u1", StartDate: "last week"}, codes.InvalidArgument, 0, 0},
+		{"start after end", &pb.GetUserStatsRequest{UserId: "u1", StartDate: day2, EndDate: day4}, codes.InvalidArgument, 0, 0},
+		{"range too long", &pb.GetUserStatsRequest{UserId: "u1", StartDate: "2020-01-01", EndDate: "2022-01-01"}, codes.InvalidArgument, 0, 0},
+		{"invalid timezone", &pb.GetUserStatsRequest{UserId: "u1", Timezone: "Mars/Olympus"}, codes.InvalidArgument, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := s.getUserStats(context.Background(), tt.req)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("code = %v, want %v (%v)", code, tt.wantCode, err)
+			}
+			if err != nil {
+				return
+			}
+			if resp.Stats.TotalTasks != tt.wantCreated || resp.Stats.CompletedTasks != tt.wantCompleted {
+				t.Errorf("created, completed = %d, %d, want %d, %d", resp.Stats.TotalTasks, resp.Stats.CompletedTasks, tt.wantCreated, tt.wantCompleted)
+			}
+		})
+	}
+
+	resp, err := s.getUserStats(context.Background(), &pb.GetUserStatsRequest{UserId: "u1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.Stats; got.PendingTasks != 3 || got.OverdueTasks != 2 || got.CompletionLatency.Count != 1 {
+		t.Errorf("pending, overdue, latency count = %d, %d, %d, want 3, 2, 1", got.PendingTasks, got.OverdueTasks, got.CompletionLatency.Count)
+	}
+}
+
+func TestGetUserStatsAvgExtensionsPerTask(t *testing.T) {
+	repo := newFakeRepository()
+	s := newTestService(repo, &fakeTaskClient{})
+
+	at3, day3 := daysAgo(3)
+	at1, day1 := daysAgo(1)
+	for _, id := range []string{"a", "b"} {
+		track(t, s, &pb.TrackEventRequest{UserId: "u1", EventType: "task.created", ResourceId: id, ClientTimestamp: at3})
+	}
+	for _, at := range []string{at3, at3, at1} {
+		track(t, s, &pb.TrackEventRequest{UserId: "u1", EventType: "task.due_date_extended", ResourceId: "a", ClientTimestamp: at})
+	}
+	track(t, s, &pb.TrackEventRequest{UserId: "u2", EventType: "task.due_date_extended", ResourceId: "x", ClientTimestamp: at3})
+
+	tests := []struct {
+		name string
+		req  *pb.GetUserStatsRequest
+		want float64
+	}{
+		{"default range", &pb.GetUserStatsRequest{UserId: "u1"}, 1.5},
+		{"day of creation", &pb.GetUserStatsRequest{UserId: "u1", StartDate: day3, EndDate: day3}, 1},
+		{"no tasks created", &pb.GetUserStatsRequest{UserId: "u1", StartDate: day1, EndDate: day1}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := s.getUserStats(context.Background(), tt.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := resp.Stats.AvgExtensionsPerTask; got != tt.want {
+				t.Errorf("avg extensions per task = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetUserStatsTaskServiceDown(t *testing.T) {
+	tests := []struct {
+		name            string
+		tasks           *fakeTaskClient
+		wantOverdue     int32
+		wantUnavailable []string
+	}{
+		{"task service up", &fakeTaskClient{overdue: 2}, 2, nil},
+		{"task service down", &fakeTaskClient{err: status.Error(codes.Unavailable, "connection refused")}, 0, []string{"overdue_tasks"}},
+		{"task service slow", &fakeTaskClient{err: status.Error(codes.DeadlineExceeded, "deadline exceeded")}, 0, []string{"overdue_tasks"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeRepository()
+			s := newTestService(repo, tt.tasks)
+			at1, _ := daysAgo(1)
+			track(t, s, &pb.TrackEventRequest{UserId: "u1", EventType: "task.created", ResourceId: "a", ClientTimestamp: at1})
+
+			resp, err := s.getUserStats(context.Background(), &pb.GetUserStatsRequest{UserId: "u1", Fresh: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := resp.Stats
+			if got.TotalTasks != 1 || got.PendingTasks != 1 {
+				t.Errorf("created, pending = %d, %d, want 1, 1", got.TotalTasks, got.PendingTasks)
+			}
+			if got.OverdueTasks != tt.wantOverdue {
+				t.Errorf("overdue = %d, want %d", got.OverdueTasks, tt.wantOverdue)
+			}
+			if !slices.Equal(got.Unavailable, tt.wantUnavailable) {
+				t.Errorf("unavailable = %v, want %v", got.Unavailable, tt.wantUnavailable)
+			}
+		})
+	}
+}
+
+func TestGetUserStatsSnapshot(t *testing.T) {
+	repo := newFakeRepository()
+	repo.snapshots["u1"] = &UserStatsSnapshot{UserID: "u1", TotalTasks: 9, SnapshotAt: "2026-01-01T00:00:00Z"}
+	s := newTestService(repo, &fakeTaskClient{})
+
+	tests := []struct {
+		name         string
+		req          *pb.GetUserStatsRequest
+		wantSnapshot bool
+	}{
+		{"default request", &pb.GetUserStatsRequest{UserId: "u1"}, true},
+		{"fresh", &pb.GetUserStatsRequest{UserId: "u1", Fresh: true}, false},
+		{"date range", &pb.GetUserStatsRequest{UserId: "u1", StartDate: "2026-01-01"}, false},
+		{"timezone", &pb.GetUserStatsRequest{UserId: "u1", Timezone: "Asia/Dhaka"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := s.getUserStats(context.Background(), tt.req)
+			if err != nil && status.Code(err) != codes.InvalidArgument {
+				t.Fatal(err)
+			}
+			if err != nil {
+				return
+			}
+			if got := resp.SnapshotAt != ""; got != tt.wantSnapshot {
+				t.Errorf("served from snapshot = %v, want %v", got, tt.wantSnapshot)
+			}
+		})
+	}
+}
+
+func TestGetTaskStats(t *testing.T) {
+	repo := newFakeRepository()
+	s := newTestService(repo, &fakeTaskClient{})
+	at3, day3 := daysAgo(3)
+	at1, day1 := daysAgo(1)
+	track(t, s, &pb.TrackEventRequest{UserId: "u1", EventType: "task.created", ResourceId: "a", ClientTimestamp: at3})
+	track(t, s, &pb.TrackEventRequest{UserId: "u2", EventType: "task.created", ResourceId: "b", ClientTimestamp: at1})
+	track(t, s, &pb.TrackEventRequest{UserId: "u2", EventType: "task.completed", ResourceId: "b", ClientTimestamp: at1})
+	track(t, s, &pb.TrackEventRequest{UserId: "u1", EventType: "task.created", ResourceId: "c", ClientTimestamp: at1})
+
+	// Active users are summed per day: u1 is active on both days
+	tests := []struct {
+		name                                   string
+		req                                    *pb.GetTaskStatsRequest
+		wantCode                               codes.Code
+		wantCreated, wantCompleted, wantActive int32
+	}{
+		{"default range", &pb.GetTaskStatsRequest{}, codes.OK, 3, 1, 3},
+		{"one day", &pb.GetTaskStatsRequest{StartDate: day3, EndDate: day3}, codes.OK, 1, 0, 1},
+		{"later day", &pb.GetTaskStatsRequest{StartDate: day1, EndDate: day1}, codes.OK, 2, 1, 2},
+		{"invalid end", &pb.GetTaskStatsRequest{EndDate: "soon"}, codes.InvalidArgument, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := s.getTaskStats(context.Background(), tt.req)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("code = %v, want %v (%v)", code, tt.wantCode, err)
+			}
+			if err != nil {
+				return
+			}
+			got := resp.Stats
+			if got.TotalTasks != tt.wantCreated || got.CompletedTasks != tt.wantCompleted || got.ActiveUsers != tt.wantActive {
+				t.Errorf("stats = %d, %d, %d, want %d, %d, %d", got.TotalTasks, got.CompletedTasks, got.ActiveUsers, tt.wantCreated, tt.wantCompleted, tt.wantActive)
+			}
+		})
+	}
+}
+
+// runOwnershipMigrationTest checks that a task.ownership_migrated event moves
+// the counters of the user whose tasks moved onto the user who has them now.
+func runOwnershipMigrationTest(t *testing.T, s *statsService) {
+	ctx := context.Background()
+	at3, _ := daysAgo(3)
+	at1, _ := daysAgo(1)
+	track(t, s, &pb.TrackEventRequest{UserId: "alice", EventType: "task.created", ResourceId: "a", ClientTimestamp: at3})
+	track(t, s, &pb.TrackEventRequest{UserId: "alice", EventType: "task.created", ResourceId: "b", ClientTimestamp: at1})
+	track(t, s, &pb.TrackEventRequest{UserId: "alice", EventType: "task.completed", ResourceId: "b", ClientTimestamp: at1})
+	track(t, s, &pb.TrackEventRequest{UserId: "bob", EventType: "task.created", ResourceId: "c", ClientTimestamp: at1})
+
+	r := dateRange{start: time.Now().AddDate(0, 0, -7), end: time.Now()}
+	check := func(when, userID string, wantCreated, wantCompleted, wantOpen int32) {
+		t.Helper()
+		created, completed, err := s.stats.RangeTotals(ctx, userID, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		open, err := s.stats.OpenTasks(ctx, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if created != wantCreated || completed != wantCompleted || open != wantOpen {
+			t.Errorf("%s: %s created %d, completed %d, has %d open; want %d, %d and %d",
+				when, userID, created, completed, open, wantCreated, wantCompleted, wantOpen)
+		}
+	}
+
+	// Delivered twice, the event moves the counters once
+	for _, when := range []string{"migrated", "migrated again"} {
+		track(t, s, &pb.TrackEventRequest{UserId: "bob", EventType: "task.ownership_migrated", ResourceId: "alice"})
+		check(when, "alice", 0, 0, 0)
+		check(when, "bob", 3, 1, 2)
+	}
+}
+
+func TestOwnershipMigrationMovesCounters(t *testing.T) {
+	runOwnershipMigrationTest(t, newTestService(newFakeRepository(), &fakeTaskClient{}))
+}
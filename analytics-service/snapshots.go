@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+	"github.com/technonext/todo-app/proto/tenant"
+)
+
+// Once a day the consolidator snapshots every user's default stats into
+// daily_user_stats, so GetUserStats can answer most requests with one read.
+
+const snapshotJob = "stats_snapshot"
+
+// UserStatsSnapshot is one user's stats for the default range as of a day.
+type UserStatsSnapshot struct {
+	UserID               string            `bson:"user_id"`
+	Date                 string            `bson:"date"`
+	TotalTasks           int32             `bson:"total_tasks"`
+	CompletedTasks       int32             `bson:"completed_tasks"`
+	PendingTasks         int32             `bson:"pending_tasks"`
+	OverdueTasks         int32             `bson:"overdue_tasks"`
+	ProductivityScore    float64           `bson:"productivity_score"`
+	CurrentStreak        int32             `bson:"current_streak"`
+	LongestStreak        int32             `bson:"longest_streak"`
+	StreakStartDate      string            `bson:"streak_start_date,omitempty"`
+	CompletionLatency    completionLatency `bson:"completion_latency"`
+	AvgExtensionsPerTask float64           `bson:"avg_extensions_per_task"`
+	SnapshotAt           string            `bson:"snapshot_at"`
+}
+
+func (u UserStatsSnapshot) toProto() *pb.UserStats {
+	return &pb.UserStats{
+		TotalTasks:           u.TotalTasks,
+		CompletedTasks:       u.CompletedTasks,
+		PendingTasks:         u.PendingTasks,
+		OverdueTasks:         u.OverdueTasks,
+		ProductivityScore:    u.ProductivityScore,
+		CurrentStreak:        u.CurrentStreak,
+		LongestStreak:        u.LongestStreak,
+		CompletionLatency:    u.CompletionLatency.toProto(),
+		StreakStartDate:      u.StreakStartDate,
+		AvgExtensionsPerTask: u.AvgExtensionsPerTask,
+	}
+}
+
+// LatestSnapshot returns the user's most recent snapshot, or nil if there is
+// none yet.
+func (m *mongoRepository) LatestSnapshot(ctx context.Context, userID string) (*UserStatsSnapshot, error) {
+	var snapshot UserStatsSnapshot
+	opts := options.FindOne().SetSort(bson.D{{Key: "date", Value: -1}})
+	err := m.snapshots.FindOne(ctx, bson.M{"user_id": userID}, opts).Decode(&snapshot)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// startStatsConsolidator snapshots user stats every day at hour (UTC). One
+// replica takes the day's lease and does the work.
+func (s *server) startStatsConsolidator(ctx context.Context, hour int) {
+	for {
+		now := time.Now().UTC()
+		next := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, time.UTC)
+		if !next.After(now) {
+			next = next.AddDate(0, 0, 1)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(next)):
+		}
+
+		leader, err := s.acquireLease(ctx, snapshotJob, 23*time.Hour)
+		if err != nil {
+			log.Printf("Failed to acquire %s lease: %v", snapshotJob, err)
+			continue
+		}
+		if !leader {
+			continue
+		}
+		if err := s.consolidateUserStats(ctx); err != nil {
+			log.Printf("Stats snapshot failed: %v", err)
+		}
+	}
+}
+
+// consolidateUserStats snapshots the stats of every user who has tasks, each
+// in their tenant.
+func (s *server) consolidateUserStats(ctx context.Context) error {
+	ctx = tenant.WithID(ctx, tenant.All)
+	cursor, err := s.userCounters.Find(ctx, bson.M{}, options.Find().SetProjection(bson.M{"_id": 1, tenant.Field: 1}))
+	if err != nil {
+		return err
+	}
+	var counters []struct {
+		UserID   string `bson:"_id"`
+		TenantID string `bson:"tenant_id"`
+	}
+	if err := cursor.All(ctx, &counters); err != nil {
+		return err
+	}
+	tenants := make(map[string][]string)
+	for _, c := range counters {
+		if c.UserID != "" {
+			tenants[c.TenantID] = append(tenants[c.TenantID], c.UserID)
+		}
+	}
+
+	now := time.Now()
+	var errs []error
+	for tenantID, userIDs := range tenants {
+		if err := s.snapshotUsers(tenant.WithID(ctx, tenantID), userIDs, now); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// snapshotUsers saves the stats of each user as of now. A user whose stats
+// cannot be computed or saved does not stop the others; the failures are
+// returned together once every user has been tried.
+func (s *statsService) snapshotUsers(ctx context.Context, userIDs []string, now time.Time) error {
+	date := now.UTC().Format(dayFormat)
+	dates := dateRange{start: now.AddDate(0, -1, 0), end: now}
+
+	var errs []error
+	for _, userID := range userIDs {
+		if err := s.snapshotUser(ctx, userID, date, dates); err != nil {
+			errs = append(errs, fmt.Errorf("user %s: %w", userID, err))
+		}
+	}
+
+	log.Printf("Stats snapshot taken for %d of %d users", len(userIDs)-len(errs), len(userIDs))
+	if len(errs) > 0 {
+		return fmt.Errorf("snapshot failed for %d users: %w", len(errs), errors.Join(errs...))
+	}
+	return nil
+}
+
+func (s *statsService) snapshotUser(ctx context.Context, userID, date string, dates dateRange) error {
+	stats, err := s.computeUserStats(ctx, userID, dates, time.UTC, true)
+	if err != nil {
+		return err
+	}
+	if len(stats.Unavailable) > 0 {
+		// A snapshot would serve the missing fields as zero all day
+		return fmt.Errorf("%s unavailable", strings.Join(stats.Unavailable, ", "))
+	}
+
+	return s.stats.SaveSnapshot(ctx, UserStatsSnapshot{
+		UserID:            userID,
+		Date:              date,
+		TotalTasks:        stats.TotalTasks,
+		CompletedTasks:    stats.CompletedTasks,
+		PendingTasks:      stats.PendingTasks,
+		OverdueTasks:      stats.OverdueTasks,
+		ProductivityScore: stats.ProductivityScore,
+		CurrentStreak:     stats.CurrentStreak,
+		LongestStreak:     stats.LongestStreak,
+		StreakStartDate:   stats.StreakStartDate,
+		CompletionLatency: completionLatency{
+			Count: stats.CompletionLatency.GetCount(),
+			Avg:   stats.CompletionLatency.GetAvgSeconds(),
+			P50:   stats.CompletionLatency.GetP50Seconds(),
+			P90:   stats.CompletionLatency.GetP90Seconds(),
+		},
+		AvgExtensionsPerTask: stats.AvgExtensionsPerTask,
+		SnapshotAt:           time.Now().Format(time.RFC3339),
+	})
+}
+
+// SaveSnapshot stores the snapshot, replacing any the user has for its date.
+func (m *mongoRepository) SaveSnapshot(ctx context.Context, snapshot UserStatsSnapshot) error {
+	_, err := m.snapshots.ReplaceOne(ctx,
+		bson.M{"user_id": snapshot.UserID, "date": snapshot.Date},
+		snapshot,
+		options.Replace().SetUpsert(true))
+	return err
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+func TestSnapshotUsers(t *testing.T) {
+	errStore := errors.New("store unavailable")
+	tests := []struct {
+		name          string
+		tasks         *fakeTaskClient
+		repoErr       map[string]error
+		wantSaved     int
+		wantErr       string
+		wantUnwrapped error
+	}{
+		{"all saved", &fakeTaskClient{overdue: 1}, nil, 2, "", nil},
+		{"stats fail", &fakeTaskClient{}, map[string]error{"RangeTotals": errStore}, 0, "snapshot failed for 2 users", errStore},
+		{"save fails", &fakeTaskClient{}, map[string]error{"SaveSnapshot": errStore}, 0, "user u1: store unavailable", errStore},
+		{"overdue unavailable", &fakeTaskClient{err: status.Error(codes.Unavailable, "down")}, nil, 0, "overdue_tasks unavailable", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeRepository()
+			s := newTestService(repo, tt.tasks)
+			for _, userID := range []string{"u1", "u2"} {
+				track(t, s, &pb.TrackEventRequest{UserId: userID, EventType: "task.created", ResourceId: userID + "-a"})
+			}
+			for method, err := range tt.repoErr {
+				repo.err[method] = err
+			}
+
+			err := s.snapshotUsers(context.Background(), []string{"u1", "u2"}, time.Now())
+			if tt.wantErr == "" && err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("err = %v, want it to contain %q", err, tt.wantErr)
+			}
+			if tt.wantUnwrapped != nil && !errors.Is(err, tt.wantUnwrapped) {
+				t.Errorf("err = %v does not wrap %v", err, tt.wantUnwrapped)
+			}
+			if len(repo.snapshots) != tt.wantSaved {
+				t.Errorf("saved %d snapshots, want %d", len(repo.snapshots), tt.wantSaved)
+			}
+		})
+	}
+}
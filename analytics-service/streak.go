@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/grpc/codes"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// streak is a user's run of consecutive days with at least one completed task.
+type streak struct {
+	current  int32
+	longest  int32
+	lastDay  string
+	startDay string
+	// The run of days ending at lastDay, kept so a saved streak can be
+	// brought up to date: it stops being current once a day is missed
+	run      int32
+	runStart string
+}
+
+// Streaks are saved per user and timezone in user_streaks, so stats can be
+// served without walking the user's completion history. A saved streak is
+// refreshed whenever it is computed, which GetUserStats does when asked for
+// fresh stats.
+type savedStreak struct {
+	ID        string `bson:"_id"`
+	UserID    string `bson:"user_id"`
+	Timezone  string `bson:"timezone"`
+	Longest   int32  `bson:"longest_streak"`
+	LastDay   string `bson:"last_completed_day"`
+	Run       int32  `bson:"run"`
+	RunStart  string `bson:"run_start"`
+	UpdatedAt string `bson:"updated_at"`
+}
+
+func streakID(userID string, loc *time.Location) string {
+	return userID + ":" + loc.String()
+}
+
+// asOf sets the current streak to the run ending at lastDay if that ends
+// today or yesterday, and clears it otherwise.
+func (st streak) asOf(today string) streak {
+	st.current, st.startDay = 0, ""
+	last, err := time.Parse(dayFormat, st.lastDay)
+	if err != nil {
+		return st
+	}
+	now, err := time.Parse(dayFormat, today)
+	if err != nil {
+		return st
+	}
+	if last.Equal(now) || last.Equal(now.AddDate(0, 0, -1)) {
+		st.current, st.startDay = st.run, st.runStart
+	}
+	return st
+}
+
+func (s *server) GetUserStreak(ctx context.Context, req *pb.GetUserStreakRequest) (*pb.GetUserStreakResponse, error) {
+	if req.UserId == "" {
+		return nil, statusError(codes.InvalidArgument, "USER_ID_REQUIRED", nil, "user_id is required")
+	}
+	timezone := req.Timezone
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	loc, err := parseTimezone(timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	st, err := s.userStreak(ctx, req.UserId, loc, true)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.GetUserStreakResponse{
+		CurrentStreak:    st.current,
+		LongestStreak:    st.longest,
+		LastCompletedDay: st.lastDay,
+		StreakStartDate:  st.startDay,
+	}, nil
+}
+
+// userStreak returns the user's saved streak brought up to today, computing
+// and saving it when fresh is set or nothing is saved yet.
+func (s *statsService) userStreak(ctx context.Context, userID string, loc *time.Location, fresh bool) (streak, error) {
+	if !fresh {
+		saved, err := s.stats.SavedStreak(ctx, userID, loc)
+		if err != nil {
+			return streak{}, err
+		}
+		if saved != nil {
+			return saved.asOf(time.Now().In(loc).Format(dayFormat)), nil
+		}
+	}
+
+	st, err := s.stats.Streak(ctx, userID, loc)
+	if err != nil {
+		return streak{}, err
+	}
+	if err := s.stats.SaveStreak(ctx, userID, loc, st); err != nil {
+		// The computed streak is still right; the next read recomputes it
+		log.Printf("Failed to save streak for user %s: %v", userID, err)
+	}
+	return st, nil
+}
+
+// SavedStreak returns the streak last saved for the user in loc, or nil if
+// there is none.
+func (m *mongoRepository) SavedStreak(ctx context.Context, userID string, loc *time.Location) (*streak, error) {
+	var saved savedStreak
+	err := m.streaks.FindOne(ctx, bson.M{"_id": streakID(userID, loc)}).Decode(&saved)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &streak{longest: saved.Longest, lastDay: saved.LastDay, run: saved.Run, runStart: saved.RunStart}, nil
+}
+
+func (m *mongoRepository) SaveStreak(ctx context.Context, userID string, loc *time.Location, st streak) error {
+	saved := savedStreak{
+		ID:        streakID(userID, loc),
+		UserID:    userID,
+		Timezone:  loc.String(),
+		Longest:   st.longest,
+		LastDay:   st.lastDay,
+		Run:       st.run,
+		RunStart:  st.runStart,
+		UpdatedAt: time.Now().Format(time.RFC3339),
+	}
+	_, err := m.streaks.ReplaceOne(ctx, bson.M{"_id": saved.ID}, saved, options.Replace().SetUpsert(true))
+	return err
+}
+
+// Streak computes the user's streaks from their completion events, with
+// days taken in loc.
+func (m *mongoRepository) Streak(ctx context.Context, userID string, loc *time.Location) (streak, error) {
+	days, err := m.completionDays(ctx, userID, loc)
+	if err != nil {
+		return streak{}, err
+	}
+	return streakOf(days, time.Now().In(loc).Format(dayFormat)), nil
+}
+
+// completionDays lists the distinct days, oldest first, on which the user
+// completed a task.
+func (m *mongoRepository) completionDays(ctx context.Context, userID string, loc *time.Location) ([]string, error) {
+	pipeline := []bson.M{
+		{"$match": bson.M{"user_id": userID, "event_type": "task.completed"}},
+		{"$group": bson.M{"_id": bson.M{"$dateToString": bson.M{
+			"format":   "%Y-%m-%d",
+			"date":     toDate("$created_at"),
+			"timezone": loc.String(),
+		}}}},
+		{"$match": bson.M{"_id": bson.M{"$ne": nil}}},
+		{"$sort": bson.M{"_id": 1}},
+	}
+	cursor, err := m.events.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var days []string
+	for cursor.Next(ctx) {
+		var row struct {
+			Day string `bson:"_id"`
+		}
+		if err := cursor.Decode(&row); err != nil {
+			return nil, err
+		}
+		days = append(days, row.Day)
+	}
+	return days, cursor.Err()
+}
+
+// streakOf walks sorted calendar days. Days are compared as calendar dates
+// rather than instants, so a 23 or 25 hour day across a DST change still
+// follows the previous one. The current streak only counts while it ends
+// today or yesterday: a user who has not completed anything yet today keeps
+// their streak until the day is over.
+func streakOf(days []string, today string) streak {
+	var st streak
+	var prev time.Time
+	for _, day := range days {
+		d, err := time.Parse(dayFormat, day)
+		if err != nil {
+			continue
+		}
+		if st.run > 0 && d.Equal(prev.AddDate(0, 0, 1)) {
+			st.run++
+		} else {
+			st.run, st.runStart = 1, day
+		}
+		if st.run > st.longest {
+			st.longest = st.run
+		}
+		prev = d
+		st.lastDay = day
+	}
+	return st.asOf(today)
+}
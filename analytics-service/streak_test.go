@@ -0,0 +1,59 @@
+package main
+
+import "testing"
+
+func TestStreakOf(t *testing.T) {
+	const today = "2026-03-10"
+	tests := []struct {
+		name         string
+		days         []string
+		wantCurrent  int32
+		wantLongest  int32
+		wantStart    string
+		wantRun      int32
+		wantRunStart string
+	}{
+		{"no completions", nil, 0, 0, "", 0, ""},
+		{"ending today", []string{"2026-03-08", "2026-03-09", "2026-03-10"}, 3, 3, "2026-03-08", 3, "2026-03-08"},
+		{"ending yesterday", []string{"2026-03-08", "2026-03-09"}, 2, 2, "2026-03-08", 2, "2026-03-08"},
+		{"gap day before today", []string{"2026-03-07", "2026-03-08"}, 0, 2, "", 2, "2026-03-07"},
+		{"gap day resets the run", []string{"2026-03-04", "2026-03-05", "2026-03-06", "2026-03-08", "2026-03-09", "2026-03-10"},
+			3, 3, "2026-03-08", 3, "2026-03-08"},
+		{"longest before a gap", []string{"2026-03-01", "2026-03-02", "2026-03-03", "2026-03-04", "2026-03-06", "2026-03-07"},
+			0, 4, "", 2, "2026-03-06"},
+		{"single day after a gap", []string{"2026-03-07", "2026-03-08", "2026-03-10"}, 1, 2, "2026-03-10", 1, "2026-03-10"},
+		{"across a month end", []string{"2026-02-27", "2026-02-28", "2026-03-01"}, 0, 3, "", 3, "2026-02-27"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := streakOf(tt.days, today)
+			if st.current != tt.wantCurrent || st.longest != tt.wantLongest || st.startDay != tt.wantStart {
+				t.Errorf("current %d from %q, longest %d; want %d from %q, %d",
+					st.current, st.startDay, st.longest, tt.wantCurrent, tt.wantStart, tt.wantLongest)
+			}
+			if st.run != tt.wantRun || st.runStart != tt.wantRunStart {
+				t.Errorf("run %d from %q, want %d from %q", st.run, st.runStart, tt.wantRun, tt.wantRunStart)
+			}
+		})
+	}
+}
+
+// A saved streak is brought up to date without the completion history, so
+// it must reset once a day is missed too.
+func TestSavedStreakAsOf(t *testing.T) {
+	saved := streakOf([]string{"2026-03-08", "2026-03-09"}, "2026-03-09")
+	tests := []struct {
+		today       string
+		wantCurrent int32
+	}{
+		{"2026-03-09", 2},
+		{"2026-03-10", 2},
+		{"2026-03-11", 0},
+	}
+	for _, tt := range tests {
+		st := saved.asOf(tt.today)
+		if st.current != tt.wantCurrent || st.longest != 2 {
+			t.Errorf("as of %s: current %d, longest %d; want %d and 2", tt.today, st.current, st.longest, tt.wantCurrent)
+		}
+	}
+}
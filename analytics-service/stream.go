@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+const (
+	// Events buffered per stream before new ones are dropped
+	streamBufferSize = 256
+	// Consecutive failed reopenings of the change stream before giving up
+	maxStreamRetries = 5
+)
+
+// Mongo error codes of change streams
+const (
+	errCodeChangeStreamUnsupported = 40573
+	errCodeInvalidResumeToken      = 260
+	errCodeChangeStreamHistoryLost = 286
+)
+
+// Change streams need MongoDB to run as a replica set, which the bundled
+// docker-compose and Kubernetes MongoDB do not, so StreamEvents is off unless
+// ANALYTICS_CHANGE_STREAMS=true. When it is on, the service checks at startup
+// that MongoDB is a replica set or sharded cluster.
+
+// loadChangeStreams reads ANALYTICS_CHANGE_STREAMS and, when it is set,
+// checks that client's deployment supports change streams.
+func loadChangeStreams(ctx context.Context, client *mongo.Client) (bool, error) {
+	enabled, err := strconv.ParseBool(getEnv("ANALYTICS_CHANGE_STREAMS", "false"))
+	if err != nil {
+		return false, fmt.Errorf("invalid ANALYTICS_CHANGE_STREAMS %q", os.Getenv("ANALYTICS_CHANGE_STREAMS"))
+	}
+	if !enabled {
+		return false, nil
+	}
+
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	if err := client.Database("admin").RunCommand(ctx, bson.M{"hello": 1}).Decode(&hello); err != nil {
+		return false, err
+	}
+	if hello.SetName == "" && hello.Msg != "isdbgrid" {
+		return false, errors.New("ANALYTICS_CHANGE_STREAMS needs MongoDB to run as a replica set or sharded cluster")
+	}
+	return true, nil
+}
+
+// StreamEvents sends events as they are tracked, from a change stream on the
+// events collection. Events are
+// buffered per stream and dropped when the consumer falls behind, so a slow
+// consumer never holds up the change stream; a marker carrying the number
+// dropped takes their place. Headers are sent once the change stream is
+// open, so callers can tell a rejected request from a quiet one.
+func (s *server) StreamEvents(req *pb.StreamEventsRequest, stream pb.AnalyticsService_StreamEventsServer) error {
+	if !s.changeStreams {
+		return statusError(codes.FailedPrecondition, "EVENT_STREAM_DISABLED", nil, "streaming events is disabled: it needs MongoDB to run as a replica set and ANALYTICS_CHANGE_STREAMS=true")
+	}
+	ctx := stream.Context()
+
+	match := bson.M{"operationType": "insert"}
+	if req.UserId != "" {
+		match["fullDocument.user_id"] = req.UserId
+	}
+	if len(req.EventTypes) > 0 {
+		match["fullDocument.event_type"] = bson.M{"$in": req.EventTypes}
+	}
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: match}}}
+
+	opts := options.ChangeStream()
+	if req.ResumeToken != "" {
+		opts.SetResumeAfter(bson.M{"_data": req.ResumeToken})
+	}
+	cs, err := s.collection.Watch(ctx, pipeline, opts)
+	if err != nil {
+		return changeStreamError(err, req.ResumeToken)
+	}
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		cs.Close(context.Background())
+		return err
+	}
+
+	items := make(chan *pb.StreamEventsResponse, streamBufferSize)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- s.pumpEvents(ctx, cs, pipeline, items)
+		close(items)
+	}()
+
+	for item := range items {
+		if err := stream.Send(item); err != nil {
+			return err
+		}
+	}
+	return <-errc
+}
+
+// pumpEvents moves changes into items without ever blocking on it, until ctx
+// ends or the change stream fails for good. A failed change stream is
+// reopened after the last event read.
+func (s *server) pumpEvents(ctx context.Context, cs *mongo.ChangeStream, pipeline mongo.Pipeline, items chan<- *pb.StreamEventsResponse) error {
+	var dropped int64
+	enqueue := func(item *pb.StreamEventsResponse) {
+		if dropped > 0 {
+			select {
+			case items <- &pb.StreamEventsResponse{Dropped: dropped}:
+				dropped = 0
+			default:
+				dropped++
+				return
+			}
+		}
+		select {
+		case items <- item:
+		default:
+			dropped++
+		}
+	}
+
+	failures := 0
+	for {
+		for cs.Next(ctx) {
+			failures = 0
+			var change struct {
+				Event Event `bson:"fullDocument"`
+			}
+			if err := cs.Decode(&change); err != nil {
+				cs.Close(context.Background())
+				return err
+			}
+			enqueue(&pb.StreamEventsResponse{
+				Event:       change.Event.toProto(),
+				ResumeToken: resumeTokenData(cs.ResumeToken()),
+			})
+		}
+		err := cs.Err()
+		token := cs.ResumeToken()
+		cs.Close(context.Background())
+
+		for {
+			if ctx.Err() != nil {
+				return nil
+			}
+			failures++
+			if failures > maxStreamRetries {
+				return err
+			}
+			log.Printf("Event stream failed, reopening (attempt %d): %v", failures, err)
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(time.Duration(failures) * time.Second):
+			}
+
+			opts := options.ChangeStream()
+			if token != nil {
+				opts.SetResumeAfter(token)
+			}
+			if cs, err = s.collection.Watch(ctx, pipeline, opts); err == nil {
+				break
+			}
+		}
+	}
+}
+
+// resumeTokenData is the opaque string inside a resume token, which is all
+// a consumer needs to hand back.
+func resumeTokenData(token bson.Raw) string {
+	data, _ := token.Lookup("_data").StringValueOK()
+	return data
+}
+
+func changeStreamError(err error, resumeToken string) error {
+	var serverErr mongo.ServerError
+	if !errors.As(err, &serverErr) {
+		return err
+	}
+	switch {
+	case serverErr.HasErrorCode(errCodeChangeStreamUnsupported):
+		return statusError(codes.FailedPrecondition, "CHANGE_STREAMS_UNSUPPORTED", nil, "streaming events needs MongoDB to run as a replica set")
+	case serverErr.HasErrorCode(errCodeInvalidResumeToken):
+		return statusError(codes.InvalidArgument, "INVALID_RESUME_TOKEN", map[string]string{"resume_token": resumeToken}, "invalid resume_token")
+	case serverErr.HasErrorCode(errCodeChangeStreamHistoryLost):
+		return statusError(codes.OutOfRange, "RESUME_TOKEN_EXPIRED", map[string]string{"resume_token": resumeToken}, "events after resume_token are no longer available")
+	}
+	return err
+}
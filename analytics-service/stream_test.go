@@ -0,0 +1,18 @@
+package main
+
+import (
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+func TestStreamEventsDisabled(t *testing.T) {
+	s := &server{}
+	err := s.StreamEvents(&pb.StreamEventsRequest{}, nil)
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("code = %v, want FailedPrecondition", status.Code(err))
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"google.golang.org/grpc/codes"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// GetTimeReport compares the time estimated for the user's tasks with the
+// time spent on them, per label and in total. Estimates are not tracked as
+// events, so it reads the task service's collection.
+func (s *server) GetTimeReport(ctx context.Context, req *pb.GetTimeReportRequest) (*pb.GetTimeReportResponse, error) {
+	if req.UserId == "" {
+		return nil, statusError(codes.InvalidArgument, "USER_ID_REQUIRED", nil, "user_id is required")
+	}
+
+	totals := bson.M{
+		"task_count":         bson.M{"$sum": 1},
+		"estimated_minutes":  bson.M{"$sum": bson.M{"$ifNull": bson.A{"$estimated_minutes", 0}}},
+		"time_spent_minutes": bson.M{"$sum": bson.M{"$ifNull": bson.A{"$time_spent_minutes", 0}}},
+	}
+	byLabel := bson.M{"_id": bson.M{"$ifNull": bson.A{"$labels", ""}}}
+	overall := bson.M{"_id": nil}
+	for field, sum := range totals {
+		byLabel[field] = sum
+		overall[field] = sum
+	}
+
+	cursor, err := s.taskCollection.Aggregate(ctx, []bson.M{
+		{"$match": bson.M{
+			"user_id":    req.UserId,
+			"is_deleted": bson.M{"$ne": true},
+			"$or": bson.A{
+				bson.M{"estimated_minutes": bson.M{"$gt": 0}},
+				bson.M{"time_spent_minutes": bson.M{"$gt": 0}},
+			},
+		}},
+		{"$facet": bson.M{
+			"labels": bson.A{
+				bson.M{"$unwind": bson.M{"path": "$labels", "preserveNullAndEmptyArrays": true}},
+				bson.M{"$group": byLabel},
+				bson.M{"$sort": bson.M{"_id": 1}},
+			},
+			"total": bson.A{bson.M{"$group": overall}},
+		}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	type timeTotals struct {
+		Label            string `bson:"_id"`
+		TaskCount        int32  `bson:"task_count"`
+		EstimatedMinutes int32  `bson:"estimated_minutes"`
+		TimeSpentMinutes int32  `bson:"time_spent_minutes"`
+	}
+	var result struct {
+		Labels []timeTotals `bson:"labels"`
+		Total  []timeTotals `bson:"total"`
+	}
+	if cursor.Next(ctx) {
+		if err := cursor.Decode(&result); err != nil {
+			return nil, err
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	resp := &pb.GetTimeReportResponse{}
+	for _, row := range result.Labels {
+		resp.Labels = append(resp.Labels, &pb.TimeReportLabel{
+			Label:            row.Label,
+			TaskCount:        row.TaskCount,
+			EstimatedMinutes: row.EstimatedMinutes,
+			TimeSpentMinutes: row.TimeSpentMinutes,
+		})
+	}
+	if len(result.Total) > 0 {
+		resp.TaskCount = result.Total[0].TaskCount
+		resp.EstimatedMinutes = result.Total[0].EstimatedMinutes
+		resp.TimeSpentMinutes = result.Total[0].TimeSpentMinutes
+	}
+	return resp, nil
+}
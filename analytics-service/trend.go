@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"google.golang.org/grpc/codes"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// GetCompletionTrend counts task completions per day, week or month. Buckets
+// follow the caller's timezone and empty ones are filled in, so charts can
+// plot the response as is.
+func (s *server) GetCompletionTrend(ctx context.Context, req *pb.GetCompletionTrendRequest) (*pb.GetCompletionTrendResponse, error) {
+	granularity := req.Granularity
+	if granularity == "" {
+		granularity = "day"
+	}
+	if granularity != "day" && granularity != "week" && granularity != "month" {
+		return nil, statusError(codes.InvalidArgument, "INVALID_GRANULARITY", map[string]string{"granularity": req.Granularity}, "granularity must be day, week or month, got %q", req.Granularity)
+	}
+
+	timezone := req.Timezone
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	loc, err := parseTimezone(timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	dates, err := parseDateRange(req.StartDate, req.EndDate, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	completedAt := toDate("$created_at")
+	match := eventsIn(dates)
+	match["event_type"] = "task.completed"
+	if req.UserId != "" {
+		match["user_id"] = req.UserId
+	}
+
+	pipeline := []bson.M{
+		{"$match": match},
+		{"$group": bson.M{
+			"_id": bson.M{"$dateTrunc": bson.M{
+				"date":        completedAt,
+				"unit":        granularity,
+				"timezone":    timezone,
+				"startOfWeek": "monday",
+			}},
+			"completions": bson.M{"$sum": eventWeight},
+		}},
+	}
+
+	cursor, err := s.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	counts := make(map[int64]int32)
+	for cursor.Next(ctx) {
+		var row struct {
+			Start       time.Time `bson:"_id"`
+			Completions int32     `bson:"completions"`
+		}
+		if err := cursor.Decode(&row); err != nil {
+			return nil, err
+		}
+		counts[row.Start.Unix()] = row.Completions
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	var buckets []*pb.TrendBucket
+	for start := truncateTo(dates.start, granularity, loc); !start.After(dates.end); start = nextBucket(start, granularity) {
+		buckets = append(buckets, &pb.TrendBucket{
+			Start:       start.Format(time.RFC3339),
+			Completions: counts[start.Unix()],
+		})
+	}
+
+	return &pb.GetCompletionTrendResponse{Buckets: buckets}, nil
+}
+
+// truncateTo returns the start of the bucket containing t, matching
+// $dateTrunc with weeks starting on Monday.
+func truncateTo(t time.Time, granularity string, loc *time.Location) time.Time {
+	t = t.In(loc)
+	year, month, day := t.Date()
+	switch granularity {
+	case "week":
+		offset := (int(t.Weekday()) + 6) % 7
+		return time.Date(year, month, day-offset, 0, 0, 0, 0, loc)
+	case "month":
+		return time.Date(year, month, 1, 0, 0, 0, 0, loc)
+	default:
+		return time.Date(year, month, day, 0, 0, 0, 0, loc)
+	}
+}
+
+func nextBucket(start time.Time, granularity string) time.Time {
+	switch granularity {
+	case "week":
+		return start.AddDate(0, 0, 7)
+	case "month":
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
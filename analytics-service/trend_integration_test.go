@@ -0,0 +1,97 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+func TestMongoCompletionTrend(t *testing.T) {
+	stats := newMongoService(t, &fakeTaskClient{})
+	repo := stats.stats.(*mongoRepository)
+	s := &server{statsService: stats, collection: repo.events}
+	ctx := context.Background()
+
+	// Each case's completions are by a user of its own
+	completions := map[string][]time.Time{
+		"gaps": {
+			time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC),
+			time.Date(2026, 3, 1, 23, 59, 59, 0, time.UTC),
+			time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC),
+		},
+		"dhaka": {
+			// 23:59 and 00:30 in Dhaka, on either side of midnight
+			time.Date(2026, 3, 1, 17, 59, 0, 0, time.UTC),
+			time.Date(2026, 3, 1, 18, 30, 0, 0, time.UTC),
+		},
+		"weekly": {
+			// Sunday, the last day of the week of 23 February
+			time.Date(2026, 3, 1, 23, 0, 0, 0, time.UTC),
+			// Monday and Sunday of the week of 2 March
+			time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC),
+			time.Date(2026, 3, 8, 23, 59, 59, 0, time.UTC),
+			time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC),
+		},
+	}
+	for userID, times := range completions {
+		for i, at := range times {
+			_, err := repo.InsertEvent(ctx, Event{
+				UserID: userID, EventType: "task.completed", ResourceID: userID + string(rune('a'+i)),
+				CreatedAt: at.In(time.Local).Format(time.RFC3339),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	tests := []struct {
+		name       string
+		req        *pb.GetCompletionTrendRequest
+		wantStarts []string
+		wantCounts []int32
+	}{
+		{"gaps filled with zeros",
+			&pb.GetCompletionTrendRequest{UserId: "gaps", StartDate: "2026-03-01", EndDate: "2026-03-05"},
+			[]string{"2026-03-01T00:00:00Z", "2026-03-02T00:00:00Z", "2026-03-03T00:00:00Z", "2026-03-04T00:00:00Z", "2026-03-05T00:00:00Z"},
+			[]int32{2, 0, 0, 1, 0}},
+		{"days in the caller's timezone",
+			&pb.GetCompletionTrendRequest{UserId: "dhaka", StartDate: "2026-03-01", EndDate: "2026-03-02", Timezone: "Asia/Dhaka"},
+			[]string{"2026-03-01T00:00:00+06:00", "2026-03-02T00:00:00+06:00"},
+			[]int32{1, 1}},
+		{"the same completions by UTC day",
+			&pb.GetCompletionTrendRequest{UserId: "dhaka", StartDate: "2026-03-01", EndDate: "2026-03-02"},
+			[]string{"2026-03-01T00:00:00Z", "2026-03-02T00:00:00Z"},
+			[]int32{2, 0}},
+		{"weeks starting on Monday",
+			&pb.GetCompletionTrendRequest{UserId: "weekly", StartDate: "2026-03-01", EndDate: "2026-03-15", Granularity: "week"},
+			[]string{"2026-02-23T00:00:00Z", "2026-03-02T00:00:00Z", "2026-03-09T00:00:00Z"},
+			[]int32{1, 2, 1}},
+		{"months",
+			&pb.GetCompletionTrendRequest{UserId: "weekly", StartDate: "2026-02-01", EndDate: "2026-03-31", Granularity: "month"},
+			[]string{"2026-02-01T00:00:00Z", "2026-03-01T00:00:00Z"},
+			[]int32{0, 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := s.GetCompletionTrend(ctx, tt.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var starts []string
+			var counts []int32
+			for _, bucket := range resp.Buckets {
+				starts = append(starts, bucket.Start)
+				counts = append(counts, bucket.Completions)
+			}
+			if !slices.Equal(starts, tt.wantStarts) || !slices.Equal(counts, tt.wantCounts) {
+				t.Errorf("buckets %v with %v completions, want %v with %v", starts, counts, tt.wantStarts, tt.wantCounts)
+			}
+		})
+	}
+}
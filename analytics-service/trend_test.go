@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTruncateTo(t *testing.T) {
+	dhaka, _ := time.LoadLocation("Asia/Dhaka")
+	tests := []struct {
+		name        string
+		at          time.Time
+		granularity string
+		loc         *time.Location
+		want        time.Time
+	}{
+		{"day", time.Date(2026, 3, 4, 15, 30, 0, 0, time.UTC), "day", time.UTC, time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC)},
+		{"day already started in the zone", time.Date(2026, 3, 3, 18, 30, 0, 0, time.UTC), "day", dhaka, time.Date(2026, 3, 4, 0, 0, 0, 0, dhaka)},
+		{"last minute of the day in the zone", time.Date(2026, 3, 3, 17, 59, 0, 0, time.UTC), "day", dhaka, time.Date(2026, 3, 3, 0, 0, 0, 0, dhaka)},
+		{"week from Wednesday", time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC), "week", time.UTC, time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)},
+		{"week from Monday", time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC), "week", time.UTC, time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)},
+		{"week from Sunday", time.Date(2026, 3, 8, 23, 59, 0, 0, time.UTC), "week", time.UTC, time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)},
+		{"week across months", time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC), "week", time.UTC, time.Date(2026, 2, 23, 0, 0, 0, 0, time.UTC)},
+		{"week already started in the zone", time.Date(2026, 3, 8, 18, 30, 0, 0, time.UTC), "week", dhaka, time.Date(2026, 3, 9, 0, 0, 0, 0, dhaka)},
+		{"month", time.Date(2026, 2, 28, 12, 0, 0, 0, time.UTC), "month", time.UTC, time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := truncateTo(tt.at, tt.granularity, tt.loc); !got.Equal(tt.want) {
+				t.Errorf("truncateTo(%v, %s) = %v, want %v", tt.at, tt.granularity, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextBucket(t *testing.T) {
+	newYork, _ := time.LoadLocation("America/New_York")
+	tests := []struct {
+		name        string
+		start       time.Time
+		granularity string
+		want        time.Time
+	}{
+		{"day", time.Date(2026, 2, 28, 0, 0, 0, 0, time.UTC), "day", time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)},
+		// The day clocks go forward is 23 hours long
+		{"day of a DST change", time.Date(2026, 3, 8, 0, 0, 0, 0, newYork), "day", time.Date(2026, 3, 9, 0, 0, 0, 0, newYork)},
+		{"week", time.Date(2026, 2, 23, 0, 0, 0, 0, time.UTC), "week", time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)},
+		{"month", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), "month", time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextBucket(tt.start, tt.granularity); !got.Equal(tt.want) {
+				t.Errorf("nextBucket(%v, %s) = %v, want %v", tt.start, tt.granularity, got, tt.want)
+			}
+		})
+	}
+}
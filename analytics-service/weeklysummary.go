@@ -0,0 +1,456 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/grpc/codes"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+	"github.com/technonext/todo-app/proto/proto/common"
+	"github.com/technonext/todo-app/proto/tenant"
+)
+
+// Every Monday after the configured hour, local time, each user who was
+// active the week before is sent a summary of it through the notification
+// service, rendered from a notification template. Users are taken to live in
+// the timezone of their digest schedule, or the configured one when they have
+// none. A marker document per (user, week) in weekly_summaries makes the
+// sends idempotent, so the job can safely run again after a partial failure.
+
+const weeklySummaryJob = "weekly_summary"
+
+// weeklySummaryConfig is when summaries go out and how they are rendered.
+type weeklySummaryConfig struct {
+	hour int
+	// Timezone of users without a digest schedule
+	loc *time.Location
+	// Name of the active notification template the summaries are rendered
+	// from, with the variables week, created, completed, overdue and
+	// busiest_day
+	template string
+}
+
+// GenerateWeeklySummary computes a user's summary of one ISO week without
+// sending it.
+func (s *server) GenerateWeeklySummary(ctx context.Context, req *pb.GenerateWeeklySummaryRequest) (*pb.GenerateWeeklySummaryResponse, error) {
+	if req.UserId == "" {
+		return nil, statusError(codes.InvalidArgument, "USER_ID_REQUIRED", nil, "user_id is required")
+	}
+	timezone := req.Timezone
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	loc, err := parseTimezone(timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	start := startOfWeek(time.Now().In(loc)).AddDate(0, 0, -7)
+	if req.Week != "" {
+		if start, err = parseISOWeek(req.Week, loc); err != nil {
+			return nil, statusError(codes.InvalidArgument, "INVALID_WEEK", map[string]string{"week": req.Week}, "invalid week %q: use an ISO week such as 2026-W07", req.Week)
+		}
+	}
+
+	summary, err := s.weeklySummary(ctx, req.UserId, start)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.GenerateWeeklySummaryResponse{Summary: summary}, nil
+}
+
+// startOfWeek returns midnight of the Monday starting t's ISO week, in t's
+// location.
+func startOfWeek(t time.Time) time.Time {
+	daysSinceMonday := (int(t.Weekday()) + 6) % 7
+	return time.Date(t.Year(), t.Month(), t.Day()-daysSinceMonday, 0, 0, 0, 0, t.Location())
+}
+
+// parseISOWeek returns the start of an ISO week written as YYYY-Www in loc.
+func parseISOWeek(week string, loc *time.Location) (time.Time, error) {
+	var year, number int
+	if _, err := fmt.Sscanf(week, "%4d-W%2d", &year, &number); err != nil {
+		return time.Time{}, err
+	}
+	// January 4th is always in week 1
+	start := startOfWeek(time.Date(year, time.January, 4, 0, 0, 0, 0, loc)).AddDate(0, 0, 7*(number-1))
+	if y, w := start.ISOWeek(); y != year || w != number {
+		return time.Time{}, fmt.Errorf("%d has no week %d", year, number)
+	}
+	return start, nil
+}
+
+func isoWeekName(t time.Time) string {
+	year, week := t.ISOWeek()
+	return fmt.Sprintf("%d-W%02d", year, week)
+}
+
+// weeklySummary computes the summary of the week starting at start.
+func (s *server) weeklySummary(ctx context.Context, userID string, start time.Time) (*pb.WeeklySummary, error) {
+	end := start.AddDate(0, 0, 7)
+	// dateRange is inclusive; Mongo dates have millisecond precision
+	week := dateRange{start: start, end: end.Add(-time.Millisecond)}
+
+	match := eventsIn(week)
+	match["user_id"] = userID
+	match["event_type"] = bson.M{"$in": bson.A{"task.created", "task.completed"}}
+
+	pipeline := []bson.M{
+		{"$match": match},
+		{"$group": bson.M{
+			"_id": bson.M{
+				"event_type": "$event_type",
+				"day": bson.M{"$isoDayOfWeek": bson.M{
+					"date":     toDate("$created_at"),
+					"timezone": start.Location().String(),
+				}},
+			},
+			"count": bson.M{"$sum": eventWeight},
+		}},
+	}
+
+	cursor, err := s.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	summary := &pb.WeeklySummary{
+		UserId: userID,
+		Week:   isoWeekName(start),
+		Start:  start.Format(time.RFC3339),
+		End:    end.Format(time.RFC3339),
+	}
+	var completedByDay [8]int32
+	for cursor.Next(ctx) {
+		var row struct {
+			ID struct {
+				EventType string `bson:"event_type"`
+				Day       int    `bson:"day"`
+			} `bson:"_id"`
+			Count int32 `bson:"count"`
+		}
+		if err := cursor.Decode(&row); err != nil {
+			return nil, err
+		}
+		if row.ID.EventType == "task.created" {
+			summary.Created += row.Count
+			continue
+		}
+		summary.Completed += row.Count
+		if row.ID.Day >= 1 && row.ID.Day <= 7 {
+			completedByDay[row.ID.Day] += row.Count
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	// Ties go to the earlier day
+	for day := 1; day <= 7; day++ {
+		if completedByDay[day] > summary.BusiestDayCompleted {
+			summary.BusiestDayCompleted = completedByDay[day]
+			summary.BusiestDay = time.Weekday(day % 7).String()
+		}
+	}
+
+	overdue, err := s.overdueAt(ctx, userID, week)
+	if err != nil {
+		return nil, err
+	}
+	summary.Overdue = overdue
+	return summary, nil
+}
+
+// overdueAt counts the user's tasks that were overdue at the end of r.
+func (s *server) overdueAt(ctx context.Context, userID string, r dateRange) (int32, error) {
+	count, err := s.taskCollection.CountDocuments(ctx, bson.M{
+		"user_id": userID,
+		"$expr":   overdueExpr(r.end),
+	})
+	return int32(count), err
+}
+
+// startWeeklySummaries checks every hour for users whose summary of last
+// week is due, from one replica at a time.
+func (s *server) startWeeklySummaries(ctx context.Context, cfg weeklySummaryConfig) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	// Summaries go to users of every tenant, each sent in the user's own
+	ctx = tenant.WithID(ctx, tenant.All)
+
+	for {
+		s.runWeeklySummaries(ctx, cfg, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// summaryWeek returns the start of the last full week before now in loc, and
+// whether its summary is due: summaries go out from hour on Monday.
+func summaryWeek(now time.Time, loc *time.Location, hour int) (time.Time, bool) {
+	local := now.In(loc)
+	thisWeek := startOfWeek(local)
+	sendAt := time.Date(thisWeek.Year(), thisWeek.Month(), thisWeek.Day(), hour, 0, 0, 0, loc)
+	return thisWeek.AddDate(0, 0, -7), !local.Before(sendAt)
+}
+
+func (s *server) runWeeklySummaries(ctx context.Context, cfg weeklySummaryConfig, now time.Time) {
+	leader, err := s.acquireLease(ctx, weeklySummaryJob, 2*time.Hour)
+	if err != nil {
+		log.Printf("Failed to acquire %s lease: %v", weeklySummaryJob, err)
+		return
+	}
+	if !leader {
+		return
+	}
+
+	sent, failed, err := s.sendWeeklySummaries(ctx, cfg, now)
+	if err != nil {
+		log.Printf("Weekly summaries failed: %v", err)
+		return
+	}
+	if sent > 0 {
+		log.Printf("Weekly summaries sent to %d users", sent)
+	}
+	if failed > 0 {
+		// The next run retries them; users already sent to are skipped
+		log.Printf("Weekly summaries failed for %d users", failed)
+	}
+}
+
+// sendWeeklySummaries sends every summary due at now that has not been sent,
+// returning how many were sent and how many failed.
+func (s *server) sendWeeklySummaries(ctx context.Context, cfg weeklySummaryConfig, now time.Time) (sent, failed int, err error) {
+	// Timezones put users at most a day apart, so anyone active in the week
+	// being summarised has events in the last 15 days
+	recent := dateRange{start: now.AddDate(0, 0, -15), end: now}
+	ids, err := s.collection.Distinct(ctx, "user_id", eventsIn(recent))
+	if err != nil {
+		return 0, 0, err
+	}
+	var userIDs []string
+	for _, id := range ids {
+		if userID, ok := id.(string); ok && userID != "" {
+			userIDs = append(userIDs, userID)
+		}
+	}
+	if len(userIDs) == 0 {
+		return 0, 0, nil
+	}
+
+	zones, err := s.userTimezones(ctx, userIDs)
+	if err != nil {
+		return 0, 0, err
+	}
+	weeks := make(map[string]time.Time)
+	for _, userID := range userIDs {
+		loc := zones[userID]
+		if loc == nil {
+			loc = cfg.loc
+		}
+		if start, due := summaryWeek(now, loc, cfg.hour); due {
+			weeks[userID] = start
+		}
+	}
+	if err := s.dropSentSummaries(ctx, weeks); err != nil {
+		return 0, 0, err
+	}
+	if len(weeks) == 0 {
+		return 0, 0, nil
+	}
+
+	// Each tenant has its own templates, looked up once per run
+	templateIDs := make(map[string]string)
+	for userID, start := range weeks {
+		claimed, err := s.sendWeeklySummary(ctx, cfg.template, templateIDs, userID, start)
+		if err != nil {
+			log.Printf("Failed to send weekly summary to user %s: %v", userID, err)
+			failed++
+			continue
+		}
+		if claimed {
+			sent++
+		}
+	}
+	return sent, failed, nil
+}
+
+// userTimezones returns the timezones of the users' digest schedules, the
+// only place a user's timezone is kept. Users without one are left out.
+func (s *server) userTimezones(ctx context.Context, userIDs []string) (map[string]*time.Location, error) {
+	cursor, err := s.digestSchedules.Find(ctx,
+		bson.M{"user_id": bson.M{"$in": userIDs}, "timezone": bson.M{"$nin": bson.A{"", nil}}},
+		options.Find().SetProjection(bson.M{"user_id": 1, "timezone": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	zones := make(map[string]*time.Location)
+	for cursor.Next(ctx) {
+		var schedule struct {
+			UserID   string `bson:"user_id"`
+			Timezone string `bson:"timezone"`
+		}
+		if err := cursor.Decode(&schedule); err != nil {
+			return nil, err
+		}
+		if loc, err := parseTimezone(schedule.Timezone); err == nil {
+			zones[schedule.UserID] = loc
+		}
+	}
+	return zones, cursor.Err()
+}
+
+// dropSentSummaries removes the users whose summary of the week in weeks has
+// already been claimed.
+func (s *server) dropSentSummaries(ctx context.Context, weeks map[string]time.Time) error {
+	if len(weeks) == 0 {
+		return nil
+	}
+	var markers bson.A
+	for userID, start := range weeks {
+		markers = append(markers, bson.M{"user_id": userID, "week": isoWeekName(start)})
+	}
+	cursor, err := s.weeklySummaries.Find(ctx, bson.M{"$or": markers})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var marker struct {
+			UserID string `bson:"user_id"`
+			Week   string `bson:"week"`
+		}
+		if err := cursor.Decode(&marker); err != nil {
+			return err
+		}
+		if start, ok := weeks[marker.UserID]; ok && isoWeekName(start) == marker.Week {
+			delete(weeks, marker.UserID)
+		}
+	}
+	return cursor.Err()
+}
+
+// userTenant returns the tenant of the user's latest event, which is the
+// user's tenant.
+func (s *server) userTenant(ctx context.Context, userID string) (string, error) {
+	var event Event
+	err := s.collection.FindOne(ctx, bson.M{"user_id": userID},
+		options.FindOne().SetSort(bson.D{{Key: "_id", Value: -1}}).SetProjection(bson.M{tenant.Field: 1})).Decode(&event)
+	if err == mongo.ErrNoDocuments {
+		return "", nil
+	}
+	return event.TenantID, err
+}
+
+// summaryTemplate returns the id of the active notification template named
+// name.
+func (s *server) summaryTemplate(ctx context.Context, name string) (string, error) {
+	page := &common.PageRequest{Limit: 100}
+	for {
+		resp, err := s.notifications.ListTemplates(ctx, &pb.ListTemplatesRequest{Pagination: page})
+		if err != nil {
+			return "", err
+		}
+		for _, t := range resp.Templates {
+			if t.Name == name && t.Status == pb.TemplateStatus_TEMPLATE_STATUS_ACTIVE {
+				return t.Id, nil
+			}
+		}
+		if resp.Pagination.GetNextCursor() == "" {
+			return "", fmt.Errorf("no active notification template named %q", name)
+		}
+		page.Cursor = resp.Pagination.NextCursor
+	}
+}
+
+// sendWeeklySummary sends one user's summary with their tenant's template
+// named template, reporting false if it had already been sent. templateIDs
+// holds the ids of the tenants' templates found so far. The marker is
+// claimed before sending and released if the send fails, so a summary is
+// never sent twice.
+func (s *server) sendWeeklySummary(ctx context.Context, template string, templateIDs map[string]string, userID string, start time.Time) (bool, error) {
+	userTenant, err := s.userTenant(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	ctx = tenant.WithID(ctx, userTenant)
+	templateID, ok := templateIDs[userTenant]
+	if !ok {
+		if templateID, err = s.summaryTemplate(ctx, template); err != nil {
+			return false, err
+		}
+		templateIDs[userTenant] = templateID
+	}
+
+	marker := bson.M{"user_id": userID, "week": isoWeekName(start)}
+	_, err = s.weeklySummaries.InsertOne(ctx, bson.M{
+		"user_id":    marker["user_id"],
+		"week":       marker["week"],
+		"claimed_at": time.Now().Format(time.RFC3339),
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	err = s.deliverWeeklySummary(ctx, templateID, userID, start)
+	if err != nil {
+		if _, delErr := s.weeklySummaries.DeleteOne(ctx, marker); delErr != nil {
+			log.Printf("Failed to release weekly summary marker for user %s: %v", userID, delErr)
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *server) deliverWeeklySummary(ctx context.Context, templateID, userID string, start time.Time) error {
+	summary, err := s.weeklySummary(ctx, userID, start)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	_, err = s.notifications.SendNotification(ctx, &pb.NotificationRequest{
+		UserId:     userID,
+		TemplateId: templateID,
+		Variables: map[string]string{
+			"week":        summary.Week,
+			"created":     strconv.Itoa(int(summary.Created)),
+			"completed":   strconv.Itoa(int(summary.Completed)),
+			"overdue":     strconv.Itoa(int(summary.Overdue)),
+			"busiest_day": summary.BusiestDay,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// The summary is out, so failing to record it must not release the marker
+	_, err = s.weeklySummaries.UpdateOne(ctx,
+		bson.M{"user_id": userID, "week": summary.Week},
+		bson.M{"$set": bson.M{"sent_at": time.Now().Format(time.RFC3339)}})
+	if err != nil {
+		log.Printf("Failed to record weekly summary for user %s: %v", userID, err)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+func TestSummaryWeek(t *testing.T) {
+	// Monday 9 March 2026, 10:00 UTC
+	now := time.Date(2026, 3, 9, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		timezone string
+		hour     int
+		wantWeek string
+		wantDue  bool
+	}{
+		{"UTC", 8, "2026-W10", true},
+		{"UTC", 11, "2026-W10", false},
+		// 03:00 on Monday: not yet due
+		{"America/Los_Angeles", 8, "2026-W10", false},
+		// 06:00 on Tuesday 10 March
+		{"Pacific/Kiritimati", 8, "2026-W10", true},
+		// 16:00 on Monday
+		{"Asia/Dhaka", 8, "2026-W10", true},
+		// Still 23:00 on Sunday 8 March, so the last full week is the one before
+		{"Pacific/Pago_Pago", 0, "2026-W09", true},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s at %d", tt.timezone, tt.hour), func(t *testing.T) {
+			loc := mustLoad(t, tt.timezone)
+			start, due := summaryWeek(now, loc, tt.hour)
+			if got := isoWeekName(start); got != tt.wantWeek || due != tt.wantDue {
+				t.Errorf("summaryWeek = %s, %v; want %s, %v", got, due, tt.wantWeek, tt.wantDue)
+			}
+			if start.Location() != loc || start.Weekday() != time.Monday || start.Hour() != 0 {
+				t.Errorf("week starts %v, want Monday midnight in %s", start, tt.timezone)
+			}
+		})
+	}
+}
+
+func TestParseISOWeek(t *testing.T) {
+	dhaka := mustLoad(t, "Asia/Dhaka")
+	tests := []struct {
+		week    string
+		loc     *time.Location
+		want    string
+		wantErr bool
+	}{
+		{"2026-W01", time.UTC, "2025-12-29T00:00:00Z", false},
+		{"2026-W10", dhaka, "2026-03-02T00:00:00+06:00", false},
+		{"2026-W53", time.UTC, "2026-12-28T00:00:00Z", false},
+		{"2025-W53", time.UTC, "", true},
+		{"2026-10", time.UTC, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.week, func(t *testing.T) {
+			start, err := parseISOWeek(tt.week, tt.loc)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parsed %s as %v", tt.week, start)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := start.Format(time.RFC3339); got != tt.want {
+				t.Errorf("start = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSummaryTemplate(t *testing.T) {
+	var templates []*pb.Template
+	for i := 0; i < 150; i++ {
+		templates = append(templates, &pb.Template{Id: fmt.Sprint(i), Name: fmt.Sprintf("other_%d", i), Status: pb.TemplateStatus_TEMPLATE_STATUS_ACTIVE})
+	}
+	templates = append(templates,
+		&pb.Template{Id: "draft", Name: "weekly_summary", Status: pb.TemplateStatus_TEMPLATE_STATUS_DRAFT},
+		&pb.Template{Id: "active", Name: "weekly_summary", Status: pb.TemplateStatus_TEMPLATE_STATUS_ACTIVE},
+	)
+	s := &server{notifications: &fakeNotificationClient{templates: templates}}
+
+	tests := []struct {
+		name    string
+		want    string
+		wantErr string
+	}{
+		{"weekly_summary", "active", ""},
+		{"other_120", "120", ""},
+		{"missing", "", "no active notification template"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := s.summaryTemplate(context.Background(), tt.name)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || id != tt.want {
+				t.Errorf("id = %q, %v; want %q", id, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name           string
+		forwardedFor   []string
+		trustedProxies int
+		want           string
+	}{
+		{"no proxies, no header", nil, 0, "10.0.0.9"},
+		{"no proxies ignore the header", []string{"203.0.113.7"}, 0, "10.0.0.9"},
+		{"one proxy, no header", nil, 1, "10.0.0.9"},
+		{"one proxy", []string{"203.0.113.7"}, 1, "203.0.113.7"},
+		{"one proxy after a spoofed entry", []string{"198.51.100.1, 203.0.113.7"}, 1, "203.0.113.7"},
+		{"two proxies", []string{"203.0.113.7, 10.0.0.5"}, 2, "203.0.113.7"},
+		{"two proxies after a spoofed entry", []string{"198.51.100.1, 203.0.113.7, 10.0.0.5"}, 2, "203.0.113.7"},
+		{"two proxies in separate headers", []string{"198.51.100.1, 203.0.113.7", "10.0.0.5"}, 2, "203.0.113.7"},
+		{"blank entries are skipped", []string{"203.0.113.7, , 10.0.0.5 "}, 2, "203.0.113.7"},
+		{"fewer entries than proxies", []string{"198.51.100.1"}, 2, "10.0.0.9"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/api/tasks", nil)
+			r.RemoteAddr = "10.0.0.9:51234"
+			for _, header := range tt.forwardedFor {
+				r.Header.Add("X-Forwarded-For", header)
+			}
+			if got := clientIP(r, tt.trustedProxies); got != tt.want {
+				t.Errorf("clientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadTrustedProxyCount(t *testing.T) {
+	for value, want := range map[string]int{"0": 0, "1": 1, "2": 2} {
+		t.Setenv("TRUSTED_PROXY_COUNT", value)
+		if got := loadTrustedProxyCount(); got != want {
+			t.Errorf("TRUSTED_PROXY_COUNT=%q gives %d, want %d", value, got, want)
+		}
+	}
+}
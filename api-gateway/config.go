@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/technonext/todo-app/proto/settings"
+)
+
+// minJWTSecretLen is the shortest JWT_SECRET accepted, 256 bits for HS256.
+const minJWTSecretLen = 32
+
+// defaultMaxDecompressedBytes caps compressed request bodies once
+// decompressed when MAX_DECOMPRESSED_BYTES is unset.
+const defaultMaxDecompressedBytes = 100 << 20
+
+// Config holds the settings validateConfig checks at startup, as read from
+// the environment, so a wrong one stops the gateway before it connects to
+// anything rather than surfacing later as a confusing failure.
+type Config struct {
+	Port string
+	// JWTSecret verifies session tokens; see jwtSecret
+	JWTSecret string
+	// CORSAllowedOrigins lists the origins browsers may call from, separated
+	// by commas, or * for any
+	CORSAllowedOrigins string
+	// RateLimitRPS caps the requests served per second, across all clients;
+	// empty for no limit
+	RateLimitRPS string
+	// MaxDecompressedBytes caps the size of compressed request bodies once
+	// decompressed; empty for defaultMaxDecompressedBytes
+	MaxDecompressedBytes string
+}
+
+func loadConfig() Config {
+	return Config{
+		Port:                 getEnv("PORT", "8080"),
+		JWTSecret:            os.Getenv("JWT_SECRET"),
+		CORSAllowedOrigins:   getEnv("CORS_ALLOWED_ORIGINS", "*"),
+		RateLimitRPS:         os.Getenv("RATE_LIMIT_RPS"),
+		MaxDecompressedBytes: os.Getenv("MAX_DECOMPRESSED_BYTES"),
+	}
+}
+
+// validateConfig reports every invalid setting in cfg, each named by its
+// environment variable.
+func validateConfig(cfg Config) error {
+	var errs []error
+	if err := settings.Port(cfg.Port); err != nil {
+		errs = append(errs, fmt.Errorf("PORT: %w", err))
+	}
+	if cfg.JWTSecret != "" {
+		if err := settings.Secret(cfg.JWTSecret, minJWTSecretLen); err != nil {
+			errs = append(errs, fmt.Errorf("JWT_SECRET: %w", err))
+		}
+	}
+	for _, origin := range cfg.allowedOrigins() {
+		if err := validOrigin(origin); err != nil {
+			errs = append(errs, fmt.Errorf("CORS_ALLOWED_ORIGINS: %w", err))
+		}
+	}
+	if cfg.RateLimitRPS != "" {
+		if rps, err := strconv.ParseFloat(cfg.RateLimitRPS, 64); err != nil || !(rps > 0) || math.IsInf(rps, 1) {
+			errs = append(errs, fmt.Errorf("RATE_LIMIT_RPS: %q is not a positive number of requests", cfg.RateLimitRPS))
+		}
+	}
+	if cfg.MaxDecompressedBytes != "" {
+		if n, err := strconv.ParseInt(cfg.MaxDecompressedBytes, 10, 64); err != nil || n <= 0 {
+			errs = append(errs, fmt.Errorf("MAX_DECOMPRESSED_BYTES: %q is not a positive number of bytes", cfg.MaxDecompressedBytes))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validOrigin checks an allowed origin is *, or a scheme and host with
+// nothing after them, as browsers send in the Origin header.
+func validOrigin(origin string) error {
+	if origin == "*" {
+		return nil
+	}
+	u, err := url.Parse(origin)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+		u.User != nil || (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("%q is not an origin such as https://app.example.com", origin)
+	}
+	return nil
+}
+
+// allowedOrigins splits CORSAllowedOrigins.
+func (c Config) allowedOrigins() []string {
+	var origins []string
+	for _, origin := range strings.Split(c.CORSAllowedOrigins, ",") {
+		origins = append(origins, strings.TrimSpace(origin))
+	}
+	return origins
+}
+
+// rateLimit returns RateLimitRPS, once validated, or 0 for no limit.
+func (c Config) rateLimit() float64 {
+	rps, _ := strconv.ParseFloat(c.RateLimitRPS, 64)
+	return rps
+}
+
+// maxDecompressedBytes returns MaxDecompressedBytes, once validated, or
+// defaultMaxDecompressedBytes when unset.
+func (c Config) maxDecompressedBytes() int64 {
+	if n, err := strconv.ParseInt(c.MaxDecompressedBytes, 10, 64); err == nil {
+		return n
+	}
+	return defaultMaxDecompressedBytes
+}
+
+// info returns the settings by environment variable for /debug/info.
+// JWTSecret is left out.
+func (c Config) info() map[string]string {
+	return map[string]string{
+		"PORT":                   c.Port,
+		"CORS_ALLOWED_ORIGINS":   c.CORSAllowedOrigins,
+		"RATE_LIMIT_RPS":         c.RateLimitRPS,
+		"MAX_DECOMPRESSED_BYTES": c.MaxDecompressedBytes,
+	}
+}
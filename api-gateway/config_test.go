@@ -0,0 +1,105 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/technonext/todo-app/proto/buildinfo"
+)
+
+func validConfig() Config {
+	return Config{
+		Port:               "8080",
+		JWTSecret:          strings.Repeat("s", minJWTSecretLen),
+		CORSAllowedOrigins: "*",
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		change    func(*Config)
+		wantField string
+	}{
+		{"valid", func(c *Config) {}, ""},
+		{"port not a number", func(c *Config) { c.Port = "http" }, "PORT"},
+		{"port zero", func(c *Config) { c.Port = "0" }, "PORT"},
+		{"port too large", func(c *Config) { c.Port = "65536" }, "PORT"},
+		{"jwt secret unset", func(c *Config) { c.JWTSecret = "" }, ""},
+		{"jwt secret too short", func(c *Config) { c.JWTSecret = "secret" }, "JWT_SECRET"},
+		{"origin list", func(c *Config) { c.CORSAllowedOrigins = "https://app.example.com, http://localhost:3000" }, ""},
+		{"origin without scheme", func(c *Config) { c.CORSAllowedOrigins = "app.example.com" }, "CORS_ALLOWED_ORIGINS"},
+		{"origin with other scheme", func(c *Config) { c.CORSAllowedOrigins = "ftp://app.example.com" }, "CORS_ALLOWED_ORIGINS"},
+		{"origin with path", func(c *Config) { c.CORSAllowedOrigins = "https://app.example.com/login" }, "CORS_ALLOWED_ORIGINS"},
+		{"empty origin in list", func(c *Config) { c.CORSAllowedOrigins = "https://app.example.com," }, "CORS_ALLOWED_ORIGINS"},
+		{"rate limit", func(c *Config) { c.RateLimitRPS = "0.5" }, ""},
+		{"rate limit not a number", func(c *Config) { c.RateLimitRPS = "fast" }, "RATE_LIMIT_RPS"},
+		{"rate limit zero", func(c *Config) { c.RateLimitRPS = "0" }, "RATE_LIMIT_RPS"},
+		{"rate limit negative", func(c *Config) { c.RateLimitRPS = "-10" }, "RATE_LIMIT_RPS"},
+		{"rate limit not finite", func(c *Config) { c.RateLimitRPS = "NaN" }, "RATE_LIMIT_RPS"},
+		{"rate limit infinite", func(c *Config) { c.RateLimitRPS = "Inf" }, "RATE_LIMIT_RPS"},
+		{"max decompressed bytes", func(c *Config) { c.MaxDecompressedBytes = "1048576" }, ""},
+		{"max decompressed bytes not a number", func(c *Config) { c.MaxDecompressedBytes = "100MB" }, "MAX_DECOMPRESSED_BYTES"},
+		{"max decompressed bytes zero", func(c *Config) { c.MaxDecompressedBytes = "0" }, "MAX_DECOMPRESSED_BYTES"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			tt.change(&cfg)
+			err := validateConfig(cfg)
+			if tt.wantField == "" {
+				if err != nil {
+					t.Fatalf("validateConfig() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantField) {
+				t.Fatalf("validateConfig() = %v, want an error naming %s", err, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestValidateConfigReportsEveryField(t *testing.T) {
+	cfg := Config{Port: "none", JWTSecret: "short", CORSAllowedOrigins: "example.com", RateLimitRPS: "0"}
+	err := validateConfig(cfg)
+	if err == nil {
+		t.Fatal("validateConfig() succeeded")
+	}
+	for _, field := range []string{"PORT", "JWT_SECRET", "CORS_ALLOWED_ORIGINS", "RATE_LIMIT_RPS"} {
+		if !strings.Contains(err.Error(), field) {
+			t.Errorf("validateConfig() = %v, want an error naming %s", err, field)
+		}
+	}
+}
+
+func TestRateLimitedRejectsExcessRequests(t *testing.T) {
+	handler := rateLimited(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), 2)
+	codes := make([]int, 0, 4)
+	for _, path := range []string{"/api/tasks", "/api/tasks", "/api/tasks", "/health"} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		codes = append(codes, rec.Code)
+	}
+	want := []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests, http.StatusOK}
+	for i := range want {
+		if codes[i] != want[i] {
+			t.Fatalf("status codes = %v, want %v", codes, want)
+		}
+	}
+}
+
+func TestConfigInfoLeavesOutSecrets(t *testing.T) {
+	cfg := validConfig()
+	info := buildinfo.NewServer("api-gateway", cfg.info()).Info()
+	for key, value := range info.Config {
+		if strings.Contains(value, cfg.JWTSecret) {
+			t.Errorf("%s shows the JWT secret", key)
+		}
+	}
+	if info.Config["PORT"] != "8080" || info.Config["CORS_ALLOWED_ORIGINS"] != "*" {
+		t.Errorf("config = %v", info.Config)
+	}
+}
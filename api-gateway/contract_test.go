@@ -0,0 +1,530 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/mux"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	"github.com/technonext/todo-app/proto/featureflags"
+	pb "github.com/technonext/todo-app/proto/proto"
+	"github.com/technonext/todo-app/proto/tenant"
+)
+
+// The contract tests pin the JSON every route answers with and the request
+// the services get for it, so a change to the protos or to how the gateway
+// encodes them shows up as a diff against testdata/contract. After a change
+// that is meant to alter the API, re-bless the goldens with
+//
+//	go test -run TestContract -update
+//
+// and review the diff like any other change to the API.
+var update = flag.Bool("update", false, "rewrite the contract goldens in testdata/contract")
+
+// contractCase is a documented request to one route.
+type contractCase struct {
+	name, method, path, body string
+}
+
+// contractCases has a request to every route, bodies as the API documents
+// them. Paths and queries name dates explicitly so nothing depends on when
+// the tests run.
+var contractCases = []contractCase{
+	{"health", "GET", "/health", ""},
+	{"health-ready", "GET", "/health/ready", ""},
+
+	{"create-task", "POST", "/api/tasks", `{"title":"Write report","description":"Quarterly numbers","user_id":"u1","due_date":"2026-03-04T12:00:00Z","priority":3,"labels":["work"],"estimated_minutes":90}`},
+	{"bulk-update-tasks", "PATCH", "/api/tasks", `{"ids":["t1","t2"],"updates":{"priority":3},"fields":["priority"]}`},
+	{"sync-tasks", "GET", "/api/tasks/sync?user_id=u1&since=2026-03-01T00:00:00Z", ""},
+	{"pending-summary", "GET", "/api/tasks/pending-summary?user_id=u1", ""},
+	{"find-similar-tasks", "GET", "/api/tasks/similar?user_id=u1&title=Write+report", ""},
+	{"tasks-calendar", "GET", "/api/tasks/calendar?user_id=u1&month=3&year=2026&timezone=UTC", ""},
+	{"list-deleted-tasks", "GET", "/api/tasks/trash?user_id=u1", ""},
+	{"export-tasks-csv", "GET", "/api/tasks/export/csv?user_id=u1", ""},
+	{"get-task", "GET", "/api/tasks/t1", ""},
+	{"update-task", "PUT", "/api/tasks/t1", `{"title":"Write the report","description":"Quarterly numbers","completed":true,"due_date":"2026-03-05T12:00:00Z","priority":2,"labels":["work"]}`},
+	{"delete-task", "DELETE", "/api/tasks/t1", ""},
+	{"update-task-status", "PUT", "/api/tasks/t1/status", `{"status":"in_progress"}`},
+	{"get-task-metrics", "GET", "/api/tasks/t1/metrics", ""},
+	{"move-task", "POST", "/api/tasks/t1/move", `{"to_user_id":"u2"}`},
+	{"extend-due-date", "POST", "/api/tasks/t1/extend-due-date", `{"new_due_date":"2026-03-05T12:00:00Z"}`},
+	{"lock-task", "POST", "/api/tasks/t1/lock", `{"user_id":"u1"}`},
+	{"unlock-task", "DELETE", "/api/tasks/t1/lock", ""},
+	{"add-checklist-item", "POST", "/api/tasks/t1/checklist", `{"text":"Outline"}`},
+	{"toggle-checklist-item", "PATCH", "/api/tasks/t1/checklist/i1", `{"done":true}`},
+	{"remove-checklist-item", "DELETE", "/api/tasks/t1/checklist/i1", ""},
+	{"get-task-notes", "GET", "/api/tasks/t1/notes", ""},
+	{"update-task-notes", "PUT", "/api/tasks/t1/notes", `{"notes":"# Outline\n\n- revenue\n- costs"}`},
+	{"list-tasks", "GET", "/api/tasks?UserId=u1&Completed=true&Page=2&Limit=5", ""},
+
+	{"create-user", "POST", "/api/users", `{"username":"alice","email":"alice@example.com","password":"correct-horse"}`},
+	{"get-user", "GET", "/api/users/u1", ""},
+	{"update-user", "PUT", "/api/users/u1", `{"username":"alice2","email":"alice2@example.com"}`},
+	{"delete-user", "DELETE", "/api/users/u1", ""},
+	{"set-digest-schedule", "POST", "/api/users/u1/digest-schedule", `{"frequency":"weekly","send_at_hour":8,"timezone":"Europe/Berlin","enabled":true}`},
+	{"delete-digest-schedule", "DELETE", "/api/users/u1/digest-schedule", ""},
+	{"weekly-report", "GET", "/api/users/u1/weekly-report?week_of=2026-03-04", ""},
+	{"authenticate", "POST", "/api/auth", `{"email":"alice@example.com","password":"correct-horse"}`},
+
+	{"send-notification", "POST", "/api/notifications", `{"user_id":"u1","message":"Report due","priority":2}`},
+	{"get-notifications", "GET", "/api/notifications?UserId=u1&UnreadOnly=true&Limit=10", ""},
+	{"test-notification", "POST", "/api/notifications/test", `{"user_id":"u1","message":"Is this thing on?"}`},
+	{"delivery-report", "GET", "/api/notifications/delivery-report?user_id=u1&start=2026-03-01&end=2026-03-31", ""},
+	{"notification-summary", "GET", "/api/notifications/summary?user_id=u1", ""},
+	{"mark-notification-read", "POST", "/api/notifications/n1/read", ""},
+	{"get-notification", "GET", "/api/notifications/n1", ""},
+
+	{"track-event", "POST", "/api/analytics/events", `{"user_id":"u1","event_type":"task.created","resource_id":"t1","client_event_id":"c1","metadata":{"priority":"high"}}`},
+	{"track-events", "POST", "/api/analytics/events/batch", `{"events":[{"user_id":"u1","event_type":"task.created","resource_id":"t1","client_event_id":"c1","metadata":{"priority":"high"}}]}`},
+	{"subscribe-events", "GET", "/api/analytics/events/subscribe?user_id=u1", ""},
+	{"user-stats", "GET", "/api/analytics/users/u1/stats?Fresh=true&StartDate=2026-03-01", ""},
+	{"completion-forecast", "GET", "/api/analytics/users/u1/forecast", ""},
+	{"peak-hours", "GET", "/api/analytics/users/u1/peak-hours?timezone=UTC", ""},
+	{"engagement-score", "GET", "/api/analytics/users/u1/engagement", ""},
+	{"user-streak", "GET", "/api/analytics/users/u1/streak?timezone=UTC", ""},
+	{"weekly-summary", "GET", "/api/analytics/users/u1/weekly-summary?week=2026-W10&timezone=UTC", ""},
+	{"task-breakdown", "GET", "/api/analytics/users/u1/breakdown?start_date=2026-03-01&end_date=2026-03-31", ""},
+	{"activity-heatmap", "GET", "/api/analytics/users/u1/heatmap?start_date=2026-03-01&end_date=2026-03-31", ""},
+	{"time-report", "GET", "/api/analytics/users/u1/time-report", ""},
+	{"burndown", "GET", "/api/analytics/users/u1/burndown?start=2026-03-02&end=2026-03-13&total=10", ""},
+	{"priority-breakdown", "GET", "/api/analytics/users/u1/priority-breakdown", ""},
+	{"task-stats", "GET", "/api/analytics/tasks/stats?StartDate=2026-03-01&EndDate=2026-03-31", ""},
+	{"completion-trend", "GET", "/api/analytics/trend?user_id=u1&start_date=2026-03-01&end_date=2026-03-31", ""},
+	{"overdue-aging", "GET", "/api/analytics/overdue?user_id=u1", ""},
+	{"export-stats", "GET", "/api/analytics/export?user_id=u1&start_date=2026-03-01&end_date=2026-03-31", ""},
+	{"completion-latency", "GET", "/api/analytics/completion-latency?user_id=u1&start_date=2026-03-01&end_date=2026-03-31", ""},
+
+	{"failed-deliveries", "GET", "/api/admin/notifications/failed?limit=10", ""},
+	{"admin-delivery-report", "GET", "/api/admin/notifications/delivery-report?start=2026-03-01&end=2026-03-31", ""},
+	{"redeliver-notification", "POST", "/api/admin/notifications/n1/redeliver", `{"channel":"webhook"}`},
+	{"backfill-analytics", "POST", "/api/admin/analytics/backfill", ""},
+	{"active-users", "GET", "/api/analytics/active-users?start_date=2026-03-01&end_date=2026-03-31", ""},
+	{"compare-users", "GET", "/api/analytics/compare?user_ids=u1,u2&start_date=2026-03-01&end_date=2026-03-31", ""},
+	{"replay-task-events", "POST", "/api/admin/tasks/events/replay?task_id=t1&since=2026-03-01T00:00:00Z", ""},
+	{"migrate-task-ownership", "POST", "/api/admin/tasks/migrate-ownership", `{"from_user_id":"u2","to_user_id":"u1"}`},
+	{"replay-events", "GET", "/api/admin/analytics/replay?user_id=u1&from=e1&event_types=task.created,task.completed", ""},
+	{"stream-events", "GET", "/api/admin/analytics/events/stream", ""},
+	{"retention-status", "GET", "/api/admin/analytics/retention", ""},
+	{"cache-stats", "GET", "/api/admin/analytics/cache", ""},
+	{"system-overview", "GET", "/api/admin/overview", ""},
+	{"feature-flags", "GET", "/api/admin/feature-flags", ""},
+	{"list-sessions", "GET", "/api/admin/sessions?user_id=u1", ""},
+	{"freeze-user", "POST", "/api/admin/users/u1/freeze", ""},
+	{"unfreeze-user", "POST", "/api/admin/users/u1/unfreeze", ""},
+	{"purge-user", "DELETE", "/api/admin/users/u1/purge", `{"confirm_user_id":"u1"}`},
+	{"deletion-status", "GET", "/api/admin/users/u1/deletion", ""},
+	{"create-template", "POST", "/api/admin/notification-templates", `{"name":"due-soon","language":"en","subject":"{{.Title}} is due","body":"{{.Title}} is due on {{.DueDate}}"}`},
+	{"list-templates", "GET", "/api/admin/notification-templates", ""},
+	{"get-template", "GET", "/api/admin/notification-templates/tpl1", ""},
+	{"update-template", "PATCH", "/api/admin/notification-templates/tpl1", `{"subject":"{{.Title}} is due soon"}`},
+	{"delete-template", "DELETE", "/api/admin/notification-templates/tpl1", ""},
+	{"clone-template", "POST", "/api/admin/notification-templates/tpl1/clone", `{"new_name":"due-soon-copy","language":"de"}`},
+	{"activate-template", "POST", "/api/admin/notification-templates/tpl1/activate", ""},
+	{"backup", "POST", "/api/admin/backup", `{"service":"task-service","collection_name":"tasks","bucket":"s3://todo-backups/nightly"}`},
+}
+
+// contractCall is a call a service got, its request as JSON.
+type contractCall struct {
+	Method  string          `json:"method"`
+	Request json.RawMessage `json:"request"`
+}
+
+// contractBackend serves every service method, answering with canonical
+// messages and recording the requests it gets.
+type contractBackend struct {
+	mu    sync.Mutex
+	calls []contractCall
+	// tenants holds the tenant each call came with, by method
+	tenants map[string][]string
+}
+
+// handle answers any method with its canonical response: once for unary
+// methods, as the only message for streaming ones.
+func (b *contractBackend) handle(srv interface{}, stream grpc.ServerStream) error {
+	fullMethod, _ := grpc.MethodFromServerStream(stream)
+	service, method, _ := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	desc, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(service))
+	if err != nil {
+		return status.Errorf(codes.Unimplemented, "unknown service %s", service)
+	}
+	md := desc.(protoreflect.ServiceDescriptor).Methods().ByName(protoreflect.Name(method))
+	if md == nil {
+		return status.Errorf(codes.Unimplemented, "unknown method %s", fullMethod)
+	}
+
+	for {
+		req := newMessage(md.Input())
+		if err := stream.RecvMsg(req); err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		if err := b.record(fullMethod, req); err != nil {
+			return err
+		}
+		b.recordTenant(stream.Context(), fullMethod)
+		if !md.IsStreamingClient() {
+			break
+		}
+	}
+
+	resp := newMessage(md.Output())
+	canonical(resp.ProtoReflect(), 0)
+	return stream.SendMsg(resp)
+}
+
+func (b *contractBackend) record(method string, req proto.Message) error {
+	body, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(req)
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.calls = append(b.calls, contractCall{Method: method, Request: body})
+	return nil
+}
+
+func (b *contractBackend) recordTenant(ctx context.Context, method string) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.tenants == nil {
+		b.tenants = make(map[string][]string)
+	}
+	b.tenants[method] = append(b.tenants[method], strings.Join(md.Get(tenant.Header), ","))
+}
+
+// sortedCalls returns the calls recorded, in an order that does not depend
+// on which of the gateway's concurrent calls arrived first.
+func (b *contractBackend) sortedCalls() []contractCall {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	calls := append([]contractCall(nil), b.calls...)
+	sort.SliceStable(calls, func(i, j int) bool {
+		if calls[i].Method != calls[j].Method {
+			return calls[i].Method < calls[j].Method
+		}
+		return string(calls[i].Request) < string(calls[j].Request)
+	})
+	return calls
+}
+
+func newMessage(desc protoreflect.MessageDescriptor) proto.Message {
+	mt, err := protoregistry.GlobalTypes.FindMessageByName(desc.FullName())
+	if err != nil {
+		panic(err)
+	}
+	return mt.New().Interface()
+}
+
+// canonical sets every field of msg, down to a few levels of nesting:
+// strings to the field's name, numbers to its number, enums to their first
+// value after the zero one, lists and maps to a single element and, of
+// each oneof, the first field. A renamed or renumbered field changes the
+// answer, and so the golden.
+func canonical(msg protoreflect.Message, depth int) {
+	fields := msg.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if oneof := fd.ContainingOneof(); oneof != nil && oneof.Fields().Get(0) != fd {
+			continue
+		}
+		switch {
+		case fd.IsMap():
+			if fd.MapValue().Message() != nil && depth >= 2 {
+				continue
+			}
+			entries := msg.Mutable(fd).Map()
+			key := canonicalValue(fd.MapKey()).MapKey()
+			if fd.MapValue().Message() != nil {
+				canonical(entries.Mutable(key).Message(), depth+1)
+			} else {
+				entries.Set(key, canonicalValue(fd.MapValue()))
+			}
+		case fd.IsList():
+			if fd.Message() != nil {
+				if depth >= 2 {
+					continue
+				}
+				canonical(msg.Mutable(fd).List().AppendMutable().Message(), depth+1)
+			} else {
+				msg.Mutable(fd).List().Append(canonicalValue(fd))
+			}
+		case fd.Message() != nil:
+			if depth < 2 {
+				canonical(msg.Mutable(fd).Message(), depth+1)
+			}
+		default:
+			msg.Set(fd, canonicalValue(fd))
+		}
+	}
+}
+
+func canonicalValue(fd protoreflect.FieldDescriptor) protoreflect.Value {
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(string(fd.Name()))
+	case protoreflect.BytesKind:
+		return protoreflect.ValueOfBytes([]byte(fd.Name()))
+	case protoreflect.BoolKind:
+		return protoreflect.ValueOfBool(true)
+	case protoreflect.EnumKind:
+		values := fd.Enum().Values()
+		if values.Len() > 1 {
+			return protoreflect.ValueOfEnum(values.Get(1).Number())
+		}
+		return protoreflect.ValueOfEnum(values.Get(0).Number())
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return protoreflect.ValueOfInt32(int32(fd.Number()))
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return protoreflect.ValueOfInt64(int64(fd.Number()))
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return protoreflect.ValueOfUint32(uint32(fd.Number()))
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return protoreflect.ValueOfUint64(uint64(fd.Number()))
+	case protoreflect.FloatKind:
+		return protoreflect.ValueOfFloat32(float32(fd.Number()) + 0.5)
+	case protoreflect.DoubleKind:
+		return protoreflect.ValueOfFloat64(float64(fd.Number()) + 0.5)
+	}
+	panic(fmt.Sprintf("no canonical value for %s", fd.Kind()))
+}
+
+// newContractClients serves a contractBackend and connects every client the
+// gateway has to it.
+func newContractClients(t *testing.T) (*ServiceClients, *contractBackend) {
+	t.Helper()
+	backend := &contractBackend{}
+	s := grpc.NewServer(grpc.UnknownServiceHandler(backend.handle))
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	flags, err := featureflags.Parse(`{"soft_delete": true, "recurring_tasks": false}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := pb.NewInfoServiceClient(conn)
+	backups := pb.NewBackupServiceClient(conn)
+	return &ServiceClients{
+		taskClient:         pb.NewTaskServiceClient(conn),
+		userClient:         pb.NewUserServiceClient(conn),
+		notificationClient: pb.NewNotificationServiceClient(conn),
+		analyticsClient:    pb.NewAnalyticsServiceClient(conn),
+		weeklyReports:      newReportCache(time.Hour),
+		summaries:          newSummaryCache(time.Hour),
+		featureFlags:       flags,
+		info: map[string]pb.InfoServiceClient{
+			"task-service":         info,
+			"user-service":         info,
+			"notification-service": info,
+			"analytics-service":    info,
+		},
+		backups: map[string]pb.BackupServiceClient{
+			"task-service":         backups,
+			"user-service":         backups,
+			"notification-service": backups,
+			"analytics-service":    backups,
+		},
+	}, backend
+}
+
+// contractGolden is what a golden records of a request to a route.
+type contractGolden struct {
+	// Calls are the calls the services got
+	Calls       []contractCall  `json:"calls"`
+	Status      int             `json:"status"`
+	ContentType string          `json:"content_type"`
+	Body        json.RawMessage `json:"body"`
+}
+
+func TestContract(t *testing.T) {
+	defer func(old string) { adminAPIKey = old }(adminAPIKey)
+	adminAPIKey = "contract-admin-key"
+
+	for _, tt := range contractCases {
+		t.Run(tt.name, func(t *testing.T) {
+			clients, backend := newContractClients(t)
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-Admin-Key", adminAPIKey)
+			rec := httptest.NewRecorder()
+			newRouter(clients).ServeHTTP(rec, req)
+
+			got := contractGolden{
+				Calls:       append([]contractCall{}, backend.sortedCalls()...),
+				Status:      rec.Code,
+				ContentType: rec.Header().Get("Content-Type"),
+				Body:        rec.Body.Bytes(),
+			}
+			// Bodies that are not JSON, such as CSV and event streams, are
+			// recorded as a string
+			if !json.Valid(got.Body) {
+				got.Body, _ = json.Marshal(rec.Body.String())
+			}
+			encoded, err := json.MarshalIndent(got, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			encoded = append(encoded, '\n')
+
+			path := filepath.Join("testdata", "contract", tt.name+".json")
+			if *update {
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, encoded, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("%v; record the golden with -update", err)
+			}
+			if !bytes.Equal(encoded, want) {
+				t.Errorf("%s %s no longer matches %s (-golden +got):\n%s\nRe-bless with -update if the change is intended.",
+					tt.method, tt.path, path, lineDiff(string(want), string(encoded)))
+			}
+		})
+	}
+}
+
+// TestContractCoversEveryRoute fails for a route without a contract case, so
+// new routes get a golden too.
+func TestContractCoversEveryRoute(t *testing.T) {
+	router := newRouter(&ServiceClients{})
+	covered := map[string]bool{}
+	for _, tt := range contractCases {
+		var match mux.RouteMatch
+		if !router.Match(httptest.NewRequest(tt.method, tt.path, nil), &match) || match.Route == nil {
+			t.Errorf("%s: %s %s matches no route", tt.name, tt.method, tt.path)
+			continue
+		}
+		template, _ := match.Route.GetPathTemplate()
+		covered[tt.method+" "+template] = true
+	}
+
+	router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, _ := route.GetMethods()
+		for _, method := range methods {
+			if !covered[method+" "+template] {
+				t.Errorf("%s %s has no contract case", method, template)
+			}
+		}
+		return nil
+	})
+}
+
+// lineDiff lists the lines of want and got that differ, after the longest
+// common subsequence of the two.
+func lineDiff(want, got string) string {
+	a, b := strings.Split(want, "\n"), strings.Split(got, "\n")
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var out strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			i, j = i+1, j+1
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			fmt.Fprintf(&out, "-%s\n", a[i])
+			i++
+		default:
+			fmt.Fprintf(&out, "+%s\n", b[j])
+			j++
+		}
+	}
+	return out.String()
+}
+
+// TestGatewayForwardsTenantOnEveryRoute sends every contract request as a
+// user of a tenant: each call the services get must carry the tenant, or
+// the service would serve it from the default tenant's data.
+func TestGatewayForwardsTenantOnEveryRoute(t *testing.T) {
+	defer func(old string) { adminAPIKey = old }(adminAPIKey)
+	adminAPIKey = "contract-admin-key"
+	defer func(old []byte) { jwtSecret = old }(jwtSecret)
+	jwtSecret = []byte("gateway-test-secret-of-32-characters")
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":    "u1",
+		"tenant": "acme",
+		"exp":    time.Now().Add(time.Hour).Unix(),
+	}).SignedString(jwtSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range contractCases {
+		t.Run(tt.name, func(t *testing.T) {
+			clients, backend := newContractClients(t)
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-Admin-Key", adminAPIKey)
+			req.Header.Set("Authorization", "Bearer "+token)
+			newRouter(clients).ServeHTTP(httptest.NewRecorder(), req)
+
+			backend.mu.Lock()
+			defer backend.mu.Unlock()
+			for method, tenants := range backend.tenants {
+				if strings.HasPrefix(method, "/todo.InfoService/") {
+					// Readiness asks about the services, not a tenant's data
+					continue
+				}
+				for _, got := range tenants {
+					if got != "acme" {
+						t.Errorf("%s got tenant %q, want acme", method, got)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// decompressed decodes request bodies sent with Content-Encoding gzip or
+// deflate (raw DEFLATE, as compress/flate reads it) before next sees them,
+// so clients can compress large imports and bulk requests. identity, or no
+// encoding, passes the body through; any other encoding is answered with
+// 415. The body is decompressed up front, up to limit bytes, so a body
+// larger than that once decompressed is answered with 413 whichever route
+// it was sent to, rather than failing part way through a handler.
+func decompressed(next http.Handler, limit int64) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+		if encoding == "" || encoding == "identity" || r.Body == nil || r.Body == http.NoBody {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var body io.ReadCloser
+		switch encoding {
+		case "gzip", "x-gzip":
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				respondWithError(w, http.StatusBadRequest, "Could not decompress request body")
+				return
+			}
+			body = zr
+		case "deflate":
+			body = flate.NewReader(r.Body)
+		default:
+			w.Header().Set("Accept-Encoding", "gzip, deflate")
+			respondWithError(w, http.StatusUnsupportedMediaType, "Unsupported Content-Encoding "+strconv.Quote(encoding))
+			return
+		}
+		defer body.Close()
+
+		data, err := io.ReadAll(io.LimitReader(body, limit+1))
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Could not decompress request body")
+			return
+		}
+		if int64(len(data)) > limit {
+			respondWithError(w, http.StatusRequestEntityTooLarge, "Request body too large")
+			return
+		}
+
+		r = r.Clone(r.Context())
+		r.Header.Del("Content-Encoding")
+		r.Body = io.NopCloser(bytes.NewReader(data))
+		r.ContentLength = int64(len(data))
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+const createTaskBody = `{"title":"Write report","user_id":"u1","labels":["work"]}`
+
+func compress(t *testing.T, encoding, body string) io.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch encoding {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "deflate":
+		var err error
+		if w, err = flate.NewWriter(&buf, flate.DefaultCompression); err != nil {
+			t.Fatal(err)
+		}
+	default:
+		return strings.NewReader(body)
+	}
+	if _, err := io.WriteString(w, body); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func TestDecompressedCreatesTask(t *testing.T) {
+	want := &pb.CreateTaskRequest{Title: "Write report", UserId: "u1", Labels: []string{"work"}}
+	for _, encoding := range []string{"", "identity", "gzip", "deflate"} {
+		t.Run("encoding "+encoding, func(t *testing.T) {
+			backend := &compatBackend{}
+			handler := decompressed(newRouter(&ServiceClients{taskClient: backend}), defaultMaxDecompressedBytes)
+			req := httptest.NewRequest(http.MethodPost, "/api/tasks", compress(t, encoding, createTaskBody))
+			if encoding != "" {
+				req.Header.Set("Content-Encoding", encoding)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != http.StatusCreated {
+				t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+			}
+			var created []proto.Message
+			for _, r := range backend.reqs {
+				if _, ok := r.(*pb.CreateTaskRequest); ok {
+					created = append(created, r)
+				}
+			}
+			if len(created) != 1 || !proto.Equal(created[0], want) {
+				t.Errorf("CreateTask requests = %v, want %v", created, want)
+			}
+		})
+	}
+}
+
+func TestDecompressedRejectsBodies(t *testing.T) {
+	tests := []struct {
+		name     string
+		encoding string
+		body     io.Reader
+		want     int
+	}{
+		{"unsupported encoding", "br", strings.NewReader(createTaskBody), http.StatusUnsupportedMediaType},
+		{"not gzip", "gzip", strings.NewReader(createTaskBody), http.StatusBadRequest},
+		{"truncated gzip", "gzip", io.LimitReader(compress(t, "gzip", createTaskBody), 20), http.StatusBadRequest},
+		{"not deflate", "deflate", strings.NewReader(createTaskBody), http.StatusBadRequest},
+		// A few kilobytes compressed, more than the limit decompressed
+		{"too large once decompressed", "gzip", compress(t, "gzip", strings.Repeat(" ", 1<<20)), http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			handler := decompressed(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { called = true }), 1<<19)
+			req := httptest.NewRequest(http.MethodPost, "/api/tasks", tt.body)
+			req.Header.Set("Content-Encoding", tt.encoding)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want || called {
+				t.Errorf("status = %d, handler called %v; want %d before the handler", rec.Code, called, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecompressedAtTheLimit(t *testing.T) {
+	body := strings.Repeat("a", 1<<10)
+	var got []byte
+	handler := decompressed(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Encoding") != "" || r.ContentLength != int64(len(body)) {
+			t.Errorf("Content-Encoding %q, length %d; want the decompressed body's", r.Header.Get("Content-Encoding"), r.ContentLength)
+		}
+		got, _ = io.ReadAll(r.Body)
+	}), int64(len(body)))
+	req := httptest.NewRequest(http.MethodPost, "/api/tasks", compress(t, "gzip", body))
+	req.Header.Set("Content-Encoding", "GZIP")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if string(got) != body {
+		t.Errorf("handler read %d bytes, want %d", len(got), len(body))
+	}
+}
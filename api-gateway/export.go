@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/csv"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// taskCSVHeader names the columns of a task CSV export.
+var taskCSVHeader = []string{"id", "title", "description", "completed", "priority", "labels", "due_date", "created_at", "updated_at"}
+
+// taskCSVRow encodes a task in the columns of taskCSVHeader. Labels share one
+// cell, separated by semicolons.
+func taskCSVRow(task *pb.Task) []string {
+	priority := ""
+	if task.Priority != pb.TaskPriority_TASK_PRIORITY_UNSPECIFIED {
+		priority = strings.ToLower(strings.TrimPrefix(task.Priority.String(), "TASK_PRIORITY_"))
+	}
+	return []string{
+		task.Id,
+		task.Title,
+		task.Description,
+		strconv.FormatBool(task.Completed),
+		priority,
+		strings.Join(task.Labels, ";"),
+		task.DueDate,
+		task.CreatedAt,
+		task.UpdatedAt,
+	}
+}
+
+// exportTasksCSVHandler streams the user's tasks as a CSV download, one row
+// per task as the task service sends it.
+func exportTasksCSVHandler(clients *ServiceClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if clients == nil || clients.taskClient == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "task service unavailable")
+			return
+		}
+		var query userQuery
+		if err := decoder.Decode(&query, r.URL.Query()); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid query parameters")
+			return
+		}
+
+		// The export runs until every task is sent or the client disconnects
+		stream, err := clients.taskClient.ExportTasks(outgoingContext(r.Context(), r), &pb.ExportTasksRequest{UserId: query.UserID})
+		if err != nil {
+			respondWithGRPCError(w, err)
+			return
+		}
+
+		// Wait for the first task so request errors still get a proper status
+		task, err := stream.Recv()
+		if err != nil && err != io.EOF {
+			respondWithGRPCError(w, err)
+			return
+		}
+
+		filename := "tasks-" + time.Now().UTC().Format("2006-01-02") + ".csv"
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+
+		flusher, _ := w.(http.Flusher)
+		out := csv.NewWriter(w)
+		if err := out.Write(taskCSVHeader); err != nil {
+			return
+		}
+		for err == nil {
+			if err = out.Write(taskCSVRow(task)); err != nil {
+				return
+			}
+			out.Flush()
+			if flusher != nil {
+				flusher.Flush()
+			}
+			task, err = stream.Recv()
+		}
+		out.Flush()
+		if err != io.EOF {
+			// The status is already sent; a truncated body is all that is left
+			log.Printf("Task export failed after it started: %v", err)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// exportTasks streams tasks, then err or io.EOF.
+type exportTasks struct {
+	pb.TaskServiceClient
+	tasks []*pb.Task
+	err   error
+}
+
+func (t *exportTasks) ExportTasks(ctx context.Context, req *pb.ExportTasksRequest, opts ...grpc.CallOption) (pb.TaskService_ExportTasksClient, error) {
+	return &taskStream{tasks: t.tasks, err: t.err}, nil
+}
+
+type taskStream struct {
+	grpc.ClientStream
+	tasks []*pb.Task
+	err   error
+}
+
+func (s *taskStream) Recv() (*pb.Task, error) {
+	if len(s.tasks) == 0 {
+		if s.err != nil {
+			return nil, s.err
+		}
+		return nil, io.EOF
+	}
+	task := s.tasks[0]
+	s.tasks = s.tasks[1:]
+	return task, nil
+}
+
+func TestExportTasksCSVRoundTrips(t *testing.T) {
+	tasks := []*pb.Task{
+		{
+			Id:          "t1",
+			Title:       `Reply to "urgent" mail, today`,
+			Description: "first line\nsecond line",
+			Completed:   true,
+			Priority:    pb.TaskPriority_TASK_PRIORITY_HIGH,
+			Labels:      []string{"work", "email;inbox"},
+			DueDate:     "2026-03-04T09:00:00Z",
+			CreatedAt:   "2026-03-01T08:00:00Z",
+			UpdatedAt:   "2026-03-02T08:00:00Z",
+		},
+		{Id: "t2", Title: "Plain"},
+	}
+	handler := exportTasksCSVHandler(&ServiceClients{taskClient: &exportTasks{tasks: tasks}})
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/api/tasks/export/csv?user_id=u1", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/csv") {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if got := rec.Header().Get("Content-Disposition"); !strings.HasPrefix(got, `attachment; filename="tasks-`) || !strings.HasSuffix(got, `.csv"`) {
+		t.Errorf("Content-Disposition = %q", got)
+	}
+
+	rows, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("export is not valid CSV: %v", err)
+	}
+	want := [][]string{
+		taskCSVHeader,
+		{"t1", `Reply to "urgent" mail, today`, "first line\nsecond line", "true", "high", "work;email;inbox", "2026-03-04T09:00:00Z", "2026-03-01T08:00:00Z", "2026-03-02T08:00:00Z"},
+		{"t2", "Plain", "", "false", "", "", "", "", ""},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows = %q, want %q", rows, want)
+	}
+}
+
+func TestExportTasksCSVErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		tasks    []*pb.Task
+		err      error
+		wantCode int
+	}{
+		{"rejected request", nil, status.Error(codes.InvalidArgument, "user_id is required"), http.StatusBadRequest},
+		// Once rows are sent the status cannot change; the body is cut short
+		{"failure after the first row", []*pb.Task{{Id: "t1"}}, status.Error(codes.Unavailable, "gone"), http.StatusOK},
+		{"no tasks", nil, nil, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := exportTasksCSVHandler(&ServiceClients{taskClient: &exportTasks{tasks: tt.tasks, err: tt.err}})
+			rec := httptest.NewRecorder()
+			handler(rec, httptest.NewRequest(http.MethodGet, "/api/tasks/export/csv?user_id=u1", nil))
+			if rec.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/technonext/todo-app/proto/faults"
+	"github.com/technonext/todo-app/proto/grpcmiddleware"
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+type faultyTaskServer struct {
+	pb.UnimplementedTaskServiceServer
+}
+
+func (faultyTaskServer) GetTask(ctx context.Context, req *pb.GetTaskRequest) (*pb.TaskResponse, error) {
+	return &pb.TaskResponse{Task: &pb.Task{Id: req.Id, Title: "Survive the faults"}}, nil
+}
+
+func TestGatewayRetriesInjectedFaults(t *testing.T) {
+	injector, err := faults.New(&pb.Fault{Method: "/todo.TaskService/GetTask", Percent: 50, Code: "UNAVAILABLE"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := grpcmiddleware.NewServer(grpcmiddleware.ServerConfig{Faults: injector})
+	pb.RegisterTaskServiceServer(s, faultyTaskServer{})
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Serve(injector.Listen(lis))
+	defer s.Stop()
+
+	opts := append([]grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())},
+		grpcmiddleware.DialOptions(grpcmiddleware.ClientConfig{RetryBackoff: time.Millisecond})...)
+	conn, err := grpc.Dial(lis.Addr().String(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	router := newRouter(&ServiceClients{taskClient: pb.NewTaskServiceClient(conn)})
+
+	const requests = 200
+	ok := 0
+	for i := 0; i < requests; i++ {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", "/api/tasks/t1", nil))
+		if rec.Code == http.StatusOK {
+			ok++
+		}
+	}
+	// Each call is tried three times, so about one in eight fails
+	if ok < requests*3/4 {
+		t.Errorf("%d of %d requests succeeded, want most of them", ok, requests)
+	}
+	if got := counterTotal(t, "grpc_client_injected_faults_total"); got < requests/4 {
+		t.Errorf("%v injected faults counted, want at least %d", got, requests/4)
+	}
+}
+
+// counterTotal sums the series of the named counter in the default registry.
+func counterTotal(t *testing.T, name string) float64 {
+	t.Helper()
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := 0.0
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			total += m.GetCounter().GetValue()
+		}
+	}
+	return total
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/utilities"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// createdMethods answer 201 Created instead of 200 OK.
+var createdMethods = map[string]bool{
+	"/todo.NotificationService/SendNotification": true,
+}
+
+// newGatewayMux serves the RPCs annotated with google.api.http through the
+// generated reverse proxy, answering the way the hand-written handlers did:
+// snake_case field names, enums as numbers, errors in our JSON envelope and
+// query parameters matched against Go field names as well as proto names.
+// Each call gets gatewayTimeout, as the handlers gave theirs.
+func newGatewayMux(clients *ServiceClients) http.Handler {
+	gw := runtime.NewServeMux(
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{
+			MarshalOptions:   protojson.MarshalOptions{UseProtoNames: true, UseEnumNumbers: true},
+			UnmarshalOptions: protojson.UnmarshalOptions{DiscardUnknown: true},
+		}),
+		runtime.WithErrorHandler(func(ctx context.Context, _ *runtime.ServeMux, _ runtime.Marshaler, w http.ResponseWriter, _ *http.Request, err error) {
+			respondWithGRPCError(w, err)
+		}),
+		runtime.SetQueryParameterParser(goNameQueryParser{}),
+		// Backends get the client details the handlers sent rather than the
+		// headers; grpc-gateway always passes Authorization on, which they ignore
+		runtime.WithIncomingHeaderMatcher(func(string) (string, bool) { return "", false }),
+		runtime.WithOutgoingHeaderMatcher(func(string) (string, bool) { return "", false }),
+		runtime.WithMetadata(func(ctx context.Context, r *http.Request) metadata.MD { return clientMetadata(r) }),
+		runtime.WithForwardResponseOption(func(ctx context.Context, w http.ResponseWriter, _ proto.Message) error {
+			if method, ok := runtime.RPCMethod(ctx); ok && createdMethods[method] {
+				w.WriteHeader(http.StatusCreated)
+			}
+			return nil
+		}),
+	)
+
+	ctx := context.Background()
+	registrations := []struct {
+		service  string
+		register func() error
+	}{
+		{"task", func() error { return pb.RegisterTaskServiceHandlerClient(ctx, gw, clients.taskClient) }},
+		{"user", func() error { return pb.RegisterUserServiceHandlerClient(ctx, gw, clients.userClient) }},
+		{"notification", func() error { return pb.RegisterNotificationServiceHandlerClient(ctx, gw, clients.notificationClient) }},
+		{"analytics", func() error { return pb.RegisterAnalyticsServiceHandlerClient(ctx, gw, clients.analyticsClient) }},
+	}
+	for _, r := range registrations {
+		if err := r.register(); err != nil {
+			log.Fatalf("Failed to register the %s service's REST routes: %v", r.service, err)
+		}
+	}
+	return withTimeout(gw, gatewayTimeout)
+}
+
+// gatewayTimeout bounds each call made through the generated proxy; a
+// Grpc-Timeout header can shorten it.
+const gatewayTimeout = 10 * time.Second
+
+// withTimeout serves next with a context that expires after timeout.
+func withTimeout(next http.Handler, timeout time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// goNameQueryParser accepts the Go field names older clients send, such as
+// UserId, by renaming them to the proto names before the default parsing.
+type goNameQueryParser struct{}
+
+func (goNameQueryParser) Parse(msg proto.Message, values url.Values, filter *utilities.DoubleArray) error {
+	fields := msg.ProtoReflect().Descriptor().Fields()
+	renamed := make(url.Values, len(values))
+	for key, vals := range values {
+		for i := 0; i < fields.Len(); i++ {
+			if name := string(fields.Get(i).Name()); goFieldName(name) == key {
+				key = name
+				break
+			}
+		}
+		renamed[key] = append(renamed[key], vals...)
+	}
+	return (&runtime.DefaultQueryParser{}).Parse(msg, renamed, filter)
+}
+
+// goFieldName returns the name protoc-gen-go gives a field: user_id becomes
+// UserId.
+func goFieldName(protoName string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(protoName, "_") {
+		if part != "" {
+			b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+	return b.String()
+}
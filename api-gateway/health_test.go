@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	"github.com/technonext/todo-app/proto/buildinfo"
+	"github.com/technonext/todo-app/proto/grpcmiddleware"
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+type downInfoClient struct{}
+
+func (downInfoClient) GetServiceInfo(ctx context.Context, req *pb.GetServiceInfoRequest, opts ...grpc.CallOption) (*pb.ServiceInfo, error) {
+	return nil, status.Error(codes.Unavailable, "connection refused")
+}
+
+func TestReadinessReportsBackendVersions(t *testing.T) {
+	s := grpcmiddleware.NewServer(grpcmiddleware.ServerConfig{
+		Info: buildinfo.NewServer("task-service", map[string]string{"PORT": "50051"}),
+	})
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Serve(lis)
+	defer s.Stop()
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	ready := func(info map[string]pb.InfoServiceClient) (int, map[string]backendStatus) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		newRouter(&ServiceClients{info: info}).ServeHTTP(rec, httptest.NewRequest("GET", "/health/ready", nil))
+		var body struct {
+			Services map[string]backendStatus `json:"services"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("body %s: %v", rec.Body, err)
+		}
+		return rec.Code, body.Services
+	}
+
+	code, services := ready(map[string]pb.InfoServiceClient{"task-service": pb.NewInfoServiceClient(conn)})
+	if code != http.StatusOK {
+		t.Errorf("status = %d, want 200", code)
+	}
+	if got := services["task-service"]; got.Status != "ok" || got.Version != buildinfo.Version || got.Commit != buildinfo.Commit {
+		t.Errorf("task-service = %+v, want ok at version %s", got, buildinfo.Version)
+	}
+
+	code, services = ready(map[string]pb.InfoServiceClient{
+		"task-service": pb.NewInfoServiceClient(conn),
+		"user-service": downInfoClient{},
+	})
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("status with a service down = %d, want 503", code)
+	}
+	if got := services["user-service"]; got.Status != "unavailable" || got.Error != "connection refused" || got.Version != "" {
+		t.Errorf("user-service = %+v, want unavailable", got)
+	}
+	if services["task-service"].Status != "ok" {
+		t.Errorf("task-service = %+v, want ok", services["task-service"])
+	}
+}
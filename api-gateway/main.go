@@ -2,18 +2,38 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
 	"encoding/json"
-	"log"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
 	"github.com/gorilla/schema"
+	"github.com/technonext/todo-app/proto/buildinfo"
+	"github.com/technonext/todo-app/proto/callctx"
+	"github.com/technonext/todo-app/proto/featureflags"
+	"github.com/technonext/todo-app/proto/grpcmiddleware"
+	"github.com/technonext/todo-app/proto/logging"
 	pb "github.com/technonext/todo-app/proto/proto"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	"github.com/technonext/todo-app/proto/tenant"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 )
 
 // Service clients
@@ -22,54 +42,168 @@ type ServiceClients struct {
 	userClient         pb.UserServiceClient
 	notificationClient pb.NotificationServiceClient
 	analyticsClient    pb.AnalyticsServiceClient
+	weeklyReports      *reportCache
+	// summaries keeps the notification summaries badges poll
+	summaries *summaryCache
+	// featureFlags are the flags of FEATURE_FLAGS, as the services read
+	// them
+	featureFlags *featureflags.FeatureFlags
+	// info reaches each service's InfoService, by service name
+	info map[string]pb.InfoServiceClient
+	// backups reaches each service's BackupService, by service name
+	backups map[string]pb.BackupServiceClient
 }
 
 var decoder = schema.NewDecoder()
 
 func main() {
+	logging.Setup("api-gateway")
+
+	cfg := loadConfig()
+	if err := validateConfig(cfg); err != nil {
+		logging.Fatal("Invalid configuration", "error", err)
+	}
+	pprof, err := grpcmiddleware.PprofFromEnv()
+	if err != nil {
+		logging.Fatal("Invalid configuration", "error", err)
+	}
+	flags, err := featureflags.FromEnv()
+	if err != nil {
+		logging.Fatal("Invalid configuration", "error", err)
+	}
+
 	// Initialize service connections
 	clients := initServiceClients()
+	clients.featureFlags = flags
+	router := newRouter(clients)
+
+	// CORS handler
+	corsHandler := handlers.CORS(
+		handlers.AllowedOrigins(cfg.allowedOrigins()),
+		handlers.AllowedMethods([]string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}),
+		handlers.AllowedHeaders([]string{"Content-Type", "Authorization", "X-Admin-Key", "X-Device-Fingerprint", "Content-Encoding", requestIDHeader}),
+		handlers.ExposedHeaders([]string{requestIDHeader}),
+	)
+
+	handler := corsHandler(decompressed(router, cfg.maxDecompressedBytes()))
+	if rps := cfg.rateLimit(); rps > 0 {
+		handler = rateLimited(handler, rps)
+	}
+	handler = withRequestID(handler)
+
+	// Start server
+	grpcmiddleware.ServeMetrics(grpcmiddleware.MetricsConfig{
+		Port:  os.Getenv("METRICS_PORT"),
+		Info:  buildinfo.NewServer("api-gateway", cfg.info()),
+		Pprof: pprof,
+	})
+	slog.Info("API Gateway starting", "port", cfg.Port, "version", buildinfo.Version)
+	logging.Fatal("API Gateway stopped", "error", http.ListenAndServe(":"+cfg.Port, handler))
+}
 
-	// Create router
+// newRouter routes the API to the services.
+func newRouter(clients *ServiceClients) *mux.Router {
 	router := mux.NewRouter()
+	// RPCs annotated with google.api.http in todo.proto
+	gw := newGatewayMux(clients)
 
 	// Health check
 	router.HandleFunc("/health", healthCheckHandler).Methods("GET")
+	router.HandleFunc("/health/ready", readinessHandler(clients)).Methods("GET")
 
 	// Task routes
-	router.HandleFunc("/api/tasks", createTaskHandler(clients)).Methods("POST")
-	router.HandleFunc("/api/tasks/{id}", getTaskHandler(clients)).Methods("GET")
-	router.HandleFunc("/api/tasks/{id}", updateTaskHandler(clients)).Methods("PUT")
-	router.HandleFunc("/api/tasks/{id}", deleteTaskHandler(clients)).Methods("DELETE")
-	router.HandleFunc("/api/tasks", listTasksHandler(clients)).Methods("GET")
+	router.HandleFunc("/api/tasks", rejectFrozen(createTaskHandler(clients))).Methods("POST")
+	router.HandleFunc("/api/tasks", rejectFrozen(bulkUpdateTasksHandler(clients))).Methods("PATCH")
+	router.HandleFunc("/api/tasks/sync", syncTasksHandler(clients)).Methods("GET")
+	router.HandleFunc("/api/tasks/pending-summary", pendingTaskCountHandler(clients)).Methods("GET")
+	router.HandleFunc("/api/tasks/similar", findSimilarTasksHandler(clients)).Methods("GET")
+	router.HandleFunc("/api/tasks/calendar", tasksCalendarViewHandler(clients)).Methods("GET")
+	router.HandleFunc("/api/tasks/trash", listDeletedTasksHandler(clients)).Methods("GET")
+	router.HandleFunc("/api/tasks/export/csv", exportTasksCSVHandler(clients)).Methods("GET")
+	router.Handle("/api/tasks/{id}", gw).Methods("GET")
+	router.HandleFunc("/api/tasks/{id}", rejectFrozen(updateTaskHandler(clients))).Methods("PUT")
+	router.Handle("/api/tasks/{id}", gw).Methods("DELETE")
+	router.HandleFunc("/api/tasks/{id}/status", rejectFrozen(updateTaskStatusHandler(clients))).Methods("PUT")
+	router.HandleFunc("/api/tasks/{id}/metrics", getTaskMetricsHandler(clients)).Methods("GET")
+	router.Handle("/api/tasks/{id}/move", rejectFrozen(gw.ServeHTTP)).Methods("POST")
+	router.Handle("/api/tasks/{id}/extend-due-date", rejectFrozen(gw.ServeHTTP)).Methods("POST")
+	router.Handle("/api/tasks/{id}/lock", rejectFrozen(gw.ServeHTTP)).Methods("POST")
+	router.Handle("/api/tasks/{id}/lock", gw).Methods("DELETE")
+	router.Handle("/api/tasks/{id}/checklist", rejectFrozen(gw.ServeHTTP)).Methods("POST")
+	router.Handle("/api/tasks/{id}/checklist/{item_id}", rejectFrozen(gw.ServeHTTP)).Methods("PATCH", "DELETE")
+	router.Handle("/api/tasks/{id}/notes", gw).Methods("GET")
+	router.Handle("/api/tasks/{id}/notes", rejectFrozen(gw.ServeHTTP)).Methods("PUT")
+	router.Handle("/api/tasks", gw).Methods("GET")
 
 	// User routes
 	router.HandleFunc("/api/users", createUserHandler(clients)).Methods("POST")
-	router.HandleFunc("/api/users/{id}", getUserHandler(clients)).Methods("GET")
-	router.HandleFunc("/api/users/{id}", updateUserHandler(clients)).Methods("PUT")
-	router.HandleFunc("/api/users/{id}", deleteUserHandler(clients)).Methods("DELETE")
-	router.HandleFunc("/api/auth", authHandler(clients)).Methods("POST")
+	router.Handle("/api/users/{id}", gw).Methods("GET")
+	router.Handle("/api/users/{id}", gw).Methods("PUT")
+	router.Handle("/api/users/{id}", gw).Methods("DELETE")
+	router.HandleFunc("/api/users/{id}/digest-schedule", setDigestScheduleHandler(clients)).Methods("POST")
+	router.HandleFunc("/api/users/{id}/digest-schedule", deleteDigestScheduleHandler(clients)).Methods("DELETE")
+	router.HandleFunc("/api/users/{id}/weekly-report", getWeeklyReportHandler(clients)).Methods("GET")
+	router.Handle("/api/auth", gw).Methods("POST")
 
 	// Notification routes
-	router.HandleFunc("/api/notifications", sendNotificationHandler(clients)).Methods("POST")
-	router.HandleFunc("/api/notifications", getNotificationsHandler(clients)).Methods("GET")
+	router.Handle("/api/notifications", gw).Methods("POST")
+	router.Handle("/api/notifications", gw).Methods("GET")
+	router.HandleFunc("/api/notifications/test", testNotificationHandler(clients)).Methods("POST")
+	router.HandleFunc("/api/notifications/delivery-report", userDeliveryReportHandler(clients)).Methods("GET")
+	router.HandleFunc("/api/notifications/summary", getNotificationSummaryHandler(clients)).Methods("GET")
+	router.HandleFunc("/api/notifications/{id}/read", markNotificationReadHandler(clients)).Methods("POST")
+	router.Handle("/api/notifications/{id}", gw).Methods("GET")
 
 	// Analytics routes
 	router.HandleFunc("/api/analytics/events", trackEventHandler(clients)).Methods("POST")
-	router.HandleFunc("/api/analytics/users/{id}/stats", getUserStatsHandler(clients)).Methods("GET")
+	router.HandleFunc("/api/analytics/events/batch", trackEventsHandler(clients)).Methods("POST")
+	router.HandleFunc("/api/analytics/events/subscribe", subscribeEventsHandler(clients)).Methods("GET")
+	router.Handle("/api/analytics/users/{id}/stats", gw).Methods("GET")
+	router.Handle("/api/analytics/users/{id}/forecast", gw).Methods("GET")
+	router.HandleFunc("/api/analytics/users/{id}/peak-hours", getPeakHoursHandler(clients)).Methods("GET")
+	router.HandleFunc("/api/analytics/users/{id}/engagement", getEngagementScoreHandler(clients)).Methods("GET")
+	router.HandleFunc("/api/analytics/users/{id}/streak", getUserStreakHandler(clients)).Methods("GET")
+	router.HandleFunc("/api/analytics/users/{id}/weekly-summary", getWeeklySummaryHandler(clients)).Methods("GET")
+	router.HandleFunc("/api/analytics/users/{id}/breakdown", getTaskBreakdownHandler(clients)).Methods("GET")
+	router.HandleFunc("/api/analytics/users/{id}/heatmap", getActivityHeatmapHandler(clients)).Methods("GET")
+	router.HandleFunc("/api/analytics/users/{id}/time-report", getTimeReportHandler(clients)).Methods("GET")
+	router.HandleFunc("/api/analytics/users/{id}/burndown", getBurndownHandler(clients)).Methods("GET")
+	router.HandleFunc("/api/analytics/users/{id}/priority-breakdown", getPriorityBreakdownHandler(clients)).Methods("GET")
 	router.HandleFunc("/api/analytics/tasks/stats", getTaskStatsHandler(clients)).Methods("GET")
-
-	// CORS handler
-	corsHandler := handlers.CORS(
-		handlers.AllowedOrigins([]string{"*"}),
-		handlers.AllowedMethods([]string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}),
-		handlers.AllowedHeaders([]string{"Content-Type", "Authorization"}),
-	)
-
-	// Start server
-	port := getEnv("PORT", "8080")
-	log.Printf("API Gateway starting on port %s", port)
-	log.Fatal(http.ListenAndServe(":"+port, corsHandler(router)))
+	router.HandleFunc("/api/analytics/trend", getCompletionTrendHandler(clients)).Methods("GET")
+	router.HandleFunc("/api/analytics/overdue", getOverdueAgingHandler(clients)).Methods("GET")
+	router.HandleFunc("/api/analytics/export", exportStatsHandler(clients)).Methods("GET")
+	router.HandleFunc("/api/analytics/completion-latency", getCompletionLatencyHandler(clients)).Methods("GET")
+
+	// Admin routes
+	router.HandleFunc("/api/admin/notifications/failed", requireAdmin(listFailedDeliveriesHandler(clients))).Methods("GET")
+	router.HandleFunc("/api/admin/notifications/delivery-report", requireAdmin(adminDeliveryReportHandler(clients))).Methods("GET")
+	router.HandleFunc("/api/admin/notifications/{id}/redeliver", requireAdmin(redeliverNotificationHandler(clients))).Methods("POST")
+	router.HandleFunc("/api/admin/analytics/backfill", requireAdmin(backfillAnalyticsHandler(clients))).Methods("POST")
+	router.HandleFunc("/api/analytics/active-users", requireAdmin(getActiveUsersHandler(clients))).Methods("GET")
+	router.HandleFunc("/api/analytics/compare", requireAdmin(compareUsersHandler(clients))).Methods("GET")
+	router.HandleFunc("/api/admin/tasks/events/replay", requireAdmin(replayTaskEventsHandler(clients))).Methods("POST")
+	router.HandleFunc("/api/admin/tasks/migrate-ownership", requireAdmin(migrateTaskOwnershipHandler(clients))).Methods("POST")
+	router.HandleFunc("/api/admin/analytics/replay", requireAdmin(replayEventsHandler(clients))).Methods("GET")
+	router.HandleFunc("/api/admin/analytics/events/stream", requireAdmin(streamEventsHandler(clients))).Methods("GET")
+	router.HandleFunc("/api/admin/analytics/retention", requireAdmin(retentionStatusHandler(clients))).Methods("GET")
+	router.HandleFunc("/api/admin/analytics/cache", requireAdmin(cacheStatsHandler(clients))).Methods("GET")
+	router.HandleFunc("/api/admin/overview", requireAdmin(systemOverviewHandler(clients))).Methods("GET")
+	router.HandleFunc("/api/admin/feature-flags", requireAdmin(featureFlagsHandler(clients))).Methods("GET")
+	router.HandleFunc("/api/admin/backup", requireAdmin(backupHandler(clients))).Methods("POST")
+	router.HandleFunc("/api/admin/sessions", requireAdmin(listSessionsHandler(clients))).Methods("GET")
+	router.HandleFunc("/api/admin/users/{id}/freeze", requireAdmin(freezeUserHandler(clients))).Methods("POST")
+	router.HandleFunc("/api/admin/users/{id}/unfreeze", requireAdmin(unfreezeUserHandler(clients))).Methods("POST")
+	router.HandleFunc("/api/admin/users/{id}/purge", requireAdmin(purgeUserDataHandler(clients))).Methods("DELETE")
+	router.HandleFunc("/api/admin/users/{id}/deletion", requireAdmin(getDeletionStatusHandler(clients))).Methods("GET")
+	router.HandleFunc("/api/admin/notification-templates", requireAdmin(createTemplateHandler(clients))).Methods("POST")
+	router.HandleFunc("/api/admin/notification-templates", requireAdmin(listTemplatesHandler(clients))).Methods("GET")
+	router.HandleFunc("/api/admin/notification-templates/{id}", requireAdmin(getTemplateHandler(clients))).Methods("GET")
+	router.HandleFunc("/api/admin/notification-templates/{id}", requireAdmin(updateTemplateHandler(clients))).Methods("PATCH")
+	router.HandleFunc("/api/admin/notification-templates/{id}", requireAdmin(deleteTemplateHandler(clients))).Methods("DELETE")
+	router.HandleFunc("/api/admin/notification-templates/{id}/clone", requireAdmin(cloneTemplateHandler(clients))).Methods("POST")
+	router.HandleFunc("/api/admin/notification-templates/{id}/activate", requireAdmin(activateTemplateHandler(clients))).Methods("POST")
+	return router
 }
 
 func initServiceClients() *ServiceClients {
@@ -80,24 +214,24 @@ func initServiceClients() *ServiceClients {
 	analyticsServiceAddr := getEnv("ANALYTICS_SERVICE_ADDR", "localhost:50054")
 
 	// Set up connections to services
-	taskConn, err := grpc.Dial(taskServiceAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	taskConn, err := grpcmiddleware.Dial(taskServiceAddr, "TASK_SERVICE_HMAC_SECRET")
 	if err != nil {
-		log.Fatalf("Failed to connect to task service: %v", err)
+		logging.Fatal("Failed to connect to task service", "error", err)
 	}
 
-	userConn, err := grpc.Dial(userServiceAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	userConn, err := grpcmiddleware.Dial(userServiceAddr, "USER_SERVICE_HMAC_SECRET")
 	if err != nil {
-		log.Fatalf("Failed to connect to user service: %v", err)
+		logging.Fatal("Failed to connect to user service", "error", err)
 	}
 
-	notificationConn, err := grpc.Dial(notificationServiceAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	notificationConn, err := grpcmiddleware.Dial(notificationServiceAddr, "NOTIFICATION_SERVICE_HMAC_SECRET")
 	if err != nil {
-		log.Fatalf("Failed to connect to notification service: %v", err)
+		logging.Fatal("Failed to connect to notification service", "error", err)
 	}
 
-	analyticsConn, err := grpc.Dial(analyticsServiceAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	analyticsConn, err := grpcmiddleware.Dial(analyticsServiceAddr, "ANALYTICS_SERVICE_HMAC_SECRET")
 	if err != nil {
-		log.Fatalf("Failed to connect to analytics service: %v", err)
+		logging.Fatal("Failed to connect to analytics service", "error", err)
 	}
 
 	return &ServiceClients{
@@ -105,6 +239,20 @@ func initServiceClients() *ServiceClients {
 		userClient:         pb.NewUserServiceClient(userConn),
 		notificationClient: pb.NewNotificationServiceClient(notificationConn),
 		analyticsClient:    pb.NewAnalyticsServiceClient(analyticsConn),
+		weeklyReports:      newReportCache(weeklyReportCacheTTL),
+		summaries:          newSummaryCache(notificationSummaryCacheTTL),
+		info: map[string]pb.InfoServiceClient{
+			"task-service":         pb.NewInfoServiceClient(taskConn),
+			"user-service":         pb.NewInfoServiceClient(userConn),
+			"notification-service": pb.NewInfoServiceClient(notificationConn),
+			"analytics-service":    pb.NewInfoServiceClient(analyticsConn),
+		},
+		backups: map[string]pb.BackupServiceClient{
+			"task-service":         pb.NewBackupServiceClient(taskConn),
+			"user-service":         pb.NewBackupServiceClient(userConn),
+			"notification-service": pb.NewBackupServiceClient(notificationConn),
+			"analytics-service":    pb.NewBackupServiceClient(analyticsConn),
+		},
 	}
 }
 
@@ -127,11 +275,273 @@ func respondWithError(w http.ResponseWriter, code int, message string) {
 	respondWithJSON(w, code, map[string]string{"error": message})
 }
 
+// respondWithGRPCError maps a backend gRPC status to the closest HTTP status.
+// When the status carries an ErrorInfo, its reason code and metadata are
+// returned alongside the message, and a BadRequest's violations as fields.
+func respondWithGRPCError(w http.ResponseWriter, err error) {
+	st := status.Convert(err)
+	code := http.StatusInternalServerError
+	switch st.Code() {
+	case codes.InvalidArgument, codes.OutOfRange:
+		code = http.StatusBadRequest
+	case codes.NotFound:
+		code = http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		code = http.StatusConflict
+	case codes.Unauthenticated:
+		code = http.StatusUnauthorized
+	case codes.PermissionDenied:
+		code = http.StatusForbidden
+	case codes.FailedPrecondition:
+		code = http.StatusPreconditionFailed
+	case codes.ResourceExhausted:
+		code = http.StatusTooManyRequests
+	case codes.Unavailable:
+		code = http.StatusServiceUnavailable
+	}
+	body := map[string]interface{}{"error": st.Message()}
+	for _, detail := range st.Details() {
+		switch detail := detail.(type) {
+		case *errdetails.ErrorInfo:
+			body["reason"] = detail.Reason
+			if len(detail.Metadata) > 0 {
+				body["metadata"] = detail.Metadata
+			}
+		case *errdetails.RetryInfo:
+			// A service shedding load says when to come back, as the rate
+			// limit does
+			if delay := detail.RetryDelay.AsDuration(); delay > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+			}
+		case *errdetails.BadRequest:
+			// Fields breaking the rules in todo.proto, listed as the request
+			// body schemas list theirs
+			body["error"] = "Invalid request payload"
+			fields := make([]fieldError, len(detail.FieldViolations))
+			for i, v := range detail.FieldViolations {
+				fields[i] = fieldError{Field: v.Field, Error: v.Description}
+			}
+			body["fields"] = fields
+		}
+	}
+	respondWithJSON(w, code, body)
+}
+
+// adminAPIKey guards the /api/admin routes; when unset they are disabled.
+var adminAPIKey = os.Getenv("ADMIN_API_KEY")
+
+// requireAdmin only lets requests carrying the admin key in X-Admin-Key through.
+func requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("X-Admin-Key")
+		if adminAPIKey == "" || subtle.ConstantTimeCompare([]byte(key), []byte(adminAPIKey)) != 1 {
+			respondWithError(w, http.StatusForbidden, "admin access required")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// rejectFrozen turns away writes from tokens issued to a frozen account
+// without asking the task service, which checks the account itself anyway.
+// The token is not verified here: a forged claim could only get its own
+// request rejected.
+func rejectFrozen(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			claims := jwt.MapClaims{}
+			if _, _, err := jwt.NewParser().ParseUnverified(token, claims); err == nil && claims["is_frozen"] == true {
+				respondWithJSON(w, http.StatusForbidden, map[string]string{"error": "account is frozen", "reason": "ACCOUNT_FROZEN"})
+				return
+			}
+		}
+		next(w, r)
+	}
+}
+
+// jwtSecret verifies session tokens, so the user a request is made for, and
+// their tenant, can be passed on to the services in the user-id and
+// tenant-id metadata. Unset, neither is passed on and the services go by the
+// user ids in requests, in the default tenant.
+var jwtSecret = []byte(os.Getenv("JWT_SECRET"))
+
+// authenticatedUser returns the user the request's bearer token was issued
+// to and the tenant in its tenant claim, or "" when there is no token or it
+// does not verify. Only the services act for every tenant, so a token
+// claiming to is treated as having no tenant.
+func authenticatedUser(r *http.Request) (userID, tenantID string) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || len(jwtSecret) == 0 {
+		return "", ""
+	}
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) { return jwtSecret, nil },
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return "", ""
+	}
+	userID, _ = claims.GetSubject()
+	if tenantID, _ = claims["tenant"].(string); tenantID == tenant.All {
+		tenantID = ""
+	}
+	return userID, tenantID
+}
+
+// trustedProxyCount is the number of reverse proxies in front of the gateway
+// that append to X-Forwarded-For. Entries left of theirs are client-supplied.
+var trustedProxyCount = loadTrustedProxyCount()
+
+func loadTrustedProxyCount() int {
+	n, err := strconv.Atoi(getEnv("TRUSTED_PROXY_COUNT", "0"))
+	if err != nil || n < 0 {
+		logging.Fatal("Invalid TRUSTED_PROXY_COUNT", "value", os.Getenv("TRUSTED_PROXY_COUNT"))
+	}
+	return n
+}
+
+// clientIP returns the address of the client behind trustedProxies proxies,
+// or the peer's when X-Forwarded-For has fewer entries than that. With no
+// trusted proxies X-Forwarded-For is ignored, since anyone can send it.
+func clientIP(r *http.Request, trustedProxies int) string {
+	remote, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		remote = r.RemoteAddr
+	}
+	if trustedProxies == 0 {
+		return remote
+	}
+
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(header, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+	}
+	if len(hops) == 0 {
+		return remote
+	}
+	// Each trusted proxy appended the address it received the request from,
+	// so the client is the entry added by the outermost one. Fewer entries
+	// mean the request did not come through every proxy, so none of them can
+	// be told apart from what the client sent; the peer at least is real.
+	if len(hops) < trustedProxies {
+		return remote
+	}
+	return hops[len(hops)-trustedProxies]
+}
+
+// deviceFingerprint prefers a fingerprint supplied by the client and falls
+// back to a hash of its browser headers.
+func deviceFingerprint(r *http.Request) string {
+	if fp := r.Header.Get("X-Device-Fingerprint"); fp != "" {
+		return fp
+	}
+	sum := sha256.Sum256([]byte(r.UserAgent() + "|" + r.Header.Get("Accept-Language")))
+	return hex.EncodeToString(sum[:16])
+}
+
+// clientLocale is the language the client prefers most, the first tag of
+// its Accept-Language, or "" when it names none. The services ignore tags
+// that are not well formed.
+func clientLocale(r *http.Request) string {
+	first, _, _ := strings.Cut(r.Header.Get("Accept-Language"), ",")
+	tag, _, _ := strings.Cut(first, ";")
+	if tag = strings.TrimSpace(tag); tag == "*" {
+		return ""
+	}
+	return tag
+}
+
+// outgoingContext returns parent carrying clientMetadata(r), for the calls a
+// handler makes to the services on r's behalf, so they see the user and
+// tenant r authenticated as.
+func outgoingContext(parent context.Context, r *http.Request) context.Context {
+	return metadata.NewOutgoingContext(parent, clientMetadata(r))
+}
+
+// clientMetadata carries details about the HTTP client, the user and tenant
+// it authenticated as, and the request's id from withRequestID, to the
+// backend.
+func clientMetadata(r *http.Request) metadata.MD {
+	md := metadata.Pairs(
+		"x-client-user-agent", r.UserAgent(),
+		"x-client-ip", clientIP(r, trustedProxyCount),
+		"x-device-fingerprint", deviceFingerprint(r),
+	)
+	userID, tenantID := authenticatedUser(r)
+	callctx.SetUserID(md, userID)
+	callctx.SetTenantID(md, tenantID)
+	callctx.SetLocale(md, clientLocale(r))
+	callctx.SetRequestID(md, grpcmiddleware.RequestID(r.Context()))
+	return md
+}
+
 // Health check handler
 func healthCheckHandler(w http.ResponseWriter, r *http.Request) {
 	respondWithJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 }
 
+// readinessTimeout bounds how long /health/ready waits for a service.
+const readinessTimeout = 2 * time.Second
+
+// backendStatus is a service's part of the readiness report.
+type backendStatus struct {
+	Status    string `json:"status"`
+	Version   string `json:"version,omitempty"`
+	Commit    string `json:"commit,omitempty"`
+	BuildDate string `json:"build_date,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// readinessHandler reports the gateway ready when every service answers its
+// InfoService, with the build each one runs, so a rollout can be checked from
+// one place. A service that does not answer makes it 503.
+func readinessHandler(clients *ServiceClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var info map[string]pb.InfoServiceClient
+		if clients != nil {
+			info = clients.info
+		}
+		ctx, cancel := context.WithTimeout(tenant.WithID(context.Background(), tenant.All), readinessTimeout)
+		defer cancel()
+
+		services := make(map[string]backendStatus, len(info))
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		for name, client := range info {
+			wg.Add(1)
+			go func(name string, client pb.InfoServiceClient) {
+				defer wg.Done()
+				backend := backendStatus{Status: "ok"}
+				if resp, err := client.GetServiceInfo(ctx, &pb.GetServiceInfoRequest{}); err != nil {
+					backend = backendStatus{Status: "unavailable", Error: status.Convert(err).Message()}
+				} else {
+					backend.Version, backend.Commit, backend.BuildDate = resp.Version, resp.Commit, resp.BuildDate
+				}
+				mu.Lock()
+				services[name] = backend
+				mu.Unlock()
+			}(name, client)
+		}
+		wg.Wait()
+
+		code, ready := http.StatusOK, "ready"
+		for _, backend := range services {
+			if backend.Status != "ok" {
+				code, ready = http.StatusServiceUnavailable, "not_ready"
+			}
+		}
+		respondWithJSON(w, code, map[string]interface{}{
+			"status":   ready,
+			"version":  buildinfo.Version,
+			"commit":   buildinfo.Commit,
+			"services": services,
+		})
+	}
+}
+
 // Task handlers
 func createTaskHandler(clients *ServiceClients) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -140,39 +550,70 @@ func createTaskHandler(clients *ServiceClients) http.HandlerFunc {
 			return
 		}
 		var req pb.CreateTaskRequest
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		if !decodeValidated(w, r, createTaskSchema, &req) {
 			return
 		}
 
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		md := clientMetadata(r)
+		ctx, cancel := context.WithTimeout(metadata.NewOutgoingContext(context.Background(), md), 10*time.Second)
 		defer cancel()
 
+		// The task service makes the authenticated user the owner, whatever
+		// the body says
+		owner := req.UserId
+		if userIDs := md.Get(callctx.UserIDHeader); len(userIDs) > 0 {
+			owner = userIDs[0]
+		}
+		// Look for duplicates before creating, so the new task is not among them.
+		// The check is advisory and never blocks creation.
+		similar, err := clients.taskClient.FindSimilarTasks(ctx, &pb.FindSimilarTasksRequest{
+			UserId: owner,
+			Title:  req.Title,
+		})
+		if err != nil {
+			slog.Warn("Failed to find similar tasks", "title", req.Title, "error", err)
+			similar = &pb.ListTasksResponse{}
+		}
+
 		resp, err := clients.taskClient.CreateTask(ctx, &req)
 		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, err.Error())
+			respondWithGRPCError(w, err)
 			return
 		}
 
-		respondWithJSON(w, http.StatusCreated, resp)
+		respondWithJSON(w, http.StatusCreated, createTaskResponse{
+			TaskResponse:        resp,
+			PotentialDuplicates: similar.Tasks,
+		})
 	}
 }
 
-func getTaskHandler(clients *ServiceClients) http.HandlerFunc {
+// createTaskResponse adds the user's existing tasks that look like the new
+// one, so the client can warn about duplicates.
+type createTaskResponse struct {
+	*pb.TaskResponse
+	PotentialDuplicates []*pb.Task `json:"potential_duplicates,omitempty"`
+}
+
+func findSimilarTasksHandler(clients *ServiceClients) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if clients == nil || clients.taskClient == nil {
 			respondWithError(w, http.StatusServiceUnavailable, "task service unavailable")
 			return
 		}
-		vars := mux.Vars(r)
-		id := vars["id"]
+		var query similarTasksQuery
+		if err := decoder.Decode(&query, r.URL.Query()); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid query parameters")
+			return
+		}
+		req := pb.FindSimilarTasksRequest{UserId: query.UserID, Title: query.Title, Threshold: query.Threshold}
 
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		ctx, cancel := context.WithTimeout(outgoingContext(context.Background(), r), 10*time.Second)
 		defer cancel()
 
-		resp, err := clients.taskClient.GetTask(ctx, &pb.GetTaskRequest{Id: id})
+		resp, err := clients.taskClient.FindSimilarTasks(ctx, &req)
 		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, err.Error())
+			respondWithGRPCError(w, err)
 			return
 		}
 
@@ -180,28 +621,25 @@ func getTaskHandler(clients *ServiceClients) http.HandlerFunc {
 	}
 }
 
-func updateTaskHandler(clients *ServiceClients) http.HandlerFunc {
+func tasksCalendarViewHandler(clients *ServiceClients) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if clients == nil || clients.taskClient == nil {
 			respondWithError(w, http.StatusServiceUnavailable, "task service unavailable")
 			return
 		}
-		vars := mux.Vars(r)
-		id := vars["id"]
-
-		var req pb.UpdateTaskRequest
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		var query calendarViewQuery
+		if err := decoder.Decode(&query, r.URL.Query()); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid query parameters")
 			return
 		}
-		req.Id = id
+		req := pb.GetTasksCalendarViewRequest{UserId: query.UserID, Month: query.Month, Year: query.Year, Timezone: query.Timezone}
 
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		ctx, cancel := context.WithTimeout(outgoingContext(context.Background(), r), 10*time.Second)
 		defer cancel()
 
-		resp, err := clients.taskClient.UpdateTask(ctx, &req)
+		resp, err := clients.taskClient.GetTasksCalendarView(ctx, &req)
 		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, err.Error())
+			respondWithGRPCError(w, err)
 			return
 		}
 
@@ -209,7 +647,7 @@ func updateTaskHandler(clients *ServiceClients) http.HandlerFunc {
 	}
 }
 
-func deleteTaskHandler(clients *ServiceClients) http.HandlerFunc {
+func getTaskMetricsHandler(clients *ServiceClients) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if clients == nil || clients.taskClient == nil {
 			respondWithError(w, http.StatusServiceUnavailable, "task service unavailable")
@@ -218,12 +656,12 @@ func deleteTaskHandler(clients *ServiceClients) http.HandlerFunc {
 		vars := mux.Vars(r)
 		id := vars["id"]
 
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		ctx, cancel := context.WithTimeout(outgoingContext(context.Background(), r), 10*time.Second)
 		defer cancel()
 
-		resp, err := clients.taskClient.DeleteTask(ctx, &pb.DeleteTaskRequest{Id: id})
+		resp, err := clients.taskClient.GetTaskMetrics(ctx, &pb.GetTaskMetricsRequest{Id: id})
 		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, err.Error())
+			respondWithGRPCError(w, err)
 			return
 		}
 
@@ -231,24 +669,29 @@ func deleteTaskHandler(clients *ServiceClients) http.HandlerFunc {
 	}
 }
 
-func listTasksHandler(clients *ServiceClients) http.HandlerFunc {
+func updateTaskHandler(clients *ServiceClients) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if clients == nil || clients.taskClient == nil {
 			respondWithError(w, http.StatusServiceUnavailable, "task service unavailable")
 			return
 		}
-		var req pb.ListTasksRequest
-		if err := decoder.Decode(&req, r.URL.Query()); err != nil {
-			respondWithError(w, http.StatusBadRequest, "Invalid query parameters")
+		vars := mux.Vars(r)
+		id := vars["id"]
+
+		var req pb.UpdateTaskRequest
+		if !decodeValidated(w, r, updateTaskSchema, &req) {
 			return
 		}
+		req.Id = id
 
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		// The task service checks the task's edit lock against the
+		// authenticated user
+		ctx, cancel := context.WithTimeout(outgoingContext(context.Background(), r), 10*time.Second)
 		defer cancel()
 
-		resp, err := clients.taskClient.ListTasks(ctx, &req)
+		resp, err := clients.taskClient.UpdateTask(ctx, &req)
 		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, err.Error())
+			respondWithGRPCError(w, err)
 			return
 		}
 
@@ -256,47 +699,77 @@ func listTasksHandler(clients *ServiceClients) http.HandlerFunc {
 	}
 }
 
-// User handlers
-func createUserHandler(clients *ServiceClients) http.HandlerFunc {
+// bulkUpdateTasksHandler applies the updates named in fields to every task in
+// ids. The task service only changes the authenticated user's tasks.
+func bulkUpdateTasksHandler(clients *ServiceClients) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if clients == nil || clients.userClient == nil {
-			respondWithError(w, http.StatusServiceUnavailable, "user service unavailable")
+		if clients == nil || clients.taskClient == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "task service unavailable")
 			return
 		}
-		var req pb.CreateUserRequest
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		var body struct {
+			IDs     []string      `json:"ids"`
+			Updates pb.TaskUpdate `json:"updates"`
+			Fields  []string      `json:"fields"`
+		}
+		if !decodeValidated(w, r, bulkUpdateTasksSchema, &body) {
 			return
 		}
 
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		ctx, cancel := context.WithTimeout(outgoingContext(context.Background(), r), 10*time.Second)
 		defer cancel()
 
-		resp, err := clients.userClient.CreateUser(ctx, &req)
+		resp, err := clients.taskClient.BulkUpdateTasks(ctx, &pb.BulkUpdateTasksRequest{
+			Ids:        body.IDs,
+			Updates:    &body.Updates,
+			UpdateMask: &fieldmaskpb.FieldMask{Paths: body.Fields},
+		})
 		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, err.Error())
+			respondWithGRPCError(w, err)
 			return
 		}
 
-		respondWithJSON(w, http.StatusCreated, resp)
+		respondWithJSON(w, http.StatusOK, resp)
 	}
 }
 
-func getUserHandler(clients *ServiceClients) http.HandlerFunc {
+// updateTaskStatusHandler accepts a status name such as "IN_PROGRESS" and
+// moves the task there if the transition is allowed.
+func updateTaskStatusHandler(clients *ServiceClients) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if clients == nil || clients.userClient == nil {
-			respondWithError(w, http.StatusServiceUnavailable, "user service unavailable")
+		if clients == nil || clients.taskClient == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "task service unavailable")
 			return
 		}
 		vars := mux.Vars(r)
 		id := vars["id"]
 
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		var body struct {
+			Status string `json:"status"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+			return
+		}
+		name := strings.ToUpper(body.Status)
+		if !strings.HasPrefix(name, "TASK_STATUS_") {
+			name = "TASK_STATUS_" + name
+		}
+		taskStatus, ok := pb.TaskStatus_value[name]
+		if !ok || taskStatus == 0 {
+			respondWithError(w, http.StatusBadRequest, "Unknown task status "+body.Status)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(outgoingContext(context.Background(), r), 10*time.Second)
 		defer cancel()
 
-		resp, err := clients.userClient.GetUser(ctx, &pb.GetUserRequest{Id: id})
+		resp, err := clients.taskClient.UpdateTaskStatus(ctx, &pb.UpdateTaskStatusRequest{
+			Id:     id,
+			Status: pb.TaskStatus(taskStatus),
+		})
 		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, err.Error())
+			respondWithGRPCError(w, err)
 			return
 		}
 
@@ -304,28 +777,53 @@ func getUserHandler(clients *ServiceClients) http.HandlerFunc {
 	}
 }
 
-func updateUserHandler(clients *ServiceClients) http.HandlerFunc {
+func syncTasksHandler(clients *ServiceClients) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if clients == nil || clients.userClient == nil {
-			respondWithError(w, http.StatusServiceUnavailable, "user service unavailable")
+		if clients == nil || clients.taskClient == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "task service unavailable")
 			return
 		}
-		vars := mux.Vars(r)
-		id := vars["id"]
+		var query syncTasksQuery
+		if err := decoder.Decode(&query, r.URL.Query()); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid query parameters")
+			return
+		}
+		req := pb.SyncTasksRequest{UserId: query.UserID, Since: query.Since}
 
-		var req pb.UpdateUserRequest
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		ctx, cancel := context.WithTimeout(outgoingContext(context.Background(), r), 10*time.Second)
+		defer cancel()
+
+		resp, err := clients.taskClient.SyncTasks(ctx, &req)
+		if err != nil {
+			respondWithGRPCError(w, err)
 			return
 		}
-		req.Id = id
 
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		respondWithJSON(w, http.StatusOK, resp)
+	}
+}
+
+// listDeletedTasksHandler lists a user's trash, with how long each task has
+// left before it is removed for good.
+func listDeletedTasksHandler(clients *ServiceClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if clients == nil || clients.taskClient == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "task service unavailable")
+			return
+		}
+		var query userPageQuery
+		if err := decoder.Decode(&query, r.URL.Query()); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid query parameters")
+			return
+		}
+		req := pb.ListDeletedTasksRequest{UserId: query.UserID, Page: query.Page, Limit: query.Limit}
+
+		ctx, cancel := context.WithTimeout(outgoingContext(context.Background(), r), 10*time.Second)
 		defer cancel()
 
-		resp, err := clients.userClient.UpdateUser(ctx, &req)
+		resp, err := clients.taskClient.ListDeletedTasks(ctx, &req)
 		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, err.Error())
+			respondWithGRPCError(w, err)
 			return
 		}
 
@@ -333,21 +831,25 @@ func updateUserHandler(clients *ServiceClients) http.HandlerFunc {
 	}
 }
 
-func deleteUserHandler(clients *ServiceClients) http.HandlerFunc {
+func pendingTaskCountHandler(clients *ServiceClients) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if clients == nil || clients.userClient == nil {
-			respondWithError(w, http.StatusServiceUnavailable, "user service unavailable")
+		if clients == nil || clients.taskClient == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "task service unavailable")
 			return
 		}
-		vars := mux.Vars(r)
-		id := vars["id"]
+		var query userQuery
+		if err := decoder.Decode(&query, r.URL.Query()); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid query parameters")
+			return
+		}
+		req := pb.GetPendingTaskCountRequest{UserId: query.UserID}
 
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		ctx, cancel := context.WithTimeout(outgoingContext(context.Background(), r), 10*time.Second)
 		defer cancel()
 
-		resp, err := clients.userClient.DeleteUser(ctx, &pb.DeleteUserRequest{Id: id})
+		resp, err := clients.taskClient.GetPendingTaskCount(ctx, &req)
 		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, err.Error())
+			respondWithGRPCError(w, err)
 			return
 		}
 
@@ -355,75 +857,76 @@ func deleteUserHandler(clients *ServiceClients) http.HandlerFunc {
 	}
 }
 
-func authHandler(clients *ServiceClients) http.HandlerFunc {
+// User handlers
+func createUserHandler(clients *ServiceClients) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if clients == nil || clients.userClient == nil {
 			respondWithError(w, http.StatusServiceUnavailable, "user service unavailable")
 			return
 		}
-		var req pb.AuthRequest
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		var req pb.CreateUserRequest
+		if !decodeValidated(w, r, createUserSchema, &req) {
 			return
 		}
 
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		ctx, cancel := context.WithTimeout(outgoingContext(context.Background(), r), 10*time.Second)
 		defer cancel()
 
-		resp, err := clients.userClient.AuthenticateUser(ctx, &req)
+		resp, err := clients.userClient.CreateUser(ctx, &req)
 		if err != nil {
-			respondWithError(w, http.StatusUnauthorized, "Authentication failed")
+			respondWithGRPCError(w, err)
 			return
 		}
 
-		respondWithJSON(w, http.StatusOK, resp)
+		respondWithJSON(w, http.StatusCreated, resp)
 	}
 }
 
-// Notification handlers
-func sendNotificationHandler(clients *ServiceClients) http.HandlerFunc {
+func setDigestScheduleHandler(clients *ServiceClients) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if clients == nil || clients.notificationClient == nil {
 			respondWithError(w, http.StatusServiceUnavailable, "notification service unavailable")
 			return
 		}
-		var req pb.NotificationRequest
+		vars := mux.Vars(r)
+		userId := vars["id"]
+
+		// A schedule is enabled unless the body says otherwise
+		req := pb.SetDigestScheduleRequest{Enabled: true}
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			respondWithError(w, http.StatusBadRequest, "Invalid request payload")
 			return
 		}
+		req.UserId = userId
 
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		ctx, cancel := context.WithTimeout(outgoingContext(context.Background(), r), 10*time.Second)
 		defer cancel()
 
-		resp, err := clients.notificationClient.SendNotification(ctx, &req)
+		resp, err := clients.notificationClient.SetDigestSchedule(ctx, &req)
 		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, err.Error())
+			respondWithGRPCError(w, err)
 			return
 		}
 
-		respondWithJSON(w, http.StatusCreated, resp)
+		respondWithJSON(w, http.StatusOK, resp)
 	}
 }
 
-func getNotificationsHandler(clients *ServiceClients) http.HandlerFunc {
+func deleteDigestScheduleHandler(clients *ServiceClients) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if clients == nil || clients.notificationClient == nil {
 			respondWithError(w, http.StatusServiceUnavailable, "notification service unavailable")
 			return
 		}
-		var req pb.GetNotificationsRequest
-		if err := decoder.Decode(&req, r.URL.Query()); err != nil {
-			respondWithError(w, http.StatusBadRequest, "Invalid query parameters")
-			return
-		}
+		vars := mux.Vars(r)
+		userId := vars["id"]
 
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		ctx, cancel := context.WithTimeout(outgoingContext(context.Background(), r), 10*time.Second)
 		defer cancel()
 
-		resp, err := clients.notificationClient.GetNotifications(ctx, &req)
+		resp, err := clients.notificationClient.DeleteDigestSchedule(ctx, &pb.DeleteDigestScheduleRequest{UserId: userId})
 		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, err.Error())
+			respondWithGRPCError(w, err)
 			return
 		}
 
@@ -431,54 +934,69 @@ func getNotificationsHandler(clients *ServiceClients) http.HandlerFunc {
 	}
 }
 
-// Analytics handlers
-func trackEventHandler(clients *ServiceClients) http.HandlerFunc {
+func listSessionsHandler(clients *ServiceClients) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if clients == nil || clients.analyticsClient == nil {
-			respondWithError(w, http.StatusServiceUnavailable, "analytics service unavailable")
+		if clients == nil || clients.userClient == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "user service unavailable")
 			return
 		}
-		var req pb.TrackEventRequest
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		var query userPageQuery
+		if err := decoder.Decode(&query, r.URL.Query()); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid query parameters")
 			return
 		}
+		req := pb.ListSessionsRequest{UserId: query.UserID, Page: query.Page, Limit: query.Limit}
 
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		ctx, cancel := context.WithTimeout(outgoingContext(context.Background(), r), 10*time.Second)
 		defer cancel()
 
-		resp, err := clients.analyticsClient.TrackEvent(ctx, &req)
+		resp, err := clients.userClient.ListSessions(ctx, &req)
 		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, err.Error())
+			respondWithGRPCError(w, err)
 			return
 		}
 
-		respondWithJSON(w, http.StatusCreated, resp)
+		respondWithJSON(w, http.StatusOK, resp)
 	}
 }
 
-func getUserStatsHandler(clients *ServiceClients) http.HandlerFunc {
+func freezeUserHandler(clients *ServiceClients) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if clients == nil || clients.analyticsClient == nil {
-			respondWithError(w, http.StatusServiceUnavailable, "analytics service unavailable")
+		if clients == nil || clients.userClient == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "user service unavailable")
 			return
 		}
 		vars := mux.Vars(r)
-		userId := vars["id"]
+		id := vars["id"]
 
-		var req pb.GetUserStatsRequest
-		if err := decoder.Decode(&req, r.URL.Query()); err != nil {
-			respondWithError(w, http.StatusBadRequest, "Invalid query parameters")
+		ctx, cancel := context.WithTimeout(outgoingContext(context.Background(), r), 10*time.Second)
+		defer cancel()
+
+		resp, err := clients.userClient.FreezeUser(ctx, &pb.FreezeUserRequest{Id: id})
+		if err != nil {
+			respondWithGRPCError(w, err)
 			return
 		}
-		req.UserId = userId
 
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		respondWithJSON(w, http.StatusOK, resp)
+	}
+}
+
+func unfreezeUserHandler(clients *ServiceClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if clients == nil || clients.userClient == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "user service unavailable")
+			return
+		}
+		vars := mux.Vars(r)
+		id := vars["id"]
+
+		ctx, cancel := context.WithTimeout(outgoingContext(context.Background(), r), 10*time.Second)
 		defer cancel()
 
-		resp, err := clients.analyticsClient.GetUserStats(ctx, &req)
+		resp, err := clients.userClient.UnfreezeUser(ctx, &pb.UnfreezeUserRequest{Id: id})
 		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, err.Error())
+			respondWithGRPCError(w, err)
 			return
 		}
 
@@ -486,24 +1004,1297 @@ func getUserStatsHandler(clients *ServiceClients) http.HandlerFunc {
 	}
 }
 
-func getTaskStatsHandler(clients *ServiceClients) http.HandlerFunc {
+// purgeUserDataHandler erases a user everywhere. The body must repeat the
+// user id as confirm_user_id; the report lists which steps completed.
+func purgeUserDataHandler(clients *ServiceClients) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if clients == nil || clients.analyticsClient == nil {
-			respondWithError(w, http.StatusServiceUnavailable, "analytics service unavailable")
+		if clients == nil || clients.userClient == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "user service unavailable")
 			return
 		}
-		var req pb.GetTaskStatsRequest
-		if err := decoder.Decode(&req, r.URL.Query()); err != nil {
-			respondWithError(w, http.StatusBadRequest, "Invalid query parameters")
+		vars := mux.Vars(r)
+		var req pb.PurgeUserDataRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request payload")
 			return
 		}
+		req.UserId = vars["id"]
 
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		// Each step has its own timeout in the user service
+		ctx, cancel := context.WithTimeout(outgoingContext(context.Background(), r), 3*time.Minute)
 		defer cancel()
 
-		resp, err := clients.analyticsClient.GetTaskStats(ctx, &req)
+		resp, err := clients.userClient.PurgeUserData(ctx, &req)
+		if err != nil {
+			respondWithGRPCError(w, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, resp)
+	}
+}
+
+// getDeletionStatusHandler shows how far erasing a deleted user's data in
+// the other services has got.
+func getDeletionStatusHandler(clients *ServiceClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if clients == nil || clients.userClient == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "user service unavailable")
+			return
+		}
+		vars := mux.Vars(r)
+
+		ctx, cancel := context.WithTimeout(outgoingContext(context.Background(), r), 10*time.Second)
+		defer cancel()
+
+		resp, err := clients.userClient.GetDeletionStatus(ctx, &pb.GetDeletionStatusRequest{UserId: vars["id"]})
+		if err != nil {
+			respondWithGRPCError(w, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, resp)
+	}
+}
+
+// Notification handlers
+func listFailedDeliveriesHandler(clients *ServiceClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if clients == nil || clients.notificationClient == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "notification service unavailable")
+			return
+		}
+		var query failedDeliveriesQuery
+		if err := decoder.Decode(&query, r.URL.Query()); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid query parameters")
+			return
+		}
+		req := pb.ListFailedDeliveriesRequest{Channel: query.Channel, StartDate: query.StartDate, EndDate: query.EndDate, Page: query.Page, Limit: query.Limit}
+
+		ctx, cancel := context.WithTimeout(outgoingContext(context.Background(), r), 10*time.Second)
+		defer cancel()
+
+		resp, err := clients.notificationClient.ListFailedDeliveries(ctx, &req)
+		if err != nil {
+			respondWithGRPCError(w, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, resp)
+	}
+}
+
+// adminDeliveryReportHandler reports on every user's notifications, or on
+// one user's when user_id is given.
+func adminDeliveryReportHandler(clients *ServiceClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		deliveryReport(clients, w, r, query.Get("user_id"), query.Get("start"), query.Get("end"))
+	}
+}
+
+// testNotificationHandler sends the notification in the body as a dry run:
+// it is rendered and its deliveries prepared, but nothing is stored or sent.
+// The response carries a delivery_preview of what would go out.
+func testNotificationHandler(clients *ServiceClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if clients == nil || clients.notificationClient == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "notification service unavailable")
+			return
+		}
+		var req pb.NotificationRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+			return
+		}
+		req.DryRun = true
+
+		ctx, cancel := context.WithTimeout(outgoingContext(context.Background(), r), 10*time.Second)
+		defer cancel()
+
+		resp, err := clients.notificationClient.SendNotification(ctx, &req)
+		if err != nil {
+			respondWithGRPCError(w, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, resp)
+	}
+}
+
+// userDeliveryReportHandler reports on the notifications of the user_id
+// given, which is required.
+func userDeliveryReportHandler(clients *ServiceClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		userId := query.Get("user_id")
+		if userId == "" {
+			respondWithError(w, http.StatusBadRequest, "user_id is required")
+			return
+		}
+		deliveryReport(clients, w, r, userId, query.Get("start"), query.Get("end"))
+	}
+}
+
+func deliveryReport(clients *ServiceClients, w http.ResponseWriter, r *http.Request, userId, start, end string) {
+	if clients == nil || clients.notificationClient == nil {
+		respondWithError(w, http.StatusServiceUnavailable, "notification service unavailable")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(outgoingContext(context.Background(), r), 10*time.Second)
+	defer cancel()
+
+	resp, err := clients.notificationClient.GetDeliveryReport(ctx, &pb.GetDeliveryReportRequest{
+		UserId:    userId,
+		StartDate: start,
+		EndDate:   end,
+	})
+	if err != nil {
+		respondWithGRPCError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+func redeliverNotificationHandler(clients *ServiceClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if clients == nil || clients.notificationClient == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "notification service unavailable")
+			return
+		}
+		vars := mux.Vars(r)
+		var req pb.RedeliverNotificationRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+			return
+		}
+		req.Id = vars["id"]
+
+		ctx, cancel := context.WithTimeout(outgoingContext(context.Background(), r), 10*time.Second)
+		defer cancel()
+
+		resp, err := clients.notificationClient.RedeliverNotification(ctx, &req)
+		if err != nil {
+			respondWithGRPCError(w, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, resp)
+	}
+}
+
+// Analytics handlers
+func trackEventHandler(clients *ServiceClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if clients == nil || clients.analyticsClient == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "analytics service unavailable")
+			return
+		}
+		var req pb.TrackEventRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(outgoingContext(context.Background(), r), 10*time.Second)
+		defer cancel()
+
+		resp, err := clients.analyticsClient.TrackEvent(ctx, &req)
+		if err != nil {
+			respondWithGRPCError(w, err)
+			return
+		}
+
+		// A retried event was already created by the first attempt
+		if resp.Duplicate {
+			respondWithJSON(w, http.StatusOK, resp)
+			return
+		}
+		respondWithJSON(w, http.StatusCreated, resp)
+	}
+}
+
+// trackEventsHandler flushes a client's queued events. The response holds a
+// result per event, so partial success is still a 200.
+func trackEventsHandler(clients *ServiceClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if clients == nil || clients.analyticsClient == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "analytics service unavailable")
+			return
+		}
+		var req pb.TrackEventsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(outgoingContext(context.Background(), r), 30*time.Second)
+		defer cancel()
+
+		resp, err := clients.analyticsClient.TrackEvents(ctx, &req)
+		if err != nil {
+			respondWithGRPCError(w, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, resp)
+	}
+}
+
+func getUserStreakHandler(clients *ServiceClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if clients == nil || clients.analyticsClient == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "analytics service unavailable")
+			return
+		}
+		vars := mux.Vars(r)
+		userId := vars["id"]
+
+		var query timezoneQuery
+		if err := decoder.Decode(&query, r.URL.Query()); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid query parameters")
+			return
+		}
+		req := pb.GetUserStreakRequest{Timezone: query.Timezone}
+		req.UserId = userId
+
+		ctx, cancel := context.WithTimeout(outgoingContext(context.Background(), r), 10*time.Second)
+		defer cancel()
+
+		resp, err := clients.analyticsClient.GetUserStreak(ctx, &req)
+		if err != nil {
+			respondWithGRPCError(w, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, resp)
+	}
+}
+
+// getTimeReportHandler compares the user's estimated and spent time per label.
+func getTimeReportHandler(clients *ServiceClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if clients == nil || clients.analyticsClient == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "analytics service unavailable")
+			return
+		}
+		vars := mux.Vars(r)
+
+		ctx, cancel := context.WithTimeout(outgoingContext(context.Background(), r), 10*time.Second)
+		defer cancel()
+
+		resp, err := clients.analyticsClient.GetTimeReport(ctx, &pb.GetTimeReportRequest{UserId: vars["id"]})
+		if err != nil {
+			respondWithGRPCError(w, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, resp)
+	}
+}
+
+func getPriorityBreakdownHandler(clients *ServiceClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if clients == nil || clients.analyticsClient == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "analytics service unavailable")
+			return
+		}
+		vars := mux.Vars(r)
+
+		ctx, cancel := context.WithTimeout(outgoingContext(context.Background(), r), 10*time.Second)
+		defer cancel()
+
+		resp, err := clients.analyticsClient.GetPriorityBreakdown(ctx, &pb.GetPriorityBreakdownRequest{UserId: vars["id"]})
+		if err != nil {
+			respondWithGRPCError(w, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, resp)
+	}
+}
+
+func getBurndownHandler(clients *ServiceClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if clients == nil || clients.analyticsClient == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "analytics service unavailable")
+			return
+		}
+		vars := mux.Vars(r)
+
+		var query burndownQuery
+		if err := decoder.Decode(&query, r.URL.Query()); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid query parameters")
+			return
+		}
+		req := pb.GetBurndownRequest{UserId: vars["id"], SprintStart: query.Start, SprintEnd: query.End, TotalTasks: query.Total}
+
+		ctx, cancel := context.WithTimeout(outgoingContext(context.Background(), r), 10*time.Second)
+		defer cancel()
+
+		resp, err := clients.analyticsClient.GetBurndown(ctx, &req)
+		if err != nil {
+			respondWithGRPCError(w, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, resp)
+	}
+}
+
+func getWeeklySummaryHandler(clients *ServiceClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if clients == nil || clients.analyticsClient == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "analytics service unavailable")
+			return
+		}
+		vars := mux.Vars(r)
+		userId := vars["id"]
+
+		var query weeklySummaryQuery
+		if err := decoder.Decode(&query, r.URL.Query()); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid query parameters")
+			return
+		}
+		req := pb.GenerateWeeklySummaryRequest{Week: query.Week, Timezone: query.Timezone}
+		req.UserId = userId
+
+		ctx, cancel := context.WithTimeout(outgoingContext(context.Background(), r), 10*time.Second)
+		defer cancel()
+
+		resp, err := clients.analyticsClient.GenerateWeeklySummary(ctx, &req)
+		if err != nil {
+			respondWithGRPCError(w, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, resp)
+	}
+}
+
+func getTaskBreakdownHandler(clients *ServiceClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if clients == nil || clients.analyticsClient == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "analytics service unavailable")
+			return
+		}
+		vars := mux.Vars(r)
+		userId := vars["id"]
+
+		var query taskBreakdownQuery
+		if err := decoder.Decode(&query, r.URL.Query()); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid query parameters")
+			return
+		}
+		req := pb.GetTaskBreakdownRequest{StartDate: query.StartDate, EndDate: query.EndDate, TopLabels: query.TopLabels}
+		req.UserId = userId
+
+		ctx, cancel := context.WithTimeout(outgoingContext(context.Background(), r), 10*time.Second)
+		defer cancel()
+
+		resp, err := clients.analyticsClient.GetTaskBreakdown(ctx, &req)
+		if err != nil {
+			respondWithGRPCError(w, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, resp)
+	}
+}
+
+func getActivityHeatmapHandler(clients *ServiceClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if clients == nil || clients.analyticsClient == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "analytics service unavailable")
+			return
+		}
+		vars := mux.Vars(r)
+		userId := vars["id"]
+
+		var query zonedRangeQuery
+		if err := decoder.Decode(&query, r.URL.Query()); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid query parameters")
+			return
+		}
+		req := pb.GetActivityHeatmapRequest{StartDate: query.StartDate, EndDate: query.EndDate, Timezone: query.Timezone}
+		req.UserId = userId
+
+		ctx, cancel := context.WithTimeout(outgoingContext(context.Background(), r), 10*time.Second)
+		defer cancel()
+
+		resp, err := clients.analyticsClient.GetActivityHeatmap(ctx, &req)
+		if err != nil {
+			respondWithGRPCError(w, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, resp)
+	}
+}
+
+func getTaskStatsHandler(clients *ServiceClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if clients == nil || clients.analyticsClient == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "analytics service unavailable")
+			return
+		}
+		var req pb.GetTaskStatsRequest
+		if err := decoder.Decode(&req, r.URL.Query()); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid query parameters")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(outgoingContext(context.Background(), r), 10*time.Second)
+		defer cancel()
+
+		resp, err := clients.analyticsClient.GetTaskStats(ctx, &req)
+		if err != nil {
+			respondWithGRPCError(w, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, resp)
+	}
+}
+
+func getPeakHoursHandler(clients *ServiceClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if clients == nil || clients.analyticsClient == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "analytics service unavailable")
+			return
+		}
+		vars := mux.Vars(r)
+		userId := vars["id"]
+
+		var query zonedRangeQuery
+		if err := decoder.Decode(&query, r.URL.Query()); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid query parameters")
+			return
+		}
+		req := pb.GetPeakHoursRequest{StartDate: query.StartDate, EndDate: query.EndDate, Timezone: query.Timezone}
+		req.UserId = userId
+
+		ctx, cancel := context.WithTimeout(outgoingContext(context.Background(), r), 10*time.Second)
+		defer cancel()
+
+		resp, err := clients.analyticsClient.GetPeakHours(ctx, &req)
+		if err != nil {
+			respondWithGRPCError(w, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, resp)
+	}
+}
+
+func getEngagementScoreHandler(clients *ServiceClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if clients == nil || clients.analyticsClient == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "analytics service unavailable")
+			return
+		}
+		vars := mux.Vars(r)
+		userId := vars["id"]
+
+		ctx, cancel := context.WithTimeout(outgoingContext(context.Background(), r), 10*time.Second)
+		defer cancel()
+
+		resp, err := clients.analyticsClient.GetEngagementScore(ctx, &pb.GetEngagementScoreRequest{UserId: userId})
+		if err != nil {
+			respondWithGRPCError(w, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, resp)
+	}
+}
+
+func getActiveUsersHandler(clients *ServiceClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if clients == nil || clients.analyticsClient == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "analytics service unavailable")
+			return
+		}
+		var query activeUsersQuery
+		if err := decoder.Decode(&query, r.URL.Query()); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid query parameters")
+			return
+		}
+		req := pb.GetActiveUsersRequest{StartDate: query.StartDate, EndDate: query.EndDate, Granularity: query.Granularity, Timezone: query.Timezone}
+
+		ctx, cancel := context.WithTimeout(outgoingContext(context.Background(), r), 30*time.Second)
+		defer cancel()
+
+		resp, err := clients.analyticsClient.GetActiveUsers(ctx, &req)
+		if err != nil {
+			respondWithGRPCError(w, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, resp)
+	}
+}
+
+func compareUsersHandler(clients *ServiceClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if clients == nil || clients.analyticsClient == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "analytics service unavailable")
+			return
+		}
+		var query compareUsersQuery
+		if err := decoder.Decode(&query, r.URL.Query()); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid query parameters")
+			return
+		}
+		req := pb.CompareUsersRequest{StartDate: query.StartDate, EndDate: query.EndDate}
+		for _, id := range strings.Split(query.UserIDs, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				req.UserIds = append(req.UserIds, id)
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(outgoingContext(context.Background(), r), 10*time.Second)
+		defer cancel()
+
+		resp, err := clients.analyticsClient.CompareUsersProductivity(ctx, &req)
+		if err != nil {
+			respondWithGRPCError(w, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, resp)
+	}
+}
+
+func getCompletionLatencyHandler(clients *ServiceClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if clients == nil || clients.analyticsClient == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "analytics service unavailable")
+			return
+		}
+		var query completionLatencyQuery
+		if err := decoder.Decode(&query, r.URL.Query()); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid query parameters")
+			return
+		}
+		req := pb.GetCompletionLatencyRequest{UserId: query.UserID, StartDate: query.StartDate, EndDate: query.EndDate}
+
+		ctx, cancel := context.WithTimeout(outgoingContext(context.Background(), r), 10*time.Second)
+		defer cancel()
+
+		resp, err := clients.analyticsClient.GetCompletionLatency(ctx, &req)
+		if err != nil {
+			respondWithGRPCError(w, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, resp)
+	}
+}
+
+func getCompletionTrendHandler(clients *ServiceClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if clients == nil || clients.analyticsClient == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "analytics service unavailable")
+			return
+		}
+		var query completionTrendQuery
+		if err := decoder.Decode(&query, r.URL.Query()); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid query parameters")
+			return
+		}
+		req := pb.GetCompletionTrendRequest{UserId: query.UserID, StartDate: query.StartDate, EndDate: query.EndDate, Granularity: query.Granularity, Timezone: query.Timezone}
+
+		ctx, cancel := context.WithTimeout(outgoingContext(context.Background(), r), 10*time.Second)
+		defer cancel()
+
+		resp, err := clients.analyticsClient.GetCompletionTrend(ctx, &req)
+		if err != nil {
+			respondWithGRPCError(w, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, resp)
+	}
+}
+
+// getOverdueAgingHandler reports how long overdue tasks are, for one user
+// when user_id is given and across all users otherwise.
+func getOverdueAgingHandler(clients *ServiceClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if clients == nil || clients.analyticsClient == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "analytics service unavailable")
+			return
+		}
+		var query overdueAgingQuery
+		if err := decoder.Decode(&query, r.URL.Query()); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid query parameters")
+			return
+		}
+		req := pb.GetOverdueAgingRequest{UserId: query.UserID, AsOf: query.AsOf, Weeks: query.Weeks}
+
+		ctx, cancel := context.WithTimeout(outgoingContext(context.Background(), r), 10*time.Second)
+		defer cancel()
+
+		resp, err := clients.analyticsClient.GetOverdueAging(ctx, &req)
+		if err != nil {
+			respondWithGRPCError(w, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, resp)
+	}
+}
+
+// backfillAnalyticsHandler replays existing tasks into analytics events. It
+// scans every task, so it gets a longer deadline than regular requests.
+func backfillAnalyticsHandler(clients *ServiceClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if clients == nil || clients.analyticsClient == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "analytics service unavailable")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(outgoingContext(context.Background(), r), 5*time.Minute)
+		defer cancel()
+
+		resp, err := clients.analyticsClient.BackfillAnalytics(ctx, &pb.BackfillRequest{})
+		if err != nil {
+			respondWithGRPCError(w, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, resp)
+	}
+}
+
+// replayTaskEventsHandler has the task service publish the task events still
+// in its outbox again, for one task when task_id is given.
+func replayTaskEventsHandler(clients *ServiceClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if clients == nil || clients.taskClient == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "task service unavailable")
+			return
+		}
+		var query replayTaskEventsQuery
+		if err := decoder.Decode(&query, r.URL.Query()); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid query parameters")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(outgoingContext(context.Background(), r), 30*time.Second)
+		defer cancel()
+
+		resp, err := clients.taskClient.ReplayTaskEvents(ctx, &pb.ReplayTaskEventsRequest{TaskId: query.TaskID, Since: query.Since})
+		if err != nil {
+			respondWithGRPCError(w, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, resp)
+	}
+}
+
+// migrateTaskOwnershipHandler moves every task of from_user_id to
+// to_user_id, when merging two accounts.
+func migrateTaskOwnershipHandler(clients *ServiceClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if clients == nil || clients.taskClient == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "task service unavailable")
+			return
+		}
+		var req pb.MigrateOwnershipRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(outgoingContext(context.Background(), r), time.Minute)
+		defer cancel()
+
+		resp, err := clients.taskClient.MigrateTaskOwnership(ctx, &req)
+		if err != nil {
+			respondWithGRPCError(w, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, resp)
+	}
+}
+
+func retentionStatusHandler(clients *ServiceClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if clients == nil || clients.analyticsClient == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "analytics service unavailable")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(outgoingContext(context.Background(), r), 10*time.Second)
+		defer cancel()
+
+		resp, err := clients.analyticsClient.GetRetentionStatus(ctx, &pb.GetRetentionStatusRequest{})
+		if err != nil {
+			respondWithGRPCError(w, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, resp)
+	}
+}
+
+// featureFlagsHandler reports the feature flags of FEATURE_FLAGS, with the
+// flags it leaves out that are on by default. They are read at startup, so
+// the report is read-only.
+func featureFlagsHandler(clients *ServiceClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		respondWithJSON(w, http.StatusOK, map[string]interface{}{"flags": clients.featureFlags.All()})
+	}
+}
+
+// backupHandler has a service write one of its collections to a bucket,
+// such as {"service": "task-service", "collection_name": "tasks", "bucket":
+// "s3://todo-backups/nightly"}. Services without BACKUP_PROVIDER answer 501.
+func backupHandler(clients *ServiceClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if clients == nil || clients.backups == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "backup service unavailable")
+			return
+		}
+		var body struct {
+			Service        string `json:"service"`
+			CollectionName string `json:"collection_name"`
+			Bucket         string `json:"bucket"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+			return
+		}
+		client, ok := clients.backups[body.Service]
+		if !ok {
+			respondWithError(w, http.StatusBadRequest, fmt.Sprintf("unknown service %q", body.Service))
+			return
+		}
+
+		// Large collections take a while to write and upload
+		ctx, cancel := context.WithTimeout(outgoingContext(context.Background(), r), 10*time.Minute)
+		defer cancel()
+
+		resp, err := client.BackupCollection(ctx, &pb.BackupRequest{CollectionName: body.CollectionName, Bucket: body.Bucket})
+		if err != nil {
+			respondWithGRPCError(w, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, resp)
+	}
+}
+
+func cacheStatsHandler(clients *ServiceClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if clients == nil || clients.analyticsClient == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "analytics service unavailable")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(outgoingContext(context.Background(), r), 10*time.Second)
+		defer cancel()
+
+		resp, err := clients.analyticsClient.GetCacheStats(ctx, &pb.GetCacheStatsRequest{})
+		if err != nil {
+			respondWithGRPCError(w, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, resp)
+	}
+}
+
+// systemOverviewHandler reports users, today's tasks and today's
+// notifications; sections the analytics service could not fill are marked
+// unavailable rather than failing the request.
+func systemOverviewHandler(clients *ServiceClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if clients == nil || clients.analyticsClient == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "analytics service unavailable")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(outgoingContext(context.Background(), r), 10*time.Second)
+		defer cancel()
+
+		resp, err := clients.analyticsClient.GetSystemOverview(ctx, &pb.GetSystemOverviewRequest{})
+		if err != nil {
+			respondWithGRPCError(w, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, resp)
+	}
+}
+
+// exportStatsHandler streams task stats as a CSV download. csv is the only
+// format, and the default.
+func exportStatsHandler(clients *ServiceClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if clients == nil || clients.analyticsClient == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "analytics service unavailable")
+			return
+		}
+		query := r.URL.Query()
+		if format := query.Get("format"); format != "" && format != "csv" {
+			respondWithError(w, http.StatusBadRequest, "format must be csv")
+			return
+		}
+		req := &pb.ExportStatsRequest{
+			StartDate:   query.Get("start_date"),
+			EndDate:     query.Get("end_date"),
+			Granularity: query.Get("granularity"),
+			Scope:       query.Get("scope"),
+			UserId:      query.Get("user_id"),
+		}
+
+		// The export runs until every row is sent or the client disconnects
+		stream, err := clients.analyticsClient.ExportStats(outgoingContext(r.Context(), r), req)
+		if err != nil {
+			respondWithGRPCError(w, err)
+			return
+		}
+
+		// Wait for the first chunk so request errors still get a proper status
+		chunk, err := stream.Recv()
+		if err != nil {
+			respondWithGRPCError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="task-stats.csv"`)
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+
+		flusher, _ := w.(http.Flusher)
+		for err == nil {
+			if _, err = w.Write(chunk.Data); err != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+			chunk, err = stream.Recv()
+		}
+		if err != io.EOF {
+			// The status is already sent; a truncated body is all that is left
+			slog.Error("Stats export failed after it started", "error", err)
+		}
+	}
+}
+
+// replayEventsHandler streams historical events as server-sent events, one
+// "event" message per analytics event with its id as the SSE id, followed by
+// a "done" message. Errors after the stream started arrive as an "error"
+// message, and the last SSE id can be passed back as from to resume.
+func replayEventsHandler(clients *ServiceClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if clients == nil || clients.analyticsClient == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "analytics service unavailable")
+			return
+		}
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			respondWithError(w, http.StatusInternalServerError, "streaming unsupported")
+			return
+		}
+
+		query := r.URL.Query()
+		req := &pb.ReplayEventsRequest{
+			UserId:      query.Get("user_id"),
+			FromEventId: query.Get("from"),
+		}
+		for _, types := range query["event_types"] {
+			for _, t := range strings.Split(types, ",") {
+				if t = strings.TrimSpace(t); t != "" {
+					req.EventTypes = append(req.EventTypes, t)
+				}
+			}
+		}
+
+		// The replay runs until every event is sent or the client disconnects
+		stream, err := clients.analyticsClient.ReplayEvents(outgoingContext(r.Context(), r), req)
+		if err != nil {
+			respondWithGRPCError(w, err)
+			return
+		}
+
+		// Wait for the first event so request errors still get a proper status
+		event, err := stream.Recv()
+		if err != nil && err != io.EOF {
+			respondWithGRPCError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+
+		for err == nil {
+			data, _ := json.Marshal(event)
+			fmt.Fprintf(w, "id: %s\nevent: event\ndata: %s\n\n", event.Id, data)
+			flusher.Flush()
+			event, err = stream.Recv()
+		}
+
+		if err != io.EOF {
+			data, _ := json.Marshal(map[string]string{"error": status.Convert(err).Message()})
+			fmt.Fprintf(w, "event: error\ndata: %s\n\n", data)
+		} else {
+			fmt.Fprint(w, "event: done\ndata: {}\n\n")
+		}
+		flusher.Flush()
+	}
+}
+
+// streamEventsHandler streams live events as server-sent events, one "event"
+// message per analytics event with its resume token as the SSE id. When the
+// client falls behind, a "dropped" message says how many events it missed.
+// Reconnecting with Last-Event-ID, or resume_token, picks up after that event.
+// The analytics service only streams with ANALYTICS_CHANGE_STREAMS=true and
+// MongoDB running as a replica set; otherwise this fails with 412.
+func streamEventsHandler(clients *ServiceClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if clients == nil || clients.analyticsClient == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "analytics service unavailable")
+			return
+		}
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			respondWithError(w, http.StatusInternalServerError, "streaming unsupported")
+			return
+		}
+
+		query := r.URL.Query()
+		req := &pb.StreamEventsRequest{
+			UserId:      query.Get("user_id"),
+			ResumeToken: r.Header.Get("Last-Event-ID"),
+		}
+		if token := query.Get("resume_token"); token != "" {
+			req.ResumeToken = token
+		}
+		for _, types := range query["event_types"] {
+			for _, t := range strings.Split(types, ",") {
+				if t = strings.TrimSpace(t); t != "" {
+					req.EventTypes = append(req.EventTypes, t)
+				}
+			}
+		}
+
+		// The stream runs until the client disconnects
+		stream, err := clients.analyticsClient.StreamEvents(outgoingContext(r.Context(), r), req)
+		if err != nil {
+			respondWithGRPCError(w, err)
+			return
+		}
+
+		// Headers arrive once the stream is open; without them the request
+		// was rejected
+		if md, err := stream.Header(); err != nil || md == nil {
+			if _, err = stream.Recv(); err == nil || err == io.EOF {
+				err = status.Error(codes.Internal, "event stream ended unexpectedly")
+			}
+			respondWithGRPCError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		for {
+			msg, err := stream.Recv()
+			if err != nil {
+				if err != io.EOF && r.Context().Err() == nil {
+					data, _ := json.Marshal(map[string]string{"error": status.Convert(err).Message()})
+					fmt.Fprintf(w, "event: error\ndata: %s\n\n", data)
+					flusher.Flush()
+				}
+				return
+			}
+
+			if msg.Dropped > 0 {
+				fmt.Fprintf(w, "event: dropped\ndata: {\"dropped\":%d}\n\n", msg.Dropped)
+			} else {
+				data, _ := json.Marshal(msg.Event)
+				fmt.Fprintf(w, "id: %s\nevent: event\ndata: %s\n\n", msg.ResumeToken, data)
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// subscribeEventsHandler streams a user's events as server-sent events as
+// they are tracked, one "event" message per analytics event with its id as
+// the SSE id. Only events tracked by the analytics instance the stream is
+// connected to arrive; it needs no replica set, unlike streamEventsHandler.
+func subscribeEventsHandler(clients *ServiceClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if clients == nil || clients.analyticsClient == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "analytics service unavailable")
+			return
+		}
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			respondWithError(w, http.StatusInternalServerError, "streaming unsupported")
+			return
+		}
+
+		query := r.URL.Query()
+		req := &pb.SubscribeRequest{UserId: query.Get("user_id")}
+		for _, types := range query["types"] {
+			for _, t := range strings.Split(types, ",") {
+				if t = strings.TrimSpace(t); t != "" {
+					req.EventTypes = append(req.EventTypes, t)
+				}
+			}
+		}
+
+		// The subscription runs until the client disconnects
+		stream, err := clients.analyticsClient.SubscribeToEvents(outgoingContext(r.Context(), r), req)
+		if err != nil {
+			respondWithGRPCError(w, err)
+			return
+		}
+
+		// Headers arrive once the subscription is in place; without them the
+		// request was rejected
+		if md, err := stream.Header(); err != nil || md == nil {
+			if _, err = stream.Recv(); err == nil || err == io.EOF {
+				err = status.Error(codes.Internal, "event subscription ended unexpectedly")
+			}
+			respondWithGRPCError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		for {
+			event, err := stream.Recv()
+			if err != nil {
+				if err != io.EOF && r.Context().Err() == nil {
+					data, _ := json.Marshal(map[string]string{"error": status.Convert(err).Message()})
+					fmt.Fprintf(w, "event: error\ndata: %s\n\n", data)
+					flusher.Flush()
+				}
+				return
+			}
+
+			data, _ := json.Marshal(event)
+			fmt.Fprintf(w, "id: %s\nevent: event\ndata: %s\n\n", event.Id, data)
+			flusher.Flush()
+		}
+	}
+}
+
+// Notification template handlers
+func createTemplateHandler(clients *ServiceClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if clients == nil || clients.notificationClient == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "notification service unavailable")
+			return
+		}
+		var req pb.CreateTemplateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(outgoingContext(context.Background(), r), 10*time.Second)
+		defer cancel()
+
+		resp, err := clients.notificationClient.CreateTemplate(ctx, &req)
+		if err != nil {
+			respondWithGRPCError(w, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusCreated, resp)
+	}
+}
+
+func listTemplatesHandler(clients *ServiceClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if clients == nil || clients.notificationClient == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "notification service unavailable")
+			return
+		}
+		var query templatesQuery
+		if err := decoder.Decode(&query, r.URL.Query()); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid query parameters")
+			return
+		}
+		req := pb.ListTemplatesRequest{Language: query.Language, Page: query.Page, Limit: query.Limit}
+
+		ctx, cancel := context.WithTimeout(outgoingContext(context.Background(), r), 10*time.Second)
+		defer cancel()
+
+		resp, err := clients.notificationClient.ListTemplates(ctx, &req)
+		if err != nil {
+			respondWithGRPCError(w, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, resp)
+	}
+}
+
+func getTemplateHandler(clients *ServiceClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if clients == nil || clients.notificationClient == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "notification service unavailable")
+			return
+		}
+		vars := mux.Vars(r)
+
+		ctx, cancel := context.WithTimeout(outgoingContext(context.Background(), r), 10*time.Second)
+		defer cancel()
+
+		resp, err := clients.notificationClient.GetTemplate(ctx, &pb.GetTemplateRequest{Id: vars["id"]})
+		if err != nil {
+			respondWithGRPCError(w, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, resp)
+	}
+}
+
+// updateTemplateHandler applies a partial update: only the fields present in
+// the JSON body are changed.
+func updateTemplateHandler(clients *ServiceClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if clients == nil || clients.notificationClient == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "notification service unavailable")
+			return
+		}
+		vars := mux.Vars(r)
+		var fields map[string]json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&fields); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+			return
+		}
+
+		var template pb.Template
+		mask := &fieldmaskpb.FieldMask{}
+		for name, value := range fields {
+			var s string
+			if err := json.Unmarshal(value, &s); err != nil {
+				respondWithError(w, http.StatusBadRequest, "Template fields must be strings")
+				return
+			}
+			switch name {
+			case "name":
+				template.Name = s
+			case "language":
+				template.Language = s
+			case "subject":
+				template.Subject = s
+			case "body":
+				template.Body = s
+			default:
+				respondWithError(w, http.StatusBadRequest, "Unknown template field "+name)
+				return
+			}
+			mask.Paths = append(mask.Paths, name)
+		}
+		template.Id = vars["id"]
+
+		ctx, cancel := context.WithTimeout(outgoingContext(context.Background(), r), 10*time.Second)
+		defer cancel()
+
+		resp, err := clients.notificationClient.UpdateTemplate(ctx, &pb.UpdateTemplateRequest{
+			Template:   &template,
+			UpdateMask: mask,
+		})
+		if err != nil {
+			respondWithGRPCError(w, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, resp)
+	}
+}
+
+func deleteTemplateHandler(clients *ServiceClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if clients == nil || clients.notificationClient == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "notification service unavailable")
+			return
+		}
+		vars := mux.Vars(r)
+
+		ctx, cancel := context.WithTimeout(outgoingContext(context.Background(), r), 10*time.Second)
+		defer cancel()
+
+		resp, err := clients.notificationClient.DeleteTemplate(ctx, &pb.DeleteTemplateRequest{Id: vars["id"]})
+		if err != nil {
+			respondWithGRPCError(w, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, resp)
+	}
+}
+
+// cloneTemplateHandler copies a template into a draft; the body names the
+// copy and may override its language.
+func cloneTemplateHandler(clients *ServiceClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if clients == nil || clients.notificationClient == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "notification service unavailable")
+			return
+		}
+		vars := mux.Vars(r)
+		var req pb.CloneTemplateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+			return
+		}
+		req.SourceTemplateId = vars["id"]
+
+		ctx, cancel := context.WithTimeout(outgoingContext(context.Background(), r), 10*time.Second)
+		defer cancel()
+
+		resp, err := clients.notificationClient.CloneTemplate(ctx, &req)
+		if err != nil {
+			respondWithGRPCError(w, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusCreated, resp)
+	}
+}
+
+func activateTemplateHandler(clients *ServiceClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if clients == nil || clients.notificationClient == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "notification service unavailable")
+			return
+		}
+		vars := mux.Vars(r)
+
+		ctx, cancel := context.WithTimeout(outgoingContext(context.Background(), r), 10*time.Second)
+		defer cancel()
+
+		resp, err := clients.notificationClient.ActivateTemplate(ctx, &pb.ActivateTemplateRequest{Id: vars["id"]})
 		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, err.Error())
+			respondWithGRPCError(w, err)
 			return
 		}
 
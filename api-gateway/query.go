@@ -0,0 +1,113 @@
+package main
+
+// Query parameters of the endpoints that take them, named in snake_case.
+// Older endpoints decode their query straight into the gRPC request, matching
+// keys against its Go field names.
+
+type similarTasksQuery struct {
+	UserID    string  `schema:"user_id"`
+	Title     string  `schema:"title"`
+	Threshold float32 `schema:"threshold"`
+}
+
+type calendarViewQuery struct {
+	UserID   string `schema:"user_id"`
+	Month    int32  `schema:"month"`
+	Year     int32  `schema:"year"`
+	Timezone string `schema:"timezone"`
+}
+
+type syncTasksQuery struct {
+	UserID string `schema:"user_id"`
+	Since  string `schema:"since"`
+}
+
+type userPageQuery struct {
+	UserID string `schema:"user_id"`
+	Page   int32  `schema:"page"`
+	Limit  int32  `schema:"limit"`
+}
+
+type userQuery struct {
+	UserID string `schema:"user_id"`
+}
+
+type failedDeliveriesQuery struct {
+	Channel   string `schema:"channel"`
+	StartDate string `schema:"start_date"`
+	EndDate   string `schema:"end_date"`
+	Page      int32  `schema:"page"`
+	Limit     int32  `schema:"limit"`
+}
+
+type templatesQuery struct {
+	Language string `schema:"language"`
+	Page     int32  `schema:"page"`
+	Limit    int32  `schema:"limit"`
+}
+
+type timezoneQuery struct {
+	Timezone string `schema:"timezone"`
+}
+
+type weeklySummaryQuery struct {
+	Week     string `schema:"week"`
+	Timezone string `schema:"timezone"`
+}
+
+type taskBreakdownQuery struct {
+	StartDate string `schema:"start_date"`
+	EndDate   string `schema:"end_date"`
+	TopLabels int32  `schema:"top_labels"`
+}
+
+type zonedRangeQuery struct {
+	StartDate string `schema:"start_date"`
+	EndDate   string `schema:"end_date"`
+	Timezone  string `schema:"timezone"`
+}
+
+type activeUsersQuery struct {
+	StartDate   string `schema:"start_date"`
+	EndDate     string `schema:"end_date"`
+	Granularity string `schema:"granularity"`
+	Timezone    string `schema:"timezone"`
+}
+
+type completionLatencyQuery struct {
+	UserID    string `schema:"user_id"`
+	StartDate string `schema:"start_date"`
+	EndDate   string `schema:"end_date"`
+}
+
+type completionTrendQuery struct {
+	UserID      string `schema:"user_id"`
+	StartDate   string `schema:"start_date"`
+	EndDate     string `schema:"end_date"`
+	Granularity string `schema:"granularity"`
+	Timezone    string `schema:"timezone"`
+}
+
+type overdueAgingQuery struct {
+	UserID string `schema:"user_id"`
+	AsOf   string `schema:"as_of"`
+	Weeks  int32  `schema:"weeks"`
+}
+
+type burndownQuery struct {
+	Start string `schema:"start"`
+	End   string `schema:"end"`
+	Total int32  `schema:"total"`
+}
+
+type replayTaskEventsQuery struct {
+	TaskID string `schema:"task_id"`
+	Since  string `schema:"since"`
+}
+
+type compareUsersQuery struct {
+	// Comma-separated
+	UserIDs   string `schema:"user_ids"`
+	StartDate string `schema:"start_date"`
+	EndDate   string `schema:"end_date"`
+}
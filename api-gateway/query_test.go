@@ -0,0 +1,88 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+func TestDecodeQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		into  interface{}
+		check func(interface{}) bool
+	}{
+		{
+			name:  "snake_case keys",
+			query: "user_id=u1&start_date=2026-01-01&end_date=2026-01-31&granularity=week&timezone=Asia/Dhaka",
+			into:  &completionTrendQuery{},
+			check: func(v interface{}) bool {
+				q := v.(*completionTrendQuery)
+				return q.UserID == "u1" && q.StartDate == "2026-01-01" && q.EndDate == "2026-01-31" &&
+					q.Granularity == "week" && q.Timezone == "Asia/Dhaka"
+			},
+		},
+		{
+			name:  "numbers",
+			query: "user_id=u1&title=Buy+milk&threshold=0.5",
+			into:  &similarTasksQuery{},
+			check: func(v interface{}) bool {
+				q := v.(*similarTasksQuery)
+				return q.UserID == "u1" && q.Title == "Buy milk" && q.Threshold == 0.5
+			},
+		},
+		{
+			name:  "paging",
+			query: "user_id=u1&page=2&limit=50",
+			into:  &userPageQuery{},
+			check: func(v interface{}) bool {
+				q := v.(*userPageQuery)
+				return q.UserID == "u1" && q.Page == 2 && q.Limit == 50
+			},
+		},
+		{
+			name:  "proto field names on older endpoints",
+			query: "UserId=u1&Completed=true&Page=3&CreatedAfter=2026-01-01T00:00:00Z",
+			into:  &pb.ListTasksRequest{},
+			check: func(v interface{}) bool {
+				req := v.(*pb.ListTasksRequest)
+				return req.UserId == "u1" && req.Completed && req.Page == 3 && req.CreatedAfter == "2026-01-01T00:00:00Z"
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := decoder.Decode(tt.into, values); err != nil {
+				t.Fatal(err)
+			}
+			if !tt.check(tt.into) {
+				t.Errorf("decoded %+v", tt.into)
+			}
+		})
+	}
+}
+
+func TestDecodeQueryRejectsBadValues(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		into  interface{}
+	}{
+		{"unknown key", "user_id=u1&since_id=5", &syncTasksQuery{}},
+		{"not a number", "weeks=many", &overdueAgingQuery{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, _ := url.ParseQuery(tt.query)
+			if err := decoder.Decode(tt.into, values); err == nil {
+				t.Errorf("decoded %q without error", tt.query)
+			}
+		})
+	}
+}
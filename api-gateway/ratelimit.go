@@ -0,0 +1,23 @@
+package main
+
+import (
+	"math"
+	"net/http"
+
+	"golang.org/x/time/rate"
+)
+
+// rateLimited serves at most rps requests a second through next, with bursts
+// of up to a second's worth, and answers the rest with 429. Health checks
+// are never limited, so an overloaded gateway is not also restarted.
+func rateLimited(next http.Handler, rps float64) http.Handler {
+	limiter := rate.NewLimiter(rate.Limit(rps), int(math.Max(1, math.Ceil(rps))))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" && !limiter.Allow() {
+			w.Header().Set("Retry-After", "1")
+			respondWithError(w, http.StatusTooManyRequests, "Too many requests")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+const (
+	weeklyReportTimeout  = 8 * time.Second
+	weeklyReportCacheTTL = time.Hour
+	// Most completed tasks listed in a report
+	weeklyReportMaxTasks = 100
+)
+
+// WeeklyReport gathers a user's week from the task and analytics services.
+// A section that could not be loaded is null, with the reason in Errors under
+// the section's name.
+type WeeklyReport struct {
+	UserID         string                `json:"user_id"`
+	WeekStart      string                `json:"week_start"`
+	WeekEnd        string                `json:"week_end"`
+	Summary        *weeklyReportSummary  `json:"summary"`
+	ByLabel        []*pb.BreakdownBucket `json:"by_label"`
+	ByHour         []*pb.HourBucket      `json:"by_hour"`
+	CompletedTasks []*pb.Task            `json:"completed_tasks"`
+	Errors         map[string]string     `json:"errors,omitempty"`
+}
+
+type weeklyReportSummary struct {
+	Completed int32 `json:"completed"`
+	// Completions per day of the week, Monday first
+	Daily []*pb.TrendBucket `json:"daily"`
+}
+
+// reportCache keeps complete weekly reports by user and week, since each one
+// costs four backend calls.
+type reportCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]cachedReport
+}
+
+type cachedReport struct {
+	report  *WeeklyReport
+	expires time.Time
+}
+
+func newReportCache(ttl time.Duration) *reportCache {
+	return &reportCache{ttl: ttl, entries: make(map[string]cachedReport)}
+}
+
+func (c *reportCache) get(key string, now time.Time) (*WeeklyReport, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || now.After(entry.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return entry.report, true
+}
+
+func (c *reportCache) put(key string, report *WeeklyReport, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Expired reports are only dropped here, which keeps the map bounded by
+	// the reports requested in the last TTL
+	for k, entry := range c.entries {
+		if now.After(entry.expires) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = cachedReport{report: report, expires: now.Add(c.ttl)}
+}
+
+// getWeeklyReportHandler serves the report of the Monday-to-Sunday week
+// containing week_of (YYYY-MM-DD, default today) in timezone (default UTC).
+func getWeeklyReportHandler(clients *ServiceClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if clients == nil || (clients.taskClient == nil && clients.analyticsClient == nil) {
+			respondWithError(w, http.StatusServiceUnavailable, "task and analytics services unavailable")
+			return
+		}
+		userId := mux.Vars(r)["id"]
+
+		timezone := r.URL.Query().Get("timezone")
+		if timezone == "" {
+			timezone = "UTC"
+		}
+		loc, err := time.LoadLocation(timezone)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid timezone")
+			return
+		}
+		day := time.Now().In(loc)
+		if weekOf := r.URL.Query().Get("week_of"); weekOf != "" {
+			if day, err = time.ParseInLocation("2006-01-02", weekOf, loc); err != nil {
+				respondWithError(w, http.StatusBadRequest, "Invalid week_of: use YYYY-MM-DD")
+				return
+			}
+		}
+		start := time.Date(day.Year(), day.Month(), day.Day()-(int(day.Weekday())+6)%7, 0, 0, 0, 0, loc)
+
+		key := userId + "|" + start.Format(time.RFC3339)
+		if report, ok := clients.weeklyReports.get(key, time.Now()); ok {
+			respondWithJSON(w, http.StatusOK, report)
+			return
+		}
+
+		report, err := buildWeeklyReport(outgoingContext(r.Context(), r), clients, userId, start)
+		if err != nil {
+			respondWithGRPCError(w, err)
+			return
+		}
+		if len(report.Errors) == 0 {
+			clients.weeklyReports.put(key, report, time.Now())
+		}
+		respondWithJSON(w, http.StatusOK, report)
+	}
+}
+
+// buildWeeklyReport loads the report's sections concurrently. A section
+// that fails is recorded in the report rather than failing it, unless the
+// failure means no section can succeed, such as the user not existing: that
+// cancels the other sections and is returned instead.
+func buildWeeklyReport(ctx context.Context, clients *ServiceClients, userId string, start time.Time) (*WeeklyReport, error) {
+	end := start.AddDate(0, 0, 7)
+	// The analytics services' date ranges are inclusive
+	lastInstant := end.Add(-time.Second).Format(time.RFC3339)
+	timezone := start.Location().String()
+
+	report := &WeeklyReport{
+		UserID:    userId,
+		WeekStart: start.Format(time.RFC3339),
+		WeekEnd:   end.Format(time.RFC3339),
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, weeklyReportTimeout)
+	defer cancel()
+
+	var mu sync.Mutex
+	g, ctx := errgroup.WithContext(ctx)
+	section := func(name string, load func() error) {
+		g.Go(func() error {
+			err := load()
+			if err == nil {
+				return nil
+			}
+			if failsReport(err) {
+				return err
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if report.Errors == nil {
+				report.Errors = make(map[string]string)
+			}
+			report.Errors[name] = status.Convert(err).Message()
+			return nil
+		})
+	}
+	errUnavailable := func(service string) error {
+		return errors.New(service + " service unavailable")
+	}
+
+	section("summary", func() error {
+		if clients.analyticsClient == nil {
+			return errUnavailable("analytics")
+		}
+		resp, err := clients.analyticsClient.GetCompletionTrend(ctx, &pb.GetCompletionTrendRequest{
+			UserId:      userId,
+			StartDate:   report.WeekStart,
+			EndDate:     lastInstant,
+			Granularity: "day",
+			Timezone:    timezone,
+		})
+		if err != nil {
+			return err
+		}
+		summary := &weeklyReportSummary{Daily: resp.Buckets}
+		for _, bucket := range resp.Buckets {
+			summary.Completed += bucket.Completions
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		report.Summary = summary
+		return nil
+	})
+
+	section("by_label", func() error {
+		if clients.analyticsClient == nil {
+			return errUnavailable("analytics")
+		}
+		resp, err := clients.analyticsClient.GetTaskBreakdown(ctx, &pb.GetTaskBreakdownRequest{
+			UserId:    userId,
+			StartDate: report.WeekStart,
+			EndDate:   lastInstant,
+		})
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		report.ByLabel = nonNil(resp.Labels)
+		return nil
+	})
+
+	section("by_hour", func() error {
+		if clients.analyticsClient == nil {
+			return errUnavailable("analytics")
+		}
+		resp, err := clients.analyticsClient.GetPeakHours(ctx, &pb.GetPeakHoursRequest{
+			UserId:    userId,
+			Timezone:  timezone,
+			StartDate: report.WeekStart,
+			EndDate:   lastInstant,
+		})
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		report.ByHour = nonNil(resp.Hours)
+		return nil
+	})
+
+	section("completed_tasks", func() error {
+		if clients.taskClient == nil {
+			return errUnavailable("task")
+		}
+		resp, err := clients.taskClient.ListTasks(ctx, &pb.ListTasksRequest{
+			UserId:        userId,
+			Completed:     true,
+			Limit:         weeklyReportMaxTasks,
+			CreatedAfter:  report.WeekStart,
+			CreatedBefore: report.WeekEnd,
+		})
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		report.CompletedTasks = nonNil(resp.Tasks)
+		return nil
+	})
+
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// failsReport reports whether a section's error would fail every section
+// alike, as opposed to one backend having trouble.
+func failsReport(err error) bool {
+	switch status.Code(err) {
+	case codes.InvalidArgument, codes.NotFound, codes.PermissionDenied, codes.Unauthenticated:
+		return true
+	}
+	return false
+}
+
+// nonNil turns an empty result into [] so that only failed sections are
+// null in the report.
+func nonNil[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// reportAnalytics answers the report's analytics calls, failing those named
+// in errs. GetPeakHours waits for its context when block is set.
+type reportAnalytics struct {
+	pb.AnalyticsServiceClient
+	calls int32
+	errs  map[string]error
+	block bool
+}
+
+func (a *reportAnalytics) GetCompletionTrend(ctx context.Context, req *pb.GetCompletionTrendRequest, opts ...grpc.CallOption) (*pb.GetCompletionTrendResponse, error) {
+	atomic.AddInt32(&a.calls, 1)
+	if err := a.errs["trend"]; err != nil {
+		return nil, err
+	}
+	return &pb.GetCompletionTrendResponse{Buckets: []*pb.TrendBucket{{Completions: 2}, {Completions: 3}}}, nil
+}
+
+func (a *reportAnalytics) GetTaskBreakdown(ctx context.Context, req *pb.GetTaskBreakdownRequest, opts ...grpc.CallOption) (*pb.GetTaskBreakdownResponse, error) {
+	atomic.AddInt32(&a.calls, 1)
+	if err := a.errs["breakdown"]; err != nil {
+		return nil, err
+	}
+	return &pb.GetTaskBreakdownResponse{}, nil
+}
+
+func (a *reportAnalytics) GetPeakHours(ctx context.Context, req *pb.GetPeakHoursRequest, opts ...grpc.CallOption) (*pb.GetPeakHoursResponse, error) {
+	atomic.AddInt32(&a.calls, 1)
+	if a.block {
+		<-ctx.Done()
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+	if err := a.errs["peak"]; err != nil {
+		return nil, err
+	}
+	return &pb.GetPeakHoursResponse{Hours: []*pb.HourBucket{{Hour: 9, Completions: 4}}}, nil
+}
+
+type reportTasks struct {
+	pb.TaskServiceClient
+	err error
+}
+
+func (t *reportTasks) ListTasks(ctx context.Context, req *pb.ListTasksRequest, opts ...grpc.CallOption) (*pb.ListTasksResponse, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	return &pb.ListTasksResponse{Tasks: []*pb.Task{{Id: "t1", Completed: true}}}, nil
+}
+
+func getWeeklyReport(t *testing.T, clients *ServiceClients) (int, map[string]json.RawMessage) {
+	t.Helper()
+	router := mux.NewRouter()
+	router.HandleFunc("/api/users/{id}/weekly-report", getWeeklyReportHandler(clients))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/users/u1/weekly-report?week_of=2026-03-04", nil))
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode %q: %v", rec.Body.String(), err)
+	}
+	return rec.Code, body
+}
+
+func TestWeeklyReportCache(t *testing.T) {
+	analytics := &reportAnalytics{}
+	clients := &ServiceClients{analyticsClient: analytics, taskClient: &reportTasks{}, weeklyReports: newReportCache(time.Hour)}
+
+	for i := 0; i < 2; i++ {
+		code, body := getWeeklyReport(t, clients)
+		if code != http.StatusOK || string(body["week_start"]) != `"2026-03-02T00:00:00Z"` {
+			t.Fatalf("request %d: %d %v", i, code, body)
+		}
+	}
+	if calls := atomic.LoadInt32(&analytics.calls); calls != 3 {
+		t.Errorf("%d analytics calls, want 3 from the first request only", calls)
+	}
+
+	// Incomplete reports are not cached
+	analytics.errs = map[string]error{"peak": status.Error(codes.Unavailable, "down")}
+	clients.weeklyReports = newReportCache(time.Hour)
+	getWeeklyReport(t, clients)
+	getWeeklyReport(t, clients)
+	if calls := atomic.LoadInt32(&analytics.calls); calls != 9 {
+		t.Errorf("%d analytics calls, want 9", calls)
+	}
+}
+
+func TestWeeklyReportPartialFailure(t *testing.T) {
+	tests := []struct {
+		name       string
+		analytics  *reportAnalytics
+		tasks      *reportTasks
+		wantCode   int
+		wantNull   []string
+		wantFilled []string
+	}{
+		{
+			name:       "one section down",
+			analytics:  &reportAnalytics{errs: map[string]error{"peak": status.Error(codes.Unavailable, "analytics down")}},
+			tasks:      &reportTasks{},
+			wantCode:   http.StatusOK,
+			wantNull:   []string{"by_hour"},
+			wantFilled: []string{"summary", "by_label", "completed_tasks"},
+		},
+		{
+			name:       "task service failing",
+			analytics:  &reportAnalytics{},
+			tasks:      &reportTasks{err: status.Error(codes.Internal, "boom")},
+			wantCode:   http.StatusOK,
+			wantNull:   []string{"completed_tasks"},
+			wantFilled: []string{"summary", "by_label", "by_hour"},
+		},
+		{
+			name:      "unknown user cancels the rest",
+			analytics: &reportAnalytics{block: true},
+			tasks:     &reportTasks{err: status.Error(codes.NotFound, "user u1 not found")},
+			wantCode:  http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clients := &ServiceClients{analyticsClient: tt.analytics, taskClient: tt.tasks, weeklyReports: newReportCache(time.Hour)}
+			started := time.Now()
+			code, body := getWeeklyReport(t, clients)
+			if code != tt.wantCode {
+				t.Fatalf("status = %d, want %d: %v", code, tt.wantCode, body)
+			}
+			if time.Since(started) > weeklyReportTimeout/2 {
+				t.Errorf("took %v: sections were not cancelled", time.Since(started))
+			}
+
+			var errs map[string]string
+			if raw, ok := body["errors"]; ok {
+				json.Unmarshal(raw, &errs)
+			}
+			for _, name := range tt.wantNull {
+				if string(body[name]) != "null" || errs[name] == "" {
+					t.Errorf("%s = %s, error %q; want null with an error", name, body[name], errs[name])
+				}
+			}
+			for _, name := range tt.wantFilled {
+				if string(body[name]) == "null" || errs[name] != "" {
+					t.Errorf("%s = %s, error %q; want it filled", name, body[name], errs[name])
+				}
+			}
+		})
+	}
+}
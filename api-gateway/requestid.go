@@ -0,0 +1,26 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/technonext/todo-app/proto/callctx"
+	"github.com/technonext/todo-app/proto/grpcmiddleware"
+)
+
+// requestIDHeader carries the id of a client's request, both ways.
+const requestIDHeader = "X-Request-Id"
+
+// withRequestID gives each request one id, the client's X-Request-Id or a new
+// one when it sent none or a malformed one, and answers with it.
+// clientMetadata sends it with every call made for the request, so the
+// services log them all under the same id.
+func withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !callctx.ValidRequestID(id) {
+			id = grpcmiddleware.NewRequestID()
+		}
+		w.Header().Set(requestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(grpcmiddleware.WithRequestID(r.Context(), id)))
+	})
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/technonext/todo-app/proto/callctx"
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// requestIDBackend records the request id each call came with.
+type requestIDBackend struct {
+	pb.TaskServiceClient
+
+	mu  sync.Mutex
+	ids [][]string
+}
+
+func (b *requestIDBackend) record(ctx context.Context) {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.ids = append(b.ids, md.Get(callctx.RequestIDHeader))
+}
+
+func (b *requestIDBackend) FindSimilarTasks(ctx context.Context, req *pb.FindSimilarTasksRequest, opts ...grpc.CallOption) (*pb.ListTasksResponse, error) {
+	b.record(ctx)
+	return &pb.ListTasksResponse{}, nil
+}
+
+func (b *requestIDBackend) CreateTask(ctx context.Context, req *pb.CreateTaskRequest, opts ...grpc.CallOption) (*pb.TaskResponse, error) {
+	b.record(ctx)
+	return &pb.TaskResponse{Task: &pb.Task{Id: "t1", Title: req.Title}}, nil
+}
+
+func (b *requestIDBackend) GetTask(ctx context.Context, req *pb.GetTaskRequest, opts ...grpc.CallOption) (*pb.TaskResponse, error) {
+	b.record(ctx)
+	return &pb.TaskResponse{Task: &pb.Task{Id: req.Id}}, nil
+}
+
+func TestRequestIDFollowsRequest(t *testing.T) {
+	tests := []struct {
+		name   string
+		sent   string
+		keeps  bool
+		method string
+		path   string
+		body   string
+	}{
+		{"client's id, handler with two calls", "req-1", true, "POST", "/api/tasks", `{"title":"Write report"}`},
+		{"no id, handler with two calls", "", false, "POST", "/api/tasks", `{"title":"Write report"}`},
+		{"malformed id", "req 1", false, "POST", "/api/tasks", `{"title":"Write report"}`},
+		{"client's id, generated proxy", "req-2", true, "GET", "/api/tasks/t1", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := &requestIDBackend{}
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.sent != "" {
+				req.Header.Set(requestIDHeader, tt.sent)
+			}
+			rec := httptest.NewRecorder()
+			withRequestID(newRouter(&ServiceClients{taskClient: backend})).ServeHTTP(rec, req)
+
+			answered := rec.Header().Get(requestIDHeader)
+			if tt.keeps && answered != tt.sent {
+				t.Errorf("answered %s %q, want the client's %q", requestIDHeader, answered, tt.sent)
+			}
+			if !tt.keeps && (answered == "" || answered == tt.sent) {
+				t.Errorf("answered %s %q, want a new id", requestIDHeader, answered)
+			}
+			if len(backend.ids) == 0 {
+				t.Fatal("no calls reached the backend")
+			}
+			for i, ids := range backend.ids {
+				if len(ids) != 1 || ids[0] != answered {
+					t.Errorf("call %d sent request ids %v, want [%s]", i, ids, answered)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/technonext/todo-app/proto/grpcmiddleware"
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// busyTaskServer holds every GetTask until release is closed.
+type busyTaskServer struct {
+	pb.UnimplementedTaskServiceServer
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s busyTaskServer) GetTask(ctx context.Context, req *pb.GetTaskRequest) (*pb.TaskResponse, error) {
+	s.started <- struct{}{}
+	<-s.release
+	return &pb.TaskResponse{Task: &pb.Task{Id: req.Id, Title: "Hold the line"}}, nil
+}
+
+func TestGatewayAnswersShedCallsWith429(t *testing.T) {
+	busy := busyTaskServer{started: make(chan struct{}), release: make(chan struct{})}
+	s := grpcmiddleware.NewServer(grpcmiddleware.ServerConfig{MaxInFlight: 1})
+	pb.RegisterTaskServiceServer(s, busy)
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Serve(lis)
+	defer s.Stop()
+
+	opts := append([]grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())},
+		grpcmiddleware.DialOptions(grpcmiddleware.ClientConfig{})...)
+	conn, err := grpc.Dial(lis.Addr().String(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	router := newRouter(&ServiceClients{taskClient: pb.NewTaskServiceClient(conn)})
+
+	first := make(chan int)
+	go func() {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", "/api/tasks/t1", nil))
+		first <- rec.Code
+	}()
+	<-busy.started
+
+	// The service's one slot is taken, so the next call is refused at once
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/api/tasks/t2", nil))
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("status while saturated = %d, want 429", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("Retry-After = %q, want 1", got)
+	}
+
+	close(busy.release)
+	if code := <-first; code != http.StatusOK {
+		t.Errorf("status of the call holding the slot = %d, want 200", code)
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+const (
+	notificationSummaryTimeout = 5 * time.Second
+	// Badges poll the summary; a notification sent through the service
+	// shows up within this long
+	notificationSummaryCacheTTL = 10 * time.Second
+)
+
+// summaryCache keeps users' notification summaries for a few seconds, by
+// tenant and user. Marking a notification read through the gateway drops
+// its user's summary, and a summary fetched before that is not kept, so a
+// badge never counts a notification just read.
+type summaryCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]cachedSummary
+	// When each summary was last invalidated, kept as long as a fetch
+	// started before it can still be running
+	invalidated map[string]time.Time
+}
+
+type cachedSummary struct {
+	summary *pb.GetNotificationSummaryResponse
+	expires time.Time
+}
+
+func newSummaryCache(ttl time.Duration) *summaryCache {
+	return &summaryCache{ttl: ttl, entries: make(map[string]cachedSummary), invalidated: make(map[string]time.Time)}
+}
+
+func (c *summaryCache) get(key string, now time.Time) (*pb.GetNotificationSummaryResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || now.After(entry.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return entry.summary, true
+}
+
+// put keeps summary, fetched from started on, unless key was invalidated
+// since: the summary may predate the change.
+func (c *summaryCache) put(key string, summary *pb.GetNotificationSummaryResponse, started, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Expired summaries and invalidations are only dropped here, which
+	// keeps the maps bounded by the users seen in the last TTL
+	for k, entry := range c.entries {
+		if now.After(entry.expires) {
+			delete(c.entries, k)
+		}
+	}
+	for k, at := range c.invalidated {
+		if now.Sub(at) > notificationSummaryTimeout {
+			delete(c.invalidated, k)
+		}
+	}
+	if at, ok := c.invalidated[key]; ok && !started.After(at) {
+		return
+	}
+	c.entries[key] = cachedSummary{summary: summary, expires: now.Add(c.ttl)}
+}
+
+func (c *summaryCache) invalidate(key string, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+	c.invalidated[key] = now
+}
+
+// summaryKey names a user's summary, within the tenant r is authenticated
+// for.
+func summaryKey(r *http.Request, userID string) string {
+	_, tenantID := authenticatedUser(r)
+	return tenantID + "|" + userID
+}
+
+// getNotificationSummaryHandler serves the user_id's unread notification
+// counts, in all and by type, for badges.
+func getNotificationSummaryHandler(clients *ServiceClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if clients == nil || clients.notificationClient == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "notification service unavailable")
+			return
+		}
+		userId := r.URL.Query().Get("user_id")
+		if userId == "" {
+			respondWithError(w, http.StatusBadRequest, "user_id is required")
+			return
+		}
+
+		key := summaryKey(r, userId)
+		if summary, ok := clients.summaries.get(key, time.Now()); ok {
+			respondWithJSON(w, http.StatusOK, summary)
+			return
+		}
+
+		started := time.Now()
+		ctx, cancel := context.WithTimeout(outgoingContext(context.Background(), r), notificationSummaryTimeout)
+		defer cancel()
+		summary, err := clients.notificationClient.GetNotificationSummary(ctx, &pb.GetNotificationSummaryRequest{UserId: userId})
+		if err != nil {
+			respondWithGRPCError(w, err)
+			return
+		}
+		clients.summaries.put(key, summary, started, time.Now())
+		respondWithJSON(w, http.StatusOK, summary)
+	}
+}
+
+// markNotificationReadHandler marks a notification read and drops its
+// user's cached summary once it is.
+func markNotificationReadHandler(clients *ServiceClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if clients == nil || clients.notificationClient == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "notification service unavailable")
+			return
+		}
+		ctx, cancel := context.WithTimeout(outgoingContext(context.Background(), r), 10*time.Second)
+		defer cancel()
+		resp, err := clients.notificationClient.MarkNotificationRead(ctx, &pb.MarkNotificationReadRequest{Id: mux.Vars(r)["id"]})
+		if err != nil {
+			respondWithGRPCError(w, err)
+			return
+		}
+		clients.summaries.invalidate(summaryKey(r, resp.Notification.GetUserId()), time.Now())
+		respondWithJSON(w, http.StatusOK, resp)
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// summaryBackend counts u1's unread notifications, which marking any read
+// brings down by one.
+type summaryBackend struct {
+	pb.NotificationServiceClient
+
+	mu        sync.Mutex
+	unread    int64
+	summaries int
+	// duringSummary, when set, runs after a summary is counted and before
+	// it is returned
+	duringSummary func()
+}
+
+func (b *summaryBackend) GetNotificationSummary(ctx context.Context, req *pb.GetNotificationSummaryRequest, opts ...grpc.CallOption) (*pb.GetNotificationSummaryResponse, error) {
+	b.mu.Lock()
+	b.summaries++
+	resp := &pb.GetNotificationSummaryResponse{
+		Unread: b.unread,
+		ByType: []*pb.NotificationTypeCount{
+			{Type: pb.NotificationType_NOTIFICATION_TYPE_TASK, Unread: b.unread},
+			{Type: pb.NotificationType_NOTIFICATION_TYPE_SYSTEM},
+		},
+	}
+	during := b.duringSummary
+	b.duringSummary = nil
+	b.mu.Unlock()
+	if during != nil {
+		during()
+	}
+	return resp, nil
+}
+
+func (b *summaryBackend) MarkNotificationRead(ctx context.Context, req *pb.MarkNotificationReadRequest, opts ...grpc.CallOption) (*pb.NotificationResponse, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.unread--
+	return &pb.NotificationResponse{Notification: &pb.Notification{Id: req.Id, UserId: "u1", Read: true}}, nil
+}
+
+func (b *summaryBackend) calls() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.summaries
+}
+
+// getUnread fetches u1's summary through router and returns its unread
+// count.
+func getUnread(t *testing.T, router http.Handler) int64 {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/notifications/summary?user_id=u1", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	var summary struct {
+		Unread int64 `json:"unread"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &summary); err != nil {
+		t.Fatal(err)
+	}
+	return summary.Unread
+}
+
+func markRead(t *testing.T, router http.Handler, id string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/notifications/"+id+"/read", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("marking %s read: status = %d: %s", id, rec.Code, rec.Body)
+	}
+}
+
+func TestNotificationSummaryCachedUntilMarkRead(t *testing.T) {
+	backend := &summaryBackend{unread: 3}
+	router := newRouter(&ServiceClients{notificationClient: backend, summaries: newSummaryCache(time.Hour)})
+
+	if got := getUnread(t, router); got != 3 {
+		t.Fatalf("unread = %d, want 3", got)
+	}
+	if got := getUnread(t, router); got != 3 || backend.calls() != 1 {
+		t.Errorf("polled again: unread = %d after %d calls, want 3 from the cache", got, backend.calls())
+	}
+
+	markRead(t, router, "n1")
+	if got := getUnread(t, router); got != 2 || backend.calls() != 2 {
+		t.Errorf("after marking read: unread = %d after %d calls, want 2 fetched again", got, backend.calls())
+	}
+}
+
+func TestNotificationSummaryFetchedBeforeMarkReadNotCached(t *testing.T) {
+	backend := &summaryBackend{unread: 3}
+	router := newRouter(&ServiceClients{notificationClient: backend, summaries: newSummaryCache(time.Hour)})
+
+	// The notification is marked read while the summary counting it is on
+	// its way back
+	backend.duringSummary = func() { markRead(t, router, "n1") }
+	if got := getUnread(t, router); got != 3 {
+		t.Fatalf("unread = %d, want the 3 counted", got)
+	}
+	if got := getUnread(t, router); got != 2 || backend.calls() != 2 {
+		t.Errorf("unread = %d after %d calls, want 2 fetched again rather than the cached 3", got, backend.calls())
+	}
+	if got := getUnread(t, router); got != 2 || backend.calls() != 2 {
+		t.Errorf("unread = %d after %d calls, want 2 from the cache", got, backend.calls())
+	}
+}
+
+func TestSummaryCache(t *testing.T) {
+	now := time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC)
+	c := newSummaryCache(10 * time.Second)
+	summary := &pb.GetNotificationSummaryResponse{Unread: 1}
+
+	c.put("acme|u1", summary, now, now)
+	if got, ok := c.get("acme|u1", now.Add(10*time.Second)); !ok || got != summary {
+		t.Errorf("get() within the TTL = %v, %v; want the summary", got, ok)
+	}
+	if _, ok := c.get("acme|u1", now.Add(11*time.Second)); ok {
+		t.Error("get() after the TTL found the summary")
+	}
+
+	// Invalidated while being fetched
+	c.invalidate("acme|u1", now.Add(time.Second))
+	c.put("acme|u1", summary, now, now.Add(2*time.Second))
+	if _, ok := c.get("acme|u1", now.Add(2*time.Second)); ok {
+		t.Error("kept a summary fetched before the invalidation")
+	}
+	c.put("acme|u1", summary, now.Add(time.Second+time.Millisecond), now.Add(2*time.Second))
+	if _, ok := c.get("acme|u1", now.Add(2*time.Second)); !ok {
+		t.Error("dropped a summary fetched after the invalidation")
+	}
+	if _, ok := c.get("other|u1", now.Add(2*time.Second)); ok {
+		t.Error("found the summary under another tenant")
+	}
+
+	c.put("acme|u2", summary, now, now.Add(time.Minute))
+	if len(c.invalidated) != 0 {
+		t.Errorf("invalidations kept after every fetch they could affect: %v", c.invalidated)
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// maxBodyBytes caps request bodies read for validation.
+const maxBodyBytes = 1 << 20
+
+// Request body schemas. They only check the shape of the body: property
+// names follow the proto JSON names, unknown properties are rejected so typos
+// do not silently drop data, and values must have the right JSON type. Rules
+// on the values, such as title lengths, live in todo.proto and are enforced
+// by the services, whose violations respondWithGRPCError lists the same way.
+var (
+	createTaskSchema = mustSchema(`{
+		"type": "object",
+		"additionalProperties": false,
+		"properties": {
+			"title":             {"type": "string"},
+			"description":       {"type": "string"},
+			"user_id":           {"type": "string"},
+			"due_date":          {"type": "string"},
+			"priority":          {"type": "integer"},
+			"labels":            {"type": "array", "items": {"type": "string"}},
+			"estimated_minutes": {"type": "integer"}
+		}
+	}`)
+
+	updateTaskSchema = mustSchema(`{
+		"type": "object",
+		"additionalProperties": false,
+		"properties": {
+			"id":                     {"type": "string"},
+			"title":                  {"type": "string"},
+			"description":            {"type": "string"},
+			"completed":              {"type": "boolean"},
+			"due_date":               {"type": "string"},
+			"status":                 {"type": "integer"},
+			"priority":               {"type": "integer"},
+			"labels":                 {"type": "array", "items": {"type": "string"}},
+			"estimated_minutes":      {"type": "integer"},
+			"add_time_spent_minutes": {"type": "integer"},
+			"user_id":                {"type": "string"}
+		}
+	}`)
+
+	bulkUpdateTasksSchema = mustSchema(`{
+		"type": "object",
+		"additionalProperties": false,
+		"required": ["ids", "fields"],
+		"properties": {
+			"ids":     {"type": "array", "items": {"type": "string"}},
+			"updates": {
+				"type": "object",
+				"additionalProperties": false,
+				"properties": {
+					"title":             {"type": "string"},
+					"description":       {"type": "string"},
+					"due_date":          {"type": "string"},
+					"priority":          {"type": "integer"},
+					"labels":            {"type": "array", "items": {"type": "string"}},
+					"estimated_minutes": {"type": "integer"}
+				}
+			},
+			"fields":  {"type": "array", "items": {"type": "string"}}
+		}
+	}`)
+
+	createUserSchema = mustSchema(`{
+		"type": "object",
+		"additionalProperties": false,
+		"properties": {
+			"username": {"type": "string"},
+			"email":    {"type": "string"},
+			"password": {"type": "string"}
+		}
+	}`)
+)
+
+func mustSchema(schema string) *gojsonschema.Schema {
+	s, err := gojsonschema.NewSchema(gojsonschema.NewStringLoader(schema))
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+type fieldError struct {
+	Field string `json:"field"`
+	Error string `json:"error"`
+}
+
+// decodeValidated validates the request body against schema and decodes it
+// into v. On failure it writes a 400 listing the offending fields and
+// returns false.
+func decodeValidated(w http.ResponseWriter, r *http.Request, schema *gojsonschema.Schema, v interface{}) bool {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		respondWithError(w, http.StatusRequestEntityTooLarge, "Request body too large")
+		return false
+	}
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Could not read request body")
+		return false
+	}
+
+	result, err := schema.Validate(gojsonschema.NewBytesLoader(body))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return false
+	}
+	if !result.Valid() {
+		// Report one error per field
+		var fields []fieldError
+		seen := make(map[string]bool)
+		for _, e := range result.Errors() {
+			fe := describeSchemaError(e)
+			if !seen[fe.Field] {
+				seen[fe.Field] = true
+				fields = append(fields, fe)
+			}
+		}
+		respondWithJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error":  "Invalid request payload",
+			"fields": fields,
+		})
+		return false
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return false
+	}
+	return true
+}
+
+func describeSchemaError(e gojsonschema.ResultError) fieldError {
+	if e.Type() == "additional_property_not_allowed" {
+		return fieldError{Field: e.Details()["property"].(string), Error: "unknown field"}
+	}
+	return fieldError{Field: e.Field(), Error: e.Description()}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/technonext/todo-app/proto/grpcmiddleware"
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// failingReader fails every read, as a connection dropped mid-body does.
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, errors.New("connection reset") }
+
+func TestDecodeValidated(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       io.Reader
+		wantOK     bool
+		wantStatus int
+	}{
+		{"valid", strings.NewReader(`{"username": "alice", "email": "a@example.com", "password": "secret123"}`), true, http.StatusOK},
+		{"too large", strings.NewReader(`{"username": "` + strings.Repeat("a", maxBodyBytes) + `"}`), false, http.StatusRequestEntityTooLarge},
+		{"read error", failingReader{}, false, http.StatusBadRequest},
+		{"malformed", strings.NewReader(`{"username":`), false, http.StatusBadRequest},
+		{"fails schema", strings.NewReader(`{"username": 42}`), false, http.StatusBadRequest},
+		{"unknown field", strings.NewReader(`{"user_name": "alice"}`), false, http.StatusBadRequest},
+		// Values are checked by the service against the rules in todo.proto
+		{"breaks proto rules", strings.NewReader(`{"username": "al"}`), true, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/api/users", tt.body)
+			var req pb.CreateUserRequest
+			if ok := decodeValidated(w, r, createUserSchema, &req); ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+// acceptingTaskServer accepts every task request that reaches it.
+type acceptingTaskServer struct {
+	pb.UnimplementedTaskServiceServer
+}
+
+func (acceptingTaskServer) CreateTask(ctx context.Context, req *pb.CreateTaskRequest) (*pb.TaskResponse, error) {
+	return &pb.TaskResponse{Task: &pb.Task{Id: "t1", Title: req.Title, UserId: req.UserId}}, nil
+}
+
+func (acceptingTaskServer) ListTasks(ctx context.Context, req *pb.ListTasksRequest) (*pb.ListTasksResponse, error) {
+	return &pb.ListTasksResponse{}, nil
+}
+
+// TestProtoRulesEnforcedOverGRPCAndHTTP checks that the rules in todo.proto
+// reach callers of both layers through the shared server chain, with neither
+// the task server nor the gateway handlers checking them.
+func TestProtoRulesEnforcedOverGRPCAndHTTP(t *testing.T) {
+	lis := bufconn.Listen(1 << 20)
+	s := grpcmiddleware.NewServer(grpcmiddleware.ServerConfig{})
+	pb.RegisterTaskServiceServer(s, acceptingTaskServer{})
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	clients := &ServiceClients{taskClient: pb.NewTaskServiceClient(conn)}
+
+	_, err = clients.taskClient.CreateTask(context.Background(), &pb.CreateTaskRequest{UserId: "u1", Labels: []string{strings.Repeat("a", 51)}})
+	if code := status.Code(err); code != codes.InvalidArgument {
+		t.Errorf("gRPC: code = %v, want InvalidArgument", code)
+	}
+
+	tests := []struct {
+		name, method, path, body string
+		wantFields               []fieldError
+	}{
+		{
+			"hand-written handler", http.MethodPost, "/api/tasks",
+			`{"user_id": "u1", "labels": ["` + strings.Repeat("a", 51) + `"]}`,
+			[]fieldError{{"title", "required"}, {"labels[0]", "must be at most 50 characters"}},
+		},
+		{
+			"generated proxy", http.MethodGet, "/api/tasks?user_id=u1&limit=500", "",
+			[]fieldError{{"limit", "must be at most 100"}},
+		},
+	}
+	router := newRouter(clients)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400: %s", w.Code, w.Body)
+			}
+			var body struct {
+				Error  string       `json:"error"`
+				Fields []fieldError `json:"fields"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Error != "Invalid request payload" || !reflect.DeepEqual(body.Fields, tt.wantFields) {
+				t.Errorf("body = %+v, want fields %+v", body, tt.wantFields)
+			}
+		})
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/tasks", strings.NewReader(`{"user_id": "u1", "title": "Write report"}`)))
+	if w.Code != http.StatusCreated {
+		t.Errorf("valid task: status = %d, want 201: %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,41 @@
+// Command accountkeys generates the service accounts' keys for
+// REQUIRE_SERVICE_AUTH, as lines for a .env file:
+//
+//	go run ./cmd/accountkeys >> .env
+//
+// Each account gets its own private key, in <ACCOUNT>_ACCOUNT_KEY, which
+// only that service is given as SERVICE_ACCOUNT_KEY. Every service is given
+// SERVICE_ACCOUNT_PUBLIC_KEYS, the public keys it checks callers' tokens
+// against. See package serviceauth.
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/technonext/todo-app/proto/serviceauth"
+)
+
+func main() {
+	keys, err := serviceauth.GenerateKeys()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "accountkeys: %v\n", err)
+		os.Exit(1)
+	}
+	accounts := make([]string, 0, len(keys))
+	for account := range keys {
+		accounts = append(accounts, account)
+	}
+	sort.Strings(accounts)
+	for _, account := range accounts {
+		fmt.Printf("%s_ACCOUNT_KEY=%s\n", envName(account), serviceauth.FormatPrivateKey(keys[account]))
+	}
+	fmt.Printf("SERVICE_ACCOUNT_PUBLIC_KEYS=%s\n", serviceauth.FormatPublicKeys(keys))
+}
+
+// envName turns an account such as task-service into TASK_SERVICE.
+func envName(account string) string {
+	return strings.ToUpper(strings.ReplaceAll(account, "-", "_"))
+}
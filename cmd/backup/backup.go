@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// backupOptions say what to back up from a database.
+type backupOptions struct {
+	collections []string
+	// Keep the fields in omittedFields
+	includePasswordHashes bool
+	now                   time.Time
+}
+
+// backup writes collections of db to dir, which must not hold a backup
+// already. Collections are read one after another, not at a single point in
+// time, so writes during a backup may be caught in some collections only.
+func backup(ctx context.Context, db *mongo.Database, dir string, opts backupOptions) (*manifest, error) {
+	if _, err := os.Stat(filepath.Join(dir, manifestFile)); err == nil {
+		return nil, fmt.Errorf("%s already holds a backup", dir)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	m := &manifest{Version: formatVersion, CreatedAt: opts.now.UTC().Format(time.RFC3339)}
+	for _, name := range opts.collections {
+		c := collectionBackup{Database: db.Name(), Name: name, File: name + ".jsonl.gz"}
+		if !opts.includePasswordHashes {
+			c.Omitted = omittedFields[name]
+		}
+		if err := backupCollection(ctx, db.Collection(name), filepath.Join(dir, c.File), &c); err != nil {
+			return nil, fmt.Errorf("backing up %s: %w", name, err)
+		}
+		m.Collections = append(m.Collections, c)
+	}
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, manifestFile), append(data, '\n'), 0o644); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// backupCollection writes the documents of coll to path, filling in the
+// count and checksum of c.
+func backupCollection(ctx context.Context, coll *mongo.Collection, path string, c *collectionBackup) (err error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	h := sha256.New()
+	buf := bufio.NewWriter(io.MultiWriter(f, h))
+	zw := gzip.NewWriter(buf)
+
+	cursor, err := coll.Find(ctx, bson.D{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		var doc interface{} = cursor.Current
+		if len(c.Omitted) > 0 {
+			var d bson.D
+			if err := bson.Unmarshal(cursor.Current, &d); err != nil {
+				return err
+			}
+			doc = slices.DeleteFunc(d, func(e bson.E) bool { return slices.Contains(c.Omitted, e.Key) })
+		}
+		// Canonical extended JSON keeps every BSON type, so documents come
+		// back exactly as they were
+		line, err := bson.MarshalExtJSON(doc, true, false)
+		if err != nil {
+			return err
+		}
+		if _, err := zw.Write(append(line, '\n')); err != nil {
+			return err
+		}
+		c.Documents++
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	if err := buf.Flush(); err != nil {
+		return err
+	}
+	c.SHA256 = hex.EncodeToString(h.Sum(nil))
+	return nil
+}
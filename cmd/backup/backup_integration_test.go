@@ -0,0 +1,225 @@
+//go:build integration
+
+package main
+
+// These tests back up and restore a real MongoDB. Start one with
+//
+//	docker run --rm -d -p 27017:27017 mongo:5.0
+//
+// and run go test -tags integration ./... ; MONGO_TEST_URI overrides the
+// default mongodb://localhost:27017. Each test works in its own databases,
+// dropped afterwards.
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func connect(t *testing.T) *mongo.Client {
+	t.Helper()
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		uri = "mongodb://localhost:27017"
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Ping(ctx, nil); err != nil {
+		t.Skipf("MongoDB not reachable at %s: %v", uri, err)
+	}
+	t.Cleanup(func() { client.Disconnect(context.Background()) })
+	return client
+}
+
+// testDatabase returns a new database, dropped after the test.
+func testDatabase(t *testing.T, client *mongo.Client) *mongo.Database {
+	t.Helper()
+	db := client.Database(fmt.Sprintf("backup_test_%d", time.Now().UnixNano()))
+	t.Cleanup(func() { db.Drop(context.Background()) })
+	return db
+}
+
+// seedDatabase fills the collections backed up by default, with documents
+// of every BSON type the services store.
+func seedDatabase(t *testing.T, db *mongo.Database) {
+	t.Helper()
+	ctx := context.Background()
+	created := primitive.NewDateTimeFromTime(time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC))
+	for i := 0; i < 1200; i++ {
+		userID := primitive.NewObjectID()
+		docs := map[string]bson.D{
+			"users": {
+				{Key: "_id", Value: userID},
+				{Key: "username", Value: fmt.Sprintf("user%d", i)},
+				{Key: "email", Value: fmt.Sprintf("user%d@example.com", i)},
+				{Key: "password", Value: "$2a$10$hash"},
+				{Key: "created_at", Value: created},
+			},
+			"tasks": {
+				{Key: "_id", Value: primitive.NewObjectID()},
+				{Key: "user_id", Value: userID.Hex()},
+				{Key: "title", Value: fmt.Sprintf("Task %d", i)},
+				{Key: "labels", Value: bson.A{"work", "home"}},
+				{Key: "estimated_minutes", Value: int32(i)},
+				{Key: "view_count", Value: int64(i) << 33},
+				{Key: "completed", Value: i%2 == 0},
+				{Key: "status_history", Value: bson.A{bson.D{{Key: "from", Value: "A"}, {Key: "to", Value: "B"}}}},
+			},
+			"notifications": {
+				{Key: "_id", Value: primitive.NewObjectID()},
+				{Key: "user_id", Value: userID.Hex()},
+				{Key: "message", Value: "Due soon"},
+				{Key: "sent_at", Value: created},
+			},
+			"events": {
+				{Key: "_id", Value: primitive.NewObjectID()},
+				{Key: "user_id", Value: userID.Hex()},
+				{Key: "event_type", Value: "task.created"},
+				{Key: "metadata", Value: bson.D{{Key: "score", Value: 0.5}, {Key: "tags", Value: bson.A{}}}},
+				{Key: "tenant_id", Value: "acme"},
+			},
+		}
+		for name, doc := range docs {
+			if _, err := db.Collection(name).InsertOne(ctx, doc); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+// snapshot returns every document of the default collections of db, as
+// raw BSON by collection and _id.
+func snapshot(t *testing.T, db *mongo.Database) map[string]map[string]bson.Raw {
+	t.Helper()
+	ctx := context.Background()
+	docs := make(map[string]map[string]bson.Raw)
+	for _, name := range []string{"users", "tasks", "notifications", "events"} {
+		cursor, err := db.Collection(name).Find(ctx, bson.D{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		docs[name] = make(map[string]bson.Raw)
+		for cursor.Next(ctx) {
+			docs[name][cursor.Current.Lookup("_id").String()] = append(bson.Raw(nil), cursor.Current...)
+		}
+		if err := cursor.Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return docs
+}
+
+func TestBackupRoundTrip(t *testing.T) {
+	client := connect(t)
+	ctx := context.Background()
+	db := testDatabase(t, client)
+	seedDatabase(t, db)
+	want := snapshot(t, db)
+
+	dir := t.TempDir()
+	m, err := backup(ctx, db, dir, backupOptions{
+		collections:           []string{"users", "tasks", "notifications", "events"},
+		includePasswordHashes: true,
+		now:                   time.Now(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range m.Collections {
+		if c.Documents != 1200 || len(c.Omitted) > 0 {
+			t.Errorf("%s: %d documents, omitting %v; want 1200 and nothing omitted", c.Name, c.Documents, c.Omitted)
+		}
+	}
+
+	if err := db.Drop(ctx); err != nil {
+		t.Fatal(err)
+	}
+	results, err := restore(ctx, client, dir, restoreOptions{batchSize: 500})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range results {
+		if r.Inserted != 1200 || r.Updated != 0 {
+			t.Errorf("%s: %d inserted, %d updated; want 1200 and 0", r.Name, r.Inserted, r.Updated)
+		}
+	}
+
+	got := snapshot(t, db)
+	for name, docs := range want {
+		if len(got[name]) != len(docs) {
+			t.Errorf("%s: %d documents restored, want %d", name, len(got[name]), len(docs))
+		}
+		sampled := 0
+		for id, doc := range docs {
+			if sampled++; sampled > 50 {
+				break
+			}
+			if !bytes.Equal(got[name][id], doc) {
+				t.Errorf("%s %s restored as %v, want %v", name, id, got[name][id], doc)
+			}
+		}
+	}
+
+	// Restoring again only replaces what is there
+	results, err = restore(ctx, client, dir, restoreOptions{batchSize: 500})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := results[0]; r.Inserted != 0 || r.Updated != 1200 {
+		t.Errorf("restoring again: %d inserted, %d updated; want 0 and 1200", r.Inserted, r.Updated)
+	}
+}
+
+func TestBackupWithoutPasswordHashes(t *testing.T) {
+	client := connect(t)
+	ctx := context.Background()
+	db := testDatabase(t, client)
+	seedDatabase(t, db)
+
+	dir := t.TempDir()
+	if _, err := backup(ctx, db, dir, backupOptions{collections: []string{"users"}, now: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := backup(ctx, db, dir, backupOptions{collections: []string{"users"}, now: time.Now()}); err == nil {
+		t.Error("backing up over a backup succeeded")
+	}
+
+	// Users still in the database keep their hashes
+	if _, err := db.Collection("users").UpdateMany(ctx, bson.D{}, bson.D{{Key: "$set", Value: bson.D{{Key: "username", Value: "renamed"}}}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := restore(ctx, client, dir, restoreOptions{batchSize: 100}); err != nil {
+		t.Fatal(err)
+	}
+	var user bson.M
+	if err := db.Collection("users").FindOne(ctx, bson.D{}).Decode(&user); err != nil {
+		t.Fatal(err)
+	}
+	if user["password"] != "$2a$10$hash" || user["username"] == "renamed" {
+		t.Errorf("restored user = %v, want the backed up username and the stored hash", user)
+	}
+
+	// Restored into another database, users have none
+	copyDB := testDatabase(t, client)
+	if _, err := restore(ctx, client, dir, restoreOptions{remap: map[string]string{db.Name(): copyDB.Name()}, batchSize: 100}); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := copyDB.Collection("users").CountDocuments(ctx, bson.D{}); err != nil || n != 1200 {
+		t.Fatalf("restored %d users into the remapped database (%v), want 1200", n, err)
+	}
+	if n, _ := copyDB.Collection("users").CountDocuments(ctx, bson.D{{Key: "password", Value: bson.D{{Key: "$exists", Value: true}}}}); n != 0 {
+		t.Errorf("%d users restored with a password hash the backup left out", n)
+	}
+}
@@ -0,0 +1,25 @@
+module technonext/todo-app/cmd/backup
+
+go 1.24.0
+
+toolchain go1.24.9
+
+require (
+	github.com/technonext/todo-app/proto v0.0.0
+	go.mongodb.org/mongo-driver v1.17.4
+)
+
+require (
+	github.com/golang/snappy v0.0.4 // indirect
+	github.com/klauspost/compress v1.17.9 // indirect
+	github.com/montanaflynn/stats v0.7.1 // indirect
+	github.com/xdg-go/pbkdf2 v1.0.0 // indirect
+	github.com/xdg-go/scram v1.1.2 // indirect
+	github.com/xdg-go/stringprep v1.0.4 // indirect
+	github.com/youmark/pkcs8 v0.0.0-20240726163527-a2c0da244d78 // indirect
+	golang.org/x/crypto v0.40.0 // indirect
+	golang.org/x/sync v0.17.0 // indirect
+	golang.org/x/text v0.29.0 // indirect
+)
+
+replace github.com/technonext/todo-app/proto => ../../proto
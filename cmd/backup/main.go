@@ -0,0 +1,130 @@
+// Command backup exports the services' data for disaster recovery, and
+// restores it.
+//
+//	backup -out ./backup-2026-10-16                  # back up users, tasks, notifications and events
+//	backup -restore ./backup-2026-10-16              # restore into the databases backed up
+//	backup -restore ./backup -remap todo_app=todo_copy
+//
+// It connects to MongoDB from the same MONGO_* environment variables as the
+// services; see package mongoutil. A backup is a directory holding one
+// gzip'd JSON-lines file per collection and a manifest listing their
+// document counts and checksums. Restoring checks the manifest and every
+// file before writing anything, then upserts the documents by _id in
+// batches.
+//
+// The users' password hashes are left out unless -include-password-hashes
+// is given, so a backup is safe to move around. Users restored from such a
+// backup cannot log in until their passwords are set again, unless they
+// still exist in the database restored into.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/technonext/todo-app/proto/mongoutil"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// stringList is a flag that may be given more than once.
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// run parses args and backs up or restores, returning the exit status: 1
+// when anything failed.
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("backup", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var (
+		out         = flags.String("out", "", "directory to write a backup to")
+		from        = flags.String("restore", "", "directory of a backup to restore")
+		collections = flags.String("collections", "users,tasks,notifications,events", "collections to back up, comma-separated")
+		passwords   = flags.Bool("include-password-hashes", false, "keep the users' password hashes in the backup")
+		batchSize   = flags.Int("batch", 500, "documents written per round-trip when restoring")
+		remap       stringList
+	)
+	flags.Var(&remap, "remap", "restore the collections of database from into to, as from=to; may be repeated")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if (*out == "") == (*from == "") {
+		fmt.Fprintln(stderr, "give exactly one of -out and -restore")
+		return 2
+	}
+	if *batchSize < 1 {
+		fmt.Fprintln(stderr, "-batch must be at least 1")
+		return 2
+	}
+	databases, err := parseRemap(remap)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	cfg, err := mongoutil.ConfigFromEnv()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	client, err := mongoutil.Connect(ctx, cfg)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	defer client.Disconnect(context.Background())
+
+	start := time.Now()
+	if *out != "" {
+		var names []string
+		for _, name := range strings.Split(*collections, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+		m, err := backup(ctx, client.Database(cfg.DatabaseName()), *out, backupOptions{
+			collections:           names,
+			includePasswordHashes: *passwords,
+			now:                   start,
+		})
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		for _, c := range m.Collections {
+			fmt.Fprintf(stdout, "%s.%s: %d documents\n", c.Database, c.Name, c.Documents)
+		}
+		fmt.Fprintf(stdout, "Backed up to %s in %v\n", *out, time.Since(start).Round(time.Millisecond))
+		return 0
+	}
+
+	results, err := restore(ctx, client, *from, restoreOptions{remap: databases, batchSize: *batchSize})
+	for _, r := range results {
+		fmt.Fprintf(stdout, "%s.%s: %d inserted, %d updated\n", r.Target, r.Name, r.Inserted, r.Updated)
+		if len(r.Omitted) > 0 {
+			fmt.Fprintf(stderr, "%s.%s was backed up without %s\n", r.Target, r.Name, strings.Join(r.Omitted, ", "))
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "Restored from %s in %v\n", *from, time.Since(start).Round(time.Millisecond))
+	return 0
+}
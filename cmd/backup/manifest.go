@@ -0,0 +1,136 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	manifestFile = "manifest.json"
+	// formatVersion is bumped whenever the layout of a backup changes, so
+	// restore refuses backups it does not understand
+	formatVersion = 1
+)
+
+// manifest describes a backup. It is written last, so a backup cut short has
+// none and cannot be restored.
+type manifest struct {
+	Version     int                `json:"version"`
+	CreatedAt   string             `json:"created_at"`
+	Collections []collectionBackup `json:"collections"`
+}
+
+// collectionBackup is one collection's file: its documents as canonical
+// extended JSON, one per line, gzip'd.
+type collectionBackup struct {
+	Database  string `json:"database"`
+	Name      string `json:"name"`
+	File      string `json:"file"`
+	Documents int64  `json:"documents"`
+	// Of the gzip'd file
+	SHA256 string `json:"sha256"`
+	// Fields left out of every document, such as the users' password hashes
+	Omitted []string `json:"omitted,omitempty"`
+}
+
+// omittedFields are left out of a backup unless -include-password-hashes is
+// given, by collection.
+var omittedFields = map[string][]string{
+	"users": {"password"},
+}
+
+// readManifest reads the manifest of the backup in dir and checks that it
+// is a backup this version can restore, with every file intact, before
+// anything is written.
+func readManifest(dir string) (*manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, manifestFile))
+	if err != nil {
+		return nil, fmt.Errorf("reading manifest: %w", err)
+	}
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	if err := m.validate(); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+
+	for _, c := range m.Collections {
+		sum, err := fileSHA256(filepath.Join(dir, c.File))
+		if err != nil {
+			return nil, err
+		}
+		if sum != c.SHA256 {
+			return nil, fmt.Errorf("%s is corrupt: its checksum does not match the manifest", c.File)
+		}
+	}
+	return &m, nil
+}
+
+// validate checks the manifest itself, without looking at the files.
+func (m *manifest) validate() error {
+	if m.Version > formatVersion {
+		return fmt.Errorf("version %d was written by a newer backup tool; this one reads up to %d", m.Version, formatVersion)
+	}
+	if m.Version < 1 {
+		return fmt.Errorf("unknown version %d", m.Version)
+	}
+	if len(m.Collections) == 0 {
+		return fmt.Errorf("no collections")
+	}
+	seen := make(map[string]bool)
+	for _, c := range m.Collections {
+		if c.Database == "" || c.Name == "" {
+			return fmt.Errorf("collection without a database or name")
+		}
+		key := c.Database + "." + c.Name
+		if seen[key] {
+			return fmt.Errorf("%s is listed twice", key)
+		}
+		seen[key] = true
+		// Files are named by the backup, never given paths
+		if c.File == "" || c.File != filepath.Base(c.File) || strings.HasPrefix(c.File, ".") {
+			return fmt.Errorf("%s has invalid file %q", key, c.File)
+		}
+		if c.Documents < 0 || len(c.SHA256) != sha256.Size*2 {
+			return fmt.Errorf("%s has an invalid document count or checksum", key)
+		}
+	}
+	return nil
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("reading %s: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// parseRemap parses -remap values, each from=to, into database names to
+// restore into.
+func parseRemap(values []string) (map[string]string, error) {
+	remap := make(map[string]string)
+	for _, v := range values {
+		from, to, ok := strings.Cut(v, "=")
+		if !ok || from == "" || to == "" {
+			return nil, fmt.Errorf("invalid -remap %q: want from=to", v)
+		}
+		if _, dup := remap[from]; dup {
+			return nil, fmt.Errorf("-remap of %s given twice", from)
+		}
+		remap[from] = to
+	}
+	return remap, nil
+}
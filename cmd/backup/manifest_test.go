@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// writeBackup writes files and m, with the checksums m lacks filled in, to a
+// new directory, returning it.
+func writeBackup(t *testing.T, m manifest, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, c := range m.Collections {
+		if c.SHA256 == "" {
+			sum, err := fileSHA256(filepath.Join(dir, c.File))
+			if err != nil {
+				t.Fatal(err)
+			}
+			m.Collections[i].SHA256 = sum
+		}
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, manifestFile), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestReadManifest(t *testing.T) {
+	users := collectionBackup{Database: "todo_app", Name: "users", File: "users.jsonl.gz", Documents: 1}
+	tests := []struct {
+		name    string
+		m       manifest
+		corrupt bool
+		wantErr string
+	}{
+		{"valid", manifest{Version: formatVersion, Collections: []collectionBackup{users}}, false, ""},
+		{"newer version", manifest{Version: formatVersion + 1, Collections: []collectionBackup{users}}, false, "newer backup tool"},
+		{"no version", manifest{Collections: []collectionBackup{users}}, false, "unknown version"},
+		{"no collections", manifest{Version: formatVersion}, false, "no collections"},
+		{"listed twice", manifest{Version: formatVersion, Collections: []collectionBackup{users, users}}, false, "listed twice"},
+		{"path as file", manifest{Version: formatVersion, Collections: []collectionBackup{{Database: "todo_app", Name: "users", File: "../users.jsonl.gz", SHA256: strings.Repeat("0", 64)}}}, false, "invalid file"},
+		{"corrupt file", manifest{Version: formatVersion, Collections: []collectionBackup{users}}, true, "corrupt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeBackup(t, tt.m, map[string]string{"users.jsonl.gz": "backed up"})
+			if tt.corrupt {
+				os.WriteFile(filepath.Join(dir, "users.jsonl.gz"), []byte("changed"), 0o600)
+			}
+			_, err := readManifest(dir)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("readManifest() = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("readManifest() = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseRemap(t *testing.T) {
+	remap, err := parseRemap([]string{"todo_app=todo_copy", "other=another"})
+	if err != nil || remap["todo_app"] != "todo_copy" || remap["other"] != "another" {
+		t.Errorf("parseRemap() = %v, %v", remap, err)
+	}
+	for _, bad := range [][]string{{"todo_app"}, {"=todo_copy"}, {"todo_app="}, {"a=b", "a=c"}} {
+		if _, err := parseRemap(bad); err == nil {
+			t.Errorf("parseRemap(%q) succeeded, want an error", bad)
+		}
+	}
+}
+
+func TestUpsert(t *testing.T) {
+	line := []byte(`{"_id":{"$oid":"65f1c0ffee0000000000000a"},"username":"ana","created_at":{"$date":{"$numberLong":"1700000000000"}}}`)
+
+	model, err := upsert(line, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replace, ok := model.(*mongo.ReplaceOneModel)
+	if !ok || !*replace.Upsert {
+		t.Fatalf("upsert() = %T, want an upserting replacement", model)
+	}
+	if doc := replace.Replacement.(bson.D); len(doc) != 3 {
+		t.Errorf("replacement = %v, want the whole document", doc)
+	}
+
+	model, err = upsert(line, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	update, ok := model.(*mongo.UpdateOneModel)
+	if !ok || !*update.Upsert {
+		t.Fatalf("upsert() = %T, want an upserting update when merging", model)
+	}
+	set := update.Update.(bson.D)[0].Value.(bson.D)
+	if len(set) != 2 || set[0].Key != "username" {
+		t.Errorf("$set = %v, want every field but _id", set)
+	}
+
+	if _, err := upsert([]byte(`{"username":"ana"}`), false); err != errNoID {
+		t.Errorf("upsert() of a document without _id = %v, want errNoID", err)
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// restoreOptions say where and how to restore a backup.
+type restoreOptions struct {
+	// Database names to restore into instead of the backed up ones
+	remap     map[string]string
+	batchSize int
+}
+
+// restored is what restoring one collection did.
+type restored struct {
+	collectionBackup
+	// The database restored into
+	Target            string
+	Inserted, Updated int64
+}
+
+// restore writes the backup in dir back, upserting every document by _id so
+// restoring twice, or into a database that still holds some of the data, is
+// safe. Documents backed up without some fields are merged into the stored
+// ones, so a restore without password hashes keeps the hashes of users who
+// still exist.
+func restore(ctx context.Context, client *mongo.Client, dir string, opts restoreOptions) ([]restored, error) {
+	m, err := readManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []restored
+	for _, c := range m.Collections {
+		r := restored{collectionBackup: c, Target: c.Database}
+		if to, ok := opts.remap[c.Database]; ok {
+			r.Target = to
+		}
+		coll := client.Database(r.Target).Collection(c.Name)
+		if err := restoreCollection(ctx, coll, filepath.Join(dir, c.File), opts.batchSize, &r); err != nil {
+			return results, fmt.Errorf("restoring %s.%s: %w", r.Target, c.Name, err)
+		}
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+// restoreCollection upserts the documents in path into coll, batchSize at a
+// time, counting them in r.
+func restoreCollection(ctx context.Context, coll *mongo.Collection, path string, batchSize int, r *restored) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	lines := bufio.NewReader(zr)
+
+	var read int64
+	batch := make([]mongo.WriteModel, 0, batchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		result, err := coll.BulkWrite(ctx, batch, options.BulkWrite().SetOrdered(false))
+		if err != nil {
+			return err
+		}
+		r.Inserted += result.UpsertedCount
+		r.Updated += result.MatchedCount
+		batch = batch[:0]
+		return nil
+	}
+
+	for {
+		line, err := lines.ReadBytes('\n')
+		if err == io.EOF && len(line) == 0 {
+			break
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+		read++
+		model, err := upsert(line, len(r.Omitted) > 0)
+		if err != nil {
+			return fmt.Errorf("document %d: %w", read, err)
+		}
+		batch = append(batch, model)
+		if len(batch) == batchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+
+	if read != r.Documents {
+		return fmt.Errorf("%s holds %d documents, the manifest says %d", r.File, read, r.Documents)
+	}
+	return nil
+}
+
+// errNoID is returned for a backed up document without an _id, which cannot
+// be upserted.
+var errNoID = errors.New("document has no _id")
+
+// upsert returns the write restoring the document on line: a replacement,
+// or when fields were omitted from the backup, an update of the fields it
+// has.
+func upsert(line []byte, merge bool) (mongo.WriteModel, error) {
+	var doc bson.D
+	if err := bson.UnmarshalExtJSON(line, true, &doc); err != nil {
+		return nil, err
+	}
+	var id interface{}
+	fields := make(bson.D, 0, len(doc))
+	for _, e := range doc {
+		if e.Key == "_id" {
+			id = e.Value
+		} else {
+			fields = append(fields, e)
+		}
+	}
+	if id == nil {
+		return nil, errNoID
+	}
+
+	filter := bson.D{{Key: "_id", Value: id}}
+	if merge {
+		return mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(bson.D{{Key: "$set", Value: fields}}).SetUpsert(true), nil
+	}
+	return mongo.NewReplaceOneModel().SetFilter(filter).SetReplacement(doc).SetUpsert(true), nil
+}
@@ -0,0 +1,238 @@
+// Command devserver runs the whole stack on this machine without MongoDB or
+// any other dependency: the four services with STORAGE_DRIVER=memory and the
+// gateway in front of them, with a demo user to sign in as.
+//
+//	go run ./cmd/devserver
+//	todocli login --email demo@example.com   # password demo-password
+//
+// Every service is a main package in a module of its own, so they cannot be
+// linked into one binary: devserver builds each into a temporary directory
+// and runs them as its children, prefixing their logs with the service's
+// name. Everything they store is lost when devserver stops, on Ctrl-C or once
+// any service exits. Only the RPCs the memory driver covers are served; the
+// rest answer UNSUPPORTED_BY_STORAGE.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// service is one binary of the stack, with its settings.
+type service struct {
+	name string
+	env  []string
+}
+
+// devJWTSecret signs the sessions of the user service for the gateway. It is
+// public, so tokens from devserver are good for nothing else.
+const devJWTSecret = "devserver-jwt-secret-not-for-production"
+
+// readyTimeout bounds how long the stack may take to answer /health/ready.
+const readyTimeout = 30 * time.Second
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run builds and runs the stack until interrupted or until a service exits,
+// returning the exit status.
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("devserver", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var (
+		root     = flags.String("root", ".", "repository checkout to build the services from")
+		port     = flags.String("port", "8080", "port of the gateway")
+		email    = flags.String("demo-email", "demo@example.com", "email of the demo user; empty creates none")
+		password = flags.String("demo-password", "demo-password", "password of the demo user")
+	)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	bin, err := os.MkdirTemp("", "devserver")
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	defer os.RemoveAll(bin)
+
+	stack := []service{
+		{"task-service", []string{"PORT=50051"}},
+		{"user-service", []string{"PORT=50052", "JWT_SECRET=" + devJWTSecret}},
+		{"notification-service", []string{"PORT=50053"}},
+		{"analytics-service", []string{"PORT=50054"}},
+		{"api-gateway", []string{"PORT=" + *port, "JWT_SECRET=" + devJWTSecret}},
+	}
+	for _, svc := range stack {
+		fmt.Fprintf(stdout, "Building %s\n", svc.name)
+		if err := build(ctx, filepath.Join(*root, svc.name), filepath.Join(bin, svc.name), stderr); err != nil {
+			fmt.Fprintf(stderr, "build %s: %v\n", svc.name, err)
+			return 1
+		}
+	}
+
+	// Every service finds the others at the gateway's default addresses
+	common := append(os.Environ(),
+		"STORAGE_DRIVER=memory",
+		"TASK_SERVICE_ADDR=localhost:50051",
+		"USER_SERVICE_ADDR=localhost:50052",
+		"NOTIFICATION_SERVICE_ADDR=localhost:50053",
+		"ANALYTICS_SERVICE_ADDR=localhost:50054",
+		"METRICS_PORT=",
+	)
+	exited := make(chan error, len(stack))
+	var running []*exec.Cmd
+	defer func() {
+		for _, cmd := range running {
+			cmd.Process.Signal(os.Interrupt)
+		}
+		for range running {
+			<-exited
+		}
+	}()
+	out := &lockedWriter{w: stdout}
+	for _, svc := range stack {
+		cmd := exec.Command(filepath.Join(bin, svc.name))
+		cmd.Env = append(append([]string(nil), common...), svc.env...)
+		logs := prefixed(out, svc.name)
+		cmd.Stdout, cmd.Stderr = logs, logs
+		if err := cmd.Start(); err != nil {
+			fmt.Fprintf(stderr, "start %s: %v\n", svc.name, err)
+			return 1
+		}
+		running = append(running, cmd)
+		go func(name string) {
+			err := cmd.Wait()
+			logs.Close()
+			exited <- fmt.Errorf("%s exited: %v", name, err)
+		}(svc.name)
+	}
+
+	api := "http://localhost:" + *port
+	if err := waitReady(ctx, api+"/health/ready", exited); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if *email != "" {
+		if err := createUser(ctx, api+"/api/users", *email, *password); err != nil {
+			fmt.Fprintln(stderr, "create demo user:", err)
+			return 1
+		}
+		fmt.Fprintf(stdout, "Demo user %s, password %s\n", *email, *password)
+	}
+	fmt.Fprintf(stdout, "API ready at %s; Ctrl-C stops it\n", api)
+
+	select {
+	case <-ctx.Done():
+		return 0
+	case err := <-exited:
+		// The deferred shutdown waits for this one too
+		exited <- err
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+}
+
+// build compiles the main package in dir into out.
+func build(ctx context.Context, dir, out string, stderr io.Writer) error {
+	cmd := exec.CommandContext(ctx, "go", "build", "-o", out, ".")
+	cmd.Dir = dir
+	cmd.Stdout, cmd.Stderr = stderr, stderr
+	return cmd.Run()
+}
+
+// waitReady polls url until it answers 200, failing early when a service
+// exits or ctx is done.
+func waitReady(ctx context.Context, url string, exited chan error) error {
+	ctx, cancel := context.WithTimeout(ctx, readyTimeout)
+	defer cancel()
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		if resp, err := http.DefaultClient.Do(req); err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("stack not ready after %s: %w", readyTimeout, ctx.Err())
+		case err := <-exited:
+			exited <- err
+			return err
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+}
+
+// createUser signs up the demo user through the gateway.
+func createUser(ctx context.Context, url, email, password string) error {
+	body, err := json.Marshal(map[string]string{"username": "demo", "email": email, "password": password})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return errors.New(resp.Status + ": " + string(bytes.TrimSpace(msg)))
+	}
+	return nil
+}
+
+// lockedWriter lets the services' logs share one writer a line at a time.
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) writeLine(line string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	io.WriteString(l.w, line)
+}
+
+// prefixed returns a writer copying each line written to it to out, after
+// the name in brackets. Closing it flushes a last line without a newline.
+func prefixed(out *lockedWriter, name string) io.WriteCloser {
+	r, w := io.Pipe()
+	go func() {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			out.writeLine(fmt.Sprintf("[%s] %s\n", name, scanner.Text()))
+		}
+		// Drain a line too long to scan, so the service never blocks
+		io.Copy(io.Discard, r)
+	}()
+	return w
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// errNotFound is returned by login for users that do not exist or whose
+// password does not match.
+var errNotFound = errors.New("not found")
+
+// session is a logged-in user.
+type session struct {
+	userID string
+	token  string
+}
+
+// backend is where the seed data is written: the gateway, or the services
+// themselves with --direct.
+type backend interface {
+	createUser(ctx context.Context, u seedUser) error
+	login(ctx context.Context, email, password string) (session, error)
+	deleteUser(ctx context.Context, s session) error
+	createTask(ctx context.Context, s session, t seedTask) (string, error)
+	setTaskStatus(ctx context.Context, s session, taskID, status string) error
+	listTasks(ctx context.Context, s session) error
+	sendNotification(ctx context.Context, s session, n seedNotification) error
+	trackEvents(ctx context.Context, s session, events []trackedEvent) error
+}
+
+// trackedEvent is a seedEvent with the id of the task it is about.
+type trackedEvent struct {
+	seedEvent
+	TaskID string
+	// ID makes the event idempotent, so a rerun does not count it twice
+	ID string
+}
+
+// gatewayBackend writes through the gateway's JSON API.
+type gatewayBackend struct {
+	baseURL string
+	http    *http.Client
+}
+
+func newGatewayBackend(baseURL string) *gatewayBackend {
+	return &gatewayBackend{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// gatewayError is an error status from the gateway.
+type gatewayError struct {
+	status  int
+	Message string `json:"error"`
+	Reason  string `json:"reason"`
+}
+
+func (e *gatewayError) Error() string {
+	if e.Reason != "" {
+		return fmt.Sprintf("%s (%s)", e.Message, e.Reason)
+	}
+	return fmt.Sprintf("%s (HTTP %d)", e.Message, e.status)
+}
+
+func (b *gatewayBackend) do(ctx context.Context, method, path, token string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, b.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := b.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= 400 {
+		gwErr := &gatewayError{status: resp.StatusCode}
+		if json.Unmarshal(data, gwErr) != nil || gwErr.Message == "" {
+			gwErr.Message = http.StatusText(resp.StatusCode)
+		}
+		if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusNotFound {
+			return fmt.Errorf("%w: %v", errNotFound, gwErr)
+		}
+		return gwErr
+	}
+	if out != nil {
+		return json.Unmarshal(data, out)
+	}
+	return nil
+}
+
+func (b *gatewayBackend) createUser(ctx context.Context, u seedUser) error {
+	body := map[string]string{"username": u.Username, "email": u.Email, "password": u.Password}
+	return b.do(ctx, http.MethodPost, "/api/users", "", body, nil)
+}
+
+func (b *gatewayBackend) login(ctx context.Context, email, password string) (session, error) {
+	var resp struct {
+		Token string `json:"token"`
+		User  struct {
+			ID string `json:"id"`
+		} `json:"user"`
+	}
+	body := map[string]string{"email": email, "password": password}
+	if err := b.do(ctx, http.MethodPost, "/api/auth", "", body, &resp); err != nil {
+		return session{}, err
+	}
+	return session{userID: resp.User.ID, token: resp.Token}, nil
+}
+
+func (b *gatewayBackend) deleteUser(ctx context.Context, s session) error {
+	return b.do(ctx, http.MethodDelete, "/api/users/"+url.PathEscape(s.userID), s.token, nil, nil)
+}
+
+func (b *gatewayBackend) createTask(ctx context.Context, s session, t seedTask) (string, error) {
+	body := map[string]interface{}{
+		"title":    t.Title,
+		"user_id":  s.userID,
+		"priority": enumNumber(t.Priority),
+		"labels":   t.Labels,
+	}
+	if t.Description != "" {
+		body["description"] = t.Description
+	}
+	if t.DueDate != "" {
+		body["due_date"] = t.DueDate
+	}
+	if t.EstimatedMinutes > 0 {
+		body["estimated_minutes"] = t.EstimatedMinutes
+	}
+	var resp struct {
+		Task struct {
+			ID string `json:"id"`
+		} `json:"task"`
+	}
+	if err := b.do(ctx, http.MethodPost, "/api/tasks", s.token, body, &resp); err != nil {
+		return "", err
+	}
+	return resp.Task.ID, nil
+}
+
+func (b *gatewayBackend) setTaskStatus(ctx context.Context, s session, taskID, status string) error {
+	return b.do(ctx, http.MethodPut, "/api/tasks/"+url.PathEscape(taskID)+"/status", s.token, map[string]string{"status": status}, nil)
+}
+
+func (b *gatewayBackend) listTasks(ctx context.Context, s session) error {
+	return b.do(ctx, http.MethodGet, "/api/tasks?user_id="+url.QueryEscape(s.userID)+"&pagination.limit=20", s.token, nil, nil)
+}
+
+func (b *gatewayBackend) sendNotification(ctx context.Context, s session, n seedNotification) error {
+	body := map[string]interface{}{"user_id": s.userID, "message": n.Message, "priority": "NOTIFICATION_PRIORITY_" + strings.ToUpper(n.Priority)}
+	return b.do(ctx, http.MethodPost, "/api/notifications", s.token, body, nil)
+}
+
+func (b *gatewayBackend) trackEvents(ctx context.Context, s session, events []trackedEvent) error {
+	batch := make([]map[string]string, len(events))
+	for i, e := range events {
+		batch[i] = map[string]string{
+			"user_id":          s.userID,
+			"event_type":       e.Type,
+			"resource_id":      e.TaskID,
+			"client_event_id":  e.ID,
+			"client_timestamp": e.At.Format(time.RFC3339),
+		}
+	}
+	return b.do(ctx, http.MethodPost, "/api/analytics/events/batch", s.token, map[string]interface{}{"events": batch}, nil)
+}
+
+// enumNumber is the number of a low to urgent priority in TaskPriority.
+func enumNumber(priority string) int {
+	for i, name := range []string{"low", "medium", "high", "urgent"} {
+		if name == priority {
+			return i + 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// Task statuses and priorities by the names the gateway accepts.
+const (
+	statusNotStarted = "not_started"
+	statusInProgress = "in_progress"
+	statusOnHold     = "on_hold"
+	statusCompleted  = "completed"
+)
+
+var (
+	// statusWeights and priorityWeights are the share of tasks with each
+	// status and priority, in percent
+	statusWeights = []weighted{
+		{statusNotStarted, 35},
+		{statusInProgress, 25},
+		{statusOnHold, 10},
+		{statusCompleted, 30},
+	}
+	priorityWeights = []weighted{
+		{"low", 30},
+		{"medium", 40},
+		{"high", 20},
+		{"urgent", 10},
+	}
+
+	taskVerbs = []string{"Write", "Review", "Plan", "Fix", "Call", "Book", "Update", "Prepare", "Clean", "Pay"}
+	taskNouns = []string{"report", "budget", "dentist", "slides", "flights", "invoice", "garden", "newsletter", "tax return", "team offsite"}
+	tags      = []string{"work", "personal", "home", "errands", "health", "finance", "reading", "travel"}
+
+	notificationMessages = []string{
+		"Reminder: %s is due soon",
+		"%s was updated",
+		"You were mentioned on %s",
+		"Weekly digest: %s is still open",
+	}
+)
+
+type weighted struct {
+	value  string
+	weight int
+}
+
+func pick(rnd *rand.Rand, choices []weighted) string {
+	total := 0
+	for _, c := range choices {
+		total += c.weight
+	}
+	n := rnd.Intn(total)
+	for _, c := range choices {
+		if n < c.weight {
+			return c.value
+		}
+		n -= c.weight
+	}
+	return choices[len(choices)-1].value
+}
+
+// seedUser is one generated user with everything created for them.
+type seedUser struct {
+	Username      string
+	Email         string
+	Password      string
+	Tasks         []seedTask
+	Notifications []seedNotification
+	// Events are backdated activity on the user's tasks, tracked on top of
+	// the events the task service tracks itself
+	Events []seedEvent
+}
+
+type seedTask struct {
+	Title            string
+	Description      string
+	DueDate          string
+	Priority         string
+	Labels           []string
+	EstimatedMinutes int32
+	// Status is reached from not_started through the allowed transitions
+	Status string
+}
+
+type seedNotification struct {
+	Message  string
+	Priority string
+}
+
+type seedEvent struct {
+	Type string
+	// Task is the index of the task in seedUser.Tasks the event is about
+	Task int
+	At   time.Time
+}
+
+// generator makes the seed data. Each user is generated from the seed and
+// their index alone, so a run creates the same data whatever order its
+// workers take the users in, and --wipe finds the users again.
+type generator struct {
+	seed   int64
+	marker string
+	// base is the time due dates and events are relative to
+	base          time.Time
+	tasks         int
+	notifications int
+}
+
+// credentials are the user's deterministic login.
+func (g *generator) credentials(i int) (username, email, password string) {
+	username = fmt.Sprintf("%s-user-%04d", g.marker, i)
+	return username, username + "@seed.example.com", fmt.Sprintf("%s-password-%d-%04d", g.marker, g.seed, i)
+}
+
+func (g *generator) user(i int) seedUser {
+	rnd := rand.New(rand.NewSource(g.seed*1_000_003 + int64(i)))
+	var u seedUser
+	u.Username, u.Email, u.Password = g.credentials(i)
+
+	for t := 0; t < g.tasks; t++ {
+		task := g.task(rnd)
+		u.Tasks = append(u.Tasks, task)
+
+		// Activity over the past week, which is as late as analytics accepts
+		// events
+		created := g.base.Add(-time.Duration(rnd.Intn(7*24)) * time.Hour)
+		u.Events = append(u.Events, seedEvent{Type: "task.created", Task: t, At: created})
+		if task.Status == statusCompleted {
+			completed := created.Add(time.Duration(rnd.Int63n(int64(g.base.Sub(created)) + 1)))
+			u.Events = append(u.Events, seedEvent{Type: "task.completed", Task: t, At: completed})
+		}
+	}
+	for n := 0; n < g.notifications; n++ {
+		about := "your tasks"
+		if len(u.Tasks) > 0 {
+			about = u.Tasks[rnd.Intn(len(u.Tasks))].Title
+		}
+		priority := "normal"
+		if rnd.Intn(5) == 0 {
+			priority = "high"
+		}
+		u.Notifications = append(u.Notifications, seedNotification{
+			Message:  fmt.Sprintf(notificationMessages[rnd.Intn(len(notificationMessages))], about),
+			Priority: priority,
+		})
+	}
+	return u
+}
+
+func (g *generator) task(rnd *rand.Rand) seedTask {
+	t := seedTask{
+		Title:    taskVerbs[rnd.Intn(len(taskVerbs))] + " " + taskNouns[rnd.Intn(len(taskNouns))],
+		Priority: pick(rnd, priorityWeights),
+		Status:   pick(rnd, statusWeights),
+		// Seeded tasks are labeled with the marker so they can be told apart
+		Labels: []string{g.marker},
+	}
+	if rnd.Intn(2) == 0 {
+		t.Description = "Seeded task: " + t.Title
+	}
+
+	// A fifth have no due date, a fifth are overdue and the rest are due
+	// within a month, on the hour
+	switch n := rnd.Intn(10); {
+	case n < 2:
+	case n < 4:
+		t.DueDate = g.base.Add(-time.Duration(1+rnd.Intn(14*24)) * time.Hour).Truncate(time.Hour).Format(time.RFC3339)
+	default:
+		t.DueDate = g.base.Add(time.Duration(1+rnd.Intn(30*24)) * time.Hour).Truncate(time.Hour).Format(time.RFC3339)
+	}
+
+	for _, i := range rnd.Perm(len(tags))[:rnd.Intn(4)] {
+		t.Labels = append(t.Labels, tags[i])
+	}
+	if rnd.Intn(3) > 0 {
+		t.EstimatedMinutes = int32(15 * (1 + rnd.Intn(16)))
+	}
+	return t
+}
+
+// statusPath is the status updates taking a new task to status, following
+// the task service's state machine.
+func statusPath(status string) []string {
+	switch status {
+	case statusInProgress:
+		return []string{statusInProgress}
+	case statusOnHold:
+		return []string{statusInProgress, statusOnHold}
+	case statusCompleted:
+		return []string{statusCompleted}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testGenerator(seed int64) *generator {
+	return &generator{
+		seed:          seed,
+		marker:        "seed",
+		base:          time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC),
+		tasks:         50,
+		notifications: 5,
+	}
+}
+
+func TestGeneratorIsDeterministic(t *testing.T) {
+	a, b := testGenerator(7), testGenerator(7)
+	// Workers take users in any order, so user 3 must not depend on the
+	// users generated before it
+	for _, i := range []int{0, 1, 2} {
+		a.user(i)
+	}
+	for i := 3; i < 6; i++ {
+		if got, want := a.user(i), b.user(i); !reflect.DeepEqual(got, want) {
+			t.Fatalf("user %d differs between runs with the same seed:\n%+v\n%+v", i, got, want)
+		}
+	}
+
+	if reflect.DeepEqual(testGenerator(7).user(0).Tasks, testGenerator(8).user(0).Tasks) {
+		t.Error("seeds 7 and 8 generated the same tasks")
+	}
+	if reflect.DeepEqual(a.user(0).Tasks, a.user(1).Tasks) {
+		t.Error("users 0 and 1 got the same tasks")
+	}
+
+	username, email, password := a.credentials(3)
+	u := a.user(3)
+	if u.Username != username || u.Email != email || u.Password != password {
+		t.Errorf("user 3 is %s/%s/%s, credentials say %s/%s/%s", u.Username, u.Email, u.Password, username, email, password)
+	}
+	if !strings.HasPrefix(username, "seed-") || len(password) < 8 {
+		t.Errorf("credentials %s/%s lack the marker or a valid password", username, password)
+	}
+}
+
+func TestGeneratorDistribution(t *testing.T) {
+	g := testGenerator(1)
+	statuses := map[string]int{}
+	var overdue, undated, completedEvents int
+	for i := 0; i < 20; i++ {
+		u := g.user(i)
+		if len(u.Tasks) != g.tasks || len(u.Notifications) != g.notifications {
+			t.Fatalf("user %d has %d tasks and %d notifications", i, len(u.Tasks), len(u.Notifications))
+		}
+		for _, task := range u.Tasks {
+			statuses[task.Status]++
+			if task.Labels[0] != g.marker {
+				t.Errorf("task %q labeled %v, without the marker first", task.Title, task.Labels)
+			}
+			switch {
+			case task.DueDate == "":
+				undated++
+			case task.DueDate < g.base.Format(time.RFC3339):
+				overdue++
+			}
+		}
+		for _, e := range u.Events {
+			if e.At.After(g.base) || g.base.Sub(e.At) >= 7*24*time.Hour {
+				t.Errorf("event %s at %v is outside the week before %v", e.Type, e.At, g.base)
+			}
+			if e.Type == "task.completed" {
+				completedEvents++
+				if u.Tasks[e.Task].Status != statusCompleted {
+					t.Errorf("completion tracked for a %s task", u.Tasks[e.Task].Status)
+				}
+			}
+		}
+	}
+
+	total := 20 * g.tasks
+	for _, w := range statusWeights {
+		share := 100 * statuses[w.value] / total
+		if share < w.weight-5 || share > w.weight+5 {
+			t.Errorf("%d%% of tasks are %s, want about %d%%", share, w.value, w.weight)
+		}
+	}
+	if completedEvents != statuses[statusCompleted] {
+		t.Errorf("%d completion events for %d completed tasks", completedEvents, statuses[statusCompleted])
+	}
+	if overdue == 0 || undated == 0 {
+		t.Errorf("%d overdue and %d undated tasks, want some of each", overdue, undated)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	var samples []time.Duration
+	for i := 1; i <= 100; i++ {
+		samples = append(samples, time.Duration(i)*time.Millisecond)
+	}
+	for p, want := range map[float64]time.Duration{50: 50 * time.Millisecond, 99: 99 * time.Millisecond, 100: 100 * time.Millisecond, 0: time.Millisecond} {
+		if got := percentile(samples, p); got != want {
+			t.Errorf("p%v = %v, want %v", p, got, want)
+		}
+	}
+	if got := percentile(nil, 50); got != 0 {
+		t.Errorf("p50 of no samples = %v", got)
+	}
+}
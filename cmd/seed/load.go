@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// loadOps are the calls load generation makes, in percent of requests.
+var loadOps = []weighted{
+	{"list_tasks", 70},
+	{"create_task", 20},
+	{"send_notification", 10},
+}
+
+// latencies records how long each kind of call took.
+type latencies struct {
+	mu      sync.Mutex
+	samples map[string][]time.Duration
+	errors  map[string]int
+}
+
+func newLatencies() *latencies {
+	return &latencies{samples: map[string][]time.Duration{}, errors: map[string]int{}}
+}
+
+func (l *latencies) record(op string, took time.Duration, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.samples[op] = append(l.samples[op], took)
+	if err != nil {
+		l.errors[op]++
+	}
+}
+
+// percentile is the nearest-rank p-th percentile of sorted samples.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// write prints a row per call kind with its count, errors and latency
+// percentiles.
+func (l *latencies) write(w io.Writer) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	ops := make([]string, 0, len(l.samples))
+	for op := range l.samples {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "CALL\tCOUNT\tERRORS\tP50\tP90\tP99\tMAX")
+	for _, op := range ops {
+		sorted := append([]time.Duration(nil), l.samples[op]...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%v\t%v\t%v\t%v\n", op, len(sorted), l.errors[op],
+			percentile(sorted, 50).Round(time.Microsecond), percentile(sorted, 90).Round(time.Microsecond),
+			percentile(sorted, 99).Round(time.Microsecond), sorted[len(sorted)-1].Round(time.Microsecond))
+	}
+	return tw.Flush()
+}
+
+// loadStats counts load generation requests for the periodic report.
+type loadStats struct {
+	sent, dropped, failed int64
+	mu                    sync.Mutex
+}
+
+func (s *loadStats) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return fmt.Sprintf("requests %d, failed %d, dropped %d", s.sent, s.failed, s.dropped)
+}
+
+func (s *loadStats) add(sent, dropped, failed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent += sent
+	s.dropped += dropped
+	s.failed += failed
+}
+
+// generateLoad sends rps requests a second for duration as the seeded users,
+// spread over the workers. Requests due while every worker is busy are
+// dropped and counted, so a slow backend shows as dropped requests rather
+// than a lower rate.
+func (r *runner) generateLoad(ctx context.Context, rps float64, duration time.Duration, stats *loadStats, lat *latencies) error {
+	var sessions []session
+	for i := 0; i < r.users; i++ {
+		_, email, password := r.gen.credentials(i)
+		s, err := r.backend.login(ctx, email, password)
+		if err != nil {
+			continue
+		}
+		sessions = append(sessions, s)
+	}
+	if len(sessions) == 0 {
+		return fmt.Errorf("none of the %d users could log in, seed them first", r.users)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+	rnd := rand.New(rand.NewSource(r.gen.seed))
+	jobs := make(chan int64)
+	var wg sync.WaitGroup
+	for w := 0; w < r.workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				r.loadRequest(ctx, job, sessions, lat, stats)
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / rps))
+	defer ticker.Stop()
+	for done := false; !done; {
+		select {
+		case <-ctx.Done():
+			done = true
+		case <-ticker.C:
+			select {
+			case jobs <- rnd.Int63():
+			default:
+				stats.add(0, 1, 0)
+			}
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	return nil
+}
+
+// loadRequest makes one call picked by job, which also seeds what it sends.
+func (r *runner) loadRequest(ctx context.Context, job int64, sessions []session, lat *latencies, stats *loadStats) {
+	rnd := rand.New(rand.NewSource(job))
+	s := sessions[rnd.Intn(len(sessions))]
+	op := pick(rnd, loadOps)
+
+	start := time.Now()
+	var err error
+	switch op {
+	case "list_tasks":
+		err = r.backend.listTasks(ctx, s)
+	case "create_task":
+		_, err = r.backend.createTask(ctx, s, r.gen.task(rnd))
+	case "send_notification":
+		err = r.backend.sendNotification(ctx, s, seedNotification{Message: "Load test notification", Priority: "normal"})
+	}
+	// Calls cut off by the end of the run say nothing about latency
+	if ctx.Err() != nil {
+		return
+	}
+	lat.record(op, time.Since(start), err)
+	var failed int64
+	if err != nil {
+		failed = 1
+	}
+	stats.add(1, 0, failed)
+}
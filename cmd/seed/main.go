@@ -0,0 +1,154 @@
+// Command seed fills a running stack with realistic data, and doubles as a
+// load generator.
+//
+//	seed -users 50 -tasks 40             # create 50 users with 40 tasks each
+//	seed -users 50 -rps 100 -duration 1m # load the stack as those users
+//	seed -users 50 -wipe                 # delete them and all their data
+//
+// It writes through the gateway at TODO_API_URL, or with -direct to the
+// services over gRPC at the addresses the gateway uses, signing calls when
+// REQUIRE_SERVICE_AUTH is set.
+//
+// Users are generated from -seed and their index, with deterministic
+// credentials, so the same flags always create the same data. Their names,
+// emails and task labels carry the -marker, and -wipe finds them again by
+// logging in and deletes them, which erases their tasks, notifications and
+// events in the other services. A user who can already log in is skipped, so
+// seeding again adds nothing.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func getEnv(key, fallback string) string {
+	if value, exists := os.LookupEnv(key); exists {
+		return value
+	}
+	return fallback
+}
+
+// run parses args and seeds, wipes or generates load, returning the exit
+// status: 1 when anything failed.
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("seed", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var (
+		apiURL   = flags.String("api-url", getEnv("TODO_API_URL", "http://localhost:8080"), "gateway URL (TODO_API_URL)")
+		direct   = flags.Bool("direct", false, "write to the services over gRPC instead of through the gateway")
+		users    = flags.Int("users", 10, "users to seed, wipe or generate load as")
+		tasks    = flags.Int("tasks", 20, "tasks per user")
+		notes    = flags.Int("notifications", 3, "notifications per user")
+		seed     = flags.Int64("seed", 1, "seed of the generated data")
+		marker   = flags.String("marker", "seed", "marks seeded users and tasks; lowercase letters, digits and dashes")
+		workers  = flags.Int("workers", 8, "concurrent workers")
+		wipe     = flags.Bool("wipe", false, "delete the seeded users and their data instead of seeding")
+		rps      = flags.Float64("rps", 0, "generate this many requests a second as the seeded users instead of seeding")
+		duration = flags.Duration("duration", 30*time.Second, "how long to generate load for")
+		every    = flags.Duration("progress", 2*time.Second, "how often to report progress")
+	)
+	addrs := serviceAddrs{}
+	flags.StringVar(&addrs.user, "user-addr", getEnv("USER_SERVICE_ADDR", "localhost:50052"), "user service address with -direct")
+	flags.StringVar(&addrs.task, "task-addr", getEnv("TASK_SERVICE_ADDR", "localhost:50051"), "task service address with -direct")
+	flags.StringVar(&addrs.notification, "notification-addr", getEnv("NOTIFICATION_SERVICE_ADDR", "localhost:50053"), "notification service address with -direct")
+	flags.StringVar(&addrs.analytics, "analytics-addr", getEnv("ANALYTICS_SERVICE_ADDR", "localhost:50054"), "analytics service address with -direct")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if err := validMarker(*marker); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	if *users < 0 || *tasks < 0 || *notes < 0 || *workers < 1 || *rps < 0 {
+		fmt.Fprintln(stderr, "-users, -tasks, -notifications and -rps cannot be negative, and -workers must be at least 1")
+		return 2
+	}
+
+	var b backend = newGatewayBackend(*apiURL)
+	if *direct {
+		grpcBackend, err := newDirectBackend(addrs)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		defer grpcBackend.close()
+		b = grpcBackend
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	r := &runner{
+		backend: b,
+		gen: &generator{
+			seed:          *seed,
+			marker:        *marker,
+			base:          time.Now().UTC().Truncate(time.Hour),
+			tasks:         *tasks,
+			notifications: *notes,
+		},
+		users:    *users,
+		workers:  *workers,
+		progress: &progress{},
+		log:      stderr,
+	}
+
+	start := time.Now()
+	done := make(chan struct{})
+	if *rps > 0 {
+		stats := &loadStats{}
+		lat := newLatencies()
+		go report(stderr, stats, *every, done)
+		err := r.generateLoad(ctx, *rps, *duration, stats, lat)
+		close(done)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		elapsed := time.Since(start)
+		fmt.Fprintf(stdout, "%d requests in %v (%.1f/s), %d failed, %d dropped\n",
+			stats.sent, elapsed.Round(time.Millisecond), float64(stats.sent)/elapsed.Seconds(), stats.failed, stats.dropped)
+		if err := lat.write(stdout); err != nil || stats.failed > 0 {
+			return 1
+		}
+		return 0
+	}
+
+	go report(stderr, r.progress, *every, done)
+	if *wipe {
+		r.each(ctx, r.wipeUser)
+		close(done)
+		fmt.Fprintf(stdout, "Deleted %d users in %v, %d were not seeded, %d errors\n",
+			r.progress.users.Load(), time.Since(start).Round(time.Millisecond), r.progress.skipped.Load(), r.progress.errors.Load())
+	} else {
+		r.each(ctx, r.seedUser)
+		close(done)
+		fmt.Fprintf(stdout, "Done in %v: %v\n", time.Since(start).Round(time.Millisecond), r.progress)
+	}
+	if r.progress.errors.Load() > 0 || ctx.Err() != nil {
+		return 1
+	}
+	return 0
+}
+
+// validMarker checks the marker makes valid usernames, emails and labels.
+func validMarker(marker string) error {
+	if marker == "" || len(marker) > 30 {
+		return fmt.Errorf("-marker must be 1 to 30 characters")
+	}
+	for _, c := range marker {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-') {
+			return fmt.Errorf("-marker %q may only hold lowercase letters, digits and dashes", marker)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maxEventBatch is the most events analytics takes in one batch.
+const maxEventBatch = 1000
+
+// progress counts what a run has done so far, for the periodic report.
+type progress struct {
+	users, skipped, tasks, notifications, events, errors atomic.Int64
+}
+
+func (p *progress) String() string {
+	return fmt.Sprintf("users %d (%d already seeded), tasks %d, notifications %d, events %d, errors %d",
+		p.users.Load(), p.skipped.Load(), p.tasks.Load(), p.notifications.Load(), p.events.Load(), p.errors.Load())
+}
+
+// report writes p to w every interval until stop is closed.
+func report(w io.Writer, p fmt.Stringer, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			fmt.Fprintln(w, p)
+		case <-stop:
+			return
+		}
+	}
+}
+
+// runner works through users 0 to users-1 with concurrent workers.
+type runner struct {
+	backend  backend
+	gen      *generator
+	users    int
+	workers  int
+	progress *progress
+	log      io.Writer
+	mu       sync.Mutex
+}
+
+// each calls fn for every user index, from workers goroutines. Errors are
+// counted and logged, and do not stop the other users.
+func (r *runner) each(ctx context.Context, fn func(context.Context, int) error) {
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < r.workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if err := fn(ctx, i); err != nil {
+					r.progress.errors.Add(1)
+					r.mu.Lock()
+					fmt.Fprintf(r.log, "user %d: %v\n", i, err)
+					r.mu.Unlock()
+				}
+			}
+		}()
+	}
+	for i := 0; i < r.users && ctx.Err() == nil; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
+
+// seedUser creates user i with their tasks, notifications and events. A user
+// who can already log in was seeded by an earlier run and is skipped, so
+// running again with the same flags adds nothing.
+func (r *runner) seedUser(ctx context.Context, i int) error {
+	u := r.gen.user(i)
+	if _, err := r.backend.login(ctx, u.Email, u.Password); err == nil {
+		r.progress.skipped.Add(1)
+		return nil
+	} else if !errors.Is(err, errNotFound) {
+		return err
+	}
+	if err := r.backend.createUser(ctx, u); err != nil {
+		return fmt.Errorf("creating %s: %w", u.Username, err)
+	}
+	s, err := r.backend.login(ctx, u.Email, u.Password)
+	if err != nil {
+		return fmt.Errorf("logging in as %s: %w", u.Username, err)
+	}
+	r.progress.users.Add(1)
+
+	taskIDs := make([]string, len(u.Tasks))
+	for t, task := range u.Tasks {
+		id, err := r.backend.createTask(ctx, s, task)
+		if err != nil {
+			return fmt.Errorf("creating task %d of %s: %w", t, u.Username, err)
+		}
+		for _, status := range statusPath(task.Status) {
+			if err := r.backend.setTaskStatus(ctx, s, id, status); err != nil {
+				return fmt.Errorf("moving task %s to %s: %w", id, status, err)
+			}
+		}
+		taskIDs[t] = id
+		r.progress.tasks.Add(1)
+	}
+
+	for _, n := range u.Notifications {
+		if err := r.backend.sendNotification(ctx, s, n); err != nil {
+			return fmt.Errorf("notifying %s: %w", u.Username, err)
+		}
+		r.progress.notifications.Add(1)
+	}
+
+	if len(u.Events) > 0 {
+		events := make([]trackedEvent, len(u.Events))
+		for e, event := range u.Events {
+			events[e] = trackedEvent{
+				seedEvent: event,
+				TaskID:    taskIDs[event.Task],
+				ID:        fmt.Sprintf("%s-%d-%d", r.gen.marker, i, e),
+			}
+		}
+		for len(events) > 0 {
+			batch := events[:min(len(events), maxEventBatch)]
+			if err := r.backend.trackEvents(ctx, s, batch); err != nil {
+				return fmt.Errorf("tracking events of %s: %w", u.Username, err)
+			}
+			r.progress.events.Add(int64(len(batch)))
+			events = events[len(batch):]
+		}
+	}
+	return nil
+}
+
+// wipeUser deletes user i, whose deletion erases their tasks, notifications
+// and events in the other services. Users that were never seeded are
+// skipped.
+func (r *runner) wipeUser(ctx context.Context, i int) error {
+	_, email, password := r.gen.credentials(i)
+	s, err := r.backend.login(ctx, email, password)
+	if errors.Is(err, errNotFound) {
+		r.progress.skipped.Add(1)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := r.backend.deleteUser(ctx, s); err != nil {
+		return fmt.Errorf("deleting %s: %w", email, err)
+	}
+	r.progress.users.Add(1)
+	return nil
+}
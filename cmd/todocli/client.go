@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	defaultAPIURL = "http://localhost:8080"
+	// listPageSize is the page size list --all asks for, the gateway's maximum
+	listPageSize = 100
+)
+
+// apiError is an error response from the gateway.
+type apiError struct {
+	Status  int
+	Message string `json:"error"`
+	Reason  string `json:"reason"`
+}
+
+func (e *apiError) Error() string {
+	if e.Reason != "" {
+		return fmt.Sprintf("%s (%s)", e.Message, e.Reason)
+	}
+	return fmt.Sprintf("%s (HTTP %d)", e.Message, e.Status)
+}
+
+// exitCode is the status todocli exits with after the error, as listed in
+// the package documentation.
+func (e *apiError) exitCode() int {
+	switch e.Status {
+	case http.StatusBadRequest:
+		return 3
+	case http.StatusUnauthorized:
+		return 4
+	case http.StatusForbidden:
+		return 5
+	case http.StatusNotFound:
+		return 6
+	case http.StatusConflict:
+		return 7
+	case http.StatusPreconditionFailed:
+		return 8
+	case http.StatusTooManyRequests:
+		return 9
+	}
+	return 10
+}
+
+// client calls the gateway's JSON API.
+type client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+func newClient(baseURL, token string) *client {
+	return &client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		token:   token,
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// do sends body, when not nil, as JSON and decodes the response into out,
+// when not nil. Error statuses are returned as an *apiError.
+func (c *client) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= 400 {
+		apiErr := &apiError{Status: resp.StatusCode}
+		if json.Unmarshal(data, apiErr) != nil || apiErr.Message == "" {
+			apiErr.Message = strings.TrimSpace(string(data))
+			if apiErr.Message == "" {
+				apiErr.Message = http.StatusText(resp.StatusCode)
+			}
+		}
+		return apiErr
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("decoding the response to %s %s: %w", method, path, err)
+	}
+	return nil
+}
+
+// listResult is what a list endpoint returned. Items are kept as the
+// gateway sent them so JSON output shows every field.
+type listResult struct {
+	Items      []json.RawMessage
+	Total      int64
+	NextCursor string
+}
+
+// list gets a page from a list endpoint whose response holds its items under
+// key. With all set it follows the pages' cursors from the first one and
+// returns every item.
+func (c *client) list(ctx context.Context, path string, query url.Values, key string, all bool) (listResult, error) {
+	var result listResult
+	if all {
+		query.Set("pagination.limit", fmt.Sprint(listPageSize))
+		query.Del("pagination.cursor")
+	}
+	for {
+		var page map[string]json.RawMessage
+		if err := c.do(ctx, http.MethodGet, path, query, nil, &page); err != nil {
+			return listResult{}, err
+		}
+		var items []json.RawMessage
+		var pagination struct {
+			NextCursor string `json:"next_cursor"`
+		}
+		var total int64
+		for name, dst := range map[string]interface{}{key: &items, "pagination": &pagination, "total": &total} {
+			if raw, ok := page[name]; ok {
+				if err := json.Unmarshal(raw, dst); err != nil {
+					return listResult{}, fmt.Errorf("decoding %s from %s: %w", name, path, err)
+				}
+			}
+		}
+		result.Items = append(result.Items, items...)
+		result.Total = total
+		result.NextCursor = pagination.NextCursor
+
+		if !all || result.NextCursor == "" || len(items) == 0 {
+			return result, nil
+		}
+		query.Set("pagination.cursor", result.NextCursor)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// config is what login leaves for later commands.
+type config struct {
+	Token  string `json:"token"`
+	UserID string `json:"user_id"`
+	Email  string `json:"email"`
+}
+
+// defaultConfigPath is todocli/config.json in the user's config directory,
+// or empty when there is none.
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "todocli", "config.json")
+}
+
+// loadConfig reads the config at path; a missing file is an empty config.
+func loadConfig(path string) (config, error) {
+	var cfg config
+	if path == "" {
+		return cfg, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("reading config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// saveConfig writes cfg to path, readable only by the user since it holds
+// the token.
+func saveConfig(path string, cfg config) error {
+	if path == "" {
+		return errors.New("no config file location, pass --config")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o600)
+}
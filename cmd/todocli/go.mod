@@ -0,0 +1,12 @@
+module technonext/todo-app/cmd/todocli
+
+go 1.24.0
+
+toolchain go1.24.9
+
+require github.com/spf13/cobra v1.10.1
+
+require (
+	github.com/inconshreveable/mousetrap v1.1.0 // indirect
+	github.com/spf13/pflag v1.0.9 // indirect
+)
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func newLoginCmd(a *app) *cobra.Command {
+	var email, password string
+	cmd := &cobra.Command{
+		Use:   "login",
+		Short: "Log in and keep the token in the config file",
+		Long: "Log in and keep the token in the config file.\n\n" +
+			"Without --password the password is read from the first line of standard input.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if password == "" {
+				line, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+				if err != nil && line == "" {
+					return errors.New("no password given, pass --password or write it to standard input")
+				}
+				password = strings.TrimRight(line, "\r\n")
+			}
+
+			var resp struct {
+				Token string `json:"token"`
+				User  struct {
+					ID string `json:"id"`
+				} `json:"user"`
+			}
+			body := map[string]string{"email": email, "password": password}
+			if err := newClient(a.apiURL, "").do(cmd.Context(), http.MethodPost, "/api/auth", nil, body, &resp); err != nil {
+				return err
+			}
+			if resp.Token == "" || resp.User.ID == "" {
+				return errors.New("the gateway returned no token")
+			}
+			if err := saveConfig(a.configPath, config{Token: resp.Token, UserID: resp.User.ID, Email: email}); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Logged in as %s (%s)\n", email, resp.User.ID)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&email, "email", "", "account email")
+	cmd.Flags().StringVar(&password, "password", "", "account password")
+	cmd.MarkFlagRequired("email")
+	return cmd
+}
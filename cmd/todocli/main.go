@@ -0,0 +1,58 @@
+// Command todocli manages tasks, notifications and stats through the API
+// gateway.
+//
+//	todocli login --email me@example.com
+//	todocli task list --all -o json
+//	todocli task create --title "Write report" --priority high
+//	todocli task complete <id>
+//
+// The gateway is at TODO_API_URL, http://localhost:8080 by default. login
+// keeps the token and user id in a config file that later commands read.
+//
+// Errors are printed with the API's reason code, and the exit status tells
+// them apart: 1 for errors before or without a response, and for the
+// gateway's answers
+//
+//	3  bad request (400)
+//	4  not logged in or token rejected (401)
+//	5  forbidden (403)
+//	6  not found (404)
+//	7  conflict (409)
+//	8  precondition failed (412)
+//	9  rate limited or over a limit (429)
+//	10 any other error status
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+const (
+	exitOK    = 0
+	exitError = 1
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run executes the command line args and returns the exit status.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	root := newRootCmd()
+	root.SetArgs(args)
+	root.SetIn(stdin)
+	root.SetOut(stdout)
+	root.SetErr(stderr)
+	if err := root.Execute(); err != nil {
+		fmt.Fprintln(stderr, "Error:", err)
+		var apiErr *apiError
+		if errors.As(err, &apiErr) {
+			return apiErr.exitCode()
+		}
+		return exitError
+	}
+	return exitOK
+}
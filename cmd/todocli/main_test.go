@@ -0,0 +1,368 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const stubToken = "stub-token"
+
+// stubGateway answers the routes todocli calls the way the gateway does:
+// proto field names, enum numbers and {"error", "reason"} bodies. It serves
+// five tasks in pages of at most two and records every request.
+type stubGateway struct {
+	mu       sync.Mutex
+	requests []string
+	bodies   []map[string]interface{}
+}
+
+func (g *stubGateway) record(r *http.Request) {
+	var body map[string]interface{}
+	json.NewDecoder(r.Body).Decode(&body)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.requests = append(g.requests, r.Method+" "+r.URL.RequestURI())
+	g.bodies = append(g.bodies, body)
+}
+
+func respond(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func stubTask(i int) map[string]interface{} {
+	return map[string]interface{}{"id": fmt.Sprintf("t%d", i), "title": fmt.Sprintf("Task %d", i), "user_id": "u1", "status": 2, "priority": 3, "labels": []string{"work"}}
+}
+
+func (g *stubGateway) handler() http.Handler {
+	mux := http.NewServeMux()
+	authorized := func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			g.record(r)
+			if r.Header.Get("Authorization") != "Bearer "+stubToken {
+				respond(w, http.StatusUnauthorized, map[string]string{"error": "missing token"})
+				return
+			}
+			next(w, r)
+		}
+	}
+
+	mux.HandleFunc("POST /api/auth", func(w http.ResponseWriter, r *http.Request) {
+		g.record(r)
+		respond(w, http.StatusOK, map[string]interface{}{"token": stubToken, "user": map[string]string{"id": "u1", "email": "alice@example.com"}})
+	})
+	mux.HandleFunc("GET /api/tasks", authorized(func(w http.ResponseWriter, r *http.Request) {
+		const total = 5
+		limit, _ := strconv.Atoi(r.URL.Query().Get("pagination.limit"))
+		if limit <= 0 || limit > 2 {
+			limit = 2
+		}
+		offset, _ := strconv.Atoi(r.URL.Query().Get("pagination.cursor"))
+		tasks := []map[string]interface{}{}
+		for i := offset; i < total && i < offset+limit; i++ {
+			tasks = append(tasks, stubTask(i+1))
+		}
+		page := map[string]interface{}{"total": strconv.Itoa(total), "limit": limit}
+		if next := offset + len(tasks); next < total {
+			page["next_cursor"] = strconv.Itoa(next)
+		}
+		respond(w, http.StatusOK, map[string]interface{}{"tasks": tasks, "total": total, "pagination": page})
+	}))
+	mux.HandleFunc("POST /api/tasks", authorized(func(w http.ResponseWriter, r *http.Request) {
+		respond(w, http.StatusCreated, map[string]interface{}{"task": stubTask(9)})
+	}))
+	mux.HandleFunc("PUT /api/tasks/{id}/status", authorized(func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") != "t1" {
+			respond(w, http.StatusNotFound, map[string]string{"error": "task " + r.PathValue("id") + " not found", "reason": "TASK_NOT_FOUND"})
+			return
+		}
+		task := stubTask(1)
+		task["status"] = 4
+		task["completed"] = true
+		respond(w, http.StatusOK, map[string]interface{}{"task": task})
+	}))
+	mux.HandleFunc("DELETE /api/tasks/{id}", authorized(func(w http.ResponseWriter, r *http.Request) {
+		respond(w, http.StatusOK, map[string]interface{}{})
+	}))
+	mux.HandleFunc("GET /api/notifications", authorized(func(w http.ResponseWriter, r *http.Request) {
+		respond(w, http.StatusOK, map[string]interface{}{
+			"notifications": []map[string]interface{}{{"id": "n1", "message": "Report due", "priority": 2, "created_at": "2026-03-04T11:00:00Z"}},
+			"total":         1,
+			"pagination":    map[string]interface{}{"total": "1", "limit": 20},
+		})
+	}))
+	mux.HandleFunc("GET /api/notifications/{id}", authorized(func(w http.ResponseWriter, r *http.Request) {
+		respond(w, http.StatusOK, map[string]interface{}{"notification": map[string]interface{}{"id": r.PathValue("id"), "message": "Report due", "read": true}})
+	}))
+	mux.HandleFunc("GET /api/analytics/users/{id}/stats", authorized(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("start_date") == "soon" {
+			respond(w, http.StatusBadRequest, map[string]string{"error": "invalid start_date", "reason": "INVALID_START_DATE"})
+			return
+		}
+		respond(w, http.StatusOK, map[string]interface{}{"stats": map[string]interface{}{"total_tasks": 4, "completed_tasks": 3, "productivity_score": 72.5, "current_streak": 2}})
+	}))
+	return mux
+}
+
+// cli runs todocli against a stub gateway found through TODO_API_URL, with a
+// config file of its own.
+type cli struct {
+	gateway *stubGateway
+	config  string
+}
+
+func newCLI(t *testing.T) *cli {
+	g := &stubGateway{}
+	srv := httptest.NewServer(g.handler())
+	t.Cleanup(srv.Close)
+	t.Setenv("TODO_API_URL", srv.URL)
+	return &cli{gateway: g, config: filepath.Join(t.TempDir(), "config.json")}
+}
+
+// newLoggedInCLI is newCLI with the stub's token already saved.
+func newLoggedInCLI(t *testing.T) *cli {
+	c := newCLI(t)
+	if err := saveConfig(c.config, config{Token: stubToken, UserID: "u1"}); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func (c *cli) run(stdin string, args ...string) (code int, stdout, stderr string) {
+	var out, errOut bytes.Buffer
+	code = run(append(args, "--config", c.config), strings.NewReader(stdin), &out, &errOut)
+	return code, out.String(), errOut.String()
+}
+
+func (c *cli) requests() []string {
+	c.gateway.mu.Lock()
+	defer c.gateway.mu.Unlock()
+	return append([]string(nil), c.gateway.requests...)
+}
+
+func TestLoginStoresToken(t *testing.T) {
+	c := newCLI(t)
+	code, stdout, stderr := c.run("hunter2\n", "login", "--email", "alice@example.com")
+	if code != exitOK {
+		t.Fatalf("login exited %d: %s", code, stderr)
+	}
+	if !strings.Contains(stdout, "alice@example.com (u1)") {
+		t.Errorf("login printed %q", stdout)
+	}
+	if got := c.gateway.bodies[0]["password"]; got != "hunter2" {
+		t.Errorf("password sent as %v, want the line from stdin", got)
+	}
+
+	cfg, err := loadConfig(c.config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Token != stubToken || cfg.UserID != "u1" {
+		t.Errorf("config holds %+v", cfg)
+	}
+	info, err := os.Stat(c.config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("config file mode %v, want 0600", perm)
+	}
+
+	if code, _, stderr := c.run("", "task", "list"); code != exitOK {
+		t.Errorf("task list after login exited %d: %s", code, stderr)
+	}
+}
+
+func TestNotLoggedIn(t *testing.T) {
+	c := newCLI(t)
+	code, _, stderr := c.run("", "task", "list")
+	if code != exitError || !strings.Contains(stderr, "todocli login") {
+		t.Errorf("exited %d with %q, want %d telling to log in", code, stderr, exitError)
+	}
+	if reqs := c.requests(); len(reqs) != 0 {
+		t.Errorf("sent %v without a token", reqs)
+	}
+}
+
+func TestTaskListTable(t *testing.T) {
+	c := newLoggedInCLI(t)
+	code, stdout, stderr := c.run("", "task", "list", "--completed", "--limit", "2")
+	if code != exitOK {
+		t.Fatalf("exited %d: %s", code, stderr)
+	}
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "ID") {
+		t.Fatalf("table:\n%s", stdout)
+	}
+	for _, want := range []string{"t1", "Task 1", "in_progress", "high", "work"} {
+		if !strings.Contains(lines[1], want) {
+			t.Errorf("row %q lacks %q", lines[1], want)
+		}
+	}
+	if !strings.Contains(stderr, "--cursor 2") {
+		t.Errorf("stderr %q does not point to the next page", stderr)
+	}
+	if want := "GET /api/tasks?completed=true&pagination.limit=2&user_id=u1"; c.requests()[0] != want {
+		t.Errorf("requested %s, want %s", c.requests()[0], want)
+	}
+}
+
+func TestTaskListAllFollowsCursors(t *testing.T) {
+	c := newLoggedInCLI(t)
+	code, stdout, stderr := c.run("", "task", "list", "--all", "-o", "json")
+	if code != exitOK {
+		t.Fatalf("exited %d: %s", code, stderr)
+	}
+	var out struct {
+		Tasks      []task `json:"tasks"`
+		Total      int    `json:"total"`
+		NextCursor string `json:"next_cursor"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &out); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, stdout)
+	}
+	if len(out.Tasks) != 5 || out.Total != 5 || out.NextCursor != "" {
+		t.Fatalf("got %d tasks of %d, next cursor %q", len(out.Tasks), out.Total, out.NextCursor)
+	}
+	for i, task := range out.Tasks {
+		if want := fmt.Sprintf("t%d", i+1); task.ID != want {
+			t.Errorf("task %d is %s, want %s", i, task.ID, want)
+		}
+	}
+	if reqs := c.requests(); len(reqs) != 3 {
+		t.Errorf("fetched %d pages, want 3: %v", len(reqs), reqs)
+	}
+}
+
+func TestTaskCreate(t *testing.T) {
+	c := newLoggedInCLI(t)
+	code, stdout, stderr := c.run("", "task", "create", "--title", "Write report", "--priority", "High", "--label", "work,q3", "--due", "2026-03-04T12:00:00Z")
+	if code != exitOK {
+		t.Fatalf("exited %d: %s", code, stderr)
+	}
+	if !strings.Contains(stdout, "t9") {
+		t.Errorf("output lacks the new task:\n%s", stdout)
+	}
+	body := c.gateway.bodies[0]
+	want := map[string]interface{}{"title": "Write report", "user_id": "u1", "priority": float64(3), "due_date": "2026-03-04T12:00:00Z"}
+	for k, v := range want {
+		if body[k] != v {
+			t.Errorf("%s sent as %v, want %v", k, body[k], v)
+		}
+	}
+	if labels := fmt.Sprint(body["labels"]); labels != "[work q3]" {
+		t.Errorf("labels sent as %s", labels)
+	}
+
+	if code, _, _ := c.run("", "task", "create", "--title", "x", "--priority", "whenever"); code != exitError {
+		t.Errorf("unknown priority exited %d, want %d", code, exitError)
+	}
+}
+
+func TestTaskCompleteAndDelete(t *testing.T) {
+	c := newLoggedInCLI(t)
+	code, stdout, stderr := c.run("", "task", "complete", "t1")
+	if code != exitOK {
+		t.Fatalf("complete exited %d: %s", code, stderr)
+	}
+	if !strings.Contains(stdout, "completed") {
+		t.Errorf("complete printed:\n%s", stdout)
+	}
+	if got := c.gateway.bodies[0]["status"]; got != "completed" {
+		t.Errorf("status sent as %v", got)
+	}
+
+	if code, _, stderr := c.run("", "task", "delete", "t1"); code != exitOK {
+		t.Fatalf("delete exited %d: %s", code, stderr)
+	}
+	want := []string{"PUT /api/tasks/t1/status", "DELETE /api/tasks/t1"}
+	if reqs := c.requests(); fmt.Sprint(reqs) != fmt.Sprint(want) {
+		t.Errorf("requested %v, want %v", reqs, want)
+	}
+}
+
+func TestAPIErrorsSetExitCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		code     int
+		reason   string
+		loggedIn bool
+	}{
+		{"not found", []string{"task", "complete", "t404"}, 6, "TASK_NOT_FOUND", true},
+		{"bad request", []string{"stats", "--start", "soon"}, 3, "INVALID_START_DATE", true},
+		{"token rejected", []string{"notification", "list"}, 4, "missing token", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newCLI(t)
+			cfg := config{Token: "expired", UserID: "u1"}
+			if tt.loggedIn {
+				cfg.Token = stubToken
+			}
+			if err := saveConfig(c.config, cfg); err != nil {
+				t.Fatal(err)
+			}
+			code, _, stderr := c.run("", tt.args...)
+			if code != tt.code || !strings.Contains(stderr, tt.reason) {
+				t.Errorf("exited %d with %q, want %d with %s", code, stderr, tt.code, tt.reason)
+			}
+		})
+	}
+}
+
+func TestNotificationsAndStats(t *testing.T) {
+	c := newLoggedInCLI(t)
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"notification", "list", "--unread"}, []string{"n1", "high", "Report due"}},
+		{[]string{"notification", "read", "n1"}, []string{"n1", "Read", "true", "Report due"}},
+		{[]string{"stats"}, []string{"Total tasks", "4", "Productivity score", "72.5", "2 days"}},
+	}
+	for _, tt := range tests {
+		code, stdout, stderr := c.run("", tt.args...)
+		if code != exitOK {
+			t.Errorf("%v exited %d: %s", tt.args, code, stderr)
+			continue
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(stdout, want) {
+				t.Errorf("%v output lacks %q:\n%s", tt.args, want, stdout)
+			}
+		}
+	}
+	want := []string{
+		"GET /api/notifications?unread_only=true&user_id=u1",
+		"GET /api/notifications/n1",
+		"GET /api/analytics/users/u1/stats",
+	}
+	if reqs := c.requests(); fmt.Sprint(reqs) != fmt.Sprint(want) {
+		t.Errorf("requested %v, want %v", reqs, want)
+	}
+}
+
+func TestOutputFlag(t *testing.T) {
+	c := newLoggedInCLI(t)
+	if code, _, stderr := c.run("", "stats", "-o", "yaml"); code != exitError || !strings.Contains(stderr, "yaml") {
+		t.Errorf("-o yaml exited %d with %q", code, stderr)
+	}
+	code, stdout, _ := c.run("", "stats", "-o", "json")
+	var out struct {
+		Stats userStats `json:"stats"`
+	}
+	if code != exitOK || json.Unmarshal([]byte(stdout), &out) != nil || out.Stats.TotalTasks != 4 {
+		t.Errorf("-o json exited %d with %q", code, stdout)
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var notificationPriorities = []string{"unspecified", "normal", "high"}
+
+type notification struct {
+	ID        string `json:"id"`
+	Message   string `json:"message"`
+	Read      bool   `json:"read"`
+	CreatedAt string `json:"created_at"`
+	Priority  int    `json:"priority"`
+}
+
+func newNotificationCmd(a *app) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "notification",
+		Aliases: []string{"notifications"},
+		Short:   "List and read notifications",
+	}
+	cmd.AddCommand(
+		newNotificationListCmd(a),
+		newNotificationReadCmd(a),
+	)
+	return cmd
+}
+
+func newNotificationListCmd(a *app) *cobra.Command {
+	var (
+		unread bool
+		limit  int
+		cursor string
+		all    bool
+	)
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List your notifications",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, cfg, err := a.session()
+			if err != nil {
+				return err
+			}
+			query := url.Values{"user_id": {cfg.UserID}}
+			if unread {
+				query.Set("unread_only", "true")
+			}
+			if limit > 0 {
+				query.Set("pagination.limit", fmt.Sprint(limit))
+			}
+			if cursor != "" {
+				query.Set("pagination.cursor", cursor)
+			}
+
+			result, err := c.list(cmd.Context(), "/api/notifications", query, "notifications", all)
+			if err != nil {
+				return err
+			}
+			if a.output == outputJSON {
+				return printList(cmd, "notifications", result)
+			}
+			return printNotifications(cmd, result)
+		},
+	}
+	flags := cmd.Flags()
+	flags.BoolVar(&unread, "unread", false, "only unread notifications")
+	flags.IntVar(&limit, "limit", 0, "notifications per page, the gateway's default when 0")
+	flags.StringVar(&cursor, "cursor", "", "next_cursor of the previous page")
+	flags.BoolVar(&all, "all", false, "list every page")
+	cmd.MarkFlagsMutuallyExclusive("all", "cursor")
+	return cmd
+}
+
+func printNotifications(cmd *cobra.Command, result listResult) error {
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tREAD\tPRIORITY\tCREATED\tMESSAGE")
+	for _, raw := range result.Items {
+		var n notification
+		if err := json.Unmarshal(raw, &n); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s\t%t\t%s\t%s\t%s\n", n.ID, n.Read, enumName(notificationPriorities, n.Priority), n.CreatedAt, n.Message)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return printListFooter(cmd, result)
+}
+
+// The gateway has no route marking a notification read, so read shows one in
+// full.
+func newNotificationReadCmd(a *app) *cobra.Command {
+	return &cobra.Command{
+		Use:   "read <id>",
+		Short: "Show a notification",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, _, err := a.session()
+			if err != nil {
+				return err
+			}
+			var resp struct {
+				Notification json.RawMessage `json:"notification"`
+			}
+			if err := c.do(cmd.Context(), http.MethodGet, "/api/notifications/"+url.PathEscape(args[0]), nil, nil, &resp); err != nil {
+				return err
+			}
+			if a.output == outputJSON {
+				return printJSON(cmd, resp)
+			}
+			var n notification
+			if err := json.Unmarshal(resp.Notification, &n); err != nil {
+				return err
+			}
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintf(w, "ID\t%s\n", n.ID)
+			fmt.Fprintf(w, "Created\t%s\n", n.CreatedAt)
+			fmt.Fprintf(w, "Priority\t%s\n", enumName(notificationPriorities, n.Priority))
+			fmt.Fprintf(w, "Read\t%t\n", n.Read)
+			fmt.Fprintf(w, "Message\t%s\n", n.Message)
+			return w.Flush()
+		},
+	}
+}
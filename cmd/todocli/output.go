@@ -0,0 +1,32 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// printList writes a list result as JSON, with its items under key as the
+// gateway returns them.
+func printList(cmd *cobra.Command, key string, result listResult) error {
+	out := map[string]interface{}{key: result.Items, "total": result.Total}
+	if result.Items == nil {
+		out[key] = []json.RawMessage{}
+	}
+	if result.NextCursor != "" {
+		out["next_cursor"] = result.NextCursor
+	}
+	return printJSON(cmd, out)
+}
+
+// printListFooter tells how to get the next page, on standard error so the
+// table can be piped on its own.
+func printListFooter(cmd *cobra.Command, result listResult) error {
+	if result.NextCursor == "" {
+		return nil
+	}
+	_, err := fmt.Fprintf(cmd.ErrOrStderr(), "%d of %d shown, next page with --cursor %s or all with --all\n",
+		len(result.Items), result.Total, result.NextCursor)
+	return err
+}
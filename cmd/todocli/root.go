@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+// app holds the global flags the commands share.
+type app struct {
+	apiURL     string
+	configPath string
+	output     string
+}
+
+func newRootCmd() *cobra.Command {
+	a := &app{}
+	root := &cobra.Command{
+		Use:           "todocli",
+		Short:         "Manage tasks, notifications and stats through the TODO API gateway",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if a.output != outputTable && a.output != outputJSON {
+				return fmt.Errorf("unknown output %q, use %s or %s", a.output, outputTable, outputJSON)
+			}
+			return nil
+		},
+	}
+
+	apiURL := os.Getenv("TODO_API_URL")
+	if apiURL == "" {
+		apiURL = defaultAPIURL
+	}
+	flags := root.PersistentFlags()
+	flags.StringVar(&a.apiURL, "api-url", apiURL, "gateway URL (TODO_API_URL)")
+	flags.StringVar(&a.configPath, "config", defaultConfigPath(), "config file login writes the token to")
+	flags.StringVarP(&a.output, "output", "o", outputTable, "output format: table or json")
+
+	root.AddCommand(
+		newLoginCmd(a),
+		newTaskCmd(a),
+		newNotificationCmd(a),
+		newStatsCmd(a),
+	)
+	return root
+}
+
+// session is a client for the logged-in user.
+func (a *app) session() (*client, config, error) {
+	cfg, err := loadConfig(a.configPath)
+	if err != nil {
+		return nil, cfg, err
+	}
+	if cfg.Token == "" || cfg.UserID == "" {
+		return nil, cfg, errors.New("not logged in, run todocli login")
+	}
+	return newClient(a.apiURL, cfg.Token), cfg, nil
+}
+
+// printJSON writes v indented to the command's output.
+func printJSON(cmd *cobra.Command, v interface{}) error {
+	enc := json.NewEncoder(cmd.OutOrStdout())
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+type userStats struct {
+	TotalTasks           int      `json:"total_tasks"`
+	CompletedTasks       int      `json:"completed_tasks"`
+	PendingTasks         int      `json:"pending_tasks"`
+	OverdueTasks         int      `json:"overdue_tasks"`
+	ProductivityScore    float64  `json:"productivity_score"`
+	CurrentStreak        int      `json:"current_streak"`
+	LongestStreak        int      `json:"longest_streak"`
+	AvgExtensionsPerTask float64  `json:"avg_extensions_per_task"`
+	Unavailable          []string `json:"unavailable"`
+}
+
+func newStatsCmd(a *app) *cobra.Command {
+	var (
+		start, end string
+		fresh      bool
+	)
+	cmd := &cobra.Command{
+		Use:   "stats",
+		Short: "Show your task statistics",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, cfg, err := a.session()
+			if err != nil {
+				return err
+			}
+			query := url.Values{}
+			if start != "" {
+				query.Set("start_date", start)
+			}
+			if end != "" {
+				query.Set("end_date", end)
+			}
+			if fresh {
+				query.Set("fresh", "true")
+			}
+			var resp struct {
+				Stats      json.RawMessage `json:"stats"`
+				SnapshotAt string          `json:"snapshot_at,omitempty"`
+			}
+			path := "/api/analytics/users/" + url.PathEscape(cfg.UserID) + "/stats"
+			if err := c.do(cmd.Context(), http.MethodGet, path, query, nil, &resp); err != nil {
+				return err
+			}
+			if a.output == outputJSON {
+				return printJSON(cmd, resp)
+			}
+
+			var stats userStats
+			if len(resp.Stats) > 0 {
+				if err := json.Unmarshal(resp.Stats, &stats); err != nil {
+					return err
+				}
+			}
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintf(w, "Total tasks\t%d\n", stats.TotalTasks)
+			fmt.Fprintf(w, "Completed\t%d\n", stats.CompletedTasks)
+			fmt.Fprintf(w, "Pending\t%d\n", stats.PendingTasks)
+			fmt.Fprintf(w, "Overdue\t%d\n", stats.OverdueTasks)
+			fmt.Fprintf(w, "Productivity score\t%.1f\n", stats.ProductivityScore)
+			fmt.Fprintf(w, "Current streak\t%d days\n", stats.CurrentStreak)
+			fmt.Fprintf(w, "Longest streak\t%d days\n", stats.LongestStreak)
+			fmt.Fprintf(w, "Extensions per task\t%.2f\n", stats.AvgExtensionsPerTask)
+			if len(stats.Unavailable) > 0 {
+				fmt.Fprintf(w, "Unavailable\t%v\n", stats.Unavailable)
+			}
+			if resp.SnapshotAt != "" {
+				fmt.Fprintf(w, "As of\t%s\n", resp.SnapshotAt)
+			}
+			return w.Flush()
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVar(&start, "start", "", "start of the range as YYYY-MM-DD")
+	flags.StringVar(&end, "end", "", "end of the range as YYYY-MM-DD")
+	flags.BoolVar(&fresh, "fresh", false, "compute the stats now instead of using the daily snapshot")
+	return cmd
+}
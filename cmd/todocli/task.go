@@ -0,0 +1,256 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// Task statuses and priorities by their enum numbers, as the gateway sends
+// them
+var (
+	taskStatuses   = []string{"unspecified", "not_started", "in_progress", "on_hold", "completed"}
+	taskPriorities = []string{"unspecified", "low", "medium", "high", "urgent"}
+)
+
+type task struct {
+	ID       string   `json:"id"`
+	Title    string   `json:"title"`
+	Status   int      `json:"status"`
+	Priority int      `json:"priority"`
+	DueDate  string   `json:"due_date"`
+	Labels   []string `json:"labels"`
+}
+
+func enumName(names []string, n int) string {
+	if n < 0 || n >= len(names) {
+		return fmt.Sprint(n)
+	}
+	return names[n]
+}
+
+func newTaskCmd(a *app) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "task",
+		Aliases: []string{"tasks"},
+		Short:   "List, create, complete and delete tasks",
+	}
+	cmd.AddCommand(
+		newTaskListCmd(a),
+		newTaskCreateCmd(a),
+		newTaskCompleteCmd(a),
+		newTaskDeleteCmd(a),
+	)
+	return cmd
+}
+
+func newTaskListCmd(a *app) *cobra.Command {
+	var (
+		completed   bool
+		search      string
+		limit       int
+		cursor      string
+		all         bool
+		createdFrom string
+		createdTo   string
+	)
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List your tasks",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, cfg, err := a.session()
+			if err != nil {
+				return err
+			}
+			query := url.Values{"user_id": {cfg.UserID}}
+			if completed {
+				query.Set("completed", "true")
+			}
+			if search != "" {
+				query.Set("query", search)
+			}
+			if createdFrom != "" {
+				query.Set("created_after", createdFrom)
+			}
+			if createdTo != "" {
+				query.Set("created_before", createdTo)
+			}
+			if limit > 0 {
+				query.Set("pagination.limit", fmt.Sprint(limit))
+			}
+			if cursor != "" {
+				query.Set("pagination.cursor", cursor)
+			}
+
+			result, err := c.list(cmd.Context(), "/api/tasks", query, "tasks", all)
+			if err != nil {
+				return err
+			}
+			if a.output == outputJSON {
+				return printList(cmd, "tasks", result)
+			}
+			return printTasks(cmd, result)
+		},
+	}
+	flags := cmd.Flags()
+	flags.BoolVar(&completed, "completed", false, "only completed tasks")
+	flags.StringVar(&search, "query", "", "free-text search, best match first")
+	flags.StringVar(&createdFrom, "created-after", "", "only tasks created at or after this RFC3339 time")
+	flags.StringVar(&createdTo, "created-before", "", "only tasks created before this RFC3339 time")
+	flags.IntVar(&limit, "limit", 0, "tasks per page, the gateway's default when 0")
+	flags.StringVar(&cursor, "cursor", "", "next_cursor of the previous page")
+	flags.BoolVar(&all, "all", false, "list every page")
+	cmd.MarkFlagsMutuallyExclusive("all", "cursor")
+	return cmd
+}
+
+func printTasks(cmd *cobra.Command, result listResult) error {
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTITLE\tSTATUS\tPRIORITY\tDUE\tLABELS")
+	for _, raw := range result.Items {
+		var t task
+		if err := json.Unmarshal(raw, &t); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", t.ID, t.Title, enumName(taskStatuses, t.Status),
+			enumName(taskPriorities, t.Priority), t.DueDate, strings.Join(t.Labels, ","))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return printListFooter(cmd, result)
+}
+
+func newTaskCreateCmd(a *app) *cobra.Command {
+	var (
+		title       string
+		description string
+		dueDate     string
+		priority    string
+		labels      []string
+	)
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create a task",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			body := map[string]interface{}{"title": title}
+			if description != "" {
+				body["description"] = description
+			}
+			if dueDate != "" {
+				body["due_date"] = dueDate
+			}
+			if priority != "" {
+				p := enumNumber(taskPriorities, priority)
+				if p <= 0 {
+					return fmt.Errorf("unknown priority %q, use low, medium, high or urgent", priority)
+				}
+				body["priority"] = p
+			}
+			if len(labels) > 0 {
+				body["labels"] = labels
+			}
+
+			c, cfg, err := a.session()
+			if err != nil {
+				return err
+			}
+			body["user_id"] = cfg.UserID
+			var resp struct {
+				Task                json.RawMessage   `json:"task"`
+				PotentialDuplicates []json.RawMessage `json:"potential_duplicates"`
+			}
+			if err := c.do(cmd.Context(), http.MethodPost, "/api/tasks", nil, body, &resp); err != nil {
+				return err
+			}
+			if a.output == outputJSON {
+				return printJSON(cmd, resp)
+			}
+			if err := printTask(cmd, resp.Task); err != nil {
+				return err
+			}
+			if n := len(resp.PotentialDuplicates); n > 0 {
+				fmt.Fprintf(cmd.OutOrStdout(), "%d existing tasks look similar\n", n)
+			}
+			return nil
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVar(&title, "title", "", "task title")
+	flags.StringVar(&description, "description", "", "task description")
+	flags.StringVar(&dueDate, "due", "", "due date as RFC3339")
+	flags.StringVar(&priority, "priority", "", "low, medium, high or urgent")
+	flags.StringSliceVar(&labels, "label", nil, "label the task; repeat or separate with commas")
+	cmd.MarkFlagRequired("title")
+	return cmd
+}
+
+func newTaskCompleteCmd(a *app) *cobra.Command {
+	return &cobra.Command{
+		Use:   "complete <id>",
+		Short: "Mark a task completed",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, _, err := a.session()
+			if err != nil {
+				return err
+			}
+			var resp struct {
+				Task json.RawMessage `json:"task"`
+			}
+			path := "/api/tasks/" + url.PathEscape(args[0]) + "/status"
+			if err := c.do(cmd.Context(), http.MethodPut, path, nil, map[string]string{"status": "completed"}, &resp); err != nil {
+				return err
+			}
+			if a.output == outputJSON {
+				return printJSON(cmd, resp)
+			}
+			return printTask(cmd, resp.Task)
+		},
+	}
+}
+
+func newTaskDeleteCmd(a *app) *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete <id>",
+		Short: "Move a task to the trash",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, _, err := a.session()
+			if err != nil {
+				return err
+			}
+			if err := c.do(cmd.Context(), http.MethodDelete, "/api/tasks/"+url.PathEscape(args[0]), nil, nil, nil); err != nil {
+				return err
+			}
+			if a.output == outputJSON {
+				return printJSON(cmd, map[string]string{"deleted": args[0]})
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Deleted task %s\n", args[0])
+			return nil
+		},
+	}
+}
+
+// printTask writes one task as a table row.
+func printTask(cmd *cobra.Command, raw json.RawMessage) error {
+	return printTasks(cmd, listResult{Items: []json.RawMessage{raw}})
+}
+
+// enumNumber is the number of the named value, or -1 when there is none.
+func enumNumber(names []string, name string) int {
+	name = strings.ToLower(name)
+	for i, n := range names {
+		if n == name {
+			return i
+		}
+	}
+	return -1
+}
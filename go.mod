@@ -5,3 +5,17 @@ go 1.24.0
 toolchain go1.24.9
 
 replace github.com/technonext/todo-app/proto => ./proto
+
+require github.com/technonext/todo-app/proto v0.0.0
+
+require (
+	github.com/envoyproxy/protoc-gen-validate v1.2.1 // indirect
+	github.com/grpc-ecosystem/grpc-gateway/v2 v2.27.3 // indirect
+	golang.org/x/net v0.42.0 // indirect
+	golang.org/x/sys v0.34.0 // indirect
+	golang.org/x/text v0.29.0 // indirect
+	google.golang.org/genproto/googleapis/api v0.0.0-20250929231259-57b25ae835d4 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20250929231259-57b25ae835d4 // indirect
+	google.golang.org/grpc v1.76.0 // indirect
+	google.golang.org/protobuf v1.36.10 // indirect
+)
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// deliveryChannels are the channels a deliverer exists for, whether or not
+// this deployment configures them.
+var deliveryChannels = map[string]bool{"email": true, "webhook": true}
+
+// Deliverer sends a stored notification over one outbound channel.
+type Deliverer interface {
+	Channel() string
+	Deliver(ctx context.Context, n Notification) error
+	// Preview prepares the delivery of n without sending it, returning what
+	// would be sent
+	Preview(ctx context.Context, n Notification) (string, error)
+}
+
+// deliverersFromEnv returns a deliverer for every channel that is configured.
+// With no SMTP host or webhook URL set, notifications are only stored.
+func deliverersFromEnv(users pb.UserServiceClient) []Deliverer {
+	var deliverers []Deliverer
+
+	if host := os.Getenv("SMTP_HOST"); host != "" {
+		var auth smtp.Auth
+		if username := os.Getenv("SMTP_USERNAME"); username != "" {
+			auth = smtp.PlainAuth("", username, os.Getenv("SMTP_PASSWORD"), host)
+		}
+		deliverers = append(deliverers, &emailDeliverer{
+			addr:  net.JoinHostPort(host, getEnv("SMTP_PORT", "587")),
+			from:  getEnv("SMTP_FROM", "no-reply@todo-app.local"),
+			auth:  auth,
+			users: users,
+		})
+	}
+
+	if url := os.Getenv("NOTIFICATION_WEBHOOK_URL"); url != "" {
+		deliverers = append(deliverers, &webhookDeliverer{
+			url:    url,
+			client: &http.Client{Timeout: 10 * time.Second},
+		})
+	}
+
+	for _, d := range deliverers {
+		log.Printf("Notification delivery channel enabled: %s", d.Channel())
+	}
+	return deliverers
+}
+
+// emailDeliverer sends notifications through an SMTP relay to the address
+// on the user's account.
+type emailDeliverer struct {
+	addr  string
+	from  string
+	auth  smtp.Auth
+	users pb.UserServiceClient
+}
+
+func (e *emailDeliverer) Channel() string { return "email" }
+
+func (e *emailDeliverer) Deliver(ctx context.Context, n Notification) error {
+	to, msg, err := e.message(ctx, n)
+	if err != nil {
+		return err
+	}
+	return smtp.SendMail(e.addr, e.auth, e.from, []string{to}, msg)
+}
+
+func (e *emailDeliverer) Preview(ctx context.Context, n Notification) (string, error) {
+	_, msg, err := e.message(ctx, n)
+	return string(msg), err
+}
+
+// message returns the address on the user's account and the email for n.
+func (e *emailDeliverer) message(ctx context.Context, n Notification) (string, []byte, error) {
+	resp, err := e.users.GetUser(ctx, &pb.GetUserRequest{Id: n.UserID})
+	if err != nil {
+		return "", nil, fmt.Errorf("look up recipient: %w", err)
+	}
+	to := resp.User.GetEmail()
+	if to == "" {
+		return "", nil, fmt.Errorf("user %s has no email address", n.UserID)
+	}
+
+	subject := n.Subject
+	if subject == "" {
+		subject = "Todo notification"
+	}
+	return to, e.compose(to, subject, "text/plain", n.Message), nil
+}
+
+func (e *emailDeliverer) send(to, subject, contentType, body string) error {
+	return smtp.SendMail(e.addr, e.auth, e.from, []string{to}, e.compose(to, subject, contentType, body))
+}
+
+func (e *emailDeliverer) compose(to, subject, contentType, body string) []byte {
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", e.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: %s; charset=UTF-8\r\n\r\n", contentType)
+	msg.WriteString(body)
+	return []byte(msg.String())
+}
+
+// webhookDeliverer POSTs notifications as JSON to a fixed URL.
+type webhookDeliverer struct {
+	url    string
+	client *http.Client
+}
+
+func (h *webhookDeliverer) Channel() string { return "webhook" }
+
+func (h *webhookDeliverer) Deliver(ctx context.Context, n Notification) error {
+	payload, err := h.payload(n)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+	return nil
+}
+
+func (h *webhookDeliverer) Preview(ctx context.Context, n Notification) (string, error) {
+	payload, err := h.payload(n)
+	return fmt.Sprintf("POST %s\n%s", h.url, payload), err
+}
+
+func (h *webhookDeliverer) payload(n Notification) ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"id":          n.publicID(),
+		"user_id":     n.UserID,
+		"message":     n.Message,
+		"occurrences": n.Occurrences,
+		"priority":    n.priority().String(),
+		"created_at":  n.CreatedAt,
+	})
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+	"github.com/technonext/todo-app/proto/tenant"
+)
+
+// Tasks due within this many minutes get a deadline alert
+const deadlineAlertWindowMinutes = 60
+
+// deadlineAlertScheduler warns users an hour before their tasks are due. The
+// task service lists the tasks and records each alert, so a task is alerted
+// once per due date. Replicas share a lease in the scheduler_locks collection
+// and only its holder runs.
+type deadlineAlertScheduler struct {
+	tasks    pb.TaskServiceClient
+	notify   func(context.Context, *pb.NotificationRequest) (*pb.NotificationResponse, error)
+	locks    *mongo.Collection
+	interval time.Duration
+}
+
+// start alerts due tasks now and then every interval.
+func (d *deadlineAlertScheduler) start() {
+	go func() {
+		for {
+			// Tasks are listed for every tenant, and each alerted in its own
+			d.alertDue(tenant.WithID(context.Background(), tenant.All), time.Now())
+			time.Sleep(d.interval)
+		}
+	}()
+}
+
+// alertDue alerts every task due within the window, if this replica holds
+// the lease. A task whose alert fails stays unmarked and is retried on the
+// next run; its collapse key keeps a retry in the same collapse window from
+// notifying twice.
+func (d *deadlineAlertScheduler) alertDue(ctx context.Context, now time.Time) {
+	held, err := d.acquire(ctx, now)
+	if err != nil {
+		log.Printf("Failed to acquire deadline alert lock: %v", err)
+		return
+	}
+	if !held {
+		return
+	}
+
+	resp, err := d.tasks.GetTasksNearDeadline(ctx, &pb.GetTasksNearDeadlineRequest{WithinMinutes: deadlineAlertWindowMinutes})
+	if err != nil {
+		log.Printf("Failed to list tasks near their deadline: %v", err)
+		return
+	}
+	for _, task := range resp.Tasks {
+		if err := d.alert(ctx, task, now); err != nil {
+			log.Printf("Failed to send deadline alert for task %s: %v", task.Id, err)
+		}
+	}
+}
+
+func (d *deadlineAlertScheduler) alert(ctx context.Context, task *pb.Task, now time.Time) error {
+	ctx = tenant.WithID(ctx, task.TenantId)
+	_, err := d.notify(ctx, &pb.NotificationRequest{
+		UserId:      task.UserId,
+		Message:     deadlineAlertMessage(task, now),
+		CollapseKey: "deadline:" + task.Id,
+		Priority:    pb.NotificationPriority_NOTIFICATION_PRIORITY_HIGH,
+		Type:        pb.NotificationType_NOTIFICATION_TYPE_TASK,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = d.tasks.MarkDeadlineAlertSent(ctx, &pb.MarkDeadlineAlertSentRequest{Id: task.Id})
+	return err
+}
+
+func deadlineAlertMessage(task *pb.Task, now time.Time) string {
+	due, err := time.Parse(time.RFC3339, task.DueDate)
+	if err != nil {
+		return fmt.Sprintf("Task %q is due soon", task.Title)
+	}
+	minutes := int(math.Ceil(due.Sub(now).Minutes()))
+	if minutes == 1 {
+		return fmt.Sprintf("Task %q is due in 1 minute", task.Title)
+	}
+	return fmt.Sprintf("Task %q is due in %d minutes", task.Title, minutes)
+}
+
+// acquire takes or renews the scheduler lease until one interval after now.
+// The upsert only matches a lease this replica holds or one that has
+// expired; otherwise it tries to insert the lock again and fails on its _id.
+func (d *deadlineAlertScheduler) acquire(ctx context.Context, now time.Time) (bool, error) {
+	_, err := d.locks.UpdateOne(ctx,
+		bson.M{
+			"_id": "deadline_alerts",
+			"$or": bson.A{
+				bson.M{"owner": instanceID},
+				bson.M{"expires_at": bson.M{"$lt": now}},
+			},
+		},
+		bson.M{"$set": bson.M{"owner": instanceID, "expires_at": now.Add(d.interval)}},
+		options.Update().SetUpsert(true),
+	)
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -0,0 +1,132 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+	"github.com/technonext/todo-app/proto/tenant"
+)
+
+// fakeTaskService implements the deadline calls of the task service over an
+// in-memory task list.
+type fakeTaskService struct {
+	pb.TaskServiceClient
+	mu    sync.Mutex
+	tasks []*pb.Task
+}
+
+func (f *fakeTaskService) GetTasksNearDeadline(ctx context.Context, req *pb.GetTasksNearDeadlineRequest, opts ...grpc.CallOption) (*pb.ListTasksResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := time.Now()
+	resp := &pb.ListTasksResponse{}
+	for _, task := range f.tasks {
+		due, err := time.Parse(time.RFC3339, task.DueDate)
+		if err != nil || task.Completed || task.DeadlineAlertSent {
+			continue
+		}
+		if !due.Before(now) && !due.After(now.Add(time.Duration(req.WithinMinutes)*time.Minute)) {
+			resp.Tasks = append(resp.Tasks, task)
+		}
+	}
+	return resp, nil
+}
+
+func (f *fakeTaskService) MarkDeadlineAlertSent(ctx context.Context, req *pb.MarkDeadlineAlertSentRequest, opts ...grpc.CallOption) (*pb.TaskResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, task := range f.tasks {
+		if task.Id == req.Id {
+			task.DeadlineAlertSent = true
+			return &pb.TaskResponse{Task: task}, nil
+		}
+	}
+	return nil, statusError(codes.NotFound, "TASK_NOT_FOUND", nil, "task %s not found", req.Id)
+}
+
+func TestDeadlineAlerts(t *testing.T) {
+	collection := newTestCollection(t)
+	s := &server{
+		collection:     tenant.Scoped(collection),
+		collapseWindow: time.Minute,
+		deliveries:     newDeliveryQueue(collection, nil, 3),
+	}
+	now := time.Now()
+	tasks := &fakeTaskService{tasks: []*pb.Task{
+		{Id: "soon", UserId: "u1", Title: "Write report", DueDate: now.Add(30 * time.Minute).Format(time.RFC3339)},
+		{Id: "later", UserId: "u1", Title: "Plan trip", DueDate: now.Add(2 * time.Hour).Format(time.RFC3339)},
+	}}
+	d := &deadlineAlertScheduler{
+		tasks:    tasks,
+		notify:   s.SendNotification,
+		locks:    collection.Database().Collection("scheduler_locks"),
+		interval: time.Minute,
+	}
+	ctx := context.Background()
+
+	d.alertDue(ctx, now)
+	// A second run finds the task already alerted
+	d.alertDue(ctx, now)
+
+	resp, err := s.GetNotifications(ctx, &pb.GetNotificationsRequest{UserId: "u1", Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Notifications) != 1 {
+		t.Fatalf("sent %d notifications, want 1", len(resp.Notifications))
+	}
+	n := resp.Notifications[0]
+	if n.Priority != pb.NotificationPriority_NOTIFICATION_PRIORITY_HIGH || n.Occurrences != 1 {
+		t.Errorf("notification %q has priority %v and %d occurrences", n.Message, n.Priority, n.Occurrences)
+	}
+	if want := `Task "Write report" is due in 30 minutes`; n.Message != want {
+		t.Errorf("message = %q, want %q", n.Message, want)
+	}
+	if !tasks.tasks[0].DeadlineAlertSent {
+		t.Error("task due in 30 minutes not marked as alerted")
+	}
+	if tasks.tasks[1].DeadlineAlertSent {
+		t.Error("task due in 2 hours marked as alerted")
+	}
+}
+
+func TestDeadlineAlertLock(t *testing.T) {
+	collection := newTestCollection(t)
+	d := &deadlineAlertScheduler{locks: collection.Database().Collection("scheduler_locks"), interval: time.Minute}
+	ctx := context.Background()
+
+	self := instanceID
+	t.Cleanup(func() { instanceID = self })
+
+	now := time.Now()
+	steps := []struct {
+		name     string
+		instance string
+		at       time.Time
+		want     bool
+	}{
+		{"first run", "a", now, true},
+		{"renewed by its holder", "a", now.Add(30 * time.Second), true},
+		{"held by another replica", "b", now.Add(time.Minute), false},
+		{"taken over after expiry", "b", now.Add(2 * time.Minute), true},
+		{"lost by the old holder", "a", now.Add(2 * time.Minute), false},
+	}
+	for _, step := range steps {
+		instanceID = step.instance
+		got, err := d.acquire(ctx, step.at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != step.want {
+			t.Errorf("%s: acquired = %v, want %v", step.name, got, step.want)
+		}
+	}
+}
@@ -0,0 +1,386 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/grpc/codes"
+
+	"github.com/technonext/todo-app/proto/pagination"
+	pb "github.com/technonext/todo-app/proto/proto"
+	"github.com/technonext/todo-app/proto/tenant"
+)
+
+const (
+	deliveryPending   = "pending"
+	deliveryDelivered = "delivered"
+	deliveryFailed    = "failed"
+
+	deliveryAttemptTimeout = 30 * time.Second
+)
+
+// instanceID identifies this replica as the holder of delivery claims.
+var instanceID = fmt.Sprintf("%s-%d", hostname(), os.Getpid())
+
+func hostname() string {
+	name, err := os.Hostname()
+	if err != nil {
+		return "notification"
+	}
+	return name
+}
+
+// Delivery is the status trail of a notification on one channel.
+type Delivery struct {
+	Channel     string `bson:"channel"`
+	Status      string `bson:"status"`
+	Attempts    int32  `bson:"attempts"`
+	LastError   string `bson:"last_error,omitempty"`
+	CreatedAt   string `bson:"created_at"`
+	UpdatedAt   string `bson:"updated_at"`
+	DeliveredAt string `bson:"delivered_at,omitempty"`
+	// The replica working on a pending delivery, until its claim expires
+	ClaimedBy      string    `bson:"claimed_by,omitempty"`
+	ClaimExpiresAt time.Time `bson:"claim_expires_at,omitempty"`
+}
+
+func (d Delivery) toProto() *pb.Delivery {
+	return &pb.Delivery{
+		Channel:     d.Channel,
+		Status:      d.Status,
+		Attempts:    d.Attempts,
+		LastError:   d.LastError,
+		CreatedAt:   d.CreatedAt,
+		UpdatedAt:   d.UpdatedAt,
+		DeliveredAt: d.DeliveredAt,
+	}
+}
+
+type deliveryJob struct {
+	notificationID primitive.ObjectID
+	channel        string
+}
+
+// deliveryQueue hands stored notifications to the configured deliverers.
+// Every attempt is recorded on the notification document, so a delivery left
+// pending by a crash is picked up again on the next start. Each attempt first
+// claims the delivery, so replicas never work on the same one at once.
+type deliveryQueue struct {
+	collection  *mongo.Collection
+	deliverers  map[string]Deliverer
+	jobs        chan deliveryJob
+	maxAttempts int
+}
+
+func newDeliveryQueue(collection *mongo.Collection, deliverers []Deliverer, maxAttempts int) *deliveryQueue {
+	q := &deliveryQueue{
+		collection:  collection,
+		deliverers:  make(map[string]Deliverer),
+		jobs:        make(chan deliveryJob, 1000),
+		maxAttempts: maxAttempts,
+	}
+	for _, d := range deliverers {
+		q.deliverers[d.Channel()] = d
+	}
+	return q
+}
+
+// pendingDeliveries builds the initial trail for a new notification.
+func (q *deliveryQueue) pendingDeliveries(now string) []Delivery {
+	deliveries := make([]Delivery, 0, len(q.deliverers))
+	for channel := range q.deliverers {
+		deliveries = append(deliveries, Delivery{
+			Channel:   channel,
+			Status:    deliveryPending,
+			CreatedAt: now,
+			UpdatedAt: now,
+		})
+	}
+	return deliveries
+}
+
+// preview returns n as rendered followed by what each configured channel
+// would send for it, or why it could not.
+func (q *deliveryQueue) preview(ctx context.Context, n Notification) string {
+	var b strings.Builder
+	if n.Subject != "" {
+		fmt.Fprintf(&b, "Subject: %s\n\n", n.Subject)
+	}
+	b.WriteString(n.Message)
+
+	channels := make([]string, 0, len(q.deliverers))
+	for channel := range q.deliverers {
+		channels = append(channels, channel)
+	}
+	sort.Strings(channels)
+	for _, channel := range channels {
+		fmt.Fprintf(&b, "\n\n--- %s ---\n", channel)
+		preview, err := q.deliverers[channel].Preview(ctx, n)
+		if err != nil {
+			fmt.Fprintf(&b, "would fail: %v", err)
+			continue
+		}
+		b.WriteString(preview)
+	}
+	return b.String()
+}
+
+func (q *deliveryQueue) enqueueAll(n Notification) {
+	for _, d := range n.Deliveries {
+		if d.Status == deliveryPending {
+			q.enqueue(n.ID, d.Channel)
+		}
+	}
+}
+
+func (q *deliveryQueue) enqueue(id primitive.ObjectID, channel string) {
+	select {
+	case q.jobs <- deliveryJob{notificationID: id, channel: channel}:
+	default:
+		// The delivery stays pending and is retried on the next start
+		log.Printf("Delivery queue full, deferring %s delivery of notification %s", channel, id.Hex())
+	}
+}
+
+func (q *deliveryQueue) start(workers int) {
+	for i := 0; i < workers; i++ {
+		go func() {
+			for job := range q.jobs {
+				q.process(job)
+			}
+		}()
+	}
+}
+
+func (q *deliveryQueue) process(job deliveryJob) {
+	deliverer, ok := q.deliverers[job.channel]
+	if !ok {
+		return
+	}
+
+	backoff := time.Second
+	for attempt := 1; attempt <= q.maxAttempts; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), deliveryAttemptTimeout)
+
+		// The claim lasts through the attempt and the backoff after it
+		claimed, err := q.claim(ctx, job, time.Now().Add(deliveryAttemptTimeout+2*backoff))
+		if err != nil || !claimed {
+			cancel()
+			if err != nil {
+				log.Printf("Failed to claim %s delivery of notification %s: %v", job.channel, job.notificationID.Hex(), err)
+			}
+			return
+		}
+
+		var n Notification
+		if err := q.collection.FindOne(ctx, bson.M{"_id": job.notificationID}).Decode(&n); err != nil {
+			cancel()
+			log.Printf("Failed to load notification %s for delivery: %v", job.notificationID.Hex(), err)
+			return
+		}
+
+		// Deliverers calling other services call them for the notification's
+		// tenant
+		err = deliverer.Deliver(tenant.WithID(ctx, n.TenantID), n)
+		q.recordAttempt(ctx, job, err, attempt == q.maxAttempts)
+		cancel()
+
+		if err == nil {
+			return
+		}
+		log.Printf("Delivery of notification %s via %s failed (attempt %d/%d): %v",
+			job.notificationID.Hex(), job.channel, attempt, q.maxAttempts, err)
+
+		if attempt < q.maxAttempts {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+}
+
+// claim takes the job's delivery for this replica until expires, reporting
+// whether it got it. A delivery can be claimed while it is pending and not
+// claimed by another replica, or that replica's claim has expired.
+func (q *deliveryQueue) claim(ctx context.Context, job deliveryJob, expires time.Time) (bool, error) {
+	now := time.Now()
+	result, err := q.collection.UpdateOne(ctx,
+		bson.M{
+			"_id": job.notificationID,
+			"deliveries": bson.M{"$elemMatch": bson.M{
+				"channel": job.channel,
+				"status":  deliveryPending,
+				"$or": bson.A{
+					bson.M{"claimed_by": instanceID},
+					bson.M{"claim_expires_at": bson.M{"$lt": now}},
+					bson.M{"claim_expires_at": bson.M{"$exists": false}},
+				},
+			}},
+		},
+		bson.M{"$set": bson.M{
+			"deliveries.$.claimed_by":       instanceID,
+			"deliveries.$.claim_expires_at": expires,
+		}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount == 1, nil
+}
+
+func (q *deliveryQueue) recordAttempt(ctx context.Context, job deliveryJob, deliverErr error, final bool) {
+	now := time.Now().Format(time.RFC3339)
+	set := bson.M{"deliveries.$.updated_at": now}
+	if deliverErr == nil {
+		set["deliveries.$.status"] = deliveryDelivered
+		set["deliveries.$.delivered_at"] = now
+		set["deliveries.$.last_error"] = ""
+	} else {
+		set["deliveries.$.last_error"] = deliverErr.Error()
+		if final {
+			set["deliveries.$.status"] = deliveryFailed
+		}
+	}
+
+	_, err := q.collection.UpdateOne(ctx,
+		bson.M{"_id": job.notificationID, "deliveries.channel": job.channel},
+		bson.M{"$set": set, "$inc": bson.M{"deliveries.$.attempts": 1}},
+	)
+	if err != nil {
+		log.Printf("Failed to record delivery attempt for notification %s: %v", job.notificationID.Hex(), err)
+	}
+}
+
+// requeuePending re-enqueues deliveries a previous process left unfinished.
+// Deliveries another replica is still working on are skipped when their
+// attempt claims them.
+func (q *deliveryQueue) requeuePending(ctx context.Context) error {
+	cursor, err := q.collection.Find(ctx, bson.M{"deliveries.status": deliveryPending})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var n Notification
+		if err := cursor.Decode(&n); err != nil {
+			return err
+		}
+		q.enqueueAll(n)
+	}
+	return cursor.Err()
+}
+
+func (s *server) GetNotification(ctx context.Context, req *pb.GetNotificationRequest) (*pb.NotificationResponse, error) {
+	notification, err := s.repository().Get(ctx, req.Id)
+	if err == errNoNotification {
+		return nil, statusError(codes.NotFound, "NOTIFICATION_NOT_FOUND", map[string]string{"notification_id": req.Id}, "notification %s not found", req.Id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.NotificationResponse{Notification: notification.toProto()}, nil
+}
+
+func (s *server) ListFailedDeliveries(ctx context.Context, req *pb.ListFailedDeliveriesRequest) (*pb.ListFailedDeliveriesResponse, error) {
+	match := bson.M{"status": deliveryFailed}
+	if req.Channel != "" {
+		match["channel"] = req.Channel
+	}
+	updatedAt := bson.M{}
+	if req.StartDate != "" {
+		updatedAt["$gte"] = req.StartDate
+	}
+	if req.EndDate != "" {
+		updatedAt["$lte"] = req.EndDate
+	}
+	if len(updatedAt) > 0 {
+		match["updated_at"] = updatedAt
+	}
+	filter := bson.M{"deliveries": bson.M{"$elemMatch": match}}
+
+	page, err := pagination.Resolve(req.Pagination, req.Page, req.Limit, pagination.DefaultLimits)
+	if err != nil {
+		return nil, err
+	}
+	findOptions := page.FindOptions()
+	findOptions.SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	cursor, err := s.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var notifications []*pb.Notification
+	for cursor.Next(ctx) {
+		var notification Notification
+		if err := cursor.Decode(&notification); err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, notification.toProto())
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	count, err := s.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.ListFailedDeliveriesResponse{
+		Notifications: notifications,
+		Total:         int32(count),
+		Pagination:    page.Response(count, len(notifications)),
+	}, nil
+}
+
+func (s *server) RedeliverNotification(ctx context.Context, req *pb.RedeliverNotificationRequest) (*pb.NotificationResponse, error) {
+	filter, err := notificationFilter(req.Id)
+	if err != nil {
+		return nil, err
+	}
+	if !deliveryChannels[req.Channel] {
+		return nil, statusError(codes.InvalidArgument, "INVALID_CHANNEL", map[string]string{"channel": req.Channel}, "channel must be email or webhook, got %q", req.Channel)
+	}
+	if _, ok := s.deliveries.deliverers[req.Channel]; !ok {
+		return nil, statusError(codes.FailedPrecondition, "CHANNEL_NOT_CONFIGURED", map[string]string{"channel": req.Channel}, "delivery channel %q is not configured", req.Channel)
+	}
+
+	filter["deliveries"] = bson.M{"$elemMatch": bson.M{"channel": req.Channel, "status": deliveryFailed}}
+	update := bson.M{
+		"$set": bson.M{
+			"deliveries.$.status":     deliveryPending,
+			"deliveries.$.updated_at": time.Now().Format(time.RFC3339),
+		},
+		"$unset": bson.M{"deliveries.$.claimed_by": "", "deliveries.$.claim_expires_at": ""},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var notification Notification
+	err = s.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&notification)
+	if err == mongo.ErrNoDocuments {
+		if _, getErr := s.GetNotification(ctx, &pb.GetNotificationRequest{Id: req.Id}); getErr != nil {
+			return nil, getErr
+		}
+		return nil, statusError(codes.FailedPrecondition, "NO_FAILED_DELIVERY", map[string]string{"notification_id": req.Id, "channel": req.Channel}, "notification %s has no failed %s delivery", req.Id, req.Channel)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.deliveries.enqueue(notification.ID, req.Channel)
+
+	return &pb.NotificationResponse{Notification: notification.toProto()}, nil
+}
@@ -0,0 +1,86 @@
+//go:build integration
+
+package main
+
+// These tests run against MongoDB. Start one with
+//
+//	docker run --rm -d -p 27017:27017 mongo:5.0
+//
+// and run go test -tags integration ./... ; MONGO_TEST_URI overrides the
+// default mongodb://localhost:27017.
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func newTestCollection(t *testing.T) *mongo.Collection {
+	t.Helper()
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		uri = "mongodb://localhost:27017"
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Ping(ctx, nil); err != nil {
+		t.Skipf("MongoDB not reachable at %s: %v", uri, err)
+	}
+
+	db := client.Database(fmt.Sprintf("notifications_test_%d", time.Now().UnixNano()))
+	t.Cleanup(func() {
+		db.Drop(context.Background())
+		client.Disconnect(context.Background())
+	})
+	return db.Collection("notifications")
+}
+
+func TestDeliveryClaim(t *testing.T) {
+	collection := newTestCollection(t)
+	q := newDeliveryQueue(collection, []Deliverer{stubDeliverer{"email"}}, 3)
+	ctx := context.Background()
+
+	now := time.Now().Format(time.RFC3339)
+	n := Notification{UserID: "u1", Message: "hi", CreatedAt: now, Deliveries: q.pendingDeliveries(now)}
+	result, err := collection.InsertOne(ctx, n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job := deliveryJob{notificationID: result.InsertedID.(primitive.ObjectID), channel: "email"}
+
+	self := instanceID
+	t.Cleanup(func() { instanceID = self })
+
+	steps := []struct {
+		name     string
+		instance string
+		expires  time.Time
+		want     bool
+	}{
+		{"first claim", "a", time.Now().Add(time.Minute), true},
+		{"renewed by its holder", "a", time.Now().Add(time.Minute), true},
+		{"held by another replica", "b", time.Now().Add(time.Minute), false},
+		{"expired", "a", time.Now().Add(-time.Second), true},
+		{"taken over after expiry", "b", time.Now().Add(time.Minute), true},
+	}
+	for _, step := range steps {
+		instanceID = step.instance
+		got, err := q.claim(ctx, job, step.expires)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != step.want {
+			t.Errorf("%s: claimed = %v, want %v", step.name, got, step.want)
+		}
+	}
+}
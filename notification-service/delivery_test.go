@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// stubDeliverer is a configured channel that is never used.
+type stubDeliverer struct{ channel string }
+
+func (d stubDeliverer) Channel() string { return d.channel }
+
+func (d stubDeliverer) Deliver(ctx context.Context, n Notification) error { return nil }
+
+func (d stubDeliverer) Preview(ctx context.Context, n Notification) (string, error) {
+	return d.channel + ": " + n.Message, nil
+}
+
+func TestRedeliverNotificationValidation(t *testing.T) {
+	s := &server{deliveries: newDeliveryQueue(nil, []Deliverer{stubDeliverer{"email"}}, 3)}
+	id := primitive.NewObjectID().Hex()
+
+	tests := []struct {
+		name     string
+		req      *pb.RedeliverNotificationRequest
+		wantCode codes.Code
+		reason   string
+	}{
+		{"invalid id", &pb.RedeliverNotificationRequest{Id: "nope", Channel: "email"}, codes.InvalidArgument, "INVALID_NOTIFICATION_ID"},
+		{"empty channel", &pb.RedeliverNotificationRequest{Id: id}, codes.InvalidArgument, "INVALID_CHANNEL"},
+		{"unknown channel", &pb.RedeliverNotificationRequest{Id: id, Channel: "sms"}, codes.InvalidArgument, "INVALID_CHANNEL"},
+		{"channel not configured", &pb.RedeliverNotificationRequest{Id: id, Channel: "webhook"}, codes.FailedPrecondition, "CHANNEL_NOT_CONFIGURED"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.RedeliverNotification(context.Background(), tt.req)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("code = %v, want %v (%v)", code, tt.wantCode, err)
+			}
+			if reason := errorReason(err); reason != tt.reason {
+				t.Errorf("reason = %q, want %q", reason, tt.reason)
+			}
+		})
+	}
+}
+
+// errorReason returns the ErrorInfo reason attached to err.
+func errorReason(err error) string {
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(interface{ GetReason() string }); ok {
+			return info.GetReason()
+		}
+	}
+	return ""
+}
+
+// failingDeliverer is a configured channel that cannot prepare deliveries.
+type failingDeliverer struct{ stubDeliverer }
+
+func (d failingDeliverer) Preview(ctx context.Context, n Notification) (string, error) {
+	return "", errors.New("user has no email address")
+}
+
+func TestDeliveryPreview(t *testing.T) {
+	q := newDeliveryQueue(nil, []Deliverer{stubDeliverer{"webhook"}, failingDeliverer{stubDeliverer{"email"}}}, 3)
+	got := q.preview(context.Background(), Notification{Subject: "Due soon", Message: "Write report is due"})
+	want := "Subject: Due soon\n\nWrite report is due" +
+		"\n\n--- email ---\nwould fail: user has no email address" +
+		"\n\n--- webhook ---\nwebhook: Write report is due"
+	if got != want {
+		t.Errorf("preview() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"html/template"
+	"log"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/grpc/codes"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+	"github.com/technonext/todo-app/proto/tenant"
+)
+
+type DigestSchedule struct {
+	UserID     string `bson:"user_id"`
+	Frequency  string `bson:"frequency"`
+	SendAtHour int32  `bson:"send_at_hour"`
+	Timezone   string `bson:"timezone"`
+	LastSentAt string `bson:"last_sent_at"`
+	Enabled    bool   `bson:"enabled"`
+}
+
+func (d DigestSchedule) toProto() *pb.DigestSchedule {
+	return &pb.DigestSchedule{
+		UserId:     d.UserID,
+		Frequency:  d.Frequency,
+		SendAtHour: d.SendAtHour,
+		Timezone:   d.Timezone,
+		LastSentAt: d.LastSentAt,
+		Enabled:    d.Enabled,
+	}
+}
+
+// due reports whether the digest should go out at now: during its local send
+// hour, once per day or once per week.
+func (d DigestSchedule) due(now time.Time) bool {
+	loc, err := time.LoadLocation(d.Timezone)
+	if err != nil {
+		return false
+	}
+	local := now.In(loc)
+	if local.Hour() != int(d.SendAtHour) {
+		return false
+	}
+	if d.LastSentAt == "" {
+		return true
+	}
+	last, err := time.Parse(time.RFC3339, d.LastSentAt)
+	if err != nil {
+		return true
+	}
+
+	if d.Frequency == "weekly" {
+		// Anywhere in the send hour a week later
+		return local.Sub(last) >= 7*24*time.Hour-time.Hour
+	}
+	return last.In(loc).Format("2006-01-02") != local.Format("2006-01-02")
+}
+
+// SetDigestSchedule creates or replaces the user's digest schedule.
+func (s *server) SetDigestSchedule(ctx context.Context, req *pb.SetDigestScheduleRequest) (*pb.DigestScheduleResponse, error) {
+	if req.UserId == "" {
+		return nil, statusError(codes.InvalidArgument, "USER_ID_REQUIRED", nil, "user_id is required")
+	}
+	if req.Frequency != "daily" && req.Frequency != "weekly" {
+		return nil, statusError(codes.InvalidArgument, "INVALID_DIGEST_FREQUENCY", map[string]string{"frequency": req.Frequency}, "frequency must be daily or weekly, got %q", req.Frequency)
+	}
+	if req.SendAtHour < 0 || req.SendAtHour > 23 {
+		return nil, statusError(codes.InvalidArgument, "INVALID_SEND_HOUR", map[string]string{"send_at_hour": strconv.Itoa(int(req.SendAtHour))}, "send_at_hour must be between 0 and 23, got %d", req.SendAtHour)
+	}
+	timezone := req.Timezone
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return nil, statusError(codes.InvalidArgument, "INVALID_TIMEZONE", map[string]string{"timezone": req.Timezone}, "invalid timezone %q", req.Timezone)
+	}
+
+	var schedule DigestSchedule
+	err := s.digests.FindOneAndUpdate(ctx,
+		bson.M{"user_id": req.UserId},
+		bson.M{
+			"$set": bson.M{
+				"frequency":    req.Frequency,
+				"send_at_hour": req.SendAtHour,
+				"timezone":     timezone,
+				"enabled":      req.Enabled,
+			},
+			"$setOnInsert": bson.M{"last_sent_at": ""},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&schedule)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.DigestScheduleResponse{Schedule: schedule.toProto()}, nil
+}
+
+func (s *server) DeleteDigestSchedule(ctx context.Context, req *pb.DeleteDigestScheduleRequest) (*pb.DeleteDigestScheduleResponse, error) {
+	result, err := s.digests.DeleteOne(ctx, bson.M{"user_id": req.UserId})
+	if err != nil {
+		return nil, err
+	}
+	if result.DeletedCount == 0 {
+		return nil, statusError(codes.NotFound, "DIGEST_SCHEDULE_NOT_FOUND", map[string]string{"user_id": req.UserId}, "no digest schedule for user %s", req.UserId)
+	}
+	return &pb.DeleteDigestScheduleResponse{Success: true}, nil
+}
+
+var digestTemplate = template.Must(template.New("digest").Parse(`<!DOCTYPE html>
+<html>
+<body>
+<p>Hi {{.Username}},</p>
+<p>Here is your {{.Frequency}} summary:</p>
+<ul>
+<li>{{.Pending}} pending tasks{{if .Overdue}}, {{.Overdue}} of them overdue{{end}}</li>
+<li>{{.Unread}} unread notifications</li>
+</ul>
+</body>
+</html>
+`))
+
+type digestData struct {
+	Username  string
+	Frequency string
+	Pending   int32
+	Overdue   int32
+	Unread    int64
+}
+
+// digestScheduler emails each enabled digest during its send hour. Task
+// counts are read straight from the tasks collection: the task and analytics
+// services both call this service, so calling either back would make a cycle.
+type digestScheduler struct {
+	schedules     *mongo.Collection
+	notifications *mongo.Collection
+	tasks         *mongo.Collection
+	email         *emailDeliverer
+}
+
+// start checks for due digests now and then at the top of every hour.
+func (d *digestScheduler) start() {
+	go func() {
+		for {
+			// Digests are sent for users of every tenant
+			d.sendDue(tenant.WithID(context.Background(), tenant.All), time.Now())
+			next := time.Now().Truncate(time.Hour).Add(time.Hour)
+			time.Sleep(time.Until(next))
+		}
+	}()
+}
+
+// sendDue sends every digest due at now. Schedules are claimed by moving
+// last_sent_at before sending, so replicas never send the same digest twice;
+// a failed send gives the claim back so the next run retries it.
+func (d *digestScheduler) sendDue(ctx context.Context, now time.Time) {
+	cursor, err := d.schedules.Find(ctx, bson.M{"enabled": true})
+	if err != nil {
+		log.Printf("Failed to load digest schedules: %v", err)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var schedule DigestSchedule
+		if err := cursor.Decode(&schedule); err != nil {
+			log.Printf("Failed to decode digest schedule: %v", err)
+			continue
+		}
+		if !schedule.due(now) {
+			continue
+		}
+
+		result, err := d.schedules.UpdateOne(ctx,
+			bson.M{"user_id": schedule.UserID, "last_sent_at": schedule.LastSentAt},
+			bson.M{"$set": bson.M{"last_sent_at": now.Format(time.RFC3339)}})
+		if err != nil {
+			log.Printf("Failed to claim digest for user %s: %v", schedule.UserID, err)
+			continue
+		}
+		if result.ModifiedCount == 0 {
+			continue
+		}
+
+		if err := d.send(ctx, schedule); err != nil {
+			log.Printf("Failed to send digest to user %s: %v", schedule.UserID, err)
+			d.release(ctx, schedule, now)
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		log.Printf("Failed to load digest schedules: %v", err)
+	}
+}
+
+// release restores the last_sent_at the schedule had before it was claimed at
+// now, unless it has changed since.
+func (d *digestScheduler) release(ctx context.Context, schedule DigestSchedule, now time.Time) {
+	_, err := d.schedules.UpdateOne(ctx,
+		bson.M{"user_id": schedule.UserID, "last_sent_at": now.Format(time.RFC3339)},
+		bson.M{"$set": bson.M{"last_sent_at": schedule.LastSentAt}})
+	if err != nil {
+		log.Printf("Failed to release digest claim for user %s: %v", schedule.UserID, err)
+	}
+}
+
+func (d *digestScheduler) send(ctx context.Context, schedule DigestSchedule) error {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	user, err := d.email.users.GetUser(ctx, &pb.GetUserRequest{Id: schedule.UserID})
+	if err != nil {
+		return err
+	}
+	if user.User.GetEmail() == "" {
+		return statusError(codes.FailedPrecondition, "USER_EMAIL_MISSING", map[string]string{"user_id": schedule.UserID}, "user %s has no email address", schedule.UserID)
+	}
+
+	pending, overdue, err := d.pendingTasks(ctx, schedule.UserID, time.Now())
+	if err != nil {
+		return err
+	}
+
+	unread, err := d.notifications.CountDocuments(ctx, bson.M{"user_id": schedule.UserID, "read": false})
+	if err != nil {
+		return err
+	}
+
+	var body bytes.Buffer
+	err = digestTemplate.Execute(&body, digestData{
+		Username:  user.User.GetUsername(),
+		Frequency: schedule.Frequency,
+		Pending:   pending,
+		Overdue:   overdue,
+		Unread:    unread,
+	})
+	if err != nil {
+		return err
+	}
+
+	return d.email.send(user.User.GetEmail(), "Your "+schedule.Frequency+" todo digest", "text/html", body.String())
+}
+
+// pendingTasks counts the user's incomplete tasks outside the trash, and how
+// many of them were due before now.
+func (d *digestScheduler) pendingTasks(ctx context.Context, userID string, now time.Time) (pending, overdue int32, err error) {
+	cursor, err := d.tasks.Aggregate(ctx, bson.A{
+		bson.M{"$match": bson.M{"user_id": userID, "completed": false, "is_deleted": bson.M{"$ne": true}}},
+		// due_date is a date or an RFC3339 string; anything unparseable has
+		// no due date
+		bson.M{"$group": bson.M{
+			"_id":     nil,
+			"pending": bson.M{"$sum": 1},
+			"overdue": bson.M{"$sum": bson.M{"$cond": bson.A{
+				bson.M{"$lt": bson.A{
+					bson.M{"$convert": bson.M{"input": "$due_date", "to": "date", "onError": nil, "onNull": nil}},
+					now,
+				}},
+				1, 0,
+			}}},
+		}},
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var counts struct {
+		Pending int32 `bson:"pending"`
+		Overdue int32 `bson:"overdue"`
+	}
+	if cursor.Next(ctx) {
+		if err := cursor.Decode(&counts); err != nil {
+			return 0, 0, err
+		}
+	}
+	return counts.Pending, counts.Overdue, cursor.Err()
+}
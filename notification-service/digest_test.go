@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDigestScheduleDue(t *testing.T) {
+	// 09:30 in Dhaka (UTC+6)
+	now := time.Date(2024, 5, 6, 3, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		schedule DigestSchedule
+		want     bool
+	}{
+		{"never sent", DigestSchedule{Frequency: "daily", SendAtHour: 9, Timezone: "Asia/Dhaka"}, true},
+		{"other hour", DigestSchedule{Frequency: "daily", SendAtHour: 3, Timezone: "Asia/Dhaka"}, false},
+		{"hour in UTC", DigestSchedule{Frequency: "daily", SendAtHour: 3, Timezone: "UTC"}, true},
+		{"sent yesterday", DigestSchedule{Frequency: "daily", SendAtHour: 9, Timezone: "Asia/Dhaka", LastSentAt: "2024-05-05T03:00:00Z"}, true},
+		{"sent today", DigestSchedule{Frequency: "daily", SendAtHour: 9, Timezone: "Asia/Dhaka", LastSentAt: "2024-05-06T03:00:00Z"}, false},
+		{"weekly after six days", DigestSchedule{Frequency: "weekly", SendAtHour: 9, Timezone: "Asia/Dhaka", LastSentAt: "2024-04-30T03:00:00Z"}, false},
+		{"weekly after a week", DigestSchedule{Frequency: "weekly", SendAtHour: 9, Timezone: "Asia/Dhaka", LastSentAt: "2024-04-29T03:59:00Z"}, true},
+		{"unknown zone", DigestSchedule{Frequency: "daily", SendAtHour: 9, Timezone: "Mars/Base"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.schedule.due(now); got != tt.want {
+				t.Errorf("due() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDigestTemplate(t *testing.T) {
+	tests := []struct {
+		name string
+		data digestData
+		want []string
+		not  []string
+	}{
+		{
+			name: "overdue",
+			data: digestData{Username: "ana", Frequency: "daily", Pending: 5, Overdue: 1, Unread: 3},
+			want: []string{"Hi ana,", "daily summary", "5 pending tasks, 1 of them overdue", "3 unread notifications"},
+		},
+		{
+			name: "nothing overdue",
+			data: digestData{Username: "ana", Frequency: "weekly", Pending: 4},
+			want: []string{"weekly summary", "4 pending tasks</li>"},
+			not:  []string{"overdue"},
+		},
+		{
+			name: "escapes the username",
+			data: digestData{Username: "<b>ana</b>", Frequency: "daily"},
+			want: []string{"Hi &lt;b&gt;ana&lt;/b&gt;,"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body bytes.Buffer
+			if err := digestTemplate.Execute(&body, tt.data); err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(body.String(), want) {
+					t.Errorf("digest missing %q:\n%s", want, body.String())
+				}
+			}
+			for _, not := range tt.not {
+				if strings.Contains(body.String(), not) {
+					t.Errorf("digest contains %q:\n%s", not, body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestEmailDelivererSend(t *testing.T) {
+	addr, received := startSMTPServer(t)
+	e := &emailDeliverer{addr: addr, from: "no-reply@todo-app.local"}
+
+	if err := e.send("ana@example.com", "Your daily todo digest", "text/html", "<p>hi</p>"); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case msg := <-received:
+		for _, want := range []string{
+			"MAIL FROM:<no-reply@todo-app.local>",
+			"RCPT TO:<ana@example.com>",
+			"Subject: Your daily todo digest",
+			"Content-Type: text/html; charset=UTF-8",
+			"<p>hi</p>",
+		} {
+			if !strings.Contains(msg, want) {
+				t.Errorf("message missing %q:\n%s", want, msg)
+			}
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message received")
+	}
+}
+
+// startSMTPServer accepts one SMTP session and sends everything the client
+// wrote on received.
+func startSMTPServer(t *testing.T) (string, <-chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var session strings.Builder
+		r := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+		reply("220 localhost ESMTP")
+		inData := false
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			session.WriteString(line)
+			switch {
+			case inData && line == ".\r\n":
+				inData = false
+				reply("250 OK")
+			case inData:
+			case strings.HasPrefix(line, "EHLO"), strings.HasPrefix(line, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(line, "DATA"):
+				inData = true
+				reply("354 End data with <CR><LF>.<CR><LF>")
+			case strings.HasPrefix(line, "QUIT"):
+				reply("221 Bye")
+				received <- session.String()
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+	return ln.Addr().String(), received
+}
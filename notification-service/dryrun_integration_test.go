@@ -0,0 +1,88 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+	"github.com/technonext/todo-app/proto/tenant"
+)
+
+func TestSendNotificationDryRun(t *testing.T) {
+	collection := newTestCollection(t)
+	db := collection.Database()
+	s := &server{
+		collection:     tenant.Scoped(collection),
+		templates:      tenant.Scoped(db.Collection("notification_templates")),
+		rateLimits:     tenant.Scoped(db.Collection("notification_rate_limits")),
+		rateLimit:      2,
+		collapseWindow: time.Minute,
+		deliveries:     newDeliveryQueue(collection, []Deliverer{stubDeliverer{"webhook"}}, 3),
+	}
+	ctx := context.Background()
+
+	tmpl, err := s.templates.InsertOne(ctx, Template{Name: "due", Subject: "{{.title}} is due", Body: "Finish {{.title}} today"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	templateID := tmpl.InsertedID.(primitive.ObjectID).Hex()
+
+	counts := func() (int64, int64) {
+		t.Helper()
+		notifications, err := collection.CountDocuments(ctx, bson.M{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		windows, err := s.rateLimits.CountDocuments(ctx, bson.M{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return notifications, windows
+	}
+
+	for i := 0; i < 3; i++ {
+		resp, err := s.SendNotification(ctx, &pb.NotificationRequest{
+			UserId:      "u1",
+			TemplateId:  templateID,
+			Variables:   map[string]string{"title": "Write report"},
+			CollapseKey: "due",
+			DryRun:      true,
+		})
+		if err != nil {
+			t.Fatalf("dry run %d: %v", i, err)
+		}
+		if resp.Notification.Id == "" || resp.Notification.Message != "Finish Write report today" {
+			t.Errorf("notification = %+v, want the rendered template under a synthetic id", resp.Notification)
+		}
+		if !strings.Contains(resp.DeliveryPreview, "Subject: Write report is due") || !strings.Contains(resp.DeliveryPreview, "--- webhook ---\nwebhook: Finish Write report today") {
+			t.Errorf("delivery_preview = %q, want the rendered message and the webhook's", resp.DeliveryPreview)
+		}
+	}
+	// Dry runs neither store notifications nor count against the rate limit
+	if notifications, windows := counts(); notifications != 0 || windows != 0 {
+		t.Fatalf("after dry runs: %d notifications and %d rate limit windows, want none", notifications, windows)
+	}
+
+	// A dry run reports the rate limit a real send would hit
+	for i := 0; i < 2; i++ {
+		if _, err := s.SendNotification(ctx, &pb.NotificationRequest{UserId: "u1", Message: "hi"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err = s.SendNotification(ctx, &pb.NotificationRequest{UserId: "u1", Message: "hi", DryRun: true})
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("dry run over the rate limit: code = %v, want ResourceExhausted", status.Code(err))
+	}
+	if notifications, _ := counts(); notifications != 2 {
+		t.Errorf("%d notifications stored, want the 2 real sends", notifications)
+	}
+}
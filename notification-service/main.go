@@ -2,10 +2,12 @@ package main
 
 import (
 	"context"
-	"fmt"
-	"log"
+	"flag"
+	"log/slog"
 	"net"
+	"net/http"
 	"os"
+	"strconv"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -13,147 +15,489 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/codes"
 
+	"github.com/technonext/todo-app/proto/backup"
+	"github.com/technonext/todo-app/proto/buildinfo"
+	"github.com/technonext/todo-app/proto/diagnostics"
+	"github.com/technonext/todo-app/proto/faults"
+	"github.com/technonext/todo-app/proto/grpcmiddleware"
+	"github.com/technonext/todo-app/proto/logging"
+	"github.com/technonext/todo-app/proto/mongoutil"
+	"github.com/technonext/todo-app/proto/pagination"
 	pb "github.com/technonext/todo-app/proto/proto"
+	"github.com/technonext/todo-app/proto/serviceauth"
+	"github.com/technonext/todo-app/proto/tenant"
 )
 
 type server struct {
 	pb.UnimplementedNotificationServiceServer
-	collection *mongo.Collection
+	collection     *tenant.Collection
+	templates      *tenant.Collection
+	digests        *tenant.Collection
+	rateLimits     *tenant.Collection
+	rateLimit      int
+	collapseWindow time.Duration
+	deliveries     *deliveryQueue
+	// Stores the notifications of the core RPCs; nil uses collection and
+	// rateLimits
+	notifications NotificationRepository
 }
 
 type Notification struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty"`
-	UserID    string             `bson:"user_id"`
-	Message   string             `bson:"message"`
-	Read      bool               `bson:"read"`
-	CreatedAt string             `bson:"created_at"`
+	ID             primitive.ObjectID `bson:"_id,omitempty"`
+	UserID         string             `bson:"user_id"`
+	Message        string             `bson:"message"`
+	Read           bool               `bson:"read"`
+	CreatedAt      string             `bson:"created_at"`
+	CollapseKey    string             `bson:"collapse_key,omitempty"`
+	CollapseWindow string             `bson:"collapse_window,omitempty"`
+	Occurrences    int32              `bson:"occurrences"`
+	Deliveries     []Delivery         `bson:"deliveries,omitempty"`
+	TemplateID     string             `bson:"template_id,omitempty"`
+	Subject        string             `bson:"subject,omitempty"`
+	Priority       string             `bson:"priority,omitempty"`
+	Type           string             `bson:"type,omitempty"`
+	// The tenant the notification belongs to, empty for the default tenant
+	TenantID string `bson:"tenant_id,omitempty"`
+	// The id the API shows, set when sent; see notificationid.go
+	PublicID string `bson:"public_id,omitempty"`
+	// The version of the document's layout; see notificationSchema
+	SchemaVersion int `bson:"schema_version,omitempty"`
+}
+
+func (n Notification) toProto() *pb.Notification {
+	occurrences := n.Occurrences
+	if occurrences == 0 {
+		// Documents written before collapsing existed have no counter
+		occurrences = 1
+	}
+	deliveries := make([]*pb.Delivery, 0, len(n.Deliveries))
+	for _, d := range n.Deliveries {
+		deliveries = append(deliveries, d.toProto())
+	}
+	return &pb.Notification{
+		Id:          n.publicID(),
+		UserId:      n.UserID,
+		Message:     n.Message,
+		Read:        n.Read,
+		CreatedAt:   n.CreatedAt,
+		CollapseKey: n.CollapseKey,
+		Occurrences: occurrences,
+		Deliveries:  deliveries,
+		TemplateId:  n.TemplateID,
+		Priority:    n.priority(),
+		Type:        n.notificationType(),
+	}
+}
+
+// notificationType defaults to system, including for documents written
+// before notifications had a type.
+func (n Notification) notificationType() pb.NotificationType {
+	if t := pb.NotificationType(pb.NotificationType_value[n.Type]); t != pb.NotificationType_NOTIFICATION_TYPE_UNSPECIFIED {
+		return t
+	}
+	return pb.NotificationType_NOTIFICATION_TYPE_SYSTEM
+}
+
+// priority defaults to normal, including for documents written before
+// notifications had one.
+func (n Notification) priority() pb.NotificationPriority {
+	if p := pb.NotificationPriority(pb.NotificationPriority_value[n.Priority]); p != pb.NotificationPriority_NOTIFICATION_PRIORITY_UNSPECIFIED {
+		return p
+	}
+	return pb.NotificationPriority_NOTIFICATION_PRIORITY_NORMAL
 }
 
 func (s *server) SendNotification(ctx context.Context, req *pb.NotificationRequest) (*pb.NotificationResponse, error) {
+	checkRateLimit := s.checkRateLimit
+	if req.DryRun {
+		checkRateLimit = s.previewRateLimit
+	}
+	if err := checkRateLimit(ctx, req.UserId); err != nil {
+		return nil, err
+	}
+
 	now := time.Now().Format(time.RFC3339)
 	notification := Notification{
-		UserID:    req.UserId,
-		Message:   req.Message,
-		Read:      false,
-		CreatedAt: now,
+		UserID:      req.UserId,
+		Message:     req.Message,
+		Read:        false,
+		CreatedAt:   now,
+		CollapseKey: req.CollapseKey,
+		Occurrences: 1,
+		TemplateID:  req.TemplateId,
+	}
+	if req.Priority != pb.NotificationPriority_NOTIFICATION_PRIORITY_UNSPECIFIED {
+		notification.Priority = req.Priority.String()
+	}
+	if req.Type != pb.NotificationType_NOTIFICATION_TYPE_UNSPECIFIED {
+		notification.Type = req.Type.String()
 	}
 
-	result, err := s.collection.InsertOne(ctx, notification)
-	if err != nil {
-		log.Printf("Failed to create notification: %v", err)
-		return nil, err
+	if req.TemplateId != "" {
+		subject, message, err := s.renderTemplate(ctx, req.TemplateId, req.Variables)
+		if err != nil {
+			return nil, err
+		}
+		notification.Subject = subject
+		notification.Message = message
+	}
+
+	if req.DryRun {
+		return s.previewNotification(ctx, notification)
+	}
+	if notification.CollapseKey != "" {
+		return s.collapseNotification(ctx, notification)
 	}
 
-	oid, ok := result.InsertedID.(primitive.ObjectID)
-	if !ok {
-		log.Printf("Failed to convert ObjectID")
+	notification.Deliveries = s.deliveries.pendingDeliveries(now)
+
+	var err error
+	for attempt := 0; attempt < publicIDAttempts; attempt++ {
+		notification.PublicID = newNotificationPublicID()
+		if notification.ID, err = s.repository().Insert(ctx, notification); err != errPublicIDTaken {
+			break
+		}
+		logging.FromContext(ctx).Warn("Notification public id taken, retrying", "public_id", notification.PublicID)
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to create notification", "error", err)
 		return nil, err
 	}
+	s.deliveries.enqueueAll(notification)
+
+	return &pb.NotificationResponse{Notification: notification.toProto()}, nil
+}
+
+// previewNotification answers a dry run with n as it would be stored, under
+// an id that never is, and a preview of its deliveries. Nothing is stored or
+// sent, and collapsing is not tried.
+func (s *server) previewNotification(ctx context.Context, n Notification) (*pb.NotificationResponse, error) {
+	n.ID = primitive.NewObjectID()
+	n.PublicID = newNotificationPublicID()
+	n.Deliveries = s.deliveries.pendingDeliveries(n.CreatedAt)
+	logging.FromContext(ctx).Info("Dry-run notification", "user_id", n.UserID, "template_id", n.TemplateID, "channels", len(n.Deliveries))
 
 	return &pb.NotificationResponse{
-		Notification: &pb.Notification{
-			Id:        oid.Hex(),
-			UserId:    notification.UserID,
-			Message:   notification.Message,
-			Read:      notification.Read,
-			CreatedAt: notification.CreatedAt,
-		},
+		Notification:    n.toProto(),
+		DeliveryPreview: s.deliveries.preview(ctx, n),
 	}, nil
 }
 
 func (s *server) GetNotifications(ctx context.Context, req *pb.GetNotificationsRequest) (*pb.GetNotificationsResponse, error) {
-	filter := bson.M{"user_id": req.UserId}
-	if req.UnreadOnly {
-		filter["read"] = false
+	page, err := pagination.Resolve(req.Pagination, req.Page, req.Limit, pagination.DefaultLimits)
+	if err != nil {
+		return nil, err
 	}
 
-	findOptions := options.Find()
-	findOptions.SetLimit(int64(req.Limit))
-	findOptions.SetSkip(int64(req.Page * req.Limit))
-	findOptions.SetSort(bson.D{{"created_at", -1}})
-
-	cursor, err := s.collection.Find(ctx, filter, findOptions)
+	stored, count, err := s.repository().List(ctx, req.UserId, req.UnreadOnly, page)
 	if err != nil {
 		return nil, err
 	}
-	defer cursor.Close(ctx)
-
 	var notifications []*pb.Notification
-	for cursor.Next(ctx) {
-		var notification Notification
-		if err := cursor.Decode(&notification); err != nil {
-			return nil, err
-		}
-		notifications = append(notifications, &pb.Notification{
-			Id:        notification.ID.Hex(),
-			UserId:    notification.UserID,
-			Message:   notification.Message,
-			Read:      notification.Read,
-			CreatedAt: notification.CreatedAt,
-		})
+	for _, notification := range stored {
+		notifications = append(notifications, notification.toProto())
 	}
 
-	if err := cursor.Err(); err != nil {
-		return nil, err
+	return &pb.GetNotificationsResponse{
+		Notifications: notifications,
+		Total:         int32(count),
+		Pagination:    page.Response(count, len(notifications)),
+	}, nil
+}
+
+// DeleteNotificationsByUser deletes everything kept about a user: their
+// notifications with any pending deliveries, their digest schedule and their
+// rate limit windows.
+func (s *server) DeleteNotificationsByUser(ctx context.Context, req *pb.DeleteNotificationsByUserRequest) (*pb.DeleteNotificationsByUserResponse, error) {
+	if req.UserId == "" {
+		return nil, statusError(codes.InvalidArgument, "USER_ID_REQUIRED", nil, "user_id is required")
 	}
+	filter := bson.M{"user_id": req.UserId}
 
-	count, err := s.collection.CountDocuments(ctx, filter)
+	result, err := s.collection.DeleteMany(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
-
-	return &pb.GetNotificationsResponse{
-		Notifications: notifications,
-		Total:         int32(count),
-	}, nil
+	if _, err := s.digests.DeleteMany(ctx, filter); err != nil {
+		return nil, err
+	}
+	if _, err := s.rateLimits.DeleteMany(ctx, filter); err != nil {
+		return nil, err
+	}
+	return &pb.DeleteNotificationsByUserResponse{DeletedCount: int32(result.DeletedCount)}, nil
 }
 
 func main() {
-	// Read the environment variables
-	mongoUser := os.Getenv("MONGO_USERNAME")
-	mongoPass := os.Getenv("MONGO_PASSWORD")
-	mongoHost := os.Getenv("MONGO_HOST")
-	if mongoUser == "" || mongoPass == "" || mongoHost == "" {
-		log.Fatal("Error: MONGO_USERNAME, MONGO_PASSWORD, and MONGO_HOST must be set")
-	}
-	// Build the connection string
-	mongoURI := fmt.Sprintf("mongodb://%s:%s@%s/todo_app?authSource=admin", mongoUser, mongoPass, mongoHost)
-
-	log.Printf("Connecting to MongoDB at %s...", mongoHost)
-	// Connect to MongoDB
-	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI(mongoURI))
+	migrateOnly := flag.Bool("migrate", false, "apply pending data migrations and exit")
+	dryRun := flag.Bool("dry-run", false, "with -migrate, report what the pending migrations would change without applying them")
+	flag.Parse()
+	logging.Setup("notification-service")
+
+	mongoConfig, err := mongoutil.ConfigFromEnv()
 	if err != nil {
-		log.Fatalf("Failed to connect to MongoDB: %v", err)
+		logging.Fatal("Invalid MongoDB settings", "error", err)
 	}
-	defer client.Disconnect(context.Background())
-
-	// Check the connection
-	err = client.Ping(context.Background(), nil)
+	cfg := loadConfig(mongoConfig)
+	if err := validateConfig(cfg); err != nil {
+		logging.Fatal("Invalid configuration", "error", err)
+	}
+	rateLimit, err := strconv.Atoi(getEnv("NOTIFICATION_RATE_LIMIT_PER_MINUTE", "60"))
 	if err != nil {
-		log.Fatalf("Failed to ping MongoDB: %v", err)
+		logging.Fatal("Invalid NOTIFICATION_RATE_LIMIT_PER_MINUTE", "error", err)
+	}
+	collapseWindow, err := time.ParseDuration(getEnv("NOTIFICATION_COLLAPSE_WINDOW", "1m"))
+	if err != nil || collapseWindow <= 0 {
+		logging.Fatal("Invalid NOTIFICATION_COLLAPSE_WINDOW", "value", os.Getenv("NOTIFICATION_COLLAPSE_WINDOW"))
 	}
+	backupStore, err := backup.FromEnv(context.Background())
+	if err != nil {
+		logging.Fatal("Invalid configuration", "error", err)
+	}
+	srv := &server{rateLimit: rateLimit, collapseWindow: collapseWindow}
+
+	var diagnosticsHandler http.Handler
+	var backups *backup.Server
+	var locks *mongo.Collection
+	if cfg.StorageDriver == storageMemory {
+		if *migrateOnly {
+			return
+		}
+		slog.Warn("Notifications are kept in memory and lost when the service stops")
+		srv.notifications = newMemoryNotifications()
+		// Deliveries are recorded on MongoDB documents, so none are sent
+		srv.deliveries = newDeliveryQueue(nil, nil, 1)
+	} else {
+		slog.Info("Connecting to MongoDB")
+		client, err := mongoutil.Connect(context.Background(), mongoConfig)
+		if err != nil {
+			logging.Fatal("Failed to connect to MongoDB", "error", err)
+		}
+		defer client.Disconnect(context.Background())
+
+		if *migrateOnly {
+			if err := runMigrations(context.Background(), client.Database(mongoConfig.DatabaseName()), *dryRun, os.Stdout); err != nil {
+				logging.Fatal("Migration failed", "error", err)
+			}
+			return
+		}
+		warnPendingMigrations(context.Background(), client.Database(mongoConfig.DatabaseName()))
+		if backupStore != nil {
+			backups = backup.NewServer(client.Database(mongoConfig.DatabaseName()), backupStore)
+		}
+
+		collection := client.Database(mongoConfig.DatabaseName()).Collection("notifications", notificationSchema.Decoding(nil, Notification{}))
+		if err := notificationSchema.Check(context.Background(), collection); err != nil {
+			logging.Fatal("Database is newer than this release", "error", err)
+		}
+		rateLimits := client.Database(mongoConfig.DatabaseName()).Collection("notification_rate_limits")
+		templates := client.Database(mongoConfig.DatabaseName()).Collection("notification_templates")
+		digests := client.Database(mongoConfig.DatabaseName()).Collection("digest_schedules")
+
+		// Collapsed notifications are unique per user, key and window so concurrent
+		// sends upsert into the same document
+		err = mongoutil.EnsureIndexes(context.Background(), collection, mongo.IndexModel{
+			Keys: bson.D{
+				{Key: "tenant_id", Value: 1},
+				{Key: "user_id", Value: 1},
+				{Key: "collapse_key", Value: 1},
+				{Key: "collapse_window", Value: 1},
+			},
+			Options: options.Index().
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"collapse_key": bson.M{"$exists": true}}),
+		})
+		if err != nil {
+			logging.Fatal("Failed to create notification indexes", "error", err)
+		}
+		if err := ensurePublicIDIndex(context.Background(), collection); err != nil {
+			logging.Fatal("Failed to create notification public id index", "error", err)
+		}
+
+		// Expired rate limit windows are removed by MongoDB
+		err = mongoutil.EnsureIndexes(context.Background(), rateLimits, mongo.IndexModel{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		})
+		if err != nil {
+			logging.Fatal("Failed to create rate limit indexes", "error", err)
+		}
+
+		// A template name is unique per tenant and language; names were
+		// unique across tenants before templates were kept per tenant
+		if err := mongoutil.DropIndexes(context.Background(), templates, "name_1_language_1"); err != nil {
+			logging.Fatal("Failed to drop template indexes", "error", err)
+		}
+		err = mongoutil.EnsureIndexes(context.Background(), templates, mongo.IndexModel{
+			Keys:    bson.D{{Key: "tenant_id", Value: 1}, {Key: "name", Value: 1}, {Key: "language", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		})
+		if err != nil {
+			logging.Fatal("Failed to create template indexes", "error", err)
+		}
+
+		err = mongoutil.EnsureIndexes(context.Background(), collection, mongo.IndexModel{
+			Keys: bson.D{{Key: "deliveries.status", Value: 1}, {Key: "deliveries.channel", Value: 1}},
+		})
+		if err != nil {
+			logging.Fatal("Failed to create delivery indexes", "error", err)
+		}
+
+		// Unread counts by type are answered from the index alone, as badges
+		// poll them
+		err = mongoutil.EnsureIndexes(context.Background(), collection, mongo.IndexModel{
+			Keys: bson.D{
+				{Key: "tenant_id", Value: 1},
+				{Key: "user_id", Value: 1},
+				{Key: "read", Value: 1},
+				{Key: "type", Value: 1},
+			},
+		})
+		if err != nil {
+			logging.Fatal("Failed to create notification summary indexes", "error", err)
+		}
+
+		err = mongoutil.EnsureIndexes(context.Background(), digests, mongo.IndexModel{
+			Keys:    bson.D{{Key: "user_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		})
+		if err != nil {
+			logging.Fatal("Failed to create digest schedule indexes", "error", err)
+		}
+
+		// The user service resolves recipient addresses for email delivery
+		userConn, err := grpcmiddleware.Dial(getEnv("USER_SERVICE_ADDR", "localhost:50052"), "USER_SERVICE_HMAC_SECRET")
+		if err != nil {
+			logging.Fatal("Failed to connect to user service", "error", err)
+		}
+		defer userConn.Close()
+
+		maxAttempts, err := strconv.Atoi(getEnv("NOTIFICATION_DELIVERY_MAX_ATTEMPTS", "3"))
+		if err != nil || maxAttempts < 1 {
+			logging.Fatal("Invalid NOTIFICATION_DELIVERY_MAX_ATTEMPTS", "value", os.Getenv("NOTIFICATION_DELIVERY_MAX_ATTEMPTS"))
+		}
+		deliverers := deliverersFromEnv(pb.NewUserServiceClient(userConn))
+		deliveries := newDeliveryQueue(collection, deliverers, maxAttempts)
+		deliveries.start(4)
+		if err := deliveries.requeuePending(context.Background()); err != nil {
+			slog.Error("Failed to requeue pending deliveries", "error", err)
+		}
 
-	collection := client.Database("todo_app").Collection("notifications")
+		var email *emailDeliverer
+		for _, d := range deliverers {
+			if e, ok := d.(*emailDeliverer); ok {
+				email = e
+			}
+		}
+		if email != nil {
+			digest := &digestScheduler{
+				schedules:     digests,
+				notifications: collection,
+				tasks:         client.Database(mongoConfig.DatabaseName()).Collection("tasks"),
+				email:         email,
+			}
+			digest.start()
+		} else {
+			slog.Warn("Digest emails disabled: SMTP_HOST is not set")
+		}
 
-	// Get port from environment variable
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "50053"
+		srv.collection = tenant.Scoped(collection)
+		srv.templates = tenant.Scoped(templates)
+		srv.digests = tenant.Scoped(digests)
+		srv.rateLimits = tenant.Scoped(rateLimits)
+		srv.deliveries = deliveries
+		locks = client.Database(mongoConfig.DatabaseName()).Collection("scheduler_locks")
+		diagnosticsHandler = diagnostics.NewServer(collection)
 	}
 
+	port := cfg.Port
+
 	lis, err := net.Listen("tcp", ":"+port)
 	if err != nil {
-		log.Fatalf("Failed to listen: %v", err)
+		logging.Fatal("Failed to listen", "error", err)
+	}
+
+	auth, err := serviceauth.FromEnv("NOTIFICATION_SERVICE_HMAC_SECRET")
+	if err != nil {
+		logging.Fatal("Invalid configuration", "error", err)
+	}
+	reflection, err := grpcmiddleware.ReflectionFromEnv()
+	if err != nil {
+		logging.Fatal("Invalid configuration", "error", err)
+	}
+
+	// Deadline alerts go through SendNotification, so they are rate limited
+	// and delivered like any other notification
+	taskConn, err := grpcmiddleware.Dial(getEnv("TASK_SERVICE_ADDR", "localhost:50051"), "TASK_SERVICE_HMAC_SECRET")
+	if err != nil {
+		logging.Fatal("Failed to connect to task service", "error", err)
+	}
+	defer taskConn.Close()
+	alertInterval, err := time.ParseDuration(getEnv("DEADLINE_ALERT_INTERVAL", "1m"))
+	if err != nil || alertInterval <= 0 {
+		logging.Fatal("Invalid DEADLINE_ALERT_INTERVAL", "value", os.Getenv("DEADLINE_ALERT_INTERVAL"))
+	}
+	alerts := &deadlineAlertScheduler{
+		tasks:    pb.NewTaskServiceClient(taskConn),
+		notify:   srv.SendNotification,
+		locks:    locks,
+		interval: alertInterval,
+	}
+	if locks != nil {
+		alerts.start()
+	} else {
+		slog.Warn("Deadline alerts disabled: their lock is kept in MongoDB")
 	}
 
-	s := grpc.NewServer()
-	pb.RegisterNotificationServiceServer(s, &server{collection: collection})
-	reflection.Register(s)
+	requireTenant, err := tenant.RequiredFromEnv()
+	if err != nil {
+		logging.Fatal("Invalid configuration", "error", err)
+	}
+	injector, err := faults.FromEnv()
+	if err != nil {
+		logging.Fatal("Invalid configuration", "error", err)
+	}
+	pprof, err := grpcmiddleware.PprofFromEnv()
+	if err != nil {
+		logging.Fatal("Invalid configuration", "error", err)
+	}
+	info := buildinfo.NewServer("notification-service", cfg.Info())
+
+	var unary []grpc.UnaryServerInterceptor
+	if cfg.StorageDriver == storageMemory {
+		unary = append(unary, memoryUnaryInterceptor)
+	}
+	s := grpcmiddleware.NewServer(grpcmiddleware.ServerConfig{
+		Auth:                 auth,
+		Reflection:           reflection,
+		RequireTenant:        requireTenant,
+		Faults:               injector,
+		Info:                 info,
+		MaxRecvMsgSize:       cfg.RecvMsgSize(),
+		MaxConcurrentStreams: cfg.StreamLimit(),
+		MaxInFlight:          cfg.InFlightLimit(),
+		Unary:                unary,
+	})
+	pb.RegisterNotificationServiceServer(s, srv)
+	backups.Register(s)
+
+	grpcmiddleware.ServeMetrics(grpcmiddleware.MetricsConfig{
+		Port:        os.Getenv("METRICS_PORT"),
+		Diagnostics: diagnosticsHandler,
+		Info:        info,
+		Pprof:       pprof,
+	})
+
+	slog.Info("Notification service listening", "port", port, "version", buildinfo.Version, "storage", cfg.StorageDriver)
+	if err := s.Serve(injector.Listen(lis)); err != nil {
+		logging.Fatal("Failed to serve", "error", err)
+	}
+}
 
-	log.Printf("Notification service listening on port %s", port)
-	if err := s.Serve(lis); err != nil {
-		log.Fatalf("Failed to serve: %v", err)
+func getEnv(key, fallback string) string {
+	if value, exists := os.LookupEnv(key); exists {
+		return value
 	}
+	return fallback
 }
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	"github.com/technonext/todo-app/proto/pagination"
+	pb "github.com/technonext/todo-app/proto/proto"
+	"github.com/technonext/todo-app/proto/publicid"
+	"github.com/technonext/todo-app/proto/tenant"
+)
+
+// memoryNotifications keeps notifications and rate limit windows in the
+// process's memory, for local development without a database; they are gone
+// once the service stops.
+type memoryNotifications struct {
+	mu            sync.Mutex
+	notifications map[primitive.ObjectID]Notification
+	// Ids in the order the notifications were stored
+	order []primitive.ObjectID
+	// Sends counted by rateLimitWindowID, and when each window started
+	sends   map[string]int
+	windows map[string]time.Time
+}
+
+func newMemoryNotifications() *memoryNotifications {
+	return &memoryNotifications{
+		notifications: make(map[primitive.ObjectID]Notification),
+		sends:         make(map[string]int),
+		windows:       make(map[string]time.Time),
+	}
+}
+
+// inScope reports whether n belongs to ctx's tenant.
+func inScope(ctx context.Context, n Notification) bool {
+	id, ok := tenant.Scope(ctx)
+	return !ok || n.TenantID == id
+}
+
+// cloneNotification returns n with a deliveries slice of its own.
+func cloneNotification(n Notification) Notification {
+	n.Deliveries = append([]Delivery(nil), n.Deliveries...)
+	return n
+}
+
+// publicIDTaken reports whether another notification has publicID. The
+// caller holds mu.
+func (m *memoryNotifications) publicIDTaken(publicID string) bool {
+	for _, other := range m.notifications {
+		if publicID != "" && other.PublicID == publicID {
+			return true
+		}
+	}
+	return false
+}
+
+// store adds n under a new ObjectID. The caller holds mu.
+func (m *memoryNotifications) store(ctx context.Context, n Notification) Notification {
+	if id, ok := tenant.Scope(ctx); ok {
+		n.TenantID = id
+	}
+	n.ID = primitive.NewObjectID()
+	m.notifications[n.ID] = cloneNotification(n)
+	m.order = append(m.order, n.ID)
+	return n
+}
+
+func (m *memoryNotifications) Insert(ctx context.Context, n Notification) (primitive.ObjectID, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.publicIDTaken(n.PublicID) {
+		return primitive.NilObjectID, errPublicIDTaken
+	}
+	return m.store(ctx, n).ID, nil
+}
+
+func (m *memoryNotifications) Collapse(ctx context.Context, n Notification, window string) (Notification, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, id := range m.order {
+		open := m.notifications[id]
+		if open.UserID != n.UserID || open.CollapseKey != n.CollapseKey || open.CollapseWindow != window || !inScope(ctx, open) {
+			continue
+		}
+		open.Message, open.Read = n.Message, false
+		if n.Priority != "" {
+			open.Priority = n.Priority
+		}
+		if n.Type != "" {
+			open.Type = n.Type
+		}
+		if n.TemplateID != "" {
+			open.TemplateID, open.Subject = n.TemplateID, n.Subject
+		}
+		open.Occurrences++
+		m.notifications[id] = open
+		return cloneNotification(open), nil
+	}
+
+	if m.publicIDTaken(n.PublicID) {
+		return Notification{}, errPublicIDTaken
+	}
+	n.CollapseWindow = window
+	n.Occurrences = 1
+	return m.store(ctx, n), nil
+}
+
+// lookup returns the notification of ctx's tenant id names, in either
+// form. The caller holds mu.
+func (m *memoryNotifications) lookup(ctx context.Context, id string) (Notification, error) {
+	filter, err := notificationFilter(id)
+	if err != nil {
+		return Notification{}, err
+	}
+	for _, n := range m.notifications {
+		if !inScope(ctx, n) {
+			continue
+		}
+		if oid, ok := filter["_id"].(primitive.ObjectID); ok && n.ID == oid {
+			return n, nil
+		}
+		if n.PublicID != "" && filter[publicid.Field] == n.PublicID {
+			return n, nil
+		}
+	}
+	return Notification{}, errNoNotification
+}
+
+func (m *memoryNotifications) Get(ctx context.Context, id string) (Notification, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n, err := m.lookup(ctx, id)
+	if err != nil {
+		return Notification{}, err
+	}
+	return cloneNotification(n), nil
+}
+
+func (m *memoryNotifications) MarkRead(ctx context.Context, id string) (Notification, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n, err := m.lookup(ctx, id)
+	if err != nil {
+		return Notification{}, err
+	}
+	n.Read = true
+	m.notifications[n.ID] = n
+	return cloneNotification(n), nil
+}
+
+func (m *memoryNotifications) UnreadByType(ctx context.Context, userID string) (map[pb.NotificationType]int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	counts := make(map[pb.NotificationType]int64)
+	for _, n := range m.notifications {
+		if n.UserID == userID && !n.Read && inScope(ctx, n) {
+			counts[n.notificationType()]++
+		}
+	}
+	return counts, nil
+}
+
+func (m *memoryNotifications) List(ctx context.Context, userID string, unreadOnly bool, page pagination.Page) ([]Notification, int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var notifications []Notification
+	for i := len(m.order) - 1; i >= 0; i-- {
+		n := m.notifications[m.order[i]]
+		if n.UserID != userID || (unreadOnly && n.Read) || !inScope(ctx, n) {
+			continue
+		}
+		notifications = append(notifications, cloneNotification(n))
+	}
+	sort.SliceStable(notifications, func(i, j int) bool { return notifications[i].CreatedAt > notifications[j].CreatedAt })
+
+	total := int64(len(notifications))
+	if page.Offset >= total {
+		return nil, total, nil
+	}
+	notifications = notifications[page.Offset:]
+	if page.Limit > 0 && int(page.Limit) < len(notifications) {
+		notifications = notifications[:page.Limit]
+	}
+	return notifications, total, nil
+}
+
+// CountSend also drops the windows that have ended, which MongoDB's TTL
+// index does for mongoNotifications.
+func (m *memoryNotifications) CountSend(ctx context.Context, userID string, window time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, started := range m.windows {
+		if started.Before(window) {
+			delete(m.windows, id)
+			delete(m.sends, id)
+		}
+	}
+	id := rateLimitWindowID(userID, window)
+	m.windows[id] = window
+	m.sends[id]++
+	return m.sends[id], nil
+}
+
+func (m *memoryNotifications) Sends(ctx context.Context, userID string, window time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.sends[rateLimitWindowID(userID, window)], nil
+}
+
+// memoryMethods are the NotificationService methods served with
+// STORAGE_DRIVER=memory, those NotificationRepository covers.
+var memoryMethods = map[string]bool{
+	pb.NotificationService_SendNotification_FullMethodName:       true,
+	pb.NotificationService_GetNotifications_FullMethodName:       true,
+	pb.NotificationService_GetNotification_FullMethodName:        true,
+	pb.NotificationService_GetNotificationSummary_FullMethodName: true,
+	pb.NotificationService_MarkNotificationRead_FullMethodName:   true,
+}
+
+// checkMemoryMethod refuses the NotificationService methods not in
+// memoryMethods, and sends from a template, as templates are kept in MongoDB.
+func checkMemoryMethod(method string, req interface{}) error {
+	if !strings.HasPrefix(method, "/todo.NotificationService/") {
+		return nil
+	}
+	if !memoryMethods[method] {
+		return statusError(codes.Unimplemented, "UNSUPPORTED_BY_STORAGE", map[string]string{"method": method, "storage_driver": storageMemory},
+			"%s needs STORAGE_DRIVER=%s", method, storageMongo)
+	}
+	if r, ok := req.(*pb.NotificationRequest); ok && r.TemplateId != "" {
+		return statusError(codes.Unimplemented, "UNSUPPORTED_BY_STORAGE", map[string]string{"method": method, "storage_driver": storageMemory, "field": "template_id"},
+			"sending from a template needs STORAGE_DRIVER=%s", storageMongo)
+	}
+	return nil
+}
+
+func memoryUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := checkMemoryMethod(info.FullMethod, req); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
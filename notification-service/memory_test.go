@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+	"github.com/technonext/todo-app/proto/tenant"
+)
+
+func TestCoreRPCsInMemory(t *testing.T) {
+	s := &server{
+		notifications:  newMemoryNotifications(),
+		deliveries:     newDeliveryQueue(nil, nil, 1),
+		rateLimit:      3,
+		collapseWindow: time.Minute,
+	}
+	ctx := tenant.WithID(context.Background(), "acme")
+
+	sent, err := s.SendNotification(ctx, &pb.NotificationRequest{UserId: "u1", Message: "Welcome"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.GetNotification(ctx, &pb.GetNotificationRequest{Id: sent.Notification.Id})
+	if err != nil || got.Notification.Message != "Welcome" {
+		t.Fatalf("GetNotification() = %v, %v; want the sent notification", got, err)
+	}
+	if _, err := s.GetNotification(tenant.WithID(context.Background(), "other"), &pb.GetNotificationRequest{Id: sent.Notification.Id}); status.Code(err) != codes.NotFound {
+		t.Errorf("GetNotification() for another tenant = %v, want NotFound", err)
+	}
+
+	// Sends with the same collapse key in a window make one notification
+	for _, message := range []string{"1 comment", "2 comments"} {
+		if _, err := s.SendNotification(ctx, &pb.NotificationRequest{UserId: "u1", Message: message, CollapseKey: "comments"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	list, err := s.GetNotifications(ctx, &pb.GetNotificationsRequest{UserId: "u1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.Total != 2 {
+		t.Fatalf("listed %d notifications, want 2", list.Total)
+	}
+	for _, n := range list.Notifications {
+		if n.CollapseKey == "comments" && (n.Message != "2 comments" || n.Occurrences != 2) {
+			t.Errorf("collapsed notification = %q seen %d times, want the latest message seen twice", n.Message, n.Occurrences)
+		}
+	}
+	page, err := s.GetNotifications(ctx, &pb.GetNotificationsRequest{UserId: "u1", Limit: 1})
+	if err != nil || len(page.Notifications) != 1 || page.Total != 2 {
+		t.Errorf("first page = %v, %v; want 1 of 2 notifications", page, err)
+	}
+
+	// The fourth send in a minute goes over the limit of 3
+	_, err = s.SendNotification(ctx, &pb.NotificationRequest{UserId: "u1", Message: "One too many"})
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("send over the rate limit = %v, want ResourceExhausted", err)
+	}
+}
+
+func TestCheckMemoryMethod(t *testing.T) {
+	tests := []struct {
+		method string
+		req    interface{}
+		want   codes.Code
+	}{
+		{pb.NotificationService_SendNotification_FullMethodName, &pb.NotificationRequest{Message: "Hi"}, codes.OK},
+		{pb.NotificationService_SendNotification_FullMethodName, &pb.NotificationRequest{TemplateId: "t1"}, codes.Unimplemented},
+		{pb.NotificationService_GetNotifications_FullMethodName, &pb.GetNotificationsRequest{}, codes.OK},
+		{pb.NotificationService_GetNotificationSummary_FullMethodName, &pb.GetNotificationSummaryRequest{}, codes.OK},
+		{pb.NotificationService_CreateTemplate_FullMethodName, &pb.CreateTemplateRequest{}, codes.Unimplemented},
+		{pb.NotificationService_DeleteNotificationsByUser_FullMethodName, &pb.DeleteNotificationsByUserRequest{}, codes.Unimplemented},
+		{"/todo.FaultService/SetFaults", nil, codes.OK},
+	}
+	for _, tt := range tests {
+		if got := status.Code(checkMemoryMethod(tt.method, tt.req)); got != tt.want {
+			t.Errorf("checkMemoryMethod(%s, %v) = %v, want %v", tt.method, tt.req, got, tt.want)
+		}
+	}
+}
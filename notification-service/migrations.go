@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"text/tabwriter"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/technonext/todo-app/proto/logging"
+	"github.com/technonext/todo-app/proto/migrate"
+	"github.com/technonext/todo-app/proto/publicid"
+	"github.com/technonext/todo-app/proto/schema"
+	"github.com/technonext/todo-app/proto/tenant"
+)
+
+// notificationMigrations are the notification service's data migrations
+// over db, applied with -migrate. Versions are never reused or reordered
+// once released.
+func notificationMigrations(db *mongo.Database) []migrate.Migration {
+	return []migrate.Migration{
+		{
+			Version:       1,
+			Description:   "backfill public_id",
+			Collection:    "notifications",
+			Filter:        bson.M{publicid.Field: bson.M{"$exists": false}},
+			Update:        publicid.Backfill(publicid.Notification),
+			SchemaVersion: 1,
+		},
+		{
+			Version:     2,
+			Description: "count the occurrences of notifications from before collapsing",
+			Collection:  "notifications",
+			Filter:      bson.M{"occurrences": bson.M{"$exists": false}},
+			Update: func(bson.Raw) (bson.M, error) {
+				return bson.M{"$set": bson.M{"occurrences": 1}}, nil
+			},
+			SchemaVersion: 2,
+		},
+		{
+			Version:     3,
+			Description: "digest schedules carry their user's tenant",
+			Collection:  "digest_schedules",
+			Filter:      bson.M{tenant.Field: bson.M{"$exists": false}},
+			Update:      userTenants(db.Collection("notifications")),
+		},
+	}
+}
+
+// notificationSchema is the history of the layout of notifications, a
+// version per migration changing it. Notifications are decoded through it,
+// so those the migrations have not reached yet read as if they had.
+var notificationSchema = schema.Schema{
+	Collection: "notifications",
+	Upgrades: []schema.Upgrade{
+		// Public ids, which publicid.Or stands in for
+		nil,
+		countOccurrence,
+	},
+}
+
+// countOccurrence gives a notification stored before collapsing existed the
+// one occurrence it stands for.
+func countOccurrence(doc bson.M) error {
+	if _, ok := doc["occurrences"]; !ok {
+		doc["occurrences"] = int32(1)
+	}
+	return nil
+}
+
+// userTenants sets a document's tenant_id to the tenant of its user_id,
+// taken from the user's latest notification. Documents of the default
+// tenant's users, and of users without notifications, are left alone.
+func userTenants(notifications *mongo.Collection) func(bson.Raw) (bson.M, error) {
+	known := make(map[string]string)
+	return func(doc bson.Raw) (bson.M, error) {
+		userID, ok := doc.Lookup("user_id").StringValueOK()
+		if !ok {
+			return nil, nil
+		}
+		tenantID, ok := known[userID]
+		if !ok {
+			var n Notification
+			opts := options.FindOne().SetSort(bson.D{{Key: "_id", Value: -1}}).SetProjection(bson.M{tenant.Field: 1})
+			err := notifications.FindOne(context.Background(), bson.M{"user_id": userID}, opts).Decode(&n)
+			if err != nil && err != mongo.ErrNoDocuments {
+				return nil, err
+			}
+			tenantID = n.TenantID
+			known[userID] = tenantID
+		}
+		if tenantID == "" {
+			return nil, nil
+		}
+		return bson.M{"$set": bson.M{tenant.Field: tenantID}}, nil
+	}
+}
+
+// runMigrations applies the pending migrations, or with dryRun reports what
+// they would change, and writes a line per migration to w.
+func runMigrations(ctx context.Context, db *mongo.Database, dryRun bool, w io.Writer) error {
+	runner, err := migrate.New(db, "notification-service", notificationMigrations(db)...)
+	if err != nil {
+		return err
+	}
+	run := runner.Run
+	if dryRun {
+		run = runner.DryRun
+	}
+	results, err := run(ctx)
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tDESCRIPTION\tSCANNED\tCHANGED")
+	for _, r := range results {
+		fmt.Fprintf(tw, "%d\t%s\t%d\t%d\n", r.Version, r.Description, r.Scanned, r.Changed)
+	}
+	tw.Flush()
+	if len(results) == 0 && err == nil {
+		fmt.Fprintln(w, "No pending migrations")
+	}
+	return err
+}
+
+// warnPendingMigrations logs the migrations the database still needs.
+func warnPendingMigrations(ctx context.Context, db *mongo.Database) {
+	runner, err := migrate.New(db, "notification-service", notificationMigrations(db)...)
+	if err != nil {
+		logging.Fatal("Invalid migrations", "error", err)
+	}
+	pending, err := runner.Pending(ctx)
+	if err != nil {
+		slog.Warn("Failed to check for pending migrations", "error", err)
+		return
+	}
+	for _, m := range pending {
+		slog.Warn("Data migration pending, apply it with -migrate", "version", m.Version, "description", m.Description)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/technonext/todo-app/proto/publicid"
+)
+
+func TestDecodeNotificationVersions(t *testing.T) {
+	oid := primitive.NewObjectID()
+	// A notification as each release stored it
+	tests := []struct {
+		name        string
+		doc         bson.M
+		publicID    string
+		occurrences int32
+		version     int
+	}{
+		{"version 0, before public ids and collapsing",
+			bson.M{"_id": oid, "user_id": "u1", "message": "Task due", "read": false, "created_at": "2026-03-01T09:00:00Z"},
+			oid.Hex(), 1, 2},
+		{"version 1, public ids",
+			bson.M{"_id": oid, "user_id": "u1", "message": "Task due", "read": false, "created_at": "2026-03-01T09:00:00Z", "public_id": "ntf_01HV4Z3K9Q8W5N2M7R6T1Y0XCB", "schema_version": 1},
+			"ntf_01HV4Z3K9Q8W5N2M7R6T1Y0XCB", 1, 2},
+		{"version 1, collapsed before the migration",
+			bson.M{"_id": oid, "user_id": "u1", "message": "Task due", "read": false, "created_at": "2026-03-01T09:00:00Z", "public_id": "ntf_01HV4Z3K9Q8W5N2M7R6T1Y0XCB", "occurrences": int32(4), "schema_version": 1},
+			"ntf_01HV4Z3K9Q8W5N2M7R6T1Y0XCB", 4, 2},
+		{"version 2, occurrences counted",
+			bson.M{"_id": oid, "user_id": "u1", "message": "Task due", "read": false, "created_at": "2026-03-01T09:00:00Z", "public_id": "ntf_01HV4Z3K9Q8W5N2M7R6T1Y0XCB", "occurrences": int32(3), "schema_version": 2},
+			"ntf_01HV4Z3K9Q8W5N2M7R6T1Y0XCB", 3, 2},
+		{"version 3, from the next release",
+			bson.M{"_id": oid, "user_id": "u1", "message": "Task due", "read": false, "created_at": "2026-03-01T09:00:00Z", "public_id": "ntf_01HV4Z3K9Q8W5N2M7R6T1Y0XCB", "occurrences": int32(3), "snoozed_until": "2026-03-02T09:00:00Z", "schema_version": 3},
+			"ntf_01HV4Z3K9Q8W5N2M7R6T1Y0XCB", 3, 3},
+	}
+	registry := notificationSchema.Decoding(nil, Notification{}).Registry
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cursor, err := mongo.NewCursorFromDocuments([]interface{}{tt.doc}, nil, registry)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var notifications []Notification
+			if err := cursor.All(context.Background(), &notifications); err != nil {
+				t.Fatal(err)
+			}
+			n := notifications[0]
+			if n.ID != oid || n.UserID != "u1" || n.Message != "Task due" {
+				t.Errorf("notification = %+v, want Task due for u1", n)
+			}
+			if got := publicid.Or(n.PublicID, n.ID); got != tt.publicID {
+				t.Errorf("public id = %q, want %q", got, tt.publicID)
+			}
+			if n.Occurrences != tt.occurrences {
+				t.Errorf("occurrences = %d, want %d", n.Occurrences, tt.occurrences)
+			}
+			if n.SchemaVersion != tt.version {
+				t.Errorf("schema version = %d, want %d", n.SchemaVersion, tt.version)
+			}
+		})
+	}
+}
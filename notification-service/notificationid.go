@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/grpc/codes"
+
+	"github.com/technonext/todo-app/proto/mongoutil"
+	"github.com/technonext/todo-app/proto/publicid"
+)
+
+// Notifications are known outside the service by their public ids. Until
+// clients have all moved over, the RPCs taking a notification id also accept
+// its ObjectID hex.
+
+// newNotificationPublicID returns the public id of a new notification;
+// tests replace it to force collisions.
+var newNotificationPublicID = func() string { return publicid.New(publicid.Notification) }
+
+// publicIDAttempts is how many public ids a send tries before giving up.
+const publicIDAttempts = 3
+
+// publicID returns the id the API shows for the notification: its ObjectID
+// hex until the backfill migration gives it a public id.
+func (n Notification) publicID() string {
+	return publicid.Or(n.PublicID, n.ID)
+}
+
+// isPublicIDTaken reports whether err is a write refused for reusing a
+// public id.
+func isPublicIDTaken(err error) bool {
+	return mongo.IsDuplicateKeyError(err) && strings.Contains(err.Error(), publicid.Field)
+}
+
+// ensurePublicIDIndex creates the unique index of public ids. Notifications
+// sent before public ids have none until the backfill migration runs.
+func ensurePublicIDIndex(ctx context.Context, collection *mongo.Collection) error {
+	return mongoutil.EnsureIndexes(ctx, collection, mongo.IndexModel{
+		Keys: bson.D{{Key: publicid.Field, Value: 1}},
+		Options: options.Index().SetUnique(true).
+			SetPartialFilterExpression(bson.M{publicid.Field: bson.M{"$exists": true}}),
+	})
+}
+
+// notificationFilter returns the query for the notification id names, in
+// either form.
+func notificationFilter(id string) (bson.M, error) {
+	filter, err := publicid.Filter(publicid.Notification, id)
+	if err != nil {
+		return nil, statusError(codes.InvalidArgument, "INVALID_NOTIFICATION_ID", map[string]string{"notification_id": id}, "invalid notification id %q", id)
+	}
+	return filter, nil
+}
@@ -0,0 +1,149 @@
+//go:build integration
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+	"github.com/technonext/todo-app/proto/publicid"
+	"github.com/technonext/todo-app/proto/tenant"
+)
+
+func newPublicIDTestServer(t *testing.T) *server {
+	t.Helper()
+	collection := newTestCollection(t)
+	if err := ensurePublicIDIndex(context.Background(), collection); err != nil {
+		t.Fatal(err)
+	}
+	db := collection.Database()
+	return &server{
+		collection:     tenant.Scoped(collection),
+		rateLimits:     tenant.Scoped(db.Collection("notification_rate_limits")),
+		rateLimit:      100,
+		collapseWindow: time.Minute,
+		deliveries:     newDeliveryQueue(collection, nil, 3),
+	}
+}
+
+func TestNotificationLookupByEitherID(t *testing.T) {
+	s := newPublicIDTestServer(t)
+	ctx := context.Background()
+
+	sent, err := s.SendNotification(ctx, &pb.NotificationRequest{UserId: "u1", Message: "hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicID := sent.Notification.Id
+	if !publicid.Valid(publicid.Notification, publicID) {
+		t.Fatalf("sent notification id = %q, want a public id", publicID)
+	}
+	var stored Notification
+	if err := s.collection.FindOne(ctx, bson.M{publicid.Field: publicID}).Decode(&stored); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []string{publicID, stored.ID.Hex()} {
+		got, err := s.GetNotification(ctx, &pb.GetNotificationRequest{Id: id})
+		if err != nil {
+			t.Fatalf("GetNotification(%s): %v", id, err)
+		}
+		if got.Notification.Id != publicID {
+			t.Errorf("GetNotification(%s).Id = %q, want only the public id %q", id, got.Notification.Id, publicID)
+		}
+	}
+	if _, err := s.GetNotification(ctx, &pb.GetNotificationRequest{Id: publicid.New(publicid.Task)}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("GetNotification of a task id: code = %v, want InvalidArgument", status.Code(err))
+	}
+	if _, err := s.GetNotification(ctx, &pb.GetNotificationRequest{Id: publicid.New(publicid.Notification)}); status.Code(err) != codes.NotFound {
+		t.Errorf("GetNotification of an unknown public id: code = %v, want NotFound", status.Code(err))
+	}
+
+	collapsed, err := s.SendNotification(ctx, &pb.NotificationRequest{UserId: "u1", Message: "again", CollapseKey: "k"})
+	if err != nil || !publicid.Valid(publicid.Notification, collapsed.Notification.Id) {
+		t.Fatalf("collapsed notification = %v, %v; want a public id", collapsed, err)
+	}
+	again, err := s.SendNotification(ctx, &pb.NotificationRequest{UserId: "u1", Message: "again", CollapseKey: "k"})
+	if err != nil || again.Notification.Id != collapsed.Notification.Id {
+		t.Errorf("collapsing into %v (%v), want the id of the window's notification %s", again, err, collapsed.Notification.Id)
+	}
+}
+
+func TestSendNotificationRetriesTakenPublicID(t *testing.T) {
+	s := newPublicIDTestServer(t)
+	ctx := context.Background()
+
+	taken := publicid.New(publicid.Notification)
+	var generated []string
+	next := func(ids ...string) {
+		generated = ids
+		newNotificationPublicID = func() string {
+			id := generated[0]
+			if len(generated) > 1 {
+				generated = generated[1:]
+			}
+			return id
+		}
+	}
+	t.Cleanup(func() { newNotificationPublicID = func() string { return publicid.New(publicid.Notification) } })
+
+	next(taken)
+	if _, err := s.SendNotification(ctx, &pb.NotificationRequest{UserId: "u1", Message: "first"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, collapseKey := range []string{"", "k"} {
+		fresh := publicid.New(publicid.Notification)
+		next(taken, fresh)
+		sent, err := s.SendNotification(ctx, &pb.NotificationRequest{UserId: "u1", Message: "second", CollapseKey: collapseKey})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sent.Notification.Id != fresh {
+			t.Errorf("collapse key %q: sent with %q, want the id generated after the collision", collapseKey, sent.Notification.Id)
+		}
+	}
+
+	next(taken)
+	if _, err := s.SendNotification(ctx, &pb.NotificationRequest{UserId: "u1", Message: "third"}); err == nil {
+		t.Error("SendNotification succeeded though every public id was taken")
+	}
+	if n, _ := s.collection.CountDocuments(ctx, bson.M{publicid.Field: taken}); n != 1 {
+		t.Errorf("%d notifications with public id %s, want 1", n, taken)
+	}
+}
+
+func TestNotificationPublicIDBackfill(t *testing.T) {
+	s := newPublicIDTestServer(t)
+	ctx := context.Background()
+
+	// A notification sent before public ids
+	oid := primitive.NewObjectID()
+	if _, err := s.collection.InsertOne(ctx, Notification{ID: oid, UserID: "u1", Message: "old"}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.GetNotification(ctx, &pb.GetNotificationRequest{Id: oid.Hex()})
+	if err != nil || got.Notification.Id != oid.Hex() {
+		t.Fatalf("GetNotification before the backfill = %v, %v; want the ObjectID hex", got, err)
+	}
+
+	var out bytes.Buffer
+	if err := runMigrations(ctx, s.collection.Database(), false, &out); err != nil {
+		t.Fatal(err)
+	}
+	got, err = s.GetNotification(ctx, &pb.GetNotificationRequest{Id: oid.Hex()})
+	if err != nil || !publicid.Valid(publicid.Notification, got.Notification.Id) {
+		t.Fatalf("GetNotification after the backfill = %v, %v; want a public id", got, err)
+	}
+	if byPublic, err := s.GetNotification(ctx, &pb.GetNotificationRequest{Id: got.Notification.Id}); err != nil || byPublic.Notification.Message != "old" {
+		t.Errorf("GetNotification(%s) = %v, %v", got.Notification.Id, byPublic, err)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc/codes"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// checkRateLimit counts a send against the user's fixed one-minute window.
+// The counter is kept by the repository so the limit holds across concurrent
+// requests and, in MongoDB, service replicas.
+func (s *server) checkRateLimit(ctx context.Context, userID string) error {
+	if s.rateLimit <= 0 {
+		return nil
+	}
+
+	count, err := s.repository().CountSend(ctx, userID, time.Now().UTC().Truncate(time.Minute))
+	if err != nil {
+		return err
+	}
+
+	if count > s.rateLimit {
+		return s.rateLimited(userID)
+	}
+	return nil
+}
+
+// previewRateLimit is checkRateLimit for a dry run: it fails when a send
+// would be rate limited now, without counting one.
+func (s *server) previewRateLimit(ctx context.Context, userID string) error {
+	if s.rateLimit <= 0 {
+		return nil
+	}
+
+	count, err := s.repository().Sends(ctx, userID, time.Now().UTC().Truncate(time.Minute))
+	if err != nil {
+		return err
+	}
+
+	if count >= s.rateLimit {
+		return s.rateLimited(userID)
+	}
+	return nil
+}
+
+func (s *server) rateLimited(userID string) error {
+	return statusError(codes.ResourceExhausted, "RATE_LIMITED", map[string]string{"user_id": userID, "limit_per_minute": strconv.Itoa(int(s.rateLimit))}, "rate limit of %d notifications per minute exceeded for user %s", s.rateLimit, userID)
+}
+
+// collapseNotification merges a notification into the one already open for
+// the same user and collapse key in the current window, bumping its
+// occurrence count instead of inserting a new row.
+func (s *server) collapseNotification(ctx context.Context, n Notification) (*pb.NotificationResponse, error) {
+	window := time.Now().UTC().Truncate(s.collapseWindow).Format(time.RFC3339)
+	n.Deliveries = s.deliveries.pendingDeliveries(n.CreatedAt)
+
+	var notification Notification
+	var err error
+	for attempt := 0; attempt < publicIDAttempts; attempt++ {
+		n.PublicID = newNotificationPublicID()
+		if notification, err = s.repository().Collapse(ctx, n, window); err != errPublicIDTaken {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the first occurrence in a window goes out; later ones just bump the count
+	if notification.Occurrences == 1 {
+		s.deliveries.enqueueAll(notification)
+	}
+
+	return &pb.NotificationResponse{Notification: notification.toProto()}, nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// newRateLimitedServer returns an in-memory server allowing limit sends a
+// minute, collapsing within window.
+func newRateLimitedServer(limit int, window time.Duration) *server {
+	return &server{
+		notifications:  newMemoryNotifications(),
+		deliveries:     newDeliveryQueue(nil, nil, 1),
+		rateLimit:      limit,
+		collapseWindow: window,
+	}
+}
+
+// awayFromMinuteEnd waits for the next minute when the current one is about
+// to end, so that a test's sends share one rate limit window.
+func awayFromMinuteEnd() {
+	if left := time.Until(time.Now().Truncate(time.Minute).Add(time.Minute)); left < 5*time.Second {
+		time.Sleep(left)
+	}
+}
+
+func TestRateLimitBoundary(t *testing.T) {
+	awayFromMinuteEnd()
+	s := newRateLimitedServer(5, time.Minute)
+	ctx := context.Background()
+	send := func(userID string, dryRun bool) error {
+		_, err := s.SendNotification(ctx, &pb.NotificationRequest{UserId: userID, Message: "Hi", DryRun: dryRun})
+		return err
+	}
+
+	for i := 1; i <= 5; i++ {
+		if err := send("u1", false); err != nil {
+			t.Fatalf("send %d of a limit of 5 = %v, want it sent", i, err)
+		}
+	}
+	// A dry run at the limit reports the next send would be refused, and
+	// does not count as one
+	if err := send("u1", true); errorReason(err) != "RATE_LIMITED" {
+		t.Errorf("dry run at the limit = %v, want RATE_LIMITED", err)
+	}
+	err := send("u1", false)
+	if status.Code(err) != codes.ResourceExhausted || errorReason(err) != "RATE_LIMITED" {
+		t.Errorf("send 6 of a limit of 5 = %v, want ResourceExhausted with RATE_LIMITED", err)
+	}
+	if err := send("u2", false); err != nil {
+		t.Errorf("another user's send = %v, want it sent", err)
+	}
+	if err := send("u2", true); err != nil {
+		t.Errorf("dry run below the limit = %v, want it allowed", err)
+	}
+
+	list, err := s.GetNotifications(ctx, &pb.GetNotificationsRequest{UserId: "u1"})
+	if err != nil || list.Total != 5 {
+		t.Errorf("u1 has %d notifications (%v), want 5", list.GetTotal(), err)
+	}
+}
+
+func TestRateLimitConcurrentSends(t *testing.T) {
+	awayFromMinuteEnd()
+	s := newRateLimitedServer(5, time.Minute)
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	sent, limited := 0, 0
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.SendNotification(ctx, &pb.NotificationRequest{UserId: "u1", Message: "Hi"})
+			mu.Lock()
+			defer mu.Unlock()
+			switch status.Code(err) {
+			case codes.OK:
+				sent++
+			case codes.ResourceExhausted:
+				limited++
+			default:
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if sent != 5 || limited != 15 {
+		t.Errorf("sent %d, limited %d of 20 concurrent sends; want 5 and 15", sent, limited)
+	}
+}
+
+func TestCollapseCounter(t *testing.T) {
+	s := newRateLimitedServer(0, time.Hour)
+	ctx := context.Background()
+	send := func(userID, key, message string) *pb.Notification {
+		t.Helper()
+		resp, err := s.SendNotification(ctx, &pb.NotificationRequest{UserId: userID, Message: message, CollapseKey: key})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.Notification
+	}
+
+	first := send("u1", "comments", "1 comment")
+	if first.Occurrences != 1 {
+		t.Fatalf("first occurrence counted %d, want 1", first.Occurrences)
+	}
+	for i, message := range []string{"2 comments", "3 comments"} {
+		got := send("u1", "comments", message)
+		if got.Id != first.Id || got.Occurrences != int32(i+2) || got.Message != message {
+			t.Errorf("collapsed %s = %s seen %d times with %q, want %s seen %d times", message, got.Id, got.Occurrences, got.Message, first.Id, i+2)
+		}
+	}
+	if other := send("u1", "likes", "1 like"); other.Id == first.Id || other.Occurrences != 1 {
+		t.Errorf("another key = %s seen %d times, want a new notification", other.Id, other.Occurrences)
+	}
+	if other := send("u2", "comments", "1 comment"); other.Id == first.Id || other.Occurrences != 1 {
+		t.Errorf("another user = %s seen %d times, want a new notification", other.Id, other.Occurrences)
+	}
+
+	// Concurrent sends each count once
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := s.SendNotification(ctx, &pb.NotificationRequest{UserId: "u1", Message: "More comments", CollapseKey: "comments"}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	got, err := s.GetNotification(ctx, &pb.GetNotificationRequest{Id: first.Id})
+	if err != nil || got.Notification.Occurrences != 13 {
+		t.Errorf("after concurrent sends seen %d times (%v), want 13", got.GetNotification().GetOccurrences(), err)
+	}
+}
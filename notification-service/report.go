@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"google.golang.org/grpc/codes"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// GetDeliveryReport counts the deliveries of notifications created in the
+// range by channel and status. Channels appear once they have a delivery,
+// sorted by name.
+func (s *server) GetDeliveryReport(ctx context.Context, req *pb.GetDeliveryReportRequest) (*pb.GetDeliveryReportResponse, error) {
+	match := bson.M{"deliveries.0": bson.M{"$exists": true}}
+	if req.UserId != "" {
+		match["user_id"] = req.UserId
+	}
+
+	createdAt := bson.M{"$dateFromString": bson.M{"dateString": "$created_at", "onError": nil, "onNull": nil}}
+	var bounds bson.A
+	if req.StartDate != "" {
+		start, err := parseReportDate(req.StartDate, false)
+		if err != nil {
+			return nil, statusError(codes.InvalidArgument, "INVALID_START_DATE", map[string]string{"start_date": req.StartDate}, "invalid start_date %q: use RFC3339 or YYYY-MM-DD", req.StartDate)
+		}
+		bounds = append(bounds, bson.M{"$gte": bson.A{createdAt, start}})
+	}
+	if req.EndDate != "" {
+		end, err := parseReportDate(req.EndDate, true)
+		if err != nil {
+			return nil, statusError(codes.InvalidArgument, "INVALID_END_DATE", map[string]string{"end_date": req.EndDate}, "invalid end_date %q: use RFC3339 or YYYY-MM-DD", req.EndDate)
+		}
+		bounds = append(bounds, bson.M{"$lte": bson.A{createdAt, end}})
+	}
+	if len(bounds) > 0 {
+		match["$expr"] = bson.M{"$and": bounds}
+	}
+
+	countOf := func(status string) bson.M {
+		return bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$deliveries.status", status}}, 1, 0}}}
+	}
+	pipeline := []bson.M{
+		{"$match": match},
+		{"$unwind": "$deliveries"},
+		{"$group": bson.M{
+			"_id":       "$deliveries.channel",
+			"sent":      bson.M{"$sum": 1},
+			"delivered": countOf(deliveryDelivered),
+			"failed":    countOf(deliveryFailed),
+			"pending":   countOf(deliveryPending),
+		}},
+		{"$sort": bson.M{"_id": 1}},
+	}
+
+	cursor, err := s.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	resp := &pb.GetDeliveryReportResponse{}
+	for cursor.Next(ctx) {
+		var row struct {
+			Channel   string `bson:"_id"`
+			Sent      int32  `bson:"sent"`
+			Delivered int32  `bson:"delivered"`
+			Failed    int32  `bson:"failed"`
+			Pending   int32  `bson:"pending"`
+		}
+		if err := cursor.Decode(&row); err != nil {
+			return nil, err
+		}
+
+		report := &pb.ChannelReport{
+			Channel:   row.Channel,
+			Sent:      row.Sent,
+			Delivered: row.Delivered,
+			Failed:    row.Failed,
+			Pending:   row.Pending,
+		}
+		if settled := row.Delivered + row.Failed; settled > 0 {
+			report.DeliveryRate = float64(row.Delivered) / float64(settled)
+		}
+		resp.Channels = append(resp.Channels, report)
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// parseReportDate accepts RFC3339 or a UTC date, which stands for the start
+// of the day, or its end when endOfDay is set.
+func parseReportDate(value string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return t, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1).Add(-time.Millisecond)
+	}
+	return t, nil
+}
+
+// GetNotificationCounts counts notifications created since req.Since, or all
+// of them when it is empty, and their failed webhook deliveries.
+func (s *server) GetNotificationCounts(ctx context.Context, req *pb.GetNotificationCountsRequest) (*pb.GetNotificationCountsResponse, error) {
+	filter := bson.M{}
+	if req.Since != "" {
+		since, err := time.Parse(time.RFC3339, req.Since)
+		if err != nil {
+			return nil, statusError(codes.InvalidArgument, "INVALID_SINCE", map[string]string{"since": req.Since}, "invalid since %q: use RFC3339", req.Since)
+		}
+		createdAt := bson.M{"$dateFromString": bson.M{"dateString": "$created_at", "onError": nil, "onNull": nil}}
+		filter["$expr"] = bson.M{"$gte": bson.A{createdAt, since}}
+	}
+
+	sent, err := s.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	pipeline := []bson.M{
+		{"$match": filter},
+		{"$unwind": "$deliveries"},
+		{"$match": bson.M{"deliveries.channel": "webhook", "deliveries.status": deliveryFailed}},
+		{"$count": "failed"},
+	}
+	cursor, err := s.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	resp := &pb.GetNotificationCountsResponse{Sent: sent}
+	if cursor.Next(ctx) {
+		var row struct {
+			Failed int64 `bson:"failed"`
+		}
+		if err := cursor.Decode(&row); err != nil {
+			return nil, err
+		}
+		resp.FailedWebhookDeliveries = row.Failed
+	}
+	return resp, cursor.Err()
+}
@@ -0,0 +1,75 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+func TestGetDeliveryReportStatuses(t *testing.T) {
+	s := newPublicIDTestServer(t)
+	ctx := context.Background()
+	notification := func(userID, createdAt string, deliveries ...Delivery) interface{} {
+		return bson.M{"user_id": userID, "message": "Hi", "created_at": createdAt, "deliveries": deliveries}
+	}
+	delivery := func(channel, status string) Delivery {
+		return Delivery{Channel: channel, Status: status}
+	}
+	_, err := s.collection.InsertMany(ctx, []interface{}{
+		notification("u1", "2026-03-01T09:00:00Z", delivery("email", deliveryDelivered), delivery("webhook", deliveryFailed)),
+		notification("u1", "2026-03-02T09:00:00Z", delivery("email", deliveryDelivered), delivery("webhook", deliveryPending)),
+		notification("u1", "2026-03-03T09:00:00Z", delivery("email", deliveryFailed), delivery("webhook", deliveryFailed)),
+		notification("u1", "2026-03-04T09:00:00Z", delivery("push", deliveryPending)),
+		notification("u2", "2026-03-02T09:00:00Z", delivery("email", deliveryDelivered), delivery("push", deliveryDelivered)),
+		// Without deliveries there is nothing to report
+		notification("u1", "2026-03-02T09:00:00Z"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		req  *pb.GetDeliveryReportRequest
+		want []*pb.ChannelReport
+	}{
+		{"every status", &pb.GetDeliveryReportRequest{UserId: "u1"}, []*pb.ChannelReport{
+			{Channel: "email", Sent: 3, Delivered: 2, Failed: 1, DeliveryRate: 2.0 / 3},
+			{Channel: "push", Sent: 1, Pending: 1},
+			{Channel: "webhook", Sent: 3, Failed: 2, Pending: 1},
+		}},
+		{"all users", &pb.GetDeliveryReportRequest{}, []*pb.ChannelReport{
+			{Channel: "email", Sent: 4, Delivered: 3, Failed: 1, DeliveryRate: 0.75},
+			{Channel: "push", Sent: 2, Delivered: 1, Pending: 1, DeliveryRate: 1},
+			{Channel: "webhook", Sent: 3, Failed: 2, Pending: 1},
+		}},
+		{"date range", &pb.GetDeliveryReportRequest{UserId: "u1", StartDate: "2026-03-02", EndDate: "2026-03-03"}, []*pb.ChannelReport{
+			{Channel: "email", Sent: 2, Delivered: 1, Failed: 1, DeliveryRate: 0.5},
+			{Channel: "webhook", Sent: 2, Failed: 1, Pending: 1},
+		}},
+		{"no deliveries", &pb.GetDeliveryReportRequest{UserId: "u3"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := s.GetDeliveryReport(ctx, tt.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(resp.Channels) != len(tt.want) {
+				t.Fatalf("got %v, want %v", resp.Channels, tt.want)
+			}
+			for i, got := range resp.Channels {
+				want := tt.want[i]
+				if got.Channel != want.Channel || got.Sent != want.Sent || got.Delivered != want.Delivered ||
+					got.Failed != want.Failed || got.Pending != want.Pending || got.DeliveryRate != want.DeliveryRate {
+					t.Errorf("got %v, want %v", got, want)
+				}
+			}
+		})
+	}
+}
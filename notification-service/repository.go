@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/technonext/todo-app/proto/pagination"
+	pb "github.com/technonext/todo-app/proto/proto"
+	"github.com/technonext/todo-app/proto/publicid"
+	"github.com/technonext/todo-app/proto/schema"
+	"github.com/technonext/todo-app/proto/tenant"
+)
+
+// Errors of a NotificationRepository, which the RPCs turn into status errors
+var (
+	// errNoNotification is returned for a notification that does not exist
+	// or belongs to another tenant
+	errNoNotification = errors.New("notification not found")
+	// errPublicIDTaken is returned when another notification already has
+	// the new notification's public id
+	errPublicIDTaken = errors.New("public id taken")
+)
+
+// NotificationRepository stores the notifications and rate limit windows
+// behind the core RPCs: SendNotification, GetNotifications,
+// GetNotification, GetNotificationSummary and MarkNotificationRead.
+// STORAGE_DRIVER picks the implementation, mongoNotifications or
+// memoryNotifications. The other RPCs and the background jobs work on the
+// MongoDB collections directly, so in memory only these RPCs are served; see
+// memoryMethods.
+//
+// Every method is limited to the tenant of its context, as a
+// tenant.Collection is.
+type NotificationRepository interface {
+	// Insert stores a new notification and returns its ObjectID.
+	Insert(ctx context.Context, n Notification) (primitive.ObjectID, error)
+	// Collapse merges n into the notification open for its user and
+	// collapse key in window, or stores it as the window's first, and
+	// returns the notification as stored. It fails with errPublicIDTaken
+	// when it should be retried with another public id.
+	Collapse(ctx context.Context, n Notification, window string) (Notification, error)
+	// Get returns the notification id names, in either form.
+	Get(ctx context.Context, id string) (Notification, error)
+	// List returns the page of a user's notifications, newest first, with
+	// how many there are in all.
+	List(ctx context.Context, userID string, unreadOnly bool, page pagination.Page) ([]Notification, int64, error)
+	// MarkRead marks the notification id names, in either form, read and
+	// returns it as stored.
+	MarkRead(ctx context.Context, id string) (Notification, error)
+	// UnreadByType counts a user's unread notifications by type, those
+	// without one under NOTIFICATION_TYPE_SYSTEM.
+	UnreadByType(ctx context.Context, userID string) (map[pb.NotificationType]int64, error)
+	// CountSend counts a send by the user in the rate limit window starting
+	// at window and returns the sends counted in it.
+	CountSend(ctx context.Context, userID string, window time.Time) (int, error)
+	// Sends returns the sends counted for the user in window.
+	Sends(ctx context.Context, userID string, window time.Time) (int, error)
+}
+
+// repository returns the store of the core RPCs: notifications when set, or
+// else the MongoDB collections.
+func (s *server) repository() NotificationRepository {
+	if s.notifications != nil {
+		return s.notifications
+	}
+	return mongoNotifications{collection: s.collection, rateLimits: s.rateLimits}
+}
+
+// mongoNotifications stores notifications and rate limit windows as
+// documents of two collections. The windows' counters are updated in place,
+// so the limit holds across concurrent requests and service replicas.
+type mongoNotifications struct {
+	collection *tenant.Collection
+	rateLimits *tenant.Collection
+}
+
+func (m mongoNotifications) Insert(ctx context.Context, n Notification) (primitive.ObjectID, error) {
+	n.SchemaVersion = notificationSchema.Version()
+	result, err := m.collection.InsertOne(ctx, n)
+	if isPublicIDTaken(err) {
+		return primitive.NilObjectID, errPublicIDTaken
+	}
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	oid, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return primitive.NilObjectID, errors.New("inserted notification id is not an ObjectID")
+	}
+	return oid, nil
+}
+
+func (m mongoNotifications) Collapse(ctx context.Context, n Notification, window string) (Notification, error) {
+	filter := bson.M{
+		"user_id":         n.UserID,
+		"collapse_key":    n.CollapseKey,
+		"collapse_window": window,
+	}
+	set := bson.M{
+		"message": n.Message,
+		"read":    false,
+	}
+	if n.Priority != "" {
+		set["priority"] = n.Priority
+	}
+	if n.Type != "" {
+		set["type"] = n.Type
+	}
+	if n.TemplateID != "" {
+		set["template_id"] = n.TemplateID
+		set["subject"] = n.Subject
+	}
+	update := bson.M{
+		"$set": set,
+		"$inc": bson.M{"occurrences": 1},
+		"$setOnInsert": bson.M{
+			"created_at":   n.CreatedAt,
+			"deliveries":   n.Deliveries,
+			publicid.Field: n.PublicID,
+			schema.Field:   notificationSchema.Version(),
+		},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var notification Notification
+	err := m.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&notification)
+	if mongo.IsDuplicateKeyError(err) {
+		// Either lost the race to open the window, when the unique index
+		// guarantees the retry matches, or the public id was taken, when the
+		// retry tries another
+		return notification, errPublicIDTaken
+	}
+	return notification, err
+}
+
+func (m mongoNotifications) Get(ctx context.Context, id string) (Notification, error) {
+	var notification Notification
+	filter, err := notificationFilter(id)
+	if err != nil {
+		return notification, err
+	}
+	err = m.collection.FindOne(ctx, filter).Decode(&notification)
+	if err == mongo.ErrNoDocuments {
+		return notification, errNoNotification
+	}
+	return notification, err
+}
+
+func (m mongoNotifications) List(ctx context.Context, userID string, unreadOnly bool, page pagination.Page) ([]Notification, int64, error) {
+	filter := bson.M{"user_id": userID}
+	if unreadOnly {
+		filter["read"] = false
+	}
+	findOptions := page.FindOptions()
+	findOptions.SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	cursor, err := m.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var notifications []Notification
+	if err := cursor.All(ctx, &notifications); err != nil {
+		return nil, 0, err
+	}
+	count, err := m.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	return notifications, count, nil
+}
+
+func (m mongoNotifications) MarkRead(ctx context.Context, id string) (Notification, error) {
+	var notification Notification
+	filter, err := notificationFilter(id)
+	if err != nil {
+		return notification, err
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err = m.collection.FindOneAndUpdate(ctx, filter, bson.M{"$set": bson.M{"read": true}}, opts).Decode(&notification)
+	if err == mongo.ErrNoDocuments {
+		return notification, errNoNotification
+	}
+	return notification, err
+}
+
+// UnreadByType counts in one pass over the tenant_id, user_id, read, type
+// index, which the summary badges poll.
+func (m mongoNotifications) UnreadByType(ctx context.Context, userID string) (map[pb.NotificationType]int64, error) {
+	cursor, err := m.collection.Aggregate(ctx, bson.A{
+		bson.M{"$match": bson.M{"user_id": userID, "read": false}},
+		bson.M{"$group": bson.M{"_id": "$type", "count": bson.M{"$sum": 1}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var groups []struct {
+		Type  string `bson:"_id"`
+		Count int64  `bson:"count"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+	counts := make(map[pb.NotificationType]int64)
+	for _, g := range groups {
+		counts[Notification{Type: g.Type}.notificationType()] += g.Count
+	}
+	return counts, nil
+}
+
+// rateLimitWindowID is the _id of the user's rate limit window.
+func rateLimitWindowID(userID string, window time.Time) string {
+	return fmt.Sprintf("%s:%d", userID, window.Unix())
+}
+
+func (m mongoNotifications) CountSend(ctx context.Context, userID string, window time.Time) (int, error) {
+	filter := bson.M{"_id": rateLimitWindowID(userID, window)}
+	update := bson.M{
+		"$inc": bson.M{"count": 1},
+		"$setOnInsert": bson.M{
+			"user_id":    userID,
+			"expires_at": window.Add(2 * time.Minute),
+		},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var counter struct {
+		Count int `bson:"count"`
+	}
+	err := m.rateLimits.FindOneAndUpdate(ctx, filter, update, opts).Decode(&counter)
+	if mongo.IsDuplicateKeyError(err) {
+		// A concurrent upsert created the window first; the retry will match it
+		err = m.rateLimits.FindOneAndUpdate(ctx, filter, update, opts).Decode(&counter)
+	}
+	return counter.Count, err
+}
+
+func (m mongoNotifications) Sends(ctx context.Context, userID string, window time.Time) (int, error) {
+	var counter struct {
+		Count int `bson:"count"`
+	}
+	err := m.rateLimits.FindOne(ctx, bson.M{"_id": rateLimitWindowID(userID, window)}).Decode(&counter)
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	}
+	return counter.Count, err
+}
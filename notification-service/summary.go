@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// GetNotificationSummary counts the user's unread notifications in all and
+// by type, listing every type so a badge with none unread still shows.
+func (s *server) GetNotificationSummary(ctx context.Context, req *pb.GetNotificationSummaryRequest) (*pb.GetNotificationSummaryResponse, error) {
+	counts, err := s.repository().UnreadByType(ctx, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+	resp := &pb.GetNotificationSummaryResponse{}
+	for t := pb.NotificationType_NOTIFICATION_TYPE_TASK; int(t) < len(pb.NotificationType_name); t++ {
+		resp.Unread += counts[t]
+		resp.ByType = append(resp.ByType, &pb.NotificationTypeCount{Type: t, Unread: counts[t]})
+	}
+	return resp, nil
+}
+
+// MarkNotificationRead marks a notification read, which takes it out of its
+// user's summary.
+func (s *server) MarkNotificationRead(ctx context.Context, req *pb.MarkNotificationReadRequest) (*pb.NotificationResponse, error) {
+	notification, err := s.repository().MarkRead(ctx, req.Id)
+	if err == errNoNotification {
+		return nil, statusError(codes.NotFound, "NOTIFICATION_NOT_FOUND", map[string]string{"notification_id": req.Id}, "notification %s not found", req.Id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &pb.NotificationResponse{Notification: notification.toProto()}, nil
+}
@@ -0,0 +1,37 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/technonext/todo-app/proto/tenant"
+)
+
+func TestNotificationSummaryInMongo(t *testing.T) {
+	collection := newTestCollection(t)
+	if err := ensurePublicIDIndex(context.Background(), collection); err != nil {
+		t.Fatal(err)
+	}
+	s := &server{
+		collection:     tenant.Scoped(collection),
+		rateLimits:     tenant.Scoped(collection.Database().Collection("notification_rate_limits")),
+		rateLimit:      100,
+		collapseWindow: time.Minute,
+		deliveries:     newDeliveryQueue(collection, nil, 3),
+	}
+	testNotificationSummary(t, s)
+
+	// Stored before notifications had a type, counted as system
+	if _, err := collection.InsertOne(context.Background(), bson.M{"user_id": "u4", "message": "Old", "read": false, "tenant_id": "acme"}); err != nil {
+		t.Fatal(err)
+	}
+	counts, err := s.repository().UnreadByType(tenant.WithID(context.Background(), "acme"), "u4")
+	if err != nil || len(counts) != 1 || counts[systemType] != 1 {
+		t.Errorf("UnreadByType() = %v, %v; want 1 system", counts, err)
+	}
+}
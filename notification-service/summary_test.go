@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+	"github.com/technonext/todo-app/proto/publicid"
+	"github.com/technonext/todo-app/proto/tenant"
+)
+
+const (
+	taskType   = pb.NotificationType_NOTIFICATION_TYPE_TASK
+	systemType = pb.NotificationType_NOTIFICATION_TYPE_SYSTEM
+)
+
+// testNotificationSummary checks the summary and marking read against the
+// store behind s.
+func testNotificationSummary(t *testing.T, s *server) {
+	t.Helper()
+	ctx := tenant.WithID(context.Background(), "acme")
+	send := func(ctx context.Context, req *pb.NotificationRequest) *pb.Notification {
+		t.Helper()
+		resp, err := s.SendNotification(ctx, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.Notification
+	}
+	summary := func(userID string) (int64, map[pb.NotificationType]int64) {
+		t.Helper()
+		resp, err := s.GetNotificationSummary(ctx, &pb.GetNotificationSummaryRequest{UserId: userID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.ByType) != 2 || resp.ByType[0].Type != taskType || resp.ByType[1].Type != systemType {
+			t.Fatalf("by type = %v, want task then system", resp.ByType)
+		}
+		return resp.Unread, map[pb.NotificationType]int64{taskType: resp.ByType[0].Unread, systemType: resp.ByType[1].Unread}
+	}
+
+	due := send(ctx, &pb.NotificationRequest{UserId: "u1", Message: "Task due", Type: taskType})
+	// Collapsed into one notification
+	send(ctx, &pb.NotificationRequest{UserId: "u1", Message: "Task moved", CollapseKey: "moved", Type: taskType})
+	moved := send(ctx, &pb.NotificationRequest{UserId: "u1", Message: "Task moved again", CollapseKey: "moved", Type: taskType})
+	// Unspecified is sent as system
+	welcome := send(ctx, &pb.NotificationRequest{UserId: "u1", Message: "Welcome"})
+	send(ctx, &pb.NotificationRequest{UserId: "u1", Message: "Maintenance tonight", Type: systemType})
+	send(ctx, &pb.NotificationRequest{UserId: "u2", Message: "Task due", Type: taskType})
+	send(tenant.WithID(context.Background(), "other"), &pb.NotificationRequest{UserId: "u1", Message: "Task due", Type: taskType})
+	if welcome.Type != systemType || due.Type != taskType {
+		t.Errorf("types = %v and %v, want system and task", welcome.Type, due.Type)
+	}
+
+	if unread, byType := summary("u1"); unread != 4 || byType[taskType] != 2 || byType[systemType] != 2 {
+		t.Errorf("summary = %d unread, %v; want 4, 2 task and 2 system", unread, byType)
+	}
+	if unread, byType := summary("u3"); unread != 0 || byType[taskType] != 0 || byType[systemType] != 0 {
+		t.Errorf("summary of a user without notifications = %d unread, %v; want none", unread, byType)
+	}
+
+	read, err := s.MarkNotificationRead(ctx, &pb.MarkNotificationReadRequest{Id: due.Id})
+	if err != nil || !read.Notification.Read || read.Notification.Id != due.Id {
+		t.Fatalf("MarkNotificationRead() = %v, %v; want the notification read", read, err)
+	}
+	if _, err := s.MarkNotificationRead(ctx, &pb.MarkNotificationReadRequest{Id: moved.Id}); err != nil {
+		t.Fatal(err)
+	}
+	if unread, byType := summary("u1"); unread != 2 || byType[taskType] != 0 || byType[systemType] != 2 {
+		t.Errorf("summary after marking read = %d unread, %v; want 2 system", unread, byType)
+	}
+
+	// Another occurrence makes a collapsed notification unread again
+	send(ctx, &pb.NotificationRequest{UserId: "u1", Message: "Task moved back", CollapseKey: "moved", Type: taskType})
+	if unread, byType := summary("u1"); unread != 3 || byType[taskType] != 1 {
+		t.Errorf("summary after another occurrence = %d unread, %v; want 3 with 1 task", unread, byType)
+	}
+
+	if _, err := s.MarkNotificationRead(tenant.WithID(context.Background(), "other"), &pb.MarkNotificationReadRequest{Id: welcome.Id}); status.Code(err) != codes.NotFound {
+		t.Errorf("MarkNotificationRead() for another tenant = %v, want NotFound", err)
+	}
+	if _, err := s.MarkNotificationRead(ctx, &pb.MarkNotificationReadRequest{Id: publicid.New(publicid.Notification)}); status.Code(err) != codes.NotFound {
+		t.Errorf("MarkNotificationRead() of an unknown notification = %v, want NotFound", err)
+	}
+}
+
+func TestNotificationSummaryInMemory(t *testing.T) {
+	testNotificationSummary(t, &server{
+		notifications:  newMemoryNotifications(),
+		deliveries:     newDeliveryQueue(nil, nil, 1),
+		rateLimit:      100,
+		collapseWindow: time.Minute,
+	})
+}
+
+func TestNotificationTypeOfStoredNotifications(t *testing.T) {
+	tests := []struct {
+		stored string
+		want   pb.NotificationType
+	}{
+		{"NOTIFICATION_TYPE_TASK", taskType},
+		{"NOTIFICATION_TYPE_SYSTEM", systemType},
+		// Stored before notifications had a type
+		{"", systemType},
+		// Written by a newer release
+		{"NOTIFICATION_TYPE_BILLING", systemType},
+	}
+	for _, tt := range tests {
+		if got := (Notification{Type: tt.stored}).notificationType(); got != tt.want {
+			t.Errorf("notificationType() of %q = %v, want %v", tt.stored, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"text/template"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/grpc/codes"
+
+	"github.com/technonext/todo-app/proto/pagination"
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// templateInUseWindow is how far back a notification keeps its template from
+// being deleted.
+const templateInUseWindow = 7 * 24 * time.Hour
+
+// Template statuses. Templates stored before statuses existed have none and
+// count as active.
+const (
+	templateDraft  = "draft"
+	templateActive = "active"
+)
+
+type Template struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	Name      string             `bson:"name"`
+	Language  string             `bson:"language"`
+	Subject   string             `bson:"subject"`
+	Body      string             `bson:"body"`
+	CreatedAt string             `bson:"created_at"`
+	UpdatedAt string             `bson:"updated_at"`
+	Status    string             `bson:"status,omitempty"`
+}
+
+func (t Template) active() bool {
+	return t.Status != templateDraft
+}
+
+func (t Template) toProto() *pb.Template {
+	p := &pb.Template{
+		Id:        t.ID.Hex(),
+		Name:      t.Name,
+		Language:  t.Language,
+		Subject:   t.Subject,
+		Body:      t.Body,
+		CreatedAt: t.CreatedAt,
+		UpdatedAt: t.UpdatedAt,
+		Status:    pb.TemplateStatus_TEMPLATE_STATUS_ACTIVE,
+	}
+	if !t.active() {
+		p.Status = pb.TemplateStatus_TEMPLATE_STATUS_DRAFT
+	}
+	return p
+}
+
+// parseTemplate checks that subject and body are valid Go templates.
+func parseTemplate(subject, body string) error {
+	if _, err := template.New("subject").Parse(subject); err != nil {
+		return statusError(codes.InvalidArgument, "INVALID_TEMPLATE", map[string]string{"field": "subject"}, "invalid template subject: %v", err)
+	}
+	if _, err := template.New("body").Parse(body); err != nil {
+		return statusError(codes.InvalidArgument, "INVALID_TEMPLATE", map[string]string{"field": "body"}, "invalid template body: %v", err)
+	}
+	return nil
+}
+
+func render(name, text string, variables map[string]string) (string, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var out strings.Builder
+	if err := tmpl.Execute(&out, variables); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// renderTemplate loads an active template and renders its subject and body.
+func (s *server) renderTemplate(ctx context.Context, id string, variables map[string]string) (string, string, error) {
+	t, err := s.findTemplate(ctx, id)
+	if err != nil {
+		return "", "", err
+	}
+	if !t.active() {
+		return "", "", statusError(codes.FailedPrecondition, "TEMPLATE_NOT_ACTIVE", map[string]string{"template_id": id}, "template %s is a draft: activate it before sending", id)
+	}
+	subject, err := render("subject", t.Subject, variables)
+	if err != nil {
+		return "", "", statusError(codes.InvalidArgument, "TEMPLATE_RENDER_FAILED", map[string]string{"template_id": id, "field": "subject"}, "render template %s: %v", id, err)
+	}
+	body, err := render("body", t.Body, variables)
+	if err != nil {
+		return "", "", statusError(codes.InvalidArgument, "TEMPLATE_RENDER_FAILED", map[string]string{"template_id": id, "field": "body"}, "render template %s: %v", id, err)
+	}
+	return subject, body, nil
+}
+
+func (s *server) findTemplate(ctx context.Context, id string) (Template, error) {
+	var t Template
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return t, statusError(codes.InvalidArgument, "INVALID_TEMPLATE_ID", map[string]string{"template_id": id}, "invalid template id %q", id)
+	}
+	err = s.templates.FindOne(ctx, bson.M{"_id": oid}).Decode(&t)
+	if err == mongo.ErrNoDocuments {
+		return t, statusError(codes.NotFound, "TEMPLATE_NOT_FOUND", map[string]string{"template_id": id}, "template %s not found", id)
+	}
+	return t, err
+}
+
+func (s *server) CreateTemplate(ctx context.Context, req *pb.CreateTemplateRequest) (*pb.TemplateResponse, error) {
+	if req.Name == "" || req.Body == "" {
+		return nil, statusError(codes.InvalidArgument, "TEMPLATE_FIELDS_REQUIRED", nil, "name and body are required")
+	}
+	if err := parseTemplate(req.Subject, req.Body); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	t := Template{
+		Name:      req.Name,
+		Language:  req.Language,
+		Subject:   req.Subject,
+		Body:      req.Body,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Status:    templateActive,
+	}
+	return s.insertTemplate(ctx, t)
+}
+
+func (s *server) insertTemplate(ctx context.Context, t Template) (*pb.TemplateResponse, error) {
+	result, err := s.templates.InsertOne(ctx, t)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, statusError(codes.AlreadyExists, "TEMPLATE_EXISTS", map[string]string{"name": t.Name, "language": t.Language}, "template %q already exists for language %q", t.Name, t.Language)
+	}
+	if err != nil {
+		return nil, err
+	}
+	t.ID = result.InsertedID.(primitive.ObjectID)
+
+	return &pb.TemplateResponse{Template: t.toProto()}, nil
+}
+
+// CloneTemplate copies a template under a new name, and optionally another
+// language, as a draft that can be edited before it is activated. Only active
+// templates are cloned; a draft is edited in place instead.
+func (s *server) CloneTemplate(ctx context.Context, req *pb.CloneTemplateRequest) (*pb.TemplateResponse, error) {
+	if req.NewName == "" {
+		return nil, statusError(codes.InvalidArgument, "TEMPLATE_FIELDS_REQUIRED", map[string]string{"field": "new_name"}, "new_name is required")
+	}
+	source, err := s.findTemplate(ctx, req.SourceTemplateId)
+	if err != nil {
+		return nil, err
+	}
+	if !source.active() {
+		return nil, statusError(codes.FailedPrecondition, "TEMPLATE_NOT_ACTIVE", map[string]string{"template_id": req.SourceTemplateId}, "template %s is a draft: activate it before cloning", req.SourceTemplateId)
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	clone := source
+	clone.ID = primitive.NilObjectID
+	clone.Name = req.NewName
+	if req.Language != "" {
+		clone.Language = req.Language
+	}
+	clone.CreatedAt = now
+	clone.UpdatedAt = now
+	clone.Status = templateDraft
+	return s.insertTemplate(ctx, clone)
+}
+
+// ActivateTemplate makes a draft template available to SendNotification.
+// Activating an active template changes nothing.
+func (s *server) ActivateTemplate(ctx context.Context, req *pb.ActivateTemplateRequest) (*pb.TemplateResponse, error) {
+	t, err := s.findTemplate(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	if t.active() {
+		return &pb.TemplateResponse{Template: t.toProto()}, nil
+	}
+
+	t.Status = templateActive
+	t.UpdatedAt = time.Now().Format(time.RFC3339)
+	_, err = s.templates.UpdateOne(ctx, bson.M{"_id": t.ID}, bson.M{"$set": bson.M{"status": t.Status, "updated_at": t.UpdatedAt}})
+	if err != nil {
+		return nil, err
+	}
+	return &pb.TemplateResponse{Template: t.toProto()}, nil
+}
+
+func (s *server) ListTemplates(ctx context.Context, req *pb.ListTemplatesRequest) (*pb.ListTemplatesResponse, error) {
+	filter := bson.M{}
+	if req.Language != "" {
+		filter["language"] = req.Language
+	}
+
+	page, err := pagination.Resolve(req.Pagination, req.Page, req.Limit, pagination.DefaultLimits)
+	if err != nil {
+		return nil, err
+	}
+	findOptions := page.FindOptions()
+	findOptions.SetSort(bson.D{{Key: "name", Value: 1}, {Key: "language", Value: 1}})
+
+	cursor, err := s.templates.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var templates []*pb.Template
+	for cursor.Next(ctx) {
+		var t Template
+		if err := cursor.Decode(&t); err != nil {
+			return nil, err
+		}
+		templates = append(templates, t.toProto())
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	count, err := s.templates.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.ListTemplatesResponse{
+		Templates:  templates,
+		Total:      int32(count),
+		Pagination: page.Response(count, len(templates)),
+	}, nil
+}
+
+func (s *server) GetTemplate(ctx context.Context, req *pb.GetTemplateRequest) (*pb.TemplateResponse, error) {
+	t, err := s.findTemplate(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.TemplateResponse{Template: t.toProto()}, nil
+}
+
+// UpdateTemplate only changes the fields named in update_mask.
+func (s *server) UpdateTemplate(ctx context.Context, req *pb.UpdateTemplateRequest) (*pb.TemplateResponse, error) {
+	if req.Template == nil {
+		return nil, statusError(codes.InvalidArgument, "TEMPLATE_REQUIRED", nil, "template is required")
+	}
+	current, err := s.findTemplate(ctx, req.Template.Id)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.UpdateMask.GetPaths()) == 0 {
+		return nil, statusError(codes.InvalidArgument, "EMPTY_UPDATE_MASK", nil, "update_mask must name at least one field")
+	}
+
+	set := bson.M{}
+	for _, path := range req.UpdateMask.GetPaths() {
+		switch path {
+		case "name":
+			if req.Template.Name == "" {
+				return nil, statusError(codes.InvalidArgument, "TEMPLATE_FIELDS_REQUIRED", map[string]string{"field": "name"}, "name cannot be empty")
+			}
+			current.Name = req.Template.Name
+			set["name"] = current.Name
+		case "language":
+			current.Language = req.Template.Language
+			set["language"] = current.Language
+		case "subject":
+			current.Subject = req.Template.Subject
+			set["subject"] = current.Subject
+		case "body":
+			if req.Template.Body == "" {
+				return nil, statusError(codes.InvalidArgument, "TEMPLATE_FIELDS_REQUIRED", map[string]string{"field": "body"}, "body cannot be empty")
+			}
+			current.Body = req.Template.Body
+			set["body"] = current.Body
+		default:
+			return nil, statusError(codes.InvalidArgument, "INVALID_UPDATE_MASK", map[string]string{"path": path}, "unknown update_mask path %q", path)
+		}
+	}
+	if err := parseTemplate(current.Subject, current.Body); err != nil {
+		return nil, err
+	}
+
+	current.UpdatedAt = time.Now().Format(time.RFC3339)
+	set["updated_at"] = current.UpdatedAt
+
+	_, err = s.templates.UpdateOne(ctx, bson.M{"_id": current.ID}, bson.M{"$set": set})
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, statusError(codes.AlreadyExists, "TEMPLATE_EXISTS", map[string]string{"name": current.Name, "language": current.Language}, "template %q already exists for language %q", current.Name, current.Language)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.TemplateResponse{Template: current.toProto()}, nil
+}
+
+// DeleteTemplate refuses to remove a template that recent notifications were
+// rendered from, so their history stays explainable.
+func (s *server) DeleteTemplate(ctx context.Context, req *pb.DeleteTemplateRequest) (*pb.DeleteTemplateResponse, error) {
+	t, err := s.findTemplate(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	since := time.Now().Add(-templateInUseWindow).Format(time.RFC3339)
+	inUse, err := s.collection.CountDocuments(ctx, bson.M{
+		"template_id": req.Id,
+		"created_at":  bson.M{"$gte": since},
+	}, options.Count().SetLimit(1))
+	if err != nil {
+		return nil, err
+	}
+	if inUse > 0 {
+		return nil, statusError(codes.FailedPrecondition, "TEMPLATE_IN_USE", map[string]string{"template_id": req.Id}, "template %s was used by a notification in the last 7 days", req.Id)
+	}
+
+	result, err := s.templates.DeleteOne(ctx, bson.M{"_id": t.ID})
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.DeleteTemplateResponse{Success: result.DeletedCount > 0}, nil
+}
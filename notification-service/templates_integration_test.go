@@ -0,0 +1,123 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+	"github.com/technonext/todo-app/proto/tenant"
+)
+
+// newTemplateTestServer returns a server sending notifications from
+// templates kept in MongoDB.
+func newTemplateTestServer(t *testing.T) *server {
+	t.Helper()
+	s := newPublicIDTestServer(t)
+	s.templates = tenant.Scoped(s.collection.Database().Collection("notification_templates"))
+	return s
+}
+
+// createTemplate creates an active template called name.
+func createTemplate(t *testing.T, s *server, name string) *pb.Template {
+	t.Helper()
+	resp, err := s.CreateTemplate(context.Background(), &pb.CreateTemplateRequest{Name: name, Language: "en", Subject: "Hi {{.name}}", Body: "Hello {{.name}}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.Template
+}
+
+func TestDeleteTemplateInUse(t *testing.T) {
+	s := newTemplateTestServer(t)
+	ctx := context.Background()
+
+	used := createTemplate(t, s, "used")
+	if _, err := s.SendNotification(ctx, &pb.NotificationRequest{UserId: "u1", TemplateId: used.Id, Variables: map[string]string{"name": "Ann"}}); err != nil {
+		t.Fatal(err)
+	}
+	// Last used before the in-use window
+	usedLongAgo := createTemplate(t, s, "used long ago")
+	_, err := s.collection.InsertOne(ctx, bson.M{
+		"user_id": "u1", "message": "Hello Ann", "template_id": usedLongAgo.Id,
+		"created_at": time.Now().Add(-templateInUseWindow - time.Hour).Format(time.RFC3339),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	unused := createTemplate(t, s, "unused")
+
+	_, err = s.DeleteTemplate(ctx, &pb.DeleteTemplateRequest{Id: used.Id})
+	if status.Code(err) != codes.FailedPrecondition || errorReason(err) != "TEMPLATE_IN_USE" {
+		t.Errorf("deleting a template used today = %v, want FailedPrecondition with TEMPLATE_IN_USE", err)
+	}
+	if _, err := s.GetTemplate(ctx, &pb.GetTemplateRequest{Id: used.Id}); err != nil {
+		t.Errorf("template refused deletion is gone: %v", err)
+	}
+
+	for _, template := range []*pb.Template{usedLongAgo, unused} {
+		resp, err := s.DeleteTemplate(ctx, &pb.DeleteTemplateRequest{Id: template.Id})
+		if err != nil || !resp.Success {
+			t.Errorf("deleting %q = %v, %v; want it deleted", template.Name, resp, err)
+		}
+		if _, err := s.GetTemplate(ctx, &pb.GetTemplateRequest{Id: template.Id}); status.Code(err) != codes.NotFound {
+			t.Errorf("GetTemplate of deleted %q = %v, want NotFound", template.Name, err)
+		}
+	}
+}
+
+func TestCloneTemplate(t *testing.T) {
+	s := newTemplateTestServer(t)
+	ctx := context.Background()
+	source := createTemplate(t, s, "welcome")
+
+	resp, err := s.CloneTemplate(ctx, &pb.CloneTemplateRequest{SourceTemplateId: source.Id, NewName: "welcome back", Language: "bn"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	clone := resp.Template
+	if clone.Id == "" || clone.Id == source.Id {
+		t.Errorf("clone id = %q, want a new id besides the source's %q", clone.Id, source.Id)
+	}
+	if clone.Name != "welcome back" || clone.Language != "bn" || clone.Subject != source.Subject || clone.Body != source.Body ||
+		clone.Status != pb.TemplateStatus_TEMPLATE_STATUS_DRAFT {
+		t.Errorf("clone = %v, want a draft copy of %v named %q in bn", clone, source, "welcome back")
+	}
+	got, err := s.GetTemplate(ctx, &pb.GetTemplateRequest{Id: source.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(got.Template, source) {
+		t.Errorf("source after cloning = %v, want it unchanged from %v", got.Template, source)
+	}
+
+	// The clone is a draft until it is activated
+	send := &pb.NotificationRequest{UserId: "u1", TemplateId: clone.Id, Variables: map[string]string{"name": "Ann"}}
+	_, err = s.SendNotification(ctx, send)
+	if status.Code(err) != codes.FailedPrecondition || errorReason(err) != "TEMPLATE_NOT_ACTIVE" {
+		t.Errorf("sending with a draft = %v, want FailedPrecondition with TEMPLATE_NOT_ACTIVE", err)
+	}
+	_, err = s.CloneTemplate(ctx, &pb.CloneTemplateRequest{SourceTemplateId: clone.Id, NewName: "welcome again"})
+	if status.Code(err) != codes.FailedPrecondition || errorReason(err) != "TEMPLATE_NOT_ACTIVE" {
+		t.Errorf("cloning a draft = %v, want FailedPrecondition with TEMPLATE_NOT_ACTIVE", err)
+	}
+	if _, err := s.ActivateTemplate(ctx, &pb.ActivateTemplateRequest{Id: clone.Id}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.SendNotification(ctx, send); err != nil {
+		t.Errorf("sending with the activated clone = %v, want it sent", err)
+	}
+
+	_, err = s.CloneTemplate(ctx, &pb.CloneTemplateRequest{SourceTemplateId: primitive.NewObjectID().Hex(), NewName: "missing"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("cloning a missing template = %v, want NotFound", err)
+	}
+}
@@ -0,0 +1,180 @@
+// Package backup serves the BackupService, which copies one of a service's
+// MongoDB collections to a bucket for disaster recovery, so operators can
+// schedule backups through the gateway's POST /api/admin/backup rather than
+// running cmd/backup next to the database.
+//
+// A backup is one gzip'd NDJSON file of the collection's documents in
+// canonical extended JSON, the format cmd/backup writes and restores, named
+// after the collection and the time it was taken:
+//
+//	s3://todo-backups/nightly/tasks-20261016T020000Z.ndjson.gz
+//
+// BACKUP_PROVIDER picks where backups go; see FromEnv. The file is written
+// to a temporary file first and uploaded once complete, so a failed backup
+// leaves nothing behind in the bucket.
+package backup
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// contentType is the type of backup files.
+const contentType = "application/gzip"
+
+// errorDomain identifies these errors in their ErrorInfo.
+const errorDomain = "backup"
+
+// omittedFields are left out of backups, by collection. Backups travel to
+// buckets other people can read, so they never hold password hashes; use
+// cmd/backup -include-password-hashes to keep them.
+var omittedFields = map[string][]string{
+	"users": {"password"},
+}
+
+// collectionPattern matches the names of collections that may be backed up,
+// leaving out MongoDB's system collections.
+var collectionPattern = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9_.-]*$`)
+
+// Server backs up the collections of one database.
+type Server struct {
+	pb.UnimplementedBackupServiceServer
+	db    *mongo.Database
+	store Store
+	now   func() time.Time
+}
+
+// NewServer creates a Server backing up db's collections to store.
+func NewServer(db *mongo.Database, store Store) *Server {
+	return &Server{db: db, store: store, now: time.Now}
+}
+
+// Register serves the BackupService on srv.
+func (s *Server) Register(srv *grpc.Server) {
+	if s != nil {
+		pb.RegisterBackupServiceServer(srv, s)
+	}
+}
+
+// BackupCollection writes the collection to the bucket. Documents written
+// during the backup may or may not be in it.
+func (s *Server) BackupCollection(ctx context.Context, req *pb.BackupRequest) (*pb.BackupResponse, error) {
+	name := req.CollectionName
+	if !collectionPattern.MatchString(name) || strings.HasPrefix(name, "system.") {
+		return nil, statusError(codes.InvalidArgument, "INVALID_COLLECTION", map[string]string{"collection_name": name}, "invalid collection name %q", name)
+	}
+	loc, err := parseBucket(req.Bucket, s.store.Scheme())
+	if err != nil {
+		return nil, statusError(codes.InvalidArgument, "INVALID_BUCKET", map[string]string{"bucket": req.Bucket}, "%v", err)
+	}
+	names, err := s.db.ListCollectionNames(ctx, bson.M{"name": name})
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, statusError(codes.NotFound, "COLLECTION_NOT_FOUND", map[string]string{"collection_name": name}, "collection %s not found", name)
+	}
+
+	cursor, err := s.db.Collection(name).Find(ctx, bson.D{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	return s.write(ctx, cursor, name, loc)
+}
+
+// write uploads the documents of cursor, from the collection name, to loc.
+func (s *Server) write(ctx context.Context, cursor *mongo.Cursor, name string, loc location) (*pb.BackupResponse, error) {
+	tmp, err := os.CreateTemp("", "backup-*.ndjson.gz")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}()
+
+	count, err := writeDocuments(ctx, tmp, cursor, omittedFields[name])
+	if err != nil {
+		return nil, fmt.Errorf("backing up %s: %w", name, err)
+	}
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	file := fmt.Sprintf("%s-%s.ndjson.gz", name, s.now().UTC().Format("20060102T150405Z"))
+	key := loc.key(file)
+	if err := s.store.Put(ctx, loc.bucket, key, tmp, size); err != nil {
+		return nil, fmt.Errorf("uploading the backup of %s: %w", name, err)
+	}
+	uri := fmt.Sprintf("%s://%s/%s", s.store.Scheme(), loc.bucket, key)
+	slog.Info("Backed up collection", "collection", name, "file", uri, "documents", count, "bytes", size)
+	return &pb.BackupResponse{FileName: uri, DocumentCount: count, SizeBytes: size}, nil
+}
+
+// writeDocuments writes the documents of cursor to w as gzip'd lines of
+// canonical extended JSON, without the fields omitted, and counts them.
+func writeDocuments(ctx context.Context, w io.Writer, cursor *mongo.Cursor, omitted []string) (int64, error) {
+	buf := bufio.NewWriter(w)
+	zw := gzip.NewWriter(buf)
+	var count int64
+	for cursor.Next(ctx) {
+		var doc interface{} = cursor.Current
+		if len(omitted) > 0 {
+			var d bson.D
+			if err := bson.Unmarshal(cursor.Current, &d); err != nil {
+				return count, err
+			}
+			doc = slices.DeleteFunc(d, func(e bson.E) bool { return slices.Contains(omitted, e.Key) })
+		}
+		// Canonical extended JSON keeps every BSON type, so documents come
+		// back exactly as they were
+		line, err := bson.MarshalExtJSON(doc, true, false)
+		if err != nil {
+			return count, err
+		}
+		if _, err := zw.Write(append(line, '\n')); err != nil {
+			return count, err
+		}
+		count++
+	}
+	if err := cursor.Err(); err != nil {
+		return count, err
+	}
+	if err := zw.Close(); err != nil {
+		return count, err
+	}
+	return count, buf.Flush()
+}
+
+func statusError(code codes.Code, reason string, metadata map[string]string, format string, args ...interface{}) error {
+	st := status.Newf(code, format, args...)
+	detailed, err := st.WithDetails(&errdetails.ErrorInfo{Domain: errorDomain, Reason: reason, Metadata: metadata})
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
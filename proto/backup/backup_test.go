@@ -0,0 +1,205 @@
+package backup
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+func TestLocalBackup(t *testing.T) {
+	dir := t.TempDir()
+	s := &Server{store: &LocalStore{Dir: dir}, now: func() time.Time {
+		return time.Date(2026, 10, 16, 2, 0, 0, 0, time.UTC)
+	}}
+	created := primitive.NewDateTimeFromTime(time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC))
+	docs := []interface{}{
+		bson.D{{Key: "_id", Value: "u1"}, {Key: "email", Value: "ada@example.com"}, {Key: "password", Value: "$2a$10$hash"}, {Key: "created_at", Value: created}},
+		bson.D{{Key: "_id", Value: "u2"}, {Key: "email", Value: "alan@example.com"}, {Key: "password", Value: "$2a$10$other"}, {Key: "age", Value: int64(41)}},
+	}
+	cursor, err := mongo.NewCursorFromDocuments(docs, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loc, err := parseBucket("file://nightly/2026/10", "file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := s.write(context.Background(), cursor, "users", loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "file://nightly/2026/10/users-20261016T020000Z.ndjson.gz"; resp.FileName != want {
+		t.Errorf("file name = %q, want %q", resp.FileName, want)
+	}
+	if resp.DocumentCount != 2 {
+		t.Errorf("document count = %d, want 2", resp.DocumentCount)
+	}
+	path := filepath.Join(dir, "nightly", "2026", "10", "users-20261016T020000Z.ndjson.gz")
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != resp.SizeBytes {
+		t.Errorf("size = %d, want the file's %d bytes", resp.SizeBytes, info.Size())
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []bson.D
+	lines := bufio.NewScanner(zr)
+	for lines.Scan() {
+		var doc bson.D
+		if err := bson.UnmarshalExtJSON(lines.Bytes(), true, &doc); err != nil {
+			t.Fatalf("line %q: %v", lines.Text(), err)
+		}
+		got = append(got, doc)
+	}
+	if err := lines.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("file holds %d documents, want 2", len(got))
+	}
+	// The types survive, and the password hashes are left out
+	first := got[0].Map()
+	if first["_id"] != "u1" || first["email"] != "ada@example.com" || first["created_at"] != created {
+		t.Errorf("first document = %v, want u1 as written", got[0])
+	}
+	if got[1].Map()["age"] != int64(41) {
+		t.Errorf("age = %T %v, want int64 41", got[1].Map()["age"], got[1].Map()["age"])
+	}
+	for _, doc := range got {
+		if _, ok := doc.Map()["password"]; ok {
+			t.Errorf("document %v holds its password hash", doc)
+		}
+	}
+}
+
+func TestLocalBackupKeepsExistingFiles(t *testing.T) {
+	dir := t.TempDir()
+	s := &Server{store: &LocalStore{Dir: dir}, now: func() time.Time { return time.Unix(0, 0) }}
+	loc := location{bucket: "nightly"}
+	for i, want := range []codes.Code{codes.OK, codes.Unknown} {
+		cursor, err := mongo.NewCursorFromDocuments([]interface{}{bson.D{{Key: "_id", Value: i}}}, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.write(context.Background(), cursor, "tasks", loc); status.Code(err) != want {
+			t.Errorf("backup %d = %v, want %v", i, err, want)
+		}
+	}
+	entries, err := os.ReadDir(filepath.Join(dir, "nightly"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("bucket holds %d files, want the first backup only", len(entries))
+	}
+}
+
+func TestParseBucket(t *testing.T) {
+	tests := []struct {
+		raw, scheme string
+		want        location
+		ok          bool
+	}{
+		{"s3://todo-backups", "s3", location{bucket: "todo-backups"}, true},
+		{"s3://todo-backups/nightly/", "s3", location{bucket: "todo-backups", prefix: "nightly"}, true},
+		{"gs://todo-backups/a/b", "gs", location{bucket: "todo-backups", prefix: "a/b"}, true},
+		{"file://nightly", "file", location{bucket: "nightly"}, true},
+		{"gs://todo-backups", "s3", location{}, false},
+		{"todo-backups", "s3", location{}, false},
+		{"s3:///nightly", "s3", location{}, false},
+		{"file://../etc", "file", location{}, false},
+		{"file://nightly/../../etc", "file", location{}, false},
+		{"file://nightly/%2e%2e/%2e%2e", "file", location{}, false},
+		{"file://nightly/a\\..\\b", "file", location{}, false},
+		{"s3://user:secret@todo-backups", "s3", location{}, false},
+		{"s3://todo-backups:9000", "s3", location{}, false},
+		{"s3://todo-backups?versioned=true", "s3", location{}, false},
+	}
+	for _, tt := range tests {
+		got, err := parseBucket(tt.raw, tt.scheme)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("parseBucket(%q, %q) = %v, %v; want %v, ok %v", tt.raw, tt.scheme, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestBackupCollectionValidation(t *testing.T) {
+	s := NewServer(nil, &LocalStore{Dir: t.TempDir()})
+	tests := []struct {
+		name   string
+		req    *pb.BackupRequest
+		reason string
+	}{
+		{"system collection", &pb.BackupRequest{CollectionName: "system.users", Bucket: "file://nightly"}, "INVALID_COLLECTION"},
+		{"collection with a slash", &pb.BackupRequest{CollectionName: "tasks/../users", Bucket: "file://nightly"}, "INVALID_COLLECTION"},
+		{"bucket of another provider", &pb.BackupRequest{CollectionName: "tasks", Bucket: "s3://nightly"}, "INVALID_BUCKET"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.BackupCollection(context.Background(), tt.req)
+			st := status.Convert(err)
+			if st.Code() != codes.InvalidArgument || len(st.Details()) == 0 {
+				t.Fatalf("err = %v, want InvalidArgument", err)
+			}
+			if info, ok := st.Details()[0].(*errdetails.ErrorInfo); !ok || info.Reason != tt.reason {
+				t.Errorf("details = %v, want reason %s", st.Details(), tt.reason)
+			}
+		})
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	tests := []struct {
+		env    map[string]string
+		scheme string
+		ok     bool
+	}{
+		{map[string]string{}, "", true},
+		{map[string]string{"BACKUP_PROVIDER": "local", "BACKUP_LOCAL_DIR": "/var/backups"}, "file", true},
+		{map[string]string{"BACKUP_PROVIDER": "gcs", "GCS_HMAC_ACCESS_KEY_ID": "GOOG1E", "GCS_HMAC_SECRET": "secret"}, "gs", true},
+		{map[string]string{"BACKUP_PROVIDER": "gcs"}, "", false},
+		{map[string]string{"BACKUP_PROVIDER": "ftp"}, "", false},
+	}
+	for _, tt := range tests {
+		for _, key := range []string{"BACKUP_PROVIDER", "BACKUP_LOCAL_DIR", "GCS_HMAC_ACCESS_KEY_ID", "GCS_HMAC_SECRET"} {
+			t.Setenv(key, tt.env[key])
+		}
+		store, err := FromEnv(context.Background())
+		if (err == nil) != tt.ok {
+			t.Errorf("FromEnv with %v = %v, want ok %v", tt.env, err, tt.ok)
+			continue
+		}
+		var scheme string
+		if store != nil {
+			scheme = store.Scheme()
+		}
+		if scheme != tt.scheme {
+			t.Errorf("FromEnv with %v scheme = %q, want %q", tt.env, scheme, tt.scheme)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// Store keeps backup files in buckets.
+type Store interface {
+	// Scheme is the URI scheme of the store's buckets
+	Scheme() string
+	// Put writes the size bytes of body as key in bucket
+	Put(ctx context.Context, bucket, key string, body io.ReadSeeker, size int64) error
+}
+
+// gcsEndpoint serves Cloud Storage's S3-compatible XML API.
+const gcsEndpoint = "https://storage.googleapis.com"
+
+// FromEnv returns the store BACKUP_PROVIDER names, or nil when it is unset:
+//
+//   - s3 writes to Amazon S3 with the credentials and region of the AWS
+//     SDK's usual environment variables and shared files
+//   - gcs writes to Cloud Storage with the HMAC key in GCS_HMAC_ACCESS_KEY_ID
+//     and GCS_HMAC_SECRET, through its S3-compatible API
+//   - local writes below BACKUP_LOCAL_DIR, ./backups by default, for
+//     development and tests
+func FromEnv(ctx context.Context) (Store, error) {
+	switch provider := os.Getenv("BACKUP_PROVIDER"); provider {
+	case "":
+		return nil, nil
+	case "s3":
+		cfg, err := config.LoadDefaultConfig(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("loading AWS configuration: %v", err)
+		}
+		return &s3Store{scheme: "s3", client: s3.NewFromConfig(cfg)}, nil
+	case "gcs":
+		keyID, secret := os.Getenv("GCS_HMAC_ACCESS_KEY_ID"), os.Getenv("GCS_HMAC_SECRET")
+		if keyID == "" || secret == "" {
+			return nil, errors.New("BACKUP_PROVIDER=gcs needs GCS_HMAC_ACCESS_KEY_ID and GCS_HMAC_SECRET")
+		}
+		client := s3.New(s3.Options{
+			Region:       "auto",
+			BaseEndpoint: aws.String(gcsEndpoint),
+			Credentials:  credentials.NewStaticCredentialsProvider(keyID, secret, ""),
+			// Cloud Storage rejects the checksums the SDK adds by default
+			RequestChecksumCalculation: aws.RequestChecksumCalculationWhenRequired,
+			ResponseChecksumValidation: aws.ResponseChecksumValidationWhenRequired,
+		})
+		return &s3Store{scheme: "gs", client: client}, nil
+	case "local":
+		dir := os.Getenv("BACKUP_LOCAL_DIR")
+		if dir == "" {
+			dir = "backups"
+		}
+		return &LocalStore{Dir: dir}, nil
+	default:
+		return nil, fmt.Errorf("invalid BACKUP_PROVIDER %q: use s3, gcs or local", provider)
+	}
+}
+
+// s3Store writes to S3, or to Cloud Storage through its S3-compatible API.
+type s3Store struct {
+	scheme string
+	client *s3.Client
+}
+
+func (s *s3Store) Scheme() string {
+	return s.scheme
+}
+
+func (s *s3Store) Put(ctx context.Context, bucket, key string, body io.ReadSeeker, size int64) error {
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(bucket),
+		Key:           aws.String(key),
+		Body:          body,
+		ContentLength: aws.Int64(size),
+		ContentType:   aws.String(contentType),
+	})
+	return err
+}
+
+// LocalStore writes each bucket as a directory below Dir.
+type LocalStore struct {
+	Dir string
+}
+
+func (s *LocalStore) Scheme() string {
+	return "file"
+}
+
+func (s *LocalStore) Put(ctx context.Context, bucket, key string, body io.ReadSeeker, size int64) (err error) {
+	dest := filepath.Join(s.Dir, bucket, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(dest)
+		}
+	}()
+	_, err = io.Copy(f, body)
+	return err
+}
+
+// location is where in a store a backup goes.
+type location struct {
+	bucket, prefix string
+}
+
+// parseBucket reads a bucket URI of scheme, such as s3://bucket/prefix.
+// Bucket names and prefixes may not climb out of their bucket with "..",
+// which would let a local backup write anywhere.
+func parseBucket(raw, scheme string) (location, error) {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != scheme || u.Host == "" || u.Port() != "" || u.User != nil || u.RawQuery != "" || u.Fragment != "" {
+		return location{}, fmt.Errorf("bucket must look like %s://bucket/prefix", scheme)
+	}
+	prefix := strings.Trim(u.Path, "/")
+	for _, part := range append([]string{u.Host}, strings.Split(prefix, "/")...) {
+		if part == ".." || part == "." || strings.Contains(part, `\`) {
+			return location{}, fmt.Errorf("bucket %q may not contain %q", raw, part)
+		}
+	}
+	return location{bucket: u.Host, prefix: prefix}, nil
+}
+
+// key is the object name of file in l.
+func (l location) key(file string) string {
+	return path.Join(l.prefix, file)
+}
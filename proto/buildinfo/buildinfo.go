@@ -0,0 +1,98 @@
+// Package buildinfo tells which build of a service is running, how long it
+// has been up and the settings it runs with, over the InfoService and at
+// /debug/info next to the metrics.
+//
+// The build is stamped at link time, as the Dockerfiles do:
+//
+//	go build -ldflags "-X github.com/technonext/todo-app/proto/buildinfo.Version=v1.4.0
+//	    -X github.com/technonext/todo-app/proto/buildinfo.Commit=$(git rev-parse HEAD)
+//	    -X github.com/technonext/todo-app/proto/buildinfo.Date=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+package buildinfo
+
+import (
+	"context"
+	"net/http"
+	"runtime"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/technonext/todo-app/proto/logging"
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// Set with -ldflags -X; builds without them report a dev build.
+var (
+	Version = "dev"
+	Commit  = "unknown"
+	Date    = "unknown"
+)
+
+// started approximates when the process started.
+var started = time.Now()
+
+// Server reports on one service.
+type Server struct {
+	pb.UnimplementedInfoServiceServer
+	service string
+	config  map[string]string
+}
+
+// NewServer creates a Server for service, showing config, its settings by
+// environment variable. Settings named like secrets are dropped and
+// credentials in the others redacted, so a caller passing its whole
+// configuration leaks nothing.
+func NewServer(service string, config map[string]string) *Server {
+	safe := make(map[string]string, len(config))
+	for key, value := range config {
+		if !logging.IsSecretKey(key) {
+			safe[key] = logging.Redact(value)
+		}
+	}
+	return &Server{service: service, config: safe}
+}
+
+// Info returns the report.
+func (s *Server) Info() *pb.ServiceInfo {
+	config := make(map[string]string, len(s.config))
+	for key, value := range s.config {
+		config[key] = value
+	}
+	return &pb.ServiceInfo{
+		Service:       s.service,
+		Version:       Version,
+		Commit:        Commit,
+		BuildDate:     Date,
+		GoVersion:     runtime.Version(),
+		UptimeSeconds: time.Since(started).Seconds(),
+		Config:        config,
+	}
+}
+
+// Register serves the InfoService on srv.
+func (s *Server) Register(srv *grpc.Server) {
+	if s != nil {
+		pb.RegisterInfoServiceServer(srv, s)
+	}
+}
+
+func (s *Server) GetServiceInfo(ctx context.Context, req *pb.GetServiceInfoRequest) (*pb.ServiceInfo, error) {
+	return s.Info(), nil
+}
+
+// ServeHTTP answers GET /debug/info with the report as JSON.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}.Marshal(s.Info())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
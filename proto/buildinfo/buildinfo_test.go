@@ -0,0 +1,85 @@
+package buildinfo
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestLdflagsStampTheBuild(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a binary")
+	}
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("no go tool")
+	}
+	const pkg = "github.com/technonext/todo-app/proto/buildinfo"
+	ldflags := "-X " + pkg + ".Version=v1.4.0 -X " + pkg + ".Commit=0123abc -X " + pkg + ".Date=2026-10-16T09:00:00Z"
+	out, err := exec.Command(goTool, "run", "-ldflags", ldflags, "./testdata/stamped").CombinedOutput()
+	if err != nil {
+		t.Fatalf("go run: %v\n%s", err, out)
+	}
+	if got, want := strings.TrimSpace(string(out)), "v1.4.0 0123abc 2026-10-16T09:00:00Z"; got != want {
+		t.Errorf("stamped build = %q, want %q", got, want)
+	}
+
+	out, err = exec.Command(goTool, "run", "./testdata/stamped").CombinedOutput()
+	if err != nil {
+		t.Fatalf("go run: %v\n%s", err, out)
+	}
+	if got, want := strings.TrimSpace(string(out)), "dev unknown unknown"; got != want {
+		t.Errorf("unstamped build = %q, want %q", got, want)
+	}
+}
+
+func TestInfoLeavesOutSecrets(t *testing.T) {
+	s := NewServer("task-service", map[string]string{
+		"PORT":                     "50051",
+		"MONGO_PASSWORD":           "hunter2-mongo",
+		"JWT_SECRET":               "hunter2-jwt",
+		"TASK_SERVICE_HMAC_SECRET": "hunter2-hmac",
+		"POSTGRES_URL":             "postgres://tasks:hunter2-pg@db:5432/tasks",
+	})
+
+	info, err := s.GetServiceInfo(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Service != "task-service" || info.Version != Version || info.GoVersion == "" || info.UptimeSeconds < 0 {
+		t.Errorf("info = %v", info)
+	}
+	if info.Config["PORT"] != "50051" || info.Config["POSTGRES_URL"] != "postgres://[REDACTED]@db:5432/tasks" {
+		t.Errorf("config = %v, want the port and the Postgres URL without its credentials", info.Config)
+	}
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/info", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d", w.Code)
+	}
+	if strings.Contains(w.Body.String(), "hunter2") {
+		t.Errorf("/debug/info shows a secret: %s", w.Body)
+	}
+	var body struct {
+		Service   string            `json:"service"`
+		BuildDate string            `json:"build_date"`
+		Config    map[string]string `json:"config"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Service != "task-service" || body.BuildDate != Date || len(body.Config) != 2 {
+		t.Errorf("body = %+v", body)
+	}
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/debug/info", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST status = %d, want 405", w.Code)
+	}
+}
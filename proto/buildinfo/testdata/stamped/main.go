@@ -0,0 +1,13 @@
+// Command stamped prints the build buildinfo was linked with, for the test
+// of the -ldflags the Dockerfiles pass.
+package main
+
+import (
+	"fmt"
+
+	"github.com/technonext/todo-app/proto/buildinfo"
+)
+
+func main() {
+	fmt.Println(buildinfo.Version, buildinfo.Commit, buildinfo.Date)
+}
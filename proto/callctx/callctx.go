@@ -0,0 +1,158 @@
+// Package callctx carries who a call is made for from the gateway to the
+// services' logic: the user and tenant the gateway authenticated, the
+// request id and the client's locale.
+//
+// The gateway puts them in a call's metadata with the Set functions. The
+// server interceptor reads them back, installed by grpcmiddleware after
+// authentication so only trusted callers' metadata is believed, and
+// CallerFrom hands them to handlers as a Caller. The task service uses it to
+// make the caller the owner of the tasks they create, to check task locks
+// against them, and to record them in each task's status and due date
+// history. Services have no per-user preferences to look up yet; the
+// caller's Locale is carried for when they do.
+//
+// Metadata that is missing or malformed, such as an overlong id or one with
+// control characters, leaves its field empty, so handlers check for "" rather
+// than for hostile values.
+//
+// Scoping data to the tenant is still the tenant package's work; TenantID
+// only tells handlers who the caller is.
+package callctx
+
+import (
+	"context"
+	"regexp"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/technonext/todo-app/proto/tenant"
+)
+
+const (
+	// UserIDHeader carries the id of the user a call is made for, as the
+	// gateway verified it from their session token
+	UserIDHeader = "user-id"
+	// TenantHeader carries the caller's tenant; see package tenant
+	TenantHeader = tenant.Header
+	// RequestIDHeader carries the id one request keeps across services
+	RequestIDHeader = "x-request-id"
+	// LocaleHeader carries the BCP 47 language tag the client prefers, such
+	// as en-US
+	LocaleHeader = "x-locale"
+)
+
+// maxValueLength bounds every field; longer values are malformed.
+const maxValueLength = 128
+
+var (
+	// Ids are ObjectIDs, public ids and the like
+	idPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]+$`)
+	// Tenants are ids, or tenant.All for background jobs
+	tenantPattern = regexp.MustCompile(`^(\*|[A-Za-z0-9._:-]+)$`)
+	// A language, then subtags such as a script or region
+	localePattern = regexp.MustCompile(`^[A-Za-z]{2,8}(-[A-Za-z0-9]{1,8})*$`)
+)
+
+// Caller is who a call is made for. Each field is empty when the caller did
+// not send it or sent a malformed value.
+type Caller struct {
+	// UserID is empty for calls not made for a user, such as those from
+	// other services' background jobs
+	UserID   string
+	TenantID string
+	// RequestID is the id the caller sent; grpcmiddleware makes one up for
+	// calls without one, which RequestID does not show
+	RequestID string
+	Locale    string
+}
+
+// SetUserID sends id as the user a call is made for; "" sends none.
+func SetUserID(md metadata.MD, id string) {
+	set(md, UserIDHeader, id)
+}
+
+// SetTenantID sends id as the caller's tenant; "" sends none.
+func SetTenantID(md metadata.MD, id string) {
+	set(md, TenantHeader, id)
+}
+
+// SetRequestID sends id as the call's request id; "" sends none.
+func SetRequestID(md metadata.MD, id string) {
+	set(md, RequestIDHeader, id)
+}
+
+// SetLocale sends locale as the client's; "" sends none.
+func SetLocale(md metadata.MD, locale string) {
+	set(md, LocaleHeader, locale)
+}
+
+// ValidRequestID reports whether the server interceptor accepts id as a
+// request id, so the gateway can replace a client's malformed one.
+func ValidRequestID(id string) bool {
+	return len(id) <= maxValueLength && idPattern.MatchString(id)
+}
+
+func set(md metadata.MD, key, value string) {
+	if value != "" {
+		md.Set(key, value)
+	}
+}
+
+// FromIncoming reads the caller from the metadata of the call ctx serves.
+func FromIncoming(ctx context.Context) Caller {
+	md, _ := metadata.FromIncomingContext(ctx)
+	return Caller{
+		UserID:    valid(md, UserIDHeader, idPattern),
+		TenantID:  valid(md, TenantHeader, tenantPattern),
+		RequestID: valid(md, RequestIDHeader, idPattern),
+		Locale:    valid(md, LocaleHeader, localePattern),
+	}
+}
+
+// valid returns the first value of key when it is well formed, or "".
+func valid(md metadata.MD, key string, pattern *regexp.Regexp) string {
+	values := md.Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+	if value := values[0]; len(value) <= maxValueLength && pattern.MatchString(value) {
+		return value
+	}
+	return ""
+}
+
+type contextKey struct{}
+
+// WithCaller returns ctx made for c, for calls the server interceptor does
+// not see, such as tests'.
+func WithCaller(ctx context.Context, c Caller) context.Context {
+	return context.WithValue(ctx, contextKey{}, c)
+}
+
+// CallerFrom returns who the call ctx belongs to is made for, or a zero
+// Caller outside a call.
+func CallerFrom(ctx context.Context) Caller {
+	c, _ := ctx.Value(contextKey{}).(Caller)
+	return c
+}
+
+// UnaryServerInterceptor makes the call's caller available to CallerFrom.
+func UnaryServerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	return handler(WithCaller(ctx, FromIncoming(ctx)), req)
+}
+
+// StreamServerInterceptor is UnaryServerInterceptor for streaming calls.
+func StreamServerInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx := WithCaller(ss.Context(), FromIncoming(ss.Context()))
+	return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
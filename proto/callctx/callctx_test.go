@@ -0,0 +1,90 @@
+package callctx
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestRoundTrip(t *testing.T) {
+	want := Caller{
+		UserID:    "65f1c0ffee0123456789abcd",
+		TenantID:  "acme",
+		RequestID: "4bf92f3577b34da6a3ce929d0e0e4736",
+		Locale:    "zh-Hant-TW",
+	}
+	md := metadata.MD{}
+	SetUserID(md, want.UserID)
+	SetTenantID(md, want.TenantID)
+	SetRequestID(md, want.RequestID)
+	SetLocale(md, want.Locale)
+
+	var got Caller
+	_, err := UnaryServerInterceptor(metadata.NewIncomingContext(context.Background(), md), nil, &grpc.UnaryServerInfo{},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			got = CallerFrom(ctx)
+			return nil, nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("caller = %+v, want %+v", got, want)
+	}
+}
+
+func TestEmptyFieldsAreNotSent(t *testing.T) {
+	md := metadata.MD{}
+	SetUserID(md, "")
+	SetTenantID(md, "")
+	SetRequestID(md, "")
+	SetLocale(md, "")
+	if md.Len() != 0 {
+		t.Errorf("metadata = %v, want none", md)
+	}
+}
+
+func TestHostileMetadata(t *testing.T) {
+	tests := []struct {
+		name string
+		md   metadata.MD
+		want Caller
+	}{
+		{"none", nil, Caller{}},
+		{"empty values", metadata.Pairs(UserIDHeader, "", LocaleHeader, ""), Caller{}},
+		{"overlong id", metadata.Pairs(UserIDHeader, strings.Repeat("a", maxValueLength+1)), Caller{}},
+		{"longest id", metadata.Pairs(UserIDHeader, strings.Repeat("a", maxValueLength)), Caller{UserID: strings.Repeat("a", maxValueLength)}},
+		{"control characters", metadata.Pairs(UserIDHeader, "u1\nadmin", RequestIDHeader, "r1\x00"), Caller{}},
+		{"spaces", metadata.Pairs(UserIDHeader, "u1 ", TenantHeader, " acme"), Caller{}},
+		{"non-ASCII", metadata.Pairs(UserIDHeader, "usér", LocaleHeader, "fr-ÇA"), Caller{}},
+		{"query operators", metadata.Pairs(UserIDHeader, `{"$ne": ""}`), Caller{}},
+		{"path traversal", metadata.Pairs(TenantHeader, "../other"), Caller{}},
+		{"all tenants", metadata.Pairs(TenantHeader, "*"), Caller{TenantID: "*"}},
+		{"wildcard id", metadata.Pairs(UserIDHeader, "*", TenantHeader, "acme*"), Caller{}},
+		{"malformed locale", metadata.Pairs(LocaleHeader, "en_US"), Caller{}},
+		{"accept-language list", metadata.Pairs(LocaleHeader, "en-US,en;q=0.9"), Caller{}},
+		{"first of several values", metadata.Pairs(UserIDHeader, "u1", UserIDHeader, "u2"), Caller{UserID: "u1"}},
+		{"malformed first value", metadata.Pairs(UserIDHeader, "u1\r\n", UserIDHeader, "u2"), Caller{}},
+		{"one malformed field", metadata.Pairs(UserIDHeader, "u1", LocaleHeader, "not a locale"), Caller{UserID: "u1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.md != nil {
+				ctx = metadata.NewIncomingContext(ctx, tt.md)
+			}
+			if got := FromIncoming(ctx); got != tt.want {
+				t.Errorf("caller = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCallerFromOutsideACall(t *testing.T) {
+	if got := CallerFrom(context.Background()); got != (Caller{}) {
+		t.Errorf("caller = %+v, want none", got)
+	}
+}
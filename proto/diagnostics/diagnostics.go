@@ -0,0 +1,100 @@
+// Package diagnostics reports what a service's readiness does not: how long
+// MongoDB takes to answer, how busy the connection pool is, how large the
+// service's collection has grown and how the process itself is doing. It is
+// an operator's endpoint, served next to the metrics rather than over gRPC.
+package diagnostics
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/technonext/todo-app/proto/mongoutil"
+)
+
+// started approximates when the process started.
+var started = time.Now()
+
+// timeout bounds the MongoDB calls of one report.
+const timeout = 5 * time.Second
+
+// SystemHealthRequest asks for a report; it has no options yet.
+type SystemHealthRequest struct{}
+
+// SystemHealthResponse is a snapshot of the service. The MongoDB fields are
+// zero when MongoDB could not be reached, which MongoDBError explains.
+type SystemHealthResponse struct {
+	MongoDBPingMs           float64 `json:"mongodb_ping_ms"`
+	MongoDBPoolCheckedOut   int32   `json:"mongodb_pool_checked_out"`
+	MongoDBPoolSize         int32   `json:"mongodb_pool_size"`
+	CollectionDocumentCount int64   `json:"collection_document_count"`
+	ServiceUptimeSeconds    float64 `json:"service_uptime_seconds"`
+	GoroutineCount          int32   `json:"goroutine_count"`
+	MongoDBError            string  `json:"mongodb_error,omitempty"`
+}
+
+// Server reports on a service whose data lives in collection.
+type Server struct {
+	collection *mongo.Collection
+}
+
+// NewServer creates a Server for the service's main collection.
+func NewServer(collection *mongo.Collection) *Server {
+	return &Server{collection: collection}
+}
+
+// GetSystemHealth takes a snapshot. It does not fail when MongoDB is
+// unreachable, since that is when the rest of the report matters most.
+func (s *Server) GetSystemHealth(ctx context.Context, _ *SystemHealthRequest) (*SystemHealthResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	pool := mongoutil.Pool()
+	resp := &SystemHealthResponse{
+		MongoDBPoolCheckedOut: pool.CheckedOut,
+		MongoDBPoolSize:       pool.Size,
+		ServiceUptimeSeconds:  time.Since(started).Seconds(),
+		GoroutineCount:        int32(runtime.NumGoroutine()),
+	}
+
+	start := time.Now()
+	if err := s.collection.Database().Client().Ping(ctx, nil); err != nil {
+		resp.MongoDBError = err.Error()
+		return resp, nil
+	}
+	resp.MongoDBPingMs = float64(time.Since(start).Microseconds()) / 1000
+
+	count, err := s.collection.EstimatedDocumentCount(ctx)
+	if err != nil {
+		resp.MongoDBError = err.Error()
+		return resp, nil
+	}
+	resp.CollectionDocumentCount = count
+	return resp, nil
+}
+
+// ServeHTTP answers GET /diagnostics with the snapshot as JSON, with status
+// 503 when MongoDB could not be reached.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	resp, err := s.GetSystemHealth(r.Context(), &SystemHealthRequest{})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	code := http.StatusOK
+	if resp.MongoDBError != "" {
+		code = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(resp)
+}
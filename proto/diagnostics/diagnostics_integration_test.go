@@ -0,0 +1,54 @@
+//go:build integration
+
+package diagnostics
+
+// Run with a MongoDB at MONGO_TEST_URI (default mongodb://localhost:27017)
+// and go test -tags integration ./...
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/technonext/todo-app/proto/mongoutil"
+)
+
+func TestGetSystemHealth(t *testing.T) {
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		uri = "mongodb://localhost:27017"
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	client, err := mongoutil.Connect(ctx, mongoutil.Config{URI: uri, ServerSelectionTimeout: 2 * time.Second, ConnectAttempts: 1})
+	if err != nil {
+		t.Skipf("MongoDB not reachable at %s: %v", uri, err)
+	}
+	db := client.Database(fmt.Sprintf("diagnostics_test_%d", time.Now().UnixNano()))
+	t.Cleanup(func() {
+		db.Drop(context.Background())
+		client.Disconnect(context.Background())
+	})
+	collection := db.Collection("items")
+	if _, err := collection.InsertMany(ctx, []interface{}{bson.M{"n": 1}, bson.M{"n": 2}}); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := NewServer(collection).GetSystemHealth(ctx, &SystemHealthRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.MongoDBError != "" {
+		t.Fatalf("mongodb_error = %s", resp.MongoDBError)
+	}
+	if resp.CollectionDocumentCount != 2 {
+		t.Errorf("collection_document_count = %d, want 2", resp.CollectionDocumentCount)
+	}
+	if resp.MongoDBPoolSize < 1 || resp.MongoDBPoolCheckedOut < 0 || resp.MongoDBPoolCheckedOut > resp.MongoDBPoolSize {
+		t.Errorf("pool = %d checked out of %d", resp.MongoDBPoolCheckedOut, resp.MongoDBPoolSize)
+	}
+}
@@ -0,0 +1,56 @@
+package diagnostics
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestDiagnosticsWithoutMongoDB(t *testing.T) {
+	// Nothing listens on port 1, so the ping fails once selection times out
+	client, err := mongo.Connect(context.Background(), options.Client().
+		ApplyURI("mongodb://127.0.0.1:1").
+		SetServerSelectionTimeout(100*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Disconnect(context.Background()) })
+	s := NewServer(client.Database("todo_app").Collection("tasks"))
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/diagnostics", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", w.Code)
+	}
+	var resp struct {
+		UptimeSeconds  *float64 `json:"service_uptime_seconds"`
+		GoroutineCount *int32   `json:"goroutine_count"`
+		MongoDBError   string   `json:"mongodb_error"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("body %s: %v", w.Body, err)
+	}
+	if resp.UptimeSeconds == nil || *resp.UptimeSeconds < 0 {
+		t.Errorf("service_uptime_seconds = %v, want >= 0", resp.UptimeSeconds)
+	}
+	if resp.GoroutineCount == nil || *resp.GoroutineCount <= 0 {
+		t.Errorf("goroutine_count = %v, want > 0", resp.GoroutineCount)
+	}
+	if resp.MongoDBError == "" {
+		t.Error("mongodb_error is empty for an unreachable server")
+	}
+}
+
+func TestDiagnosticsRejectsOtherMethods(t *testing.T) {
+	w := httptest.NewRecorder()
+	NewServer(nil).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/diagnostics", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("status = %d, want 405", w.Code)
+	}
+}
@@ -0,0 +1,294 @@
+// Package faults injects failures into a service's calls, so the retries and
+// timeouts of its callers can be tested without killing anything. It is off
+// unless FAULTS_ENABLED=true, and never meant for production.
+//
+// A fault applies to a share of the calls whose method matches it: it delays
+// them, fails them with a code, or closes the caller's connection as if the
+// service had gone away. Faults are read from FAULTS at startup, as the JSON
+// of SetFaultsRequest's faults:
+//
+//	FAULTS='[{"method":"/todo.TaskService/GetTask","percent":50,"code":"UNAVAILABLE"}]'
+//
+// and replaced at run time with the FaultService RPCs. Injected errors carry
+// an ErrorInfo with Domain and Reason, and every injection is counted in
+// grpc_server_faults_injected_total, so they can be told from real failures.
+package faults
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net"
+	"os"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// Domain and Reason fill the ErrorInfo of injected errors.
+const (
+	Domain = "faults"
+	Reason = "FAULT_INJECTED"
+)
+
+var (
+	injected = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpc_server_faults_injected_total",
+		Help: "Faults injected into gRPC calls, by method and kind of fault.",
+	}, []string{"method", "fault"})
+
+	registerOnce sync.Once
+)
+
+// Injector applies the faults it holds to the calls of a server. A nil
+// Injector injects nothing, so services can use it whether or not faults are
+// enabled.
+type Injector struct {
+	pb.UnimplementedFaultServiceServer
+
+	mu     sync.RWMutex
+	faults []*pb.Fault
+	// chance returns a number in [0, 100) deciding whether a call gets a
+	// fault; tests replace it to be deterministic
+	chance func() float64
+
+	// The connections accepted by Listen, by the caller's address
+	conns sync.Map
+}
+
+// New returns an injector holding faults.
+func New(faults ...*pb.Fault) (*Injector, error) {
+	registerOnce.Do(func() { prometheus.MustRegister(injected) })
+	i := &Injector{chance: func() float64 { return rand.Float64() * 100 }}
+	if err := i.Set(faults); err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+// FromEnv returns an injector holding the faults in FAULTS when
+// FAULTS_ENABLED=true, or nil when faults are disabled, the default.
+func FromEnv() (*Injector, error) {
+	enabled := false
+	if raw := os.Getenv("FAULTS_ENABLED"); raw != "" {
+		var err error
+		if enabled, err = strconv.ParseBool(raw); err != nil {
+			return nil, fmt.Errorf("invalid FAULTS_ENABLED %q", raw)
+		}
+	}
+	raw := os.Getenv("FAULTS")
+	if !enabled {
+		if raw != "" {
+			return nil, fmt.Errorf("FAULTS is set but FAULTS_ENABLED is not true")
+		}
+		return nil, nil
+	}
+
+	var req pb.SetFaultsRequest
+	if raw != "" {
+		if err := protojson.Unmarshal([]byte(`{"faults":`+raw+`}`), &req); err != nil {
+			return nil, fmt.Errorf("invalid FAULTS: %v", err)
+		}
+	}
+	i, err := New(req.Faults...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid FAULTS: %v", err)
+	}
+	return i, nil
+}
+
+// Set replaces the faults, leaving them as they were if any is invalid.
+func (i *Injector) Set(faults []*pb.Fault) error {
+	copied := make([]*pb.Fault, len(faults))
+	for n, f := range faults {
+		if _, err := path.Match(f.Method, ""); err != nil {
+			return fmt.Errorf("fault %d: invalid method pattern %q", n, f.Method)
+		}
+		if f.Percent < 0 || f.Percent > 100 {
+			return fmt.Errorf("fault %d: percent %d is not between 0 and 100", n, f.Percent)
+		}
+		if f.LatencyMs < 0 {
+			return fmt.Errorf("fault %d: negative latency", n)
+		}
+		if f.Code != "" {
+			code, err := parseCode(f.Code)
+			if err != nil || code == codes.OK {
+				return fmt.Errorf("fault %d: invalid code %q", n, f.Code)
+			}
+		}
+		copied[n] = proto.Clone(f).(*pb.Fault)
+	}
+	i.mu.Lock()
+	i.faults = copied
+	i.mu.Unlock()
+	return nil
+}
+
+// Faults returns the faults held.
+func (i *Injector) Faults() []*pb.Fault {
+	if i == nil {
+		return nil
+	}
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	faults := make([]*pb.Fault, len(i.faults))
+	for n, f := range i.faults {
+		faults[n] = proto.Clone(f).(*pb.Fault)
+	}
+	return faults
+}
+
+func parseCode(name string) (codes.Code, error) {
+	var code codes.Code
+	err := code.UnmarshalJSON([]byte(strconv.Quote(name)))
+	return code, err
+}
+
+// inject applies the faults matching method to a call, returning the error
+// the call fails with, or nil to let it run.
+func (i *Injector) inject(ctx context.Context, method string) error {
+	if i == nil || path.Dir(method) == "/"+pb.FaultService_ServiceDesc.ServiceName {
+		// The faults can always be changed back
+		return nil
+	}
+	i.mu.RLock()
+	faults := i.faults
+	i.mu.RUnlock()
+
+	for _, f := range faults {
+		if matched, _ := path.Match(f.Method, method); f.Method != "" && !matched {
+			continue
+		}
+		if i.chance() >= float64(f.Percent) {
+			continue
+		}
+		if f.LatencyMs > 0 {
+			injected.WithLabelValues(method, "latency").Inc()
+			timer := time.NewTimer(time.Duration(f.LatencyMs) * time.Millisecond)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return status.FromContextError(ctx.Err()).Err()
+			case <-timer.C:
+			}
+		}
+		if f.ResetConnection {
+			injected.WithLabelValues(method, "reset").Inc()
+			i.reset(ctx)
+			return faultError(codes.Unavailable, "connection reset")
+		}
+		if f.Code != "" {
+			code, _ := parseCode(f.Code)
+			injected.WithLabelValues(method, "error").Inc()
+			return faultError(code, code.String())
+		}
+	}
+	return nil
+}
+
+func faultError(code codes.Code, what string) error {
+	st, _ := status.New(code, "fault injected: "+what).
+		WithDetails(&errdetails.ErrorInfo{Domain: Domain, Reason: Reason})
+	return st.Err()
+}
+
+// UnaryServerInterceptor injects the faults into unary calls.
+func (i *Injector) UnaryServerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := i.inject(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// StreamServerInterceptor injects the faults into streaming calls before
+// they start.
+func (i *Injector) StreamServerInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := i.inject(ss.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+// Register serves the FaultService on s.
+func (i *Injector) Register(s *grpc.Server) {
+	if i != nil {
+		pb.RegisterFaultServiceServer(s, i)
+	}
+}
+
+func (i *Injector) GetFaults(ctx context.Context, req *pb.GetFaultsRequest) (*pb.FaultsResponse, error) {
+	return &pb.FaultsResponse{Faults: i.Faults()}, nil
+}
+
+func (i *Injector) SetFaults(ctx context.Context, req *pb.SetFaultsRequest) (*pb.FaultsResponse, error) {
+	if err := i.Set(req.Faults); err != nil {
+		st, _ := status.New(codes.InvalidArgument, err.Error()).
+			WithDetails(&errdetails.ErrorInfo{Domain: Domain, Reason: "INVALID_FAULT"})
+		return nil, st.Err()
+	}
+	return &pb.FaultsResponse{Faults: i.Faults()}, nil
+}
+
+// Listen tracks the connections lis accepts, so reset faults can close them.
+func (i *Injector) Listen(lis net.Listener) net.Listener {
+	if i == nil {
+		return lis
+	}
+	return &listener{Listener: lis, injector: i}
+}
+
+// reset closes the connection the call came in on. TCP connections are
+// closed without lingering, so the caller gets a reset rather than an
+// orderly close.
+func (i *Injector) reset(ctx context.Context) {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return
+	}
+	conn, ok := i.conns.Load(p.Addr.String())
+	if !ok {
+		return
+	}
+	if tcp, ok := conn.(*trackedConn).Conn.(*net.TCPConn); ok {
+		tcp.SetLinger(0)
+	}
+	conn.(*trackedConn).Close()
+}
+
+type listener struct {
+	net.Listener
+	injector *Injector
+}
+
+func (l *listener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	tracked := &trackedConn{Conn: conn, conns: &l.injector.conns}
+	l.injector.conns.Store(conn.RemoteAddr().String(), tracked)
+	return tracked, nil
+}
+
+type trackedConn struct {
+	net.Conn
+	conns     *sync.Map
+	closeOnce sync.Once
+}
+
+func (c *trackedConn) Close() error {
+	c.closeOnce.Do(func() { c.conns.CompareAndDelete(c.RemoteAddr().String(), c) })
+	return c.Conn.Close()
+}
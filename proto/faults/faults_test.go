@@ -0,0 +1,128 @@
+package faults
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+func TestFromEnv(t *testing.T) {
+	tests := []struct {
+		name       string
+		enabled    string
+		faults     string
+		wantNil    bool
+		wantFaults int
+		wantErr    bool
+	}{
+		{"disabled by default", "", "", true, 0, false},
+		{"enabled without faults", "true", "", false, 0, false},
+		{"enabled with faults", "true", `[{"method":"/todo.TaskService/GetTask","percent":50,"code":"UNAVAILABLE"}]`, false, 1, false},
+		{"faults while disabled", "false", `[{"percent":50,"code":"UNAVAILABLE"}]`, true, 0, true},
+		{"malformed faults", "true", `{"percent":50}`, true, 0, true},
+		{"unknown code", "true", `[{"percent":50,"code":"BROKEN"}]`, true, 0, true},
+		{"invalid switch", "yes please", "", true, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("FAULTS_ENABLED", tt.enabled)
+			t.Setenv("FAULTS", tt.faults)
+			i, err := FromEnv()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if (i == nil) != tt.wantNil {
+				t.Fatalf("injector = %v, want nil %v", i, tt.wantNil)
+			}
+			if got := len(i.Faults()); got != tt.wantFaults {
+				t.Errorf("%d faults, want %d", got, tt.wantFaults)
+			}
+		})
+	}
+}
+
+func TestSetRejectsInvalidFaults(t *testing.T) {
+	i, err := New(&pb.Fault{Percent: 10, Code: "INTERNAL"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []*pb.Fault{
+		{Method: "[", Percent: 10},
+		{Percent: 101},
+		{LatencyMs: -1},
+		{Code: "OK"},
+	} {
+		if err := i.Set([]*pb.Fault{f}); err == nil {
+			t.Errorf("Set(%v) succeeded", f)
+		}
+	}
+	if faults := i.Faults(); len(faults) != 1 || faults[0].Code != "INTERNAL" {
+		t.Errorf("faults after failed sets = %v, want the first one kept", faults)
+	}
+}
+
+func TestInject(t *testing.T) {
+	i, err := New(
+		&pb.Fault{Method: "/todo.TaskService/GetTask", Percent: 50, Code: "UNAVAILABLE"},
+		&pb.Fault{Method: "/todo.UserService/*", Percent: 100, LatencyMs: 20},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var roll float64
+	i.chance = func() float64 { return roll }
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		method   string
+		roll     float64
+		wantCode codes.Code
+		slow     bool
+	}{
+		{"within the percentage", "/todo.TaskService/GetTask", 49, codes.Unavailable, false},
+		{"outside the percentage", "/todo.TaskService/GetTask", 50, codes.OK, false},
+		{"other method", "/todo.TaskService/ListTasks", 0, codes.OK, false},
+		{"latency", "/todo.UserService/GetUser", 0, codes.OK, true},
+		{"fault service exempt", "/todo.FaultService/SetFaults", 0, codes.OK, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roll = tt.roll
+			start := time.Now()
+			_, err := i.UnaryServerInterceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method}, func(ctx context.Context, req interface{}) (interface{}, error) {
+				return nil, nil
+			})
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("err = %v, want %v", err, tt.wantCode)
+			}
+			if slow := time.Since(start) >= 20*time.Millisecond; slow != tt.slow {
+				t.Errorf("took %v, want delayed %v", time.Since(start), tt.slow)
+			}
+			if err == nil {
+				return
+			}
+			info, ok := status.Convert(err).Details()[0].(*errdetails.ErrorInfo)
+			if !ok || info.Domain != Domain || info.Reason != Reason {
+				t.Errorf("details = %v, want an injected fault", status.Convert(err).Details())
+			}
+		})
+	}
+}
+
+func TestNilInjectorInjectsNothing(t *testing.T) {
+	var i *Injector
+	if err := i.inject(context.Background(), "/todo.TaskService/GetTask"); err != nil {
+		t.Error(err)
+	}
+	if i.Listen(nil) != nil {
+		t.Error("Listen wrapped the listener of a nil injector")
+	}
+}
@@ -0,0 +1,98 @@
+// Package featureflags turns optional features on and off per deployment,
+// so a new feature can ship dark and be enabled, or disabled again, by
+// changing the environment rather than the code. The services with optional
+// features, and the gateway, which reports them, read the same
+// FEATURE_FLAGS, a JSON object of flags by name:
+//
+//	FEATURE_FLAGS='{"soft_delete": true, "recurring_tasks": false}'
+//
+// A flag missing from FEATURE_FLAGS is off, unless it guards behaviour the
+// services had before it was a flag: those are on until FEATURE_FLAGS turns
+// them off, so deployments that set no flags keep working as they did. Flags
+// a service does not know are kept, and reported by the gateway, but change
+// nothing.
+package featureflags
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// The flags the services check
+const (
+	// SoftDelete makes DeleteTask move tasks to the trash, where
+	// ListDeletedTasks lists them until the reaper removes them, rather
+	// than deleting them for good
+	SoftDelete = "soft_delete"
+)
+
+// defaults are the flags on unless FEATURE_FLAGS turns them off. Tasks went
+// to the trash before soft delete was a flag, so turning it off must be a
+// choice: a deployment upgraded without FEATURE_FLAGS would otherwise start
+// deleting tasks for good.
+var defaults = map[string]bool{
+	SoftDelete: true,
+}
+
+// FeatureFlags holds the flags read at startup. A nil FeatureFlags has
+// every flag at its default, so services can use one whether or not
+// FEATURE_FLAGS is set.
+type FeatureFlags struct {
+	flags map[string]bool
+}
+
+// Parse reads flags from the JSON object raw over the defaults; an empty raw
+// leaves every flag at its default.
+func Parse(raw string) (*FeatureFlags, error) {
+	flags := make(map[string]bool)
+	for name, on := range defaults {
+		flags[name] = on
+	}
+	if raw != "" {
+		if err := json.Unmarshal([]byte(raw), &flags); err != nil {
+			return nil, fmt.Errorf(`not a JSON object of flags, such as {"%s": true}: %v`, SoftDelete, err)
+		}
+	}
+	return &FeatureFlags{flags: flags}, nil
+}
+
+// FromEnv reads the flags in FEATURE_FLAGS.
+func FromEnv() (*FeatureFlags, error) {
+	f, err := Parse(os.Getenv("FEATURE_FLAGS"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid FEATURE_FLAGS: %v", err)
+	}
+	return f, nil
+}
+
+// FeatureFlag reports whether the flag name is on in FEATURE_FLAGS, for
+// code without a FeatureFlags at hand. Services check FEATURE_FLAGS when they
+// start, with FromEnv; should it not parse, every flag is at its default.
+func FeatureFlag(name string) bool {
+	f, err := FromEnv()
+	if err != nil {
+		return defaults[name]
+	}
+	return f.IsEnabled(name)
+}
+
+// IsEnabled reports whether the flag name is on.
+func (f *FeatureFlags) IsEnabled(name string) bool {
+	if f == nil {
+		return defaults[name]
+	}
+	return f.flags[name]
+}
+
+// All returns a copy of every flag, defaults included, by name.
+func (f *FeatureFlags) All() map[string]bool {
+	if f == nil {
+		f = &FeatureFlags{flags: defaults}
+	}
+	all := make(map[string]bool)
+	for name, on := range f.flags {
+		all[name] = on
+	}
+	return all
+}
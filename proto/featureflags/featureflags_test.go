@@ -0,0 +1,79 @@
+package featureflags
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	f, err := Parse(`{"soft_delete": false, "recurring_tasks": true}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]bool{SoftDelete: false, "recurring_tasks": true, "rbac": false} {
+		if got := f.IsEnabled(name); got != want {
+			t.Errorf("IsEnabled(%q) = %v, want %v", name, got, want)
+		}
+	}
+	if got := f.All(); len(got) != 2 {
+		t.Errorf("All() = %v, want both flags set", got)
+	}
+
+	for _, raw := range []string{`["soft_delete"]`, `{"soft_delete": "yes"}`, `soft_delete=true`} {
+		if _, err := Parse(raw); err == nil {
+			t.Errorf("Parse(%q) succeeded", raw)
+		}
+	}
+}
+
+func TestDefaults(t *testing.T) {
+	f, err := Parse(`{"recurring_tasks": true}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !f.IsEnabled(SoftDelete) {
+		t.Error("soft_delete is off when FEATURE_FLAGS leaves it out, want it on")
+	}
+	if got := f.All(); len(got) != 2 || !got[SoftDelete] {
+		t.Errorf("All() = %v, want soft_delete reported on", got)
+	}
+}
+
+func TestNilFlagsAreDefaults(t *testing.T) {
+	var f *FeatureFlags
+	if !f.IsEnabled(SoftDelete) || f.IsEnabled("recurring_tasks") {
+		t.Error("a nil FeatureFlags does not have the default flags")
+	}
+	if got := f.All(); len(got) != 1 || !got[SoftDelete] {
+		t.Errorf("All() = %v, want the defaults", got)
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv("FEATURE_FLAGS", "")
+	f, err := FromEnv()
+	if err != nil || !f.IsEnabled(SoftDelete) || f.IsEnabled("recurring_tasks") {
+		t.Errorf("FromEnv() without FEATURE_FLAGS = %v, %v; want the defaults", f.All(), err)
+	}
+	t.Setenv("FEATURE_FLAGS", "{")
+	if _, err := FromEnv(); err == nil {
+		t.Error("FromEnv() accepted invalid JSON")
+	}
+}
+
+func TestFeatureFlag(t *testing.T) {
+	tests := []struct {
+		env  string
+		name string
+		want bool
+	}{
+		{"", SoftDelete, true},
+		{`{"soft_delete": false}`, SoftDelete, false},
+		{`{"recurring_tasks": true}`, "recurring_tasks", true},
+		{`{"recurring_tasks": true}`, "rbac", false},
+		{"{", SoftDelete, true},
+	}
+	for _, tt := range tests {
+		t.Setenv("FEATURE_FLAGS", tt.env)
+		if got := FeatureFlag(tt.name); got != tt.want {
+			t.Errorf("FeatureFlag(%q) with FEATURE_FLAGS=%s = %v, want %v", tt.name, tt.env, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+// Package grpcerror builds the gRPC errors the services return, with an
+// ErrorInfo in their details so callers can switch on a stable reason code
+// instead of the message.
+package grpcerror
+
+import (
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Domain identifies the service an error comes from, such as "task.service".
+type Domain string
+
+// Errorf returns a gRPC error with the given code and message, whose
+// ErrorInfo carries the domain, reason and metadata.
+func (d Domain) Errorf(code codes.Code, reason string, metadata map[string]string, format string, args ...interface{}) error {
+	st := status.Newf(code, format, args...)
+	detailed, err := st.WithDetails(&errdetails.ErrorInfo{
+		Domain:   string(d),
+		Reason:   reason,
+		Metadata: metadata,
+	})
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
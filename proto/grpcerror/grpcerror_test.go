@@ -0,0 +1,29 @@
+package grpcerror
+
+import (
+	"testing"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestErrorfCarriesErrorInfo(t *testing.T) {
+	err := Domain("task.service").Errorf(codes.NotFound, "TASK_NOT_FOUND", map[string]string{"task_id": "t1"}, "task %s not found", "t1")
+
+	st := status.Convert(err)
+	if st.Code() != codes.NotFound || st.Message() != "task t1 not found" {
+		t.Fatalf("status = %v %q", st.Code(), st.Message())
+	}
+	details := st.Details()
+	if len(details) != 1 {
+		t.Fatalf("got %d details, want 1", len(details))
+	}
+	info, ok := details[0].(*errdetails.ErrorInfo)
+	if !ok {
+		t.Fatalf("detail is %T, want ErrorInfo", details[0])
+	}
+	if info.Domain != "task.service" || info.Reason != "TASK_NOT_FOUND" || info.Metadata["task_id"] != "t1" {
+		t.Errorf("ErrorInfo = %+v", info)
+	}
+}
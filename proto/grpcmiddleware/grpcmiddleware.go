@@ -0,0 +1,205 @@
+// Package grpcmiddleware is the interceptor chain every service and the
+// gateway share, so each of them gets the same request ids, logs, metrics,
+// panic recovery, service authentication and request validation by passing a
+// config.
+//
+// Servers run, outermost first: request id, logging, metrics, the in-flight
+// limit for unary calls when set, recovery, fault injection when enabled,
+// authentication, tenant, callctx and, for unary calls, the service's own
+// interceptors and validation. So the log and metrics of a call that
+// panicked or was failed on purpose record its code, handlers only believe
+// the user and tenant an authenticated caller sent, and only authenticated
+// callers learn which fields were invalid. Clients run request id, metrics,
+// retry for unary calls, tenant and signing, so each retry is signed afresh.
+package grpcmiddleware
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/reflection"
+
+	"github.com/technonext/todo-app/proto/buildinfo"
+	"github.com/technonext/todo-app/proto/callctx"
+	"github.com/technonext/todo-app/proto/faults"
+	"github.com/technonext/todo-app/proto/serviceauth"
+	"github.com/technonext/todo-app/proto/tenant"
+)
+
+// ServerConfig configures the interceptors of a service's server.
+type ServerConfig struct {
+	// Auth verifies calls when set
+	Auth *serviceauth.HMACInterceptor
+	// Logger defaults to slog.Default()
+	Logger *slog.Logger
+	// Reflection serves gRPC reflection, which lets anyone who reaches the
+	// port list every method with tools like grpcurl; see ReflectionFromEnv
+	Reflection bool
+	// RequireTenant refuses calls that carry no tenant; see
+	// tenant.RequiredFromEnv
+	RequireTenant bool
+	// Faults, when set, are injected into calls and served the FaultService;
+	// see faults.FromEnv
+	Faults *faults.Injector
+	// Info, when set, is served as the InfoService
+	Info *buildinfo.Server
+	// MaxRecvMsgSize bounds the size of a request in bytes; gRPC's default
+	// of 4 MB when 0
+	MaxRecvMsgSize int
+	// MaxConcurrentStreams bounds the calls each client connection may have
+	// open at once; gRPC's default, unlimited, when 0
+	MaxConcurrentStreams uint32
+	// MaxInFlight bounds the unary calls handled at once across every
+	// connection, refusing the rest with codes.ResourceExhausted; unlimited
+	// when 0. A service on MongoDB keeps it within its connection pool, so
+	// calls are refused before they queue for a connection
+	MaxInFlight int
+	// Unary interceptors run after authentication and before validation, so
+	// they can trust metadata only other services send and fill in fields
+	// validation requires
+	Unary []grpc.UnaryServerInterceptor
+}
+
+// NewServer creates a server with the chain installed, followed by opts,
+// serving reflection when cfg.Reflection is set, the FaultService when
+// cfg.Faults is and the InfoService when cfg.Info is.
+func NewServer(cfg ServerConfig, opts ...grpc.ServerOption) *grpc.Server {
+	s := grpc.NewServer(append(ServerOptions(cfg), opts...)...)
+	if cfg.Reflection {
+		reflection.Register(s)
+	}
+	cfg.Faults.Register(s)
+	cfg.Info.Register(s)
+	return s
+}
+
+// ReflectionFromEnv reports whether to serve gRPC reflection. It follows
+// GRPC_REFLECTION_ENABLED, which defaults to true only when
+// APP_ENV=development.
+func ReflectionFromEnv() (bool, error) {
+	appEnv := os.Getenv("APP_ENV")
+	switch appEnv {
+	case "":
+		appEnv = "production"
+	case "development", "production":
+	default:
+		return false, fmt.Errorf("invalid APP_ENV %q: use development or production", appEnv)
+	}
+	raw := os.Getenv("GRPC_REFLECTION_ENABLED")
+	if raw == "" {
+		return appEnv == "development", nil
+	}
+	enabled, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, fmt.Errorf("invalid GRPC_REFLECTION_ENABLED %q", raw)
+	}
+	return enabled, nil
+}
+
+// ServerOptions install the chain. Interceptors passed to grpc.NewServer
+// after them run inside the chain, after validation.
+func ServerOptions(cfg ServerConfig) []grpc.ServerOption {
+	logger := cfg.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	registerMetrics()
+
+	unary := []grpc.UnaryServerInterceptor{
+		unaryServerRequestID,
+		unaryServerLogging(logger),
+		unaryServerMetrics,
+	}
+	if cfg.MaxInFlight > 0 {
+		unary = append(unary, unaryServerLimit(cfg.MaxInFlight))
+	}
+	unary = append(unary, unaryServerRecovery(logger))
+	stream := []grpc.StreamServerInterceptor{
+		streamServerRequestID,
+		streamServerLogging(logger),
+		streamServerMetrics,
+		streamServerRecovery(logger),
+	}
+	if cfg.Faults != nil {
+		unary = append(unary, cfg.Faults.UnaryServerInterceptor)
+		stream = append(stream, cfg.Faults.StreamServerInterceptor)
+	}
+	if cfg.Auth != nil {
+		unary = append(unary, cfg.Auth.UnaryServerInterceptor)
+		stream = append(stream, cfg.Auth.StreamServerInterceptor)
+	}
+	unary = append(unary, tenant.UnaryServerInterceptor(cfg.RequireTenant), callctx.UnaryServerInterceptor)
+	stream = append(stream, tenant.StreamServerInterceptor(cfg.RequireTenant), callctx.StreamServerInterceptor)
+	unary = append(unary, cfg.Unary...)
+	unary = append(unary, unaryServerValidation)
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(stream...),
+	}
+	if cfg.MaxRecvMsgSize > 0 {
+		opts = append(opts, grpc.MaxRecvMsgSize(cfg.MaxRecvMsgSize))
+	}
+	if cfg.MaxConcurrentStreams > 0 {
+		opts = append(opts, grpc.MaxConcurrentStreams(cfg.MaxConcurrentStreams))
+	}
+	return opts
+}
+
+// ClientConfig configures the interceptors of a connection to a service.
+type ClientConfig struct {
+	// Auth signs calls when set
+	Auth *serviceauth.HMACInterceptor
+	// MaxAttempts bounds how often a unary call that failed with
+	// codes.Unavailable is tried; defaults to 3
+	MaxAttempts int
+	// RetryBackoff is the wait before the first retry, doubling after each;
+	// defaults to 100ms
+	RetryBackoff time.Duration
+}
+
+// Dial connects to another service with the chain installed, signing calls
+// with the secret in secretVar when service auth is required.
+func Dial(addr, secretVar string) (*grpc.ClientConn, error) {
+	auth, err := serviceauth.FromEnv(secretVar)
+	if err != nil {
+		return nil, err
+	}
+	opts := append([]grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}, DialOptions(ClientConfig{Auth: auth})...)
+	return grpc.Dial(addr, opts...)
+}
+
+// DialOptions install the chain on a connection.
+func DialOptions(cfg ClientConfig) []grpc.DialOption {
+	if cfg.MaxAttempts == 0 {
+		cfg.MaxAttempts = 3
+	}
+	if cfg.RetryBackoff == 0 {
+		cfg.RetryBackoff = 100 * time.Millisecond
+	}
+	registerMetrics()
+
+	unary := []grpc.UnaryClientInterceptor{
+		unaryClientRequestID,
+		unaryClientMetrics,
+		unaryClientRetry(cfg.MaxAttempts, cfg.RetryBackoff),
+		tenant.UnaryClientInterceptor,
+	}
+	stream := []grpc.StreamClientInterceptor{
+		streamClientRequestID,
+		streamClientMetrics,
+		tenant.StreamClientInterceptor,
+	}
+	if cfg.Auth != nil {
+		unary = append(unary, cfg.Auth.UnaryClientInterceptor)
+		stream = append(stream, cfg.Auth.StreamClientInterceptor)
+	}
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(unary...),
+		grpc.WithChainStreamInterceptor(stream...),
+	}
+}
@@ -0,0 +1,85 @@
+package grpcmiddleware
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestReflectionFromEnv(t *testing.T) {
+	tests := []struct {
+		appEnv, enabled string
+		want            bool
+		wantErr         bool
+	}{
+		{"", "", false, false},
+		{"production", "", false, false},
+		{"development", "", true, false},
+		{"production", "true", true, false},
+		{"development", "false", false, false},
+		{"staging", "", false, true},
+		{"production", "maybe", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.appEnv+"/"+tt.enabled, func(t *testing.T) {
+			t.Setenv("APP_ENV", tt.appEnv)
+			t.Setenv("GRPC_REFLECTION_ENABLED", tt.enabled)
+			got, err := ReflectionFromEnv()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("enabled = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// listServices asks a server built by NewServer for its services over
+// reflection.
+func listServices(t *testing.T, cfg ServerConfig) error {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	s := NewServer(cfg)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return err
+	}
+	if err := stream.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	}); err != nil {
+		return err
+	}
+	_, err = stream.Recv()
+	return err
+}
+
+func TestNewServerReflection(t *testing.T) {
+	if err := listServices(t, ServerConfig{Reflection: true}); err != nil {
+		t.Errorf("reflection enabled: %v", err)
+	}
+	if code := status.Code(listServices(t, ServerConfig{})); code != codes.Unimplemented {
+		t.Errorf("reflection disabled: code = %v, want Unimplemented", code)
+	}
+}
@@ -0,0 +1,70 @@
+package grpcmiddleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// ShedDomain and ShedReason fill the ErrorInfo of calls refused because the
+// server was saturated.
+const (
+	ShedDomain = "grpcmiddleware"
+	ShedReason = "OVERLOADED"
+)
+
+// shedRetryDelay is the wait a refused caller is told to keep before trying
+// again, in the call's RetryInfo.
+const shedRetryDelay = time.Second
+
+var (
+	serverInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "grpc_server_in_flight",
+		Help: "Unary gRPC calls being handled.",
+	})
+	serverShed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpc_server_shed_total",
+		Help: "Unary gRPC calls refused because MaxInFlight were being handled, by method.",
+	}, []string{"method"})
+)
+
+// unaryServerLimit handles at most max calls at a time and refuses the rest
+// at once with codes.ResourceExhausted, rather than letting them queue for
+// the database until they time out. The refusal carries a RetryInfo saying
+// when to try again, which the gateway turns into Retry-After.
+func unaryServerLimit(max int) grpc.UnaryServerInterceptor {
+	slots := make(chan struct{}, max)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		select {
+		case slots <- struct{}{}:
+		default:
+			serverShed.WithLabelValues(info.FullMethod).Inc()
+			return nil, overloaded(max)
+		}
+		serverInFlight.Inc()
+		defer func() {
+			serverInFlight.Dec()
+			<-slots
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// overloaded is the error of a call shed by unaryServerLimit.
+func overloaded(max int) error {
+	st := status.Newf(codes.ResourceExhausted, "server is handling its limit of %d calls; retry in %s", max, shedRetryDelay)
+	detailed, err := st.WithDetails(
+		&errdetails.ErrorInfo{Domain: ShedDomain, Reason: ShedReason},
+		&errdetails.RetryInfo{RetryDelay: durationpb.New(shedRetryDelay)},
+	)
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
@@ -0,0 +1,70 @@
+package grpcmiddleware
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryServerLimit(t *testing.T) {
+	limit := unaryServerLimit(2)
+	info := &grpc.UnaryServerInfo{FullMethod: "/test/Limited"}
+	release := make(chan struct{})
+	started := make(chan struct{})
+	done := make(chan error)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := limit(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+				started <- struct{}{}
+				<-release
+				return nil, nil
+			})
+			done <- err
+		}()
+		<-started
+	}
+	if got := testutil.ToFloat64(serverInFlight); got != 2 {
+		t.Errorf("in flight = %v, want 2", got)
+	}
+
+	// A third call while both slots are taken is refused at once
+	_, err := limit(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		t.Error("handler ran over the limit")
+		return nil, nil
+	})
+	st := status.Convert(err)
+	if st.Code() != codes.ResourceExhausted {
+		t.Fatalf("call over the limit = %v, want ResourceExhausted", err)
+	}
+	var retry *errdetails.RetryInfo
+	for _, detail := range st.Details() {
+		if d, ok := detail.(*errdetails.RetryInfo); ok {
+			retry = d
+		}
+	}
+	if retry == nil || retry.RetryDelay.AsDuration() != shedRetryDelay {
+		t.Errorf("retry info = %v, want a delay of %s", retry, shedRetryDelay)
+	}
+	if got := testutil.ToFloat64(serverShed.WithLabelValues(info.FullMethod)); got != 1 {
+		t.Errorf("shed = %v, want 1", got)
+	}
+
+	close(release)
+	for i := 0; i < 2; i++ {
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	}
+	if got := testutil.ToFloat64(serverInFlight); got != 0 {
+		t.Errorf("in flight after the calls = %v, want 0", got)
+	}
+	// The freed slots take calls again
+	if _, err := limit(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) { return nil, nil }); err != nil {
+		t.Errorf("call after the others ended = %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+package grpcmiddleware
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/technonext/todo-app/proto/logging"
+)
+
+// logCall logs one finished call on logger, which carries the call's
+// request and user ids. Server faults are errors; codes a caller brings on
+// itself, such as NotFound, are logged as info like successes.
+func logCall(ctx context.Context, logger *slog.Logger, method string, start time.Time, err error) {
+	st := status.Convert(err)
+	level := slog.LevelInfo
+	switch st.Code() {
+	case codes.Unknown, codes.Internal, codes.DataLoss, codes.Unavailable, codes.DeadlineExceeded:
+		level = slog.LevelError
+	}
+
+	attrs := []slog.Attr{
+		slog.String("method", method),
+		slog.String("code", st.Code().String()),
+		slog.Duration("duration", time.Since(start)),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", st.Message()))
+	}
+	logger.LogAttrs(ctx, level, "grpc call", attrs...)
+}
+
+func unaryServerLogging(logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		callLogger := logging.ForRequest(logger, RequestID(ctx), requestUserID(req))
+		resp, err := handler(logging.NewContext(ctx, callLogger), req)
+		logCall(ctx, callLogger, info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+func streamServerLogging(logger *slog.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		callLogger := logging.ForRequest(logger, RequestID(ss.Context()), "")
+		err := handler(srv, &contextStream{ServerStream: ss, ctx: logging.NewContext(ss.Context(), callLogger)})
+		logCall(ss.Context(), callLogger, info.FullMethod, start, err)
+		return err
+	}
+}
+
+// requestUserID returns the user_id field of a request, which most requests
+// about one user's data have, or "".
+func requestUserID(req interface{}) string {
+	msg, ok := req.(proto.Message)
+	if !ok {
+		return ""
+	}
+	m := msg.ProtoReflect()
+	fd := m.Descriptor().Fields().ByName("user_id")
+	if fd == nil || fd.Kind() != protoreflect.StringKind || fd.IsList() {
+		return ""
+	}
+	return m.Get(fd).String()
+}
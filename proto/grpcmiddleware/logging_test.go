@@ -0,0 +1,64 @@
+package grpcmiddleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/technonext/todo-app/proto/logging"
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+func TestLogCallLevels(t *testing.T) {
+	tests := []struct {
+		err   error
+		level string
+	}{
+		{nil, "level=INFO"},
+		{status.Error(codes.NotFound, "missing"), "level=INFO"},
+		{status.Error(codes.Internal, "broken"), "level=ERROR"},
+		{status.Error(codes.Unavailable, "down"), "level=ERROR"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		logCall(context.Background(), slog.New(slog.NewTextHandler(&buf, nil)), "/test/Method", time.Now(), tt.err)
+		if !strings.Contains(buf.String(), tt.level) {
+			t.Errorf("%v logged %q, want %s", status.Code(tt.err), buf.String(), tt.level)
+		}
+	}
+}
+
+func TestUnaryLoggingScopesLoggerToRequest(t *testing.T) {
+	var buf bytes.Buffer
+	interceptor := unaryServerLogging(slog.New(slog.NewJSONHandler(&buf, nil)))
+	ctx := WithRequestID(context.Background(), "req-1")
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		logging.FromContext(ctx).Info("handling")
+		return nil, nil
+	}
+	if _, err := interceptor(ctx, &pb.ListTasksRequest{UserId: "u1"}, &grpc.UnaryServerInfo{FullMethod: "/todo.TaskService/ListTasks"}, handler); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("logged %d lines, want the handler's and the call's: %s", len(lines), buf.String())
+	}
+	for _, line := range lines {
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatal(err)
+		}
+		if entry["request_id"] != "req-1" || entry["user_id"] != "u1" {
+			t.Errorf("%s: want request_id req-1 and user_id u1", line)
+		}
+	}
+}
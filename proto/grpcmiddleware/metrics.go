@@ -0,0 +1,157 @@
+package grpcmiddleware
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// Calls are counted and timed per method and status code. Client streams are
+// measured until they are established, server streams until they end.
+var (
+	serverHandled = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpc_server_handled_total",
+		Help: "gRPC calls handled, by method and status code.",
+	}, []string{"method", "code"})
+	serverDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "grpc_server_handling_seconds",
+		Help:    "Time taken to handle gRPC calls.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "code"})
+	clientHandled = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpc_client_handled_total",
+		Help: "gRPC calls made, by method and status code.",
+	}, []string{"method", "code"})
+	clientDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "grpc_client_handling_seconds",
+		Help:    "Time taken by gRPC calls made, including retries.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "code"})
+	clientRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpc_client_retries_total",
+		Help: "gRPC calls retried, by method and the code of the failed attempt.",
+	}, []string{"method", "code"})
+	// Attempts failed by a fault the service injected on purpose; see
+	// package faults
+	clientInjectedFaults = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpc_client_injected_faults_total",
+		Help: "gRPC call attempts failed by an injected fault, by method and status code.",
+	}, []string{"method", "code"})
+
+	registerOnce sync.Once
+)
+
+func registerMetrics() {
+	registerOnce.Do(func() {
+		prometheus.MustRegister(serverHandled, serverDuration, clientHandled, clientDuration, clientRetries, clientInjectedFaults,
+			serverInFlight, serverShed)
+	})
+}
+
+func observe(handled *prometheus.CounterVec, duration *prometheus.HistogramVec, method string, start time.Time, err error) {
+	code := status.Code(err).String()
+	handled.WithLabelValues(method, code).Inc()
+	duration.WithLabelValues(method, code).Observe(time.Since(start).Seconds())
+}
+
+func unaryServerMetrics(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	observe(serverHandled, serverDuration, info.FullMethod, start, err)
+	return resp, err
+}
+
+func streamServerMetrics(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, ss)
+	observe(serverHandled, serverDuration, info.FullMethod, start, err)
+	return err
+}
+
+func unaryClientMetrics(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	start := time.Now()
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	observe(clientHandled, clientDuration, method, start, err)
+	return err
+}
+
+func streamClientMetrics(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	start := time.Now()
+	stream, err := streamer(ctx, desc, cc, method, opts...)
+	observe(clientHandled, clientDuration, method, start, err)
+	return stream, err
+}
+
+// MetricsConfig configures the operator's HTTP server, which serves the
+// metrics at /metrics.
+type MetricsConfig struct {
+	// Port is where to listen; no server is started when empty
+	Port string
+	// Diagnostics, when set, is served at /diagnostics
+	Diagnostics http.Handler
+	// Info, when set, is served at /debug/info
+	Info http.Handler
+	// Pprof serves the net/http/pprof profiles at /debug/pprof/; see
+	// PprofFromEnv
+	Pprof bool
+}
+
+// ServeMetrics starts the operator's HTTP server in the background.
+func ServeMetrics(cfg MetricsConfig) {
+	if cfg.Port == "" {
+		return
+	}
+	registerMetrics()
+	go func() {
+		slog.Info("Serving metrics", "port", cfg.Port, "pprof", cfg.Pprof)
+		if err := http.ListenAndServe(":"+cfg.Port, metricsMux(cfg)); err != nil {
+			slog.Error("Metrics server stopped", "error", err)
+		}
+	}()
+}
+
+func metricsMux(cfg MetricsConfig) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	if cfg.Diagnostics != nil {
+		mux.Handle("/diagnostics", cfg.Diagnostics)
+	}
+	if cfg.Info != nil {
+		mux.Handle("/debug/info", cfg.Info)
+	}
+	if cfg.Pprof {
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	}
+	return mux
+}
+
+// PprofFromEnv reports whether to serve live profiles next to the metrics,
+// following PPROF_ENABLED, false by default. Profiles show the process's
+// command line and can hold a CPU for as long as the caller asks, so they
+// stay off unless someone is profiling.
+func PprofFromEnv() (bool, error) {
+	raw := os.Getenv("PPROF_ENABLED")
+	if raw == "" {
+		return false, nil
+	}
+	enabled, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, fmt.Errorf("invalid PPROF_ENABLED %q", raw)
+	}
+	return enabled, nil
+}
@@ -0,0 +1,57 @@
+package grpcmiddleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPprofFromEnv(t *testing.T) {
+	tests := []struct {
+		enabled string
+		want    bool
+		wantErr bool
+	}{
+		{"", false, false},
+		{"true", true, false},
+		{"false", false, false},
+		{"sometimes", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.enabled, func(t *testing.T) {
+			t.Setenv("PPROF_ENABLED", tt.enabled)
+			got, err := PprofFromEnv()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("enabled = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMetricsMux(t *testing.T) {
+	info := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("info")) })
+	tests := []struct {
+		name string
+		cfg  MetricsConfig
+		path string
+		want int
+	}{
+		{"info", MetricsConfig{Info: info}, "/debug/info", http.StatusOK},
+		{"no info", MetricsConfig{}, "/debug/info", http.StatusNotFound},
+		{"pprof", MetricsConfig{Pprof: true}, "/debug/pprof/", http.StatusOK},
+		{"pprof disabled", MetricsConfig{Info: info}, "/debug/pprof/", http.StatusNotFound},
+		{"pprof disabled, goroutines", MetricsConfig{}, "/debug/pprof/goroutine", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			metricsMux(tt.cfg).ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.want {
+				t.Errorf("GET %s = %d, want %d", tt.path, w.Code, tt.want)
+			}
+		})
+	}
+}
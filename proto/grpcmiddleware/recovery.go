@@ -0,0 +1,44 @@
+package grpcmiddleware
+
+import (
+	"context"
+	"log/slog"
+	"runtime/debug"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// recovered turns a handler panic into codes.Internal, logging the panic and
+// its stack. The caller only learns that the call failed.
+func recovered(ctx context.Context, logger *slog.Logger, method string, p interface{}) error {
+	logger.ErrorContext(ctx, "grpc handler panicked",
+		"method", method,
+		"request_id", RequestID(ctx),
+		"panic", p,
+		"stack", string(debug.Stack()))
+	return status.Error(codes.Internal, "internal error")
+}
+
+func unaryServerRecovery(logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				resp, err = nil, recovered(ctx, logger, info.FullMethod, p)
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+func streamServerRecovery(logger *slog.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				err = recovered(ss.Context(), logger, info.FullMethod, p)
+			}
+		}()
+		return handler(srv, ss)
+	}
+}
@@ -0,0 +1,92 @@
+package grpcmiddleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/technonext/todo-app/proto/callctx"
+)
+
+// RequestIDHeader carries a call's request id. Servers reuse the caller's id
+// or make one up, and return it in the response headers; clients pass on the
+// id of the call they are serving, so one id follows a request across
+// services.
+const RequestIDHeader = callctx.RequestIDHeader
+
+type requestIDKey struct{}
+
+// RequestID returns the request id of the call ctx belongs to, or "".
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// WithRequestID returns ctx with the request id set, for calls made outside
+// a server call, such as the gateway's.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// NewRequestID makes up a request id, for callers that were sent none, such
+// as the gateway for a client's request.
+func NewRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// incomingRequestID returns the caller's request id, or a new one when it
+// sent none or a malformed one.
+func incomingRequestID(ctx context.Context) string {
+	if id := callctx.FromIncoming(ctx).RequestID; id != "" {
+		return id
+	}
+	return NewRequestID()
+}
+
+func unaryServerRequestID(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	id := incomingRequestID(ctx)
+	grpc.SetHeader(ctx, metadata.Pairs(RequestIDHeader, id))
+	return handler(WithRequestID(ctx, id), req)
+}
+
+func streamServerRequestID(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	id := incomingRequestID(ss.Context())
+	ss.SetHeader(metadata.Pairs(RequestIDHeader, id))
+	return handler(srv, &contextStream{ServerStream: ss, ctx: WithRequestID(ss.Context(), id)})
+}
+
+// contextStream replaces a server stream's context.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+// outgoingRequestID adds the request id of ctx, or a new one, to the call,
+// unless the caller already put one in the call's metadata.
+func outgoingRequestID(ctx context.Context) context.Context {
+	if md, _ := metadata.FromOutgoingContext(ctx); len(md.Get(RequestIDHeader)) > 0 {
+		return ctx
+	}
+	id := RequestID(ctx)
+	if id == "" {
+		id = NewRequestID()
+	}
+	return metadata.AppendToOutgoingContext(ctx, RequestIDHeader, id)
+}
+
+func unaryClientRequestID(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return invoker(outgoingRequestID(ctx), method, req, reply, cc, opts...)
+}
+
+func streamClientRequestID(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return streamer(outgoingRequestID(ctx), desc, cc, method, opts...)
+}
@@ -0,0 +1,54 @@
+package grpcmiddleware
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestUnaryServerRequestID(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/test/Method"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return RequestID(ctx), nil
+	}
+
+	incoming := metadata.NewIncomingContext(context.Background(), metadata.Pairs(RequestIDHeader, "abc"))
+	got, _ := unaryServerRequestID(incoming, nil, info, handler)
+	if got != "abc" {
+		t.Errorf("request id = %q, want the caller's abc", got)
+	}
+
+	got, _ = unaryServerRequestID(context.Background(), nil, info, handler)
+	if id, _ := got.(string); len(id) != 32 {
+		t.Errorf("request id = %q, want a new 32 character id", got)
+	}
+}
+
+func TestUnaryClientRequestIDPassesOnID(t *testing.T) {
+	var sent []string
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		sent = md.Get(RequestIDHeader)
+		return nil
+	}
+	unaryClientRequestID(WithRequestID(context.Background(), "abc"), "/test/Method", nil, nil, nil, invoker)
+	if len(sent) != 1 || sent[0] != "abc" {
+		t.Errorf("sent request ids %v, want [abc]", sent)
+	}
+}
+
+func TestUnaryClientRequestIDKeepsMetadataID(t *testing.T) {
+	var sent []string
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		sent = md.Get(RequestIDHeader)
+		return nil
+	}
+	ctx := metadata.NewOutgoingContext(context.Background(), metadata.Pairs(RequestIDHeader, "abc"))
+	unaryClientRequestID(ctx, "/test/Method", nil, nil, nil, invoker)
+	if len(sent) != 1 || sent[0] != "abc" {
+		t.Errorf("sent request ids %v, want [abc]", sent)
+	}
+}
@@ -0,0 +1,53 @@
+package grpcmiddleware
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/technonext/todo-app/proto/faults"
+)
+
+// unaryClientRetry retries calls that fail with codes.Unavailable, which gRPC
+// returns when the call did not reach a server, such as while a service
+// restarts. Other failures are returned at once. Retries, and attempts failed
+// by injected faults, are counted so their effect can be watched.
+func unaryClientRetry(maxAttempts int, backoff time.Duration) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		wait := backoff
+		for attempt := 1; ; attempt++ {
+			err := invoker(ctx, method, req, reply, cc, opts...)
+			code := status.Code(err)
+			if injectedFault(err) {
+				clientInjectedFaults.WithLabelValues(method, code.String()).Inc()
+			}
+			if code != codes.Unavailable || attempt >= maxAttempts {
+				return err
+			}
+			clientRetries.WithLabelValues(method, code.String()).Inc()
+
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return err
+			case <-timer.C:
+			}
+			wait *= 2
+		}
+	}
+}
+
+// injectedFault reports whether err was injected by package faults.
+func injectedFault(err error) bool {
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok && info.Domain == faults.Domain && info.Reason == faults.Reason {
+			return true
+		}
+	}
+	return false
+}
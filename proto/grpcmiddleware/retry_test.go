@@ -0,0 +1,58 @@
+package grpcmiddleware
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryClientRetry(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "down")
+	tests := []struct {
+		name         string
+		results      []error
+		wantAttempts int
+		wantCode     codes.Code
+	}{
+		{"success", []error{nil}, 1, codes.OK},
+		{"recovers after unavailable", []error{unavailable, unavailable, nil}, 3, codes.OK},
+		{"gives up after max attempts", []error{unavailable, unavailable, unavailable, nil}, 3, codes.Unavailable},
+		{"other codes are not retried", []error{status.Error(codes.NotFound, "missing"), nil}, 1, codes.NotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+				err := tt.results[attempts]
+				attempts++
+				return err
+			}
+			err := unaryClientRetry(3, time.Millisecond)(context.Background(), "/test/Method", nil, nil, nil, invoker)
+			if attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", attempts, tt.wantAttempts)
+			}
+			if status.Code(err) != tt.wantCode {
+				t.Errorf("code = %v, want %v", status.Code(err), tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestUnaryClientRetryStopsWhenCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	attempts := 0
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		attempts++
+		cancel()
+		return status.Error(codes.Unavailable, "down")
+	}
+	err := unaryClientRetry(5, time.Hour)(ctx, "/test/Method", nil, nil, nil, invoker)
+	if attempts != 1 || !errors.Is(ctx.Err(), context.Canceled) || status.Code(err) != codes.Unavailable {
+		t.Errorf("attempts = %d, err = %v", attempts, err)
+	}
+}
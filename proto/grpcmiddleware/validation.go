@@ -0,0 +1,21 @@
+package grpcmiddleware
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/technonext/todo-app/proto/validation"
+)
+
+// unaryServerValidation rejects requests breaking the validate rules in
+// todo.proto with codes.InvalidArgument before they reach the handler.
+func unaryServerValidation(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if msg, ok := req.(proto.Message); ok {
+		if err := validation.Check(msg); err != nil {
+			return nil, err
+		}
+	}
+	return handler(ctx, req)
+}
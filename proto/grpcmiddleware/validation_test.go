@@ -0,0 +1,88 @@
+package grpcmiddleware
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// createOnlyTaskServer counts the CreateTask calls reaching it.
+type createOnlyTaskServer struct {
+	pb.UnimplementedTaskServiceServer
+	calls int
+}
+
+func (s *createOnlyTaskServer) CreateTask(ctx context.Context, req *pb.CreateTaskRequest) (*pb.TaskResponse, error) {
+	s.calls++
+	return &pb.TaskResponse{Task: &pb.Task{Title: req.Title}}, nil
+}
+
+func TestServerValidatesRequests(t *testing.T) {
+	lis := bufconn.Listen(1 << 20)
+	s := NewServer(ServerConfig{})
+	backend := &createOnlyTaskServer{}
+	pb.RegisterTaskServiceServer(s, backend)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	client := pb.NewTaskServiceClient(conn)
+
+	_, err = client.CreateTask(context.Background(), &pb.CreateTaskRequest{UserId: "u1"})
+	if code := status.Code(err); code != codes.InvalidArgument {
+		t.Fatalf("empty title: code = %v, want InvalidArgument", code)
+	}
+	if backend.calls != 0 {
+		t.Fatalf("handler ran %d times for an invalid request", backend.calls)
+	}
+
+	if _, err := client.CreateTask(context.Background(), &pb.CreateTaskRequest{UserId: "u1", Title: "Write report"}); err != nil {
+		t.Fatalf("valid request: %v", err)
+	}
+	if backend.calls != 1 {
+		t.Errorf("handler ran %d times, want 1", backend.calls)
+	}
+}
+
+func TestServerRunsUnaryInterceptorsBeforeValidation(t *testing.T) {
+	lis := bufconn.Listen(1 << 20)
+	fillTitle := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		req.(*pb.CreateTaskRequest).Title = "Untitled"
+		return handler(ctx, req)
+	}
+	s := NewServer(ServerConfig{Unary: []grpc.UnaryServerInterceptor{fillTitle}})
+	backend := &createOnlyTaskServer{}
+	pb.RegisterTaskServiceServer(s, backend)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	resp, err := pb.NewTaskServiceClient(conn).CreateTask(context.Background(), &pb.CreateTaskRequest{UserId: "u1"})
+	if err != nil {
+		t.Fatalf("title filled in before validation: %v", err)
+	}
+	if resp.Task.Title != "Untitled" {
+		t.Errorf("handler saw title %q", resp.Task.Title)
+	}
+}
@@ -0,0 +1,99 @@
+// Package logging sets up the structured logs every service writes: one JSON
+// object per line on stderr, at the level LOG_LEVEL names (debug, info, warn
+// or error; info by default), each carrying the service's name and version.
+// Secrets are redacted before anything is written; see Redact.
+//
+// Setup also routes the log package through the same logger, so code still
+// calling log.Printf writes the same JSON at info level.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"runtime/debug"
+	"strings"
+)
+
+// Setup makes a JSON logger for service the default of slog and the log
+// package, and returns it. An invalid LOG_LEVEL is reported and ignored.
+func Setup(service string) *slog.Logger {
+	level, err := ParseLevel(os.Getenv("LOG_LEVEL"))
+	logger := New(os.Stderr, service, level)
+	slog.SetDefault(logger)
+	if err != nil {
+		logger.Warn("Ignoring LOG_LEVEL", "error", err)
+	}
+	return logger
+}
+
+// New returns a JSON logger writing to w records at level or above, tagged
+// with service and Version.
+func New(w io.Writer, service string, level slog.Leveler) *slog.Logger {
+	handler := slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level})
+	return slog.New(redactingHandler{handler}).With("service", service, "version", Version())
+}
+
+// ParseLevel reads a LOG_LEVEL value; empty means info.
+func ParseLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(s) {
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "debug":
+		return slog.LevelDebug, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return slog.LevelInfo, fmt.Errorf("invalid LOG_LEVEL %q: use debug, info, warn or error", s)
+}
+
+// Version is SERVICE_VERSION, or else the VCS revision the binary was built
+// from, or "dev".
+func Version() string {
+	if v := os.Getenv("SERVICE_VERSION"); v != "" {
+		return v
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" && len(setting.Value) >= 12 {
+				return setting.Value[:12]
+			}
+		}
+	}
+	return "dev"
+}
+
+// Fatal logs msg and args as an error on the default logger and exits.
+func Fatal(msg string, args ...interface{}) {
+	slog.Default().Error(msg, args...)
+	os.Exit(1)
+}
+
+type loggerKey struct{}
+
+// NewContext returns ctx carrying logger, for FromContext.
+func NewContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext returns the logger of the request ctx belongs to, which
+// carries its request and user ids, or the default logger.
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// ForRequest returns logger tagged with the request id and, when known, the
+// id of the user the request is about.
+func ForRequest(logger *slog.Logger, requestID, userID string) *slog.Logger {
+	if userID == "" {
+		return logger.With("request_id", requestID)
+	}
+	return logger.With("request_id", requestID, "user_id", userID)
+}
@@ -0,0 +1,370 @@
+// Package migrate runs a service's one-shot data migrations: versioned
+// changes to documents already stored, such as converting a field to a new
+// type, applied in order and recorded so each runs once.
+//
+// A migration walks the documents its Filter matches in _id order, in
+// batches, and writes the change Update returns for each. Progress is saved
+// after every batch, so a run that stops part way resumes after the last
+// batch it finished instead of starting over. The migrations collection
+// holds a record per service and version, and a lock document per service
+// that keeps two runners from migrating the same service at once.
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/technonext/todo-app/proto/schema"
+)
+
+const (
+	// Collection records applied migrations and holds the locks
+	Collection = "migrations"
+	// DefaultBatchSize is how many documents a batch reads when the runner
+	// sets no BatchSize
+	DefaultBatchSize = 500
+	// lockTTL is how long a runner's lock lasts without being renewed. Each
+	// batch renews it, so it only runs out when the runner died.
+	lockTTL = 2 * time.Minute
+)
+
+// ErrLocked is returned by Run while another runner is migrating the service.
+var ErrLocked = errors.New("another runner is migrating")
+
+// Migration is one versioned change to a service's documents.
+type Migration struct {
+	// Version orders the service's migrations; versions are positive and
+	// never reused
+	Version     int
+	Description string
+	// Collection holds the documents to migrate
+	Collection string
+	// Filter matches the documents that may need the change; nil matches
+	// them all. It is checked again when a change is written, so a document
+	// changed meanwhile is left alone.
+	Filter bson.M
+	// Update returns the update for one document, or nil when the document
+	// needs no change. An error stops the migration.
+	Update func(doc bson.Raw) (bson.M, error)
+	// SchemaVersion, when set, is the schema version of the documents in
+	// their new layout; see package schema. The documents the migration
+	// changes are stamped with it, unless already newer.
+	SchemaVersion int
+}
+
+// Result describes what a migration did, or in a dry run would do.
+type Result struct {
+	Version     int
+	Description string
+	// Scanned counts the documents the filter matched, Changed those that
+	// were updated. A resumed migration counts its earlier runs too.
+	Scanned int64
+	Changed int64
+}
+
+// record is a migration's progress, stored in Collection.
+type record struct {
+	ID          string `bson:"_id"`
+	Service     string `bson:"service"`
+	Version     int    `bson:"version"`
+	Description string `bson:"description"`
+	// LastID is the _id of the last document of the last finished batch
+	LastID    interface{} `bson:"last_id,omitempty"`
+	Scanned   int64       `bson:"scanned"`
+	Changed   int64       `bson:"changed"`
+	StartedAt time.Time   `bson:"started_at"`
+	AppliedAt *time.Time  `bson:"applied_at,omitempty"`
+}
+
+// Runner applies one service's migrations to a database.
+type Runner struct {
+	db         *mongo.Database
+	records    *mongo.Collection
+	service    string
+	migrations []Migration
+	owner      string
+
+	// BatchSize is how many documents a batch reads; 0 means
+	// DefaultBatchSize
+	BatchSize int
+}
+
+// New creates a Runner for the service's migrations, which may be listed
+// in any order.
+func New(db *mongo.Database, service string, migrations ...Migration) (*Runner, error) {
+	sorted := append([]Migration(nil), migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	// The last schema version of each collection so far
+	schemaVersions := map[string]int{}
+	for i, m := range sorted {
+		if m.Version <= 0 {
+			return nil, fmt.Errorf("migration %q has version %d, versions must be positive", m.Description, m.Version)
+		}
+		if i > 0 && sorted[i-1].Version == m.Version {
+			return nil, fmt.Errorf("migrations %q and %q share version %d", sorted[i-1].Description, m.Description, m.Version)
+		}
+		if m.Collection == "" || m.Update == nil {
+			return nil, fmt.Errorf("migration %d needs a collection and an update", m.Version)
+		}
+		if m.SchemaVersion != 0 {
+			if last := schemaVersions[m.Collection]; m.SchemaVersion <= last {
+				return nil, fmt.Errorf("migration %d has schema version %d, after %d for %s", m.Version, m.SchemaVersion, last, m.Collection)
+			}
+			schemaVersions[m.Collection] = m.SchemaVersion
+		}
+	}
+	host, _ := os.Hostname()
+	return &Runner{
+		db:         db,
+		records:    db.Collection(Collection),
+		service:    service,
+		migrations: sorted,
+		owner:      fmt.Sprintf("%s/%d/%s", host, os.Getpid(), primitive.NewObjectID().Hex()),
+	}, nil
+}
+
+func (r *Runner) recordID(version int) string {
+	return fmt.Sprintf("%s/%d", r.service, version)
+}
+
+func (r *Runner) batchSize() int64 {
+	if r.BatchSize > 0 {
+		return int64(r.BatchSize)
+	}
+	return DefaultBatchSize
+}
+
+// Pending lists the migrations not applied yet, in version order.
+func (r *Runner) Pending(ctx context.Context) ([]Migration, error) {
+	cursor, err := r.records.Find(ctx, bson.M{"service": r.service, "applied_at": bson.M{"$exists": true}})
+	if err != nil {
+		return nil, err
+	}
+	var applied []record
+	if err := cursor.All(ctx, &applied); err != nil {
+		return nil, err
+	}
+	done := make(map[int]bool, len(applied))
+	for _, rec := range applied {
+		done[rec.Version] = true
+	}
+
+	var pending []Migration
+	for _, m := range r.migrations {
+		if !done[m.Version] {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// Run applies the pending migrations in order, returning ErrLocked while
+// another runner holds the service's lock. The results cover the migrations
+// that finished, and the one that failed if any.
+func (r *Runner) Run(ctx context.Context) ([]Result, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.unlock()
+
+	pending, err := r.Pending(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var results []Result
+	for _, m := range pending {
+		result, err := r.apply(ctx, m, false)
+		results = append(results, result)
+		if err != nil {
+			return results, fmt.Errorf("migration %d (%s): %w", m.Version, m.Description, err)
+		}
+	}
+	return results, nil
+}
+
+// DryRun reports what Run would change without writing anything or taking
+// the lock. Migrations a previous run left part way are counted from where
+// Run would resume.
+func (r *Runner) DryRun(ctx context.Context) ([]Result, error) {
+	pending, err := r.Pending(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var results []Result
+	for _, m := range pending {
+		result, err := r.apply(ctx, m, true)
+		results = append(results, result)
+		if err != nil {
+			return results, fmt.Errorf("migration %d (%s): %w", m.Version, m.Description, err)
+		}
+	}
+	return results, nil
+}
+
+// apply works through one migration in batches, from where an earlier run
+// stopped. A dry run only counts.
+func (r *Runner) apply(ctx context.Context, m Migration, dryRun bool) (Result, error) {
+	rec := record{
+		ID:          r.recordID(m.Version),
+		Service:     r.service,
+		Version:     m.Version,
+		Description: m.Description,
+		StartedAt:   time.Now(),
+	}
+	err := r.records.FindOne(ctx, bson.M{"_id": rec.ID}).Decode(&rec)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return Result{Version: m.Version, Description: m.Description}, err
+	}
+	if err == nil && !dryRun {
+		slog.Info("Resuming migration", "service", r.service, "version", m.Version, "scanned", rec.Scanned)
+	}
+	if dryRun {
+		// Only what is left counts as what the run would do
+		rec.Scanned, rec.Changed = 0, 0
+	}
+
+	collection := r.db.Collection(m.Collection)
+	find := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(r.batchSize())
+	for {
+		filter := matching(m.Filter)
+		if rec.LastID != nil {
+			filter = bson.M{"$and": bson.A{filter, bson.M{"_id": bson.M{"$gt": rec.LastID}}}}
+		}
+		cursor, err := collection.Find(ctx, filter, find)
+		if err != nil {
+			return rec.result(), err
+		}
+		var batch []bson.Raw
+		if err := cursor.All(ctx, &batch); err != nil {
+			return rec.result(), err
+		}
+
+		var models []mongo.WriteModel
+		for _, doc := range batch {
+			update, err := m.Update(doc)
+			if err != nil {
+				return rec.result(), fmt.Errorf("document %v: %w", doc.Lookup("_id"), err)
+			}
+			if update != nil {
+				if m.SchemaVersion > 0 {
+					update = stamped(update, m.SchemaVersion)
+				}
+				selector := bson.M{"$and": bson.A{bson.M{"_id": doc.Lookup("_id")}, matching(m.Filter)}}
+				models = append(models, mongo.NewUpdateOneModel().SetFilter(selector).SetUpdate(update))
+			}
+		}
+		rec.Scanned += int64(len(batch))
+		if len(batch) > 0 {
+			rec.LastID = batch[len(batch)-1].Lookup("_id")
+		}
+
+		if dryRun {
+			rec.Changed += int64(len(models))
+		} else {
+			if len(models) > 0 {
+				written, err := collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+				if err != nil {
+					return rec.result(), err
+				}
+				rec.Changed += written.ModifiedCount
+			}
+			if int64(len(batch)) < r.batchSize() {
+				now := time.Now()
+				rec.AppliedAt = &now
+			}
+			if err := r.save(ctx, rec); err != nil {
+				return rec.result(), err
+			}
+			slog.Info("Migrating", "service", r.service, "version", m.Version, "scanned", rec.Scanned, "changed", rec.Changed)
+		}
+		if int64(len(batch)) < r.batchSize() {
+			return rec.result(), nil
+		}
+	}
+}
+
+// stamped returns update also raising the document's schema version to
+// version.
+func stamped(update bson.M, version int) bson.M {
+	raise, _ := update["$max"].(bson.M)
+	if raise == nil {
+		raise = bson.M{}
+	}
+	raise[schema.Field] = version
+	update["$max"] = raise
+	return update
+}
+
+func matching(filter bson.M) bson.M {
+	if filter == nil {
+		return bson.M{}
+	}
+	return filter
+}
+
+func (rec record) result() Result {
+	return Result{Version: rec.Version, Description: rec.Description, Scanned: rec.Scanned, Changed: rec.Changed}
+}
+
+// save stores the migration's progress and renews the lock, failing if
+// another runner has taken it over since.
+func (r *Runner) save(ctx context.Context, rec record) error {
+	_, err := r.records.ReplaceOne(ctx, bson.M{"_id": rec.ID}, rec, options.Replace().SetUpsert(true))
+	if err != nil {
+		return err
+	}
+	renewed, err := r.records.UpdateOne(ctx,
+		bson.M{"_id": r.lockID(), "owner": r.owner},
+		bson.M{"$set": bson.M{"expires_at": time.Now().Add(lockTTL)}},
+	)
+	if err != nil {
+		return err
+	}
+	if renewed.MatchedCount == 0 {
+		return fmt.Errorf("lost the migration lock of %s", r.service)
+	}
+	return nil
+}
+
+func (r *Runner) lockID() string {
+	return r.service + "/lock"
+}
+
+// lock takes the service's lock, or returns ErrLocked while another
+// runner's has not run out.
+func (r *Runner) lock(ctx context.Context) error {
+	now := time.Now()
+	_, err := r.records.UpdateOne(ctx,
+		bson.M{"_id": r.lockID(), "expires_at": bson.M{"$lt": now}},
+		bson.M{"$set": bson.M{"owner": r.owner, "expires_at": now.Add(lockTTL)}},
+		options.Update().SetUpsert(true),
+	)
+	if mongo.IsDuplicateKeyError(err) {
+		var holder struct {
+			Owner     string    `bson:"owner"`
+			ExpiresAt time.Time `bson:"expires_at"`
+		}
+		r.records.FindOne(ctx, bson.M{"_id": r.lockID()}).Decode(&holder)
+		return fmt.Errorf("%w %s: %s holds the lock until %s", ErrLocked, r.service, holder.Owner, holder.ExpiresAt.Format(time.RFC3339))
+	}
+	return err
+}
+
+// unlock releases the lock, if this runner still holds it.
+func (r *Runner) unlock() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := r.records.DeleteOne(ctx, bson.M{"_id": r.lockID(), "owner": r.owner}); err != nil {
+		slog.Warn("Failed to release the migration lock", "service", r.service, "error", err)
+	}
+}
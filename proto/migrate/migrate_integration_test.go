@@ -0,0 +1,225 @@
+//go:build integration
+
+package migrate
+
+// Run with a MongoDB at MONGO_TEST_URI (default mongodb://localhost:27017)
+// and go test -tags integration ./...
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func testDatabase(t *testing.T) *mongo.Database {
+	t.Helper()
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		uri = "mongodb://localhost:27017"
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri).SetServerSelectionTimeout(2*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Ping(ctx, nil); err != nil {
+		t.Skipf("MongoDB not reachable at %s: %v", uri, err)
+	}
+	db := client.Database(fmt.Sprintf("migrate_test_%d", time.Now().UnixNano()))
+	t.Cleanup(func() {
+		db.Drop(context.Background())
+		client.Disconnect(context.Background())
+	})
+	return db
+}
+
+// upper is a migration uppercasing the name of items, failing on the
+// names in fail.
+func upper(fail map[string]bool) Migration {
+	return Migration{
+		Version:     1,
+		Description: "uppercase names",
+		Collection:  "items",
+		Filter:      bson.M{"name": bson.M{"$regex": "[a-z]"}},
+		Update: func(doc bson.Raw) (bson.M, error) {
+			name := doc.Lookup("name").StringValue()
+			if fail[name] {
+				return nil, errors.New("refusing " + name)
+			}
+			if name == "skip" {
+				return nil, nil
+			}
+			return bson.M{"$set": bson.M{"name": strings.ToUpper(name)}}, nil
+		},
+	}
+}
+
+func TestRunResumesInBatches(t *testing.T) {
+	db := testDatabase(t)
+	ctx := context.Background()
+	items := db.Collection("items")
+	for i, name := range []string{"a", "b", "C", "d", "skip", "e", "f"} {
+		if _, err := items.InsertOne(ctx, bson.M{"_id": i, "name": name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fixed, err := New(db, "svc", upper(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixed.BatchSize = 2
+	dry, err := fixed.DryRun(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dry) != 1 || dry[0].Scanned != 6 || dry[0].Changed != 5 {
+		t.Fatalf("dry run = %+v, want 6 scanned and 5 changed", dry)
+	}
+	if n, _ := items.CountDocuments(ctx, bson.M{"name": "a"}); n != 1 {
+		t.Fatal("the dry run changed documents")
+	}
+
+	// The run fails in the batch holding e, after saving the batches before
+	failing, err := New(db, "svc", upper(map[string]bool{"e": true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	failing.BatchSize = 2
+	if _, err := failing.Run(ctx); err == nil || !strings.Contains(err.Error(), "refusing e") {
+		t.Fatalf("err = %v, want the failure on e", err)
+	}
+	if pending, _ := failing.Pending(ctx); len(pending) != 1 {
+		t.Fatalf("pending = %v, want the failed migration", pending)
+	}
+
+	dry, err = fixed.DryRun(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dry[0].Scanned != 2 || dry[0].Changed != 2 {
+		t.Errorf("dry run of the rest = %+v, want e and f", dry[0])
+	}
+	results, err := fixed.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Scanned != 6 || results[0].Changed != 5 {
+		t.Errorf("results = %+v, want 6 scanned and 5 changed over both runs", results)
+	}
+
+	cursor, err := items.Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var docs []struct {
+		Name string `bson:"name"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, doc := range docs {
+		names = append(names, doc.Name)
+	}
+	if got := strings.Join(names, ","); got != "A,B,C,D,skip,E,F" {
+		t.Errorf("names = %s", got)
+	}
+
+	if pending, _ := fixed.Pending(ctx); len(pending) != 0 {
+		t.Errorf("pending after the run = %v", pending)
+	}
+	if results, err := fixed.Run(ctx); err != nil || len(results) != 0 {
+		t.Errorf("second run = %v, %v, want nothing to do", results, err)
+	}
+}
+
+func TestRunStampsSchemaVersion(t *testing.T) {
+	db := testDatabase(t)
+	ctx := context.Background()
+	items := db.Collection("items")
+	docs := []interface{}{
+		bson.M{"_id": 1, "name": "a"},
+		bson.M{"_id": 2, "name": "skip"},
+		bson.M{"_id": 3, "name": "b", "schema_version": 5},
+	}
+	if _, err := items.InsertMany(ctx, docs); err != nil {
+		t.Fatal(err)
+	}
+
+	m := upper(nil)
+	m.SchemaVersion = 2
+	runner, err := New(db, "svc", m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runner.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// Only changed documents are stamped, and never with an older version
+	for id, want := range map[int]int32{1: 2, 2: 0, 3: 5} {
+		var doc struct {
+			SchemaVersion int32 `bson:"schema_version"`
+		}
+		if err := items.FindOne(ctx, bson.M{"_id": id}).Decode(&doc); err != nil {
+			t.Fatal(err)
+		}
+		if doc.SchemaVersion != want {
+			t.Errorf("item %d has schema version %d, want %d", id, doc.SchemaVersion, want)
+		}
+	}
+}
+
+func TestRunnersExcludeEachOther(t *testing.T) {
+	db := testDatabase(t)
+	ctx := context.Background()
+
+	first, err := New(db, "svc", upper(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := New(db, "svc", upper(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := New(db, "other", upper(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := first.lock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := second.Run(ctx); !errors.Is(err, ErrLocked) {
+		t.Errorf("second runner: err = %v, want ErrLocked", err)
+	}
+	if _, err := other.Run(ctx); err != nil {
+		t.Errorf("another service's runner: %v", err)
+	}
+
+	first.unlock()
+	if _, err := second.Run(ctx); err != nil {
+		t.Errorf("after the first runner let go: %v", err)
+	}
+
+	// A lock whose runner died runs out
+	if err := first.lock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Collection(Collection).UpdateOne(ctx, bson.M{"_id": first.lockID()}, bson.M{"$set": bson.M{"expires_at": time.Now().Add(-time.Second)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := second.lock(ctx); err != nil {
+		t.Errorf("taking over an expired lock: %v", err)
+	}
+}
@@ -0,0 +1,66 @@
+package migrate
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestNewChecksMigrations(t *testing.T) {
+	// New does not talk to the server, which need not exist
+	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI("mongodb://127.0.0.1:1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Disconnect(context.Background())
+	db := client.Database("migrate_test")
+
+	update := func(bson.Raw) (bson.M, error) { return nil, nil }
+	tests := []struct {
+		name       string
+		migrations []Migration
+		wantErr    string
+	}{
+		{"none", nil, ""},
+		{"out of order", []Migration{{Version: 2, Collection: "c", Update: update}, {Version: 1, Collection: "c", Update: update}}, ""},
+		{"zero version", []Migration{{Version: 0, Collection: "c", Update: update}}, "must be positive"},
+		{"shared version", []Migration{{Version: 1, Description: "a", Collection: "c", Update: update}, {Version: 1, Description: "b", Collection: "c", Update: update}}, "share version 1"},
+		{"no collection", []Migration{{Version: 1, Update: update}}, "needs a collection"},
+		{"no update", []Migration{{Version: 1, Collection: "c"}}, "needs a collection and an update"},
+		{"schema versions", []Migration{{Version: 1, Collection: "c", Update: update, SchemaVersion: 1}, {Version: 2, Collection: "d", Update: update, SchemaVersion: 1}, {Version: 3, Collection: "c", Update: update}, {Version: 4, Collection: "c", Update: update, SchemaVersion: 2}}, ""},
+		{"negative schema version", []Migration{{Version: 1, Collection: "c", Update: update, SchemaVersion: -1}}, "schema version -1"},
+		{"schema version going back", []Migration{{Version: 1, Collection: "c", Update: update, SchemaVersion: 2}, {Version: 2, Collection: "c", Update: update, SchemaVersion: 2}}, "schema version 2, after 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := New(db, "svc", tt.migrations...)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 1; i < len(r.migrations); i++ {
+				if r.migrations[i-1].Version >= r.migrations[i].Version {
+					t.Errorf("migrations not in version order: %d before %d", r.migrations[i-1].Version, r.migrations[i].Version)
+				}
+			}
+		})
+	}
+}
+
+func TestStampedRaisesSchemaVersion(t *testing.T) {
+	update := stamped(bson.M{"$set": bson.M{"name": "A"}, "$max": bson.M{"seen": 3}}, 2)
+	want := bson.M{"$set": bson.M{"name": "A"}, "$max": bson.M{"seen": 3, "schema_version": 2}}
+	if !reflect.DeepEqual(update, want) {
+		t.Errorf("update = %v, want %v", update, want)
+	}
+}
@@ -0,0 +1,43 @@
+package mongoutil
+
+import (
+	"reflect"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsoncodec"
+	"go.mongodb.org/mongo-driver/bson/bsonrw"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DatesAsStrings returns collection options that decode BSON dates into
+// string fields as RFC3339 text in the local zone, the way the services
+// have always stored timestamps. Collections whose date fields are being
+// migrated from strings to dates are opened with it, so code reading them
+// into strings works on documents of either kind.
+func DatesAsStrings() *options.CollectionOptions {
+	return options.Collection().SetRegistry(dateStringRegistry())
+}
+
+func dateStringRegistry() *bsoncodec.Registry {
+	registry := bson.NewRegistry()
+	stringType := reflect.TypeOf("")
+	strings, err := registry.LookupDecoder(stringType)
+	if err != nil {
+		panic(err)
+	}
+	registry.RegisterTypeDecoder(stringType, bsoncodec.ValueDecoderFunc(
+		func(dc bsoncodec.DecodeContext, vr bsonrw.ValueReader, val reflect.Value) error {
+			if vr.Type() != bsontype.DateTime {
+				return strings.DecodeValue(dc, vr, val)
+			}
+			ms, err := vr.ReadDateTime()
+			if err != nil {
+				return err
+			}
+			val.SetString(time.UnixMilli(ms).In(time.Local).Format(time.RFC3339))
+			return nil
+		}))
+	return registry
+}
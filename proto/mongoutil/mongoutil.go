@@ -0,0 +1,264 @@
+// Package mongoutil connects the services to MongoDB with one set of
+// settings, read from the same environment variables in every service.
+//
+// The connection is given either as MONGO_URI or as MONGO_USERNAME,
+// MONGO_PASSWORD and MONGO_HOST, never both, and tuned with:
+//
+//	MONGO_DATABASE                    database holding the collections (todo_app)
+//	MONGO_AUTH_SOURCE                 database to authenticate against (admin)
+//	MONGO_TLS                         connect over TLS (false)
+//	MONGO_TLS_CA_FILE                 PEM CA bundle to verify the server with
+//	MONGO_MAX_POOL_SIZE               connections per server (100)
+//	MONGO_MIN_POOL_SIZE               idle connections kept open (0)
+//	MONGO_SERVER_SELECTION_TIMEOUT    how long an operation waits for a server (10s)
+//	MONGO_CONNECT_ATTEMPTS            tries before giving up at startup (5)
+//	MONGO_CONNECT_BACKOFF             wait after the first failed try, doubling (1s)
+package mongoutil
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Config describes how to reach MongoDB.
+type Config struct {
+	// URI is the full connection string. When empty, it is built from
+	// Username, Password and Host, which may list several host:port pairs.
+	URI      string
+	Username string
+	Password string
+	Host     string
+	// Database holds the services' collections and goes in the path of a
+	// built URI; defaults to todo_app
+	Database string
+	// AuthSource defaults to admin for a built URI
+	AuthSource string
+
+	TLS       bool
+	TLSCAFile string
+
+	MaxPoolSize            uint64
+	MinPoolSize            uint64
+	ServerSelectionTimeout time.Duration
+
+	// ConnectAttempts bounds how often Connect tries to reach the server
+	ConnectAttempts int
+	ConnectBackoff  time.Duration
+}
+
+// ConfigFromEnv reads the settings listed in the package documentation.
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		URI:        os.Getenv("MONGO_URI"),
+		Username:   os.Getenv("MONGO_USERNAME"),
+		Password:   os.Getenv("MONGO_PASSWORD"),
+		Host:       os.Getenv("MONGO_HOST"),
+		Database:   os.Getenv("MONGO_DATABASE"),
+		AuthSource: os.Getenv("MONGO_AUTH_SOURCE"),
+		TLSCAFile:  os.Getenv("MONGO_TLS_CA_FILE"),
+	}
+
+	var err error
+	if cfg.TLS, err = strconv.ParseBool(getEnv("MONGO_TLS", "false")); err != nil {
+		return cfg, fmt.Errorf("invalid MONGO_TLS %q", os.Getenv("MONGO_TLS"))
+	}
+	if cfg.MaxPoolSize, err = strconv.ParseUint(getEnv("MONGO_MAX_POOL_SIZE", "100"), 10, 64); err != nil || cfg.MaxPoolSize == 0 {
+		return cfg, fmt.Errorf("invalid MONGO_MAX_POOL_SIZE %q", os.Getenv("MONGO_MAX_POOL_SIZE"))
+	}
+	if cfg.MinPoolSize, err = strconv.ParseUint(getEnv("MONGO_MIN_POOL_SIZE", "0"), 10, 64); err != nil || cfg.MinPoolSize > cfg.MaxPoolSize {
+		return cfg, fmt.Errorf("invalid MONGO_MIN_POOL_SIZE %q: must not exceed MONGO_MAX_POOL_SIZE", os.Getenv("MONGO_MIN_POOL_SIZE"))
+	}
+	if cfg.ServerSelectionTimeout, err = time.ParseDuration(getEnv("MONGO_SERVER_SELECTION_TIMEOUT", "10s")); err != nil || cfg.ServerSelectionTimeout <= 0 {
+		return cfg, fmt.Errorf("invalid MONGO_SERVER_SELECTION_TIMEOUT %q", os.Getenv("MONGO_SERVER_SELECTION_TIMEOUT"))
+	}
+	if cfg.ConnectAttempts, err = strconv.Atoi(getEnv("MONGO_CONNECT_ATTEMPTS", "5")); err != nil || cfg.ConnectAttempts < 1 {
+		return cfg, fmt.Errorf("invalid MONGO_CONNECT_ATTEMPTS %q", os.Getenv("MONGO_CONNECT_ATTEMPTS"))
+	}
+	if cfg.ConnectBackoff, err = time.ParseDuration(getEnv("MONGO_CONNECT_BACKOFF", "1s")); err != nil || cfg.ConnectBackoff < 0 {
+		return cfg, fmt.Errorf("invalid MONGO_CONNECT_BACKOFF %q", os.Getenv("MONGO_CONNECT_BACKOFF"))
+	}
+	return cfg, nil
+}
+
+// BuildURI returns the connection string: URI as given, or one assembled
+// from the components with the credentials escaped.
+func (c Config) BuildURI() (string, error) {
+	components := c.Username != "" || c.Password != "" || c.Host != ""
+	if c.URI != "" {
+		if components {
+			return "", errors.New("set either MONGO_URI or MONGO_USERNAME, MONGO_PASSWORD and MONGO_HOST, not both")
+		}
+		return c.URI, nil
+	}
+	if c.Username == "" || c.Password == "" || c.Host == "" {
+		return "", errors.New("MONGO_URI, or MONGO_USERNAME, MONGO_PASSWORD and MONGO_HOST, must be set")
+	}
+
+	database := c.DatabaseName()
+	authSource := c.AuthSource
+	if authSource == "" {
+		authSource = "admin"
+	}
+	u := url.URL{
+		Scheme:   "mongodb",
+		User:     url.UserPassword(c.Username, c.Password),
+		Host:     c.Host,
+		Path:     "/" + database,
+		RawQuery: url.Values{"authSource": {authSource}}.Encode(),
+	}
+	return u.String(), nil
+}
+
+// Settings returns the settings by environment variable, for reporting.
+// MONGO_PASSWORD is left out; MONGO_URI, when set, may hold credentials the
+// caller must redact.
+func (c Config) Settings() map[string]string {
+	settings := map[string]string{
+		"MONGO_DATABASE":                 c.DatabaseName(),
+		"MONGO_TLS":                      strconv.FormatBool(c.TLS),
+		"MONGO_MAX_POOL_SIZE":            strconv.FormatUint(c.MaxPoolSize, 10),
+		"MONGO_MIN_POOL_SIZE":            strconv.FormatUint(c.MinPoolSize, 10),
+		"MONGO_SERVER_SELECTION_TIMEOUT": c.ServerSelectionTimeout.String(),
+		"MONGO_CONNECT_ATTEMPTS":         strconv.Itoa(c.ConnectAttempts),
+		"MONGO_CONNECT_BACKOFF":          c.ConnectBackoff.String(),
+	}
+	if c.URI != "" {
+		settings["MONGO_URI"] = c.URI
+	} else {
+		settings["MONGO_USERNAME"] = c.Username
+		settings["MONGO_HOST"] = c.Host
+		settings["MONGO_AUTH_SOURCE"] = c.AuthSource
+	}
+	if c.TLSCAFile != "" {
+		settings["MONGO_TLS_CA_FILE"] = c.TLSCAFile
+	}
+	return settings
+}
+
+// DatabaseName returns the database the services keep their collections in.
+func (c Config) DatabaseName() string {
+	if c.Database == "" {
+		return "todo_app"
+	}
+	return c.Database
+}
+
+// clientOptions turns the config into driver options. Settings in the URI
+// are overridden by the ones in the config.
+func (c Config) clientOptions() (*options.ClientOptions, error) {
+	uri, err := c.BuildURI()
+	if err != nil {
+		return nil, err
+	}
+	opts := options.Client().ApplyURI(uri)
+	if c.URI != "" && c.AuthSource != "" && opts.Auth != nil {
+		opts.Auth.AuthSource = c.AuthSource
+	}
+	if c.MaxPoolSize > 0 {
+		opts.SetMaxPoolSize(c.MaxPoolSize)
+	}
+	opts.SetMinPoolSize(c.MinPoolSize)
+	opts.SetPoolMonitor(poolMonitor)
+	if c.ServerSelectionTimeout > 0 {
+		opts.SetServerSelectionTimeout(c.ServerSelectionTimeout)
+	}
+
+	if c.TLS || c.TLSCAFile != "" {
+		tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+		if c.TLSCAFile != "" {
+			pem, err := os.ReadFile(c.TLSCAFile)
+			if err != nil {
+				return nil, fmt.Errorf("read MONGO_TLS_CA_FILE: %w", err)
+			}
+			tlsConfig.RootCAs = x509.NewCertPool()
+			if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates in MONGO_TLS_CA_FILE %s", c.TLSCAFile)
+			}
+		}
+		opts.SetTLSConfig(tlsConfig)
+	}
+	return opts, nil
+}
+
+// Connect connects and pings the server, retrying with backoff so a service
+// started alongside MongoDB waits for it rather than exiting.
+func Connect(ctx context.Context, cfg Config) (*mongo.Client, error) {
+	opts, err := cfg.clientOptions()
+	if err != nil {
+		return nil, err
+	}
+	attempts := cfg.ConnectAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	wait := cfg.ConnectBackoff
+	for attempt := 1; ; attempt++ {
+		client, err := mongo.Connect(ctx, opts)
+		if err == nil {
+			if err = client.Ping(ctx, nil); err == nil {
+				return client, nil
+			}
+			client.Disconnect(context.Background())
+		}
+		if attempt >= attempts {
+			return nil, fmt.Errorf("connect to MongoDB after %d attempts: %w", attempt, err)
+		}
+
+		slog.Warn("Failed to connect to MongoDB, retrying", "attempt", attempt, "attempts", attempts, "retry_in", wait, "error", err)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
+
+// EnsureIndexes creates the indexes that do not exist yet. Creating an
+// index that exists with the same definition does nothing, so services call
+// it on every start; one that exists with different options is reported
+// rather than changed, since that needs it dropped first.
+func EnsureIndexes(ctx context.Context, collection *mongo.Collection, models ...mongo.IndexModel) error {
+	_, err := collection.Indexes().CreateMany(ctx, models)
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && (cmdErr.Name == "IndexOptionsConflict" || cmdErr.Name == "IndexKeySpecsConflict") {
+		return fmt.Errorf("index on %s conflicts with an existing one, which must be dropped to apply the new definition: %w", collection.Name(), err)
+	}
+	return err
+}
+
+// DropIndexes drops the named indexes, replaced by ones on other keys.
+// Indexes that do not exist, such as those an earlier start dropped, are
+// skipped, so services call it on every start.
+func DropIndexes(ctx context.Context, collection *mongo.Collection, names ...string) error {
+	for _, name := range names {
+		_, err := collection.Indexes().DropOne(ctx, name)
+		var cmdErr mongo.CommandError
+		if errors.As(err, &cmdErr) && (cmdErr.Name == "IndexNotFound" || cmdErr.Name == "NamespaceNotFound") {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("dropping index %s on %s: %w", name, collection.Name(), err)
+		}
+	}
+	return nil
+}
+
+func getEnv(key, fallback string) string {
+	if value, exists := os.LookupEnv(key); exists {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,122 @@
+//go:build integration
+
+package mongoutil
+
+// Run with a MongoDB at MONGO_TEST_URI (default mongodb://localhost:27017)
+// and go test -tags integration ./...
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestEnsureIndexes(t *testing.T) {
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		uri = "mongodb://localhost:27017"
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	client, err := Connect(ctx, Config{URI: uri, ServerSelectionTimeout: 2 * time.Second, ConnectAttempts: 1})
+	if err != nil {
+		t.Skipf("MongoDB not reachable at %s: %v", uri, err)
+	}
+	db := client.Database(fmt.Sprintf("mongoutil_test_%d", time.Now().UnixNano()))
+	t.Cleanup(func() {
+		db.Drop(context.Background())
+		client.Disconnect(context.Background())
+	})
+	collection := db.Collection("items")
+
+	models := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "email", Value: 1}}, Options: options.Index().SetUnique(true)},
+	}
+	tests := []struct {
+		name    string
+		models  []mongo.IndexModel
+		wantErr string
+	}{
+		{"create", models, ""},
+		{"same again", models, ""},
+		{"one of them again", models[1:], ""},
+		{"changed options", []mongo.IndexModel{{Keys: bson.D{{Key: "email", Value: 1}}}}, "must be dropped"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := EnsureIndexes(ctx, collection, tt.models...)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			specs, err := collection.Indexes().ListSpecifications(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// _id plus the two created
+			if len(specs) != 3 {
+				t.Errorf("%d indexes, want 3", len(specs))
+			}
+		})
+	}
+}
+
+func TestDropIndexes(t *testing.T) {
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		uri = "mongodb://localhost:27017"
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	client, err := Connect(ctx, Config{URI: uri, ServerSelectionTimeout: 2 * time.Second, ConnectAttempts: 1})
+	if err != nil {
+		t.Skipf("MongoDB not reachable at %s: %v", uri, err)
+	}
+	db := client.Database(fmt.Sprintf("mongoutil_test_%d", time.Now().UnixNano()))
+	t.Cleanup(func() {
+		db.Drop(context.Background())
+		client.Disconnect(context.Background())
+	})
+
+	// The collection does not exist yet
+	if err := DropIndexes(ctx, db.Collection("items"), "name_1"); err != nil {
+		t.Fatalf("dropping from a missing collection: %v", err)
+	}
+
+	collection := db.Collection("items")
+	err = EnsureIndexes(ctx, collection,
+		mongo.IndexModel{Keys: bson.D{{Key: "name", Value: 1}}},
+		mongo.IndexModel{Keys: bson.D{{Key: "kept", Value: 1}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := DropIndexes(ctx, collection, "name_1"); err != nil {
+			t.Fatalf("drop %d: %v", i+1, err)
+		}
+	}
+	specs, err := collection.Indexes().ListSpecifications(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, spec := range specs {
+		names = append(names, spec.Name)
+	}
+	if len(names) != 2 || names[1] != "kept_1" {
+		t.Errorf("indexes = %v, want _id_ and kept_1", names)
+	}
+}
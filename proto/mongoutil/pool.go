@@ -0,0 +1,39 @@
+package mongoutil
+
+import (
+	"sync/atomic"
+
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// PoolStats describes the connections of every client Connect opened.
+type PoolStats struct {
+	// Open connections, idle or in use
+	Size int32
+	// Connections currently checked out by operations
+	CheckedOut int32
+}
+
+var poolSize, poolCheckedOut atomic.Int32
+
+// Pool returns the current pool stats; a pool whose CheckedOut nears
+// MONGO_MAX_POOL_SIZE makes operations queue for a connection.
+func Pool() PoolStats {
+	return PoolStats{Size: poolSize.Load(), CheckedOut: poolCheckedOut.Load()}
+}
+
+// poolMonitor keeps the counters Pool reports.
+var poolMonitor = &event.PoolMonitor{
+	Event: func(e *event.PoolEvent) {
+		switch e.Type {
+		case event.ConnectionCreated:
+			poolSize.Add(1)
+		case event.ConnectionClosed:
+			poolSize.Add(-1)
+		case event.GetSucceeded:
+			poolCheckedOut.Add(1)
+		case event.ConnectionReturned:
+			poolCheckedOut.Add(-1)
+		}
+	},
+}
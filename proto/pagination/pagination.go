@@ -0,0 +1,113 @@
+// Package pagination pages every list RPC the same way. It resolves a
+// common.PageRequest into the skip and limit of a MongoDB find and describes
+// the page found in a common.PageResponse.
+//
+// Pages are numbered from 0. A limit of 0 takes the RPC's default and larger
+// ones are capped at its maximum. Cursors are opaque to clients but only hold
+// the offset of the next page, so like page numbers they can skip or repeat
+// items that change between requests.
+package pagination
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/grpc/codes"
+
+	"github.com/technonext/todo-app/proto/grpcerror"
+	"github.com/technonext/todo-app/proto/proto/common"
+)
+
+// Domain is the ErrorInfo domain of the errors for malformed requests.
+const Domain grpcerror.Domain = "todo.pagination"
+
+// cursorPrefix versions the cursor format.
+const cursorPrefix = "o1:"
+
+// Limits are the page sizes of an RPC.
+type Limits struct {
+	// Default is used for a limit of 0; if it is 0 too, every item is listed
+	Default int32
+	// Max caps larger limits; 0 leaves them uncapped
+	Max int32
+}
+
+// DefaultLimits are the page sizes of the list RPCs.
+var DefaultLimits = Limits{Default: 20, Max: 100}
+
+// Page is a resolved page: the items to skip, and how many to return, with a
+// Limit of 0 meaning all of them.
+type Page struct {
+	Offset int64
+	Limit  int32
+}
+
+// Resolve returns the page req asks for. A nil req comes from a client that
+// predates PageRequest, so the request's deprecated page and limit fields are
+// used as they always were, with neither default nor cap: a limit of 0 lists
+// every item.
+func Resolve(req *common.PageRequest, legacyPage, legacyLimit int32, limits Limits) (Page, error) {
+	if req == nil {
+		return Page{Offset: int64(legacyPage) * int64(legacyLimit), Limit: legacyLimit}, nil
+	}
+
+	limit := req.Limit
+	switch {
+	case limit < 0:
+		return Page{}, Domain.Errorf(codes.InvalidArgument, "INVALID_LIMIT", map[string]string{"limit": strconv.Itoa(int(limit))}, "limit must not be negative, got %d", limit)
+	case limit == 0:
+		limit = limits.Default
+	case limits.Max > 0 && limit > limits.Max:
+		limit = limits.Max
+	}
+
+	if req.Cursor != "" {
+		offset, ok := decodeCursor(req.Cursor)
+		if !ok {
+			return Page{}, Domain.Errorf(codes.InvalidArgument, "INVALID_CURSOR", map[string]string{"cursor": req.Cursor}, "invalid cursor %q: pass the next_cursor of the previous page", req.Cursor)
+		}
+		return Page{Offset: offset, Limit: limit}, nil
+	}
+	if req.Page < 0 {
+		return Page{}, Domain.Errorf(codes.InvalidArgument, "INVALID_PAGE", map[string]string{"page": strconv.Itoa(int(req.Page))}, "page must not be negative, got %d", req.Page)
+	}
+	return Page{Offset: int64(req.Page) * int64(limit), Limit: limit}, nil
+}
+
+// FindOptions returns find options skipping to the page and limited to it;
+// callers add their sort.
+func (p Page) FindOptions() *options.FindOptions {
+	return options.Find().SetSkip(p.Offset).SetLimit(int64(p.Limit))
+}
+
+// Response describes the page, which held returned of the total items
+// matching the request. It has a next cursor unless it is the last page.
+func (p Page) Response(total int64, returned int) *common.PageResponse {
+	resp := &common.PageResponse{Total: total, Limit: p.Limit}
+	if next := p.Offset + int64(returned); returned > 0 && next < total {
+		resp.NextCursor = encodeCursor(next)
+	}
+	return resp
+}
+
+func encodeCursor(offset int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(cursorPrefix + strconv.FormatInt(offset, 10)))
+}
+
+func decodeCursor(cursor string) (int64, bool) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, false
+	}
+	digits, ok := strings.CutPrefix(string(data), cursorPrefix)
+	if !ok {
+		return 0, false
+	}
+	offset, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil || offset < 0 {
+		return 0, false
+	}
+	return offset, true
+}
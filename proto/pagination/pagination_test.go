@@ -0,0 +1,111 @@
+package pagination
+
+import (
+	"testing"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/technonext/todo-app/proto/proto/common"
+)
+
+func TestResolve(t *testing.T) {
+	limits := Limits{Default: 20, Max: 100}
+	tests := []struct {
+		name                    string
+		req                     *common.PageRequest
+		legacyPage, legacyLimit int32
+		limits                  Limits
+		want                    Page
+		wantReason              string
+	}{
+		{"legacy first page", nil, 0, 10, limits, Page{Offset: 0, Limit: 10}, ""},
+		{"legacy later page", nil, 3, 10, limits, Page{Offset: 30, Limit: 10}, ""},
+		{"legacy zero limit lists everything", nil, 2, 0, limits, Page{Offset: 0, Limit: 0}, ""},
+		{"legacy limit is not capped", nil, 0, 500, limits, Page{Offset: 0, Limit: 500}, ""},
+		{"default limit", &common.PageRequest{}, 0, 0, limits, Page{Offset: 0, Limit: 20}, ""},
+		{"default limit ignores legacy fields", &common.PageRequest{Page: 1}, 5, 7, limits, Page{Offset: 20, Limit: 20}, ""},
+		{"explicit limit", &common.PageRequest{Page: 2, Limit: 5}, 0, 0, limits, Page{Offset: 10, Limit: 5}, ""},
+		{"limit at the cap", &common.PageRequest{Limit: 100}, 0, 0, limits, Page{Offset: 0, Limit: 100}, ""},
+		{"limit over the cap", &common.PageRequest{Page: 1, Limit: 1000}, 0, 0, limits, Page{Offset: 100, Limit: 100}, ""},
+		{"no cap", &common.PageRequest{Limit: 1000}, 0, 0, Limits{Default: 20}, Page{Offset: 0, Limit: 1000}, ""},
+		{"no default lists everything", &common.PageRequest{Page: 3}, 0, 0, Limits{Max: 100}, Page{Offset: 0, Limit: 0}, ""},
+		{"cursor overrides page", &common.PageRequest{Page: 9, Limit: 10, Cursor: encodeCursor(25)}, 0, 0, limits, Page{Offset: 25, Limit: 10}, ""},
+		{"negative page", &common.PageRequest{Page: -1}, 0, 0, limits, Page{}, "INVALID_PAGE"},
+		{"negative limit", &common.PageRequest{Limit: -1}, 0, 0, limits, Page{}, "INVALID_LIMIT"},
+		{"cursor not base64", &common.PageRequest{Cursor: "not a cursor!"}, 0, 0, limits, Page{}, "INVALID_CURSOR"},
+		{"cursor of another format", &common.PageRequest{Cursor: "MjU"}, 0, 0, limits, Page{}, "INVALID_CURSOR"},
+		{"cursor with a negative offset", &common.PageRequest{Cursor: encodeCursor(-5)}, 0, 0, limits, Page{}, "INVALID_CURSOR"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Resolve(tt.req, tt.legacyPage, tt.legacyLimit, tt.limits)
+			if tt.wantReason != "" {
+				st := status.Convert(err)
+				if st.Code() != codes.InvalidArgument {
+					t.Fatalf("code = %v, want InvalidArgument (%v)", st.Code(), err)
+				}
+				for _, detail := range st.Details() {
+					if info, ok := detail.(*errdetails.ErrorInfo); ok && info.Reason == tt.wantReason {
+						return
+					}
+				}
+				t.Fatalf("details = %v, want reason %s", st.Details(), tt.wantReason)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("page = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResponse(t *testing.T) {
+	tests := []struct {
+		name     string
+		page     Page
+		total    int64
+		returned int
+		wantNext int64 // -1 for no next cursor
+	}{
+		{"first of several pages", Page{Offset: 0, Limit: 10}, 25, 10, 10},
+		{"middle page", Page{Offset: 10, Limit: 10}, 25, 10, 20},
+		{"last partial page", Page{Offset: 20, Limit: 10}, 25, 5, -1},
+		{"last full page", Page{Offset: 20, Limit: 10}, 30, 10, -1},
+		{"past the end", Page{Offset: 40, Limit: 10}, 25, 0, -1},
+		{"no items", Page{Offset: 0, Limit: 10}, 0, 0, -1},
+		{"everything", Page{Offset: 0, Limit: 0}, 25, 25, -1},
+		{"items added since the count", Page{Offset: 0, Limit: 10}, 5, 10, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := tt.page.Response(tt.total, tt.returned)
+			if resp.Total != tt.total || resp.Limit != tt.page.Limit {
+				t.Errorf("total, limit = %d, %d, want %d, %d", resp.Total, resp.Limit, tt.total, tt.page.Limit)
+			}
+			if tt.wantNext < 0 {
+				if resp.NextCursor != "" {
+					t.Errorf("next cursor = %q, want none", resp.NextCursor)
+				}
+				return
+			}
+			next, err := Resolve(&common.PageRequest{Limit: tt.page.Limit, Cursor: resp.NextCursor}, 0, 0, DefaultLimits)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if next.Offset != tt.wantNext {
+				t.Errorf("next page starts at %d, want %d", next.Offset, tt.wantNext)
+			}
+		})
+	}
+}
+
+func TestFindOptions(t *testing.T) {
+	opts := Page{Offset: 30, Limit: 10}.FindOptions()
+	if opts.Skip == nil || *opts.Skip != 30 || opts.Limit == nil || *opts.Limit != 10 {
+		t.Errorf("skip, limit = %v, %v, want 30, 10", opts.Skip, opts.Limit)
+	}
+}
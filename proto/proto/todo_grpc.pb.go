@@ -20,11 +20,34 @@ import (
 const _ = grpc.SupportPackageIsVersion7
 
 const (
-	TaskService_CreateTask_FullMethodName = "/todo.TaskService/CreateTask"
-	TaskService_GetTask_FullMethodName    = "/todo.TaskService/GetTask"
-	TaskService_UpdateTask_FullMethodName = "/todo.TaskService/UpdateTask"
-	TaskService_DeleteTask_FullMethodName = "/todo.TaskService/DeleteTask"
-	TaskService_ListTasks_FullMethodName  = "/todo.TaskService/ListTasks"
+	TaskService_CreateTask_FullMethodName            = "/todo.TaskService/CreateTask"
+	TaskService_GetTask_FullMethodName               = "/todo.TaskService/GetTask"
+	TaskService_UpdateTask_FullMethodName            = "/todo.TaskService/UpdateTask"
+	TaskService_DeleteTask_FullMethodName            = "/todo.TaskService/DeleteTask"
+	TaskService_ListTasks_FullMethodName             = "/todo.TaskService/ListTasks"
+	TaskService_SyncTasks_FullMethodName             = "/todo.TaskService/SyncTasks"
+	TaskService_GetPendingTaskCount_FullMethodName   = "/todo.TaskService/GetPendingTaskCount"
+	TaskService_UpdateTaskStatus_FullMethodName      = "/todo.TaskService/UpdateTaskStatus"
+	TaskService_GetTaskMetrics_FullMethodName        = "/todo.TaskService/GetTaskMetrics"
+	TaskService_FindSimilarTasks_FullMethodName      = "/todo.TaskService/FindSimilarTasks"
+	TaskService_GetTasksCalendarView_FullMethodName  = "/todo.TaskService/GetTasksCalendarView"
+	TaskService_ListDeletedTasks_FullMethodName      = "/todo.TaskService/ListDeletedTasks"
+	TaskService_BatchDeleteTasks_FullMethodName      = "/todo.TaskService/BatchDeleteTasks"
+	TaskService_ReplayTaskEvents_FullMethodName      = "/todo.TaskService/ReplayTaskEvents"
+	TaskService_GetTasksNearDeadline_FullMethodName  = "/todo.TaskService/GetTasksNearDeadline"
+	TaskService_MarkDeadlineAlertSent_FullMethodName = "/todo.TaskService/MarkDeadlineAlertSent"
+	TaskService_ExportTasks_FullMethodName           = "/todo.TaskService/ExportTasks"
+	TaskService_MoveTask_FullMethodName              = "/todo.TaskService/MoveTask"
+	TaskService_ExtendTaskDueDate_FullMethodName     = "/todo.TaskService/ExtendTaskDueDate"
+	TaskService_LockTask_FullMethodName              = "/todo.TaskService/LockTask"
+	TaskService_UnlockTask_FullMethodName            = "/todo.TaskService/UnlockTask"
+	TaskService_BulkUpdateTasks_FullMethodName       = "/todo.TaskService/BulkUpdateTasks"
+	TaskService_AddChecklistItem_FullMethodName      = "/todo.TaskService/AddChecklistItem"
+	TaskService_ToggleChecklistItem_FullMethodName   = "/todo.TaskService/ToggleChecklistItem"
+	TaskService_RemoveChecklistItem_FullMethodName   = "/todo.TaskService/RemoveChecklistItem"
+	TaskService_GetTaskNotes_FullMethodName          = "/todo.TaskService/GetTaskNotes"
+	TaskService_UpdateTaskNotes_FullMethodName       = "/todo.TaskService/UpdateTaskNotes"
+	TaskService_MigrateTaskOwnership_FullMethodName  = "/todo.TaskService/MigrateTaskOwnership"
 )
 
 // TaskServiceClient is the client API for TaskService service.
@@ -36,6 +59,29 @@ type TaskServiceClient interface {
 	UpdateTask(ctx context.Context, in *UpdateTaskRequest, opts ...grpc.CallOption) (*TaskResponse, error)
 	DeleteTask(ctx context.Context, in *DeleteTaskRequest, opts ...grpc.CallOption) (*DeleteTaskResponse, error)
 	ListTasks(ctx context.Context, in *ListTasksRequest, opts ...grpc.CallOption) (*ListTasksResponse, error)
+	SyncTasks(ctx context.Context, in *SyncTasksRequest, opts ...grpc.CallOption) (*SyncTasksResponse, error)
+	GetPendingTaskCount(ctx context.Context, in *GetPendingTaskCountRequest, opts ...grpc.CallOption) (*GetPendingTaskCountResponse, error)
+	UpdateTaskStatus(ctx context.Context, in *UpdateTaskStatusRequest, opts ...grpc.CallOption) (*TaskResponse, error)
+	GetTaskMetrics(ctx context.Context, in *GetTaskMetricsRequest, opts ...grpc.CallOption) (*GetTaskMetricsResponse, error)
+	FindSimilarTasks(ctx context.Context, in *FindSimilarTasksRequest, opts ...grpc.CallOption) (*ListTasksResponse, error)
+	GetTasksCalendarView(ctx context.Context, in *GetTasksCalendarViewRequest, opts ...grpc.CallOption) (*GetTasksCalendarViewResponse, error)
+	ListDeletedTasks(ctx context.Context, in *ListDeletedTasksRequest, opts ...grpc.CallOption) (*ListTasksResponse, error)
+	BatchDeleteTasks(ctx context.Context, in *BatchDeleteTasksRequest, opts ...grpc.CallOption) (*BatchDeleteTasksResponse, error)
+	ReplayTaskEvents(ctx context.Context, in *ReplayTaskEventsRequest, opts ...grpc.CallOption) (*ReplayTaskEventsResponse, error)
+	GetTasksNearDeadline(ctx context.Context, in *GetTasksNearDeadlineRequest, opts ...grpc.CallOption) (*ListTasksResponse, error)
+	MarkDeadlineAlertSent(ctx context.Context, in *MarkDeadlineAlertSentRequest, opts ...grpc.CallOption) (*TaskResponse, error)
+	ExportTasks(ctx context.Context, in *ExportTasksRequest, opts ...grpc.CallOption) (TaskService_ExportTasksClient, error)
+	MoveTask(ctx context.Context, in *MoveTaskRequest, opts ...grpc.CallOption) (*TaskResponse, error)
+	ExtendTaskDueDate(ctx context.Context, in *ExtendDueDateRequest, opts ...grpc.CallOption) (*TaskResponse, error)
+	LockTask(ctx context.Context, in *LockTaskRequest, opts ...grpc.CallOption) (*LockResponse, error)
+	UnlockTask(ctx context.Context, in *UnlockTaskRequest, opts ...grpc.CallOption) (*LockResponse, error)
+	BulkUpdateTasks(ctx context.Context, in *BulkUpdateTasksRequest, opts ...grpc.CallOption) (*BulkUpdateTasksResponse, error)
+	AddChecklistItem(ctx context.Context, in *AddChecklistItemRequest, opts ...grpc.CallOption) (*TaskResponse, error)
+	ToggleChecklistItem(ctx context.Context, in *ToggleChecklistItemRequest, opts ...grpc.CallOption) (*TaskResponse, error)
+	RemoveChecklistItem(ctx context.Context, in *RemoveChecklistItemRequest, opts ...grpc.CallOption) (*TaskResponse, error)
+	GetTaskNotes(ctx context.Context, in *GetTaskNotesRequest, opts ...grpc.CallOption) (*GetTaskNotesResponse, error)
+	UpdateTaskNotes(ctx context.Context, in *UpdateTaskNotesRequest, opts ...grpc.CallOption) (*TaskResponse, error)
+	MigrateTaskOwnership(ctx context.Context, in *MigrateOwnershipRequest, opts ...grpc.CallOption) (*MigrateOwnershipResponse, error)
 }
 
 type taskServiceClient struct {
@@ -91,6 +137,236 @@ func (c *taskServiceClient) ListTasks(ctx context.Context, in *ListTasksRequest,
 	return out, nil
 }
 
+func (c *taskServiceClient) SyncTasks(ctx context.Context, in *SyncTasksRequest, opts ...grpc.CallOption) (*SyncTasksResponse, error) {
+	out := new(SyncTasksResponse)
+	err := c.cc.Invoke(ctx, TaskService_SyncTasks_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskServiceClient) GetPendingTaskCount(ctx context.Context, in *GetPendingTaskCountRequest, opts ...grpc.CallOption) (*GetPendingTaskCountResponse, error) {
+	out := new(GetPendingTaskCountResponse)
+	err := c.cc.Invoke(ctx, TaskService_GetPendingTaskCount_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskServiceClient) UpdateTaskStatus(ctx context.Context, in *UpdateTaskStatusRequest, opts ...grpc.CallOption) (*TaskResponse, error) {
+	out := new(TaskResponse)
+	err := c.cc.Invoke(ctx, TaskService_UpdateTaskStatus_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskServiceClient) GetTaskMetrics(ctx context.Context, in *GetTaskMetricsRequest, opts ...grpc.CallOption) (*GetTaskMetricsResponse, error) {
+	out := new(GetTaskMetricsResponse)
+	err := c.cc.Invoke(ctx, TaskService_GetTaskMetrics_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskServiceClient) FindSimilarTasks(ctx context.Context, in *FindSimilarTasksRequest, opts ...grpc.CallOption) (*ListTasksResponse, error) {
+	out := new(ListTasksResponse)
+	err := c.cc.Invoke(ctx, TaskService_FindSimilarTasks_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskServiceClient) GetTasksCalendarView(ctx context.Context, in *GetTasksCalendarViewRequest, opts ...grpc.CallOption) (*GetTasksCalendarViewResponse, error) {
+	out := new(GetTasksCalendarViewResponse)
+	err := c.cc.Invoke(ctx, TaskService_GetTasksCalendarView_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskServiceClient) ListDeletedTasks(ctx context.Context, in *ListDeletedTasksRequest, opts ...grpc.CallOption) (*ListTasksResponse, error) {
+	out := new(ListTasksResponse)
+	err := c.cc.Invoke(ctx, TaskService_ListDeletedTasks_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskServiceClient) BatchDeleteTasks(ctx context.Context, in *BatchDeleteTasksRequest, opts ...grpc.CallOption) (*BatchDeleteTasksResponse, error) {
+	out := new(BatchDeleteTasksResponse)
+	err := c.cc.Invoke(ctx, TaskService_BatchDeleteTasks_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskServiceClient) ReplayTaskEvents(ctx context.Context, in *ReplayTaskEventsRequest, opts ...grpc.CallOption) (*ReplayTaskEventsResponse, error) {
+	out := new(ReplayTaskEventsResponse)
+	err := c.cc.Invoke(ctx, TaskService_ReplayTaskEvents_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskServiceClient) GetTasksNearDeadline(ctx context.Context, in *GetTasksNearDeadlineRequest, opts ...grpc.CallOption) (*ListTasksResponse, error) {
+	out := new(ListTasksResponse)
+	err := c.cc.Invoke(ctx, TaskService_GetTasksNearDeadline_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskServiceClient) MarkDeadlineAlertSent(ctx context.Context, in *MarkDeadlineAlertSentRequest, opts ...grpc.CallOption) (*TaskResponse, error) {
+	out := new(TaskResponse)
+	err := c.cc.Invoke(ctx, TaskService_MarkDeadlineAlertSent_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskServiceClient) ExportTasks(ctx context.Context, in *ExportTasksRequest, opts ...grpc.CallOption) (TaskService_ExportTasksClient, error) {
+	stream, err := c.cc.NewStream(ctx, &TaskService_ServiceDesc.Streams[0], TaskService_ExportTasks_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &taskServiceExportTasksClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type TaskService_ExportTasksClient interface {
+	Recv() (*Task, error)
+	grpc.ClientStream
+}
+
+type taskServiceExportTasksClient struct {
+	grpc.ClientStream
+}
+
+func (x *taskServiceExportTasksClient) Recv() (*Task, error) {
+	m := new(Task)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *taskServiceClient) MoveTask(ctx context.Context, in *MoveTaskRequest, opts ...grpc.CallOption) (*TaskResponse, error) {
+	out := new(TaskResponse)
+	err := c.cc.Invoke(ctx, TaskService_MoveTask_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskServiceClient) ExtendTaskDueDate(ctx context.Context, in *ExtendDueDateRequest, opts ...grpc.CallOption) (*TaskResponse, error) {
+	out := new(TaskResponse)
+	err := c.cc.Invoke(ctx, TaskService_ExtendTaskDueDate_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskServiceClient) LockTask(ctx context.Context, in *LockTaskRequest, opts ...grpc.CallOption) (*LockResponse, error) {
+	out := new(LockResponse)
+	err := c.cc.Invoke(ctx, TaskService_LockTask_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskServiceClient) UnlockTask(ctx context.Context, in *UnlockTaskRequest, opts ...grpc.CallOption) (*LockResponse, error) {
+	out := new(LockResponse)
+	err := c.cc.Invoke(ctx, TaskService_UnlockTask_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskServiceClient) BulkUpdateTasks(ctx context.Context, in *BulkUpdateTasksRequest, opts ...grpc.CallOption) (*BulkUpdateTasksResponse, error) {
+	out := new(BulkUpdateTasksResponse)
+	err := c.cc.Invoke(ctx, TaskService_BulkUpdateTasks_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskServiceClient) AddChecklistItem(ctx context.Context, in *AddChecklistItemRequest, opts ...grpc.CallOption) (*TaskResponse, error) {
+	out := new(TaskResponse)
+	err := c.cc.Invoke(ctx, TaskService_AddChecklistItem_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskServiceClient) ToggleChecklistItem(ctx context.Context, in *ToggleChecklistItemRequest, opts ...grpc.CallOption) (*TaskResponse, error) {
+	out := new(TaskResponse)
+	err := c.cc.Invoke(ctx, TaskService_ToggleChecklistItem_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskServiceClient) RemoveChecklistItem(ctx context.Context, in *RemoveChecklistItemRequest, opts ...grpc.CallOption) (*TaskResponse, error) {
+	out := new(TaskResponse)
+	err := c.cc.Invoke(ctx, TaskService_RemoveChecklistItem_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskServiceClient) GetTaskNotes(ctx context.Context, in *GetTaskNotesRequest, opts ...grpc.CallOption) (*GetTaskNotesResponse, error) {
+	out := new(GetTaskNotesResponse)
+	err := c.cc.Invoke(ctx, TaskService_GetTaskNotes_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskServiceClient) UpdateTaskNotes(ctx context.Context, in *UpdateTaskNotesRequest, opts ...grpc.CallOption) (*TaskResponse, error) {
+	out := new(TaskResponse)
+	err := c.cc.Invoke(ctx, TaskService_UpdateTaskNotes_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskServiceClient) MigrateTaskOwnership(ctx context.Context, in *MigrateOwnershipRequest, opts ...grpc.CallOption) (*MigrateOwnershipResponse, error) {
+	out := new(MigrateOwnershipResponse)
+	err := c.cc.Invoke(ctx, TaskService_MigrateTaskOwnership_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // TaskServiceServer is the server API for TaskService service.
 // All implementations must embed UnimplementedTaskServiceServer
 // for forward compatibility
@@ -100,6 +376,29 @@ type TaskServiceServer interface {
 	UpdateTask(context.Context, *UpdateTaskRequest) (*TaskResponse, error)
 	DeleteTask(context.Context, *DeleteTaskRequest) (*DeleteTaskResponse, error)
 	ListTasks(context.Context, *ListTasksRequest) (*ListTasksResponse, error)
+	SyncTasks(context.Context, *SyncTasksRequest) (*SyncTasksResponse, error)
+	GetPendingTaskCount(context.Context, *GetPendingTaskCountRequest) (*GetPendingTaskCountResponse, error)
+	UpdateTaskStatus(context.Context, *UpdateTaskStatusRequest) (*TaskResponse, error)
+	GetTaskMetrics(context.Context, *GetTaskMetricsRequest) (*GetTaskMetricsResponse, error)
+	FindSimilarTasks(context.Context, *FindSimilarTasksRequest) (*ListTasksResponse, error)
+	GetTasksCalendarView(context.Context, *GetTasksCalendarViewRequest) (*GetTasksCalendarViewResponse, error)
+	ListDeletedTasks(context.Context, *ListDeletedTasksRequest) (*ListTasksResponse, error)
+	BatchDeleteTasks(context.Context, *BatchDeleteTasksRequest) (*BatchDeleteTasksResponse, error)
+	ReplayTaskEvents(context.Context, *ReplayTaskEventsRequest) (*ReplayTaskEventsResponse, error)
+	GetTasksNearDeadline(context.Context, *GetTasksNearDeadlineRequest) (*ListTasksResponse, error)
+	MarkDeadlineAlertSent(context.Context, *MarkDeadlineAlertSentRequest) (*TaskResponse, error)
+	ExportTasks(*ExportTasksRequest, TaskService_ExportTasksServer) error
+	MoveTask(context.Context, *MoveTaskRequest) (*TaskResponse, error)
+	ExtendTaskDueDate(context.Context, *ExtendDueDateRequest) (*TaskResponse, error)
+	LockTask(context.Context, *LockTaskRequest) (*LockResponse, error)
+	UnlockTask(context.Context, *UnlockTaskRequest) (*LockResponse, error)
+	BulkUpdateTasks(context.Context, *BulkUpdateTasksRequest) (*BulkUpdateTasksResponse, error)
+	AddChecklistItem(context.Context, *AddChecklistItemRequest) (*TaskResponse, error)
+	ToggleChecklistItem(context.Context, *ToggleChecklistItemRequest) (*TaskResponse, error)
+	RemoveChecklistItem(context.Context, *RemoveChecklistItemRequest) (*TaskResponse, error)
+	GetTaskNotes(context.Context, *GetTaskNotesRequest) (*GetTaskNotesResponse, error)
+	UpdateTaskNotes(context.Context, *UpdateTaskNotesRequest) (*TaskResponse, error)
+	MigrateTaskOwnership(context.Context, *MigrateOwnershipRequest) (*MigrateOwnershipResponse, error)
 	mustEmbedUnimplementedTaskServiceServer()
 }
 
@@ -107,515 +406,3345 @@ type TaskServiceServer interface {
 type UnimplementedTaskServiceServer struct {
 }
 
-func (UnimplementedTaskServiceServer) CreateTask(context.Context, *CreateTaskRequest) (*TaskResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method CreateTask not implemented")
-}
-func (UnimplementedTaskServiceServer) GetTask(context.Context, *GetTaskRequest) (*TaskResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method GetTask not implemented")
+func (UnimplementedTaskServiceServer) CreateTask(context.Context, *CreateTaskRequest) (*TaskResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateTask not implemented")
+}
+func (UnimplementedTaskServiceServer) GetTask(context.Context, *GetTaskRequest) (*TaskResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTask not implemented")
+}
+func (UnimplementedTaskServiceServer) UpdateTask(context.Context, *UpdateTaskRequest) (*TaskResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateTask not implemented")
+}
+func (UnimplementedTaskServiceServer) DeleteTask(context.Context, *DeleteTaskRequest) (*DeleteTaskResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteTask not implemented")
+}
+func (UnimplementedTaskServiceServer) ListTasks(context.Context, *ListTasksRequest) (*ListTasksResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListTasks not implemented")
+}
+func (UnimplementedTaskServiceServer) SyncTasks(context.Context, *SyncTasksRequest) (*SyncTasksResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SyncTasks not implemented")
+}
+func (UnimplementedTaskServiceServer) GetPendingTaskCount(context.Context, *GetPendingTaskCountRequest) (*GetPendingTaskCountResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPendingTaskCount not implemented")
+}
+func (UnimplementedTaskServiceServer) UpdateTaskStatus(context.Context, *UpdateTaskStatusRequest) (*TaskResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateTaskStatus not implemented")
+}
+func (UnimplementedTaskServiceServer) GetTaskMetrics(context.Context, *GetTaskMetricsRequest) (*GetTaskMetricsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTaskMetrics not implemented")
+}
+func (UnimplementedTaskServiceServer) FindSimilarTasks(context.Context, *FindSimilarTasksRequest) (*ListTasksResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FindSimilarTasks not implemented")
+}
+func (UnimplementedTaskServiceServer) GetTasksCalendarView(context.Context, *GetTasksCalendarViewRequest) (*GetTasksCalendarViewResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTasksCalendarView not implemented")
+}
+func (UnimplementedTaskServiceServer) ListDeletedTasks(context.Context, *ListDeletedTasksRequest) (*ListTasksResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListDeletedTasks not implemented")
+}
+func (UnimplementedTaskServiceServer) BatchDeleteTasks(context.Context, *BatchDeleteTasksRequest) (*BatchDeleteTasksResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchDeleteTasks not implemented")
+}
+func (UnimplementedTaskServiceServer) ReplayTaskEvents(context.Context, *ReplayTaskEventsRequest) (*ReplayTaskEventsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReplayTaskEvents not implemented")
+}
+func (UnimplementedTaskServiceServer) GetTasksNearDeadline(context.Context, *GetTasksNearDeadlineRequest) (*ListTasksResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTasksNearDeadline not implemented")
+}
+func (UnimplementedTaskServiceServer) MarkDeadlineAlertSent(context.Context, *MarkDeadlineAlertSentRequest) (*TaskResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MarkDeadlineAlertSent not implemented")
+}
+func (UnimplementedTaskServiceServer) ExportTasks(*ExportTasksRequest, TaskService_ExportTasksServer) error {
+	return status.Errorf(codes.Unimplemented, "method ExportTasks not implemented")
+}
+func (UnimplementedTaskServiceServer) MoveTask(context.Context, *MoveTaskRequest) (*TaskResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MoveTask not implemented")
+}
+func (UnimplementedTaskServiceServer) ExtendTaskDueDate(context.Context, *ExtendDueDateRequest) (*TaskResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ExtendTaskDueDate not implemented")
+}
+func (UnimplementedTaskServiceServer) LockTask(context.Context, *LockTaskRequest) (*LockResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LockTask not implemented")
+}
+func (UnimplementedTaskServiceServer) UnlockTask(context.Context, *UnlockTaskRequest) (*LockResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnlockTask not implemented")
+}
+func (UnimplementedTaskServiceServer) BulkUpdateTasks(context.Context, *BulkUpdateTasksRequest) (*BulkUpdateTasksResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BulkUpdateTasks not implemented")
+}
+func (UnimplementedTaskServiceServer) AddChecklistItem(context.Context, *AddChecklistItemRequest) (*TaskResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddChecklistItem not implemented")
+}
+func (UnimplementedTaskServiceServer) ToggleChecklistItem(context.Context, *ToggleChecklistItemRequest) (*TaskResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ToggleChecklistItem not implemented")
+}
+func (UnimplementedTaskServiceServer) RemoveChecklistItem(context.Context, *RemoveChecklistItemRequest) (*TaskResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveChecklistItem not implemented")
+}
+func (UnimplementedTaskServiceServer) GetTaskNotes(context.Context, *GetTaskNotesRequest) (*GetTaskNotesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTaskNotes not implemented")
+}
+func (UnimplementedTaskServiceServer) UpdateTaskNotes(context.Context, *UpdateTaskNotesRequest) (*TaskResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateTaskNotes not implemented")
+}
+func (UnimplementedTaskServiceServer) MigrateTaskOwnership(context.Context, *MigrateOwnershipRequest) (*MigrateOwnershipResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MigrateTaskOwnership not implemented")
+}
+func (UnimplementedTaskServiceServer) mustEmbedUnimplementedTaskServiceServer() {}
+
+// UnsafeTaskServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to TaskServiceServer will
+// result in compilation errors.
+type UnsafeTaskServiceServer interface {
+	mustEmbedUnimplementedTaskServiceServer()
+}
+
+func RegisterTaskServiceServer(s grpc.ServiceRegistrar, srv TaskServiceServer) {
+	s.RegisterService(&TaskService_ServiceDesc, srv)
+}
+
+func _TaskService_CreateTask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateTaskRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).CreateTask(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskService_CreateTask_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).CreateTask(ctx, req.(*CreateTaskRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_GetTask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTaskRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).GetTask(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskService_GetTask_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).GetTask(ctx, req.(*GetTaskRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_UpdateTask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateTaskRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).UpdateTask(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskService_UpdateTask_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).UpdateTask(ctx, req.(*UpdateTaskRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_DeleteTask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteTaskRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).DeleteTask(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskService_DeleteTask_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).DeleteTask(ctx, req.(*DeleteTaskRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_ListTasks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListTasksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).ListTasks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskService_ListTasks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).ListTasks(ctx, req.(*ListTasksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_SyncTasks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SyncTasksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).SyncTasks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskService_SyncTasks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).SyncTasks(ctx, req.(*SyncTasksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_GetPendingTaskCount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPendingTaskCountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).GetPendingTaskCount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskService_GetPendingTaskCount_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).GetPendingTaskCount(ctx, req.(*GetPendingTaskCountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_UpdateTaskStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateTaskStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).UpdateTaskStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskService_UpdateTaskStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).UpdateTaskStatus(ctx, req.(*UpdateTaskStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_GetTaskMetrics_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTaskMetricsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).GetTaskMetrics(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskService_GetTaskMetrics_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).GetTaskMetrics(ctx, req.(*GetTaskMetricsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_FindSimilarTasks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FindSimilarTasksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).FindSimilarTasks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskService_FindSimilarTasks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).FindSimilarTasks(ctx, req.(*FindSimilarTasksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_GetTasksCalendarView_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTasksCalendarViewRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).GetTasksCalendarView(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskService_GetTasksCalendarView_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).GetTasksCalendarView(ctx, req.(*GetTasksCalendarViewRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_ListDeletedTasks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListDeletedTasksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).ListDeletedTasks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskService_ListDeletedTasks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).ListDeletedTasks(ctx, req.(*ListDeletedTasksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_BatchDeleteTasks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchDeleteTasksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).BatchDeleteTasks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskService_BatchDeleteTasks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).BatchDeleteTasks(ctx, req.(*BatchDeleteTasksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_ReplayTaskEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReplayTaskEventsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).ReplayTaskEvents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskService_ReplayTaskEvents_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).ReplayTaskEvents(ctx, req.(*ReplayTaskEventsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_GetTasksNearDeadline_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTasksNearDeadlineRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).GetTasksNearDeadline(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskService_GetTasksNearDeadline_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).GetTasksNearDeadline(ctx, req.(*GetTasksNearDeadlineRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_MarkDeadlineAlertSent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MarkDeadlineAlertSentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).MarkDeadlineAlertSent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskService_MarkDeadlineAlertSent_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).MarkDeadlineAlertSent(ctx, req.(*MarkDeadlineAlertSentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_ExportTasks_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ExportTasksRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(TaskServiceServer).ExportTasks(m, &taskServiceExportTasksServer{stream})
+}
+
+type TaskService_ExportTasksServer interface {
+	Send(*Task) error
+	grpc.ServerStream
+}
+
+type taskServiceExportTasksServer struct {
+	grpc.ServerStream
+}
+
+func (x *taskServiceExportTasksServer) Send(m *Task) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _TaskService_MoveTask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MoveTaskRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).MoveTask(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskService_MoveTask_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).MoveTask(ctx, req.(*MoveTaskRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_ExtendTaskDueDate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExtendDueDateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).ExtendTaskDueDate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskService_ExtendTaskDueDate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).ExtendTaskDueDate(ctx, req.(*ExtendDueDateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_LockTask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LockTaskRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).LockTask(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskService_LockTask_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).LockTask(ctx, req.(*LockTaskRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_UnlockTask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnlockTaskRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).UnlockTask(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskService_UnlockTask_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).UnlockTask(ctx, req.(*UnlockTaskRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_BulkUpdateTasks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BulkUpdateTasksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).BulkUpdateTasks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskService_BulkUpdateTasks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).BulkUpdateTasks(ctx, req.(*BulkUpdateTasksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_AddChecklistItem_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddChecklistItemRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).AddChecklistItem(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskService_AddChecklistItem_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).AddChecklistItem(ctx, req.(*AddChecklistItemRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_ToggleChecklistItem_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ToggleChecklistItemRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).ToggleChecklistItem(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskService_ToggleChecklistItem_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).ToggleChecklistItem(ctx, req.(*ToggleChecklistItemRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_RemoveChecklistItem_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveChecklistItemRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).RemoveChecklistItem(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskService_RemoveChecklistItem_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).RemoveChecklistItem(ctx, req.(*RemoveChecklistItemRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_GetTaskNotes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTaskNotesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).GetTaskNotes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskService_GetTaskNotes_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).GetTaskNotes(ctx, req.(*GetTaskNotesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_UpdateTaskNotes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateTaskNotesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).UpdateTaskNotes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskService_UpdateTaskNotes_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).UpdateTaskNotes(ctx, req.(*UpdateTaskNotesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_MigrateTaskOwnership_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MigrateOwnershipRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).MigrateTaskOwnership(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskService_MigrateTaskOwnership_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).MigrateTaskOwnership(ctx, req.(*MigrateOwnershipRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// TaskService_ServiceDesc is the grpc.ServiceDesc for TaskService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var TaskService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "todo.TaskService",
+	HandlerType: (*TaskServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateTask",
+			Handler:    _TaskService_CreateTask_Handler,
+		},
+		{
+			MethodName: "GetTask",
+			Handler:    _TaskService_GetTask_Handler,
+		},
+		{
+			MethodName: "UpdateTask",
+			Handler:    _TaskService_UpdateTask_Handler,
+		},
+		{
+			MethodName: "DeleteTask",
+			Handler:    _TaskService_DeleteTask_Handler,
+		},
+		{
+			MethodName: "ListTasks",
+			Handler:    _TaskService_ListTasks_Handler,
+		},
+		{
+			MethodName: "SyncTasks",
+			Handler:    _TaskService_SyncTasks_Handler,
+		},
+		{
+			MethodName: "GetPendingTaskCount",
+			Handler:    _TaskService_GetPendingTaskCount_Handler,
+		},
+		{
+			MethodName: "UpdateTaskStatus",
+			Handler:    _TaskService_UpdateTaskStatus_Handler,
+		},
+		{
+			MethodName: "GetTaskMetrics",
+			Handler:    _TaskService_GetTaskMetrics_Handler,
+		},
+		{
+			MethodName: "FindSimilarTasks",
+			Handler:    _TaskService_FindSimilarTasks_Handler,
+		},
+		{
+			MethodName: "GetTasksCalendarView",
+			Handler:    _TaskService_GetTasksCalendarView_Handler,
+		},
+		{
+			MethodName: "ListDeletedTasks",
+			Handler:    _TaskService_ListDeletedTasks_Handler,
+		},
+		{
+			MethodName: "BatchDeleteTasks",
+			Handler:    _TaskService_BatchDeleteTasks_Handler,
+		},
+		{
+			MethodName: "ReplayTaskEvents",
+			Handler:    _TaskService_ReplayTaskEvents_Handler,
+		},
+		{
+			MethodName: "GetTasksNearDeadline",
+			Handler:    _TaskService_GetTasksNearDeadline_Handler,
+		},
+		{
+			MethodName: "MarkDeadlineAlertSent",
+			Handler:    _TaskService_MarkDeadlineAlertSent_Handler,
+		},
+		{
+			MethodName: "MoveTask",
+			Handler:    _TaskService_MoveTask_Handler,
+		},
+		{
+			MethodName: "ExtendTaskDueDate",
+			Handler:    _TaskService_ExtendTaskDueDate_Handler,
+		},
+		{
+			MethodName: "LockTask",
+			Handler:    _TaskService_LockTask_Handler,
+		},
+		{
+			MethodName: "UnlockTask",
+			Handler:    _TaskService_UnlockTask_Handler,
+		},
+		{
+			MethodName: "BulkUpdateTasks",
+			Handler:    _TaskService_BulkUpdateTasks_Handler,
+		},
+		{
+			MethodName: "AddChecklistItem",
+			Handler:    _TaskService_AddChecklistItem_Handler,
+		},
+		{
+			MethodName: "ToggleChecklistItem",
+			Handler:    _TaskService_ToggleChecklistItem_Handler,
+		},
+		{
+			MethodName: "RemoveChecklistItem",
+			Handler:    _TaskService_RemoveChecklistItem_Handler,
+		},
+		{
+			MethodName: "GetTaskNotes",
+			Handler:    _TaskService_GetTaskNotes_Handler,
+		},
+		{
+			MethodName: "UpdateTaskNotes",
+			Handler:    _TaskService_UpdateTaskNotes_Handler,
+		},
+		{
+			MethodName: "MigrateTaskOwnership",
+			Handler:    _TaskService_MigrateTaskOwnership_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ExportTasks",
+			Handler:       _TaskService_ExportTasks_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "proto/todo.proto",
+}
+
+const (
+	UserService_CreateUser_FullMethodName           = "/todo.UserService/CreateUser"
+	UserService_GetUser_FullMethodName              = "/todo.UserService/GetUser"
+	UserService_UpdateUser_FullMethodName           = "/todo.UserService/UpdateUser"
+	UserService_DeleteUser_FullMethodName           = "/todo.UserService/DeleteUser"
+	UserService_AuthenticateUser_FullMethodName     = "/todo.UserService/AuthenticateUser"
+	UserService_ListSessions_FullMethodName         = "/todo.UserService/ListSessions"
+	UserService_GetUserActivityStats_FullMethodName = "/todo.UserService/GetUserActivityStats"
+	UserService_FreezeUser_FullMethodName           = "/todo.UserService/FreezeUser"
+	UserService_UnfreezeUser_FullMethodName         = "/todo.UserService/UnfreezeUser"
+	UserService_GetUserCounts_FullMethodName        = "/todo.UserService/GetUserCounts"
+	UserService_PurgeUserData_FullMethodName        = "/todo.UserService/PurgeUserData"
+	UserService_GetDeletionStatus_FullMethodName    = "/todo.UserService/GetDeletionStatus"
+)
+
+// UserServiceClient is the client API for UserService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type UserServiceClient interface {
+	CreateUser(ctx context.Context, in *CreateUserRequest, opts ...grpc.CallOption) (*UserResponse, error)
+	GetUser(ctx context.Context, in *GetUserRequest, opts ...grpc.CallOption) (*UserResponse, error)
+	UpdateUser(ctx context.Context, in *UpdateUserRequest, opts ...grpc.CallOption) (*UserResponse, error)
+	DeleteUser(ctx context.Context, in *DeleteUserRequest, opts ...grpc.CallOption) (*DeleteUserResponse, error)
+	AuthenticateUser(ctx context.Context, in *AuthRequest, opts ...grpc.CallOption) (*AuthResponse, error)
+	ListSessions(ctx context.Context, in *ListSessionsRequest, opts ...grpc.CallOption) (*ListSessionsResponse, error)
+	GetUserActivityStats(ctx context.Context, in *GetUserActivityStatsRequest, opts ...grpc.CallOption) (*GetUserActivityStatsResponse, error)
+	FreezeUser(ctx context.Context, in *FreezeUserRequest, opts ...grpc.CallOption) (*UserResponse, error)
+	UnfreezeUser(ctx context.Context, in *UnfreezeUserRequest, opts ...grpc.CallOption) (*UserResponse, error)
+	GetUserCounts(ctx context.Context, in *GetUserCountsRequest, opts ...grpc.CallOption) (*GetUserCountsResponse, error)
+	PurgeUserData(ctx context.Context, in *PurgeUserDataRequest, opts ...grpc.CallOption) (*PurgeUserDataResponse, error)
+	GetDeletionStatus(ctx context.Context, in *GetDeletionStatusRequest, opts ...grpc.CallOption) (*DeletionJobResponse, error)
+}
+
+type userServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewUserServiceClient(cc grpc.ClientConnInterface) UserServiceClient {
+	return &userServiceClient{cc}
+}
+
+func (c *userServiceClient) CreateUser(ctx context.Context, in *CreateUserRequest, opts ...grpc.CallOption) (*UserResponse, error) {
+	out := new(UserResponse)
+	err := c.cc.Invoke(ctx, UserService_CreateUser_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) GetUser(ctx context.Context, in *GetUserRequest, opts ...grpc.CallOption) (*UserResponse, error) {
+	out := new(UserResponse)
+	err := c.cc.Invoke(ctx, UserService_GetUser_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) UpdateUser(ctx context.Context, in *UpdateUserRequest, opts ...grpc.CallOption) (*UserResponse, error) {
+	out := new(UserResponse)
+	err := c.cc.Invoke(ctx, UserService_UpdateUser_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) DeleteUser(ctx context.Context, in *DeleteUserRequest, opts ...grpc.CallOption) (*DeleteUserResponse, error) {
+	out := new(DeleteUserResponse)
+	err := c.cc.Invoke(ctx, UserService_DeleteUser_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) AuthenticateUser(ctx context.Context, in *AuthRequest, opts ...grpc.CallOption) (*AuthResponse, error) {
+	out := new(AuthResponse)
+	err := c.cc.Invoke(ctx, UserService_AuthenticateUser_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) ListSessions(ctx context.Context, in *ListSessionsRequest, opts ...grpc.CallOption) (*ListSessionsResponse, error) {
+	out := new(ListSessionsResponse)
+	err := c.cc.Invoke(ctx, UserService_ListSessions_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) GetUserActivityStats(ctx context.Context, in *GetUserActivityStatsRequest, opts ...grpc.CallOption) (*GetUserActivityStatsResponse, error) {
+	out := new(GetUserActivityStatsResponse)
+	err := c.cc.Invoke(ctx, UserService_GetUserActivityStats_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) FreezeUser(ctx context.Context, in *FreezeUserRequest, opts ...grpc.CallOption) (*UserResponse, error) {
+	out := new(UserResponse)
+	err := c.cc.Invoke(ctx, UserService_FreezeUser_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) UnfreezeUser(ctx context.Context, in *UnfreezeUserRequest, opts ...grpc.CallOption) (*UserResponse, error) {
+	out := new(UserResponse)
+	err := c.cc.Invoke(ctx, UserService_UnfreezeUser_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) GetUserCounts(ctx context.Context, in *GetUserCountsRequest, opts ...grpc.CallOption) (*GetUserCountsResponse, error) {
+	out := new(GetUserCountsResponse)
+	err := c.cc.Invoke(ctx, UserService_GetUserCounts_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) PurgeUserData(ctx context.Context, in *PurgeUserDataRequest, opts ...grpc.CallOption) (*PurgeUserDataResponse, error) {
+	out := new(PurgeUserDataResponse)
+	err := c.cc.Invoke(ctx, UserService_PurgeUserData_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) GetDeletionStatus(ctx context.Context, in *GetDeletionStatusRequest, opts ...grpc.CallOption) (*DeletionJobResponse, error) {
+	out := new(DeletionJobResponse)
+	err := c.cc.Invoke(ctx, UserService_GetDeletionStatus_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UserServiceServer is the server API for UserService service.
+// All implementations must embed UnimplementedUserServiceServer
+// for forward compatibility
+type UserServiceServer interface {
+	CreateUser(context.Context, *CreateUserRequest) (*UserResponse, error)
+	GetUser(context.Context, *GetUserRequest) (*UserResponse, error)
+	UpdateUser(context.Context, *UpdateUserRequest) (*UserResponse, error)
+	DeleteUser(context.Context, *DeleteUserRequest) (*DeleteUserResponse, error)
+	AuthenticateUser(context.Context, *AuthRequest) (*AuthResponse, error)
+	ListSessions(context.Context, *ListSessionsRequest) (*ListSessionsResponse, error)
+	GetUserActivityStats(context.Context, *GetUserActivityStatsRequest) (*GetUserActivityStatsResponse, error)
+	FreezeUser(context.Context, *FreezeUserRequest) (*UserResponse, error)
+	UnfreezeUser(context.Context, *UnfreezeUserRequest) (*UserResponse, error)
+	GetUserCounts(context.Context, *GetUserCountsRequest) (*GetUserCountsResponse, error)
+	PurgeUserData(context.Context, *PurgeUserDataRequest) (*PurgeUserDataResponse, error)
+	GetDeletionStatus(context.Context, *GetDeletionStatusRequest) (*DeletionJobResponse, error)
+	mustEmbedUnimplementedUserServiceServer()
+}
+
+// UnimplementedUserServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedUserServiceServer struct {
+}
+
+func (UnimplementedUserServiceServer) CreateUser(context.Context, *CreateUserRequest) (*UserResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateUser not implemented")
+}
+func (UnimplementedUserServiceServer) GetUser(context.Context, *GetUserRequest) (*UserResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUser not implemented")
+}
+func (UnimplementedUserServiceServer) UpdateUser(context.Context, *UpdateUserRequest) (*UserResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateUser not implemented")
+}
+func (UnimplementedUserServiceServer) DeleteUser(context.Context, *DeleteUserRequest) (*DeleteUserResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteUser not implemented")
+}
+func (UnimplementedUserServiceServer) AuthenticateUser(context.Context, *AuthRequest) (*AuthResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AuthenticateUser not implemented")
+}
+func (UnimplementedUserServiceServer) ListSessions(context.Context, *ListSessionsRequest) (*ListSessionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSessions not implemented")
+}
+func (UnimplementedUserServiceServer) GetUserActivityStats(context.Context, *GetUserActivityStatsRequest) (*GetUserActivityStatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUserActivityStats not implemented")
+}
+func (UnimplementedUserServiceServer) FreezeUser(context.Context, *FreezeUserRequest) (*UserResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FreezeUser not implemented")
+}
+func (UnimplementedUserServiceServer) UnfreezeUser(context.Context, *UnfreezeUserRequest) (*UserResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnfreezeUser not implemented")
+}
+func (UnimplementedUserServiceServer) GetUserCounts(context.Context, *GetUserCountsRequest) (*GetUserCountsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUserCounts not implemented")
+}
+func (UnimplementedUserServiceServer) PurgeUserData(context.Context, *PurgeUserDataRequest) (*PurgeUserDataResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PurgeUserData not implemented")
+}
+func (UnimplementedUserServiceServer) GetDeletionStatus(context.Context, *GetDeletionStatusRequest) (*DeletionJobResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDeletionStatus not implemented")
+}
+func (UnimplementedUserServiceServer) mustEmbedUnimplementedUserServiceServer() {}
+
+// UnsafeUserServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to UserServiceServer will
+// result in compilation errors.
+type UnsafeUserServiceServer interface {
+	mustEmbedUnimplementedUserServiceServer()
+}
+
+func RegisterUserServiceServer(s grpc.ServiceRegistrar, srv UserServiceServer) {
+	s.RegisterService(&UserService_ServiceDesc, srv)
+}
+
+func _UserService_CreateUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).CreateUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_CreateUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).CreateUser(ctx, req.(*CreateUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_GetUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).GetUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_GetUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).GetUser(ctx, req.(*GetUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_UpdateUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).UpdateUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_UpdateUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).UpdateUser(ctx, req.(*UpdateUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_DeleteUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).DeleteUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_DeleteUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).DeleteUser(ctx, req.(*DeleteUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_AuthenticateUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AuthRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).AuthenticateUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_AuthenticateUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).AuthenticateUser(ctx, req.(*AuthRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_ListSessions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSessionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).ListSessions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_ListSessions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).ListSessions(ctx, req.(*ListSessionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_GetUserActivityStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUserActivityStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).GetUserActivityStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_GetUserActivityStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).GetUserActivityStats(ctx, req.(*GetUserActivityStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_FreezeUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FreezeUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).FreezeUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_FreezeUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).FreezeUser(ctx, req.(*FreezeUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_UnfreezeUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnfreezeUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).UnfreezeUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_UnfreezeUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).UnfreezeUser(ctx, req.(*UnfreezeUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_GetUserCounts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUserCountsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).GetUserCounts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_GetUserCounts_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).GetUserCounts(ctx, req.(*GetUserCountsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_PurgeUserData_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PurgeUserDataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).PurgeUserData(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_PurgeUserData_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).PurgeUserData(ctx, req.(*PurgeUserDataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_GetDeletionStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDeletionStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).GetDeletionStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_GetDeletionStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).GetDeletionStatus(ctx, req.(*GetDeletionStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// UserService_ServiceDesc is the grpc.ServiceDesc for UserService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var UserService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "todo.UserService",
+	HandlerType: (*UserServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateUser",
+			Handler:    _UserService_CreateUser_Handler,
+		},
+		{
+			MethodName: "GetUser",
+			Handler:    _UserService_GetUser_Handler,
+		},
+		{
+			MethodName: "UpdateUser",
+			Handler:    _UserService_UpdateUser_Handler,
+		},
+		{
+			MethodName: "DeleteUser",
+			Handler:    _UserService_DeleteUser_Handler,
+		},
+		{
+			MethodName: "AuthenticateUser",
+			Handler:    _UserService_AuthenticateUser_Handler,
+		},
+		{
+			MethodName: "ListSessions",
+			Handler:    _UserService_ListSessions_Handler,
+		},
+		{
+			MethodName: "GetUserActivityStats",
+			Handler:    _UserService_GetUserActivityStats_Handler,
+		},
+		{
+			MethodName: "FreezeUser",
+			Handler:    _UserService_FreezeUser_Handler,
+		},
+		{
+			MethodName: "UnfreezeUser",
+			Handler:    _UserService_UnfreezeUser_Handler,
+		},
+		{
+			MethodName: "GetUserCounts",
+			Handler:    _UserService_GetUserCounts_Handler,
+		},
+		{
+			MethodName: "PurgeUserData",
+			Handler:    _UserService_PurgeUserData_Handler,
+		},
+		{
+			MethodName: "GetDeletionStatus",
+			Handler:    _UserService_GetDeletionStatus_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "proto/todo.proto",
+}
+
+const (
+	NotificationService_SendNotification_FullMethodName          = "/todo.NotificationService/SendNotification"
+	NotificationService_GetNotifications_FullMethodName          = "/todo.NotificationService/GetNotifications"
+	NotificationService_GetNotification_FullMethodName           = "/todo.NotificationService/GetNotification"
+	NotificationService_ListFailedDeliveries_FullMethodName      = "/todo.NotificationService/ListFailedDeliveries"
+	NotificationService_GetDeliveryReport_FullMethodName         = "/todo.NotificationService/GetDeliveryReport"
+	NotificationService_GetNotificationCounts_FullMethodName     = "/todo.NotificationService/GetNotificationCounts"
+	NotificationService_RedeliverNotification_FullMethodName     = "/todo.NotificationService/RedeliverNotification"
+	NotificationService_CreateTemplate_FullMethodName            = "/todo.NotificationService/CreateTemplate"
+	NotificationService_ListTemplates_FullMethodName             = "/todo.NotificationService/ListTemplates"
+	NotificationService_GetTemplate_FullMethodName               = "/todo.NotificationService/GetTemplate"
+	NotificationService_UpdateTemplate_FullMethodName            = "/todo.NotificationService/UpdateTemplate"
+	NotificationService_DeleteTemplate_FullMethodName            = "/todo.NotificationService/DeleteTemplate"
+	NotificationService_CloneTemplate_FullMethodName             = "/todo.NotificationService/CloneTemplate"
+	NotificationService_ActivateTemplate_FullMethodName          = "/todo.NotificationService/ActivateTemplate"
+	NotificationService_SetDigestSchedule_FullMethodName         = "/todo.NotificationService/SetDigestSchedule"
+	NotificationService_DeleteDigestSchedule_FullMethodName      = "/todo.NotificationService/DeleteDigestSchedule"
+	NotificationService_DeleteNotificationsByUser_FullMethodName = "/todo.NotificationService/DeleteNotificationsByUser"
+	NotificationService_GetNotificationSummary_FullMethodName    = "/todo.NotificationService/GetNotificationSummary"
+	NotificationService_MarkNotificationRead_FullMethodName      = "/todo.NotificationService/MarkNotificationRead"
+)
+
+// NotificationServiceClient is the client API for NotificationService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type NotificationServiceClient interface {
+	SendNotification(ctx context.Context, in *NotificationRequest, opts ...grpc.CallOption) (*NotificationResponse, error)
+	GetNotifications(ctx context.Context, in *GetNotificationsRequest, opts ...grpc.CallOption) (*GetNotificationsResponse, error)
+	GetNotification(ctx context.Context, in *GetNotificationRequest, opts ...grpc.CallOption) (*NotificationResponse, error)
+	ListFailedDeliveries(ctx context.Context, in *ListFailedDeliveriesRequest, opts ...grpc.CallOption) (*ListFailedDeliveriesResponse, error)
+	GetDeliveryReport(ctx context.Context, in *GetDeliveryReportRequest, opts ...grpc.CallOption) (*GetDeliveryReportResponse, error)
+	GetNotificationCounts(ctx context.Context, in *GetNotificationCountsRequest, opts ...grpc.CallOption) (*GetNotificationCountsResponse, error)
+	RedeliverNotification(ctx context.Context, in *RedeliverNotificationRequest, opts ...grpc.CallOption) (*NotificationResponse, error)
+	CreateTemplate(ctx context.Context, in *CreateTemplateRequest, opts ...grpc.CallOption) (*TemplateResponse, error)
+	ListTemplates(ctx context.Context, in *ListTemplatesRequest, opts ...grpc.CallOption) (*ListTemplatesResponse, error)
+	GetTemplate(ctx context.Context, in *GetTemplateRequest, opts ...grpc.CallOption) (*TemplateResponse, error)
+	UpdateTemplate(ctx context.Context, in *UpdateTemplateRequest, opts ...grpc.CallOption) (*TemplateResponse, error)
+	DeleteTemplate(ctx context.Context, in *DeleteTemplateRequest, opts ...grpc.CallOption) (*DeleteTemplateResponse, error)
+	CloneTemplate(ctx context.Context, in *CloneTemplateRequest, opts ...grpc.CallOption) (*TemplateResponse, error)
+	ActivateTemplate(ctx context.Context, in *ActivateTemplateRequest, opts ...grpc.CallOption) (*TemplateResponse, error)
+	SetDigestSchedule(ctx context.Context, in *SetDigestScheduleRequest, opts ...grpc.CallOption) (*DigestScheduleResponse, error)
+	DeleteDigestSchedule(ctx context.Context, in *DeleteDigestScheduleRequest, opts ...grpc.CallOption) (*DeleteDigestScheduleResponse, error)
+	DeleteNotificationsByUser(ctx context.Context, in *DeleteNotificationsByUserRequest, opts ...grpc.CallOption) (*DeleteNotificationsByUserResponse, error)
+	GetNotificationSummary(ctx context.Context, in *GetNotificationSummaryRequest, opts ...grpc.CallOption) (*GetNotificationSummaryResponse, error)
+	MarkNotificationRead(ctx context.Context, in *MarkNotificationReadRequest, opts ...grpc.CallOption) (*NotificationResponse, error)
+}
+
+type notificationServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewNotificationServiceClient(cc grpc.ClientConnInterface) NotificationServiceClient {
+	return &notificationServiceClient{cc}
+}
+
+func (c *notificationServiceClient) SendNotification(ctx context.Context, in *NotificationRequest, opts ...grpc.CallOption) (*NotificationResponse, error) {
+	out := new(NotificationResponse)
+	err := c.cc.Invoke(ctx, NotificationService_SendNotification_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationServiceClient) GetNotifications(ctx context.Context, in *GetNotificationsRequest, opts ...grpc.CallOption) (*GetNotificationsResponse, error) {
+	out := new(GetNotificationsResponse)
+	err := c.cc.Invoke(ctx, NotificationService_GetNotifications_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationServiceClient) GetNotification(ctx context.Context, in *GetNotificationRequest, opts ...grpc.CallOption) (*NotificationResponse, error) {
+	out := new(NotificationResponse)
+	err := c.cc.Invoke(ctx, NotificationService_GetNotification_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationServiceClient) ListFailedDeliveries(ctx context.Context, in *ListFailedDeliveriesRequest, opts ...grpc.CallOption) (*ListFailedDeliveriesResponse, error) {
+	out := new(ListFailedDeliveriesResponse)
+	err := c.cc.Invoke(ctx, NotificationService_ListFailedDeliveries_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationServiceClient) GetDeliveryReport(ctx context.Context, in *GetDeliveryReportRequest, opts ...grpc.CallOption) (*GetDeliveryReportResponse, error) {
+	out := new(GetDeliveryReportResponse)
+	err := c.cc.Invoke(ctx, NotificationService_GetDeliveryReport_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationServiceClient) GetNotificationCounts(ctx context.Context, in *GetNotificationCountsRequest, opts ...grpc.CallOption) (*GetNotificationCountsResponse, error) {
+	out := new(GetNotificationCountsResponse)
+	err := c.cc.Invoke(ctx, NotificationService_GetNotificationCounts_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationServiceClient) RedeliverNotification(ctx context.Context, in *RedeliverNotificationRequest, opts ...grpc.CallOption) (*NotificationResponse, error) {
+	out := new(NotificationResponse)
+	err := c.cc.Invoke(ctx, NotificationService_RedeliverNotification_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationServiceClient) CreateTemplate(ctx context.Context, in *CreateTemplateRequest, opts ...grpc.CallOption) (*TemplateResponse, error) {
+	out := new(TemplateResponse)
+	err := c.cc.Invoke(ctx, NotificationService_CreateTemplate_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationServiceClient) ListTemplates(ctx context.Context, in *ListTemplatesRequest, opts ...grpc.CallOption) (*ListTemplatesResponse, error) {
+	out := new(ListTemplatesResponse)
+	err := c.cc.Invoke(ctx, NotificationService_ListTemplates_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationServiceClient) GetTemplate(ctx context.Context, in *GetTemplateRequest, opts ...grpc.CallOption) (*TemplateResponse, error) {
+	out := new(TemplateResponse)
+	err := c.cc.Invoke(ctx, NotificationService_GetTemplate_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationServiceClient) UpdateTemplate(ctx context.Context, in *UpdateTemplateRequest, opts ...grpc.CallOption) (*TemplateResponse, error) {
+	out := new(TemplateResponse)
+	err := c.cc.Invoke(ctx, NotificationService_UpdateTemplate_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationServiceClient) DeleteTemplate(ctx context.Context, in *DeleteTemplateRequest, opts ...grpc.CallOption) (*DeleteTemplateResponse, error) {
+	out := new(DeleteTemplateResponse)
+	err := c.cc.Invoke(ctx, NotificationService_DeleteTemplate_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationServiceClient) CloneTemplate(ctx context.Context, in *CloneTemplateRequest, opts ...grpc.CallOption) (*TemplateResponse, error) {
+	out := new(TemplateResponse)
+	err := c.cc.Invoke(ctx, NotificationService_CloneTemplate_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationServiceClient) ActivateTemplate(ctx context.Context, in *ActivateTemplateRequest, opts ...grpc.CallOption) (*TemplateResponse, error) {
+	out := new(TemplateResponse)
+	err := c.cc.Invoke(ctx, NotificationService_ActivateTemplate_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationServiceClient) SetDigestSchedule(ctx context.Context, in *SetDigestScheduleRequest, opts ...grpc.CallOption) (*DigestScheduleResponse, error) {
+	out := new(DigestScheduleResponse)
+	err := c.cc.Invoke(ctx, NotificationService_SetDigestSchedule_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationServiceClient) DeleteDigestSchedule(ctx context.Context, in *DeleteDigestScheduleRequest, opts ...grpc.CallOption) (*DeleteDigestScheduleResponse, error) {
+	out := new(DeleteDigestScheduleResponse)
+	err := c.cc.Invoke(ctx, NotificationService_DeleteDigestSchedule_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationServiceClient) DeleteNotificationsByUser(ctx context.Context, in *DeleteNotificationsByUserRequest, opts ...grpc.CallOption) (*DeleteNotificationsByUserResponse, error) {
+	out := new(DeleteNotificationsByUserResponse)
+	err := c.cc.Invoke(ctx, NotificationService_DeleteNotificationsByUser_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationServiceClient) GetNotificationSummary(ctx context.Context, in *GetNotificationSummaryRequest, opts ...grpc.CallOption) (*GetNotificationSummaryResponse, error) {
+	out := new(GetNotificationSummaryResponse)
+	err := c.cc.Invoke(ctx, NotificationService_GetNotificationSummary_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationServiceClient) MarkNotificationRead(ctx context.Context, in *MarkNotificationReadRequest, opts ...grpc.CallOption) (*NotificationResponse, error) {
+	out := new(NotificationResponse)
+	err := c.cc.Invoke(ctx, NotificationService_MarkNotificationRead_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// NotificationServiceServer is the server API for NotificationService service.
+// All implementations must embed UnimplementedNotificationServiceServer
+// for forward compatibility
+type NotificationServiceServer interface {
+	SendNotification(context.Context, *NotificationRequest) (*NotificationResponse, error)
+	GetNotifications(context.Context, *GetNotificationsRequest) (*GetNotificationsResponse, error)
+	GetNotification(context.Context, *GetNotificationRequest) (*NotificationResponse, error)
+	ListFailedDeliveries(context.Context, *ListFailedDeliveriesRequest) (*ListFailedDeliveriesResponse, error)
+	GetDeliveryReport(context.Context, *GetDeliveryReportRequest) (*GetDeliveryReportResponse, error)
+	GetNotificationCounts(context.Context, *GetNotificationCountsRequest) (*GetNotificationCountsResponse, error)
+	RedeliverNotification(context.Context, *RedeliverNotificationRequest) (*NotificationResponse, error)
+	CreateTemplate(context.Context, *CreateTemplateRequest) (*TemplateResponse, error)
+	ListTemplates(context.Context, *ListTemplatesRequest) (*ListTemplatesResponse, error)
+	GetTemplate(context.Context, *GetTemplateRequest) (*TemplateResponse, error)
+	UpdateTemplate(context.Context, *UpdateTemplateRequest) (*TemplateResponse, error)
+	DeleteTemplate(context.Context, *DeleteTemplateRequest) (*DeleteTemplateResponse, error)
+	CloneTemplate(context.Context, *CloneTemplateRequest) (*TemplateResponse, error)
+	ActivateTemplate(context.Context, *ActivateTemplateRequest) (*TemplateResponse, error)
+	SetDigestSchedule(context.Context, *SetDigestScheduleRequest) (*DigestScheduleResponse, error)
+	DeleteDigestSchedule(context.Context, *DeleteDigestScheduleRequest) (*DeleteDigestScheduleResponse, error)
+	DeleteNotificationsByUser(context.Context, *DeleteNotificationsByUserRequest) (*DeleteNotificationsByUserResponse, error)
+	GetNotificationSummary(context.Context, *GetNotificationSummaryRequest) (*GetNotificationSummaryResponse, error)
+	MarkNotificationRead(context.Context, *MarkNotificationReadRequest) (*NotificationResponse, error)
+	mustEmbedUnimplementedNotificationServiceServer()
+}
+
+// UnimplementedNotificationServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedNotificationServiceServer struct {
+}
+
+func (UnimplementedNotificationServiceServer) SendNotification(context.Context, *NotificationRequest) (*NotificationResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SendNotification not implemented")
+}
+func (UnimplementedNotificationServiceServer) GetNotifications(context.Context, *GetNotificationsRequest) (*GetNotificationsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetNotifications not implemented")
+}
+func (UnimplementedNotificationServiceServer) GetNotification(context.Context, *GetNotificationRequest) (*NotificationResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetNotification not implemented")
+}
+func (UnimplementedNotificationServiceServer) ListFailedDeliveries(context.Context, *ListFailedDeliveriesRequest) (*ListFailedDeliveriesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListFailedDeliveries not implemented")
+}
+func (UnimplementedNotificationServiceServer) GetDeliveryReport(context.Context, *GetDeliveryReportRequest) (*GetDeliveryReportResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDeliveryReport not implemented")
+}
+func (UnimplementedNotificationServiceServer) GetNotificationCounts(context.Context, *GetNotificationCountsRequest) (*GetNotificationCountsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetNotificationCounts not implemented")
+}
+func (UnimplementedNotificationServiceServer) RedeliverNotification(context.Context, *RedeliverNotificationRequest) (*NotificationResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RedeliverNotification not implemented")
+}
+func (UnimplementedNotificationServiceServer) CreateTemplate(context.Context, *CreateTemplateRequest) (*TemplateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateTemplate not implemented")
+}
+func (UnimplementedNotificationServiceServer) ListTemplates(context.Context, *ListTemplatesRequest) (*ListTemplatesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListTemplates not implemented")
+}
+func (UnimplementedNotificationServiceServer) GetTemplate(context.Context, *GetTemplateRequest) (*TemplateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTemplate not implemented")
+}
+func (UnimplementedNotificationServiceServer) UpdateTemplate(context.Context, *UpdateTemplateRequest) (*TemplateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateTemplate not implemented")
+}
+func (UnimplementedNotificationServiceServer) DeleteTemplate(context.Context, *DeleteTemplateRequest) (*DeleteTemplateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteTemplate not implemented")
+}
+func (UnimplementedNotificationServiceServer) CloneTemplate(context.Context, *CloneTemplateRequest) (*TemplateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CloneTemplate not implemented")
+}
+func (UnimplementedNotificationServiceServer) ActivateTemplate(context.Context, *ActivateTemplateRequest) (*TemplateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ActivateTemplate not implemented")
+}
+func (UnimplementedNotificationServiceServer) SetDigestSchedule(context.Context, *SetDigestScheduleRequest) (*DigestScheduleResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetDigestSchedule not implemented")
+}
+func (UnimplementedNotificationServiceServer) DeleteDigestSchedule(context.Context, *DeleteDigestScheduleRequest) (*DeleteDigestScheduleResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteDigestSchedule not implemented")
+}
+func (UnimplementedNotificationServiceServer) DeleteNotificationsByUser(context.Context, *DeleteNotificationsByUserRequest) (*DeleteNotificationsByUserResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteNotificationsByUser not implemented")
+}
+func (UnimplementedNotificationServiceServer) GetNotificationSummary(context.Context, *GetNotificationSummaryRequest) (*GetNotificationSummaryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetNotificationSummary not implemented")
+}
+func (UnimplementedNotificationServiceServer) MarkNotificationRead(context.Context, *MarkNotificationReadRequest) (*NotificationResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MarkNotificationRead not implemented")
+}
+func (UnimplementedNotificationServiceServer) mustEmbedUnimplementedNotificationServiceServer() {}
+
+// UnsafeNotificationServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to NotificationServiceServer will
+// result in compilation errors.
+type UnsafeNotificationServiceServer interface {
+	mustEmbedUnimplementedNotificationServiceServer()
+}
+
+func RegisterNotificationServiceServer(s grpc.ServiceRegistrar, srv NotificationServiceServer) {
+	s.RegisterService(&NotificationService_ServiceDesc, srv)
+}
+
+func _NotificationService_SendNotification_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NotificationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationServiceServer).SendNotification(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NotificationService_SendNotification_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationServiceServer).SendNotification(ctx, req.(*NotificationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationService_GetNotifications_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetNotificationsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationServiceServer).GetNotifications(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NotificationService_GetNotifications_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationServiceServer).GetNotifications(ctx, req.(*GetNotificationsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationService_GetNotification_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetNotificationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationServiceServer).GetNotification(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NotificationService_GetNotification_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationServiceServer).GetNotification(ctx, req.(*GetNotificationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationService_ListFailedDeliveries_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListFailedDeliveriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationServiceServer).ListFailedDeliveries(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NotificationService_ListFailedDeliveries_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationServiceServer).ListFailedDeliveries(ctx, req.(*ListFailedDeliveriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationService_GetDeliveryReport_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDeliveryReportRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationServiceServer).GetDeliveryReport(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NotificationService_GetDeliveryReport_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationServiceServer).GetDeliveryReport(ctx, req.(*GetDeliveryReportRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationService_GetNotificationCounts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetNotificationCountsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationServiceServer).GetNotificationCounts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NotificationService_GetNotificationCounts_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationServiceServer).GetNotificationCounts(ctx, req.(*GetNotificationCountsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationService_RedeliverNotification_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RedeliverNotificationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationServiceServer).RedeliverNotification(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NotificationService_RedeliverNotification_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationServiceServer).RedeliverNotification(ctx, req.(*RedeliverNotificationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationService_CreateTemplate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateTemplateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationServiceServer).CreateTemplate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NotificationService_CreateTemplate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationServiceServer).CreateTemplate(ctx, req.(*CreateTemplateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationService_ListTemplates_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListTemplatesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationServiceServer).ListTemplates(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NotificationService_ListTemplates_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationServiceServer).ListTemplates(ctx, req.(*ListTemplatesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationService_GetTemplate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTemplateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationServiceServer).GetTemplate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NotificationService_GetTemplate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationServiceServer).GetTemplate(ctx, req.(*GetTemplateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationService_UpdateTemplate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateTemplateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationServiceServer).UpdateTemplate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NotificationService_UpdateTemplate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationServiceServer).UpdateTemplate(ctx, req.(*UpdateTemplateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationService_DeleteTemplate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteTemplateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationServiceServer).DeleteTemplate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NotificationService_DeleteTemplate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationServiceServer).DeleteTemplate(ctx, req.(*DeleteTemplateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationService_CloneTemplate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CloneTemplateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationServiceServer).CloneTemplate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NotificationService_CloneTemplate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationServiceServer).CloneTemplate(ctx, req.(*CloneTemplateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationService_ActivateTemplate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ActivateTemplateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationServiceServer).ActivateTemplate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NotificationService_ActivateTemplate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationServiceServer).ActivateTemplate(ctx, req.(*ActivateTemplateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationService_SetDigestSchedule_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetDigestScheduleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationServiceServer).SetDigestSchedule(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NotificationService_SetDigestSchedule_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationServiceServer).SetDigestSchedule(ctx, req.(*SetDigestScheduleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationService_DeleteDigestSchedule_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteDigestScheduleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationServiceServer).DeleteDigestSchedule(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NotificationService_DeleteDigestSchedule_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationServiceServer).DeleteDigestSchedule(ctx, req.(*DeleteDigestScheduleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationService_DeleteNotificationsByUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteNotificationsByUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationServiceServer).DeleteNotificationsByUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NotificationService_DeleteNotificationsByUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationServiceServer).DeleteNotificationsByUser(ctx, req.(*DeleteNotificationsByUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationService_GetNotificationSummary_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetNotificationSummaryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationServiceServer).GetNotificationSummary(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NotificationService_GetNotificationSummary_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationServiceServer).GetNotificationSummary(ctx, req.(*GetNotificationSummaryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationService_MarkNotificationRead_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MarkNotificationReadRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationServiceServer).MarkNotificationRead(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NotificationService_MarkNotificationRead_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationServiceServer).MarkNotificationRead(ctx, req.(*MarkNotificationReadRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// NotificationService_ServiceDesc is the grpc.ServiceDesc for NotificationService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var NotificationService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "todo.NotificationService",
+	HandlerType: (*NotificationServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SendNotification",
+			Handler:    _NotificationService_SendNotification_Handler,
+		},
+		{
+			MethodName: "GetNotifications",
+			Handler:    _NotificationService_GetNotifications_Handler,
+		},
+		{
+			MethodName: "GetNotification",
+			Handler:    _NotificationService_GetNotification_Handler,
+		},
+		{
+			MethodName: "ListFailedDeliveries",
+			Handler:    _NotificationService_ListFailedDeliveries_Handler,
+		},
+		{
+			MethodName: "GetDeliveryReport",
+			Handler:    _NotificationService_GetDeliveryReport_Handler,
+		},
+		{
+			MethodName: "GetNotificationCounts",
+			Handler:    _NotificationService_GetNotificationCounts_Handler,
+		},
+		{
+			MethodName: "RedeliverNotification",
+			Handler:    _NotificationService_RedeliverNotification_Handler,
+		},
+		{
+			MethodName: "CreateTemplate",
+			Handler:    _NotificationService_CreateTemplate_Handler,
+		},
+		{
+			MethodName: "ListTemplates",
+			Handler:    _NotificationService_ListTemplates_Handler,
+		},
+		{
+			MethodName: "GetTemplate",
+			Handler:    _NotificationService_GetTemplate_Handler,
+		},
+		{
+			MethodName: "UpdateTemplate",
+			Handler:    _NotificationService_UpdateTemplate_Handler,
+		},
+		{
+			MethodName: "DeleteTemplate",
+			Handler:    _NotificationService_DeleteTemplate_Handler,
+		},
+		{
+			MethodName: "CloneTemplate",
+			Handler:    _NotificationService_CloneTemplate_Handler,
+		},
+		{
+			MethodName: "ActivateTemplate",
+			Handler:    _NotificationService_ActivateTemplate_Handler,
+		},
+		{
+			MethodName: "SetDigestSchedule",
+			Handler:    _NotificationService_SetDigestSchedule_Handler,
+		},
+		{
+			MethodName: "DeleteDigestSchedule",
+			Handler:    _NotificationService_DeleteDigestSchedule_Handler,
+		},
+		{
+			MethodName: "DeleteNotificationsByUser",
+			Handler:    _NotificationService_DeleteNotificationsByUser_Handler,
+		},
+		{
+			MethodName: "GetNotificationSummary",
+			Handler:    _NotificationService_GetNotificationSummary_Handler,
+		},
+		{
+			MethodName: "MarkNotificationRead",
+			Handler:    _NotificationService_MarkNotificationRead_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "proto/todo.proto",
+}
+
+const (
+	AnalyticsService_TrackEvent_FullMethodName               = "/todo.AnalyticsService/TrackEvent"
+	AnalyticsService_TrackEvents_FullMethodName              = "/todo.AnalyticsService/TrackEvents"
+	AnalyticsService_GetUserStats_FullMethodName             = "/todo.AnalyticsService/GetUserStats"
+	AnalyticsService_GetTaskStats_FullMethodName             = "/todo.AnalyticsService/GetTaskStats"
+	AnalyticsService_GetPeakHours_FullMethodName             = "/todo.AnalyticsService/GetPeakHours"
+	AnalyticsService_GetCompletionTrend_FullMethodName       = "/todo.AnalyticsService/GetCompletionTrend"
+	AnalyticsService_BackfillAnalytics_FullMethodName        = "/todo.AnalyticsService/BackfillAnalytics"
+	AnalyticsService_GetEngagementScore_FullMethodName       = "/todo.AnalyticsService/GetEngagementScore"
+	AnalyticsService_ReplayEvents_FullMethodName             = "/todo.AnalyticsService/ReplayEvents"
+	AnalyticsService_GetUserStreak_FullMethodName            = "/todo.AnalyticsService/GetUserStreak"
+	AnalyticsService_GetActivityHeatmap_FullMethodName       = "/todo.AnalyticsService/GetActivityHeatmap"
+	AnalyticsService_GetActiveUsers_FullMethodName           = "/todo.AnalyticsService/GetActiveUsers"
+	AnalyticsService_GetCompletionLatency_FullMethodName     = "/todo.AnalyticsService/GetCompletionLatency"
+	AnalyticsService_GetRetentionStatus_FullMethodName       = "/todo.AnalyticsService/GetRetentionStatus"
+	AnalyticsService_GenerateWeeklySummary_FullMethodName    = "/todo.AnalyticsService/GenerateWeeklySummary"
+	AnalyticsService_GetTaskBreakdown_FullMethodName         = "/todo.AnalyticsService/GetTaskBreakdown"
+	AnalyticsService_GetCacheStats_FullMethodName            = "/todo.AnalyticsService/GetCacheStats"
+	AnalyticsService_StreamEvents_FullMethodName             = "/todo.AnalyticsService/StreamEvents"
+	AnalyticsService_SubscribeToEvents_FullMethodName        = "/todo.AnalyticsService/SubscribeToEvents"
+	AnalyticsService_GetSystemOverview_FullMethodName        = "/todo.AnalyticsService/GetSystemOverview"
+	AnalyticsService_GetOverdueAging_FullMethodName          = "/todo.AnalyticsService/GetOverdueAging"
+	AnalyticsService_ExportStats_FullMethodName              = "/todo.AnalyticsService/ExportStats"
+	AnalyticsService_GetTimeReport_FullMethodName            = "/todo.AnalyticsService/GetTimeReport"
+	AnalyticsService_DeleteUserEvents_FullMethodName         = "/todo.AnalyticsService/DeleteUserEvents"
+	AnalyticsService_AnonymizeUserEvents_FullMethodName      = "/todo.AnalyticsService/AnonymizeUserEvents"
+	AnalyticsService_CompareUsersProductivity_FullMethodName = "/todo.AnalyticsService/CompareUsersProductivity"
+	AnalyticsService_GetCompletionForecast_FullMethodName    = "/todo.AnalyticsService/GetCompletionForecast"
+	AnalyticsService_GetBurndown_FullMethodName              = "/todo.AnalyticsService/GetBurndown"
+	AnalyticsService_GetPriorityBreakdown_FullMethodName     = "/todo.AnalyticsService/GetPriorityBreakdown"
+)
+
+// AnalyticsServiceClient is the client API for AnalyticsService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type AnalyticsServiceClient interface {
+	TrackEvent(ctx context.Context, in *TrackEventRequest, opts ...grpc.CallOption) (*TrackEventResponse, error)
+	TrackEvents(ctx context.Context, in *TrackEventsRequest, opts ...grpc.CallOption) (*TrackEventsResponse, error)
+	GetUserStats(ctx context.Context, in *GetUserStatsRequest, opts ...grpc.CallOption) (*GetUserStatsResponse, error)
+	GetTaskStats(ctx context.Context, in *GetTaskStatsRequest, opts ...grpc.CallOption) (*GetTaskStatsResponse, error)
+	GetPeakHours(ctx context.Context, in *GetPeakHoursRequest, opts ...grpc.CallOption) (*GetPeakHoursResponse, error)
+	GetCompletionTrend(ctx context.Context, in *GetCompletionTrendRequest, opts ...grpc.CallOption) (*GetCompletionTrendResponse, error)
+	BackfillAnalytics(ctx context.Context, in *BackfillRequest, opts ...grpc.CallOption) (*BackfillResponse, error)
+	GetEngagementScore(ctx context.Context, in *GetEngagementScoreRequest, opts ...grpc.CallOption) (*GetEngagementScoreResponse, error)
+	ReplayEvents(ctx context.Context, in *ReplayEventsRequest, opts ...grpc.CallOption) (AnalyticsService_ReplayEventsClient, error)
+	GetUserStreak(ctx context.Context, in *GetUserStreakRequest, opts ...grpc.CallOption) (*GetUserStreakResponse, error)
+	GetActivityHeatmap(ctx context.Context, in *GetActivityHeatmapRequest, opts ...grpc.CallOption) (*GetActivityHeatmapResponse, error)
+	GetActiveUsers(ctx context.Context, in *GetActiveUsersRequest, opts ...grpc.CallOption) (*GetActiveUsersResponse, error)
+	GetCompletionLatency(ctx context.Context, in *GetCompletionLatencyRequest, opts ...grpc.CallOption) (*GetCompletionLatencyResponse, error)
+	GetRetentionStatus(ctx context.Context, in *GetRetentionStatusRequest, opts ...grpc.CallOption) (*GetRetentionStatusResponse, error)
+	GenerateWeeklySummary(ctx context.Context, in *GenerateWeeklySummaryRequest, opts ...grpc.CallOption) (*GenerateWeeklySummaryResponse, error)
+	GetTaskBreakdown(ctx context.Context, in *GetTaskBreakdownRequest, opts ...grpc.CallOption) (*GetTaskBreakdownResponse, error)
+	GetCacheStats(ctx context.Context, in *GetCacheStatsRequest, opts ...grpc.CallOption) (*GetCacheStatsResponse, error)
+	StreamEvents(ctx context.Context, in *StreamEventsRequest, opts ...grpc.CallOption) (AnalyticsService_StreamEventsClient, error)
+	SubscribeToEvents(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (AnalyticsService_SubscribeToEventsClient, error)
+	GetSystemOverview(ctx context.Context, in *GetSystemOverviewRequest, opts ...grpc.CallOption) (*GetSystemOverviewResponse, error)
+	GetOverdueAging(ctx context.Context, in *GetOverdueAgingRequest, opts ...grpc.CallOption) (*GetOverdueAgingResponse, error)
+	ExportStats(ctx context.Context, in *ExportStatsRequest, opts ...grpc.CallOption) (AnalyticsService_ExportStatsClient, error)
+	GetTimeReport(ctx context.Context, in *GetTimeReportRequest, opts ...grpc.CallOption) (*GetTimeReportResponse, error)
+	DeleteUserEvents(ctx context.Context, in *DeleteUserEventsRequest, opts ...grpc.CallOption) (*DeleteUserEventsResponse, error)
+	AnonymizeUserEvents(ctx context.Context, in *AnonymizeUserEventsRequest, opts ...grpc.CallOption) (*AnonymizeUserEventsResponse, error)
+	CompareUsersProductivity(ctx context.Context, in *CompareUsersRequest, opts ...grpc.CallOption) (*CompareUsersResponse, error)
+	GetCompletionForecast(ctx context.Context, in *GetCompletionForecastRequest, opts ...grpc.CallOption) (*GetCompletionForecastResponse, error)
+	GetBurndown(ctx context.Context, in *GetBurndownRequest, opts ...grpc.CallOption) (*GetBurndownResponse, error)
+	GetPriorityBreakdown(ctx context.Context, in *GetPriorityBreakdownRequest, opts ...grpc.CallOption) (*GetPriorityBreakdownResponse, error)
+}
+
+type analyticsServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAnalyticsServiceClient(cc grpc.ClientConnInterface) AnalyticsServiceClient {
+	return &analyticsServiceClient{cc}
+}
+
+func (c *analyticsServiceClient) TrackEvent(ctx context.Context, in *TrackEventRequest, opts ...grpc.CallOption) (*TrackEventResponse, error) {
+	out := new(TrackEventResponse)
+	err := c.cc.Invoke(ctx, AnalyticsService_TrackEvent_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *analyticsServiceClient) TrackEvents(ctx context.Context, in *TrackEventsRequest, opts ...grpc.CallOption) (*TrackEventsResponse, error) {
+	out := new(TrackEventsResponse)
+	err := c.cc.Invoke(ctx, AnalyticsService_TrackEvents_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *analyticsServiceClient) GetUserStats(ctx context.Context, in *GetUserStatsRequest, opts ...grpc.CallOption) (*GetUserStatsResponse, error) {
+	out := new(GetUserStatsResponse)
+	err := c.cc.Invoke(ctx, AnalyticsService_GetUserStats_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *analyticsServiceClient) GetTaskStats(ctx context.Context, in *GetTaskStatsRequest, opts ...grpc.CallOption) (*GetTaskStatsResponse, error) {
+	out := new(GetTaskStatsResponse)
+	err := c.cc.Invoke(ctx, AnalyticsService_GetTaskStats_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *analyticsServiceClient) GetPeakHours(ctx context.Context, in *GetPeakHoursRequest, opts ...grpc.CallOption) (*GetPeakHoursResponse, error) {
+	out := new(GetPeakHoursResponse)
+	err := c.cc.Invoke(ctx, AnalyticsService_GetPeakHours_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *analyticsServiceClient) GetCompletionTrend(ctx context.Context, in *GetCompletionTrendRequest, opts ...grpc.CallOption) (*GetCompletionTrendResponse, error) {
+	out := new(GetCompletionTrendResponse)
+	err := c.cc.Invoke(ctx, AnalyticsService_GetCompletionTrend_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *analyticsServiceClient) BackfillAnalytics(ctx context.Context, in *BackfillRequest, opts ...grpc.CallOption) (*BackfillResponse, error) {
+	out := new(BackfillResponse)
+	err := c.cc.Invoke(ctx, AnalyticsService_BackfillAnalytics_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *analyticsServiceClient) GetEngagementScore(ctx context.Context, in *GetEngagementScoreRequest, opts ...grpc.CallOption) (*GetEngagementScoreResponse, error) {
+	out := new(GetEngagementScoreResponse)
+	err := c.cc.Invoke(ctx, AnalyticsService_GetEngagementScore_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *analyticsServiceClient) ReplayEvents(ctx context.Context, in *ReplayEventsRequest, opts ...grpc.CallOption) (AnalyticsService_ReplayEventsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &AnalyticsService_ServiceDesc.Streams[0], AnalyticsService_ReplayEvents_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &analyticsServiceReplayEventsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type AnalyticsService_ReplayEventsClient interface {
+	Recv() (*Event, error)
+	grpc.ClientStream
+}
+
+type analyticsServiceReplayEventsClient struct {
+	grpc.ClientStream
+}
+
+func (x *analyticsServiceReplayEventsClient) Recv() (*Event, error) {
+	m := new(Event)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *analyticsServiceClient) GetUserStreak(ctx context.Context, in *GetUserStreakRequest, opts ...grpc.CallOption) (*GetUserStreakResponse, error) {
+	out := new(GetUserStreakResponse)
+	err := c.cc.Invoke(ctx, AnalyticsService_GetUserStreak_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *analyticsServiceClient) GetActivityHeatmap(ctx context.Context, in *GetActivityHeatmapRequest, opts ...grpc.CallOption) (*GetActivityHeatmapResponse, error) {
+	out := new(GetActivityHeatmapResponse)
+	err := c.cc.Invoke(ctx, AnalyticsService_GetActivityHeatmap_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *analyticsServiceClient) GetActiveUsers(ctx context.Context, in *GetActiveUsersRequest, opts ...grpc.CallOption) (*GetActiveUsersResponse, error) {
+	out := new(GetActiveUsersResponse)
+	err := c.cc.Invoke(ctx, AnalyticsService_GetActiveUsers_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *analyticsServiceClient) GetCompletionLatency(ctx context.Context, in *GetCompletionLatencyRequest, opts ...grpc.CallOption) (*GetCompletionLatencyResponse, error) {
+	out := new(GetCompletionLatencyResponse)
+	err := c.cc.Invoke(ctx, AnalyticsService_GetCompletionLatency_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *analyticsServiceClient) GetRetentionStatus(ctx context.Context, in *GetRetentionStatusRequest, opts ...grpc.CallOption) (*GetRetentionStatusResponse, error) {
+	out := new(GetRetentionStatusResponse)
+	err := c.cc.Invoke(ctx, AnalyticsService_GetRetentionStatus_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *analyticsServiceClient) GenerateWeeklySummary(ctx context.Context, in *GenerateWeeklySummaryRequest, opts ...grpc.CallOption) (*GenerateWeeklySummaryResponse, error) {
+	out := new(GenerateWeeklySummaryResponse)
+	err := c.cc.Invoke(ctx, AnalyticsService_GenerateWeeklySummary_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *analyticsServiceClient) GetTaskBreakdown(ctx context.Context, in *GetTaskBreakdownRequest, opts ...grpc.CallOption) (*GetTaskBreakdownResponse, error) {
+	out := new(GetTaskBreakdownResponse)
+	err := c.cc.Invoke(ctx, AnalyticsService_GetTaskBreakdown_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *analyticsServiceClient) GetCacheStats(ctx context.Context, in *GetCacheStatsRequest, opts ...grpc.CallOption) (*GetCacheStatsResponse, error) {
+	out := new(GetCacheStatsResponse)
+	err := c.cc.Invoke(ctx, AnalyticsService_GetCacheStats_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *analyticsServiceClient) StreamEvents(ctx context.Context, in *StreamEventsRequest, opts ...grpc.CallOption) (AnalyticsService_StreamEventsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &AnalyticsService_ServiceDesc.Streams[1], AnalyticsService_StreamEvents_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &analyticsServiceStreamEventsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type AnalyticsService_StreamEventsClient interface {
+	Recv() (*StreamEventsResponse, error)
+	grpc.ClientStream
+}
+
+type analyticsServiceStreamEventsClient struct {
+	grpc.ClientStream
+}
+
+func (x *analyticsServiceStreamEventsClient) Recv() (*StreamEventsResponse, error) {
+	m := new(StreamEventsResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *analyticsServiceClient) SubscribeToEvents(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (AnalyticsService_SubscribeToEventsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &AnalyticsService_ServiceDesc.Streams[2], AnalyticsService_SubscribeToEvents_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &analyticsServiceSubscribeToEventsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type AnalyticsService_SubscribeToEventsClient interface {
+	Recv() (*Event, error)
+	grpc.ClientStream
+}
+
+type analyticsServiceSubscribeToEventsClient struct {
+	grpc.ClientStream
+}
+
+func (x *analyticsServiceSubscribeToEventsClient) Recv() (*Event, error) {
+	m := new(Event)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *analyticsServiceClient) GetSystemOverview(ctx context.Context, in *GetSystemOverviewRequest, opts ...grpc.CallOption) (*GetSystemOverviewResponse, error) {
+	out := new(GetSystemOverviewResponse)
+	err := c.cc.Invoke(ctx, AnalyticsService_GetSystemOverview_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *analyticsServiceClient) GetOverdueAging(ctx context.Context, in *GetOverdueAgingRequest, opts ...grpc.CallOption) (*GetOverdueAgingResponse, error) {
+	out := new(GetOverdueAgingResponse)
+	err := c.cc.Invoke(ctx, AnalyticsService_GetOverdueAging_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *analyticsServiceClient) ExportStats(ctx context.Context, in *ExportStatsRequest, opts ...grpc.CallOption) (AnalyticsService_ExportStatsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &AnalyticsService_ServiceDesc.Streams[3], AnalyticsService_ExportStats_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &analyticsServiceExportStatsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type AnalyticsService_ExportStatsClient interface {
+	Recv() (*ExportStatsChunk, error)
+	grpc.ClientStream
+}
+
+type analyticsServiceExportStatsClient struct {
+	grpc.ClientStream
+}
+
+func (x *analyticsServiceExportStatsClient) Recv() (*ExportStatsChunk, error) {
+	m := new(ExportStatsChunk)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *analyticsServiceClient) GetTimeReport(ctx context.Context, in *GetTimeReportRequest, opts ...grpc.CallOption) (*GetTimeReportResponse, error) {
+	out := new(GetTimeReportResponse)
+	err := c.cc.Invoke(ctx, AnalyticsService_GetTimeReport_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *analyticsServiceClient) DeleteUserEvents(ctx context.Context, in *DeleteUserEventsRequest, opts ...grpc.CallOption) (*DeleteUserEventsResponse, error) {
+	out := new(DeleteUserEventsResponse)
+	err := c.cc.Invoke(ctx, AnalyticsService_DeleteUserEvents_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *analyticsServiceClient) AnonymizeUserEvents(ctx context.Context, in *AnonymizeUserEventsRequest, opts ...grpc.CallOption) (*AnonymizeUserEventsResponse, error) {
+	out := new(AnonymizeUserEventsResponse)
+	err := c.cc.Invoke(ctx, AnalyticsService_AnonymizeUserEvents_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *analyticsServiceClient) CompareUsersProductivity(ctx context.Context, in *CompareUsersRequest, opts ...grpc.CallOption) (*CompareUsersResponse, error) {
+	out := new(CompareUsersResponse)
+	err := c.cc.Invoke(ctx, AnalyticsService_CompareUsersProductivity_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *analyticsServiceClient) GetCompletionForecast(ctx context.Context, in *GetCompletionForecastRequest, opts ...grpc.CallOption) (*GetCompletionForecastResponse, error) {
+	out := new(GetCompletionForecastResponse)
+	err := c.cc.Invoke(ctx, AnalyticsService_GetCompletionForecast_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *analyticsServiceClient) GetBurndown(ctx context.Context, in *GetBurndownRequest, opts ...grpc.CallOption) (*GetBurndownResponse, error) {
+	out := new(GetBurndownResponse)
+	err := c.cc.Invoke(ctx, AnalyticsService_GetBurndown_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *analyticsServiceClient) GetPriorityBreakdown(ctx context.Context, in *GetPriorityBreakdownRequest, opts ...grpc.CallOption) (*GetPriorityBreakdownResponse, error) {
+	out := new(GetPriorityBreakdownResponse)
+	err := c.cc.Invoke(ctx, AnalyticsService_GetPriorityBreakdown_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AnalyticsServiceServer is the server API for AnalyticsService service.
+// All implementations must embed UnimplementedAnalyticsServiceServer
+// for forward compatibility
+type AnalyticsServiceServer interface {
+	TrackEvent(context.Context, *TrackEventRequest) (*TrackEventResponse, error)
+	TrackEvents(context.Context, *TrackEventsRequest) (*TrackEventsResponse, error)
+	GetUserStats(context.Context, *GetUserStatsRequest) (*GetUserStatsResponse, error)
+	GetTaskStats(context.Context, *GetTaskStatsRequest) (*GetTaskStatsResponse, error)
+	GetPeakHours(context.Context, *GetPeakHoursRequest) (*GetPeakHoursResponse, error)
+	GetCompletionTrend(context.Context, *GetCompletionTrendRequest) (*GetCompletionTrendResponse, error)
+	BackfillAnalytics(context.Context, *BackfillRequest) (*BackfillResponse, error)
+	GetEngagementScore(context.Context, *GetEngagementScoreRequest) (*GetEngagementScoreResponse, error)
+	ReplayEvents(*ReplayEventsRequest, AnalyticsService_ReplayEventsServer) error
+	GetUserStreak(context.Context, *GetUserStreakRequest) (*GetUserStreakResponse, error)
+	GetActivityHeatmap(context.Context, *GetActivityHeatmapRequest) (*GetActivityHeatmapResponse, error)
+	GetActiveUsers(context.Context, *GetActiveUsersRequest) (*GetActiveUsersResponse, error)
+	GetCompletionLatency(context.Context, *GetCompletionLatencyRequest) (*GetCompletionLatencyResponse, error)
+	GetRetentionStatus(context.Context, *GetRetentionStatusRequest) (*GetRetentionStatusResponse, error)
+	GenerateWeeklySummary(context.Context, *GenerateWeeklySummaryRequest) (*GenerateWeeklySummaryResponse, error)
+	GetTaskBreakdown(context.Context, *GetTaskBreakdownRequest) (*GetTaskBreakdownResponse, error)
+	GetCacheStats(context.Context, *GetCacheStatsRequest) (*GetCacheStatsResponse, error)
+	StreamEvents(*StreamEventsRequest, AnalyticsService_StreamEventsServer) error
+	SubscribeToEvents(*SubscribeRequest, AnalyticsService_SubscribeToEventsServer) error
+	GetSystemOverview(context.Context, *GetSystemOverviewRequest) (*GetSystemOverviewResponse, error)
+	GetOverdueAging(context.Context, *GetOverdueAgingRequest) (*GetOverdueAgingResponse, error)
+	ExportStats(*ExportStatsRequest, AnalyticsService_ExportStatsServer) error
+	GetTimeReport(context.Context, *GetTimeReportRequest) (*GetTimeReportResponse, error)
+	DeleteUserEvents(context.Context, *DeleteUserEventsRequest) (*DeleteUserEventsResponse, error)
+	AnonymizeUserEvents(context.Context, *AnonymizeUserEventsRequest) (*AnonymizeUserEventsResponse, error)
+	CompareUsersProductivity(context.Context, *CompareUsersRequest) (*CompareUsersResponse, error)
+	GetCompletionForecast(context.Context, *GetCompletionForecastRequest) (*GetCompletionForecastResponse, error)
+	GetBurndown(context.Context, *GetBurndownRequest) (*GetBurndownResponse, error)
+	GetPriorityBreakdown(context.Context, *GetPriorityBreakdownRequest) (*GetPriorityBreakdownResponse, error)
+	mustEmbedUnimplementedAnalyticsServiceServer()
+}
+
+// UnimplementedAnalyticsServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedAnalyticsServiceServer struct {
+}
+
+func (UnimplementedAnalyticsServiceServer) TrackEvent(context.Context, *TrackEventRequest) (*TrackEventResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TrackEvent not implemented")
+}
+func (UnimplementedAnalyticsServiceServer) TrackEvents(context.Context, *TrackEventsRequest) (*TrackEventsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TrackEvents not implemented")
+}
+func (UnimplementedAnalyticsServiceServer) GetUserStats(context.Context, *GetUserStatsRequest) (*GetUserStatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUserStats not implemented")
+}
+func (UnimplementedAnalyticsServiceServer) GetTaskStats(context.Context, *GetTaskStatsRequest) (*GetTaskStatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTaskStats not implemented")
+}
+func (UnimplementedAnalyticsServiceServer) GetPeakHours(context.Context, *GetPeakHoursRequest) (*GetPeakHoursResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPeakHours not implemented")
+}
+func (UnimplementedAnalyticsServiceServer) GetCompletionTrend(context.Context, *GetCompletionTrendRequest) (*GetCompletionTrendResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCompletionTrend not implemented")
+}
+func (UnimplementedAnalyticsServiceServer) BackfillAnalytics(context.Context, *BackfillRequest) (*BackfillResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BackfillAnalytics not implemented")
+}
+func (UnimplementedAnalyticsServiceServer) GetEngagementScore(context.Context, *GetEngagementScoreRequest) (*GetEngagementScoreResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetEngagementScore not implemented")
+}
+func (UnimplementedAnalyticsServiceServer) ReplayEvents(*ReplayEventsRequest, AnalyticsService_ReplayEventsServer) error {
+	return status.Errorf(codes.Unimplemented, "method ReplayEvents not implemented")
+}
+func (UnimplementedAnalyticsServiceServer) GetUserStreak(context.Context, *GetUserStreakRequest) (*GetUserStreakResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUserStreak not implemented")
+}
+func (UnimplementedAnalyticsServiceServer) GetActivityHeatmap(context.Context, *GetActivityHeatmapRequest) (*GetActivityHeatmapResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetActivityHeatmap not implemented")
+}
+func (UnimplementedAnalyticsServiceServer) GetActiveUsers(context.Context, *GetActiveUsersRequest) (*GetActiveUsersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetActiveUsers not implemented")
+}
+func (UnimplementedAnalyticsServiceServer) GetCompletionLatency(context.Context, *GetCompletionLatencyRequest) (*GetCompletionLatencyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCompletionLatency not implemented")
+}
+func (UnimplementedAnalyticsServiceServer) GetRetentionStatus(context.Context, *GetRetentionStatusRequest) (*GetRetentionStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRetentionStatus not implemented")
+}
+func (UnimplementedAnalyticsServiceServer) GenerateWeeklySummary(context.Context, *GenerateWeeklySummaryRequest) (*GenerateWeeklySummaryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GenerateWeeklySummary not implemented")
+}
+func (UnimplementedAnalyticsServiceServer) GetTaskBreakdown(context.Context, *GetTaskBreakdownRequest) (*GetTaskBreakdownResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTaskBreakdown not implemented")
+}
+func (UnimplementedAnalyticsServiceServer) GetCacheStats(context.Context, *GetCacheStatsRequest) (*GetCacheStatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCacheStats not implemented")
+}
+func (UnimplementedAnalyticsServiceServer) StreamEvents(*StreamEventsRequest, AnalyticsService_StreamEventsServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamEvents not implemented")
+}
+func (UnimplementedAnalyticsServiceServer) SubscribeToEvents(*SubscribeRequest, AnalyticsService_SubscribeToEventsServer) error {
+	return status.Errorf(codes.Unimplemented, "method SubscribeToEvents not implemented")
+}
+func (UnimplementedAnalyticsServiceServer) GetSystemOverview(context.Context, *GetSystemOverviewRequest) (*GetSystemOverviewResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSystemOverview not implemented")
+}
+func (UnimplementedAnalyticsServiceServer) GetOverdueAging(context.Context, *GetOverdueAgingRequest) (*GetOverdueAgingResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetOverdueAging not implemented")
+}
+func (UnimplementedAnalyticsServiceServer) ExportStats(*ExportStatsRequest, AnalyticsService_ExportStatsServer) error {
+	return status.Errorf(codes.Unimplemented, "method ExportStats not implemented")
+}
+func (UnimplementedAnalyticsServiceServer) GetTimeReport(context.Context, *GetTimeReportRequest) (*GetTimeReportResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTimeReport not implemented")
+}
+func (UnimplementedAnalyticsServiceServer) DeleteUserEvents(context.Context, *DeleteUserEventsRequest) (*DeleteUserEventsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteUserEvents not implemented")
+}
+func (UnimplementedAnalyticsServiceServer) AnonymizeUserEvents(context.Context, *AnonymizeUserEventsRequest) (*AnonymizeUserEventsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AnonymizeUserEvents not implemented")
+}
+func (UnimplementedAnalyticsServiceServer) CompareUsersProductivity(context.Context, *CompareUsersRequest) (*CompareUsersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CompareUsersProductivity not implemented")
+}
+func (UnimplementedAnalyticsServiceServer) GetCompletionForecast(context.Context, *GetCompletionForecastRequest) (*GetCompletionForecastResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCompletionForecast not implemented")
+}
+func (UnimplementedAnalyticsServiceServer) GetBurndown(context.Context, *GetBurndownRequest) (*GetBurndownResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBurndown not implemented")
+}
+func (UnimplementedAnalyticsServiceServer) GetPriorityBreakdown(context.Context, *GetPriorityBreakdownRequest) (*GetPriorityBreakdownResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPriorityBreakdown not implemented")
+}
+func (UnimplementedAnalyticsServiceServer) mustEmbedUnimplementedAnalyticsServiceServer() {}
+
+// UnsafeAnalyticsServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AnalyticsServiceServer will
+// result in compilation errors.
+type UnsafeAnalyticsServiceServer interface {
+	mustEmbedUnimplementedAnalyticsServiceServer()
+}
+
+func RegisterAnalyticsServiceServer(s grpc.ServiceRegistrar, srv AnalyticsServiceServer) {
+	s.RegisterService(&AnalyticsService_ServiceDesc, srv)
+}
+
+func _AnalyticsService_TrackEvent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TrackEventRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AnalyticsServiceServer).TrackEvent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AnalyticsService_TrackEvent_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AnalyticsServiceServer).TrackEvent(ctx, req.(*TrackEventRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AnalyticsService_TrackEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TrackEventsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AnalyticsServiceServer).TrackEvents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AnalyticsService_TrackEvents_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AnalyticsServiceServer).TrackEvents(ctx, req.(*TrackEventsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AnalyticsService_GetUserStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUserStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AnalyticsServiceServer).GetUserStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AnalyticsService_GetUserStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AnalyticsServiceServer).GetUserStats(ctx, req.(*GetUserStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AnalyticsService_GetTaskStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTaskStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AnalyticsServiceServer).GetTaskStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AnalyticsService_GetTaskStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AnalyticsServiceServer).GetTaskStats(ctx, req.(*GetTaskStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AnalyticsService_GetPeakHours_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPeakHoursRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AnalyticsServiceServer).GetPeakHours(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AnalyticsService_GetPeakHours_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AnalyticsServiceServer).GetPeakHours(ctx, req.(*GetPeakHoursRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AnalyticsService_GetCompletionTrend_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCompletionTrendRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AnalyticsServiceServer).GetCompletionTrend(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AnalyticsService_GetCompletionTrend_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AnalyticsServiceServer).GetCompletionTrend(ctx, req.(*GetCompletionTrendRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AnalyticsService_BackfillAnalytics_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BackfillRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AnalyticsServiceServer).BackfillAnalytics(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AnalyticsService_BackfillAnalytics_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AnalyticsServiceServer).BackfillAnalytics(ctx, req.(*BackfillRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AnalyticsService_GetEngagementScore_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetEngagementScoreRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AnalyticsServiceServer).GetEngagementScore(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AnalyticsService_GetEngagementScore_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AnalyticsServiceServer).GetEngagementScore(ctx, req.(*GetEngagementScoreRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AnalyticsService_ReplayEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ReplayEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AnalyticsServiceServer).ReplayEvents(m, &analyticsServiceReplayEventsServer{stream})
 }
-func (UnimplementedTaskServiceServer) UpdateTask(context.Context, *UpdateTaskRequest) (*TaskResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method UpdateTask not implemented")
+
+type AnalyticsService_ReplayEventsServer interface {
+	Send(*Event) error
+	grpc.ServerStream
 }
-func (UnimplementedTaskServiceServer) DeleteTask(context.Context, *DeleteTaskRequest) (*DeleteTaskResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method DeleteTask not implemented")
+
+type analyticsServiceReplayEventsServer struct {
+	grpc.ServerStream
 }
-func (UnimplementedTaskServiceServer) ListTasks(context.Context, *ListTasksRequest) (*ListTasksResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method ListTasks not implemented")
+
+func (x *analyticsServiceReplayEventsServer) Send(m *Event) error {
+	return x.ServerStream.SendMsg(m)
 }
-func (UnimplementedTaskServiceServer) mustEmbedUnimplementedTaskServiceServer() {}
 
-// UnsafeTaskServiceServer may be embedded to opt out of forward compatibility for this service.
-// Use of this interface is not recommended, as added methods to TaskServiceServer will
-// result in compilation errors.
-type UnsafeTaskServiceServer interface {
-	mustEmbedUnimplementedTaskServiceServer()
+func _AnalyticsService_GetUserStreak_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUserStreakRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AnalyticsServiceServer).GetUserStreak(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AnalyticsService_GetUserStreak_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AnalyticsServiceServer).GetUserStreak(ctx, req.(*GetUserStreakRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func RegisterTaskServiceServer(s grpc.ServiceRegistrar, srv TaskServiceServer) {
-	s.RegisterService(&TaskService_ServiceDesc, srv)
+func _AnalyticsService_GetActivityHeatmap_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetActivityHeatmapRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AnalyticsServiceServer).GetActivityHeatmap(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AnalyticsService_GetActivityHeatmap_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AnalyticsServiceServer).GetActivityHeatmap(ctx, req.(*GetActivityHeatmapRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func _TaskService_CreateTask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(CreateTaskRequest)
+func _AnalyticsService_GetActiveUsers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetActiveUsersRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(TaskServiceServer).CreateTask(ctx, in)
+		return srv.(AnalyticsServiceServer).GetActiveUsers(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: TaskService_CreateTask_FullMethodName,
+		FullMethod: AnalyticsService_GetActiveUsers_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(TaskServiceServer).CreateTask(ctx, req.(*CreateTaskRequest))
+		return srv.(AnalyticsServiceServer).GetActiveUsers(ctx, req.(*GetActiveUsersRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _TaskService_GetTask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(GetTaskRequest)
+func _AnalyticsService_GetCompletionLatency_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCompletionLatencyRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(TaskServiceServer).GetTask(ctx, in)
+		return srv.(AnalyticsServiceServer).GetCompletionLatency(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: TaskService_GetTask_FullMethodName,
+		FullMethod: AnalyticsService_GetCompletionLatency_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(TaskServiceServer).GetTask(ctx, req.(*GetTaskRequest))
+		return srv.(AnalyticsServiceServer).GetCompletionLatency(ctx, req.(*GetCompletionLatencyRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _TaskService_UpdateTask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(UpdateTaskRequest)
+func _AnalyticsService_GetRetentionStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRetentionStatusRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(TaskServiceServer).UpdateTask(ctx, in)
+		return srv.(AnalyticsServiceServer).GetRetentionStatus(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: TaskService_UpdateTask_FullMethodName,
+		FullMethod: AnalyticsService_GetRetentionStatus_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(TaskServiceServer).UpdateTask(ctx, req.(*UpdateTaskRequest))
+		return srv.(AnalyticsServiceServer).GetRetentionStatus(ctx, req.(*GetRetentionStatusRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _TaskService_DeleteTask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(DeleteTaskRequest)
+func _AnalyticsService_GenerateWeeklySummary_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GenerateWeeklySummaryRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(TaskServiceServer).DeleteTask(ctx, in)
+		return srv.(AnalyticsServiceServer).GenerateWeeklySummary(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: TaskService_DeleteTask_FullMethodName,
+		FullMethod: AnalyticsService_GenerateWeeklySummary_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(TaskServiceServer).DeleteTask(ctx, req.(*DeleteTaskRequest))
+		return srv.(AnalyticsServiceServer).GenerateWeeklySummary(ctx, req.(*GenerateWeeklySummaryRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _TaskService_ListTasks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(ListTasksRequest)
+func _AnalyticsService_GetTaskBreakdown_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTaskBreakdownRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(TaskServiceServer).ListTasks(ctx, in)
+		return srv.(AnalyticsServiceServer).GetTaskBreakdown(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: TaskService_ListTasks_FullMethodName,
+		FullMethod: AnalyticsService_GetTaskBreakdown_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(TaskServiceServer).ListTasks(ctx, req.(*ListTasksRequest))
+		return srv.(AnalyticsServiceServer).GetTaskBreakdown(ctx, req.(*GetTaskBreakdownRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-// TaskService_ServiceDesc is the grpc.ServiceDesc for TaskService service.
-// It's only intended for direct use with grpc.RegisterService,
-// and not to be introspected or modified (even as a copy)
-var TaskService_ServiceDesc = grpc.ServiceDesc{
-	ServiceName: "todo.TaskService",
-	HandlerType: (*TaskServiceServer)(nil),
-	Methods: []grpc.MethodDesc{
-		{
-			MethodName: "CreateTask",
-			Handler:    _TaskService_CreateTask_Handler,
-		},
-		{
-			MethodName: "GetTask",
-			Handler:    _TaskService_GetTask_Handler,
-		},
-		{
-			MethodName: "UpdateTask",
-			Handler:    _TaskService_UpdateTask_Handler,
-		},
-		{
-			MethodName: "DeleteTask",
-			Handler:    _TaskService_DeleteTask_Handler,
-		},
-		{
-			MethodName: "ListTasks",
-			Handler:    _TaskService_ListTasks_Handler,
-		},
-	},
-	Streams:  []grpc.StreamDesc{},
-	Metadata: "proto/todo.proto",
+func _AnalyticsService_GetCacheStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCacheStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AnalyticsServiceServer).GetCacheStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AnalyticsService_GetCacheStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AnalyticsServiceServer).GetCacheStats(ctx, req.(*GetCacheStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-const (
-	UserService_CreateUser_FullMethodName       = "/todo.UserService/CreateUser"
-	UserService_GetUser_FullMethodName          = "/todo.UserService/GetUser"
-	UserService_UpdateUser_FullMethodName       = "/todo.UserService/UpdateUser"
-	UserService_DeleteUser_FullMethodName       = "/todo.UserService/DeleteUser"
-	UserService_AuthenticateUser_FullMethodName = "/todo.UserService/AuthenticateUser"
-)
+func _AnalyticsService_StreamEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AnalyticsServiceServer).StreamEvents(m, &analyticsServiceStreamEventsServer{stream})
+}
 
-// UserServiceClient is the client API for UserService service.
-//
-// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
-type UserServiceClient interface {
-	CreateUser(ctx context.Context, in *CreateUserRequest, opts ...grpc.CallOption) (*UserResponse, error)
-	GetUser(ctx context.Context, in *GetUserRequest, opts ...grpc.CallOption) (*UserResponse, error)
-	UpdateUser(ctx context.Context, in *UpdateUserRequest, opts ...grpc.CallOption) (*UserResponse, error)
-	DeleteUser(ctx context.Context, in *DeleteUserRequest, opts ...grpc.CallOption) (*DeleteUserResponse, error)
-	AuthenticateUser(ctx context.Context, in *AuthRequest, opts ...grpc.CallOption) (*AuthResponse, error)
+type AnalyticsService_StreamEventsServer interface {
+	Send(*StreamEventsResponse) error
+	grpc.ServerStream
 }
 
-type userServiceClient struct {
-	cc grpc.ClientConnInterface
+type analyticsServiceStreamEventsServer struct {
+	grpc.ServerStream
 }
 
-func NewUserServiceClient(cc grpc.ClientConnInterface) UserServiceClient {
-	return &userServiceClient{cc}
+func (x *analyticsServiceStreamEventsServer) Send(m *StreamEventsResponse) error {
+	return x.ServerStream.SendMsg(m)
 }
 
-func (c *userServiceClient) CreateUser(ctx context.Context, in *CreateUserRequest, opts ...grpc.CallOption) (*UserResponse, error) {
-	out := new(UserResponse)
-	err := c.cc.Invoke(ctx, UserService_CreateUser_FullMethodName, in, out, opts...)
-	if err != nil {
-		return nil, err
+func _AnalyticsService_SubscribeToEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
 	}
-	return out, nil
+	return srv.(AnalyticsServiceServer).SubscribeToEvents(m, &analyticsServiceSubscribeToEventsServer{stream})
 }
 
-func (c *userServiceClient) GetUser(ctx context.Context, in *GetUserRequest, opts ...grpc.CallOption) (*UserResponse, error) {
-	out := new(UserResponse)
-	err := c.cc.Invoke(ctx, UserService_GetUser_FullMethodName, in, out, opts...)
-	if err != nil {
-		return nil, err
-	}
-	return out, nil
+type AnalyticsService_SubscribeToEventsServer interface {
+	Send(*Event) error
+	grpc.ServerStream
 }
 
-func (c *userServiceClient) UpdateUser(ctx context.Context, in *UpdateUserRequest, opts ...grpc.CallOption) (*UserResponse, error) {
-	out := new(UserResponse)
-	err := c.cc.Invoke(ctx, UserService_UpdateUser_FullMethodName, in, out, opts...)
-	if err != nil {
-		return nil, err
-	}
-	return out, nil
+type analyticsServiceSubscribeToEventsServer struct {
+	grpc.ServerStream
 }
 
-func (c *userServiceClient) DeleteUser(ctx context.Context, in *DeleteUserRequest, opts ...grpc.CallOption) (*DeleteUserResponse, error) {
-	out := new(DeleteUserResponse)
-	err := c.cc.Invoke(ctx, UserService_DeleteUser_FullMethodName, in, out, opts...)
-	if err != nil {
+func (x *analyticsServiceSubscribeToEventsServer) Send(m *Event) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _AnalyticsService_GetSystemOverview_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSystemOverviewRequest)
+	if err := dec(in); err != nil {
 		return nil, err
 	}
-	return out, nil
+	if interceptor == nil {
+		return srv.(AnalyticsServiceServer).GetSystemOverview(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AnalyticsService_GetSystemOverview_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AnalyticsServiceServer).GetSystemOverview(ctx, req.(*GetSystemOverviewRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (c *userServiceClient) AuthenticateUser(ctx context.Context, in *AuthRequest, opts ...grpc.CallOption) (*AuthResponse, error) {
-	out := new(AuthResponse)
-	err := c.cc.Invoke(ctx, UserService_AuthenticateUser_FullMethodName, in, out, opts...)
-	if err != nil {
+func _AnalyticsService_GetOverdueAging_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetOverdueAgingRequest)
+	if err := dec(in); err != nil {
 		return nil, err
 	}
-	return out, nil
+	if interceptor == nil {
+		return srv.(AnalyticsServiceServer).GetOverdueAging(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AnalyticsService_GetOverdueAging_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AnalyticsServiceServer).GetOverdueAging(ctx, req.(*GetOverdueAgingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-// UserServiceServer is the server API for UserService service.
-// All implementations must embed UnimplementedUserServiceServer
-// for forward compatibility
-type UserServiceServer interface {
-	CreateUser(context.Context, *CreateUserRequest) (*UserResponse, error)
-	GetUser(context.Context, *GetUserRequest) (*UserResponse, error)
-	UpdateUser(context.Context, *UpdateUserRequest) (*UserResponse, error)
-	DeleteUser(context.Context, *DeleteUserRequest) (*DeleteUserResponse, error)
-	AuthenticateUser(context.Context, *AuthRequest) (*AuthResponse, error)
-	mustEmbedUnimplementedUserServiceServer()
+func _AnalyticsService_ExportStats_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ExportStatsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AnalyticsServiceServer).ExportStats(m, &analyticsServiceExportStatsServer{stream})
 }
 
-// UnimplementedUserServiceServer must be embedded to have forward compatible implementations.
-type UnimplementedUserServiceServer struct {
+type AnalyticsService_ExportStatsServer interface {
+	Send(*ExportStatsChunk) error
+	grpc.ServerStream
 }
 
-func (UnimplementedUserServiceServer) CreateUser(context.Context, *CreateUserRequest) (*UserResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method CreateUser not implemented")
-}
-func (UnimplementedUserServiceServer) GetUser(context.Context, *GetUserRequest) (*UserResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method GetUser not implemented")
-}
-func (UnimplementedUserServiceServer) UpdateUser(context.Context, *UpdateUserRequest) (*UserResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method UpdateUser not implemented")
+type analyticsServiceExportStatsServer struct {
+	grpc.ServerStream
 }
-func (UnimplementedUserServiceServer) DeleteUser(context.Context, *DeleteUserRequest) (*DeleteUserResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method DeleteUser not implemented")
-}
-func (UnimplementedUserServiceServer) AuthenticateUser(context.Context, *AuthRequest) (*AuthResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method AuthenticateUser not implemented")
+
+func (x *analyticsServiceExportStatsServer) Send(m *ExportStatsChunk) error {
+	return x.ServerStream.SendMsg(m)
 }
-func (UnimplementedUserServiceServer) mustEmbedUnimplementedUserServiceServer() {}
 
-// UnsafeUserServiceServer may be embedded to opt out of forward compatibility for this service.
-// Use of this interface is not recommended, as added methods to UserServiceServer will
-// result in compilation errors.
-type UnsafeUserServiceServer interface {
-	mustEmbedUnimplementedUserServiceServer()
+func _AnalyticsService_GetTimeReport_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTimeReportRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AnalyticsServiceServer).GetTimeReport(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AnalyticsService_GetTimeReport_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AnalyticsServiceServer).GetTimeReport(ctx, req.(*GetTimeReportRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func RegisterUserServiceServer(s grpc.ServiceRegistrar, srv UserServiceServer) {
-	s.RegisterService(&UserService_ServiceDesc, srv)
+func _AnalyticsService_DeleteUserEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteUserEventsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AnalyticsServiceServer).DeleteUserEvents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AnalyticsService_DeleteUserEvents_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AnalyticsServiceServer).DeleteUserEvents(ctx, req.(*DeleteUserEventsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func _UserService_CreateUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(CreateUserRequest)
+func _AnalyticsService_AnonymizeUserEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AnonymizeUserEventsRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(UserServiceServer).CreateUser(ctx, in)
+		return srv.(AnalyticsServiceServer).AnonymizeUserEvents(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: UserService_CreateUser_FullMethodName,
+		FullMethod: AnalyticsService_AnonymizeUserEvents_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(UserServiceServer).CreateUser(ctx, req.(*CreateUserRequest))
+		return srv.(AnalyticsServiceServer).AnonymizeUserEvents(ctx, req.(*AnonymizeUserEventsRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _UserService_GetUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(GetUserRequest)
+func _AnalyticsService_CompareUsersProductivity_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CompareUsersRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(UserServiceServer).GetUser(ctx, in)
+		return srv.(AnalyticsServiceServer).CompareUsersProductivity(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: UserService_GetUser_FullMethodName,
+		FullMethod: AnalyticsService_CompareUsersProductivity_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(UserServiceServer).GetUser(ctx, req.(*GetUserRequest))
+		return srv.(AnalyticsServiceServer).CompareUsersProductivity(ctx, req.(*CompareUsersRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _UserService_UpdateUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(UpdateUserRequest)
+func _AnalyticsService_GetCompletionForecast_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCompletionForecastRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(UserServiceServer).UpdateUser(ctx, in)
+		return srv.(AnalyticsServiceServer).GetCompletionForecast(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: UserService_UpdateUser_FullMethodName,
+		FullMethod: AnalyticsService_GetCompletionForecast_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(UserServiceServer).UpdateUser(ctx, req.(*UpdateUserRequest))
+		return srv.(AnalyticsServiceServer).GetCompletionForecast(ctx, req.(*GetCompletionForecastRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _UserService_DeleteUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(DeleteUserRequest)
+func _AnalyticsService_GetBurndown_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetBurndownRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(UserServiceServer).DeleteUser(ctx, in)
+		return srv.(AnalyticsServiceServer).GetBurndown(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: UserService_DeleteUser_FullMethodName,
+		FullMethod: AnalyticsService_GetBurndown_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(UserServiceServer).DeleteUser(ctx, req.(*DeleteUserRequest))
+		return srv.(AnalyticsServiceServer).GetBurndown(ctx, req.(*GetBurndownRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _UserService_AuthenticateUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(AuthRequest)
+func _AnalyticsService_GetPriorityBreakdown_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPriorityBreakdownRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(UserServiceServer).AuthenticateUser(ctx, in)
+		return srv.(AnalyticsServiceServer).GetPriorityBreakdown(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: UserService_AuthenticateUser_FullMethodName,
+		FullMethod: AnalyticsService_GetPriorityBreakdown_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(UserServiceServer).AuthenticateUser(ctx, req.(*AuthRequest))
+		return srv.(AnalyticsServiceServer).GetPriorityBreakdown(ctx, req.(*GetPriorityBreakdownRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-// UserService_ServiceDesc is the grpc.ServiceDesc for UserService service.
+// AnalyticsService_ServiceDesc is the grpc.ServiceDesc for AnalyticsService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
-var UserService_ServiceDesc = grpc.ServiceDesc{
-	ServiceName: "todo.UserService",
-	HandlerType: (*UserServiceServer)(nil),
+var AnalyticsService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "todo.AnalyticsService",
+	HandlerType: (*AnalyticsServiceServer)(nil),
 	Methods: []grpc.MethodDesc{
 		{
-			MethodName: "CreateUser",
-			Handler:    _UserService_CreateUser_Handler,
+			MethodName: "TrackEvent",
+			Handler:    _AnalyticsService_TrackEvent_Handler,
 		},
 		{
-			MethodName: "GetUser",
-			Handler:    _UserService_GetUser_Handler,
+			MethodName: "TrackEvents",
+			Handler:    _AnalyticsService_TrackEvents_Handler,
 		},
 		{
-			MethodName: "UpdateUser",
-			Handler:    _UserService_UpdateUser_Handler,
+			MethodName: "GetUserStats",
+			Handler:    _AnalyticsService_GetUserStats_Handler,
 		},
 		{
-			MethodName: "DeleteUser",
-			Handler:    _UserService_DeleteUser_Handler,
+			MethodName: "GetTaskStats",
+			Handler:    _AnalyticsService_GetTaskStats_Handler,
 		},
 		{
-			MethodName: "AuthenticateUser",
-			Handler:    _UserService_AuthenticateUser_Handler,
+			MethodName: "GetPeakHours",
+			Handler:    _AnalyticsService_GetPeakHours_Handler,
+		},
+		{
+			MethodName: "GetCompletionTrend",
+			Handler:    _AnalyticsService_GetCompletionTrend_Handler,
+		},
+		{
+			MethodName: "BackfillAnalytics",
+			Handler:    _AnalyticsService_BackfillAnalytics_Handler,
+		},
+		{
+			MethodName: "GetEngagementScore",
+			Handler:    _AnalyticsService_GetEngagementScore_Handler,
+		},
+		{
+			MethodName: "GetUserStreak",
+			Handler:    _AnalyticsService_GetUserStreak_Handler,
+		},
+		{
+			MethodName: "GetActivityHeatmap",
+			Handler:    _AnalyticsService_GetActivityHeatmap_Handler,
+		},
+		{
+			MethodName: "GetActiveUsers",
+			Handler:    _AnalyticsService_GetActiveUsers_Handler,
+		},
+		{
+			MethodName: "GetCompletionLatency",
+			Handler:    _AnalyticsService_GetCompletionLatency_Handler,
+		},
+		{
+			MethodName: "GetRetentionStatus",
+			Handler:    _AnalyticsService_GetRetentionStatus_Handler,
+		},
+		{
+			MethodName: "GenerateWeeklySummary",
+			Handler:    _AnalyticsService_GenerateWeeklySummary_Handler,
+		},
+		{
+			MethodName: "GetTaskBreakdown",
+			Handler:    _AnalyticsService_GetTaskBreakdown_Handler,
+		},
+		{
+			MethodName: "GetCacheStats",
+			Handler:    _AnalyticsService_GetCacheStats_Handler,
+		},
+		{
+			MethodName: "GetSystemOverview",
+			Handler:    _AnalyticsService_GetSystemOverview_Handler,
+		},
+		{
+			MethodName: "GetOverdueAging",
+			Handler:    _AnalyticsService_GetOverdueAging_Handler,
+		},
+		{
+			MethodName: "GetTimeReport",
+			Handler:    _AnalyticsService_GetTimeReport_Handler,
+		},
+		{
+			MethodName: "DeleteUserEvents",
+			Handler:    _AnalyticsService_DeleteUserEvents_Handler,
+		},
+		{
+			MethodName: "AnonymizeUserEvents",
+			Handler:    _AnalyticsService_AnonymizeUserEvents_Handler,
+		},
+		{
+			MethodName: "CompareUsersProductivity",
+			Handler:    _AnalyticsService_CompareUsersProductivity_Handler,
+		},
+		{
+			MethodName: "GetCompletionForecast",
+			Handler:    _AnalyticsService_GetCompletionForecast_Handler,
+		},
+		{
+			MethodName: "GetBurndown",
+			Handler:    _AnalyticsService_GetBurndown_Handler,
+		},
+		{
+			MethodName: "GetPriorityBreakdown",
+			Handler:    _AnalyticsService_GetPriorityBreakdown_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ReplayEvents",
+			Handler:       _AnalyticsService_ReplayEvents_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "StreamEvents",
+			Handler:       _AnalyticsService_StreamEvents_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "SubscribeToEvents",
+			Handler:       _AnalyticsService_SubscribeToEvents_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "ExportStats",
+			Handler:       _AnalyticsService_ExportStats_Handler,
+			ServerStreams: true,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/todo.proto",
 }
 
 const (
-	NotificationService_SendNotification_FullMethodName = "/todo.NotificationService/SendNotification"
-	NotificationService_GetNotifications_FullMethodName = "/todo.NotificationService/GetNotifications"
+	FaultService_GetFaults_FullMethodName = "/todo.FaultService/GetFaults"
+	FaultService_SetFaults_FullMethodName = "/todo.FaultService/SetFaults"
 )
 
-// NotificationServiceClient is the client API for NotificationService service.
+// FaultServiceClient is the client API for FaultService service.
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
-type NotificationServiceClient interface {
-	SendNotification(ctx context.Context, in *NotificationRequest, opts ...grpc.CallOption) (*NotificationResponse, error)
-	GetNotifications(ctx context.Context, in *GetNotificationsRequest, opts ...grpc.CallOption) (*GetNotificationsResponse, error)
+type FaultServiceClient interface {
+	GetFaults(ctx context.Context, in *GetFaultsRequest, opts ...grpc.CallOption) (*FaultsResponse, error)
+	SetFaults(ctx context.Context, in *SetFaultsRequest, opts ...grpc.CallOption) (*FaultsResponse, error)
 }
 
-type notificationServiceClient struct {
+type faultServiceClient struct {
 	cc grpc.ClientConnInterface
 }
 
-func NewNotificationServiceClient(cc grpc.ClientConnInterface) NotificationServiceClient {
-	return &notificationServiceClient{cc}
+func NewFaultServiceClient(cc grpc.ClientConnInterface) FaultServiceClient {
+	return &faultServiceClient{cc}
 }
 
-func (c *notificationServiceClient) SendNotification(ctx context.Context, in *NotificationRequest, opts ...grpc.CallOption) (*NotificationResponse, error) {
-	out := new(NotificationResponse)
-	err := c.cc.Invoke(ctx, NotificationService_SendNotification_FullMethodName, in, out, opts...)
+func (c *faultServiceClient) GetFaults(ctx context.Context, in *GetFaultsRequest, opts ...grpc.CallOption) (*FaultsResponse, error) {
+	out := new(FaultsResponse)
+	err := c.cc.Invoke(ctx, FaultService_GetFaults_FullMethodName, in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func (c *notificationServiceClient) GetNotifications(ctx context.Context, in *GetNotificationsRequest, opts ...grpc.CallOption) (*GetNotificationsResponse, error) {
-	out := new(GetNotificationsResponse)
-	err := c.cc.Invoke(ctx, NotificationService_GetNotifications_FullMethodName, in, out, opts...)
+func (c *faultServiceClient) SetFaults(ctx context.Context, in *SetFaultsRequest, opts ...grpc.CallOption) (*FaultsResponse, error) {
+	out := new(FaultsResponse)
+	err := c.cc.Invoke(ctx, FaultService_SetFaults_FullMethodName, in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-// NotificationServiceServer is the server API for NotificationService service.
-// All implementations must embed UnimplementedNotificationServiceServer
+// FaultServiceServer is the server API for FaultService service.
+// All implementations must embed UnimplementedFaultServiceServer
 // for forward compatibility
-type NotificationServiceServer interface {
-	SendNotification(context.Context, *NotificationRequest) (*NotificationResponse, error)
-	GetNotifications(context.Context, *GetNotificationsRequest) (*GetNotificationsResponse, error)
-	mustEmbedUnimplementedNotificationServiceServer()
+type FaultServiceServer interface {
+	GetFaults(context.Context, *GetFaultsRequest) (*FaultsResponse, error)
+	SetFaults(context.Context, *SetFaultsRequest) (*FaultsResponse, error)
+	mustEmbedUnimplementedFaultServiceServer()
 }
 
-// UnimplementedNotificationServiceServer must be embedded to have forward compatible implementations.
-type UnimplementedNotificationServiceServer struct {
+// UnimplementedFaultServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedFaultServiceServer struct {
 }
 
-func (UnimplementedNotificationServiceServer) SendNotification(context.Context, *NotificationRequest) (*NotificationResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method SendNotification not implemented")
+func (UnimplementedFaultServiceServer) GetFaults(context.Context, *GetFaultsRequest) (*FaultsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetFaults not implemented")
 }
-func (UnimplementedNotificationServiceServer) GetNotifications(context.Context, *GetNotificationsRequest) (*GetNotificationsResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method GetNotifications not implemented")
+func (UnimplementedFaultServiceServer) SetFaults(context.Context, *SetFaultsRequest) (*FaultsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetFaults not implemented")
 }
-func (UnimplementedNotificationServiceServer) mustEmbedUnimplementedNotificationServiceServer() {}
+func (UnimplementedFaultServiceServer) mustEmbedUnimplementedFaultServiceServer() {}
 
-// UnsafeNotificationServiceServer may be embedded to opt out of forward compatibility for this service.
-// Use of this interface is not recommended, as added methods to NotificationServiceServer will
+// UnsafeFaultServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to FaultServiceServer will
 // result in compilation errors.
-type UnsafeNotificationServiceServer interface {
-	mustEmbedUnimplementedNotificationServiceServer()
+type UnsafeFaultServiceServer interface {
+	mustEmbedUnimplementedFaultServiceServer()
 }
 
-func RegisterNotificationServiceServer(s grpc.ServiceRegistrar, srv NotificationServiceServer) {
-	s.RegisterService(&NotificationService_ServiceDesc, srv)
+func RegisterFaultServiceServer(s grpc.ServiceRegistrar, srv FaultServiceServer) {
+	s.RegisterService(&FaultService_ServiceDesc, srv)
 }
 
-func _NotificationService_SendNotification_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(NotificationRequest)
+func _FaultService_GetFaults_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetFaultsRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(NotificationServiceServer).SendNotification(ctx, in)
+		return srv.(FaultServiceServer).GetFaults(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: NotificationService_SendNotification_FullMethodName,
+		FullMethod: FaultService_GetFaults_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(NotificationServiceServer).SendNotification(ctx, req.(*NotificationRequest))
+		return srv.(FaultServiceServer).GetFaults(ctx, req.(*GetFaultsRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _NotificationService_GetNotifications_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(GetNotificationsRequest)
+func _FaultService_SetFaults_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetFaultsRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(NotificationServiceServer).GetNotifications(ctx, in)
+		return srv.(FaultServiceServer).SetFaults(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: NotificationService_GetNotifications_FullMethodName,
+		FullMethod: FaultService_SetFaults_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(NotificationServiceServer).GetNotifications(ctx, req.(*GetNotificationsRequest))
+		return srv.(FaultServiceServer).SetFaults(ctx, req.(*SetFaultsRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-// NotificationService_ServiceDesc is the grpc.ServiceDesc for NotificationService service.
+// FaultService_ServiceDesc is the grpc.ServiceDesc for FaultService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
-var NotificationService_ServiceDesc = grpc.ServiceDesc{
-	ServiceName: "todo.NotificationService",
-	HandlerType: (*NotificationServiceServer)(nil),
+var FaultService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "todo.FaultService",
+	HandlerType: (*FaultServiceServer)(nil),
 	Methods: []grpc.MethodDesc{
 		{
-			MethodName: "SendNotification",
-			Handler:    _NotificationService_SendNotification_Handler,
+			MethodName: "GetFaults",
+			Handler:    _FaultService_GetFaults_Handler,
 		},
 		{
-			MethodName: "GetNotifications",
-			Handler:    _NotificationService_GetNotifications_Handler,
+			MethodName: "SetFaults",
+			Handler:    _FaultService_SetFaults_Handler,
 		},
 	},
 	Streams:  []grpc.StreamDesc{},
@@ -623,163 +3752,179 @@ var NotificationService_ServiceDesc = grpc.ServiceDesc{
 }
 
 const (
-	AnalyticsService_TrackEvent_FullMethodName   = "/todo.AnalyticsService/TrackEvent"
-	AnalyticsService_GetUserStats_FullMethodName = "/todo.AnalyticsService/GetUserStats"
-	AnalyticsService_GetTaskStats_FullMethodName = "/todo.AnalyticsService/GetTaskStats"
+	InfoService_GetServiceInfo_FullMethodName = "/todo.InfoService/GetServiceInfo"
 )
 
-// AnalyticsServiceClient is the client API for AnalyticsService service.
+// InfoServiceClient is the client API for InfoService service.
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
-type AnalyticsServiceClient interface {
-	TrackEvent(ctx context.Context, in *TrackEventRequest, opts ...grpc.CallOption) (*TrackEventResponse, error)
-	GetUserStats(ctx context.Context, in *GetUserStatsRequest, opts ...grpc.CallOption) (*GetUserStatsResponse, error)
-	GetTaskStats(ctx context.Context, in *GetTaskStatsRequest, opts ...grpc.CallOption) (*GetTaskStatsResponse, error)
+type InfoServiceClient interface {
+	GetServiceInfo(ctx context.Context, in *GetServiceInfoRequest, opts ...grpc.CallOption) (*ServiceInfo, error)
 }
 
-type analyticsServiceClient struct {
+type infoServiceClient struct {
 	cc grpc.ClientConnInterface
 }
 
-func NewAnalyticsServiceClient(cc grpc.ClientConnInterface) AnalyticsServiceClient {
-	return &analyticsServiceClient{cc}
-}
-
-func (c *analyticsServiceClient) TrackEvent(ctx context.Context, in *TrackEventRequest, opts ...grpc.CallOption) (*TrackEventResponse, error) {
-	out := new(TrackEventResponse)
-	err := c.cc.Invoke(ctx, AnalyticsService_TrackEvent_FullMethodName, in, out, opts...)
-	if err != nil {
-		return nil, err
-	}
-	return out, nil
-}
-
-func (c *analyticsServiceClient) GetUserStats(ctx context.Context, in *GetUserStatsRequest, opts ...grpc.CallOption) (*GetUserStatsResponse, error) {
-	out := new(GetUserStatsResponse)
-	err := c.cc.Invoke(ctx, AnalyticsService_GetUserStats_FullMethodName, in, out, opts...)
-	if err != nil {
-		return nil, err
-	}
-	return out, nil
+func NewInfoServiceClient(cc grpc.ClientConnInterface) InfoServiceClient {
+	return &infoServiceClient{cc}
 }
 
-func (c *analyticsServiceClient) GetTaskStats(ctx context.Context, in *GetTaskStatsRequest, opts ...grpc.CallOption) (*GetTaskStatsResponse, error) {
-	out := new(GetTaskStatsResponse)
-	err := c.cc.Invoke(ctx, AnalyticsService_GetTaskStats_FullMethodName, in, out, opts...)
+func (c *infoServiceClient) GetServiceInfo(ctx context.Context, in *GetServiceInfoRequest, opts ...grpc.CallOption) (*ServiceInfo, error) {
+	out := new(ServiceInfo)
+	err := c.cc.Invoke(ctx, InfoService_GetServiceInfo_FullMethodName, in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-// AnalyticsServiceServer is the server API for AnalyticsService service.
-// All implementations must embed UnimplementedAnalyticsServiceServer
+// InfoServiceServer is the server API for InfoService service.
+// All implementations must embed UnimplementedInfoServiceServer
 // for forward compatibility
-type AnalyticsServiceServer interface {
-	TrackEvent(context.Context, *TrackEventRequest) (*TrackEventResponse, error)
-	GetUserStats(context.Context, *GetUserStatsRequest) (*GetUserStatsResponse, error)
-	GetTaskStats(context.Context, *GetTaskStatsRequest) (*GetTaskStatsResponse, error)
-	mustEmbedUnimplementedAnalyticsServiceServer()
+type InfoServiceServer interface {
+	GetServiceInfo(context.Context, *GetServiceInfoRequest) (*ServiceInfo, error)
+	mustEmbedUnimplementedInfoServiceServer()
 }
 
-// UnimplementedAnalyticsServiceServer must be embedded to have forward compatible implementations.
-type UnimplementedAnalyticsServiceServer struct {
+// UnimplementedInfoServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedInfoServiceServer struct {
 }
 
-func (UnimplementedAnalyticsServiceServer) TrackEvent(context.Context, *TrackEventRequest) (*TrackEventResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method TrackEvent not implemented")
-}
-func (UnimplementedAnalyticsServiceServer) GetUserStats(context.Context, *GetUserStatsRequest) (*GetUserStatsResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method GetUserStats not implemented")
+func (UnimplementedInfoServiceServer) GetServiceInfo(context.Context, *GetServiceInfoRequest) (*ServiceInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetServiceInfo not implemented")
 }
-func (UnimplementedAnalyticsServiceServer) GetTaskStats(context.Context, *GetTaskStatsRequest) (*GetTaskStatsResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method GetTaskStats not implemented")
-}
-func (UnimplementedAnalyticsServiceServer) mustEmbedUnimplementedAnalyticsServiceServer() {}
+func (UnimplementedInfoServiceServer) mustEmbedUnimplementedInfoServiceServer() {}
 
-// UnsafeAnalyticsServiceServer may be embedded to opt out of forward compatibility for this service.
-// Use of this interface is not recommended, as added methods to AnalyticsServiceServer will
+// UnsafeInfoServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to InfoServiceServer will
 // result in compilation errors.
-type UnsafeAnalyticsServiceServer interface {
-	mustEmbedUnimplementedAnalyticsServiceServer()
+type UnsafeInfoServiceServer interface {
+	mustEmbedUnimplementedInfoServiceServer()
 }
 
-func RegisterAnalyticsServiceServer(s grpc.ServiceRegistrar, srv AnalyticsServiceServer) {
-	s.RegisterService(&AnalyticsService_ServiceDesc, srv)
+func RegisterInfoServiceServer(s grpc.ServiceRegistrar, srv InfoServiceServer) {
+	s.RegisterService(&InfoService_ServiceDesc, srv)
 }
 
-func _AnalyticsService_TrackEvent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(TrackEventRequest)
+func _InfoService_GetServiceInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetServiceInfoRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(AnalyticsServiceServer).TrackEvent(ctx, in)
+		return srv.(InfoServiceServer).GetServiceInfo(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: AnalyticsService_TrackEvent_FullMethodName,
+		FullMethod: InfoService_GetServiceInfo_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(AnalyticsServiceServer).TrackEvent(ctx, req.(*TrackEventRequest))
+		return srv.(InfoServiceServer).GetServiceInfo(ctx, req.(*GetServiceInfoRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _AnalyticsService_GetUserStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(GetUserStatsRequest)
-	if err := dec(in); err != nil {
+// InfoService_ServiceDesc is the grpc.ServiceDesc for InfoService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var InfoService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "todo.InfoService",
+	HandlerType: (*InfoServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetServiceInfo",
+			Handler:    _InfoService_GetServiceInfo_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "proto/todo.proto",
+}
+
+const (
+	BackupService_BackupCollection_FullMethodName = "/todo.BackupService/BackupCollection"
+)
+
+// BackupServiceClient is the client API for BackupService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type BackupServiceClient interface {
+	BackupCollection(ctx context.Context, in *BackupRequest, opts ...grpc.CallOption) (*BackupResponse, error)
+}
+
+type backupServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewBackupServiceClient(cc grpc.ClientConnInterface) BackupServiceClient {
+	return &backupServiceClient{cc}
+}
+
+func (c *backupServiceClient) BackupCollection(ctx context.Context, in *BackupRequest, opts ...grpc.CallOption) (*BackupResponse, error) {
+	out := new(BackupResponse)
+	err := c.cc.Invoke(ctx, BackupService_BackupCollection_FullMethodName, in, out, opts...)
+	if err != nil {
 		return nil, err
 	}
-	if interceptor == nil {
-		return srv.(AnalyticsServiceServer).GetUserStats(ctx, in)
-	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: AnalyticsService_GetUserStats_FullMethodName,
-	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(AnalyticsServiceServer).GetUserStats(ctx, req.(*GetUserStatsRequest))
-	}
-	return interceptor(ctx, in, info, handler)
+	return out, nil
 }
 
-func _AnalyticsService_GetTaskStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(GetTaskStatsRequest)
+// BackupServiceServer is the server API for BackupService service.
+// All implementations must embed UnimplementedBackupServiceServer
+// for forward compatibility
+type BackupServiceServer interface {
+	BackupCollection(context.Context, *BackupRequest) (*BackupResponse, error)
+	mustEmbedUnimplementedBackupServiceServer()
+}
+
+// UnimplementedBackupServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedBackupServiceServer struct {
+}
+
+func (UnimplementedBackupServiceServer) BackupCollection(context.Context, *BackupRequest) (*BackupResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BackupCollection not implemented")
+}
+func (UnimplementedBackupServiceServer) mustEmbedUnimplementedBackupServiceServer() {}
+
+// UnsafeBackupServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to BackupServiceServer will
+// result in compilation errors.
+type UnsafeBackupServiceServer interface {
+	mustEmbedUnimplementedBackupServiceServer()
+}
+
+func RegisterBackupServiceServer(s grpc.ServiceRegistrar, srv BackupServiceServer) {
+	s.RegisterService(&BackupService_ServiceDesc, srv)
+}
+
+func _BackupService_BackupCollection_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BackupRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(AnalyticsServiceServer).GetTaskStats(ctx, in)
+		return srv.(BackupServiceServer).BackupCollection(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: AnalyticsService_GetTaskStats_FullMethodName,
+		FullMethod: BackupService_BackupCollection_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(AnalyticsServiceServer).GetTaskStats(ctx, req.(*GetTaskStatsRequest))
+		return srv.(BackupServiceServer).BackupCollection(ctx, req.(*BackupRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-// AnalyticsService_ServiceDesc is the grpc.ServiceDesc for AnalyticsService service.
+// BackupService_ServiceDesc is the grpc.ServiceDesc for BackupService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
-var AnalyticsService_ServiceDesc = grpc.ServiceDesc{
-	ServiceName: "todo.AnalyticsService",
-	HandlerType: (*AnalyticsServiceServer)(nil),
+var BackupService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "todo.BackupService",
+	HandlerType: (*BackupServiceServer)(nil),
 	Methods: []grpc.MethodDesc{
 		{
-			MethodName: "TrackEvent",
-			Handler:    _AnalyticsService_TrackEvent_Handler,
-		},
-		{
-			MethodName: "GetUserStats",
-			Handler:    _AnalyticsService_GetUserStats_Handler,
-		},
-		{
-			MethodName: "GetTaskStats",
-			Handler:    _AnalyticsService_GetTaskStats_Handler,
+			MethodName: "BackupCollection",
+			Handler:    _BackupService_BackupCollection_Handler,
 		},
 	},
 	Streams:  []grpc.StreamDesc{},
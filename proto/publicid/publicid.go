@@ -0,0 +1,122 @@
+// Package publicid makes the ids the API shows for tasks, users and
+// notifications in place of their MongoDB ObjectIDs: a prefix naming the kind
+// of resource, an underscore and a ULID, such as
+// task_01HV4Z3K9Q8W5N2M7R6T1Y0XCB. A ULID starts with its creation time in
+// milliseconds, so ids sort by age, and ends in 80 random bits.
+//
+// Documents keep their ObjectID as _id and store the public id in Field,
+// under a unique index. While clients move over, the services accept a
+// document's ObjectID hex wherever they accept its public id; see Filter.
+package publicid
+
+import (
+	"crypto/rand"
+	"errors"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Prefixes of the kinds of resource with public ids
+const (
+	Task         = "task"
+	User         = "user"
+	Notification = "ntf"
+)
+
+// Field holds a document's public id.
+const Field = "public_id"
+
+// ErrInvalid is returned by Filter for an id that is neither a public id of
+// the kind asked for nor an ObjectID.
+var ErrInvalid = errors.New("invalid id")
+
+// Crockford's base32, which ULIDs are written in
+const alphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+const ulidLen = 26
+
+// New returns a new public id with prefix.
+func New(prefix string) string {
+	return At(prefix, time.Now())
+}
+
+// At returns a new public id with prefix created at t, so ids backfilled
+// for existing documents keep their order.
+func At(prefix string, t time.Time) string {
+	var random [10]byte
+	if _, err := rand.Read(random[:]); err != nil {
+		panic("publicid: reading random bytes: " + err.Error())
+	}
+
+	var id [ulidLen]byte
+	ms := uint64(t.UnixMilli())
+	for i := 9; i >= 0; i-- {
+		id[i] = alphabet[ms&31]
+		ms >>= 5
+	}
+	// The 80 random bits, five at a time
+	var bits uint64
+	var n uint
+	j := 10
+	for _, b := range random {
+		bits = bits<<8 | uint64(b)
+		n += 8
+		for n >= 5 {
+			n -= 5
+			id[j] = alphabet[(bits>>n)&31]
+			j++
+		}
+	}
+	return prefix + "_" + string(id[:])
+}
+
+// Valid reports whether id is a well-formed public id with prefix.
+func Valid(prefix, id string) bool {
+	ulid, ok := strings.CutPrefix(id, prefix+"_")
+	if !ok || len(ulid) != ulidLen || ulid[0] > '7' {
+		return false
+	}
+	for i := 0; i < len(ulid); i++ {
+		if strings.IndexByte(alphabet, ulid[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Filter returns the query for the document id names: its public id with
+// prefix, or during the transition its ObjectID hex.
+func Filter(prefix, id string) (bson.M, error) {
+	if Valid(prefix, id) {
+		return bson.M{Field: id}, nil
+	}
+	if oid, err := primitive.ObjectIDFromHex(id); err == nil {
+		return bson.M{"_id": oid}, nil
+	}
+	return nil, ErrInvalid
+}
+
+// Or returns the public id when set, and otherwise the ObjectID hex, for
+// documents created before public ids and not yet backfilled.
+func Or(publicID string, oid primitive.ObjectID) string {
+	if publicID != "" {
+		return publicID
+	}
+	return oid.Hex()
+}
+
+// Backfill returns the update of a data migration giving a document without
+// a public id one with prefix, dated by its ObjectID so the ids keep the
+// documents' order. Documents whose _id is not an ObjectID are left alone.
+func Backfill(prefix string) func(bson.Raw) (bson.M, error) {
+	return func(doc bson.Raw) (bson.M, error) {
+		oid, ok := doc.Lookup("_id").ObjectIDOK()
+		if !ok {
+			return nil, nil
+		}
+		return bson.M{"$set": bson.M{Field: At(prefix, oid.Timestamp())}}, nil
+	}
+}
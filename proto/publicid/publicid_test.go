@@ -0,0 +1,103 @@
+package publicid
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestNew(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 10000; i++ {
+		id := New(Task)
+		if !Valid(Task, id) {
+			t.Fatalf("New() = %q, which is not valid", id)
+		}
+		if seen[id] {
+			t.Fatalf("New() returned %q twice", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestAtSortsByTime(t *testing.T) {
+	base := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	var ids []string
+	for i := 0; i < 100; i++ {
+		ids = append(ids, At(Notification, base.Add(time.Duration(i)*time.Millisecond)))
+	}
+	if !sort.StringsAreSorted(ids) {
+		t.Errorf("ids of later times do not sort after earlier ones: %v", ids)
+	}
+	// The ULID of the Unix epoch is all zeros but for the random part
+	if id := At(User, time.UnixMilli(0)); id[:len("user_0000000000")] != "user_0000000000" {
+		t.Errorf("At(epoch) = %q, want a zero time part", id)
+	}
+}
+
+func TestValid(t *testing.T) {
+	id := New(Task)
+	for _, tt := range []struct {
+		prefix, id string
+		want       bool
+	}{
+		{Task, id, true},
+		{User, id, false},
+		{Task, "task_", false},
+		{Task, id + "0", false},
+		{Task, "task_01HV4Z3K9Q8W5N2M7R6T1Y0XCU", false}, // U is not in the alphabet
+		{Task, "task_81HV4Z3K9Q8W5N2M7R6T1Y0XCB", false}, // past the largest time
+		{Task, "task_01hv4z3k9q8w5n2m7r6t1y0xcb", false}, // lowercase
+		{Task, primitive.NewObjectID().Hex(), false},
+	} {
+		if got := Valid(tt.prefix, tt.id); got != tt.want {
+			t.Errorf("Valid(%q, %q) = %v, want %v", tt.prefix, tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	id := New(Task)
+	oid := primitive.NewObjectID()
+	for _, tt := range []struct {
+		id   string
+		want bson.M
+	}{
+		{id, bson.M{Field: id}},
+		{oid.Hex(), bson.M{"_id": oid}},
+	} {
+		got, err := Filter(Task, tt.id)
+		if err != nil || len(got) != 1 || got[Field] != tt.want[Field] || got["_id"] != tt.want["_id"] {
+			t.Errorf("Filter(%q) = %v, %v; want %v", tt.id, got, err, tt.want)
+		}
+	}
+	for _, bad := range []string{"", "nope", New(User)} {
+		if _, err := Filter(Task, bad); err != ErrInvalid {
+			t.Errorf("Filter(%q) = %v, want ErrInvalid", bad, err)
+		}
+	}
+}
+
+func TestBackfill(t *testing.T) {
+	oid := primitive.NewObjectIDFromTimestamp(time.Date(2024, 4, 1, 12, 0, 0, 0, time.UTC))
+	doc, err := bson.Marshal(bson.M{"_id": oid})
+	if err != nil {
+		t.Fatal(err)
+	}
+	update, err := Backfill(Task)(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, _ := update["$set"].(bson.M)[Field].(string)
+	if !Valid(Task, id) || id[len("task_"):][:10] != At(Task, oid.Timestamp())[len("task_"):][:10] {
+		t.Errorf("Backfill() set %q, want a task id from %v", id, oid.Timestamp())
+	}
+
+	doc, _ = bson.Marshal(bson.M{"_id": "settings"})
+	if update, err := Backfill(Task)(doc); update != nil || err != nil {
+		t.Errorf("Backfill() of a string _id = %v, %v; want nothing", update, err)
+	}
+}
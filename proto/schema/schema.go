@@ -0,0 +1,153 @@
+// Package schema versions the layout of the documents a service stores, so
+// replicas of two releases can share a database during a rolling deploy.
+//
+// Each document records the version of its layout in Field; documents
+// stored before versions were recorded are version 0. A Schema lists the
+// upgrades between versions, one per data migration of the collection, and
+// the service opens the collection with Decoding so every document decoded
+// into its types goes through them: a document older than the release is
+// upgraded on read the way its migration changes it, so code only sees the
+// current layout whether or not -migrate has run yet.
+//
+// A document newer than the release is decoded as it is, its unknown fields
+// ignored, which lets replicas of the previous release keep serving while
+// the next one rolls out and writes documents of its version. They only
+// update documents with $set and the like, which keep the fields they do
+// not know. A replica starting against such a database is another matter:
+// Check refuses collections holding documents newer than the release
+// understands, so a rollback past a migration fails loudly.
+package schema
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsoncodec"
+	"go.mongodb.org/mongo-driver/bson/bsonrw"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Field holds a document's schema version.
+const Field = "schema_version"
+
+// ErrTooNew is returned by Check for a collection holding documents newer
+// than the release.
+var ErrTooNew = errors.New("documents are newer than this release")
+
+// Upgrade changes doc, of the version before the upgrade's, to the
+// upgrade's layout. Documents that already have it, such as those a
+// migration's filter skipped and so still carry an older version, must be
+// left as they are.
+type Upgrade func(doc bson.M) error
+
+// Schema is the history of the layout of one collection's documents.
+type Schema struct {
+	// Collection names the collection, for errors
+	Collection string
+	// Upgrades[i] brings a document of version i to version i+1. A nil
+	// upgrade marks a change decoding copes with on its own, such as a
+	// field added or backfilled.
+	Upgrades []Upgrade
+}
+
+// Version is the schema version of the documents the release writes.
+func (s Schema) Version() int {
+	return len(s.Upgrades)
+}
+
+// Version returns the schema version of doc, 0 when it has none.
+func Version(doc bson.Raw) int {
+	value, err := doc.LookupErr(Field)
+	if err != nil {
+		return 0
+	}
+	if v, ok := value.AsInt64OK(); ok {
+		return int(v)
+	}
+	return 0
+}
+
+// Upgrade returns doc brought to the version of s. Documents of that
+// version or newer are returned as they are.
+func (s Schema) Upgrade(doc bson.Raw) (bson.Raw, error) {
+	from := Version(doc)
+	if from >= s.Version() {
+		return doc, nil
+	}
+	var fields bson.M
+	if err := bson.Unmarshal(doc, &fields); err != nil {
+		return nil, err
+	}
+	for version := from; version < s.Version(); version++ {
+		if upgrade := s.Upgrades[version]; upgrade != nil {
+			if err := upgrade(fields); err != nil {
+				return nil, fmt.Errorf("upgrading %s document %v to version %d: %w", s.Collection, fields["_id"], version+1, err)
+			}
+		}
+	}
+	fields[Field] = s.Version()
+	return bson.Marshal(fields)
+}
+
+// Decoding returns opts, or new collection options when it is nil, with a
+// registry that upgrades the documents decoded into the types of values.
+// Pass the options a collection is already opened with, such as
+// mongoutil.DatesAsStrings(), so their registry is extended.
+func (s Schema) Decoding(opts *options.CollectionOptions, values ...interface{}) *options.CollectionOptions {
+	if opts == nil {
+		opts = options.Collection()
+	}
+	if opts.Registry == nil {
+		opts.Registry = bson.NewRegistry()
+	}
+	for _, v := range values {
+		t := reflect.TypeOf(v)
+		next, err := opts.Registry.LookupDecoder(t)
+		if err != nil {
+			panic(err)
+		}
+		opts.Registry.RegisterTypeDecoder(t, s.decoder(next))
+	}
+	return opts
+}
+
+// decoder upgrades documents before next decodes them.
+func (s Schema) decoder(next bsoncodec.ValueDecoder) bsoncodec.ValueDecoder {
+	return bsoncodec.ValueDecoderFunc(func(dc bsoncodec.DecodeContext, vr bsonrw.ValueReader, val reflect.Value) error {
+		// Whole documents read as no type; only embedded ones say so
+		if t := vr.Type(); t != 0 && t != bsontype.EmbeddedDocument {
+			return next.DecodeValue(dc, vr, val)
+		}
+		doc, err := bsonrw.Copier{}.CopyDocumentToBytes(vr)
+		if err != nil {
+			return err
+		}
+		upgraded, err := s.Upgrade(doc)
+		if err != nil {
+			return err
+		}
+		return next.DecodeValue(dc, bsonrw.NewBSONDocumentReader(upgraded), val)
+	})
+}
+
+// Check returns ErrTooNew when collection holds a document of a version
+// newer than s, which this release cannot be trusted to serve. Without an
+// index on Field it scans the collection.
+func (s Schema) Check(ctx context.Context, collection *mongo.Collection) error {
+	opts := options.FindOne().
+		SetSort(bson.D{{Key: Field, Value: -1}}).
+		SetProjection(bson.M{Field: 1})
+	doc, err := collection.FindOne(ctx, bson.M{Field: bson.M{"$gt": s.Version()}}, opts).Raw()
+	if err == mongo.ErrNoDocuments {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("%w: %s holds documents of version %d, this release reads up to %d", ErrTooNew, s.Collection, Version(doc), s.Version())
+}
@@ -0,0 +1,72 @@
+//go:build integration
+
+package schema
+
+// Run with a MongoDB at MONGO_TEST_URI (default mongodb://localhost:27017)
+// and go test -tags integration ./...
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func testCollection(t *testing.T) *mongo.Collection {
+	t.Helper()
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		uri = "mongodb://localhost:27017"
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri).SetServerSelectionTimeout(2*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Ping(ctx, nil); err != nil {
+		t.Skipf("MongoDB not reachable at %s: %v", uri, err)
+	}
+	db := client.Database(fmt.Sprintf("schema_test_%d", time.Now().UnixNano()))
+	t.Cleanup(func() {
+		db.Drop(context.Background())
+		client.Disconnect(context.Background())
+	})
+	return db.Collection("items")
+}
+
+func TestCheck(t *testing.T) {
+	collection := testCollection(t)
+	ctx := context.Background()
+	if err := items.Check(ctx, collection); err != nil {
+		t.Errorf("empty collection: %v", err)
+	}
+
+	docs := []interface{}{
+		bson.M{"name": "Ada Lovelace"},
+		bson.M{"first": "Alan", "schema_version": 1},
+		bson.M{"first": "Grace", "schema_version": 3},
+	}
+	if _, err := collection.InsertMany(ctx, docs); err != nil {
+		t.Fatal(err)
+	}
+	if err := items.Check(ctx, collection); err != nil {
+		t.Errorf("documents up to this release's version: %v", err)
+	}
+
+	// A release rolled back past the next one's migration
+	if _, err := collection.InsertOne(ctx, bson.M{"first": "Edsger", "schema_version": 5}); err != nil {
+		t.Fatal(err)
+	}
+	err := items.Check(ctx, collection)
+	if !errors.Is(err, ErrTooNew) || !strings.Contains(err.Error(), "version 5, this release reads up to 3") {
+		t.Errorf("err = %v, want ErrTooNew naming version 5", err)
+	}
+}
@@ -0,0 +1,144 @@
+package schema
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// item went through three versions: names were split into first and last
+// at 1, tags were added at 2 without needing an upgrade, and counts became
+// int64 at 3.
+type item struct {
+	First         string   `bson:"first"`
+	Last          string   `bson:"last"`
+	Tags          []string `bson:"tags"`
+	Count         int64    `bson:"count"`
+	SchemaVersion int      `bson:"schema_version"`
+}
+
+var items = Schema{
+	Collection: "items",
+	Upgrades: []Upgrade{
+		func(doc bson.M) error {
+			name, ok := doc["name"].(string)
+			if !ok {
+				return nil
+			}
+			first, last, _ := strings.Cut(name, " ")
+			doc["first"], doc["last"] = first, last
+			delete(doc, "name")
+			return nil
+		},
+		nil,
+		func(doc bson.M) error {
+			if count, ok := doc["count"].(string); ok {
+				if count != "many" {
+					return errors.New("unreadable count " + count)
+				}
+				doc["count"] = int64(1000)
+			}
+			return nil
+		},
+	},
+}
+
+func decode(t *testing.T, docs ...bson.M) ([]item, error) {
+	t.Helper()
+	values := make([]interface{}, len(docs))
+	for i, doc := range docs {
+		values[i] = doc
+	}
+	cursor, err := mongo.NewCursorFromDocuments(values, nil, items.Decoding(nil, item{}).Registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []item
+	err = cursor.All(context.Background(), &got)
+	return got, err
+}
+
+func TestDecodeEachVersion(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  bson.M
+		want item
+	}{
+		{"version 0", bson.M{"name": "Ada Lovelace", "count": "many"},
+			item{First: "Ada", Last: "Lovelace", Count: 1000, SchemaVersion: 3}},
+		{"version 1", bson.M{"first": "Ada", "last": "Lovelace", "count": int32(7), "schema_version": 1},
+			item{First: "Ada", Last: "Lovelace", Count: 7, SchemaVersion: 3}},
+		{"version 2", bson.M{"first": "Ada", "tags": bson.A{"math"}, "count": "many", "schema_version": int64(2)},
+			item{First: "Ada", Tags: []string{"math"}, Count: 1000, SchemaVersion: 3}},
+		{"version 3", bson.M{"first": "Ada", "count": int64(7), "schema_version": 3},
+			item{First: "Ada", Count: 7, SchemaVersion: 3}},
+		// Stored since the migration, in the new layout but still numbered
+		// before it
+		{"new layout at an old version", bson.M{"first": "Ada", "last": "Lovelace"},
+			item{First: "Ada", Last: "Lovelace", SchemaVersion: 3}},
+		// Written by the next release: its fields are ignored
+		{"version 4", bson.M{"first": "Ada", "count": int64(7), "nickname": "Countess", "schema_version": 4},
+			item{First: "Ada", Count: 7, SchemaVersion: 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decode(t, tt.doc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != 1 || got[0].First != tt.want.First || got[0].Last != tt.want.Last ||
+				strings.Join(got[0].Tags, ",") != strings.Join(tt.want.Tags, ",") ||
+				got[0].Count != tt.want.Count || got[0].SchemaVersion != tt.want.SchemaVersion {
+				t.Errorf("decoded %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeFailingUpgrade(t *testing.T) {
+	_, err := decode(t, bson.M{"name": "Ada", "count": "few"})
+	if err == nil || !strings.Contains(err.Error(), "to version 3: unreadable count few") {
+		t.Errorf("err = %v, want the upgrade's", err)
+	}
+}
+
+func TestDecodeOtherTypes(t *testing.T) {
+	// Only the registered types are upgraded
+	raw, err := bson.Marshal(bson.M{"name": "Ada Lovelace"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc bson.M
+	if err := bson.UnmarshalWithRegistry(items.Decoding(nil, item{}).Registry, raw, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc["name"] != "Ada Lovelace" || doc["schema_version"] != nil {
+		t.Errorf("decoded %v, want the document as stored", doc)
+	}
+}
+
+func TestVersion(t *testing.T) {
+	tests := []struct {
+		doc  bson.M
+		want int
+	}{
+		{bson.M{}, 0},
+		{bson.M{"schema_version": int32(2)}, 2},
+		{bson.M{"schema_version": int64(3)}, 3},
+		{bson.M{"schema_version": 4.0}, 4},
+		{bson.M{"schema_version": "5"}, 0},
+	}
+	for _, tt := range tests {
+		raw, err := bson.Marshal(tt.doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := Version(raw); got != tt.want {
+			t.Errorf("Version(%v) = %d, want %d", tt.doc, got, tt.want)
+		}
+	}
+}
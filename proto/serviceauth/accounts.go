@@ -0,0 +1,238 @@
+package serviceauth
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// Scope grants a service account one kind of access to one service.
+type Scope string
+
+// The scopes, by service: read for queries, write for the changes users
+// make, admin for operations across users or on the service itself.
+const (
+	ScopeTasksRead          Scope = "tasks:read"
+	ScopeTasksWrite         Scope = "tasks:write"
+	ScopeTasksAdmin         Scope = "tasks:admin"
+	ScopeUsersRead          Scope = "users:read"
+	ScopeUsersWrite         Scope = "users:write"
+	ScopeUsersAdmin         Scope = "users:admin"
+	ScopeNotificationsRead  Scope = "notifications:read"
+	ScopeNotificationsSend  Scope = "notifications:send"
+	ScopeNotificationsWrite Scope = "notifications:write"
+	ScopeNotificationsAdmin Scope = "notifications:admin"
+	ScopeAnalyticsRead      Scope = "analytics:read"
+	ScopeAnalyticsWrite     Scope = "analytics:write"
+	ScopeAnalyticsAdmin     Scope = "analytics:admin"
+	ScopeFaultsAdmin        Scope = "faults:admin"
+	ScopeInfoRead           Scope = "info:read"
+	ScopeBackupsAdmin       Scope = "backups:admin"
+)
+
+// MethodScopes is the registry of the scope each method requires. Servers
+// enforcing service accounts refuse methods missing from it.
+var MethodScopes = map[string]Scope{
+	// TaskService
+	pb.TaskService_CreateTask_FullMethodName:            ScopeTasksWrite,
+	pb.TaskService_GetTask_FullMethodName:               ScopeTasksRead,
+	pb.TaskService_UpdateTask_FullMethodName:            ScopeTasksWrite,
+	pb.TaskService_DeleteTask_FullMethodName:            ScopeTasksWrite,
+	pb.TaskService_ListTasks_FullMethodName:             ScopeTasksRead,
+	pb.TaskService_SyncTasks_FullMethodName:             ScopeTasksRead,
+	pb.TaskService_GetPendingTaskCount_FullMethodName:   ScopeTasksRead,
+	pb.TaskService_UpdateTaskStatus_FullMethodName:      ScopeTasksWrite,
+	pb.TaskService_GetTaskMetrics_FullMethodName:        ScopeTasksRead,
+	pb.TaskService_FindSimilarTasks_FullMethodName:      ScopeTasksRead,
+	pb.TaskService_GetTasksCalendarView_FullMethodName:  ScopeTasksRead,
+	pb.TaskService_ListDeletedTasks_FullMethodName:      ScopeTasksRead,
+	pb.TaskService_BatchDeleteTasks_FullMethodName:      ScopeTasksAdmin,
+	pb.TaskService_ReplayTaskEvents_FullMethodName:      ScopeTasksAdmin,
+	pb.TaskService_GetTasksNearDeadline_FullMethodName:  ScopeTasksRead,
+	pb.TaskService_MarkDeadlineAlertSent_FullMethodName: ScopeTasksWrite,
+	pb.TaskService_ExportTasks_FullMethodName:           ScopeTasksRead,
+	pb.TaskService_MoveTask_FullMethodName:              ScopeTasksWrite,
+	pb.TaskService_ExtendTaskDueDate_FullMethodName:     ScopeTasksWrite,
+	pb.TaskService_LockTask_FullMethodName:              ScopeTasksWrite,
+	pb.TaskService_UnlockTask_FullMethodName:            ScopeTasksWrite,
+	pb.TaskService_BulkUpdateTasks_FullMethodName:       ScopeTasksWrite,
+	pb.TaskService_AddChecklistItem_FullMethodName:      ScopeTasksWrite,
+	pb.TaskService_ToggleChecklistItem_FullMethodName:   ScopeTasksWrite,
+	pb.TaskService_RemoveChecklistItem_FullMethodName:   ScopeTasksWrite,
+	pb.TaskService_GetTaskNotes_FullMethodName:          ScopeTasksRead,
+	pb.TaskService_UpdateTaskNotes_FullMethodName:       ScopeTasksWrite,
+	pb.TaskService_MigrateTaskOwnership_FullMethodName:  ScopeTasksAdmin,
+	// UserService
+	pb.UserService_CreateUser_FullMethodName:           ScopeUsersWrite,
+	pb.UserService_GetUser_FullMethodName:              ScopeUsersRead,
+	pb.UserService_UpdateUser_FullMethodName:           ScopeUsersWrite,
+	pb.UserService_DeleteUser_FullMethodName:           ScopeUsersWrite,
+	pb.UserService_AuthenticateUser_FullMethodName:     ScopeUsersWrite,
+	pb.UserService_ListSessions_FullMethodName:         ScopeUsersRead,
+	pb.UserService_GetUserActivityStats_FullMethodName: ScopeUsersRead,
+	pb.UserService_FreezeUser_FullMethodName:           ScopeUsersAdmin,
+	pb.UserService_UnfreezeUser_FullMethodName:         ScopeUsersAdmin,
+	pb.UserService_GetUserCounts_FullMethodName:        ScopeUsersRead,
+	pb.UserService_PurgeUserData_FullMethodName:        ScopeUsersAdmin,
+	pb.UserService_GetDeletionStatus_FullMethodName:    ScopeUsersRead,
+	// NotificationService
+	pb.NotificationService_SendNotification_FullMethodName:          ScopeNotificationsSend,
+	pb.NotificationService_GetNotifications_FullMethodName:          ScopeNotificationsRead,
+	pb.NotificationService_GetNotification_FullMethodName:           ScopeNotificationsRead,
+	pb.NotificationService_ListFailedDeliveries_FullMethodName:      ScopeNotificationsAdmin,
+	pb.NotificationService_GetDeliveryReport_FullMethodName:         ScopeNotificationsAdmin,
+	pb.NotificationService_GetNotificationCounts_FullMethodName:     ScopeNotificationsRead,
+	pb.NotificationService_RedeliverNotification_FullMethodName:     ScopeNotificationsAdmin,
+	pb.NotificationService_CreateTemplate_FullMethodName:            ScopeNotificationsAdmin,
+	pb.NotificationService_ListTemplates_FullMethodName:             ScopeNotificationsRead,
+	pb.NotificationService_GetTemplate_FullMethodName:               ScopeNotificationsRead,
+	pb.NotificationService_UpdateTemplate_FullMethodName:            ScopeNotificationsAdmin,
+	pb.NotificationService_DeleteTemplate_FullMethodName:            ScopeNotificationsAdmin,
+	pb.NotificationService_CloneTemplate_FullMethodName:             ScopeNotificationsAdmin,
+	pb.NotificationService_ActivateTemplate_FullMethodName:          ScopeNotificationsAdmin,
+	pb.NotificationService_SetDigestSchedule_FullMethodName:         ScopeNotificationsWrite,
+	pb.NotificationService_DeleteDigestSchedule_FullMethodName:      ScopeNotificationsWrite,
+	pb.NotificationService_DeleteNotificationsByUser_FullMethodName: ScopeNotificationsAdmin,
+	pb.NotificationService_GetNotificationSummary_FullMethodName:    ScopeNotificationsRead,
+	pb.NotificationService_MarkNotificationRead_FullMethodName:      ScopeNotificationsWrite,
+	// AnalyticsService
+	pb.AnalyticsService_TrackEvent_FullMethodName:               ScopeAnalyticsWrite,
+	pb.AnalyticsService_TrackEvents_FullMethodName:              ScopeAnalyticsWrite,
+	pb.AnalyticsService_GetUserStats_FullMethodName:             ScopeAnalyticsRead,
+	pb.AnalyticsService_GetTaskStats_FullMethodName:             ScopeAnalyticsRead,
+	pb.AnalyticsService_GetPeakHours_FullMethodName:             ScopeAnalyticsRead,
+	pb.AnalyticsService_GetCompletionTrend_FullMethodName:       ScopeAnalyticsRead,
+	pb.AnalyticsService_BackfillAnalytics_FullMethodName:        ScopeAnalyticsAdmin,
+	pb.AnalyticsService_GetEngagementScore_FullMethodName:       ScopeAnalyticsRead,
+	pb.AnalyticsService_ReplayEvents_FullMethodName:             ScopeAnalyticsRead,
+	pb.AnalyticsService_GetUserStreak_FullMethodName:            ScopeAnalyticsRead,
+	pb.AnalyticsService_GetActivityHeatmap_FullMethodName:       ScopeAnalyticsRead,
+	pb.AnalyticsService_GetActiveUsers_FullMethodName:           ScopeAnalyticsRead,
+	pb.AnalyticsService_GetCompletionLatency_FullMethodName:     ScopeAnalyticsRead,
+	pb.AnalyticsService_GetRetentionStatus_FullMethodName:       ScopeAnalyticsRead,
+	pb.AnalyticsService_GenerateWeeklySummary_FullMethodName:    ScopeAnalyticsRead,
+	pb.AnalyticsService_GetTaskBreakdown_FullMethodName:         ScopeAnalyticsRead,
+	pb.AnalyticsService_GetCacheStats_FullMethodName:            ScopeAnalyticsRead,
+	pb.AnalyticsService_StreamEvents_FullMethodName:             ScopeAnalyticsRead,
+	pb.AnalyticsService_SubscribeToEvents_FullMethodName:        ScopeAnalyticsRead,
+	pb.AnalyticsService_GetSystemOverview_FullMethodName:        ScopeAnalyticsRead,
+	pb.AnalyticsService_GetOverdueAging_FullMethodName:          ScopeAnalyticsRead,
+	pb.AnalyticsService_ExportStats_FullMethodName:              ScopeAnalyticsRead,
+	pb.AnalyticsService_GetTimeReport_FullMethodName:            ScopeAnalyticsRead,
+	pb.AnalyticsService_DeleteUserEvents_FullMethodName:         ScopeAnalyticsAdmin,
+	pb.AnalyticsService_AnonymizeUserEvents_FullMethodName:      ScopeAnalyticsAdmin,
+	pb.AnalyticsService_CompareUsersProductivity_FullMethodName: ScopeAnalyticsRead,
+	pb.AnalyticsService_GetCompletionForecast_FullMethodName:    ScopeAnalyticsRead,
+	pb.AnalyticsService_GetBurndown_FullMethodName:              ScopeAnalyticsRead,
+	pb.AnalyticsService_GetPriorityBreakdown_FullMethodName:     ScopeAnalyticsRead,
+	// FaultService
+	pb.FaultService_GetFaults_FullMethodName: ScopeFaultsAdmin,
+	pb.FaultService_SetFaults_FullMethodName: ScopeFaultsAdmin,
+	// InfoService
+	pb.InfoService_GetServiceInfo_FullMethodName: ScopeInfoRead,
+	// BackupService
+	pb.BackupService_BackupCollection_FullMethodName: ScopeBackupsAdmin,
+}
+
+// AccountScopes are the scopes each service account is issued, for the calls
+// it makes: the gateway serves every route, the others only what their
+// background jobs and sagas need.
+var AccountScopes = map[string][]Scope{
+	"api-gateway": {
+		ScopeTasksRead, ScopeTasksWrite, ScopeTasksAdmin,
+		ScopeUsersRead, ScopeUsersWrite, ScopeUsersAdmin,
+		ScopeNotificationsRead, ScopeNotificationsSend, ScopeNotificationsWrite, ScopeNotificationsAdmin,
+		ScopeAnalyticsRead, ScopeAnalyticsWrite, ScopeAnalyticsAdmin,
+		ScopeInfoRead, ScopeBackupsAdmin,
+	},
+	// Checks task owners, sends due date reminders and records task events
+	"task-service": {ScopeUsersRead, ScopeNotificationsSend, ScopeAnalyticsWrite},
+	// The account deletion saga erases the user's data everywhere
+	"user-service": {ScopeTasksAdmin, ScopeNotificationsAdmin, ScopeAnalyticsAdmin},
+	// The deadline scheduler finds tasks due soon and marks them alerted
+	"notification-service": {ScopeTasksRead, ScopeTasksWrite, ScopeUsersRead},
+	// Stats read the other services; weekly summaries go out as notifications
+	"analytics-service": {ScopeTasksRead, ScopeUsersRead, ScopeNotificationsRead, ScopeNotificationsSend},
+	// cmd/seed fills a development stack with demo data
+	"seed": {ScopeTasksRead, ScopeTasksWrite, ScopeUsersWrite, ScopeNotificationsSend, ScopeAnalyticsWrite},
+}
+
+// Token is a service account with the scopes it was issued.
+type Token struct {
+	Account string
+	Scopes  []Scope
+}
+
+// Has reports whether the token grants scope.
+func (t Token) Has(scope Scope) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// MintToken issues the token of account, with the scopes AccountScopes
+// grants it, signed with the account's private key.
+func MintToken(key ed25519.PrivateKey, account string) (string, error) {
+	scopes, ok := AccountScopes[account]
+	if !ok {
+		return "", fmt.Errorf("unknown service account %q", account)
+	}
+	fields := []string{account}
+	for _, scope := range scopes {
+		fields = append(fields, string(scope))
+	}
+	payload := strings.Join(fields, " ")
+	signature := ed25519.Sign(key, tokenMessage(payload))
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// ParseToken returns the token raw holds, once its signature is checked
+// against the public key keys holds for the account it names. Only the
+// scopes AccountScopes grants that account are kept, so a service whose key
+// leaks can present its own scopes but never another account's.
+func ParseToken(keys map[string]ed25519.PublicKey, raw string) (Token, error) {
+	encoded, encodedSignature, ok := strings.Cut(raw, ".")
+	if !ok {
+		return Token{}, errors.New("malformed token")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return Token{}, errors.New("malformed token")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil {
+		return Token{}, errors.New("malformed token")
+	}
+	fields := strings.Fields(string(payload))
+	if len(fields) == 0 {
+		return Token{}, errors.New("token names no account")
+	}
+	key, ok := keys[fields[0]]
+	if !ok {
+		return Token{}, fmt.Errorf("no public key for service account %q", fields[0])
+	}
+	if !ed25519.Verify(key, tokenMessage(string(payload)), signature) {
+		return Token{}, errors.New("invalid token signature")
+	}
+	token := Token{Account: fields[0]}
+	granted := Token{Scopes: AccountScopes[token.Account]}
+	for _, scope := range fields[1:] {
+		if granted.Has(Scope(scope)) {
+			token.Scopes = append(token.Scopes, Scope(scope))
+		}
+	}
+	return token, nil
+}
+
+// tokenMessage is what a token's signature covers. The prefix keeps the key
+// from signing anything that could pass for a token by accident.
+func tokenMessage(payload string) []byte {
+	return []byte("service-account:" + payload)
+}
@@ -0,0 +1,295 @@
+// Package serviceauth authenticates calls between the services with a shared
+// secret, for environments where mTLS is not available.
+//
+// Each service has its own secret, named after it (TASK_SERVICE_HMAC_SECRET
+// and so on), which it and every caller of it share. Callers sign each call
+// with HMAC-SHA256 over the timestamp, the full method name and the SHA-256
+// of the request, and send the signature and timestamp as metadata. Servers
+// reject calls that are unsigned, signed with another secret, or signed more
+// than MaxSkew away from their clock, which bounds how long a captured call
+// can be replayed. Streaming calls send their request after the headers, so
+// their signature covers an empty request instead.
+//
+// Signing is off unless REQUIRE_SERVICE_AUTH=true.
+//
+// Signed calls all look alike, so each caller also names its service
+// account. With SERVICE_ACCOUNT_KEY set, the caller mints a token at startup
+// for the account in SERVICE_ACCOUNT, carrying the scopes AccountScopes
+// grants it, signs it with the account's ed25519 private key and sends it
+// with every call. With SERVICE_ACCOUNT_PUBLIC_KEYS set, servers check each
+// token against the public key of the account it names and refuse calls
+// whose token lacks the scope MethodScopes requires, with
+// codes.PermissionDenied, so the deadline scheduler cannot delete a user's
+// tasks, say. Each service holds only its own private key and servers only
+// public keys, so a compromised service can present its own scopes but not
+// another account's. cmd/accountkeys generates the keys.
+package serviceauth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	SignatureHeader = "x-service-signature"
+	TimestampHeader = "x-timestamp"
+	TokenHeader     = "x-service-account"
+	// MaxSkew is how far a call's timestamp may be from the server's clock
+	MaxSkew = 5 * time.Minute
+)
+
+// errorDomain identifies these errors in their ErrorInfo.
+const errorDomain = "serviceauth"
+
+// HMACInterceptor signs outgoing calls and verifies incoming ones with one
+// service's secret. A nil *HMACInterceptor leaves calls alone.
+type HMACInterceptor struct {
+	secret []byte
+	now    func() time.Time
+	// token names the caller's service account on the calls it signs
+	token string
+	// accountKeys, when set, makes the server require a token signed by one
+	// of the accounts, holding the scope of each method
+	accountKeys map[string]ed25519.PublicKey
+	logger      *slog.Logger
+}
+
+// NewHMACInterceptor returns an interceptor using secret.
+func NewHMACInterceptor(secret []byte) *HMACInterceptor {
+	return &HMACInterceptor{secret: secret, now: time.Now, logger: slog.Default()}
+}
+
+// FromEnv returns the interceptor for the service whose secret is in the
+// environment variable secretVar, or nil unless REQUIRE_SERVICE_AUTH is true.
+func FromEnv(secretVar string) (*HMACInterceptor, error) {
+	required, err := strconv.ParseBool(getEnv("REQUIRE_SERVICE_AUTH", "false"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid REQUIRE_SERVICE_AUTH %q", os.Getenv("REQUIRE_SERVICE_AUTH"))
+	}
+	if !required {
+		return nil, nil
+	}
+	secret := os.Getenv(secretVar)
+	if len(secret) < 32 {
+		return nil, fmt.Errorf("%s must be set to at least 32 characters when REQUIRE_SERVICE_AUTH is true", secretVar)
+	}
+	h := NewHMACInterceptor([]byte(secret))
+	account := os.Getenv("SERVICE_ACCOUNT")
+	var private ed25519.PrivateKey
+	if value := os.Getenv("SERVICE_ACCOUNT_KEY"); value != "" {
+		if private, err = ParsePrivateKey(value); err != nil {
+			return nil, fmt.Errorf("invalid SERVICE_ACCOUNT_KEY: %v", err)
+		}
+		if err := h.UseServiceAccount(private, account); err != nil {
+			return nil, fmt.Errorf("invalid SERVICE_ACCOUNT: %v", err)
+		}
+	}
+	if value := os.Getenv("SERVICE_ACCOUNT_PUBLIC_KEYS"); value != "" {
+		keys, err := ParsePublicKeys(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SERVICE_ACCOUNT_PUBLIC_KEYS: %v", err)
+		}
+		// A mismatched pair would only show once the service's calls fail
+		if public, listed := keys[account]; private != nil && listed && !public.Equal(private.Public()) {
+			return nil, fmt.Errorf("SERVICE_ACCOUNT_KEY is not the private key SERVICE_ACCOUNT_PUBLIC_KEYS lists for %s", account)
+		}
+		h.RequireServiceAccounts(keys)
+	}
+	return h, nil
+}
+
+// UseServiceAccount makes the interceptor send the token of account, minted
+// with its private key, on the calls it signs.
+func (h *HMACInterceptor) UseServiceAccount(key ed25519.PrivateKey, account string) error {
+	token, err := MintToken(key, account)
+	if err != nil {
+		return err
+	}
+	h.token = token
+	return nil
+}
+
+// RequireServiceAccounts makes the interceptor require, on the calls it
+// verifies, a token signed by one of the accounts whose public keys keys
+// holds.
+func (h *HMACInterceptor) RequireServiceAccounts(keys map[string]ed25519.PublicKey) {
+	h.accountKeys = keys
+}
+
+// DialOptions sign the calls of a connection to the interceptor's service.
+func (h *HMACInterceptor) DialOptions() []grpc.DialOption {
+	if h == nil {
+		return nil
+	}
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(h.UnaryClientInterceptor),
+		grpc.WithChainStreamInterceptor(h.StreamClientInterceptor),
+	}
+}
+
+// ServerOptions verify every call to the server. Pass them before any other
+// interceptors so that none run for unauthenticated calls.
+func (h *HMACInterceptor) ServerOptions() []grpc.ServerOption {
+	if h == nil {
+		return nil
+	}
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(h.UnaryServerInterceptor),
+		grpc.ChainStreamInterceptor(h.StreamServerInterceptor),
+	}
+}
+
+func (h *HMACInterceptor) UnaryClientInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	ctx, err := h.sign(ctx, method, req)
+	if err != nil {
+		return err
+	}
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+func (h *HMACInterceptor) StreamClientInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	ctx, err := h.sign(ctx, method, nil)
+	if err != nil {
+		return nil, err
+	}
+	return streamer(ctx, desc, cc, method, opts...)
+}
+
+func (h *HMACInterceptor) UnaryServerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := h.verify(ctx, info.FullMethod, req); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (h *HMACInterceptor) StreamServerInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := h.verify(ss.Context(), info.FullMethod, nil); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+func (h *HMACInterceptor) sign(ctx context.Context, method string, req interface{}) (context.Context, error) {
+	timestamp := strconv.FormatInt(h.now().Unix(), 10)
+	signature, err := h.signature(timestamp, method, req)
+	if err != nil {
+		return ctx, err
+	}
+	ctx = metadata.AppendToOutgoingContext(ctx, SignatureHeader, signature, TimestampHeader, timestamp)
+	if h.token != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, TokenHeader, h.token)
+	}
+	return ctx, nil
+}
+
+func (h *HMACInterceptor) verify(ctx context.Context, method string, req interface{}) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	signatures, timestamps := md.Get(SignatureHeader), md.Get(TimestampHeader)
+	if len(signatures) != 1 || len(timestamps) != 1 {
+		return authError("SIGNATURE_MISSING", "call is not signed")
+	}
+
+	unix, err := strconv.ParseInt(timestamps[0], 10, 64)
+	if err != nil {
+		return authError("INVALID_TIMESTAMP", "invalid %s %q", TimestampHeader, timestamps[0])
+	}
+	skew := h.now().Sub(time.Unix(unix, 0))
+	if skew > MaxSkew || skew < -MaxSkew {
+		return authError("TIMESTAMP_EXPIRED", "call was signed %s from the server's time, more than %s", skew.Round(time.Second), MaxSkew)
+	}
+
+	expected, err := h.signature(timestamps[0], method, req)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(signatures[0]), []byte(expected)) {
+		return authError("INVALID_SIGNATURE", "invalid call signature")
+	}
+	if h.accountKeys != nil {
+		return h.authorize(md, method)
+	}
+	return nil
+}
+
+// authorize checks the caller's token grants the scope method requires,
+// logging refusals with the caller's account.
+func (h *HMACInterceptor) authorize(md metadata.MD, method string) error {
+	tokens := md.Get(TokenHeader)
+	if len(tokens) != 1 {
+		return authError("TOKEN_MISSING", "call names no service account")
+	}
+	token, err := ParseToken(h.accountKeys, tokens[0])
+	if err != nil {
+		return authError("INVALID_TOKEN", "invalid service account token: %v", err)
+	}
+	scope, ok := MethodScopes[method]
+	if !ok {
+		h.logger.Warn("Refused a call to a method without a scope", "account", token.Account, "method", method)
+		return permissionError("METHOD_NOT_REGISTERED", map[string]string{"account": token.Account, "method": method},
+			"%s has no scope in the registry", method)
+	}
+	if !token.Has(scope) {
+		h.logger.Warn("Refused a call missing its scope", "account", token.Account, "method", method, "scope", scope)
+		return permissionError("SCOPE_MISSING", map[string]string{"account": token.Account, "method": method, "scope": string(scope)},
+			"service account %s lacks the %s scope %s requires", token.Account, scope, method)
+	}
+	return nil
+}
+
+// signature is the hex HMAC of the timestamp, method and request hash.
+func (h *HMACInterceptor) signature(timestamp, method string, req interface{}) (string, error) {
+	var body []byte
+	if msg, ok := req.(proto.Message); ok {
+		var err error
+		if body, err = (proto.MarshalOptions{Deterministic: true}).Marshal(msg); err != nil {
+			return "", status.Errorf(codes.Internal, "marshal request to sign: %v", err)
+		}
+	}
+	bodyHash := sha256.Sum256(body)
+
+	mac := hmac.New(sha256.New, h.secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte(method))
+	mac.Write([]byte(hex.EncodeToString(bodyHash[:])))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+func authError(reason, format string, args ...interface{}) error {
+	st := status.Newf(codes.Unauthenticated, format, args...)
+	detailed, err := st.WithDetails(&errdetails.ErrorInfo{Domain: errorDomain, Reason: reason})
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+func permissionError(reason string, meta map[string]string, format string, args ...interface{}) error {
+	st := status.Newf(codes.PermissionDenied, format, args...)
+	detailed, err := st.WithDetails(&errdetails.ErrorInfo{Domain: errorDomain, Reason: reason, Metadata: meta})
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+func getEnv(key, fallback string) string {
+	if value, exists := os.LookupEnv(key); exists {
+		return value
+	}
+	return fallback
+}
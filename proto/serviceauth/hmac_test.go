@@ -0,0 +1,114 @@
+package serviceauth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+var (
+	testSecret = []byte("task-service-secret")
+	serverTime = time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC)
+)
+
+// interceptorAt returns an interceptor using testSecret whose clock reads at.
+func interceptorAt(at time.Time) *HMACInterceptor {
+	h := NewHMACInterceptor(testSecret)
+	h.now = func() time.Time { return at }
+	return h
+}
+
+// signed returns the incoming metadata of a call to method with req signed by
+// client.
+func signed(t *testing.T, client *HMACInterceptor, method string, req proto.Message) metadata.MD {
+	t.Helper()
+	ctx, err := client.sign(context.Background(), method, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	md, _ := metadata.FromOutgoingContext(ctx)
+	return md
+}
+
+// serve passes a call to method with req and md to a server at serverTime.
+func serve(md metadata.MD, method string, req proto.Message) error {
+	ctx := metadata.NewIncomingContext(context.Background(), md)
+	_, err := interceptorAt(serverTime).UnaryServerInterceptor(ctx, req, &grpc.UnaryServerInfo{FullMethod: method}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	})
+	return err
+}
+
+// reason returns the ErrorInfo reason of err, if it is Unauthenticated.
+func reason(err error) string {
+	st := status.Convert(err)
+	if st.Code() != codes.Unauthenticated {
+		return ""
+	}
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			return info.Reason
+		}
+	}
+	return ""
+}
+
+func TestVerifySignature(t *testing.T) {
+	method := pb.TaskService_GetTask_FullMethodName
+	req := &pb.GetTaskRequest{Id: "t1"}
+	if err := serve(signed(t, interceptorAt(serverTime), method, req), method, req); err != nil {
+		t.Errorf("validly signed call = %v, want it allowed", err)
+	}
+
+	tests := []struct {
+		name string
+		md   metadata.MD
+		// method and req are what the server receives
+		method string
+		req    proto.Message
+		want   string
+	}{
+		{"tampered body", signed(t, interceptorAt(serverTime), method, req),
+			method, &pb.GetTaskRequest{Id: "t2"}, "INVALID_SIGNATURE"},
+		{"tampered method", signed(t, interceptorAt(serverTime), method, req),
+			pb.TaskService_DeleteTask_FullMethodName, req, "INVALID_SIGNATURE"},
+		{"another secret", signed(t, &HMACInterceptor{secret: []byte("another-secret"), now: func() time.Time { return serverTime }}, method, req),
+			method, req, "INVALID_SIGNATURE"},
+		{"signed too long ago", signed(t, interceptorAt(serverTime.Add(-MaxSkew-time.Second)), method, req),
+			method, req, "TIMESTAMP_EXPIRED"},
+		{"signed in the future", signed(t, interceptorAt(serverTime.Add(MaxSkew+time.Second)), method, req),
+			method, req, "TIMESTAMP_EXPIRED"},
+		{"invalid timestamp", metadata.Pairs(SignatureHeader, "abc", TimestampHeader, "yesterday"),
+			method, req, "INVALID_TIMESTAMP"},
+		{"no signature", metadata.Pairs(TimestampHeader, "1772625600"), method, req, "SIGNATURE_MISSING"},
+		{"no timestamp", metadata.Pairs(SignatureHeader, "abc"), method, req, "SIGNATURE_MISSING"},
+		{"no headers", metadata.MD{}, method, req, "SIGNATURE_MISSING"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := serve(tt.md, tt.method, tt.req)
+			if got := reason(err); got != tt.want {
+				t.Errorf("call = %v, want Unauthenticated with %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyWithinSkew(t *testing.T) {
+	method := pb.TaskService_GetTask_FullMethodName
+	req := &pb.GetTaskRequest{Id: "t1"}
+	for _, skew := range []time.Duration{-MaxSkew, MaxSkew} {
+		if err := serve(signed(t, interceptorAt(serverTime.Add(skew)), method, req), method, req); err != nil {
+			t.Errorf("call signed %s from the server's time = %v, want it allowed", skew, err)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package serviceauth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// An account's private key is written as the base64 of its 32 byte ed25519
+// seed, and its public key as the base64 of the 32 byte public key. Public
+// keys are listed as account=key pairs separated by commas.
+
+// GenerateKeys makes a key pair for every account AccountScopes names.
+func GenerateKeys() (map[string]ed25519.PrivateKey, error) {
+	keys := make(map[string]ed25519.PrivateKey, len(AccountScopes))
+	for account := range AccountScopes {
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		keys[account] = private
+	}
+	return keys, nil
+}
+
+// FormatPrivateKey writes key as ParsePrivateKey reads it.
+func FormatPrivateKey(key ed25519.PrivateKey) string {
+	return base64.StdEncoding.EncodeToString(key.Seed())
+}
+
+// ParsePrivateKey reads an account's private key.
+func ParsePrivateKey(value string) (ed25519.PrivateKey, error) {
+	seed, err := base64.StdEncoding.DecodeString(value)
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("not the base64 of a %d byte ed25519 seed", ed25519.SeedSize)
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// FormatPublicKeys lists the public keys of keys, by account, as
+// ParsePublicKeys reads them.
+func FormatPublicKeys(keys map[string]ed25519.PrivateKey) string {
+	var pairs []string
+	for account, key := range keys {
+		public := key.Public().(ed25519.PublicKey)
+		pairs = append(pairs, account+"="+base64.StdEncoding.EncodeToString(public))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// ParsePublicKeys reads the accounts' public keys. Every account must be one
+// AccountScopes names.
+func ParsePublicKeys(value string) (map[string]ed25519.PublicKey, error) {
+	keys := make(map[string]ed25519.PublicKey)
+	for _, pair := range strings.Split(value, ",") {
+		account, encoded, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return nil, fmt.Errorf("%q is not account=key", pair)
+		}
+		if _, known := AccountScopes[account]; !known {
+			return nil, fmt.Errorf("unknown service account %q", account)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("the key of %s is not the base64 of a %d byte ed25519 public key", account, ed25519.PublicKeySize)
+		}
+		keys[account] = ed25519.PublicKey(key)
+	}
+	return keys, nil
+}
@@ -0,0 +1,114 @@
+package settings
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/technonext/todo-app/proto/mongoutil"
+)
+
+// StorageMongo is the STORAGE_DRIVER that keeps a service's data in MongoDB,
+// every service's default.
+const StorageMongo = "mongo"
+
+// Server holds the settings every service reads for its gRPC server and
+// storage. Services embed it in their Config, next to their own settings.
+type Server struct {
+	Port  string
+	Mongo mongoutil.Config
+	// MaxRecvMsgSize is GRPC_MAX_RECV_MSG_SIZE, the largest request in MB
+	MaxRecvMsgSize string
+	// MaxInFlight is GRPC_MAX_IN_FLIGHT, the unary calls handled at once,
+	// MONGO_MAX_POOL_SIZE on MongoDB and unlimited otherwise when empty
+	MaxInFlight string
+	// MaxConcurrentStreams is GRPC_MAX_CONCURRENT_STREAMS, the calls open on
+	// one connection; unlimited when 0
+	MaxConcurrentStreams string
+	// StorageDriver is STORAGE_DRIVER; each service checks it names one of
+	// its drivers
+	StorageDriver string
+}
+
+// LoadServer reads the settings from the environment, listening on
+// defaultPort unless PORT is set.
+func LoadServer(defaultPort string, mongo mongoutil.Config) Server {
+	return Server{
+		Port:                 getEnv("PORT", defaultPort),
+		Mongo:                mongo,
+		MaxRecvMsgSize:       getEnv("GRPC_MAX_RECV_MSG_SIZE", "4"),
+		MaxInFlight:          os.Getenv("GRPC_MAX_IN_FLIGHT"),
+		MaxConcurrentStreams: getEnv("GRPC_MAX_CONCURRENT_STREAMS", "1000"),
+		StorageDriver:        getEnv("STORAGE_DRIVER", StorageMongo),
+	}
+}
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// Errors reports every invalid setting, each named by its environment
+// variable. MongoDB's are only checked on StorageMongo.
+func (s Server) Errors() []error {
+	var errs []error
+	if err := Port(s.Port); err != nil {
+		errs = append(errs, fmt.Errorf("PORT: %w", err))
+	}
+	if s.StorageDriver == StorageMongo {
+		if _, err := s.Mongo.BuildURI(); err != nil {
+			errs = append(errs, fmt.Errorf("MONGO_URI: %w", err))
+		}
+	}
+	if _, err := MessageSize(s.MaxRecvMsgSize); err != nil {
+		errs = append(errs, fmt.Errorf("GRPC_MAX_RECV_MSG_SIZE: %w", err))
+	}
+	if _, err := InFlight(s.MaxInFlight, s.PoolSize()); err != nil {
+		errs = append(errs, fmt.Errorf("GRPC_MAX_IN_FLIGHT: %w", err))
+	}
+	if _, err := Streams(s.MaxConcurrentStreams); err != nil {
+		errs = append(errs, fmt.Errorf("GRPC_MAX_CONCURRENT_STREAMS: %w", err))
+	}
+	return errs
+}
+
+// RecvMsgSize returns MaxRecvMsgSize in bytes, once validated.
+func (s Server) RecvMsgSize() int {
+	size, _ := MessageSize(s.MaxRecvMsgSize)
+	return size
+}
+
+// PoolSize returns MONGO_MAX_POOL_SIZE on MongoDB, which bounds MaxInFlight,
+// and 0 for the other drivers.
+func (s Server) PoolSize() uint64 {
+	if s.StorageDriver != StorageMongo {
+		return 0
+	}
+	return s.Mongo.MaxPoolSize
+}
+
+// InFlightLimit returns MaxInFlight, once validated.
+func (s Server) InFlightLimit() int {
+	n, _ := InFlight(s.MaxInFlight, s.PoolSize())
+	return n
+}
+
+// StreamLimit returns MaxConcurrentStreams, once validated.
+func (s Server) StreamLimit() uint32 {
+	n, _ := Streams(s.MaxConcurrentStreams)
+	return n
+}
+
+// Info returns the settings by environment variable for the InfoService,
+// which also redacts the credentials in URIs. Services add their own.
+func (s Server) Info() map[string]string {
+	values := s.Mongo.Settings()
+	values["PORT"] = s.Port
+	values["GRPC_MAX_RECV_MSG_SIZE"] = s.MaxRecvMsgSize
+	values["GRPC_MAX_IN_FLIGHT"] = strconv.Itoa(s.InFlightLimit())
+	values["GRPC_MAX_CONCURRENT_STREAMS"] = s.MaxConcurrentStreams
+	values["STORAGE_DRIVER"] = s.StorageDriver
+	return values
+}
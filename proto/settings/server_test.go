@@ -0,0 +1,37 @@
+package settings
+
+import (
+	"testing"
+
+	"github.com/technonext/todo-app/proto/mongoutil"
+)
+
+func TestServerInFlightLimit(t *testing.T) {
+	tests := []struct {
+		name   string
+		server Server
+		want   int
+	}{
+		{"pool size on MongoDB", Server{StorageDriver: StorageMongo, Mongo: mongoutil.Config{MaxPoolSize: 100}}, 100},
+		{"set on MongoDB", Server{StorageDriver: StorageMongo, Mongo: mongoutil.Config{MaxPoolSize: 100}, MaxInFlight: "40"}, 40},
+		{"unlimited elsewhere", Server{StorageDriver: "memory", Mongo: mongoutil.Config{MaxPoolSize: 100}}, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.server.InFlightLimit(); got != tt.want {
+			t.Errorf("%s: InFlightLimit() = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLoadServerDefaults(t *testing.T) {
+	for _, key := range []string{"PORT", "GRPC_MAX_RECV_MSG_SIZE", "GRPC_MAX_IN_FLIGHT", "GRPC_MAX_CONCURRENT_STREAMS", "STORAGE_DRIVER"} {
+		t.Setenv(key, "")
+	}
+	s := LoadServer("50051", mongoutil.Config{URI: "mongodb://localhost:27017"})
+	if errs := s.Errors(); len(errs) != 0 {
+		t.Fatalf("Errors() = %v, want the defaults valid", errs)
+	}
+	if s.Port != "50051" || s.StorageDriver != StorageMongo || s.RecvMsgSize() != 4<<20 || s.StreamLimit() != 1000 {
+		t.Errorf("LoadServer() = %+v, want port 50051 on MongoDB, 4 MB messages and 1000 streams", s)
+	}
+}
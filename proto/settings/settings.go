@@ -0,0 +1,69 @@
+// Package settings checks the values of settings several services share, so
+// each service's startup validation words their errors alike. The checks
+// take the raw values read from the environment; callers name the setting
+// when they report an error. Server reads and checks the gRPC server and
+// storage settings every service has.
+package settings
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// MaxMessageSizeMB bounds GRPC_MAX_RECV_MSG_SIZE.
+const MaxMessageSizeMB = 512
+
+// Port checks a TCP port to listen on.
+func Port(value string) error {
+	port, err := strconv.Atoi(value)
+	if err != nil || port < 1 || port > 65535 {
+		return fmt.Errorf("%q is not a port between 1 and 65535", value)
+	}
+	return nil
+}
+
+// MessageSize parses a message size in megabytes, between 1 and
+// MaxMessageSizeMB, returning it in bytes.
+func MessageSize(value string) (int, error) {
+	mb, err := strconv.Atoi(value)
+	if err != nil || mb < 1 || mb > MaxMessageSizeMB {
+		return 0, fmt.Errorf("%q is not a size between 1 and %d MB", value, MaxMessageSizeMB)
+	}
+	return mb << 20, nil
+}
+
+// InFlight parses a cap on the calls a service handles at once, 0 for none.
+// A service on MongoDB passes the size of its connection pool as poolSize:
+// the cap must not exceed it, so calls are refused before they queue for a
+// connection, and an empty value takes it. Others pass 0, when an empty
+// value means no cap.
+func InFlight(value string, poolSize uint64) (int, error) {
+	if value == "" {
+		return int(poolSize), nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%q is not a number of calls", value)
+	}
+	if poolSize > 0 && (n == 0 || uint64(n) > poolSize) {
+		return 0, fmt.Errorf("%q must be between 1 and MONGO_MAX_POOL_SIZE, %d", value, poolSize)
+	}
+	return n, nil
+}
+
+// Streams parses a cap on the calls open on one connection, 0 for none.
+func Streams(value string) (uint32, error) {
+	n, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number of streams", value)
+	}
+	return uint32(n), nil
+}
+
+// Secret checks a signing secret is at least minLen characters long.
+func Secret(value string, minLen int) error {
+	if len(value) < minLen {
+		return fmt.Errorf("must be at least %d characters, not %d", minLen, len(value))
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package settings
+
+import "testing"
+
+func TestPort(t *testing.T) {
+	for value, valid := range map[string]bool{
+		"1": true, "50051": true, "65535": true,
+		"": false, "0": false, "65536": false, "-1": false, "http": false, "80a": false,
+	} {
+		if err := Port(value); (err == nil) != valid {
+			t.Errorf("Port(%q) = %v, want valid %v", value, err, valid)
+		}
+	}
+}
+
+func TestMessageSize(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int
+		valid bool
+	}{
+		{"1", 1 << 20, true},
+		{"512", 512 << 20, true},
+		{"0", 0, false},
+		{"513", 0, false},
+		{"4MB", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := MessageSize(tt.value)
+		if (err == nil) != tt.valid || got != tt.want {
+			t.Errorf("MessageSize(%q) = %d, %v; want %d, valid %v", tt.value, got, err, tt.want, tt.valid)
+		}
+	}
+}
+
+func TestInFlight(t *testing.T) {
+	tests := []struct {
+		value    string
+		poolSize uint64
+		want     int
+		valid    bool
+	}{
+		{"", 0, 0, true},
+		{"", 100, 100, true},
+		{"0", 0, 0, true},
+		{"500", 0, 500, true},
+		{"100", 100, 100, true},
+		{"101", 100, 0, false},
+		{"0", 100, 0, false},
+		{"-1", 0, 0, false},
+		{"many", 0, 0, false},
+	}
+	for _, tt := range tests {
+		got, err := InFlight(tt.value, tt.poolSize)
+		if (err == nil) != tt.valid || got != tt.want {
+			t.Errorf("InFlight(%q, %d) = %d, %v; want %d, valid %v", tt.value, tt.poolSize, got, err, tt.want, tt.valid)
+		}
+	}
+}
+
+func TestStreams(t *testing.T) {
+	for value, valid := range map[string]bool{
+		"0": true, "1000": true, "-1": false, "4294967296": false, "lots": false,
+	} {
+		if _, err := Streams(value); (err == nil) != valid {
+			t.Errorf("Streams(%q) = %v, want valid %v", value, err, valid)
+		}
+	}
+}
+
+func TestSecret(t *testing.T) {
+	if err := Secret("short", 32); err == nil {
+		t.Error("accepted a 5 character secret")
+	}
+	if err := Secret("0123456789abcdef0123456789abcdef", 32); err != nil {
+		t.Error(err)
+	}
+}
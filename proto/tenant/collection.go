@@ -0,0 +1,301 @@
+package tenant
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Collection is a collection whose operations are limited to the tenant of
+// their context. Filters and pipelines only match the tenant's documents,
+// and documents inserted or replaced are stored with its tenant_id.
+// Operations it does not override, such as Indexes, apply to the whole
+// collection.
+type Collection struct {
+	*mongo.Collection
+}
+
+// Scoped wraps c so its operations respect the context's tenant.
+func Scoped(c *mongo.Collection) *Collection {
+	return &Collection{Collection: c}
+}
+
+// Filter returns the condition matching the tenant's documents. Documents
+// of the default tenant have no tenant_id.
+func Filter(id string) bson.M {
+	if id == "" {
+		return bson.M{Field: bson.M{"$in": bson.A{nil, ""}}}
+	}
+	return bson.M{Field: id}
+}
+
+func (c *Collection) filter(ctx context.Context, filter interface{}) interface{} {
+	id, ok := Scope(ctx)
+	if !ok {
+		return filter
+	}
+	if filter == nil {
+		return Filter(id)
+	}
+	return bson.M{"$and": bson.A{filter, Filter(id)}}
+}
+
+// document returns doc with the context's tenant_id in place of its own.
+func (c *Collection) document(ctx context.Context, doc interface{}) (interface{}, error) {
+	id, ok := Scope(ctx)
+	if !ok {
+		return doc, nil
+	}
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	elements, err := bson.Raw(raw).Elements()
+	if err != nil {
+		return nil, err
+	}
+	scopedDoc := make(bson.D, 0, len(elements)+1)
+	for _, e := range elements {
+		if e.Key() != Field {
+			scopedDoc = append(scopedDoc, bson.E{Key: e.Key(), Value: e.Value()})
+		}
+	}
+	if id != "" {
+		scopedDoc = append(scopedDoc, bson.E{Key: Field, Value: id})
+	}
+	return scopedDoc, nil
+}
+
+// searchStages must come first in a pipeline, so the tenant is matched
+// right after them.
+var searchStages = map[string]bool{"$search": true, "$searchMeta": true, "$vectorSearch": true, "$geoNear": true}
+
+// pipeline limits pipeline to the context's tenant.
+func (c *Collection) pipeline(ctx context.Context, pipeline interface{}) (interface{}, error) {
+	id, ok := Scope(ctx)
+	if !ok {
+		return pipeline, nil
+	}
+	return narrow(pipeline, Filter(id))
+}
+
+// narrow adds filter to pipeline: its leading $match is narrowed, or a
+// $match is added in front, after any stage that must come first.
+func narrow(pipeline interface{}, filter bson.M) (bson.A, error) {
+	var stages bson.A
+	switch p := pipeline.(type) {
+	case nil:
+	case mongo.Pipeline:
+		for _, stage := range p {
+			stages = append(stages, stage)
+		}
+	case []bson.D:
+		for _, stage := range p {
+			stages = append(stages, stage)
+		}
+	case []bson.M:
+		for _, stage := range p {
+			stages = append(stages, stage)
+		}
+	case bson.A:
+		stages = append(stages, p...)
+	case []interface{}:
+		stages = append(stages, p...)
+	default:
+		return nil, fmt.Errorf("tenant: cannot scope a %T pipeline", pipeline)
+	}
+
+	at := 0
+	if len(stages) > 0 && searchStages[stageName(stages[0])] {
+		at = 1
+	}
+	if at < len(stages) && stageName(stages[at]) == "$match" {
+		stages[at] = bson.M{"$match": bson.M{"$and": bson.A{stageBody(stages[at]), filter}}}
+		return stages, nil
+	}
+	scopedStages := append(bson.A{}, stages[:at]...)
+	scopedStages = append(scopedStages, bson.M{"$match": filter})
+	return append(scopedStages, stages[at:]...), nil
+}
+
+func stageName(stage interface{}) string {
+	switch s := stage.(type) {
+	case bson.D:
+		if len(s) == 1 {
+			return s[0].Key
+		}
+	case bson.M:
+		if len(s) == 1 {
+			for name := range s {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+func stageBody(stage interface{}) interface{} {
+	switch s := stage.(type) {
+	case bson.D:
+		return s[0].Value
+	case bson.M:
+		for _, body := range s {
+			return body
+		}
+	}
+	return nil
+}
+
+// Find and the methods after it are those of mongo.Collection, scoped to
+// the context's tenant.
+func (c *Collection) Find(ctx context.Context, filter interface{}, opts ...*options.FindOptions) (*mongo.Cursor, error) {
+	return c.Collection.Find(ctx, c.filter(ctx, filter), opts...)
+}
+
+func (c *Collection) FindOne(ctx context.Context, filter interface{}, opts ...*options.FindOneOptions) *mongo.SingleResult {
+	return c.Collection.FindOne(ctx, c.filter(ctx, filter), opts...)
+}
+
+func (c *Collection) FindOneAndUpdate(ctx context.Context, filter, update interface{}, opts ...*options.FindOneAndUpdateOptions) *mongo.SingleResult {
+	return c.Collection.FindOneAndUpdate(ctx, c.filter(ctx, filter), update, opts...)
+}
+
+func (c *Collection) FindOneAndDelete(ctx context.Context, filter interface{}, opts ...*options.FindOneAndDeleteOptions) *mongo.SingleResult {
+	return c.Collection.FindOneAndDelete(ctx, c.filter(ctx, filter), opts...)
+}
+
+func (c *Collection) FindOneAndReplace(ctx context.Context, filter, replacement interface{}, opts ...*options.FindOneAndReplaceOptions) *mongo.SingleResult {
+	doc, err := c.document(ctx, replacement)
+	if err != nil {
+		return mongo.NewSingleResultFromDocument(bson.D{}, err, nil)
+	}
+	return c.Collection.FindOneAndReplace(ctx, c.filter(ctx, filter), doc, opts...)
+}
+
+func (c *Collection) UpdateOne(ctx context.Context, filter, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	return c.Collection.UpdateOne(ctx, c.filter(ctx, filter), update, opts...)
+}
+
+func (c *Collection) UpdateByID(ctx context.Context, id, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	return c.UpdateOne(ctx, bson.M{"_id": id}, update, opts...)
+}
+
+func (c *Collection) UpdateMany(ctx context.Context, filter, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	return c.Collection.UpdateMany(ctx, c.filter(ctx, filter), update, opts...)
+}
+
+func (c *Collection) ReplaceOne(ctx context.Context, filter, replacement interface{}, opts ...*options.ReplaceOptions) (*mongo.UpdateResult, error) {
+	doc, err := c.document(ctx, replacement)
+	if err != nil {
+		return nil, err
+	}
+	return c.Collection.ReplaceOne(ctx, c.filter(ctx, filter), doc, opts...)
+}
+
+func (c *Collection) DeleteOne(ctx context.Context, filter interface{}, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error) {
+	return c.Collection.DeleteOne(ctx, c.filter(ctx, filter), opts...)
+}
+
+func (c *Collection) DeleteMany(ctx context.Context, filter interface{}, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error) {
+	return c.Collection.DeleteMany(ctx, c.filter(ctx, filter), opts...)
+}
+
+func (c *Collection) CountDocuments(ctx context.Context, filter interface{}, opts ...*options.CountOptions) (int64, error) {
+	return c.Collection.CountDocuments(ctx, c.filter(ctx, filter), opts...)
+}
+
+func (c *Collection) Distinct(ctx context.Context, fieldName string, filter interface{}, opts ...*options.DistinctOptions) ([]interface{}, error) {
+	return c.Collection.Distinct(ctx, fieldName, c.filter(ctx, filter), opts...)
+}
+
+func (c *Collection) Aggregate(ctx context.Context, pipeline interface{}, opts ...*options.AggregateOptions) (*mongo.Cursor, error) {
+	scopedPipeline, err := c.pipeline(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	return c.Collection.Aggregate(ctx, scopedPipeline, opts...)
+}
+
+// Watch only reports changes carrying a document of the tenant; deletions,
+// and updates without the full document, are only seen by unscoped
+// watchers.
+func (c *Collection) Watch(ctx context.Context, pipeline interface{}, opts ...*options.ChangeStreamOptions) (*mongo.ChangeStream, error) {
+	id, ok := Scope(ctx)
+	if !ok {
+		return c.Collection.Watch(ctx, pipeline, opts...)
+	}
+	scopedPipeline, err := narrow(pipeline, bson.M{"fullDocument." + Field: Filter(id)[Field]})
+	if err != nil {
+		return nil, err
+	}
+	return c.Collection.Watch(ctx, scopedPipeline, opts...)
+}
+
+func (c *Collection) InsertOne(ctx context.Context, document interface{}, opts ...*options.InsertOneOptions) (*mongo.InsertOneResult, error) {
+	doc, err := c.document(ctx, document)
+	if err != nil {
+		return nil, err
+	}
+	return c.Collection.InsertOne(ctx, doc, opts...)
+}
+
+func (c *Collection) InsertMany(ctx context.Context, documents []interface{}, opts ...*options.InsertManyOptions) (*mongo.InsertManyResult, error) {
+	docs := make([]interface{}, len(documents))
+	for i, document := range documents {
+		doc, err := c.document(ctx, document)
+		if err != nil {
+			return nil, err
+		}
+		docs[i] = doc
+	}
+	return c.Collection.InsertMany(ctx, docs, opts...)
+}
+
+// BulkWrite scopes each write the way the single operations do.
+func (c *Collection) BulkWrite(ctx context.Context, models []mongo.WriteModel, opts ...*options.BulkWriteOptions) (*mongo.BulkWriteResult, error) {
+	if _, ok := Scope(ctx); !ok {
+		return c.Collection.BulkWrite(ctx, models, opts...)
+	}
+	scopedModels := make([]mongo.WriteModel, len(models))
+	for i, model := range models {
+		switch m := model.(type) {
+		case *mongo.InsertOneModel:
+			doc, err := c.document(ctx, m.Document)
+			if err != nil {
+				return nil, err
+			}
+			scopedModels[i] = mongo.NewInsertOneModel().SetDocument(doc)
+		case *mongo.UpdateOneModel:
+			copied := *m
+			copied.Filter = c.filter(ctx, m.Filter)
+			scopedModels[i] = &copied
+		case *mongo.UpdateManyModel:
+			copied := *m
+			copied.Filter = c.filter(ctx, m.Filter)
+			scopedModels[i] = &copied
+		case *mongo.ReplaceOneModel:
+			doc, err := c.document(ctx, m.Replacement)
+			if err != nil {
+				return nil, err
+			}
+			copied := *m
+			copied.Filter = c.filter(ctx, m.Filter)
+			copied.Replacement = doc
+			scopedModels[i] = &copied
+		case *mongo.DeleteOneModel:
+			copied := *m
+			copied.Filter = c.filter(ctx, m.Filter)
+			scopedModels[i] = &copied
+		case *mongo.DeleteManyModel:
+			copied := *m
+			copied.Filter = c.filter(ctx, m.Filter)
+			scopedModels[i] = &copied
+		default:
+			return nil, fmt.Errorf("tenant: cannot scope a %T write", model)
+		}
+	}
+	return c.Collection.BulkWrite(ctx, scopedModels, opts...)
+}
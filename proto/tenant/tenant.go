@@ -0,0 +1,149 @@
+// Package tenant isolates organizations sharing the services. Every task,
+// user, notification and event belongs to a tenant, stored as tenant_id, as
+// does what the services keep alongside them, such as sessions, templates
+// and the analytics counters. A call only sees the documents of its own
+// tenant.
+//
+// The gateway takes the tenant from the tenant claim of the caller's token
+// and sends it as tenant-id metadata. The server interceptor puts it in the
+// call's context, outgoing calls pass it on, and a Collection opened with
+// Scoped adds it to every filter, pipeline and new document. Documents stored
+// before tenants existed, and calls whose token has no tenant claim, belong
+// to the default tenant, "". With REQUIRE_TENANT_CLAIM=true calls without a
+// tenant are refused instead.
+//
+// Contexts with no tenant at all, such as a background job's, are not
+// scoped; jobs calling another service for every tenant say so with All.
+package tenant
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// Header carries the caller's tenant between services
+	Header = "tenant-id"
+	// All is the tenant of calls made for every tenant, by background jobs
+	// that are not acting for anyone in particular
+	All = "*"
+	// Field is the document field holding the tenant
+	Field = "tenant_id"
+)
+
+type contextKey struct{}
+
+// WithID returns a context acting for tenant id, which may be "" for the
+// default tenant or All.
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the tenant ctx acts for, and whether it acts for one
+// at all.
+func FromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(contextKey{}).(string)
+	return id, ok
+}
+
+// Scope reports the tenant ctx's queries are limited to, for stores other
+// than a Collection to scope their own.
+func Scope(ctx context.Context) (string, bool) {
+	id, ok := FromContext(ctx)
+	if !ok || id == All {
+		return "", false
+	}
+	return id, true
+}
+
+// RequiredFromEnv reads REQUIRE_TENANT_CLAIM, which defaults to false.
+func RequiredFromEnv() (bool, error) {
+	raw, ok := os.LookupEnv("REQUIRE_TENANT_CLAIM")
+	if !ok || raw == "" {
+		return false, nil
+	}
+	required, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, fmt.Errorf("invalid REQUIRE_TENANT_CLAIM %q", raw)
+	}
+	return required, nil
+}
+
+// incoming returns the tenant the caller sent, and whether it sent one.
+func incoming(ctx context.Context) (string, bool) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if ids := md.Get(Header); len(ids) > 0 {
+		return ids[0], true
+	}
+	return "", false
+}
+
+func contextFor(ctx context.Context, required bool) (context.Context, error) {
+	id, ok := incoming(ctx)
+	if !ok && required {
+		st, _ := status.New(codes.Unauthenticated, "the call has no tenant").
+			WithDetails(&errdetails.ErrorInfo{Domain: "tenant", Reason: "TENANT_REQUIRED"})
+		return nil, st.Err()
+	}
+	return WithID(ctx, id), nil
+}
+
+// UnaryServerInterceptor makes the caller's tenant the call's, refusing
+// calls without one when required.
+func UnaryServerInterceptor(required bool) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := contextFor(ctx, required)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is UnaryServerInterceptor for streaming calls.
+func StreamServerInterceptor(required bool) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := contextFor(ss.Context(), required)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// outgoing adds the context's tenant to the call's metadata.
+func outgoing(ctx context.Context) context.Context {
+	id, ok := FromContext(ctx)
+	if !ok {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, Header, id)
+}
+
+// UnaryClientInterceptor passes the context's tenant on to the service
+// called.
+func UnaryClientInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return invoker(outgoing(ctx), method, req, reply, cc, opts...)
+}
+
+// StreamClientInterceptor is UnaryClientInterceptor for streaming calls.
+func StreamClientInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return streamer(outgoing(ctx), desc, cc, method, opts...)
+}
@@ -0,0 +1,135 @@
+package tenant
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestServerInterceptor(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/todo.TaskService/GetTask"}
+	tests := []struct {
+		name     string
+		md       metadata.MD
+		required bool
+		wantID   string
+		wantCode codes.Code
+	}{
+		{"tenant sent", metadata.Pairs(Header, "acme"), true, "acme", codes.OK},
+		{"default tenant", nil, false, "", codes.OK},
+		{"tenant required", nil, true, "", codes.Unauthenticated},
+		{"every tenant", metadata.Pairs(Header, All), true, All, codes.OK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := metadata.NewIncomingContext(context.Background(), tt.md)
+			var gotID string
+			var gotOK bool
+			_, err := UnaryServerInterceptor(tt.required)(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+				gotID, gotOK = FromContext(ctx)
+				return nil, nil
+			})
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("err = %v, want %v", err, tt.wantCode)
+			}
+			if err == nil && (!gotOK || gotID != tt.wantID) {
+				t.Errorf("tenant = %q, %v, want %q", gotID, gotOK, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestClientInterceptorPassesTenantOn(t *testing.T) {
+	var got metadata.MD
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		got, _ = metadata.FromOutgoingContext(ctx)
+		return nil
+	}
+	UnaryClientInterceptor(WithID(context.Background(), "acme"), "/m", nil, nil, nil, invoker)
+	if ids := got.Get(Header); len(ids) != 1 || ids[0] != "acme" {
+		t.Errorf("outgoing %s = %v, want acme", Header, ids)
+	}
+	UnaryClientInterceptor(context.Background(), "/m", nil, nil, nil, invoker)
+	if ids := got.Get(Header); len(ids) != 0 {
+		t.Errorf("a context without a tenant sent %v", ids)
+	}
+}
+
+func TestScopedFilterAndDocument(t *testing.T) {
+	c := &Collection{}
+	acme := WithID(context.Background(), "acme")
+
+	filter := bson.M{"user_id": "u1"}
+	if got := c.filter(context.Background(), filter); !reflect.DeepEqual(got, filter) {
+		t.Errorf("unscoped filter = %v", got)
+	}
+	if got := c.filter(WithID(context.Background(), All), filter); !reflect.DeepEqual(got, filter) {
+		t.Errorf("filter for every tenant = %v", got)
+	}
+	want := bson.M{"$and": bson.A{filter, bson.M{Field: "acme"}}}
+	if got := c.filter(acme, filter); !reflect.DeepEqual(got, want) {
+		t.Errorf("scoped filter = %v, want %v", got, want)
+	}
+
+	doc, err := c.document(acme, struct {
+		Title    string `bson:"title"`
+		TenantID string `bson:"tenant_id"`
+	}{"Report", "other"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stored map[string]string
+	if err := bson.Unmarshal(raw, &stored); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(stored, map[string]string{"title": "Report", Field: "acme"}) {
+		t.Errorf("stored %v, want the context's tenant in place of the document's", stored)
+	}
+}
+
+func TestScopedPipeline(t *testing.T) {
+	c := &Collection{}
+	acme := WithID(context.Background(), "acme")
+	scope := bson.M{Field: "acme"}
+	match := bson.M{"user_id": "u1"}
+	tests := []struct {
+		name     string
+		pipeline interface{}
+		want     bson.A
+	}{
+		{"leading match narrowed",
+			mongo.Pipeline{{{Key: "$match", Value: match}}, {{Key: "$count", Value: "n"}}},
+			bson.A{bson.M{"$match": bson.M{"$and": bson.A{match, scope}}}, bson.D{{Key: "$count", Value: "n"}}}},
+		{"match added in front",
+			[]bson.M{{"$group": bson.M{"_id": nil}}},
+			bson.A{bson.M{"$match": scope}, bson.M{"$group": bson.M{"_id": nil}}}},
+		{"after $search",
+			bson.A{bson.M{"$search": bson.M{}}, bson.M{"$limit": 5}},
+			bson.A{bson.M{"$search": bson.M{}}, bson.M{"$match": scope}, bson.M{"$limit": 5}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := c.pipeline(acme, tt.pipeline)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("pipeline = %v\nwant %v", got, tt.want)
+			}
+		})
+	}
+	if _, err := c.pipeline(acme, "not a pipeline"); err == nil {
+		t.Error("scoped a string as a pipeline")
+	}
+}
@@ -0,0 +1,345 @@
+// Package validation enforces the protoc-gen-validate rules on the fields of
+// todo.proto's messages. It reads the rules from the message descriptors at
+// run time rather than from generated code, so a rule added to the proto is
+// enforced as soon as the Go code is regenerated.
+//
+// Only the rules in supportedRules are enforced; TestTodoProtoRulesSupported
+// fails when the proto uses any other.
+package validation
+
+import (
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/envoyproxy/protoc-gen-validate/validate"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Domain and Reason fill the ErrorInfo of the errors Check returns.
+const (
+	Domain = "todo.validation"
+	Reason = "INVALID_FIELDS"
+)
+
+// Check validates msg. It returns nil, or an InvalidArgument error whose
+// details hold an ErrorInfo and a BadRequest listing every violation.
+func Check(msg proto.Message) error {
+	violations := Violations(msg)
+	if len(violations) == 0 {
+		return nil
+	}
+	described := make([]string, len(violations))
+	for i, v := range violations {
+		described[i] = v.Field + " " + v.Description
+	}
+	st := status.Newf(codes.InvalidArgument, "invalid %s: %s", msg.ProtoReflect().Descriptor().Name(), strings.Join(described, "; "))
+	detailed, err := st.WithDetails(
+		&errdetails.ErrorInfo{Domain: Domain, Reason: Reason},
+		&errdetails.BadRequest{FieldViolations: violations},
+	)
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+// Violations lists the fields of msg, including those of nested messages,
+// that break their rules. Fields are named by their proto path, such as
+// labels[2] or task.title.
+func Violations(msg proto.Message) []*errdetails.BadRequest_FieldViolation {
+	var v violations
+	v.message("", msg.ProtoReflect())
+	return v
+}
+
+type violations []*errdetails.BadRequest_FieldViolation
+
+func (v *violations) add(field, format string, args ...interface{}) {
+	*v = append(*v, &errdetails.BadRequest_FieldViolation{Field: field, Description: fmt.Sprintf(format, args...)})
+}
+
+func (v *violations) message(prefix string, m protoreflect.Message) {
+	fields := m.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		path := prefix + string(fd.Name())
+		rules := fieldRules(fd)
+		switch {
+		case fd.IsList():
+			v.list(path, fd, m.Get(fd).List(), rules.GetRepeated())
+		case fd.IsMap():
+			if fd.MapValue().Message() != nil {
+				m.Get(fd).Map().Range(func(key protoreflect.MapKey, value protoreflect.Value) bool {
+					v.message(fmt.Sprintf("%s[%v].", path, key.Interface()), value.Message())
+					return true
+				})
+			}
+		case fd.Message() != nil:
+			if !m.Has(fd) {
+				if rules.GetMessage().GetRequired() {
+					v.add(path, "required")
+				}
+			} else if !rules.GetMessage().GetSkip() {
+				v.message(path+".", m.Get(fd).Message())
+			}
+		default:
+			v.scalar(path, fd, m.Get(fd), rules)
+		}
+	}
+}
+
+func (v *violations) list(path string, fd protoreflect.FieldDescriptor, list protoreflect.List, rules *validate.RepeatedRules) {
+	n := uint64(list.Len())
+	if rules != nil && !(rules.GetIgnoreEmpty() && n == 0) {
+		switch {
+		case rules.MinItems != nil && n < *rules.MinItems:
+			v.add(path, "must have at least %d items", *rules.MinItems)
+		case rules.MaxItems != nil && n > *rules.MaxItems:
+			v.add(path, "must have at most %d items", *rules.MaxItems)
+		case rules.GetUnique() && hasDuplicates(list):
+			v.add(path, "must not repeat items")
+		}
+	}
+	for i := 0; i < list.Len(); i++ {
+		item := fmt.Sprintf("%s[%d]", path, i)
+		if fd.Message() != nil {
+			v.message(item+".", list.Get(i).Message())
+		} else {
+			v.scalar(item, fd, list.Get(i), rules.GetItems())
+		}
+	}
+}
+
+func hasDuplicates(list protoreflect.List) bool {
+	seen := make(map[interface{}]bool, list.Len())
+	for i := 0; i < list.Len(); i++ {
+		item := list.Get(i).Interface()
+		if seen[item] {
+			return true
+		}
+		seen[item] = true
+	}
+	return false
+}
+
+func (v *violations) scalar(path string, fd protoreflect.FieldDescriptor, value protoreflect.Value, rules *validate.FieldRules) {
+	var problem string
+	switch {
+	case rules.GetString_() != nil:
+		problem = checkString(value.String(), rules.GetString_())
+	case rules.GetInt32() != nil:
+		problem = checkInt(value.Int(), int32Rules(rules.GetInt32()))
+	case rules.GetInt64() != nil:
+		problem = checkInt(value.Int(), int64Rules(rules.GetInt64()))
+	case rules.GetEnum() != nil:
+		problem = checkEnum(value.Enum(), fd.Enum(), rules.GetEnum())
+	}
+	if problem != "" {
+		v.add(path, "%s", problem)
+	}
+}
+
+func checkString(s string, rules *validate.StringRules) string {
+	if rules.GetIgnoreEmpty() && s == "" {
+		return ""
+	}
+	n := uint64(utf8.RuneCountInString(s))
+	switch {
+	case s == "" && (rules.GetMinLen() > 0 || rules.GetLen() > 0 || rules.GetEmail()):
+		return "required"
+	case rules.Len != nil && n != *rules.Len:
+		return fmt.Sprintf("must be exactly %d characters", *rules.Len)
+	case rules.MinLen != nil && n < *rules.MinLen:
+		return fmt.Sprintf("must be at least %d characters", *rules.MinLen)
+	case rules.MaxLen != nil && n > *rules.MaxLen:
+		return fmt.Sprintf("must be at most %d characters", *rules.MaxLen)
+	case rules.MaxBytes != nil && uint64(len(s)) > *rules.MaxBytes:
+		return fmt.Sprintf("must be at most %d bytes", *rules.MaxBytes)
+	case rules.Pattern != nil && !pattern(*rules.Pattern).MatchString(s):
+		return fmt.Sprintf("must match %s", *rules.Pattern)
+	case len(rules.In) > 0 && !containsString(rules.In, s):
+		return fmt.Sprintf("must be one of %s", strings.Join(rules.In, ", "))
+	case containsString(rules.NotIn, s):
+		return fmt.Sprintf("must not be %q", s)
+	case rules.GetEmail() && !isEmail(s):
+		return "must be an email address"
+	}
+	return ""
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// isEmail accepts a bare address such as alice@example.com, without a
+// display name or angle brackets.
+func isEmail(s string) bool {
+	addr, err := mail.ParseAddress(s)
+	return err == nil && addr.Name == "" && addr.Address == s
+}
+
+var patterns sync.Map
+
+// pattern compiles a rule's RE2 pattern once. An invalid pattern is a bug in
+// the proto, which TestTodoProtoRulesSupported catches.
+func pattern(expr string) *regexp.Regexp {
+	if re, ok := patterns.Load(expr); ok {
+		return re.(*regexp.Regexp)
+	}
+	re := regexp.MustCompile(expr)
+	patterns.Store(expr, re)
+	return re
+}
+
+// intRules are the rules of any integer width, widened to int64.
+type intRules struct {
+	lt, lte, gt, gte *int64
+	in, notIn        []int64
+	ignoreEmpty      bool
+}
+
+func int32Rules(r *validate.Int32Rules) intRules {
+	widen := func(p *int32) *int64 {
+		if p == nil {
+			return nil
+		}
+		n := int64(*p)
+		return &n
+	}
+	return intRules{
+		lt: widen(r.Lt), lte: widen(r.Lte), gt: widen(r.Gt), gte: widen(r.Gte),
+		in: widenAll(r.In), notIn: widenAll(r.NotIn), ignoreEmpty: r.GetIgnoreEmpty(),
+	}
+}
+
+func int64Rules(r *validate.Int64Rules) intRules {
+	return intRules{lt: r.Lt, lte: r.Lte, gt: r.Gt, gte: r.Gte, in: r.In, notIn: r.NotIn, ignoreEmpty: r.GetIgnoreEmpty()}
+}
+
+func checkInt(n int64, rules intRules) string {
+	if rules.ignoreEmpty && n == 0 {
+		return ""
+	}
+	switch {
+	case rules.gt != nil && n <= *rules.gt:
+		return fmt.Sprintf("must be greater than %d", *rules.gt)
+	case rules.gte != nil && n < *rules.gte:
+		return fmt.Sprintf("must be at least %d", *rules.gte)
+	case rules.lt != nil && n >= *rules.lt:
+		return fmt.Sprintf("must be less than %d", *rules.lt)
+	case rules.lte != nil && n > *rules.lte:
+		return fmt.Sprintf("must be at most %d", *rules.lte)
+	case len(rules.in) > 0 && !containsInt(rules.in, n):
+		return fmt.Sprintf("must be one of %v", rules.in)
+	case containsInt(rules.notIn, n):
+		return fmt.Sprintf("must not be %d", n)
+	}
+	return ""
+}
+
+func containsInt(values []int64, n int64) bool {
+	for _, v := range values {
+		if v == n {
+			return true
+		}
+	}
+	return false
+}
+
+func checkEnum(n protoreflect.EnumNumber, enum protoreflect.EnumDescriptor, rules *validate.EnumRules) string {
+	switch {
+	case rules.GetDefinedOnly() && enum.Values().ByNumber(n) == nil:
+		return fmt.Sprintf("unknown %s %d", enum.Name(), n)
+	case len(rules.In) > 0 && !containsInt(widenAll(rules.In), int64(n)):
+		return fmt.Sprintf("must be one of %v", rules.In)
+	case containsInt(widenAll(rules.NotIn), int64(n)):
+		return fmt.Sprintf("must not be %d", n)
+	}
+	return ""
+}
+
+func widenAll(values []int32) []int64 {
+	wide := make([]int64, len(values))
+	for i, v := range values {
+		wide[i] = int64(v)
+	}
+	return wide
+}
+
+// fieldRules returns the rules annotated on fd, or nil.
+func fieldRules(fd protoreflect.FieldDescriptor) *validate.FieldRules {
+	rules, _ := proto.GetExtension(fd.Options(), validate.E_Rules).(*validate.FieldRules)
+	return rules
+}
+
+// supportedRules lists the rules enforced, by the kind of field they apply to.
+var supportedRules = map[protoreflect.Name]map[protoreflect.Name]bool{
+	"string":   {"len": true, "min_len": true, "max_len": true, "max_bytes": true, "pattern": true, "in": true, "not_in": true, "email": true, "ignore_empty": true},
+	"int32":    {"lt": true, "lte": true, "gt": true, "gte": true, "in": true, "not_in": true, "ignore_empty": true},
+	"int64":    {"lt": true, "lte": true, "gt": true, "gte": true, "in": true, "not_in": true, "ignore_empty": true},
+	"enum":     {"defined_only": true, "in": true, "not_in": true},
+	"repeated": {"min_items": true, "max_items": true, "unique": true, "items": true, "ignore_empty": true},
+	"message":  {"required": true, "skip": true},
+}
+
+// unsupported describes the rules on fd that are not enforced, or do not
+// fit its kind.
+func unsupported(fd protoreflect.FieldDescriptor, rules *validate.FieldRules, repeated bool) []string {
+	var problems []string
+	rules.ProtoReflect().Range(func(kind protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		supported, ok := supportedRules[kind.Name()]
+		if !ok || !fits(kind.Name(), fd, repeated) {
+			problems = append(problems, fmt.Sprintf("%s: %s rules on a %s field", fd.FullName(), kind.Name(), fd.Kind()))
+			return true
+		}
+		value.Message().Range(func(rule protoreflect.FieldDescriptor, ruleValue protoreflect.Value) bool {
+			switch {
+			case !supported[rule.Name()]:
+				problems = append(problems, fmt.Sprintf("%s: %s.%s is not enforced", fd.FullName(), kind.Name(), rule.Name()))
+			case rule.Name() == "items":
+				problems = append(problems, unsupported(fd, ruleValue.Message().Interface().(*validate.FieldRules), false)...)
+			case rule.Name() == "pattern":
+				if _, err := regexp.Compile(ruleValue.String()); err != nil {
+					problems = append(problems, fmt.Sprintf("%s: %v", fd.FullName(), err))
+				}
+			}
+			return true
+		})
+		return true
+	})
+	return problems
+}
+
+// fits reports whether rules of the given kind apply to fd; repeated is set
+// while looking at fd as a whole rather than at one of its items.
+func fits(kind protoreflect.Name, fd protoreflect.FieldDescriptor, repeated bool) bool {
+	if repeated && fd.IsList() {
+		return kind == "repeated"
+	}
+	switch kind {
+	case "string":
+		return fd.Kind() == protoreflect.StringKind
+	case "int32":
+		return fd.Kind() == protoreflect.Int32Kind
+	case "int64":
+		return fd.Kind() == protoreflect.Int64Kind
+	case "enum":
+		return fd.Kind() == protoreflect.EnumKind
+	case "message":
+		return fd.Message() != nil && !fd.IsList() && !fd.IsMap()
+	}
+	return false
+}
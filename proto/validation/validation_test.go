@@ -0,0 +1,104 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+	"github.com/technonext/todo-app/proto/proto/common"
+)
+
+func TestTodoProtoRulesSupported(t *testing.T) {
+	var walk func(messages protoreflect.MessageDescriptors)
+	walk = func(messages protoreflect.MessageDescriptors) {
+		for i := 0; i < messages.Len(); i++ {
+			fields := messages.Get(i).Fields()
+			for j := 0; j < fields.Len(); j++ {
+				if rules := fieldRules(fields.Get(j)); rules != nil {
+					for _, problem := range unsupported(fields.Get(j), rules, true) {
+						t.Error(problem)
+					}
+				}
+			}
+			walk(messages.Get(i).Messages())
+		}
+	}
+	walk(pb.File_proto_todo_proto.Messages())
+	walk(common.File_proto_common_pagination_proto.Messages())
+}
+
+func TestViolations(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  proto.Message
+		want map[string]string
+	}{
+		{"valid task", &pb.CreateTaskRequest{Title: "Write report", UserId: "u1", DueDate: "2026-03-01T09:00:00Z", Labels: []string{"work"}}, nil},
+		{"missing title", &pb.CreateTaskRequest{UserId: "u1"}, map[string]string{"title": "required"}},
+		{"long title", &pb.CreateTaskRequest{Title: strings.Repeat("é", 201), UserId: "u1"}, map[string]string{"title": "must be at most 200 characters"}},
+		{"bad due date", &pb.CreateTaskRequest{Title: "t", UserId: "u1", DueDate: "2026-13-01T09:00:00Z"}, map[string]string{"due_date": ""}},
+		{"long label", &pb.CreateTaskRequest{Title: "t", UserId: "u1", Labels: []string{"ok", strings.Repeat("a", 51)}}, map[string]string{"labels[1]": "must be at most 50 characters"}},
+		{"too many labels", &pb.CreateTaskRequest{Title: "t", UserId: "u1", Labels: make([]string, 21)}, map[string]string{"labels": "must have at most 20 items"}},
+		{"unknown priority", &pb.CreateTaskRequest{Title: "t", UserId: "u1", Priority: 99}, map[string]string{"priority": "unknown TaskPriority 99"}},
+		{"notes too large", &pb.UpdateTaskNotesRequest{Id: "t1", Notes: strings.Repeat("é", 51201)}, map[string]string{"notes": "must be at most 102400 bytes"}},
+		{"notes at the limit", &pb.UpdateTaskNotesRequest{Id: "t1", Notes: strings.Repeat("é", 51200)}, nil},
+		{"negative estimate", &pb.UpdateTaskRequest{Title: "t", EstimatedMinutes: -1}, map[string]string{"estimated_minutes": "must be at least 0"}},
+		{"limit too high", &pb.ListTasksRequest{UserId: "u1", Limit: 101}, map[string]string{"limit": "must be at most 100"}},
+		{"page limit too high", &pb.ListTasksRequest{UserId: "u1", Pagination: &common.PageRequest{Limit: 101}}, map[string]string{"pagination.limit": "must be at most 100"}},
+		{"bad email", &pb.CreateUserRequest{Username: "alice", Email: "Alice <a@example.com>", Password: "secret123"}, map[string]string{"email": "must be an email address"}},
+		{"several", &pb.CreateUserRequest{Username: "al", Password: "short"}, map[string]string{"username": "must be at least 3 characters", "email": "required", "password": "must be at least 8 characters"}},
+		{"password kept", &pb.UpdateUserRequest{Username: "alice", Email: "a@example.com"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make(map[string]string)
+			for _, v := range Violations(tt.msg) {
+				got[v.Field] = v.Description
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("violations = %v, want %v", got, tt.want)
+			}
+			for field, want := range tt.want {
+				description, ok := got[field]
+				if !ok || (want != "" && description != want) {
+					t.Errorf("%s: %q, want %q", field, description, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckDetails(t *testing.T) {
+	if err := Check(&pb.CreateTaskRequest{Title: "t", UserId: "u1"}); err != nil {
+		t.Fatalf("valid request: %v", err)
+	}
+
+	st := status.Convert(Check(&pb.CreateTaskRequest{UserId: "u1", EstimatedMinutes: -5}))
+	if st.Code() != codes.InvalidArgument {
+		t.Fatalf("code = %v, want InvalidArgument", st.Code())
+	}
+	var info *errdetails.ErrorInfo
+	var fields []string
+	for _, detail := range st.Details() {
+		switch d := detail.(type) {
+		case *errdetails.ErrorInfo:
+			info = d
+		case *errdetails.BadRequest:
+			for _, v := range d.FieldViolations {
+				fields = append(fields, v.Field)
+			}
+		}
+	}
+	if info == nil || info.Reason != Reason {
+		t.Errorf("ErrorInfo = %v, want reason %s", info, Reason)
+	}
+	if strings.Join(fields, ",") != "title,estimated_minutes" {
+		t.Errorf("fields = %v, want title and estimated_minutes", fields)
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/grpc/codes"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+	"github.com/technonext/todo-app/proto/publicid"
+)
+
+// bulkUpdate returns the update setting the fields of updates named in
+// paths, as UpdateTask would set them.
+func bulkUpdate(updates *pb.TaskUpdate, paths []string, now string) (bson.M, error) {
+	if len(paths) == 0 {
+		return nil, statusError(codes.InvalidArgument, "EMPTY_UPDATE_MASK", nil, "update_mask must name at least one field")
+	}
+	if updates == nil {
+		updates = &pb.TaskUpdate{}
+	}
+	set := bson.M{"updated_at": now}
+	update := bson.M{"$set": set, "$inc": bson.M{"edit_count": 1}}
+	for _, path := range paths {
+		switch path {
+		case "title":
+			if updates.Title == "" {
+				return nil, statusError(codes.InvalidArgument, "TITLE_REQUIRED", nil, "title cannot be empty")
+			}
+			set["title"] = updates.Title
+		case "description":
+			set["description"] = updates.Description
+		case "due_date":
+			set["due_date"] = taskDate(updates.DueDate)
+			// A new due date gets an alert of its own
+			set["deadline_alert_sent"] = false
+		case "priority":
+			if updates.Priority == pb.TaskPriority_TASK_PRIORITY_UNSPECIFIED {
+				update["$unset"] = bson.M{"priority": ""}
+			} else {
+				set["priority"] = updates.Priority.String()
+			}
+		case "labels":
+			set["labels"] = normalizeLabels(updates.Labels)
+		case "estimated_minutes":
+			set["estimated_minutes"] = updates.EstimatedMinutes
+		default:
+			return nil, statusError(codes.InvalidArgument, "INVALID_UPDATE_MASK", map[string]string{"path": path}, "unknown update_mask path %q", path)
+		}
+	}
+	return update, nil
+}
+
+// BulkUpdateTasks applies the same change to several of a user's tasks with
+// a single update. Unlike UpdateTask it records no task.updated events, since
+// one update cannot stage a snapshot of each task it changes.
+func (s *server) BulkUpdateTasks(ctx context.Context, req *pb.BulkUpdateTasksRequest) (*pb.BulkUpdateTasksResponse, error) {
+	userID := requestingUser(ctx, req.UserId)
+	if userID == "" {
+		return nil, statusError(codes.InvalidArgument, "USER_ID_REQUIRED", nil, "user_id is required")
+	}
+	now := time.Now()
+	update, err := bulkUpdate(req.Updates, req.UpdateMask.GetPaths(), now.Format(time.RFC3339))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkNotFrozen(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	// Ids that are neither public ids nor ObjectIDs name no task, so they
+	// are only reported
+	oids := []primitive.ObjectID{}
+	publicIDs := []string{}
+	for _, id := range req.Ids {
+		if publicid.Valid(publicid.Task, id) {
+			publicIDs = append(publicIDs, id)
+		} else if oid, err := primitive.ObjectIDFromHex(id); err == nil {
+			oids = append(oids, oid)
+		}
+	}
+	filter := bson.M{
+		"user_id":    userID,
+		"is_deleted": notDeleted,
+		"$and": bson.A{
+			bson.M{"$or": bson.A{bson.M{"_id": bson.M{"$in": oids}}, bson.M{publicid.Field: bson.M{"$in": publicIDs}}}},
+			editableBy(userID, lockTime(now)),
+		},
+	}
+	// The tasks matching are looked up first, so the response can name the
+	// ids that matched nothing
+	cursor, err := s.collection.Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1, publicid.Field: 1}))
+	if err != nil {
+		return nil, err
+	}
+	var matched []Task
+	if err := cursor.All(ctx, &matched); err != nil {
+		return nil, err
+	}
+	found := make(map[string]bool, 2*len(matched))
+	for _, task := range matched {
+		found[task.ID.Hex()] = true
+		if task.PublicID != "" {
+			found[task.PublicID] = true
+		}
+	}
+	result, err := s.collection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &pb.BulkUpdateTasksResponse{
+		MatchedCount:  int32(result.MatchedCount),
+		ModifiedCount: int32(result.ModifiedCount),
+	}
+	for _, id := range req.Ids {
+		if !found[id] {
+			// Listed once, however often the request names it
+			found[id] = true
+			resp.NotFoundIds = append(resp.NotFoundIds, id)
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,87 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+func TestBulkUpdateTasksReportsUnmatchedIDs(t *testing.T) {
+	s := newTestServer(t, nil)
+	ctx := context.Background()
+
+	var ids []string
+	for _, title := range []string{"Plan sprint", "Review PRs", "Demo", "Locked elsewhere"} {
+		created, err := s.CreateTask(ctx, &pb.CreateTaskRequest{UserId: "u1", Title: title, Labels: []string{"backlog"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, created.Task.Id)
+	}
+	others, err := s.CreateTask(ctx, &pb.CreateTaskRequest{UserId: "u2", Title: "Someone else's"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.DeleteTask(ctx, &pb.DeleteTaskRequest{Id: ids[2]}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.LockTask(ctx, &pb.LockTaskRequest{Id: ids[3], UserId: "u2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	missing := "64b000000000000000000000"
+	resp, err := s.BulkUpdateTasks(ctx, &pb.BulkUpdateTasksRequest{
+		UserId:     "u1",
+		Ids:        []string{ids[0], ids[1], ids[1], ids[2], ids[3], others.Task.Id, missing, "not-an-id"},
+		Updates:    &pb.TaskUpdate{Title: "Ignored", Labels: []string{"sprint-12"}, Priority: pb.TaskPriority_TASK_PRIORITY_HIGH},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"labels", "priority"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.MatchedCount != 2 || resp.ModifiedCount != 2 {
+		t.Errorf("matched %d and modified %d, want 2 and 2", resp.MatchedCount, resp.ModifiedCount)
+	}
+	// Deleted, locked by someone else, owned by someone else, missing, invalid
+	wantNotFound := []string{ids[2], ids[3], others.Task.Id, missing, "not-an-id"}
+	if !reflect.DeepEqual(resp.NotFoundIds, wantNotFound) {
+		t.Errorf("not found = %v, want %v", resp.NotFoundIds, wantNotFound)
+	}
+
+	for i, id := range ids[:2] {
+		got, err := s.GetTask(ctx, &pb.GetTaskRequest{Id: id, SkipView: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Task.Title == "Ignored" || !reflect.DeepEqual(got.Task.Labels, []string{"sprint-12"}) || got.Task.Priority != pb.TaskPriority_TASK_PRIORITY_HIGH {
+			t.Errorf("task %d = %v, want only its labels and priority changed", i, got.Task)
+		}
+	}
+	locked, err := s.GetTask(ctx, &pb.GetTaskRequest{Id: ids[3], SkipView: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(locked.Task.Labels, []string{"backlog"}) {
+		t.Errorf("locked task's labels = %v, want them unchanged", locked.Task.Labels)
+	}
+
+	// Naming only tasks that match reports nothing missing
+	again, err := s.BulkUpdateTasks(ctx, &pb.BulkUpdateTasksRequest{
+		UserId:     "u1",
+		Ids:        ids[:2],
+		Updates:    &pb.TaskUpdate{Labels: []string{"sprint-12"}},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"labels"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.MatchedCount != 2 || len(again.NotFoundIds) != 0 {
+		t.Errorf("second update = %v, want both tasks matched", again)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+func TestBulkUpdateSetsMaskedFields(t *testing.T) {
+	updates := &pb.TaskUpdate{
+		Title:    "Not applied",
+		Priority: pb.TaskPriority_TASK_PRIORITY_HIGH,
+		Labels:   []string{" Sprint-12 ", "sprint-12", "Review"},
+	}
+	update, err := bulkUpdate(updates, []string{"priority", "labels"}, "now")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := bson.M{
+		"$set": bson.M{"priority": "TASK_PRIORITY_HIGH", "labels": []string{"sprint-12", "review"}, "updated_at": "now"},
+		"$inc": bson.M{"edit_count": 1},
+	}
+	if !reflect.DeepEqual(update, want) {
+		t.Errorf("update = %v, want %v", update, want)
+	}
+
+	update, err = bulkUpdate(&pb.TaskUpdate{}, []string{"priority", "due_date"}, "now")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unset := update["$unset"]; !reflect.DeepEqual(unset, bson.M{"priority": ""}) {
+		t.Errorf("$unset = %v, want the priority removed", unset)
+	}
+	if set := update["$set"].(bson.M); set["due_date"] != taskDate("") || set["deadline_alert_sent"] != false {
+		t.Errorf("$set = %v, want the due date and its alert cleared", set)
+	}
+}
+
+func TestBulkUpdateRejectsBadMasks(t *testing.T) {
+	tests := []struct {
+		name   string
+		paths  []string
+		reason string
+	}{
+		{"empty mask", nil, "EMPTY_UPDATE_MASK"},
+		{"unknown field", []string{"labels", "user_id"}, "INVALID_UPDATE_MASK"},
+		{"empty title", []string{"title"}, "TITLE_REQUIRED"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := bulkUpdate(&pb.TaskUpdate{}, tt.paths, "now")
+			if status.Code(err) != codes.InvalidArgument {
+				t.Fatalf("err = %v, want InvalidArgument", err)
+			}
+			if reason, _ := errorInfo(err); reason != tt.reason {
+				t.Errorf("reason = %s, want %s", reason, tt.reason)
+			}
+		})
+	}
+}
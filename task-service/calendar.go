@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"google.golang.org/grpc/codes"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// GetTasksCalendarView groups a user's tasks due in one month by day, for
+// calendar UIs. Completed and incomplete tasks are both included; tasks
+// without a due date are not.
+func (s *server) GetTasksCalendarView(ctx context.Context, req *pb.GetTasksCalendarViewRequest) (*pb.GetTasksCalendarViewResponse, error) {
+	if req.UserId == "" {
+		return nil, statusError(codes.InvalidArgument, "USER_ID_REQUIRED", nil, "user_id is required")
+	}
+	if req.Month < 1 || req.Month > 12 {
+		return nil, statusError(codes.InvalidArgument, "INVALID_MONTH", map[string]string{"month": strconv.Itoa(int(req.Month))}, "month must be between 1 and 12, got %d", req.Month)
+	}
+	if req.Year < 1 || req.Year > 9999 {
+		return nil, statusError(codes.InvalidArgument, "INVALID_YEAR", map[string]string{"year": strconv.Itoa(int(req.Year))}, "year must be between 1 and 9999, got %d", req.Year)
+	}
+	timezone := req.Timezone
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, statusError(codes.InvalidArgument, "INVALID_TIMEZONE", map[string]string{"timezone": req.Timezone}, "invalid timezone %q", req.Timezone)
+	}
+
+	start := time.Date(int(req.Year), time.Month(req.Month), 1, 0, 0, 0, 0, loc)
+	end := start.AddDate(0, 1, 0)
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"user_id": req.UserId, "is_deleted": notDeleted}}},
+		// due_date is a date or an RFC3339 string; anything unparseable has no due date
+		{{Key: "$addFields", Value: bson.M{"due": toDate("$due_date")}}},
+		{{Key: "$match", Value: bson.M{"due": bson.M{"$gte": start, "$lt": end}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "due", Value: 1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{"$dateToString": bson.M{
+				"format":   "%Y-%m-%d",
+				"date":     "$due",
+				"timezone": timezone,
+			}},
+			"tasks": bson.M{"$push": "$$ROOT"},
+			"count": bson.M{"$sum": 1},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+	}
+
+	cursor, err := s.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	resp := &pb.GetTasksCalendarViewResponse{}
+	for cursor.Next(ctx) {
+		var row struct {
+			Date  string `bson:"_id"`
+			Tasks []Task `bson:"tasks"`
+			Count int32  `bson:"count"`
+		}
+		if err := cursor.Decode(&row); err != nil {
+			return nil, err
+		}
+
+		day := &pb.DayBucket{Date: row.Date, TaskCount: row.Count}
+		for _, task := range row.Tasks {
+			day.Tasks = append(day.Tasks, task.toProto())
+		}
+		resp.Days = append(resp.Days, day)
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
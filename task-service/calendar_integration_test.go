@@ -0,0 +1,87 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+func TestGetTasksCalendarView(t *testing.T) {
+	s := newTestServer(t, nil)
+	ctx := context.Background()
+	for _, task := range []struct{ userID, title, due string }{
+		{"u1", "Last of January", "2024-01-31T23:59:59Z"},
+		{"u1", "First of February", "2024-02-01T00:00:00Z"},
+		{"u1", "Leap day", "2024-02-29T09:00:00Z"},
+		{"u1", "Late on leap day", "2024-02-29T20:00:00Z"},
+		{"u1", "First of March", "2024-03-01T00:00:00Z"},
+		{"u1", "End of a short February", "2023-02-28T23:59:59Z"},
+		{"u1", "After a short February", "2023-03-01T00:00:00Z"},
+		{"u1", "Someday", ""},
+		{"u2", "Not mine", "2024-02-29T09:00:00Z"},
+	} {
+		if _, err := s.CreateTask(ctx, &pb.CreateTaskRequest{UserId: task.userID, Title: task.title, DueDate: task.due}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Completed tasks are on the calendar too
+	done, err := s.CreateTask(ctx, &pb.CreateTaskRequest{UserId: "u1", Title: "Done on leap day", DueDate: "2024-02-29T12:00:00Z"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.UpdateTask(ctx, &pb.UpdateTaskRequest{Id: done.Task.Id, Title: done.Task.Title, DueDate: done.Task.DueDate, Completed: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		year     int32
+		month    int32
+		timezone string
+		want     map[string][]string
+	}{
+		{"leap year February", 2024, 2, "", map[string][]string{
+			"2024-02-01": {"First of February"},
+			"2024-02-29": {"Done on leap day", "Late on leap day", "Leap day"},
+		}},
+		{"month boundaries in a timezone", 2024, 2, "Asia/Dhaka", map[string][]string{
+			"2024-02-01": {"First of February", "Last of January"},
+			"2024-02-29": {"Done on leap day", "Leap day"},
+		}},
+		{"March after a leap day", 2024, 3, "", map[string][]string{
+			"2024-03-01": {"First of March"},
+		}},
+		{"non-leap year February", 2023, 2, "", map[string][]string{
+			"2023-02-28": {"End of a short February"},
+		}},
+		{"month with no tasks", 2024, 4, "", map[string][]string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := s.GetTasksCalendarView(ctx, &pb.GetTasksCalendarViewRequest{UserId: "u1", Year: tt.year, Month: tt.month, Timezone: tt.timezone})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(resp.Days) != len(tt.want) {
+				t.Fatalf("got %d days, want %v", len(resp.Days), tt.want)
+			}
+			for i, day := range resp.Days {
+				if i > 0 && day.Date <= resp.Days[i-1].Date {
+					t.Errorf("%s comes after %s", day.Date, resp.Days[i-1].Date)
+				}
+				want, ok := tt.want[day.Date]
+				if !ok {
+					t.Errorf("unexpected day %s", day.Date)
+					continue
+				}
+				if got := titles(day.Tasks); !slices.Equal(got, want) || int(day.TaskCount) != len(want) {
+					t.Errorf("%s has %q (count %d), want %q", day.Date, got, day.TaskCount, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/grpc/codes"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// maxChecklistItems caps a task's checklist.
+const maxChecklistItems = 20
+
+// ChecklistItem is a lightweight sub-step of a task, embedded in it rather
+// than stored as a task of its own.
+type ChecklistItem struct {
+	ID        string `bson:"id"`
+	Text      string `bson:"text"`
+	Done      bool   `bson:"done"`
+	CreatedAt string `bson:"created_at"`
+}
+
+// checklistDone reports whether items is a checklist with every item done,
+// which sets a task's completed hint.
+func checklistDone(items []ChecklistItem) bool {
+	for _, item := range items {
+		if !item.Done {
+			return false
+		}
+	}
+	return len(items) > 0
+}
+
+// checklistIndex returns the index of the item with id, or -1.
+func checklistIndex(items []ChecklistItem, id string) int {
+	for i, item := range items {
+		if item.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func checklistItemNotFound(taskID, itemID string) error {
+	return statusError(codes.NotFound, "CHECKLIST_ITEM_NOT_FOUND", map[string]string{"task_id": taskID, "item_id": itemID},
+		"task %s has no checklist item %s", taskID, itemID)
+}
+
+// checklistAsRead matches the checklist a task was read with. Items are
+// stored from ChecklistItem, so an unchanged checklist is equal field for
+// field; tasks without items have none or an emptied one.
+func checklistAsRead(items []ChecklistItem) interface{} {
+	if len(items) == 0 {
+		return bson.M{"$in": bson.A{nil, bson.A{}}}
+	}
+	return items
+}
+
+// writableTask reads the task id names for a change, failing unless its
+// owner's account can make changes.
+func (s *server) writableTask(ctx context.Context, id string) (Task, error) {
+	match, err := taskFilter(id)
+	if err != nil {
+		return Task{}, err
+	}
+	match["is_deleted"] = notDeleted
+
+	var task Task
+	err = s.collection.FindOne(ctx, match).Decode(&task)
+	if err == mongo.ErrNoDocuments {
+		return task, statusError(codes.NotFound, "TASK_NOT_FOUND", map[string]string{"task_id": id}, "task %s not found", id)
+	}
+	if err != nil {
+		return task, err
+	}
+	return task, s.checkNotFrozen(ctx, task.UserID)
+}
+
+// changeChecklist applies update, which leaves current with the checklist
+// items, and sets the completed hint to match. The update only applies while
+// the checklist is still as read, so concurrent changes can neither
+// overfill it nor leave the hint wrong.
+func (s *server) changeChecklist(ctx context.Context, current Task, items []ChecklistItem, update bson.M, opts ...*options.FindOneAndUpdateOptions) (*pb.TaskResponse, error) {
+	now := time.Now().Format(time.RFC3339)
+	hint := checklistDone(items)
+	set, _ := update["$set"].(bson.M)
+	if set == nil {
+		set = bson.M{}
+		update["$set"] = set
+	}
+	set["updated_at"] = now
+	set["completed_hint"] = hint
+	if s.outbox != nil {
+		snapshot := current
+		snapshot.Checklist, snapshot.CompletedHint, snapshot.UpdatedAt = items, hint, now
+		if err := s.stageEvent(update, "task.updated", &snapshot, now); err != nil {
+			return nil, err
+		}
+	}
+
+	filter := bson.M{"_id": current.ID, "is_deleted": notDeleted, "checklist": checklistAsRead(current.Checklist)}
+	opts = append(opts, options.FindOneAndUpdate().SetReturnDocument(options.After))
+	var updated Task
+	err := s.collection.FindOneAndUpdate(ctx, filter, update, opts...).Decode(&updated)
+	if err == mongo.ErrNoDocuments {
+		id := current.publicID()
+		return nil, statusError(codes.Aborted, "TASK_CONFLICT", map[string]string{"task_id": id}, "task %s was changed concurrently, retry the update", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	go s.trackTaskEvent("task.updated", updated)
+	return &pb.TaskResponse{Task: updated.toProto()}, nil
+}
+
+// AddChecklistItem appends an item, not done, to a task's checklist.
+func (s *server) AddChecklistItem(ctx context.Context, req *pb.AddChecklistItemRequest) (*pb.TaskResponse, error) {
+	current, err := s.writableTask(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	if len(current.Checklist) >= maxChecklistItems {
+		return nil, statusError(codes.ResourceExhausted, "CHECKLIST_FULL", map[string]string{"task_id": req.Id, "limit": strconv.Itoa(maxChecklistItems)},
+			"task %s already has %d checklist items", req.Id, len(current.Checklist))
+	}
+
+	item := ChecklistItem{ID: uuid.NewString(), Text: req.Text, CreatedAt: time.Now().Format(time.RFC3339)}
+	items := append(append([]ChecklistItem(nil), current.Checklist...), item)
+	return s.changeChecklist(ctx, current, items, bson.M{"$push": bson.M{"checklist": item}})
+}
+
+// ToggleChecklistItem marks a checklist item done or not done. An item
+// already so is left alone.
+func (s *server) ToggleChecklistItem(ctx context.Context, req *pb.ToggleChecklistItemRequest) (*pb.TaskResponse, error) {
+	current, err := s.writableTask(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	i := checklistIndex(current.Checklist, req.ItemId)
+	if i < 0 {
+		return nil, checklistItemNotFound(req.Id, req.ItemId)
+	}
+	if current.Checklist[i].Done == req.Done {
+		return &pb.TaskResponse{Task: current.toProto()}, nil
+	}
+
+	items := append([]ChecklistItem(nil), current.Checklist...)
+	items[i].Done = req.Done
+	update := bson.M{"$set": bson.M{"checklist.$[item].done": req.Done}}
+	byID := options.FindOneAndUpdate().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{bson.M{"item.id": req.ItemId}}})
+	return s.changeChecklist(ctx, current, items, update, byID)
+}
+
+// RemoveChecklistItem deletes an item from a task's checklist.
+func (s *server) RemoveChecklistItem(ctx context.Context, req *pb.RemoveChecklistItemRequest) (*pb.TaskResponse, error) {
+	current, err := s.writableTask(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	i := checklistIndex(current.Checklist, req.ItemId)
+	if i < 0 {
+		return nil, checklistItemNotFound(req.Id, req.ItemId)
+	}
+
+	items := append(append([]ChecklistItem(nil), current.Checklist[:i]...), current.Checklist[i+1:]...)
+	return s.changeChecklist(ctx, current, items, bson.M{"$pull": bson.M{"checklist": bson.M{"id": req.ItemId}}})
+}
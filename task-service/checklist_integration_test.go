@@ -0,0 +1,123 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+func TestChecklist(t *testing.T) {
+	s := newTestServer(t, nil)
+	ctx := context.Background()
+	created, err := s.CreateTask(ctx, &pb.CreateTaskRequest{UserId: "u1", Title: "Write report"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := created.Task.Id
+
+	var items []*pb.ChecklistItem
+	for _, text := range []string{"Outline", "Draft"} {
+		resp, err := s.AddChecklistItem(ctx, &pb.AddChecklistItemRequest{Id: id, Text: text})
+		if err != nil {
+			t.Fatal(err)
+		}
+		items = resp.Task.Checklist
+	}
+	if len(items) != 2 || items[0].Text != "Outline" || items[1].Text != "Draft" || items[0].Id == "" || items[0].Id == items[1].Id {
+		t.Fatalf("checklist = %v, want Outline then Draft with distinct ids", items)
+	}
+	if items[0].Done || items[0].CreatedAt == "" {
+		t.Errorf("new item = %v, want not done with a creation time", items[0])
+	}
+
+	toggle := func(itemID string, done bool) *pb.Task {
+		t.Helper()
+		resp, err := s.ToggleChecklistItem(ctx, &pb.ToggleChecklistItemRequest{Id: id, ItemId: itemID, Done: done})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.Task
+	}
+	if task := toggle(items[0].Id, true); !task.Checklist[0].Done || task.Checklist[1].Done || task.CompletedHint {
+		t.Errorf("after checking the first item: %v, hint %v", task.Checklist, task.CompletedHint)
+	}
+	task := toggle(items[1].Id, true)
+	if !task.CompletedHint {
+		t.Error("completed_hint not set with every item done")
+	}
+	if task.Completed || task.Status != pb.TaskStatus_TASK_STATUS_NOT_STARTED {
+		t.Errorf("task status = %v, want it left alone by the hint", task.Status)
+	}
+	if task := toggle(items[1].Id, false); task.CompletedHint {
+		t.Error("completed_hint still set with an item not done")
+	}
+
+	removed, err := s.RemoveChecklistItem(ctx, &pb.RemoveChecklistItemRequest{Id: id, ItemId: items[1].Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := removed.Task.Checklist; len(got) != 1 || got[0].Id != items[0].Id || !removed.Task.CompletedHint {
+		t.Errorf("after removing the open item: %v, hint %v; want the done item and the hint", got, removed.Task.CompletedHint)
+	}
+	removed, err = s.RemoveChecklistItem(ctx, &pb.RemoveChecklistItemRequest{Id: id, ItemId: items[0].Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed.Task.Checklist) != 0 || removed.Task.CompletedHint {
+		t.Errorf("emptied checklist: %v, hint %v; want no items and no hint", removed.Task.Checklist, removed.Task.CompletedHint)
+	}
+
+	_, err = s.ToggleChecklistItem(ctx, &pb.ToggleChecklistItemRequest{Id: id, ItemId: items[0].Id, Done: true})
+	if reason, _ := errorInfo(err); reason != "CHECKLIST_ITEM_NOT_FOUND" {
+		t.Errorf("toggling a removed item: %v, want CHECKLIST_ITEM_NOT_FOUND", err)
+	}
+	_, err = s.RemoveChecklistItem(ctx, &pb.RemoveChecklistItemRequest{Id: id, ItemId: "nope"})
+	if reason, _ := errorInfo(err); reason != "CHECKLIST_ITEM_NOT_FOUND" {
+		t.Errorf("removing an unknown item: %v, want CHECKLIST_ITEM_NOT_FOUND", err)
+	}
+	_, err = s.AddChecklistItem(ctx, &pb.AddChecklistItemRequest{Id: "64b000000000000000000000", Text: "Outline"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("adding to an unknown task: %v, want NotFound", err)
+	}
+}
+
+func TestChecklistCap(t *testing.T) {
+	s := newTestServer(t, nil)
+	ctx := context.Background()
+	created, err := s.CreateTask(ctx, &pb.CreateTaskRequest{UserId: "u1", Title: "Pack"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < maxChecklistItems; i++ {
+		if _, err := s.AddChecklistItem(ctx, &pb.AddChecklistItemRequest{Id: created.Task.Id, Text: fmt.Sprintf("Item %d", i)}); err != nil {
+			t.Fatalf("item %d: %v", i, err)
+		}
+	}
+	_, err = s.AddChecklistItem(ctx, &pb.AddChecklistItemRequest{Id: created.Task.Id, Text: "One too many"})
+	if reason, _ := errorInfo(err); status.Code(err) != codes.ResourceExhausted || reason != "CHECKLIST_FULL" {
+		t.Errorf("adding to a full checklist: %v, want ResourceExhausted CHECKLIST_FULL", err)
+	}
+
+	// A change racing another sees the checklist it read is gone
+	current, err := s.writableTask(ctx, created.Task.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.RemoveChecklistItem(ctx, &pb.RemoveChecklistItemRequest{Id: created.Task.Id, ItemId: current.Checklist[0].ID}); err != nil {
+		t.Fatal(err)
+	}
+	items := current.Checklist[1:]
+	_, err = s.changeChecklist(ctx, current, items, bson.M{"$pull": bson.M{"checklist": bson.M{"id": current.Checklist[0].ID}}})
+	if status.Code(err) != codes.Aborted {
+		t.Errorf("change to a checklist changed since it was read: %v, want Aborted", err)
+	}
+}
@@ -0,0 +1,23 @@
+package main
+
+import "testing"
+
+func TestChecklistDone(t *testing.T) {
+	tests := []struct {
+		name  string
+		items []ChecklistItem
+		want  bool
+	}{
+		{"no items", nil, false},
+		{"none done", []ChecklistItem{{ID: "a"}, {ID: "b"}}, false},
+		{"some done", []ChecklistItem{{ID: "a", Done: true}, {ID: "b"}}, false},
+		{"all done", []ChecklistItem{{ID: "a", Done: true}, {ID: "b", Done: true}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := checklistDone(tt.items); got != tt.want {
+				t.Errorf("checklistDone = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/technonext/todo-app/proto/mongoutil"
+	"github.com/technonext/todo-app/proto/settings"
+)
+
+// Config holds the settings validateConfig checks at startup: the shared
+// server settings and where tasks are stored.
+type Config struct {
+	settings.Server
+	// PostgresURL is where STORAGE_DRIVER=postgres stores tasks; the
+	// other drivers are "mongo" and "memory"
+	PostgresURL string
+}
+
+func loadConfig(mongo mongoutil.Config) Config {
+	return Config{
+		Server:      settings.LoadServer("50051", mongo),
+		PostgresURL: os.Getenv("POSTGRES_URL"),
+	}
+}
+
+// validateConfig reports every invalid setting in cfg, each named by its
+// environment variable.
+func validateConfig(cfg Config) error {
+	errs := cfg.Errors()
+	switch cfg.StorageDriver {
+	case storagePostgres:
+		if cfg.PostgresURL == "" {
+			errs = append(errs, errors.New("POSTGRES_URL: must be set with STORAGE_DRIVER=postgres"))
+		} else if _, err := pgxpool.ParseConfig(cfg.PostgresURL); err != nil {
+			errs = append(errs, fmt.Errorf("POSTGRES_URL: %w", err))
+		}
+	case storageMongo, storageMemory:
+	default:
+		errs = append(errs, fmt.Errorf("STORAGE_DRIVER: %q is not %s, %s or %s", cfg.StorageDriver, storageMongo, storagePostgres, storageMemory))
+	}
+	return errors.Join(errs...)
+}
+
+// info adds POSTGRES_URL to the shared settings for the InfoService, which
+// redacts its credentials.
+func (c Config) info() map[string]string {
+	values := c.Info()
+	if c.PostgresURL != "" {
+		values["POSTGRES_URL"] = c.PostgresURL
+	}
+	return values
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/technonext/todo-app/proto/buildinfo"
+	"github.com/technonext/todo-app/proto/mongoutil"
+	"github.com/technonext/todo-app/proto/settings"
+)
+
+func validConfig() Config {
+	return Config{
+		Server: settings.Server{
+			Port:                 "50051",
+			Mongo:                mongoutil.Config{URI: "mongodb://localhost:27017"},
+			MaxRecvMsgSize:       "4",
+			MaxConcurrentStreams: "1000",
+			StorageDriver:        storageMongo,
+		},
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		change    func(*Config)
+		wantField string
+	}{
+		{"valid", func(c *Config) {}, ""},
+		{"port not a number", func(c *Config) { c.Port = "http" }, "PORT"},
+		{"port zero", func(c *Config) { c.Port = "0" }, "PORT"},
+		{"port too large", func(c *Config) { c.Port = "65536" }, "PORT"},
+		{"no mongo uri", func(c *Config) { c.Mongo = mongoutil.Config{} }, "MONGO_URI"},
+		{"mongo uri and components", func(c *Config) { c.Mongo.Host = "mongo:27017" }, "MONGO_URI"},
+		{"message size not a number", func(c *Config) { c.MaxRecvMsgSize = "4MB" }, "GRPC_MAX_RECV_MSG_SIZE"},
+		{"message size zero", func(c *Config) { c.MaxRecvMsgSize = "0" }, "GRPC_MAX_RECV_MSG_SIZE"},
+		{"message size too large", func(c *Config) { c.MaxRecvMsgSize = "513" }, "GRPC_MAX_RECV_MSG_SIZE"},
+		{"in flight within the pool", func(c *Config) { c.Mongo.MaxPoolSize, c.MaxInFlight = 100, "100" }, ""},
+		{"in flight beyond the pool", func(c *Config) { c.Mongo.MaxPoolSize, c.MaxInFlight = 100, "101" }, "GRPC_MAX_IN_FLIGHT"},
+		{"in flight beyond the pool in memory", func(c *Config) {
+			c.StorageDriver, c.Mongo.MaxPoolSize, c.MaxInFlight = storageMemory, 100, "500"
+		}, ""},
+		{"streams not a number", func(c *Config) { c.MaxConcurrentStreams = "many" }, "GRPC_MAX_CONCURRENT_STREAMS"},
+		{"unknown storage driver", func(c *Config) { c.StorageDriver = "mysql" }, "STORAGE_DRIVER"},
+		{"postgres without mongo", func(c *Config) {
+			c.StorageDriver, c.PostgresURL, c.Mongo = storagePostgres, "postgres://todo@localhost:5432/todo_app", mongoutil.Config{}
+		}, ""},
+		{"memory without mongo", func(c *Config) { c.StorageDriver, c.Mongo = storageMemory, mongoutil.Config{} }, ""},
+		{"postgres without url", func(c *Config) { c.StorageDriver = storagePostgres }, "POSTGRES_URL"},
+		{"postgres with bad url", func(c *Config) { c.StorageDriver, c.PostgresURL = storagePostgres, "postgres://localhost:port" }, "POSTGRES_URL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			tt.change(&cfg)
+			err := validateConfig(cfg)
+			if tt.wantField == "" {
+				if err != nil {
+					t.Fatalf("validateConfig() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantField) {
+				t.Fatalf("validateConfig() = %v, want an error naming %s", err, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestValidateConfigReportsEveryField(t *testing.T) {
+	cfg := Config{Server: settings.Server{Port: "none", MaxRecvMsgSize: "-1", StorageDriver: storageMongo}}
+	err := validateConfig(cfg)
+	if err == nil {
+		t.Fatal("validateConfig() succeeded")
+	}
+	for _, field := range []string{"PORT", "MONGO_URI", "GRPC_MAX_RECV_MSG_SIZE"} {
+		if !strings.Contains(err.Error(), field) {
+			t.Errorf("validateConfig() = %v, want an error naming %s", err, field)
+		}
+	}
+}
+
+func TestConfigInfoLeavesOutSecrets(t *testing.T) {
+	cfg := validConfig()
+	cfg.Mongo = mongoutil.Config{Username: "todo", Password: "hunter2-mongo", Host: "mongo:27017"}
+	cfg.StorageDriver, cfg.PostgresURL = storagePostgres, "postgres://todo:hunter2-pg@db:5432/todo_app"
+
+	info := buildinfo.NewServer("task-service", cfg.info()).Info()
+	for key, value := range info.Config {
+		if strings.Contains(value, "hunter2") {
+			t.Errorf("%s = %q shows a password", key, value)
+		}
+	}
+	if info.Config["STORAGE_DRIVER"] != storagePostgres || info.Config["MONGO_HOST"] != "mongo:27017" || info.Config["POSTGRES_URL"] == "" {
+		t.Errorf("config = %v, want the storage driver, MongoDB host and redacted Postgres URL", info.Config)
+	}
+}
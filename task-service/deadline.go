@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/grpc/codes"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// Tasks listed by GetTasksNearDeadline when the request sets no limit
+const defaultNearDeadlineLimit = 100
+
+// GetTasksNearDeadline lists the tasks the notification service should alert
+// their owners about. due_date may be an RFC3339 string, so it is compared
+// as a date; tasks without a parseable one are never near their deadline.
+func (s *server) GetTasksNearDeadline(ctx context.Context, req *pb.GetTasksNearDeadlineRequest) (*pb.ListTasksResponse, error) {
+	if req.WithinMinutes <= 0 {
+		return nil, statusError(codes.InvalidArgument, "INVALID_WITHIN_MINUTES", nil, "within_minutes must be positive, got %d", req.WithinMinutes)
+	}
+	limit := int64(req.Limit)
+	if limit <= 0 {
+		limit = defaultNearDeadlineLimit
+	}
+
+	now := time.Now()
+	cursor, err := s.collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"completed":           false,
+			"is_deleted":          notDeleted,
+			"deadline_alert_sent": bson.M{"$ne": true},
+		}}},
+		{{Key: "$addFields", Value: bson.M{"due": toDate("$due_date")}}},
+		{{Key: "$match", Value: bson.M{"due": bson.M{
+			"$gte": now,
+			"$lte": now.Add(time.Duration(req.WithinMinutes) * time.Minute),
+		}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "due", Value: 1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$limit", Value: limit}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var found []Task
+	if err := cursor.All(ctx, &found); err != nil {
+		return nil, err
+	}
+
+	tasks := make([]*pb.Task, 0, len(found))
+	for _, task := range found {
+		tasks = append(tasks, task.toProto())
+	}
+	return &pb.ListTasksResponse{Tasks: tasks, Total: int32(len(tasks))}, nil
+}
+
+// MarkDeadlineAlertSent records that the task's owner has been alerted about
+// its deadline, so it is not listed again until its due date changes.
+func (s *server) MarkDeadlineAlertSent(ctx context.Context, req *pb.MarkDeadlineAlertSentRequest) (*pb.TaskResponse, error) {
+	match, err := taskFilter(req.Id)
+	if err != nil {
+		return nil, err
+	}
+	match["is_deleted"] = notDeleted
+
+	var task Task
+	err = s.collection.FindOneAndUpdate(ctx,
+		match,
+		bson.M{"$set": bson.M{"deadline_alert_sent": true}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&task)
+	if err == mongo.ErrNoDocuments {
+		return nil, statusError(codes.NotFound, "TASK_NOT_FOUND", map[string]string{"task_id": req.Id}, "task %s not found", req.Id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &pb.TaskResponse{Task: task.toProto()}, nil
+}
@@ -0,0 +1,65 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+func TestGetTasksNearDeadline(t *testing.T) {
+	s := newTestServer(t, nil)
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	ids := make(map[string]string)
+	for title, due := range map[string]time.Time{
+		"Soon":    now.Add(30 * time.Minute),
+		"Later":   now.Add(2 * time.Hour),
+		"Overdue": now.Add(-time.Hour),
+		"Done":    now.Add(20 * time.Minute),
+	} {
+		created, err := s.CreateTask(ctx, &pb.CreateTaskRequest{UserId: "u1", Title: title, DueDate: due.Format(time.RFC3339)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[title] = created.Task.Id
+	}
+	if _, err := s.UpdateTaskStatus(ctx, &pb.UpdateTaskStatusRequest{Id: ids["Done"], Status: pb.TaskStatus_TASK_STATUS_COMPLETED}); err != nil {
+		t.Fatal(err)
+	}
+
+	near := func() []*pb.Task {
+		t.Helper()
+		resp, err := s.GetTasksNearDeadline(ctx, &pb.GetTasksNearDeadlineRequest{WithinMinutes: 60})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.Tasks
+	}
+	if got := near(); len(got) != 1 || got[0].Id != ids["Soon"] {
+		t.Fatalf("near deadline = %v, want only the task due in 30 minutes", got)
+	}
+
+	marked, err := s.MarkDeadlineAlertSent(ctx, &pb.MarkDeadlineAlertSentRequest{Id: ids["Soon"]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !marked.Task.DeadlineAlertSent {
+		t.Error("task not marked as alerted")
+	}
+	if got := near(); len(got) != 0 {
+		t.Fatalf("near deadline after the alert = %v, want none", got)
+	}
+
+	// Moving the due date rearms the alert
+	if _, err := s.UpdateTask(ctx, &pb.UpdateTaskRequest{Id: ids["Soon"], Title: "Soon", DueDate: now.Add(45 * time.Minute).Format(time.RFC3339)}); err != nil {
+		t.Fatal(err)
+	}
+	if got := near(); len(got) != 1 || got[0].DeadlineAlertSent {
+		t.Fatalf("near deadline after moving the due date = %v", got)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/technonext/todo-app/proto/featureflags"
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+func TestDeleteTaskFollowsSoftDeleteFlag(t *testing.T) {
+	tests := []struct {
+		flags     string
+		wantTrash bool
+	}{
+		{`{"soft_delete": false}`, false},
+		{`{}`, true},
+		{`{"soft_delete": true}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.flags, func(t *testing.T) {
+			flags, err := featureflags.Parse(tt.flags)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tasks := newMemoryTasks()
+			s := &server{tasks: tasks, users: &fakeUserClient{}, flags: flags}
+			ctx := context.Background()
+
+			created, err := s.CreateTask(ctx, &pb.CreateTaskRequest{
+				UserId:      "u1",
+				Title:       "Write report",
+				Description: "Quarterly numbers",
+				DueDate:     "2026-03-10T17:00:00Z",
+				Labels:      []string{"work"},
+				Priority:    pb.TaskPriority_TASK_PRIORITY_HIGH,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			stored, err := s.GetTask(ctx, &pb.GetTaskRequest{Id: created.Task.Id, SkipView: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			deleted, err := s.DeleteTask(ctx, &pb.DeleteTaskRequest{Id: created.Task.Id})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !deleted.Success || !proto.Equal(deleted.DeletedTask, stored.Task) {
+				t.Errorf("deleted task = %v, want the stored task %v", deleted.DeletedTask, stored.Task)
+			}
+
+			// A task in the trash can still be resolved; a hard-deleted one
+			// is gone
+			_, err = tasks.Resolve(ctx, created.Task.Id)
+			if inTrash := err == nil; inTrash != tt.wantTrash {
+				t.Errorf("task in the trash = %v, want %v", inTrash, tt.wantTrash)
+			}
+			for _, id := range []string{created.Task.Id, newTaskPublicID()} {
+				resp, err := s.DeleteTask(ctx, &pb.DeleteTaskRequest{Id: id})
+				if status.Code(err) != codes.NotFound || resp != nil {
+					t.Errorf("deleting missing task %s = %v, %v; want NotFound and no task", id, resp, err)
+				}
+				if reason, _ := errorInfo(err); reason != "TASK_NOT_FOUND" {
+					t.Errorf("deleting missing task %s: reason %q, want TASK_NOT_FOUND", id, reason)
+				}
+			}
+		})
+	}
+}
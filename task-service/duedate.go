@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/grpc/codes"
+
+	"github.com/technonext/todo-app/proto/callctx"
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// defaultMaxExtensions is how many times a task's due date can be extended
+// unless MAX_DUE_DATE_EXTENSIONS says otherwise.
+const defaultMaxExtensions = 5
+
+// DueDateExtension records one ExtendTaskDueDate.
+type DueDateExtension struct {
+	ExtendedFrom string `bson:"extended_from"`
+	ExtendedTo   string `bson:"extended_to"`
+	ExtendedAt   string `bson:"extended_at"`
+	ExtendedBy   string `bson:"extended_by,omitempty"`
+}
+
+// loadMaxExtensions reads MAX_DUE_DATE_EXTENSIONS, which must be positive.
+func loadMaxExtensions() (int32, error) {
+	value := getEnv("MAX_DUE_DATE_EXTENSIONS", strconv.Itoa(defaultMaxExtensions))
+	limit, err := strconv.ParseInt(value, 10, 32)
+	if err != nil || limit <= 0 {
+		return 0, fmt.Errorf("invalid MAX_DUE_DATE_EXTENSIONS %q", value)
+	}
+	return int32(limit), nil
+}
+
+func (s *server) extensionLimit() int32 {
+	if s.maxExtensions > 0 {
+		return s.maxExtensions
+	}
+	return defaultMaxExtensions
+}
+
+// ExtendTaskDueDate pushes a task's due date back, counting the extension
+// and keeping it in the task's history. Only a later date is an extension,
+// and a task without a due date has nothing to extend.
+func (s *server) ExtendTaskDueDate(ctx context.Context, req *pb.ExtendDueDateRequest) (*pb.TaskResponse, error) {
+	match, err := taskFilter(req.Id)
+	if err != nil {
+		return nil, err
+	}
+	match["is_deleted"] = notDeleted
+
+	var current Task
+	err = s.collection.FindOne(ctx, match).Decode(&current)
+	if err == mongo.ErrNoDocuments {
+		return nil, statusError(codes.NotFound, "TASK_NOT_FOUND", map[string]string{"task_id": req.Id}, "task %s not found", req.Id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkNotFrozen(ctx, current.UserID); err != nil {
+		return nil, err
+	}
+
+	if current.DueDate == "" {
+		return nil, statusError(codes.FailedPrecondition, "NO_DUE_DATE", map[string]string{"task_id": req.Id}, "task %s has no due date to extend", req.Id)
+	}
+	newDue, _ := time.Parse(time.RFC3339, req.NewDueDate)
+	// Due dates stored before they were validated may not parse; any new
+	// date extends those
+	if due, err := time.Parse(time.RFC3339, current.DueDate); err == nil && !newDue.After(due) {
+		return nil, statusError(codes.InvalidArgument, "DUE_DATE_NOT_LATER", map[string]string{"due_date": current.DueDate, "new_due_date": req.NewDueDate}, "new due date %s is not after the current due date %s", req.NewDueDate, current.DueDate)
+	}
+	limit := s.extensionLimit()
+	if current.ExtensionCount >= limit {
+		return nil, statusError(codes.ResourceExhausted, "DUE_DATE_EXTENSION_LIMIT", map[string]string{"task_id": req.Id, "limit": strconv.Itoa(int(limit))}, "task %s has already been extended %d times", req.Id, current.ExtensionCount)
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	update := bson.M{
+		"$set": bson.M{
+			"due_date":   taskDate(req.NewDueDate),
+			"updated_at": now,
+			// A new due date gets an alert of its own
+			"deadline_alert_sent": false,
+		},
+		"$inc": bson.M{"extension_count": int32(1)},
+		"$push": bson.M{"due_date_extensions": DueDateExtension{
+			ExtendedFrom: current.DueDate,
+			ExtendedTo:   req.NewDueDate,
+			ExtendedAt:   now,
+			ExtendedBy:   callctx.CallerFrom(ctx).UserID,
+		}},
+	}
+	if s.outbox != nil {
+		snapshot, err := projectUpdate(current, update)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.stageEvent(update, "task.due_date_extended", &snapshot, now); err != nil {
+			return nil, err
+		}
+	}
+
+	// Concurrent extensions of the same due date would otherwise both count
+	// against the limit checked above
+	filter := bson.M{
+		"_id":             current.ID,
+		"is_deleted":      notDeleted,
+		"due_date":        current.DueDate,
+		"extension_count": bson.M{"$not": bson.M{"$gte": limit}},
+	}
+	var updated Task
+	err = s.collection.FindOneAndUpdate(ctx, filter, update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if err == mongo.ErrNoDocuments {
+		return nil, statusError(codes.Aborted, "TASK_CONFLICT", map[string]string{"task_id": req.Id}, "task %s was changed concurrently, retry the update", req.Id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	go s.trackTaskEvent("task.due_date_extended", updated)
+	return &pb.TaskResponse{Task: updated.toProto()}, nil
+}
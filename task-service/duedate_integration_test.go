@@ -0,0 +1,91 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/technonext/todo-app/proto/callctx"
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+func TestExtendTaskDueDateLimit(t *testing.T) {
+	s := newTestServer(t, nil)
+	ctx := callctx.WithCaller(context.Background(), callctx.Caller{UserID: "u1"})
+	due := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+
+	created, err := s.CreateTask(ctx, &pb.CreateTaskRequest{UserId: "u1", Title: "Report", DueDate: due.Format(time.RFC3339)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 1; i <= defaultMaxExtensions; i++ {
+		from := due
+		due = due.Add(24 * time.Hour)
+		resp, err := s.ExtendTaskDueDate(ctx, &pb.ExtendDueDateRequest{Id: created.Task.Id, NewDueDate: due.Format(time.RFC3339)})
+		if err != nil {
+			t.Fatalf("extension %d: %v", i, err)
+		}
+		task := resp.Task
+		if task.DueDate != due.Format(time.RFC3339) || task.ExtensionCount != int32(i) {
+			t.Fatalf("extension %d: due %s, count %d", i, task.DueDate, task.ExtensionCount)
+		}
+		if len(task.DueDateExtensions) != i {
+			t.Fatalf("extension %d: history has %d entries", i, len(task.DueDateExtensions))
+		}
+		last := task.DueDateExtensions[i-1]
+		if last.ExtendedFrom != from.Format(time.RFC3339) || last.ExtendedTo != task.DueDate || last.ExtendedAt == "" || last.ExtendedBy != "u1" {
+			t.Errorf("extension %d recorded as %v", i, last)
+		}
+	}
+
+	_, err = s.ExtendTaskDueDate(ctx, &pb.ExtendDueDateRequest{Id: created.Task.Id, NewDueDate: due.Add(24 * time.Hour).Format(time.RFC3339)})
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("sixth extension: %v, want ResourceExhausted", err)
+	}
+	got, err := s.GetTask(ctx, &pb.GetTaskRequest{Id: created.Task.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Task.DueDate != due.Format(time.RFC3339) || got.Task.ExtensionCount != defaultMaxExtensions {
+		t.Errorf("after the refused extension: due %s, count %d", got.Task.DueDate, got.Task.ExtensionCount)
+	}
+}
+
+func TestExtendTaskDueDateRules(t *testing.T) {
+	s := newTestServer(t, nil)
+	s.maxExtensions = 1
+	ctx := context.Background()
+	due := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+
+	undated, err := s.CreateTask(ctx, &pb.CreateTaskRequest{UserId: "u1", Title: "Someday"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.ExtendTaskDueDate(ctx, &pb.ExtendDueDateRequest{Id: undated.Task.Id, NewDueDate: due.Format(time.RFC3339)})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("extending a task without a due date: %v, want FailedPrecondition", err)
+	}
+
+	dated, err := s.CreateTask(ctx, &pb.CreateTaskRequest{UserId: "u1", Title: "Report", DueDate: due.Format(time.RFC3339)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.ExtendTaskDueDate(ctx, &pb.ExtendDueDateRequest{Id: dated.Task.Id, NewDueDate: due.Add(-time.Minute).Format(time.RFC3339)})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("moving the due date earlier: %v, want InvalidArgument", err)
+	}
+
+	if _, err := s.ExtendTaskDueDate(ctx, &pb.ExtendDueDateRequest{Id: dated.Task.Id, NewDueDate: due.Add(time.Hour).Format(time.RFC3339)}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.ExtendTaskDueDate(ctx, &pb.ExtendDueDateRequest{Id: dated.Task.Id, NewDueDate: due.Add(2 * time.Hour).Format(time.RFC3339)})
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("extending past a limit of 1: %v, want ResourceExhausted", err)
+	}
+}
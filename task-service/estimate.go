@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+	"github.com/technonext/todo-app/proto/tenant"
+)
+
+// exceededEstimate reports whether an update setting the estimate and adding
+// to the time spent takes a task that was within its estimate over it. before
+// is the task as the update found it, so concurrent updates each see their
+// own starting point and only the one that crosses the estimate notifies.
+func exceededEstimate(before Task, estimate, added int32) bool {
+	if estimate <= 0 || before.TimeSpentMinutes+added <= estimate {
+		return false
+	}
+	return before.EstimatedMinutes <= 0 || before.TimeSpentMinutes <= before.EstimatedMinutes
+}
+
+// notifyEstimateExceeded tells the user their task has gone over its time
+// estimate. It is best-effort: failures are logged and never fail the update.
+func (s *server) notifyEstimateExceeded(task Task) {
+	if s.notifications == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(tenant.WithID(context.Background(), task.TenantID), 3*time.Second)
+	defer cancel()
+
+	_, err := s.notifications.SendNotification(ctx, &pb.NotificationRequest{
+		UserId:  task.UserID,
+		Message: fmt.Sprintf("Task '%s' has exceeded its time estimate.", task.Title),
+		Type:    pb.NotificationType_NOTIFICATION_TYPE_TASK,
+	})
+	if err != nil {
+		log.Printf("Failed to notify user %s of task %s exceeding its estimate: %v", task.UserID, task.ID.Hex(), err)
+	}
+}
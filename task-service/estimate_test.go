@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestExceededEstimate(t *testing.T) {
+	tests := []struct {
+		name     string
+		before   Task
+		estimate int32
+		added    int32
+		want     bool
+	}{
+		{"no estimate", Task{TimeSpentMinutes: 50}, 0, 30, false},
+		{"within estimate", Task{EstimatedMinutes: 60, TimeSpentMinutes: 20}, 60, 30, false},
+		{"reaches estimate exactly", Task{EstimatedMinutes: 60, TimeSpentMinutes: 30}, 60, 30, false},
+		{"crosses estimate", Task{EstimatedMinutes: 60, TimeSpentMinutes: 50}, 60, 30, true},
+		{"already over", Task{EstimatedMinutes: 60, TimeSpentMinutes: 70}, 60, 30, false},
+		{"already over, nothing added", Task{EstimatedMinutes: 60, TimeSpentMinutes: 70}, 60, 0, false},
+		{"estimate lowered below time spent", Task{EstimatedMinutes: 60, TimeSpentMinutes: 40}, 30, 0, true},
+		{"estimate set on a task over it", Task{TimeSpentMinutes: 90}, 60, 0, true},
+		{"estimate raised above time spent", Task{EstimatedMinutes: 60, TimeSpentMinutes: 70}, 120, 10, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exceededEstimate(tt.before, tt.estimate, tt.added); got != tt.want {
+				t.Errorf("exceededEstimate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNotifyEstimateExceeded(t *testing.T) {
+	task := Task{ID: primitive.NewObjectID(), UserID: "u1", Title: "Write report"}
+
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"sent", nil},
+		{"notification service down", errors.New("unavailable")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notifications := &fakeNotificationClient{err: tt.err}
+			s := &server{notifications: notifications}
+			s.notifyEstimateExceeded(task)
+
+			if len(notifications.sent) != 1 {
+				t.Fatalf("sent %d notifications, want 1", len(notifications.sent))
+			}
+			got := notifications.sent[0]
+			if got.UserId != "u1" || got.Message != "Task 'Write report' has exceeded its time estimate." {
+				t.Errorf("sent %+v", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/nats-io/nuid"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+	"github.com/technonext/todo-app/proto/tenant"
+)
+
+// eventPublisher hands analytics events to the broker the analytics service
+// ingests from, so tracking them never waits on that service.
+type eventPublisher struct {
+	js      jetstream.JetStream
+	subject string
+}
+
+// newEventPublisher returns nil unless EVENT_BROKER=nats. The connection is
+// retried in the background, so a broker that is not up yet does not stop
+// the service; events are tracked over gRPC until it is.
+func newEventPublisher() *eventPublisher {
+	switch broker := os.Getenv("EVENT_BROKER"); broker {
+	case "":
+		return nil
+	case "nats":
+	default:
+		log.Fatalf("Unsupported EVENT_BROKER %q: only nats is supported", broker)
+	}
+
+	nc, err := nats.Connect(getEnv("NATS_URL", nats.DefaultURL),
+		nats.RetryOnFailedConnect(true),
+		nats.MaxReconnects(-1))
+	if err != nil {
+		log.Fatalf("Failed to connect to NATS: %v", err)
+	}
+	js, err := jetstream.New(nc)
+	if err != nil {
+		log.Fatalf("Failed to set up JetStream: %v", err)
+	}
+	return &eventPublisher{js: js, subject: getEnv("EVENT_SUBJECT", "analytics.events")}
+}
+
+// publish waits for the broker to store the event. Each event gets its own
+// message id, which the analytics service uses to skip redeliveries.
+func (p *eventPublisher) publish(ctx context.Context, req *pb.TrackEventRequest) error {
+	data, err := protojson.Marshal(req)
+	if err != nil {
+		return err
+	}
+	msg := nats.NewMsg(p.subject)
+	msg.Data = data
+	msg.Header.Set(jetstream.MsgIDHeader, nuid.Next())
+	if id, ok := tenant.FromContext(ctx); ok && id != "" {
+		msg.Header.Set(tenant.Header, id)
+	}
+	_, err = p.js.PublishMsg(ctx, msg)
+	return err
+}
+
+// taskEvent is the analytics event recording eventType for the task. Its
+// metadata carries what analytics keeps stats by, so it never has to read
+// tasks: whether the task is completed, its priority and labels, and for
+// completions whether the due date was met.
+func taskEvent(eventType string, task Task) *pb.TrackEventRequest {
+	metadata := map[string]*structpb.Value{
+		"completed": structpb.NewBoolValue(task.Completed),
+	}
+	if task.Priority != "" {
+		metadata["priority"] = structpb.NewStringValue(strings.TrimPrefix(task.Priority, "TASK_PRIORITY_"))
+	}
+	if len(task.Labels) > 0 {
+		labels := make([]*structpb.Value, len(task.Labels))
+		for i, label := range task.Labels {
+			labels[i] = structpb.NewStringValue(label)
+		}
+		metadata["labels"] = structpb.NewListValue(&structpb.ListValue{Values: labels})
+	}
+	if eventType == "task.completed" {
+		if due, err := time.Parse(time.RFC3339, task.DueDate); err == nil {
+			completedAt, err := time.Parse(time.RFC3339, task.CompletedAt)
+			if err != nil {
+				completedAt = time.Now()
+			}
+			metadata["on_time"] = structpb.NewBoolValue(!completedAt.After(due))
+		}
+	}
+
+	return &pb.TrackEventRequest{
+		UserId:     task.UserID,
+		EventType:  eventType,
+		ResourceId: task.publicID(),
+		Metadata:   &structpb.Struct{Fields: metadata},
+	}
+}
+
+// trackTaskEvent records an analytics event for the task, through the broker
+// when there is one. It is best-effort: failures are logged and never fail
+// the change that caused the event.
+func (s *server) trackTaskEvent(eventType string, task Task) {
+	s.trackEvent(task.TenantID, taskEvent(eventType, task))
+}
+
+// trackEvent records req for the tenant as trackTaskEvent does.
+func (s *server) trackEvent(tenantID string, req *pb.TrackEventRequest) {
+	if s.analytics == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(tenant.WithID(context.Background(), tenantID), 3*time.Second)
+	defer cancel()
+
+	if s.events != nil {
+		err := s.events.publish(ctx, req)
+		if err == nil {
+			return
+		}
+		log.Printf("Failed to publish %s event for %s, tracking it directly: %v", req.EventType, req.ResourceId, err)
+	}
+	if _, err := s.analytics.TrackEvent(ctx, req); err != nil {
+		log.Printf("Failed to track %s event for %s: %v", req.EventType, req.ResourceId, err)
+	}
+}
+
+// trackCompletionChange records completing or reopening a task, given the
+// task just before and after an update.
+func (s *server) trackCompletionChange(before, after Task) {
+	if after.Completed && !before.Completed {
+		go s.trackTaskEvent("task.completed", after)
+	} else if !after.Completed && before.Completed {
+		go s.trackTaskEvent("task.reopened", after)
+	}
+}
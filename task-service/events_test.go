@@ -0,0 +1,96 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+func TestTaskEvent(t *testing.T) {
+	task := Task{
+		ID:          primitive.NewObjectID(),
+		UserID:      "u1",
+		Completed:   true,
+		Priority:    "TASK_PRIORITY_HIGH",
+		Labels:      []string{"work", "urgent"},
+		DueDate:     "2026-03-10T00:00:00Z",
+		CompletedAt: "2026-03-09T12:00:00Z",
+	}
+	late := task
+	late.CompletedAt = "2026-03-11T12:00:00Z"
+	plain := Task{ID: task.ID, UserID: "u1"}
+
+	tests := []struct {
+		name         string
+		eventType    string
+		task         Task
+		wantPriority string
+		wantLabels   int
+		wantOnTime   string
+	}{
+		{"completed on time", "task.completed", task, "HIGH", 2, "true"},
+		{"completed late", "task.completed", late, "HIGH", 2, "false"},
+		{"created", "task.created", task, "HIGH", 2, ""},
+		{"no priority or labels", "task.created", plain, "", 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := taskEvent(tt.eventType, tt.task)
+			if req.UserId != "u1" || req.EventType != tt.eventType || req.ResourceId != task.ID.Hex() {
+				t.Errorf("event = %+v", req)
+			}
+			fields := req.Metadata.Fields
+			if got := fields["completed"].GetBoolValue(); got != tt.task.Completed {
+				t.Errorf("completed = %v", got)
+			}
+			if got := fields["priority"].GetStringValue(); got != tt.wantPriority {
+				t.Errorf("priority = %q, want %q", got, tt.wantPriority)
+			}
+			if got := len(fields["labels"].GetListValue().GetValues()); got != tt.wantLabels {
+				t.Errorf("%d labels, want %d", got, tt.wantLabels)
+			}
+			onTime := ""
+			if v, ok := fields["on_time"]; ok {
+				onTime = map[bool]string{true: "true", false: "false"}[v.GetBoolValue()]
+			}
+			if onTime != tt.wantOnTime {
+				t.Errorf("on_time = %q, want %q", onTime, tt.wantOnTime)
+			}
+		})
+	}
+}
+
+func TestTrackCompletionChange(t *testing.T) {
+	open, done := Task{UserID: "u1"}, Task{UserID: "u1", Completed: true}
+	tests := []struct {
+		name          string
+		before, after Task
+		want          string
+	}{
+		{"completed", open, done, "task.completed"},
+		{"reopened", done, open, "task.reopened"},
+		{"still open", open, open, ""},
+		{"still completed", done, done, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analytics := &fakeAnalyticsClient{tracked: make(chan *pb.TrackEventRequest, 1)}
+			s := &server{analytics: analytics}
+			s.trackCompletionChange(tt.before, tt.after)
+
+			select {
+			case req := <-analytics.tracked:
+				if req.EventType != tt.want {
+					t.Errorf("tracked %s, want %q", req.EventType, tt.want)
+				}
+			case <-time.After(100 * time.Millisecond):
+				if tt.want != "" {
+					t.Errorf("tracked nothing, want %s", tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/grpc/codes"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// ExportTasks streams all of the user's tasks outside the trash, oldest
+// first, without loading them into memory together.
+func (s *server) ExportTasks(req *pb.ExportTasksRequest, stream pb.TaskService_ExportTasksServer) error {
+	if req.UserId == "" {
+		return statusError(codes.InvalidArgument, "USER_ID_REQUIRED", nil, "user_id is required")
+	}
+	ctx := stream.Context()
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetBatchSize(500)
+	cursor, err := s.collection.Find(ctx, bson.M{"user_id": req.UserId, "is_deleted": notDeleted}, findOptions)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var task Task
+		if err := cursor.Decode(&task); err != nil {
+			return err
+		}
+		if err := stream.Send(task.toProto()); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+	"github.com/technonext/todo-app/proto/tenant"
+)
+
+// fakeUserClient knows users, failing every lookup with err when it is set;
+// users it does not know are not found.
+type fakeUserClient struct {
+	pb.UserServiceClient
+	users map[string]*pb.User
+	err   error
+}
+
+func (c *fakeUserClient) GetUser(ctx context.Context, req *pb.GetUserRequest, opts ...grpc.CallOption) (*pb.UserResponse, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	if user, ok := c.users[req.Id]; ok {
+		return &pb.UserResponse{User: user}, nil
+	}
+	return nil, status.Error(codes.NotFound, "user not found")
+}
+
+// fakeNotificationClient records the notifications sent, sending each on sent
+// when it is set, and fails them with err.
+type fakeNotificationClient struct {
+	pb.NotificationServiceClient
+	mu   sync.Mutex
+	sent []*pb.NotificationRequest
+	ch   chan *pb.NotificationRequest
+	err  error
+}
+
+func (c *fakeNotificationClient) SendNotification(ctx context.Context, req *pb.NotificationRequest, opts ...grpc.CallOption) (*pb.NotificationResponse, error) {
+	c.mu.Lock()
+	c.sent = append(c.sent, req)
+	c.mu.Unlock()
+	if c.ch != nil {
+		c.ch <- req
+	}
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &pb.NotificationResponse{}, nil
+}
+
+// fakeSearch records the searches made and returns tasks.
+type fakeSearch struct {
+	filter SearchFilter
+	query  string
+	limit  int64
+	tasks  []Task
+}
+
+func (f *fakeSearch) Search(ctx context.Context, collection *tenant.Collection, filter SearchFilter, query string, skip, limit int64) ([]Task, int64, error) {
+	f.filter, f.query, f.limit = filter, query, limit
+	return f.tasks, int64(len(f.tasks)), nil
+}
+
+// fakeAnalyticsClient sends each event tracked on tracked.
+type fakeAnalyticsClient struct {
+	pb.AnalyticsServiceClient
+	tracked chan *pb.TrackEventRequest
+}
+
+func (c *fakeAnalyticsClient) TrackEvent(ctx context.Context, req *pb.TrackEventRequest, opts ...grpc.CallOption) (*pb.TrackEventResponse, error) {
+	c.tracked <- req
+	return &pb.TrackEventResponse{}, nil
+}
+
+// errorInfo returns the ErrorInfo reason and metadata attached to err.
+func errorInfo(err error) (string, map[string]string) {
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(interface {
+			GetReason() string
+			GetMetadata() map[string]string
+		}); ok {
+			return info.GetReason(), info.GetMetadata()
+		}
+	}
+	return "", nil
+}
+
+// publishedEvent is a message published to fakeBroker.
+type publishedEvent struct {
+	subject string
+	msgID   string
+	event   *pb.TaskEvent
+}
+
+// fakeBroker records the events published, failing those fail returns an
+// error for.
+type fakeBroker struct {
+	mu        sync.Mutex
+	published []publishedEvent
+	fail      func(event *pb.TaskEvent) error
+}
+
+func (b *fakeBroker) Publish(ctx context.Context, subject, msgID string, data []byte) error {
+	event := &pb.TaskEvent{}
+	if err := proto.Unmarshal(data, event); err != nil {
+		return err
+	}
+	if b.fail != nil {
+		if err := b.fail(event); err != nil {
+			return err
+		}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.published = append(b.published, publishedEvent{subject: subject, msgID: msgID, event: event})
+	return nil
+}
+
+func (b *fakeBroker) events() []publishedEvent {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]publishedEvent(nil), b.published...)
+}
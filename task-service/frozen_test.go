@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/technonext/todo-app/proto/pagination"
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+func TestCreateTaskFrozenUser(t *testing.T) {
+	users := &fakeUserClient{users: map[string]*pb.User{
+		"frozen": {Id: "frozen", IsFrozen: true, FrozenAt: "2026-03-01T09:00:00Z"},
+		"active": {Id: "active"},
+	}}
+	tests := []struct {
+		name     string
+		userID   string
+		err      error
+		wantCode codes.Code
+	}{
+		{"frozen", "frozen", nil, codes.PermissionDenied},
+		{"active", "active", nil, codes.OK},
+		{"unknown to the user service", "u1", nil, codes.OK},
+		{"user service down", "active", status.Error(codes.Unavailable, "connection refused"), codes.Unavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tasks := newMemoryTasks()
+			users.err = tt.err
+			s := &server{tasks: tasks, users: users}
+			_, err := s.CreateTask(context.Background(), &pb.CreateTaskRequest{UserId: tt.userID, Title: "Write report"})
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("code = %v, want %v", code, tt.wantCode)
+			}
+			_, total, listErr := tasks.List(context.Background(), SearchFilter{UserID: tt.userID}, pagination.Page{Limit: 10})
+			if listErr != nil {
+				t.Fatal(listErr)
+			}
+			if stored := total > 0; stored != (err == nil) {
+				t.Errorf("%d tasks stored after %v", total, err)
+			}
+			if tt.wantCode != codes.PermissionDenied {
+				return
+			}
+			reason, metadata := errorInfo(err)
+			if reason != "ACCOUNT_FROZEN" || metadata["user_id"] != "frozen" || metadata["frozen_at"] != "2026-03-01T09:00:00Z" {
+				t.Errorf("error = %v with %v, want ACCOUNT_FROZEN naming the user and when", reason, metadata)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/technonext/todo-app/proto/callctx"
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+func TestStatusHistoryRecordsCaller(t *testing.T) {
+	s := &server{tasks: newMemoryTasks(), users: &fakeUserClient{}}
+	ctx := context.Background()
+	asUser := callctx.WithCaller(ctx, callctx.Caller{UserID: "u1"})
+
+	created, err := s.CreateTask(asUser, &pb.CreateTaskRequest{UserId: "u1", Title: "Write report"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := created.Task.Id
+	if _, err := s.UpdateTaskStatus(asUser, &pb.UpdateTaskStatusRequest{Id: id, Status: pb.TaskStatus_TASK_STATUS_IN_PROGRESS}); err != nil {
+		t.Fatal(err)
+	}
+	// The request's user_id names the task's owner, not who is changing it
+	asOther := callctx.WithCaller(ctx, callctx.Caller{UserID: "u2"})
+	if _, err := s.UpdateTask(asOther, &pb.UpdateTaskRequest{Id: id, UserId: "u1", Title: "Write report", Completed: true}); err != nil {
+		t.Fatal(err)
+	}
+	// Calls from other services have no caller to record
+	resp, err := s.UpdateTaskStatus(ctx, &pb.UpdateTaskStatusRequest{Id: id, Status: pb.TaskStatus_TASK_STATUS_NOT_STARTED})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"u1", "u2", ""}
+	history := resp.Task.StatusHistory
+	if len(history) != len(want) {
+		t.Fatalf("status history = %v, want %d changes", history, len(want))
+	}
+	for i, change := range history {
+		if change.ChangedBy != want[i] {
+			t.Errorf("change %d to %v made by %q, want %q", i+1, change.To, change.ChangedBy, want[i])
+		}
+	}
+}
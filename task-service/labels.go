@@ -0,0 +1,20 @@
+package main
+
+import "strings"
+
+// normalizeLabels trims and lowercases labels and drops empty and duplicate
+// ones, so "Work" and "work " count as the same label in stats. Their number
+// and length are bounded by the rules on the request in todo.proto.
+func normalizeLabels(labels []string) []string {
+	var normalized []string
+	seen := make(map[string]bool)
+	for _, label := range labels {
+		label = strings.ToLower(strings.TrimSpace(label))
+		if label == "" || seen[label] {
+			continue
+		}
+		seen[label] = true
+		normalized = append(normalized, label)
+	}
+	return normalized
+}
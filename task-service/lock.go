@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/grpc/codes"
+
+	"github.com/technonext/todo-app/proto/callctx"
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+const (
+	// defaultLockTTL is how long a task lock lasts unless
+	// TASK_LOCK_TTL_SECONDS says otherwise
+	defaultLockTTL = 30 * time.Second
+	// lockReapInterval is how often expired locks are cleared
+	lockReapInterval = 60 * time.Second
+)
+
+// loadLockTTL reads TASK_LOCK_TTL_SECONDS, which must be positive.
+func loadLockTTL() (time.Duration, error) {
+	value := getEnv("TASK_LOCK_TTL_SECONDS", strconv.Itoa(int(defaultLockTTL/time.Second)))
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds <= 0 {
+		return 0, fmt.Errorf("invalid TASK_LOCK_TTL_SECONDS %q", value)
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
+func (s *server) lockDuration() time.Duration {
+	if s.lockTTL > 0 {
+		return s.lockTTL
+	}
+	return defaultLockTTL
+}
+
+// lockTime formats times for locked_until. Locks are compared as text, so
+// they are always in UTC.
+func lockTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// requestingUser is the user the gateway authenticated, or the request's
+// user_id for calls from other services.
+func requestingUser(ctx context.Context, requested string) string {
+	if userID := callctx.CallerFrom(ctx).UserID; userID != "" {
+		return userID
+	}
+	return requested
+}
+
+// lockedAgainst reports whether the task is locked by someone other than
+// userID at now.
+func (t Task) lockedAgainst(userID, now string) bool {
+	return t.LockedBy != "" && t.LockedBy != userID && t.LockedUntil >= now
+}
+
+// editableBy matches tasks userID may edit at now: unlocked, locked by them,
+// or with the lock run out.
+func editableBy(userID, now string) bson.M {
+	return bson.M{"$or": bson.A{
+		bson.M{"locked_by": bson.M{"$in": bson.A{"", nil}}},
+		bson.M{"locked_by": userID},
+		bson.M{"locked_until": bson.M{"$lt": now}},
+	}}
+}
+
+func taskLockedError(t Task) error {
+	id := t.publicID()
+	return statusError(codes.Aborted, "TASK_LOCKED", map[string]string{"task_id": id, "locked_by": t.LockedBy, "locked_until": t.LockedUntil},
+		"task %s is locked by %s until %s", id, t.LockedBy, t.LockedUntil)
+}
+
+// LockTask locks a task for the requesting user. The lock is taken with a
+// single conditional update, so of concurrent attempts exactly one wins and
+// the others learn who holds it.
+func (s *server) LockTask(ctx context.Context, req *pb.LockTaskRequest) (*pb.LockResponse, error) {
+	match, err := taskFilter(req.Id)
+	if err != nil {
+		return nil, err
+	}
+	match["is_deleted"] = notDeleted
+	userID := requestingUser(ctx, req.UserId)
+	if userID == "" {
+		return nil, statusError(codes.InvalidArgument, "USER_ID_REQUIRED", nil, "user_id is required")
+	}
+
+	now := time.Now()
+	filter := bson.M{"$and": bson.A{match, editableBy(userID, lockTime(now))}}
+	update := bson.M{"$set": bson.M{"locked_by": userID, "locked_until": lockTime(now.Add(s.lockDuration()))}}
+
+	var locked Task
+	err = s.collection.FindOneAndUpdate(ctx, filter, update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&locked)
+	if err == mongo.ErrNoDocuments {
+		// Either there is no such task or someone else holds the lock
+		var current Task
+		err = s.collection.FindOne(ctx, match).Decode(&current)
+		if err == mongo.ErrNoDocuments {
+			return nil, statusError(codes.NotFound, "TASK_NOT_FOUND", map[string]string{"task_id": req.Id}, "task %s not found", req.Id)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return nil, taskLockedError(current)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &pb.LockResponse{TaskId: locked.publicID(), LockedBy: locked.LockedBy, LockedUntil: locked.LockedUntil}, nil
+}
+
+// UnlockTask releases the requesting user's lock on a task.
+func (s *server) UnlockTask(ctx context.Context, req *pb.UnlockTaskRequest) (*pb.LockResponse, error) {
+	match, err := taskFilter(req.Id)
+	if err != nil {
+		return nil, err
+	}
+	match["is_deleted"] = notDeleted
+	userID := requestingUser(ctx, req.UserId)
+	if userID == "" {
+		return nil, statusError(codes.InvalidArgument, "USER_ID_REQUIRED", nil, "user_id is required")
+	}
+
+	filter := bson.M{"$and": bson.A{match, editableBy(userID, lockTime(time.Now()))}}
+	unlock := bson.M{"$unset": bson.M{"locked_by": "", "locked_until": ""}}
+	var current Task
+	err = s.collection.FindOneAndUpdate(ctx, filter, unlock).Decode(&current)
+	if err == mongo.ErrNoDocuments {
+		err = s.collection.FindOne(ctx, match).Decode(&current)
+		if err == mongo.ErrNoDocuments {
+			return nil, statusError(codes.NotFound, "TASK_NOT_FOUND", map[string]string{"task_id": req.Id}, "task %s not found", req.Id)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return nil, statusError(codes.PermissionDenied, "NOT_LOCK_HOLDER", map[string]string{"task_id": req.Id, "locked_by": current.LockedBy},
+			"task %s is locked by %s, only they can unlock it", req.Id, current.LockedBy)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &pb.LockResponse{TaskId: current.publicID()}, nil
+}
+
+// startLockReaper clears locks that have run out every lockReapInterval,
+// until ctx ends. Expired locks no longer hold anyone back; clearing them
+// keeps tasks from showing a stale locked_by.
+func (s *server) startLockReaper(ctx context.Context) {
+	ticker := time.NewTicker(lockReapInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := s.reapLocks(ctx); err != nil {
+			slog.Error("Failed to clear expired task locks", "error", err)
+		}
+	}
+}
+
+func (s *server) reapLocks(ctx context.Context) error {
+	result, err := s.collection.UpdateMany(ctx,
+		bson.M{"locked_until": bson.M{"$lt": lockTime(time.Now())}},
+		bson.M{"$unset": bson.M{"locked_by": "", "locked_until": ""}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.ModifiedCount > 0 {
+		slog.Info("Cleared expired task locks", "count", result.ModifiedCount)
+	}
+	return nil
+}
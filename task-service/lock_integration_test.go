@@ -0,0 +1,155 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+func TestConcurrentLockTask(t *testing.T) {
+	s := newTestServer(t, nil)
+	ctx := context.Background()
+	created, err := s.CreateTask(ctx, &pb.CreateTaskRequest{UserId: "u1", Title: "Shared"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const users = 10
+	locks := make([]*pb.LockResponse, users)
+	errs := make([]error, users)
+	var wg sync.WaitGroup
+	for i := 0; i < users; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			locks[i], errs[i] = s.LockTask(ctx, &pb.LockTaskRequest{Id: created.Task.Id, UserId: fmt.Sprintf("user-%d", i)})
+		}(i)
+	}
+	wg.Wait()
+
+	var holder string
+	for i, err := range errs {
+		if err == nil {
+			if holder != "" {
+				t.Fatalf("both %s and user-%d got the lock", holder, i)
+			}
+			holder = locks[i].LockedBy
+		}
+	}
+	if holder == "" {
+		t.Fatal("nobody got the lock")
+	}
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		if status.Code(err) != codes.Aborted || !strings.Contains(err.Error(), holder) {
+			t.Errorf("user-%d: %v, want Aborted naming %s", i, err, holder)
+		}
+	}
+
+	// The holder can take the lock again to extend it
+	if _, err := s.LockTask(ctx, &pb.LockTaskRequest{Id: created.Task.Id, UserId: holder}); err != nil {
+		t.Errorf("relocking as the holder: %v", err)
+	}
+	got, err := s.GetTask(ctx, &pb.GetTaskRequest{Id: created.Task.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Task.LockedBy != holder || got.Task.LockedUntil == "" {
+		t.Errorf("task locked by %q until %q, want %s", got.Task.LockedBy, got.Task.LockedUntil, holder)
+	}
+}
+
+func TestLockedTaskRejectsOtherEditors(t *testing.T) {
+	s := newTestServer(t, nil)
+	ctx := context.Background()
+	created, err := s.CreateTask(ctx, &pb.CreateTaskRequest{UserId: "u1", Title: "Draft"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := created.Task.Id
+	if _, err := s.LockTask(ctx, &pb.LockTaskRequest{Id: id, UserId: "u1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.UpdateTask(ctx, &pb.UpdateTaskRequest{Id: id, UserId: "u2", Title: "Hijacked"}); status.Code(err) != codes.Aborted {
+		t.Errorf("update by u2 = %v, want Aborted", err)
+	}
+	if _, err := s.UnlockTask(ctx, &pb.UnlockTaskRequest{Id: id, UserId: "u2"}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("unlock by u2 = %v, want PermissionDenied", err)
+	}
+	updated, err := s.UpdateTask(ctx, &pb.UpdateTaskRequest{Id: id, UserId: "u1", Title: "Final"})
+	if err != nil {
+		t.Fatalf("update by the holder: %v", err)
+	}
+	if updated.Task.Title != "Final" {
+		t.Errorf("title = %q, want Final", updated.Task.Title)
+	}
+
+	if _, err := s.UnlockTask(ctx, &pb.UnlockTaskRequest{Id: id, UserId: "u1"}); err != nil {
+		t.Fatalf("unlock by the holder: %v", err)
+	}
+	if _, err := s.UpdateTask(ctx, &pb.UpdateTaskRequest{Id: id, UserId: "u2", Title: "Unlocked"}); err != nil {
+		t.Errorf("update by u2 once unlocked: %v", err)
+	}
+	if _, err := s.LockTask(ctx, &pb.LockTaskRequest{Id: "000000000000000000000000", UserId: "u1"}); status.Code(err) != codes.NotFound {
+		t.Errorf("locking a missing task = %v, want NotFound", err)
+	}
+}
+
+func TestExpiredLocks(t *testing.T) {
+	s := newTestServer(t, nil)
+	ctx := context.Background()
+	created, err := s.CreateTask(ctx, &pb.CreateTaskRequest{UserId: "u1", Title: "Abandoned"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := created.Task.Id
+	oid, _ := primitive.ObjectIDFromHex(id)
+	if _, err := s.LockTask(ctx, &pb.LockTaskRequest{Id: id, UserId: "u1"}); err != nil {
+		t.Fatal(err)
+	}
+	expire := func() {
+		t.Helper()
+		_, err := s.collection.UpdateOne(ctx, bson.M{"_id": oid},
+			bson.M{"$set": bson.M{"locked_until": lockTime(time.Now().Add(-time.Minute))}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// An expired lock no longer holds anyone back
+	expire()
+	lock, err := s.LockTask(ctx, &pb.LockTaskRequest{Id: id, UserId: "u2"})
+	if err != nil {
+		t.Fatalf("locking over an expired lock: %v", err)
+	}
+	if lock.LockedBy != "u2" {
+		t.Errorf("locked by %q, want u2", lock.LockedBy)
+	}
+
+	expire()
+	if err := s.reapLocks(ctx); err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.GetTask(ctx, &pb.GetTaskRequest{Id: id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Task.LockedBy != "" || got.Task.LockedUntil != "" {
+		t.Errorf("reaped task still locked by %q until %q", got.Task.LockedBy, got.Task.LockedUntil)
+	}
+}
@@ -2,25 +2,57 @@ package main
 
 import (
 	"context"
-	"fmt"
-	"log"
+	"flag"
+	"log/slog"
 	"net"
+	"net/http"
 	"os"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/reflection"
-
+	"google.golang.org/grpc/codes"
+
+	"github.com/technonext/todo-app/proto/backup"
+	"github.com/technonext/todo-app/proto/buildinfo"
+	"github.com/technonext/todo-app/proto/callctx"
+	"github.com/technonext/todo-app/proto/diagnostics"
+	"github.com/technonext/todo-app/proto/faults"
+	"github.com/technonext/todo-app/proto/featureflags"
+	"github.com/technonext/todo-app/proto/grpcmiddleware"
+	"github.com/technonext/todo-app/proto/logging"
+	"github.com/technonext/todo-app/proto/mongoutil"
+	"github.com/technonext/todo-app/proto/pagination"
 	pb "github.com/technonext/todo-app/proto/proto"
+	"github.com/technonext/todo-app/proto/serviceauth"
+	"github.com/technonext/todo-app/proto/tenant"
 )
 
 type server struct {
 	pb.UnimplementedTaskServiceServer
-	collection *mongo.Collection
+	collection        *tenant.Collection
+	deletedCollection *tenant.Collection
+	search            SearchBackend
+	// Stores the tasks of the core RPCs; nil uses collection
+	tasks         TaskRepository
+	users         pb.UserServiceClient
+	notifications pb.NotificationServiceClient
+	// Task events go to analytics, through the broker when events is set
+	analytics pb.AnalyticsServiceClient
+	events    *eventPublisher
+	// Publishes task changes to the broker, when one is configured
+	outbox *outbox
+	// Runs MoveTask's transactions; nil uses the collection's client
+	transactions transactor
+	// How many times a task's due date can be extended; 0 means
+	// defaultMaxExtensions
+	maxExtensions int32
+	// How long LockTask holds a task; 0 means defaultLockTTL
+	lockTTL time.Duration
+	// Turn optional features on; nil has every one off
+	flags *featureflags.FeatureFlags
 }
 
 type Task struct {
@@ -32,222 +64,704 @@ type Task struct {
 	DueDate     string             `bson:"due_date"`
 	CreatedAt   string             `bson:"created_at"`
 	UpdatedAt   string             `bson:"updated_at"`
+	CompletedAt string             `bson:"completed_at,omitempty"`
+	// The id the API shows, set at creation; see taskid.go
+	PublicID string `bson:"public_id,omitempty"`
+	// Status holds the TaskStatus name; tasks stored before statuses existed
+	// have none and are derived from Completed
+	Status        string         `bson:"status,omitempty"`
+	StatusHistory []StatusChange `bson:"status_history,omitempty"`
+	// Priority holds the TaskPriority name, empty when unspecified
+	Priority string   `bson:"priority,omitempty"`
+	Labels   []string `bson:"labels,omitempty"`
+	// Time tracking in minutes. Time spent is only ever added to
+	EstimatedMinutes int32 `bson:"estimated_minutes,omitempty"`
+	TimeSpentMinutes int32 `bson:"time_spent_minutes,omitempty"`
+	// Set by the notification service once it has alerted the owner that the
+	// task is nearly due
+	DeadlineAlertSent bool `bson:"deadline_alert_sent,omitempty"`
+	// Due date extensions, limited to maxExtensions, oldest first
+	ExtensionCount    int32              `bson:"extension_count,omitempty"`
+	DueDateExtensions []DueDateExtension `bson:"due_date_extensions,omitempty"`
+	// Engagement metrics, maintained by GetTask and UpdateTask
+	ViewCount    int64  `bson:"view_count,omitempty"`
+	EditCount    int64  `bson:"edit_count,omitempty"`
+	LastViewedAt string `bson:"last_viewed_at,omitempty"`
+	// Deleted tasks stay in the trash until the reaper removes them
+	IsDeleted bool   `bson:"is_deleted,omitempty"`
+	DeletedAt string `bson:"deleted_at,omitempty"`
+	// Changes waiting for the outbox relay, and the number of events ever
+	// recorded for the task
+	PendingEvents []pendingEvent `bson:"pending_events,omitempty"`
+	EventSeq      int64          `bson:"event_seq,omitempty"`
+	// Set while a user holds the task's edit lock; locked_until is UTC
+	// RFC3339 so it compares as text
+	LockedBy    string `bson:"locked_by,omitempty"`
+	LockedUntil string `bson:"locked_until,omitempty"`
+	// The tenant owning the task, empty for the default tenant
+	TenantID string `bson:"tenant_id,omitempty"`
+	// The version of the document's layout; see taskSchema
+	SchemaVersion int `bson:"schema_version,omitempty"`
+	// Sub-steps, oldest first, and whether they are all done; see
+	// checklist.go
+	Checklist     []ChecklistItem `bson:"checklist,omitempty"`
+	CompletedHint bool            `bson:"completed_hint,omitempty"`
+	// Markdown notes, left out of listed tasks; see notes.go
+	Notes string `bson:"notes,omitempty"`
+	// The previous owner, once MigrateTaskOwnership has moved the task; see
+	// ownership.go
+	MigratedFrom string `bson:"migrated_from,omitempty"`
+}
+
+type StatusChange struct {
+	From      string `bson:"from" json:"from"`
+	To        string `bson:"to" json:"to"`
+	ChangedAt string `bson:"changed_at" json:"changed_at"`
+	ChangedBy string `bson:"changed_by,omitempty" json:"changed_by,omitempty"`
+}
+
+func (t Task) status() pb.TaskStatus {
+	if v, ok := pb.TaskStatus_value[t.Status]; ok && v != 0 {
+		return pb.TaskStatus(v)
+	}
+	return legacyStatus(t.Completed)
+}
+
+// legacyStatus is the status of a task stored before statuses existed.
+func legacyStatus(completed bool) pb.TaskStatus {
+	if completed {
+		return pb.TaskStatus_TASK_STATUS_COMPLETED
+	}
+	return pb.TaskStatus_TASK_STATUS_NOT_STARTED
+}
+
+// taskStateMachine lists the status changes a task may go through.
+type taskStateMachine struct {
+	transitions map[pb.TaskStatus][]pb.TaskStatus
+}
+
+var taskStates = taskStateMachine{
+	transitions: map[pb.TaskStatus][]pb.TaskStatus{
+		pb.TaskStatus_TASK_STATUS_NOT_STARTED: {pb.TaskStatus_TASK_STATUS_IN_PROGRESS, pb.TaskStatus_TASK_STATUS_COMPLETED},
+		pb.TaskStatus_TASK_STATUS_IN_PROGRESS: {pb.TaskStatus_TASK_STATUS_ON_HOLD, pb.TaskStatus_TASK_STATUS_COMPLETED},
+		pb.TaskStatus_TASK_STATUS_ON_HOLD:     {pb.TaskStatus_TASK_STATUS_IN_PROGRESS},
+		pb.TaskStatus_TASK_STATUS_COMPLETED:   {pb.TaskStatus_TASK_STATUS_NOT_STARTED},
+	},
+}
+
+// Transition returns FailedPrecondition unless a task may move from one
+// status to the other.
+func (m taskStateMachine) Transition(from, to pb.TaskStatus) error {
+	for _, next := range m.transitions[from] {
+		if next == to {
+			return nil
+		}
+	}
+	return statusError(codes.FailedPrecondition, "INVALID_STATUS_TRANSITION", map[string]string{"from": from.String(), "to": to.String()}, "cannot change task status from %s to %s", from, to)
+}
+
+// DeletedTask is a tombstone kept so sync clients learn about deletions.
+type DeletedTask struct {
+	TaskID    string `bson:"task_id"`
+	UserID    string `bson:"user_id"`
+	DeletedAt string `bson:"deleted_at"`
+	TenantID  string `bson:"tenant_id,omitempty"`
+}
+
+func (t Task) toProto() *pb.Task {
+	task := &pb.Task{
+		Id:                t.publicID(),
+		Title:             t.Title,
+		Description:       t.Description,
+		UserId:            t.UserID,
+		Completed:         t.Completed,
+		DueDate:           t.DueDate,
+		CreatedAt:         t.CreatedAt,
+		UpdatedAt:         t.UpdatedAt,
+		Status:            t.status(),
+		Priority:          pb.TaskPriority(pb.TaskPriority_value[t.Priority]),
+		Labels:            t.Labels,
+		EstimatedMinutes:  t.EstimatedMinutes,
+		TimeSpentMinutes:  t.TimeSpentMinutes,
+		DeadlineAlertSent: t.DeadlineAlertSent,
+		ExtensionCount:    t.ExtensionCount,
+		LockedBy:          t.LockedBy,
+		LockedUntil:       t.LockedUntil,
+		TenantId:          t.TenantID,
+		CompletedHint:     t.CompletedHint,
+		Notes:             t.Notes,
+		MigratedFrom:      t.MigratedFrom,
+	}
+	for _, change := range t.StatusHistory {
+		task.StatusHistory = append(task.StatusHistory, &pb.StatusChange{
+			From:      pb.TaskStatus(pb.TaskStatus_value[change.From]),
+			To:        pb.TaskStatus(pb.TaskStatus_value[change.To]),
+			ChangedAt: change.ChangedAt,
+			ChangedBy: change.ChangedBy,
+		})
+	}
+	for _, extension := range t.DueDateExtensions {
+		task.DueDateExtensions = append(task.DueDateExtensions, &pb.DueDateExtension{
+			ExtendedFrom: extension.ExtendedFrom,
+			ExtendedTo:   extension.ExtendedTo,
+			ExtendedAt:   extension.ExtendedAt,
+			ExtendedBy:   extension.ExtendedBy,
+		})
+	}
+	for _, item := range t.Checklist {
+		task.Checklist = append(task.Checklist, &pb.ChecklistItem{
+			Id:        item.ID,
+			Text:      item.Text,
+			Done:      item.Done,
+			CreatedAt: item.CreatedAt,
+		})
+	}
+	return task
 }
 
 func (s *server) CreateTask(ctx context.Context, req *pb.CreateTaskRequest) (*pb.TaskResponse, error) {
+	if err := s.checkNotFrozen(ctx, req.UserId); err != nil {
+		return nil, err
+	}
+
 	now := time.Now().Format(time.RFC3339)
 	task := Task{
-		Title:       req.Title,
-		Description: req.Description,
-		UserID:      req.UserId,
-		Completed:   false,
-		DueDate:     req.DueDate,
-		CreatedAt:   now,
-		UpdatedAt:   now,
+		ID:               primitive.NewObjectID(),
+		Title:            req.Title,
+		Description:      req.Description,
+		UserID:           req.UserId,
+		Completed:        false,
+		DueDate:          req.DueDate,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+		Status:           pb.TaskStatus_TASK_STATUS_NOT_STARTED.String(),
+		Labels:           normalizeLabels(req.Labels),
+		EstimatedMinutes: req.EstimatedMinutes,
 	}
-
-	result, err := s.collection.InsertOne(ctx, task)
-	if err != nil {
-		log.Printf("Failed to create task: %v", err)
-		return nil, err
+	if req.Priority != pb.TaskPriority_TASK_PRIORITY_UNSPECIFIED {
+		task.Priority = req.Priority.String()
 	}
 
-	oid, ok := result.InsertedID.(primitive.ObjectID)
-	if !ok {
-		log.Printf("Failed to convert ObjectID")
+	var err error
+	for attempt := 0; attempt < publicIDAttempts; attempt++ {
+		task.PublicID = newTaskPublicID()
+		// The event's snapshot carries the public id, so it is staged again
+		// with each attempt
+		if s.outbox != nil {
+			event, err := newPendingEvent("task.created", &task, now)
+			if err != nil {
+				return nil, err
+			}
+			task.PendingEvents = []pendingEvent{event}
+			task.EventSeq = 1
+		}
+		if err = s.repository().Insert(ctx, task); err != errPublicIDTaken {
+			break
+		}
+		logging.FromContext(ctx).Warn("Task public id taken, retrying", "public_id", task.PublicID)
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to create task", "error", err)
 		return nil, err
 	}
 
-	return &pb.TaskResponse{
-		Task: &pb.Task{
-			Id:          oid.Hex(),
-			Title:       task.Title,
-			Description: task.Description,
-			UserId:      task.UserID,
-			Completed:   task.Completed,
-			DueDate:     task.DueDate,
-			CreatedAt:   task.CreatedAt,
-			UpdatedAt:   task.UpdatedAt,
-		},
-	}, nil
+	go s.trackTaskEvent("task.created", task)
+	return &pb.TaskResponse{Task: task.toProto()}, nil
 }
 
 func (s *server) GetTask(ctx context.Context, req *pb.GetTaskRequest) (*pb.TaskResponse, error) {
-	oid, err := primitive.ObjectIDFromHex(req.Id)
+	oid, err := s.taskObjectID(ctx, req.Id)
 	if err != nil {
 		return nil, err
 	}
 
-	var task Task
-	err = s.collection.FindOne(ctx, bson.M{"_id": oid}).Decode(&task)
+	// Count the view in the same round-trip that reads the task
+	task, err := s.repository().Get(ctx, oid, req.SkipView, time.Now().Format(time.RFC3339))
+	if err == errNoTask {
+		return nil, statusError(codes.NotFound, "TASK_NOT_FOUND", map[string]string{"task_id": req.Id}, "task %s not found", req.Id)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	return &pb.TaskResponse{
-		Task: &pb.Task{
-			Id:          task.ID.Hex(),
-			Title:       task.Title,
-			Description: task.Description,
-			UserId:      task.UserID,
-			Completed:   task.Completed,
-			DueDate:     task.DueDate,
-			CreatedAt:   task.CreatedAt,
-			UpdatedAt:   task.UpdatedAt,
-		},
-	}, nil
+	return &pb.TaskResponse{Task: task.toProto()}, nil
 }
 
 func (s *server) UpdateTask(ctx context.Context, req *pb.UpdateTaskRequest) (*pb.TaskResponse, error) {
-	oid, err := primitive.ObjectIDFromHex(req.Id)
+	oid, err := s.taskObjectID(ctx, req.Id)
 	if err != nil {
 		return nil, err
 	}
 
-	update := bson.M{
-		"$set": bson.M{
-			"title":       req.Title,
-			"description": req.Description,
-			"completed":   req.Completed,
-			"due_date":    req.DueDate,
-			"updated_at":  time.Now().Format(time.RFC3339),
+	current, err := s.repository().Get(ctx, oid, true, "")
+	if err == errNoTask {
+		return nil, statusError(codes.NotFound, "TASK_NOT_FOUND", map[string]string{"task_id": req.Id}, "task %s not found", req.Id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkNotFrozen(ctx, current.UserID); err != nil {
+		return nil, err
+	}
+	editor := requestingUser(ctx, req.UserId)
+	lockNow := lockTime(time.Now())
+	if current.lockedAgainst(editor, lockNow) {
+		return nil, taskLockedError(current)
+	}
+
+	// Clients that predate statuses complete and reopen tasks via the flag
+	from := current.status()
+	to := req.Status
+	if to == pb.TaskStatus_TASK_STATUS_UNSPECIFIED {
+		to = from
+		if req.Completed && from != pb.TaskStatus_TASK_STATUS_COMPLETED {
+			to = pb.TaskStatus_TASK_STATUS_COMPLETED
+		} else if !req.Completed && from == pb.TaskStatus_TASK_STATUS_COMPLETED {
+			to = pb.TaskStatus_TASK_STATUS_NOT_STARTED
+		}
+	}
+
+	// The lock is checked again by the update itself, in case someone locked
+	// the task since it was read
+	before, updated, err := s.applyStatus(ctx, current, taskChange{
+		Status: to,
+		Fields: &taskFields{
+			Title:            req.Title,
+			Description:      req.Description,
+			DueDate:          req.DueDate,
+			Labels:           normalizeLabels(req.Labels),
+			EstimatedMinutes: req.EstimatedMinutes,
+			Priority:         req.Priority,
 		},
+		AddTimeSpentMinutes: req.AddTimeSpentMinutes,
+		UpdatedAt:           time.Now().Format(time.RFC3339),
+		EventType:           "task.updated",
+		ChangedBy:           callctx.CallerFrom(ctx).UserID,
+		Editor:              editor,
+		LockNow:             lockNow,
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.trackCompletionChange(before, updated)
+	if exceededEstimate(before, req.EstimatedMinutes, req.AddTimeSpentMinutes) {
+		go s.notifyEstimateExceeded(updated)
+	}
+	return &pb.TaskResponse{Task: updated.toProto()}, nil
+}
+
+// UpdateTaskStatus moves a task to a new status, leaving its other fields alone.
+func (s *server) UpdateTaskStatus(ctx context.Context, req *pb.UpdateTaskStatusRequest) (*pb.TaskResponse, error) {
+	oid, err := s.taskObjectID(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	if req.Status == pb.TaskStatus_TASK_STATUS_UNSPECIFIED {
+		return nil, statusError(codes.InvalidArgument, "STATUS_REQUIRED", nil, "status is required")
 	}
 
-	_, err = s.collection.UpdateOne(ctx, bson.M{"_id": oid}, update)
+	current, err := s.repository().Get(ctx, oid, true, "")
+	if err == errNoTask {
+		return nil, statusError(codes.NotFound, "TASK_NOT_FOUND", map[string]string{"task_id": req.Id}, "task %s not found", req.Id)
+	}
 	if err != nil {
 		return nil, err
 	}
+	if err := s.checkNotFrozen(ctx, current.UserID); err != nil {
+		return nil, err
+	}
 
-	var updatedTask Task
-	err = s.collection.FindOne(ctx, bson.M{"_id": oid}).Decode(&updatedTask)
+	before, updated, err := s.applyStatus(ctx, current, taskChange{
+		Status:    req.Status,
+		UpdatedAt: time.Now().Format(time.RFC3339),
+		EventType: "task.status_changed",
+		ChangedBy: callctx.CallerFrom(ctx).UserID,
+	})
 	if err != nil {
 		return nil, err
 	}
+	s.trackCompletionChange(before, updated)
+	return &pb.TaskResponse{Task: updated.toProto()}, nil
+}
 
-	return &pb.TaskResponse{
-		Task: &pb.Task{
-			Id:          updatedTask.ID.Hex(),
-			Title:       updatedTask.Title,
-			Description: updatedTask.Description,
-			UserId:      updatedTask.UserID,
-			Completed:   updatedTask.Completed,
-			DueDate:     updatedTask.DueDate,
-			CreatedAt:   updatedTask.CreatedAt,
-			UpdatedAt:   updatedTask.UpdatedAt,
-		},
-	}, nil
+// applyStatus makes change to the current task, after checking the state
+// machine allows its move, and returns the task as it was just before the
+// change and as it is after.
+func (s *server) applyStatus(ctx context.Context, current Task, change taskChange) (before, updated Task, err error) {
+	if from := current.status(); change.Status != from {
+		if err := taskStates.Transition(from, change.Status); err != nil {
+			return before, updated, err
+		}
+	}
+	before, updated, err = s.repository().Update(ctx, current, change)
+	if err == errTaskChanged {
+		return before, updated, statusError(codes.Aborted, "TASK_CONFLICT", map[string]string{"task_id": current.publicID()}, "task %s was changed concurrently, retry the update", current.publicID())
+	}
+	return before, updated, err
 }
 
 func (s *server) DeleteTask(ctx context.Context, req *pb.DeleteTaskRequest) (*pb.DeleteTaskResponse, error) {
-	oid, err := primitive.ObjectIDFromHex(req.Id)
+	oid, err := s.taskObjectID(ctx, req.Id)
 	if err != nil {
 		return nil, err
 	}
 
-	_, err = s.collection.DeleteOne(ctx, bson.M{"_id": oid})
+	// Deleting moves the task to the trash, or with soft deletes off removes
+	// it, and returns it, so the response can describe exactly what was
+	// deleted
+	var task Task
+	if s.flags.IsEnabled(featureflags.SoftDelete) {
+		task, err = s.repository().Delete(ctx, oid, time.Now().Format(time.RFC3339))
+	} else {
+		task, err = s.repository().Purge(ctx, oid)
+	}
+	if err == errNoTask {
+		return nil, statusError(codes.NotFound, "TASK_NOT_FOUND", map[string]string{"task_id": req.Id}, "task %s not found", req.Id)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	return &pb.DeleteTaskResponse{Success: true}, nil
+	// Only MongoDB keeps the deletions SyncTasks reports
+	if s.deletedCollection != nil {
+		s.recordDeletion(ctx, task)
+	}
+	go s.trackTaskEvent("task.deleted", task)
+
+	return &pb.DeleteTaskResponse{Success: true, DeletedTask: task.toProto()}, nil
 }
 
 func (s *server) ListTasks(ctx context.Context, req *pb.ListTasksRequest) (*pb.ListTasksResponse, error) {
-	filter := bson.M{"user_id": req.UserId}
-	if req.Completed {
-		filter["completed"] = true
+	after, before, err := parseCreatedRange(req.CreatedAfter, req.CreatedBefore)
+	if err != nil {
+		return nil, err
+	}
+	listFilter := SearchFilter{UserID: req.UserId, Completed: req.Completed, CreatedAfter: after, CreatedBefore: before}
+	page, err := pagination.Resolve(req.Pagination, req.Page, req.Limit, pagination.DefaultLimits)
+	if err != nil {
+		return nil, err
 	}
 
-	findOptions := options.Find()
-	findOptions.SetLimit(int64(req.Limit))
-	findOptions.SetSkip(int64(req.Page * req.Limit))
-
-	cursor, err := s.collection.Find(ctx, filter, findOptions)
+	if req.Query != "" {
+		return s.searchTasks(ctx, listFilter, req.Query, page)
+	}
+	found, count, err := s.repository().List(ctx, listFilter, page)
 	if err != nil {
 		return nil, err
 	}
-	defer cursor.Close(ctx)
 
 	var tasks []*pb.Task
-	for cursor.Next(ctx) {
-		var task Task
-		if err := cursor.Decode(&task); err != nil {
-			return nil, err
-		}
-		tasks = append(tasks, &pb.Task{
-			Id:          task.ID.Hex(),
-			Title:       task.Title,
-			Description: task.Description,
-			UserId:      task.UserID,
-			Completed:   task.Completed,
-			DueDate:     task.DueDate,
-			CreatedAt:   task.CreatedAt,
-			UpdatedAt:   task.UpdatedAt,
-		})
+	for _, task := range found {
+		tasks = append(tasks, task.toProto())
 	}
 
-	if err := cursor.Err(); err != nil {
-		return nil, err
+	return &pb.ListTasksResponse{
+		Tasks:      tasks,
+		Total:      int32(count),
+		Pagination: page.Response(count, len(tasks)),
+	}, nil
+}
+
+// parseCreatedRange parses the RFC3339 bounds of a created range, either of
+// which may be empty.
+func parseCreatedRange(after, before string) (time.Time, time.Time, error) {
+	var from, to time.Time
+	var err error
+	if after != "" {
+		if from, err = time.Parse(time.RFC3339, after); err != nil {
+			return from, to, statusError(codes.InvalidArgument, "INVALID_CREATED_AFTER", map[string]string{"created_after": after}, "invalid created_after %q: use RFC3339", after)
+		}
 	}
+	if before != "" {
+		if to, err = time.Parse(time.RFC3339, before); err != nil {
+			return from, to, statusError(codes.InvalidArgument, "INVALID_CREATED_BEFORE", map[string]string{"created_before": before}, "invalid created_before %q: use RFC3339", before)
+		}
+	}
+	return from, to, nil
+}
 
-	count, err := s.collection.CountDocuments(ctx, filter)
+// createdBounds are the $expr conditions keeping tasks created in
+// [after, before), skipping zero bounds. created_at may be an RFC3339 string
+// in the server's zone, so it is compared as a date rather than as text.
+func createdBounds(after, before time.Time) bson.A {
+	createdAt := toDate("$created_at")
+	var bounds bson.A
+	if !after.IsZero() {
+		bounds = append(bounds, bson.M{"$gte": bson.A{createdAt, after}})
+	}
+	if !before.IsZero() {
+		bounds = append(bounds, bson.M{"$lt": bson.A{createdAt, before}})
+	}
+	return bounds
+}
+
+func (s *server) searchTasks(ctx context.Context, filter SearchFilter, query string, page pagination.Page) (*pb.ListTasksResponse, error) {
+	found, total, err := s.repository().Search(ctx, filter, query, page)
 	if err != nil {
 		return nil, err
 	}
 
-	return &pb.ListTasksResponse{
-		Tasks: tasks,
-		Total: int32(count),
-	}, nil
+	tasks := make([]*pb.Task, 0, len(found))
+	for _, task := range found {
+		tasks = append(tasks, task.toProto())
+	}
+	return &pb.ListTasksResponse{Tasks: tasks, Total: int32(total), Pagination: page.Response(total, len(tasks))}, nil
 }
 
 func main() {
-	// Get MongoDB connection string from environment variable
-	// Read the environment variables
-	// Read the environment variables
-	mongoUser := os.Getenv("MONGO_USERNAME")
-	mongoPass := os.Getenv("MONGO_PASSWORD")
-	mongoHost := os.Getenv("MONGO_HOST")
-	if mongoUser == "" || mongoPass == "" || mongoHost == "" {
-		log.Fatal("Error: MONGO_USERNAME, MONGO_PASSWORD, and MONGO_HOST must be set")
+	migrateOnly := flag.Bool("migrate", false, "apply pending data migrations and exit")
+	dryRun := flag.Bool("dry-run", false, "with -migrate, report what the pending migrations would change without applying them")
+	flag.Parse()
+	logging.Setup("task-service")
+
+	mongoConfig, err := mongoutil.ConfigFromEnv()
+	if err != nil {
+		logging.Fatal("Invalid MongoDB settings", "error", err)
+	}
+	cfg := loadConfig(mongoConfig)
+	if err := validateConfig(cfg); err != nil {
+		logging.Fatal("Invalid configuration", "error", err)
+	}
+
+	flags, err := featureflags.FromEnv()
+	if err != nil {
+		logging.Fatal("Invalid configuration", "error", err)
+	}
+	backupStore, err := backup.FromEnv(context.Background())
+	if err != nil {
+		logging.Fatal("Invalid configuration", "error", err)
+	}
+	tasks := &server{flags: flags}
+	var diagnosticsHandler http.Handler
+	var backups *backup.Server
+	switch cfg.StorageDriver {
+	case storageMemory:
+		if *migrateOnly {
+			return
+		}
+		slog.Warn("Tasks are kept in memory and lost when the service stops")
+		tasks.tasks = newMemoryTasks()
+	case storagePostgres:
+		slog.Info("Connecting to Postgres")
+		pool, err := openPostgres(context.Background(), cfg.PostgresURL)
+		if err != nil {
+			logging.Fatal("Failed to connect to Postgres", "error", err)
+		}
+		defer pool.Close()
+		// The schema is needed to serve at all, so it is always brought up
+		// to date
+		if err := migratePostgres(context.Background(), pool, *migrateOnly && *dryRun, os.Stdout); err != nil {
+			logging.Fatal("Migration failed", "error", err)
+		}
+		if *migrateOnly {
+			return
+		}
+		tasks.tasks = postgresTasks{pool: pool}
+	default:
+		slog.Info("Connecting to MongoDB")
+		client, err := mongoutil.Connect(context.Background(), mongoConfig)
+		if err != nil {
+			logging.Fatal("Failed to connect to MongoDB", "error", err)
+		}
+		defer client.Disconnect(context.Background())
+		warnIfStandalone(context.Background(), client)
+
+		db := client.Database(mongoConfig.DatabaseName())
+		if *migrateOnly {
+			if err := runMigrations(context.Background(), db, *dryRun, os.Stdout); err != nil {
+				logging.Fatal("Migration failed", "error", err)
+			}
+			return
+		}
+		warnPendingMigrations(context.Background(), db)
+		if backupStore != nil {
+			backups = backup.NewServer(db, backupStore)
+		}
+
+		collection := openTasks(db)
+		if err := taskSchema.Check(context.Background(), collection); err != nil {
+			logging.Fatal("Database is newer than this release", "error", err)
+		}
+		deletedCollection := db.Collection("deleted_tasks")
+
+		err = mongoutil.EnsureIndexes(context.Background(), deletedCollection, mongo.IndexModel{
+			Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "user_id", Value: 1}, {Key: "deleted_at", Value: 1}},
+		})
+		if err != nil {
+			logging.Fatal("Failed to create deleted task indexes", "error", err)
+		}
+
+		// Serves listing a user's tasks, always within their tenant
+		err = mongoutil.EnsureIndexes(context.Background(), collection, mongo.IndexModel{
+			Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "user_id", Value: 1}},
+		})
+		if err != nil {
+			logging.Fatal("Failed to create task indexes", "error", err)
+		}
+
+		// Serves the trash reaper, which runs for every tenant
+		err = mongoutil.EnsureIndexes(context.Background(), collection, mongo.IndexModel{
+			Keys: bson.D{{Key: "is_deleted", Value: 1}, {Key: "deleted_at", Value: 1}},
+		})
+		if err != nil {
+			logging.Fatal("Failed to create task trash index", "error", err)
+		}
+
+		if err := ensurePublicIDIndex(context.Background(), collection); err != nil {
+			logging.Fatal("Failed to create task public id index", "error", err)
+		}
+
+		search := NewSearchBackend(os.Getenv("SEARCH_PROVIDER"))
+		if search == nil {
+			logging.Fatal("Unknown SEARCH_PROVIDER: use text or atlas_search", "value", os.Getenv("SEARCH_PROVIDER"))
+		}
+
+		// The text search backend searches titles with $text. Tenants are not a
+		// prefix, as a text index prefix must be matched by equality and the
+		// default tenant is not
+		err = mongoutil.EnsureIndexes(context.Background(), collection, mongo.IndexModel{
+			Keys: bson.D{{Key: "title", Value: "text"}},
+		})
+		if err != nil {
+			logging.Fatal("Failed to create task title index", "error", err)
+		}
+
+		tasks.collection = tenant.Scoped(collection)
+		tasks.deletedCollection = tenant.Scoped(deletedCollection)
+		tasks.search = search
+		diagnosticsHandler = diagnostics.NewServer(collection)
 	}
-	// Build the connection string
-	mongoURI := fmt.Sprintf("mongodb://%s:%s@%s/todo_app?authSource=admin", mongoUser, mongoPass, mongoHost)
 
-	log.Printf("Connecting to MongoDB at %s...", mongoHost)
-	// Connect to MongoDB
-	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI(mongoURI))
+	userConn, err := grpcmiddleware.Dial(getEnv("USER_SERVICE_ADDR", "localhost:50052"), "USER_SERVICE_HMAC_SECRET")
 	if err != nil {
-		log.Fatalf("Failed to connect to MongoDB: %v", err)
+		logging.Fatal("Failed to connect to user service", "error", err)
 	}
-	defer client.Disconnect(context.Background())
+	defer userConn.Close()
 
-	// Check the connection
-	err = client.Ping(context.Background(), nil)
+	// Tasks going over their time estimate notify their owner
+	notificationConn, err := grpcmiddleware.Dial(getEnv("NOTIFICATION_SERVICE_ADDR", "localhost:50053"), "NOTIFICATION_SERVICE_HMAC_SECRET")
 	if err != nil {
-		log.Fatalf("Failed to ping MongoDB: %v", err)
+		logging.Fatal("Failed to connect to notification service", "error", err)
 	}
+	defer notificationConn.Close()
 
-	collection := client.Database("todo_app").Collection("tasks")
+	analyticsConn, err := grpcmiddleware.Dial(getEnv("ANALYTICS_SERVICE_ADDR", "localhost:50054"), "ANALYTICS_SERVICE_HMAC_SECRET")
+	if err != nil {
+		logging.Fatal("Failed to connect to analytics service", "error", err)
+	}
+	defer analyticsConn.Close()
+
+	outboxConfig, err := loadOutboxConfig()
+	if err != nil {
+		logging.Fatal("Invalid task event settings", "error", err)
+	}
+	if outboxConfig != nil && cfg.StorageDriver != storageMongo {
+		// The outbox stages events in the task documents themselves
+		slog.Warn("Task events are only published to the broker with STORAGE_DRIVER=mongo")
+		outboxConfig = nil
+	}
+	if outboxConfig != nil {
+		tasks.outbox, err = newTaskOutbox(context.Background(), tasks.collection.Database().Collection("task_outbox"), tasks.collection.Collection, outboxConfig)
+		if err != nil {
+			logging.Fatal("Failed to set up the task event outbox", "error", err)
+		}
+	}
 
-	// Get port from environment variable
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "50051"
+	maxExtensions, err := loadMaxExtensions()
+	if err != nil {
+		logging.Fatal("Invalid configuration", "error", err)
+	}
+	strictUserID, err := loadStrictUserID()
+	if err != nil {
+		logging.Fatal("Invalid configuration", "error", err)
+	}
+	lockTTL, err := loadLockTTL()
+	if err != nil {
+		logging.Fatal("Invalid configuration", "error", err)
 	}
 
+	port := cfg.Port
+
 	lis, err := net.Listen("tcp", ":"+port)
 	if err != nil {
-		log.Fatalf("Failed to listen: %v", err)
+		logging.Fatal("Failed to listen", "error", err)
 	}
 
-	s := grpc.NewServer()
-	pb.RegisterTaskServiceServer(s, &server{collection: collection})
-	reflection.Register(s)
+	tasks.users = pb.NewUserServiceClient(userConn)
+	tasks.notifications = pb.NewNotificationServiceClient(notificationConn)
+	tasks.analytics = pb.NewAnalyticsServiceClient(analyticsConn)
+	tasks.events = newEventPublisher()
+	tasks.maxExtensions = maxExtensions
+	tasks.lockTTL = lockTTL
+	if cfg.StorageDriver == storageMongo {
+		go tasks.startTrashReaper(context.Background())
+		go tasks.startLockReaper(context.Background())
+	}
+	if tasks.outbox != nil {
+		slog.Info("Publishing task events", "subject", outboxConfig.subject+".*")
+		go tasks.startOutboxRelay(context.Background())
+	}
+
+	auth, err := serviceauth.FromEnv("TASK_SERVICE_HMAC_SECRET")
+	if err != nil {
+		logging.Fatal("Invalid configuration", "error", err)
+	}
+	reflection, err := grpcmiddleware.ReflectionFromEnv()
+	if err != nil {
+		logging.Fatal("Invalid configuration", "error", err)
+	}
+
+	requireTenant, err := tenant.RequiredFromEnv()
+	if err != nil {
+		logging.Fatal("Invalid configuration", "error", err)
+	}
+	injector, err := faults.FromEnv()
+	if err != nil {
+		logging.Fatal("Invalid configuration", "error", err)
+	}
+	pprof, err := grpcmiddleware.PprofFromEnv()
+	if err != nil {
+		logging.Fatal("Invalid configuration", "error", err)
+	}
+	info := buildinfo.NewServer("task-service", cfg.info())
+
+	unary := []grpc.UnaryServerInterceptor{userIDEnforcementInterceptor(strictUserID)}
+	var opts []grpc.ServerOption
+	if cfg.StorageDriver != storageMongo {
+		unaryCheck, streamCheck := repositoryInterceptors(cfg.StorageDriver)
+		unary = append(unary, unaryCheck)
+		opts = append(opts, grpc.ChainStreamInterceptor(streamCheck))
+	}
+	s := grpcmiddleware.NewServer(grpcmiddleware.ServerConfig{
+		Auth:                 auth,
+		Reflection:           reflection,
+		RequireTenant:        requireTenant,
+		Faults:               injector,
+		Info:                 info,
+		MaxRecvMsgSize:       cfg.RecvMsgSize(),
+		MaxConcurrentStreams: cfg.StreamLimit(),
+		MaxInFlight:          cfg.InFlightLimit(),
+		Unary:                unary,
+	}, opts...)
+	pb.RegisterTaskServiceServer(s, tasks)
+	backups.Register(s)
+
+	grpcmiddleware.ServeMetrics(grpcmiddleware.MetricsConfig{
+		Port:        os.Getenv("METRICS_PORT"),
+		Diagnostics: diagnosticsHandler,
+		Info:        info,
+		Pprof:       pprof,
+	})
+
+	slog.Info("Task service listening", "port", port, "version", buildinfo.Version, "storage", cfg.StorageDriver, "feature_flags", flags.All())
+	if err := s.Serve(injector.Listen(lis)); err != nil {
+		logging.Fatal("Failed to serve", "error", err)
+	}
+}
 
-	log.Printf("Task service listening on port %s", port)
-	if err := s.Serve(lis); err != nil {
-		log.Fatalf("Failed to serve: %v", err)
+func getEnv(key, fallback string) string {
+	if value, exists := os.LookupEnv(key); exists {
+		return value
 	}
+	return fallback
 }
@@ -0,0 +1,185 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/technonext/todo-app/proto/featureflags"
+	pb "github.com/technonext/todo-app/proto/proto"
+	"github.com/technonext/todo-app/proto/tenant"
+)
+
+// newTestServer returns a server over a fresh database on MONGO_TEST_URI,
+// skipping the test when MongoDB is not reachable.
+func newTestServer(t *testing.T, notifications pb.NotificationServiceClient) *server {
+	t.Helper()
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		uri = "mongodb://localhost:27017"
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Ping(ctx, nil); err != nil {
+		t.Skipf("MongoDB not reachable at %s: %v", uri, err)
+	}
+
+	db := client.Database(fmt.Sprintf("tasks_test_%d", time.Now().UnixNano()))
+	t.Cleanup(func() {
+		db.Drop(context.Background())
+		client.Disconnect(context.Background())
+	})
+	// The tests delete into the trash
+	flags, err := featureflags.Parse(`{"soft_delete": true}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &server{
+		collection:        tenant.Scoped(openTasks(db)),
+		deletedCollection: tenant.Scoped(db.Collection("deleted_tasks")),
+		users:             &fakeUserClient{},
+		notifications:     notifications,
+		flags:             flags,
+	}
+}
+
+func TestUpdateTaskAddsTimeSpent(t *testing.T) {
+	s := newTestServer(t, nil)
+	ctx := context.Background()
+
+	created, err := s.CreateTask(ctx, &pb.CreateTaskRequest{UserId: "u1", Title: "Write report", EstimatedMinutes: 600})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := created.Task.Id
+
+	// Concurrent updates all count, as each adds with $inc rather than
+	// writing back a total it read
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.UpdateTask(ctx, &pb.UpdateTaskRequest{Id: id, Title: "Write report", EstimatedMinutes: 600, AddTimeSpentMinutes: 15})
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	got, err := s.GetTask(ctx, &pb.GetTaskRequest{Id: id, SkipView: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Task.TimeSpentMinutes != 150 {
+		t.Errorf("time spent = %d, want 150", got.Task.TimeSpentMinutes)
+	}
+}
+
+func TestUpdateTaskTracksCompletion(t *testing.T) {
+	s := newTestServer(t, nil)
+	analytics := &fakeAnalyticsClient{tracked: make(chan *pb.TrackEventRequest, 4)}
+	s.analytics = analytics
+	ctx := context.Background()
+
+	created, err := s.CreateTask(ctx, &pb.CreateTaskRequest{UserId: "u1", Title: "Write report"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := created.Task.Id
+	tracked := func() string {
+		select {
+		case req := <-analytics.tracked:
+			if req.UserId != "u1" || req.ResourceId != id {
+				t.Errorf("%s event for user %q, resource %q; want u1 and %s", req.EventType, req.UserId, req.ResourceId, id)
+			}
+			return req.EventType
+		case <-time.After(time.Second):
+			return ""
+		}
+	}
+	if got := tracked(); got != "task.created" {
+		t.Fatalf("creating tracked %q, want task.created", got)
+	}
+
+	tests := []struct {
+		name      string
+		completed bool
+		want      string
+	}{
+		{"completing", true, "task.completed"},
+		{"editing a completed task", true, ""},
+		{"reopening", false, "task.reopened"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.UpdateTask(ctx, &pb.UpdateTaskRequest{Id: id, Title: "Write report", Completed: tt.completed}); err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == "" {
+				select {
+				case req := <-analytics.tracked:
+					t.Errorf("tracked %s, want nothing", req.EventType)
+				case <-time.After(100 * time.Millisecond):
+				}
+				return
+			}
+			if got := tracked(); got != tt.want {
+				t.Errorf("tracked %q, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateTaskNotifiesOnceOverEstimate(t *testing.T) {
+	notifications := &fakeNotificationClient{ch: make(chan *pb.NotificationRequest, 4)}
+	s := newTestServer(t, notifications)
+	ctx := context.Background()
+
+	created, err := s.CreateTask(ctx, &pb.CreateTaskRequest{UserId: "u1", Title: "Write report", EstimatedMinutes: 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := created.Task.Id
+
+	steps := []struct {
+		add    int32
+		notify bool
+	}{
+		{40, false},
+		{20, false}, // exactly at the estimate
+		{10, true},  // over it
+		{30, false}, // still over it
+	}
+	for i, step := range steps {
+		_, err := s.UpdateTask(ctx, &pb.UpdateTaskRequest{Id: id, Title: "Write report", EstimatedMinutes: 60, AddTimeSpentMinutes: step.add})
+		if err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case req := <-notifications.ch:
+			if !step.notify {
+				t.Errorf("step %d: unexpected notification %q", i, req.Message)
+			} else if req.Message != "Task 'Write report' has exceeded its time estimate." {
+				t.Errorf("step %d: message = %q", i, req.Message)
+			}
+		case <-time.After(200 * time.Millisecond):
+			if step.notify {
+				t.Errorf("step %d: no notification", i)
+			}
+		}
+	}
+}
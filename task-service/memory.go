@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/technonext/todo-app/proto/pagination"
+	"github.com/technonext/todo-app/proto/tenant"
+)
+
+// memoryTasks keeps tasks in the process's memory, for local development
+// without a database; they are gone once the service stops. Search matches
+// title words with a rough English stemmer standing in for MongoDB's. Like
+// postgresTasks it has no outbox.
+type memoryTasks struct {
+	mu    sync.Mutex
+	tasks map[primitive.ObjectID]Task
+	// Ids in the order the tasks were stored, which List follows
+	order []primitive.ObjectID
+}
+
+func newMemoryTasks() *memoryTasks {
+	return &memoryTasks{tasks: make(map[primitive.ObjectID]Task)}
+}
+
+// cloneTask returns task with slices of its own, so callers cannot change
+// stored tasks through them.
+func cloneTask(task Task) Task {
+	task.Labels = append([]string(nil), task.Labels...)
+	task.StatusHistory = append([]StatusChange(nil), task.StatusHistory...)
+	task.DueDateExtensions = append([]DueDateExtension(nil), task.DueDateExtensions...)
+	task.Checklist = append([]ChecklistItem(nil), task.Checklist...)
+	return task
+}
+
+// inScope reports whether task belongs to ctx's tenant.
+func inScope(ctx context.Context, task Task) bool {
+	id, ok := tenant.Scope(ctx)
+	return !ok || task.TenantID == id
+}
+
+// live returns the task with id in ctx's tenant and outside the trash. The
+// caller holds mu.
+func (m *memoryTasks) live(ctx context.Context, id primitive.ObjectID) (Task, bool) {
+	task, ok := m.tasks[id]
+	if !ok || task.IsDeleted || !inScope(ctx, task) {
+		return Task{}, false
+	}
+	return task, true
+}
+
+func (m *memoryTasks) Insert(ctx context.Context, task Task) error {
+	if id, ok := tenant.Scope(ctx); ok {
+		task.TenantID = id
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if task.PublicID != "" {
+		for _, other := range m.tasks {
+			if other.PublicID == task.PublicID {
+				return errPublicIDTaken
+			}
+		}
+	}
+	if _, ok := m.tasks[task.ID]; !ok {
+		m.order = append(m.order, task.ID)
+	}
+	m.tasks[task.ID] = cloneTask(task)
+	return nil
+}
+
+func (m *memoryTasks) Resolve(ctx context.Context, publicID string) (primitive.ObjectID, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, task := range m.tasks {
+		if task.PublicID == publicID && inScope(ctx, task) {
+			return task.ID, nil
+		}
+	}
+	return primitive.NilObjectID, errNoTask
+}
+
+func (m *memoryTasks) Get(ctx context.Context, id primitive.ObjectID, skipView bool, viewedAt string) (Task, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	task, ok := m.live(ctx, id)
+	if !ok {
+		return Task{}, errNoTask
+	}
+	if !skipView {
+		task.ViewCount++
+		task.LastViewedAt = viewedAt
+		m.tasks[id] = task
+	}
+	return cloneTask(task), nil
+}
+
+// Update holds mu throughout, so concurrent updates apply one after the
+// other, each to the task as the previous one left it.
+func (m *memoryTasks) Update(ctx context.Context, current Task, change taskChange) (before, updated Task, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	before, ok := m.live(ctx, current.ID)
+	if !ok {
+		return before, updated, errTaskChanged
+	}
+	if before.status() != current.status() || (change.LockNow != "" && before.lockedAgainst(change.Editor, change.LockNow)) {
+		return before, updated, errTaskChanged
+	}
+	updated = change.apply(cloneTask(before))
+	m.tasks[current.ID] = updated
+	return cloneTask(before), cloneTask(updated), nil
+}
+
+func (m *memoryTasks) Delete(ctx context.Context, id primitive.ObjectID, deletedAt string) (Task, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	task, ok := m.live(ctx, id)
+	if !ok {
+		return Task{}, errNoTask
+	}
+	task.IsDeleted, task.DeletedAt = true, deletedAt
+	m.tasks[id] = task
+	return cloneTask(task), nil
+}
+
+func (m *memoryTasks) Purge(ctx context.Context, id primitive.ObjectID) (Task, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	task, ok := m.live(ctx, id)
+	if !ok {
+		return Task{}, errNoTask
+	}
+	delete(m.tasks, id)
+	for i, stored := range m.order {
+		if stored == id {
+			m.order = append(m.order[:i], m.order[i+1:]...)
+			break
+		}
+	}
+	return task, nil
+}
+
+// matching returns the tasks in ctx's tenant matching filter, in the order
+// they were stored. The caller holds mu.
+func (m *memoryTasks) matching(ctx context.Context, filter SearchFilter) []Task {
+	var tasks []Task
+	for _, id := range m.order {
+		task := m.tasks[id]
+		if task.IsDeleted || task.UserID != filter.UserID || !inScope(ctx, task) {
+			continue
+		}
+		if filter.Completed && !task.Completed {
+			continue
+		}
+		if !filter.CreatedAfter.IsZero() || !filter.CreatedBefore.IsZero() {
+			created, err := time.Parse(time.RFC3339, task.CreatedAt)
+			if err != nil || (!filter.CreatedAfter.IsZero() && created.Before(filter.CreatedAfter)) ||
+				(!filter.CreatedBefore.IsZero() && !created.Before(filter.CreatedBefore)) {
+				continue
+			}
+		}
+		// Listed tasks leave out their notes, as with withoutNotes
+		task = cloneTask(task)
+		task.Notes = ""
+		tasks = append(tasks, task)
+	}
+	return tasks
+}
+
+// List returns tasks oldest first, as MongoDB returns them in the order they
+// were stored.
+func (m *memoryTasks) List(ctx context.Context, filter SearchFilter, page pagination.Page) ([]Task, int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tasks := m.matching(ctx, filter)
+	return pageOf(tasks, page), int64(len(tasks)), nil
+}
+
+func (m *memoryTasks) Search(ctx context.Context, filter SearchFilter, query string, page pagination.Page) ([]Task, int64, error) {
+	words := queryWords(query)
+	if len(words) == 0 {
+		return nil, 0, nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	type match struct {
+		task  Task
+		score int
+	}
+	var matches []match
+	for _, task := range m.matching(ctx, filter) {
+		titleWords := queryWords(task.Title)
+		stems := make(map[string]bool, len(titleWords))
+		exact := make(map[string]bool, len(titleWords))
+		for _, w := range titleWords {
+			stems[stem(w)] = true
+			exact[w] = true
+		}
+		// Any stemmed word matches, as with $text; MinWordShare counts the
+		// words as written, as the other stores do
+		score, share := 0, 0
+		for _, w := range words {
+			if stems[stem(w)] {
+				score++
+			}
+			if exact[w] {
+				share++
+			}
+		}
+		if score == 0 || (filter.MinWordShare > 0 && share < filter.requiredWords(words)) {
+			continue
+		}
+		matches = append(matches, match{task, score})
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+
+	tasks := make([]Task, 0, len(matches))
+	for _, m := range matches {
+		tasks = append(tasks, m.task)
+	}
+	return pageOf(tasks, page), int64(len(tasks)), nil
+}
+
+// pageOf returns the page of tasks.
+func pageOf(tasks []Task, page pagination.Page) []Task {
+	if page.Offset >= int64(len(tasks)) {
+		return []Task{}
+	}
+	tasks = tasks[page.Offset:]
+	if page.Limit > 0 && int(page.Limit) < len(tasks) {
+		tasks = tasks[:page.Limit]
+	}
+	return tasks
+}
+
+// stem strips the common English inflections from a lowercased word, so
+// "walking" and "walked" match "walk" and "dogs" matches "dog".
+func stem(word string) string {
+	for _, suffix := range []string{"ing", "ed", "s"} {
+		if strings.HasSuffix(word, suffix) && len(word)-len(suffix) >= 3 && !strings.HasSuffix(word, "ss") {
+			return strings.TrimSuffix(word, suffix)
+		}
+	}
+	return word
+}
@@ -0,0 +1,26 @@
+package main
+
+import "testing"
+
+// TestMemoryRepositoryContract runs the repository contract, which the
+// database repositories are held to in TestTaskRepositoryContract, on the
+// in-memory store.
+func TestMemoryRepositoryContract(t *testing.T) {
+	runRepositoryContract(t, func(*testing.T) TaskRepository { return newMemoryTasks() })
+}
+
+func TestStem(t *testing.T) {
+	for word, want := range map[string]string{
+		"walking": "walk",
+		"walked":  "walk",
+		"dogs":    "dog",
+		"dog":     "dog",
+		"address": "address",
+		"bus":     "bus",
+		"thing":   "thing",
+	} {
+		if got := stem(word); got != want {
+			t.Errorf("stem(%q) = %q, want %q", word, got, want)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/grpc/codes"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+	"github.com/technonext/todo-app/proto/publicid"
+)
+
+// GetTaskMetrics returns how often a task was viewed and edited, without the
+// task itself. Reading the metrics does not count as a view.
+func (s *server) GetTaskMetrics(ctx context.Context, req *pb.GetTaskMetricsRequest) (*pb.GetTaskMetricsResponse, error) {
+	match, err := taskFilter(req.Id)
+	if err != nil {
+		return nil, err
+	}
+	match["is_deleted"] = notDeleted
+
+	var task Task
+	opts := options.FindOne().SetProjection(bson.M{publicid.Field: 1, "view_count": 1, "edit_count": 1, "last_viewed_at": 1})
+	err = s.collection.FindOne(ctx, match, opts).Decode(&task)
+	if err == mongo.ErrNoDocuments {
+		return nil, statusError(codes.NotFound, "TASK_NOT_FOUND", map[string]string{"task_id": req.Id}, "task %s not found", req.Id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.GetTaskMetricsResponse{
+		Metrics: &pb.TaskMetrics{
+			TaskId:       task.publicID(),
+			ViewCount:    task.ViewCount,
+			EditCount:    task.EditCount,
+			LastViewedAt: task.LastViewedAt,
+		},
+	}, nil
+}
@@ -0,0 +1,67 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+func TestGetTaskMetrics(t *testing.T) {
+	s := newTestServer(t, nil)
+	ctx := context.Background()
+
+	created, err := s.CreateTask(ctx, &pb.CreateTaskRequest{UserId: "u1", Title: "Write report"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := created.Task.Id
+	metrics := func() *pb.TaskMetrics {
+		t.Helper()
+		resp, err := s.GetTaskMetrics(ctx, &pb.GetTaskMetricsRequest{Id: id})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.Metrics
+	}
+	if got := metrics(); got.TaskId != id || got.ViewCount != 0 || got.EditCount != 0 || got.LastViewedAt != "" {
+		t.Errorf("new task's metrics = %v, want none for %s", got, id)
+	}
+
+	before := time.Now().Add(-time.Second)
+	for i := 1; i <= 3; i++ {
+		if _, err := s.GetTask(ctx, &pb.GetTaskRequest{Id: id}); err != nil {
+			t.Fatal(err)
+		}
+		if got := metrics(); got.ViewCount != int64(i) {
+			t.Errorf("after view %d, view_count = %d", i, got.ViewCount)
+		}
+	}
+	// Neither previews nor reading the metrics count as views
+	if _, err := s.GetTask(ctx, &pb.GetTaskRequest{Id: id, SkipView: true}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := s.UpdateTask(ctx, &pb.UpdateTaskRequest{Id: id, Title: "Write the report"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := metrics()
+	if got.TaskId != id || got.ViewCount != 3 || got.EditCount != 2 {
+		t.Errorf("metrics = %v, want view_count=3 and edit_count=2 for %s", got, id)
+	}
+	if viewed, err := time.Parse(time.RFC3339, got.LastViewedAt); err != nil || viewed.Before(before) {
+		t.Errorf("last_viewed_at = %q, want the last view", got.LastViewedAt)
+	}
+
+	if _, err := s.GetTaskMetrics(ctx, &pb.GetTaskMetricsRequest{Id: newTaskPublicID()}); status.Code(err) != codes.NotFound {
+		t.Errorf("metrics of a missing task = %v, want NotFound", err)
+	}
+}
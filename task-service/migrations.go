@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"text/tabwriter"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/technonext/todo-app/proto/logging"
+	"github.com/technonext/todo-app/proto/migrate"
+	"github.com/technonext/todo-app/proto/mongoutil"
+	"github.com/technonext/todo-app/proto/publicid"
+	"github.com/technonext/todo-app/proto/schema"
+)
+
+// taskMigrations are the task service's data migrations, applied with
+// -migrate. Versions are never reused or reordered once released.
+var taskMigrations = []migrate.Migration{
+	{
+		Version:     1,
+		Description: "store due_date and created_at as dates",
+		Collection:  "tasks",
+		Filter: bson.M{"$or": bson.A{
+			bson.M{"due_date": bson.M{"$type": "string", "$ne": ""}},
+			bson.M{"created_at": bson.M{"$type": "string"}},
+		}},
+		Update:        stringsToDates("due_date", "created_at"),
+		SchemaVersion: 1,
+	},
+	{
+		Version:       2,
+		Description:   "backfill public_id",
+		Collection:    "tasks",
+		Filter:        bson.M{publicid.Field: bson.M{"$exists": false}},
+		Update:        publicid.Backfill(publicid.Task),
+		SchemaVersion: 2,
+	},
+	{
+		Version:     3,
+		Description: "store the status of tasks from before statuses",
+		Collection:  "tasks",
+		Filter:      bson.M{"status": bson.M{"$exists": false}},
+		Update: func(doc bson.Raw) (bson.M, error) {
+			completed, _ := doc.Lookup("completed").BooleanOK()
+			return bson.M{"$set": bson.M{"status": legacyStatus(completed).String()}}, nil
+		},
+		SchemaVersion: 3,
+	},
+}
+
+// taskSchema is the history of the layout of tasks, a version per migration
+// changing it. Tasks are decoded through it, so those the migrations have
+// not reached yet read as if they had.
+var taskSchema = schema.Schema{
+	Collection: "tasks",
+	Upgrades: []schema.Upgrade{
+		// Dates, which DatesAsStrings reads the same as strings
+		nil,
+		// Public ids, which publicid.Or stands in for
+		nil,
+		deriveStatus,
+	},
+}
+
+// deriveStatus gives a task stored before statuses existed the status its
+// completed flag implies.
+func deriveStatus(doc bson.M) error {
+	if _, ok := doc["status"]; ok {
+		return nil
+	}
+	completed, _ := doc["completed"].(bool)
+	doc["status"] = legacyStatus(completed).String()
+	return nil
+}
+
+// openTasks opens the tasks collection of db, decoding tasks of any schema
+// version, with dates stored either as dates or as strings.
+func openTasks(db *mongo.Database) *mongo.Collection {
+	return db.Collection("tasks", taskSchema.Decoding(mongoutil.DatesAsStrings(), Task{}))
+}
+
+// stringsToDates converts the RFC3339 strings in fields to BSON dates. Empty
+// and unparseable strings are left as they are, and read as no date.
+func stringsToDates(fields ...string) func(bson.Raw) (bson.M, error) {
+	return func(doc bson.Raw) (bson.M, error) {
+		set := bson.M{}
+		for _, field := range fields {
+			value, err := doc.LookupErr(field)
+			if err != nil || value.Type != bsontype.String {
+				continue
+			}
+			if t, err := time.Parse(time.RFC3339, value.StringValue()); err == nil {
+				set[field] = t
+			}
+		}
+		if len(set) == 0 {
+			return nil, nil
+		}
+		return bson.M{"$set": set}, nil
+	}
+}
+
+// taskDate is a due date or creation time as tasks store it since the dates
+// migration: a BSON date, or the string as given when it is empty or not
+// RFC3339, as the migration leaves those.
+type taskDate string
+
+func (d taskDate) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	if t, err := time.Parse(time.RFC3339, string(d)); err == nil {
+		return bson.MarshalValue(t)
+	}
+	return bson.MarshalValue(string(d))
+}
+
+// storedTask is a task as written, with its dates as taskDates.
+type storedTask struct {
+	Task      `bson:",inline"`
+	DueDate   taskDate `bson:"due_date"`
+	CreatedAt taskDate `bson:"created_at"`
+}
+
+func newStoredTask(task Task) storedTask {
+	return storedTask{Task: task, DueDate: taskDate(task.DueDate), CreatedAt: taskDate(task.CreatedAt)}
+}
+
+// toDate reads a date field stored either as a BSON date or as an RFC3339
+// string, as a date; anything else is null.
+func toDate(field string) bson.M {
+	return bson.M{"$convert": bson.M{
+		"input":   field,
+		"to":      "date",
+		"onError": nil,
+		"onNull":  nil,
+	}}
+}
+
+// runMigrations applies the pending migrations, or with dryRun reports what
+// they would change, and writes a line per migration to w.
+func runMigrations(ctx context.Context, db *mongo.Database, dryRun bool, w io.Writer) error {
+	runner, err := migrate.New(db, "task-service", taskMigrations...)
+	if err != nil {
+		return err
+	}
+	run := runner.Run
+	if dryRun {
+		run = runner.DryRun
+	}
+	results, err := run(ctx)
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tDESCRIPTION\tSCANNED\tCHANGED")
+	for _, r := range results {
+		fmt.Fprintf(tw, "%d\t%s\t%d\t%d\n", r.Version, r.Description, r.Scanned, r.Changed)
+	}
+	tw.Flush()
+	if len(results) == 0 && err == nil {
+		fmt.Fprintln(w, "No pending migrations")
+	}
+	return err
+}
+
+// warnPendingMigrations logs the migrations the database still needs.
+func warnPendingMigrations(ctx context.Context, db *mongo.Database) {
+	runner, err := migrate.New(db, "task-service", taskMigrations...)
+	if err != nil {
+		logging.Fatal("Invalid migrations", "error", err)
+	}
+	pending, err := runner.Pending(ctx)
+	if err != nil {
+		slog.Warn("Failed to check for pending migrations", "error", err)
+		return
+	}
+	for _, m := range pending {
+		slog.Warn("Data migration pending, apply it with -migrate", "version", m.Version, "description", m.Description)
+	}
+}
@@ -0,0 +1,315 @@
+//go:build integration
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	"github.com/technonext/todo-app/proto/mongoutil"
+	pb "github.com/technonext/todo-app/proto/proto"
+	"github.com/technonext/todo-app/proto/schema"
+	"github.com/technonext/todo-app/proto/tenant"
+)
+
+func TestDateMigrationOnMixedData(t *testing.T) {
+	s := newTestServer(t, nil)
+	ctx := context.Background()
+	db := s.collection.Database()
+
+	due := time.Date(2026, 3, 14, 12, 0, 0, 0, time.UTC)
+	created := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	docs := map[string]bson.M{
+		"strings":      {"due_date": due.Format(time.RFC3339), "created_at": created.Format(time.RFC3339)},
+		"dates":        {"due_date": due, "created_at": created},
+		"half":         {"due_date": due.Format(time.RFC3339), "created_at": created},
+		"no due date":  {"due_date": "", "created_at": created.Format(time.RFC3339)},
+		"unparseable":  {"due_date": "soon", "created_at": "yesterday"},
+		"other fields": {"title": "untouched"},
+	}
+	ids := map[string]primitive.ObjectID{}
+	for name, doc := range docs {
+		doc["user_id"] = "u1"
+		doc["title"] = name
+		doc["updated_at"] = created.Format(time.RFC3339)
+		result, err := s.collection.InsertOne(ctx, doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[name] = result.InsertedID.(primitive.ObjectID)
+	}
+
+	var out bytes.Buffer
+	if err := runMigrations(ctx, db, true, &out); err != nil {
+		t.Fatal(err)
+	}
+	// strings, half, no due date and unparseable match; three get changes
+	if fields := strings.Fields(versionLine(out.String(), 1)); len(fields) < 2 || fields[len(fields)-2] != "4" || fields[len(fields)-1] != "3" {
+		t.Errorf("dry run printed %q, want 4 scanned and 3 changed", out.String())
+	}
+
+	out.Reset()
+	if err := runMigrations(ctx, db, false, &out); err != nil {
+		t.Fatal(err)
+	}
+	wantTypes := map[string][2]bsontype.Type{
+		"strings":     {bsontype.DateTime, bsontype.DateTime},
+		"dates":       {bsontype.DateTime, bsontype.DateTime},
+		"half":        {bsontype.DateTime, bsontype.DateTime},
+		"no due date": {bsontype.String, bsontype.DateTime},
+		"unparseable": {bsontype.String, bsontype.String},
+	}
+	for name, want := range wantTypes {
+		raw, err := s.collection.FindOne(ctx, bson.M{"_id": ids[name]}).Raw()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := [2]bsontype.Type{raw.Lookup("due_date").Type, raw.Lookup("created_at").Type}; got != want {
+			t.Errorf("%s: due_date, created_at types = %v, want %v", name, got, want)
+		}
+	}
+
+	out.Reset()
+	if err := runMigrations(ctx, db, false, &out); err != nil || !strings.Contains(out.String(), "No pending migrations") {
+		t.Errorf("second run printed %q, %v", out.String(), err)
+	}
+
+	// Migrated tasks read as before, next to a task created since
+	got, err := s.GetTask(ctx, &pb.GetTaskRequest{Id: ids["strings"].Hex()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotDue, err := time.Parse(time.RFC3339, got.Task.DueDate); err != nil || !gotDue.Equal(due) {
+		t.Errorf("due date read back as %q, want %v", got.Task.DueDate, due)
+	}
+	if _, err := s.CreateTask(ctx, &pb.CreateTaskRequest{UserId: "u1", Title: "new", DueDate: due.Format(time.RFC3339)}); err != nil {
+		t.Fatal(err)
+	}
+	calendar, err := s.GetTasksCalendarView(ctx, &pb.GetTasksCalendarViewRequest{UserId: "u1", Year: 2026, Month: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(calendar.Days) != 1 || calendar.Days[0].TaskCount != 4 {
+		t.Errorf("calendar = %v, want the 4 tasks due on March 14", calendar.Days)
+	}
+	synced, err := s.SyncTasks(ctx, &pb.SyncTasksRequest{UserId: "u1", Since: created.Add(-time.Hour).Format(time.RFC3339)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(synced.Created) != 5 {
+		t.Errorf("sync listed %d created tasks, want the 4 with a creation date and the new one", len(synced.Created))
+	}
+}
+
+// versionLine returns the line runMigrations printed for version.
+func versionLine(s string, version int) string {
+	for _, line := range strings.Split(s, "\n") {
+		if strings.HasPrefix(line, strconv.Itoa(version)+" ") {
+			return line
+		}
+	}
+	return ""
+}
+
+// TestMixedVersionReplicas runs the task repository of this release next
+// to one of the release before, which knows schema version 2, over the same
+// collection, as during a rolling deploy, with tasks of every version in it.
+func TestMixedVersionReplicas(t *testing.T) {
+	s := newTestServer(t, nil)
+	ctx := context.Background()
+	db := s.collection.Database()
+	previous := schema.Schema{Collection: "tasks", Upgrades: taskSchema.Upgrades[:2]}
+	current := mongoTasks{collection: s.collection}
+	old := mongoTasks{collection: tenant.Scoped(db.Collection("tasks", previous.Decoding(mongoutil.DatesAsStrings(), Task{})))}
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	// Tasks stored before statuses, and one the previous release created
+	legacyOpen, legacyDone, fromPrevious := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	for _, doc := range []bson.M{
+		{"_id": legacyOpen, "user_id": "u1", "title": "legacy open", "completed": false, "created_at": now},
+		{"_id": legacyDone, "user_id": "u1", "title": "legacy done", "completed": true, "created_at": now},
+		{"_id": fromPrevious, "user_id": "u1", "title": "previous", "status": "TASK_STATUS_NOT_STARTED", "created_at": now, "schema_version": 2},
+	} {
+		if _, err := s.collection.InsertOne(ctx, doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// And one this release created
+	created := Task{ID: primitive.NewObjectID(), UserID: "u1", Title: "current", Status: "TASK_STATUS_NOT_STARTED", CreatedAt: now}
+	if err := current.Insert(ctx, created); err != nil {
+		t.Fatal(err)
+	}
+	if err := previous.Check(ctx, db.Collection("tasks")); !errors.Is(err, schema.ErrTooNew) {
+		t.Errorf("a replica of the previous release started: %v, want ErrTooNew", err)
+	}
+	if err := taskSchema.Check(ctx, db.Collection("tasks")); err != nil {
+		t.Errorf("a replica of this release refused to start: %v", err)
+	}
+
+	// This release reads the legacy tasks with the status their flag
+	// implies, and can move them on before the migration stores it
+	done, err := current.Get(ctx, legacyDone, true, "")
+	if err != nil || done.status() != pb.TaskStatus_TASK_STATUS_COMPLETED || done.SchemaVersion != taskSchema.Version() {
+		t.Fatalf("legacy done = %+v, %v; want it completed at version %d", done, err, taskSchema.Version())
+	}
+	open, err := current.Get(ctx, legacyOpen, true, "")
+	if err != nil || open.Status != "TASK_STATUS_NOT_STARTED" {
+		t.Fatalf("legacy open = %+v, %v; want it not started", open, err)
+	}
+	start := taskChange{Status: pb.TaskStatus_TASK_STATUS_IN_PROGRESS, UpdatedAt: now, EventType: "task.updated"}
+	if _, _, err := current.Update(ctx, open, start); err != nil {
+		t.Fatalf("starting the legacy task: %v", err)
+	}
+
+	// The previous release reads tasks of this one as they are and updates
+	// them without losing what it does not know
+	for _, id := range []primitive.ObjectID{legacyOpen, created.ID} {
+		task, err := old.Get(ctx, id, true, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := pb.TaskStatus_TASK_STATUS_IN_PROGRESS
+		if id == created.ID {
+			want = pb.TaskStatus_TASK_STATUS_NOT_STARTED
+		}
+		if task.status() != want {
+			t.Errorf("previous release read %s as %s, want %s", task.Title, task.Status, want)
+		}
+		complete := taskChange{Status: pb.TaskStatus_TASK_STATUS_COMPLETED, UpdatedAt: now, EventType: "task.completed"}
+		if _, _, err := old.Update(ctx, task, complete); err != nil {
+			t.Errorf("previous release completing %s: %v", task.Title, err)
+		}
+	}
+	raw, err := s.collection.FindOne(ctx, bson.M{"_id": created.ID}).Raw()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if schema.Version(raw) != taskSchema.Version() || raw.Lookup("status").StringValue() != "TASK_STATUS_COMPLETED" {
+		t.Errorf("task updated by the previous release = %v, want it completed at version %d", raw, taskSchema.Version())
+	}
+
+	// The previous release still reads legacy tasks its own way, and its
+	// changes to them are seen by this one
+	legacy, err := old.Get(ctx, legacyDone, true, "")
+	if err != nil || legacy.Status != "" {
+		t.Fatalf("previous release read legacy done as %+v, %v; want no status", legacy, err)
+	}
+	reopen := taskChange{Status: pb.TaskStatus_TASK_STATUS_ON_HOLD, UpdatedAt: now, EventType: "task.updated"}
+	if _, _, err := old.Update(ctx, legacy, reopen); err != nil {
+		t.Fatalf("previous release holding the legacy task: %v", err)
+	}
+	if got, err := current.Get(ctx, legacyDone, true, ""); err != nil || got.status() != pb.TaskStatus_TASK_STATUS_ON_HOLD {
+		t.Errorf("legacy done after the previous release's change = %+v, %v; want it on hold", got, err)
+	}
+	// A change made against a status read before it is refused on either
+	if _, _, err := current.Update(ctx, done, start); err != errTaskChanged {
+		t.Errorf("stale update = %v, want errTaskChanged", err)
+	}
+
+	// Once every replica runs this release the migration stores the
+	// statuses, and tasks read the same
+	var out bytes.Buffer
+	if err := runMigrations(ctx, db, false, &out); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := s.collection.CountDocuments(ctx, bson.M{"status": bson.M{"$exists": false}}); n != 0 {
+		t.Errorf("%d tasks without a status after the migration", n)
+	}
+	if got, err := current.Get(ctx, fromPrevious, true, ""); err != nil || got.status() != pb.TaskStatus_TASK_STATUS_NOT_STARTED {
+		t.Errorf("task of the previous release after the migration = %+v, %v", got, err)
+	}
+}
+
+func TestTaskWritesStoreDates(t *testing.T) {
+	s := newTestServer(t, nil)
+	ctx := context.Background()
+	var out bytes.Buffer
+	if err := runMigrations(ctx, s.collection.Database(), false, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	due := time.Date(2026, 3, 14, 12, 0, 0, 0, time.UTC)
+	created, err := s.CreateTask(ctx, &pb.CreateTaskRequest{UserId: "u1", Title: "Dated", DueDate: due.Format(time.RFC3339)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := s.repository().Resolve(ctx, created.Task.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored := func(t *testing.T, field string) bson.RawValue {
+		t.Helper()
+		raw, err := s.collection.FindOne(ctx, bson.M{"_id": id}).Raw()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return raw.Lookup(field)
+	}
+
+	writes := []struct {
+		name  string
+		write func() error
+		due   time.Time
+	}{
+		{"create", func() error { return nil }, due},
+		{"update", func() error {
+			_, err := s.UpdateTask(ctx, &pb.UpdateTaskRequest{Id: created.Task.Id, Title: "Dated", DueDate: due.Add(24 * time.Hour).Format(time.RFC3339)})
+			return err
+		}, due.Add(24 * time.Hour)},
+		{"extend", func() error {
+			_, err := s.ExtendTaskDueDate(ctx, &pb.ExtendDueDateRequest{Id: created.Task.Id, NewDueDate: due.Add(48 * time.Hour).Format(time.RFC3339)})
+			return err
+		}, due.Add(48 * time.Hour)},
+		{"bulk update", func() error {
+			_, err := s.BulkUpdateTasks(ctx, &pb.BulkUpdateTasksRequest{
+				UserId:     "u1",
+				Ids:        []string{created.Task.Id},
+				Updates:    &pb.TaskUpdate{DueDate: due.Add(72 * time.Hour).Format(time.RFC3339)},
+				UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"due_date"}},
+			})
+			return err
+		}, due.Add(72 * time.Hour)},
+	}
+	for _, tt := range writes {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.write(); err != nil {
+				t.Fatal(err)
+			}
+			value := stored(t, "due_date")
+			if at, ok := value.TimeOK(); !ok || !at.Equal(tt.due) {
+				t.Errorf("due_date stored as %v %v, want the date %v", value.Type, value, tt.due)
+			}
+			if value := stored(t, "created_at"); value.Type != bsontype.DateTime {
+				t.Errorf("created_at stored as %v, want a date", value.Type)
+			}
+		})
+	}
+
+	// Clearing the due date stores the empty string the migration leaves
+	_, err = s.UpdateTask(ctx, &pb.UpdateTaskRequest{Id: created.Task.Id, Title: "Dated"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value := stored(t, "due_date"); value.StringValue() != "" {
+		t.Errorf("cleared due_date stored as %v, want \"\"", value)
+	}
+	got, err := s.GetTask(ctx, &pb.GetTaskRequest{Id: created.Task.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	readAt, _ := time.Parse(time.RFC3339, got.Task.CreatedAt)
+	createdAt, _ := time.Parse(time.RFC3339, created.Task.CreatedAt)
+	if got.Task.DueDate != "" || !readAt.Equal(createdAt) {
+		t.Errorf("read back due %q, created %q; want none and %q", got.Task.DueDate, got.Task.CreatedAt, created.Task.CreatedAt)
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/technonext/todo-app/proto/mongoutil"
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+func TestStringsToDates(t *testing.T) {
+	due := time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC)
+	created := time.Date(2026, 3, 1, 3, 0, 0, 0, time.UTC)
+	convert := stringsToDates("due_date", "created_at")
+	tests := []struct {
+		name string
+		doc  bson.M
+		want bson.M
+	}{
+		{"both strings", bson.M{"due_date": "2026-03-04T12:00:00Z", "created_at": "2026-03-01T09:00:00+06:00"},
+			bson.M{"due_date": due, "created_at": created}},
+		{"no due date", bson.M{"due_date": "", "created_at": "2026-03-01T09:00:00+06:00"},
+			bson.M{"created_at": created}},
+		{"already migrated", bson.M{"due_date": due, "created_at": created}, nil},
+		{"half migrated", bson.M{"due_date": "2026-03-04T12:00:00Z", "created_at": created},
+			bson.M{"due_date": due}},
+		{"unparseable", bson.M{"due_date": "next friday", "created_at": "yesterday"}, nil},
+		{"missing fields", bson.M{"title": "Old task"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := bson.Marshal(tt.doc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			update, err := convert(raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == nil {
+				if update != nil {
+					t.Errorf("update = %v, want none", update)
+				}
+				return
+			}
+			set, _ := update["$set"].(bson.M)
+			if len(set) != len(tt.want) {
+				t.Fatalf("update = %v, want $set of %v", update, tt.want)
+			}
+			for field, want := range tt.want {
+				if got, ok := set[field].(time.Time); !ok || !got.Equal(want.(time.Time)) {
+					t.Errorf("%s = %v, want %v", field, set[field], want)
+				}
+			}
+		})
+	}
+}
+
+func TestStoredTaskDates(t *testing.T) {
+	tests := []struct {
+		name    string
+		due     string
+		dueType bsontype.Type
+	}{
+		{"due date", "2026-03-04T12:00:00Z", bsontype.DateTime},
+		{"no due date", "", bsontype.String},
+		{"unparseable", "next friday", bsontype.String},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := bson.Marshal(newStoredTask(Task{Title: "Dated", DueDate: tt.due, CreatedAt: "2026-03-01T09:00:00+06:00"}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			raw := bson.Raw(data)
+			if got := raw.Lookup("due_date").Type; got != tt.dueType {
+				t.Errorf("due_date stored as %v, want %v", got, tt.dueType)
+			}
+			created, ok := raw.Lookup("created_at").TimeOK()
+			if !ok || !created.Equal(time.Date(2026, 3, 1, 3, 0, 0, 0, time.UTC)) {
+				t.Errorf("created_at stored as %v", raw.Lookup("created_at"))
+			}
+			if title := raw.Lookup("title").StringValue(); title != "Dated" {
+				t.Errorf("title = %q", title)
+			}
+		})
+	}
+}
+
+func TestDecodeTaskVersions(t *testing.T) {
+	oid := primitive.NewObjectID()
+	due := time.Date(2026, 3, 14, 12, 0, 0, 0, time.UTC)
+	created := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	// A task as each release stored it
+	tests := []struct {
+		name   string
+		doc    bson.M
+		status pb.TaskStatus
+	}{
+		{"version 0, dates as strings, before statuses",
+			bson.M{"_id": oid, "title": "Old", "completed": true, "due_date": due.Format(time.RFC3339), "created_at": created.Format(time.RFC3339)},
+			pb.TaskStatus_TASK_STATUS_COMPLETED},
+		{"version 1, dates as dates",
+			bson.M{"_id": oid, "title": "Old", "completed": false, "due_date": due, "created_at": created, "schema_version": 1},
+			pb.TaskStatus_TASK_STATUS_NOT_STARTED},
+		{"version 2, public ids",
+			bson.M{"_id": oid, "title": "Old", "due_date": due, "created_at": created, "public_id": "task_01HV4Z3K9Q8W5N2M7R6T1Y0XCB", "schema_version": 2},
+			pb.TaskStatus_TASK_STATUS_NOT_STARTED},
+		{"version 2 with a status",
+			bson.M{"_id": oid, "title": "Old", "status": "TASK_STATUS_IN_PROGRESS", "due_date": due, "created_at": created, "schema_version": 2},
+			pb.TaskStatus_TASK_STATUS_IN_PROGRESS},
+		{"version 3, statuses",
+			bson.M{"_id": oid, "title": "Old", "status": "TASK_STATUS_ON_HOLD", "due_date": due.Format(time.RFC3339), "created_at": created, "schema_version": 3},
+			pb.TaskStatus_TASK_STATUS_ON_HOLD},
+	}
+	registry := taskSchema.Decoding(mongoutil.DatesAsStrings(), Task{}).Registry
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cursor, err := mongo.NewCursorFromDocuments([]interface{}{tt.doc}, nil, registry)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var tasks []Task
+			if err := cursor.All(context.Background(), &tasks); err != nil {
+				t.Fatal(err)
+			}
+			task := tasks[0]
+			if task.ID != oid || task.Title != "Old" {
+				t.Errorf("task = %+v, want %s titled Old", task, oid.Hex())
+			}
+			if task.Status != tt.status.String() {
+				t.Errorf("status = %q, want %s", task.Status, tt.status)
+			}
+			if task.SchemaVersion != taskSchema.Version() {
+				t.Errorf("schema version = %d, want %d", task.SchemaVersion, taskSchema.Version())
+			}
+			if got, err := time.Parse(time.RFC3339, task.DueDate); err != nil || !got.Equal(due) {
+				t.Errorf("due date = %q, want %v", task.DueDate, due)
+			}
+			if got, err := time.Parse(time.RFC3339, task.CreatedAt); err != nil || !got.Equal(created) {
+				t.Errorf("created at = %q, want %v", task.CreatedAt, created)
+			}
+		})
+	}
+}
+
+func TestMigrationsMatchTaskSchema(t *testing.T) {
+	// Each migration of tasks bumps the schema, and the last one is the
+	// version tasks are written with
+	version := 0
+	for _, m := range taskMigrations {
+		if m.Collection != "tasks" {
+			continue
+		}
+		if m.SchemaVersion != version+1 {
+			t.Errorf("migration %d has schema version %d, want %d", m.Version, m.SchemaVersion, version+1)
+		}
+		version = m.SchemaVersion
+	}
+	if version != taskSchema.Version() {
+		t.Errorf("migrations reach schema version %d, taskSchema %d", version, taskSchema.Version())
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"google.golang.org/grpc/codes"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+	"github.com/technonext/todo-app/proto/tenant"
+)
+
+// Mongo error code for transactions on a standalone server
+const errCodeIllegalOperation = 20
+
+// transactor runs fn in a transaction, committing it when fn returns nil and
+// aborting it otherwise.
+type transactor interface {
+	WithTransaction(ctx context.Context, fn func(ctx mongo.SessionContext) error) error
+}
+
+// mongoTransactor runs transactions in a session of client. Transactions need
+// MongoDB to run as a replica set or sharded cluster.
+type mongoTransactor struct {
+	client *mongo.Client
+}
+
+func (t mongoTransactor) WithTransaction(ctx context.Context, fn func(ctx mongo.SessionContext) error) error {
+	session, err := t.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+	_, err = session.WithTransaction(ctx, func(ctx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(ctx)
+	})
+	return err
+}
+
+func (s *server) transactor() transactor {
+	if s.transactions != nil {
+		return s.transactions
+	}
+	return mongoTransactor{client: s.collection.Database().Client()}
+}
+
+// MoveTask hands a task over to another user. The ownership change and the
+// tombstone that drops the task from the previous owner's SyncTasks commit in
+// a transaction, or not at all; only then are both owners notified, on a
+// best-effort basis, since notifications cannot take part in it.
+func (s *server) MoveTask(ctx context.Context, req *pb.MoveTaskRequest) (*pb.TaskResponse, error) {
+	match, err := taskFilter(req.Id)
+	if err != nil {
+		return nil, err
+	}
+	match["is_deleted"] = notDeleted
+	if req.ToUserId == "" {
+		return nil, statusError(codes.InvalidArgument, "TO_USER_ID_REQUIRED", nil, "to_user_id is required")
+	}
+
+	var current Task
+	err = s.collection.FindOne(ctx, match).Decode(&current)
+	if err == mongo.ErrNoDocuments {
+		return nil, statusError(codes.NotFound, "TASK_NOT_FOUND", map[string]string{"task_id": req.Id}, "task %s not found", req.Id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if current.UserID == req.ToUserId {
+		return nil, statusError(codes.InvalidArgument, "SAME_OWNER", map[string]string{"task_id": req.Id, "user_id": req.ToUserId}, "task %s already belongs to %s", req.Id, req.ToUserId)
+	}
+	for _, userID := range []string{current.UserID, req.ToUserId} {
+		if err := s.checkNotFrozen(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	update := bson.M{"$set": bson.M{"user_id": req.ToUserId, "updated_at": now}}
+	if s.outbox != nil {
+		snapshot, err := projectUpdate(current, update)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.stageEvent(update, "task.updated", &snapshot, now); err != nil {
+			return nil, err
+		}
+	}
+
+	var moved Task
+	err = s.transactor().WithTransaction(ctx, func(ctx mongo.SessionContext) error {
+		// The owner read above must still be the owner, or another move won
+		result, err := s.collection.UpdateOne(ctx, bson.M{"_id": current.ID, "user_id": current.UserID, "is_deleted": notDeleted}, update)
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return statusError(codes.Aborted, "TASK_CONFLICT", map[string]string{"task_id": req.Id}, "task %s was changed concurrently, retry the move", req.Id)
+		}
+		_, err = s.deletedCollection.InsertOne(ctx, DeletedTask{
+			TaskID:    current.publicID(),
+			UserID:    current.UserID,
+			DeletedAt: now,
+			TenantID:  current.TenantID,
+		})
+		if err != nil {
+			return err
+		}
+		return s.collection.FindOne(ctx, bson.M{"_id": current.ID}).Decode(&moved)
+	})
+	var serverErr mongo.ServerError
+	if errors.As(err, &serverErr) && serverErr.HasErrorCode(errCodeIllegalOperation) {
+		return nil, statusError(codes.FailedPrecondition, "TRANSACTIONS_UNSUPPORTED", nil, "moving tasks needs MongoDB to run as a replica set")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	go s.notifyMoved(moved, current.UserID)
+	return &pb.TaskResponse{Task: moved.toProto()}, nil
+}
+
+// notifyMoved tells the previous and the new owner about a move. It is
+// best-effort: failures are logged, and one owner failing does not keep the
+// other from being told.
+func (s *server) notifyMoved(task Task, fromUserID string) {
+	if s.notifications == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(tenant.WithID(context.Background(), task.TenantID), 3*time.Second)
+	defer cancel()
+
+	messages := []*pb.NotificationRequest{
+		{UserId: fromUserID, Message: fmt.Sprintf("Task '%s' was moved to another user.", task.Title), Type: pb.NotificationType_NOTIFICATION_TYPE_TASK},
+		{UserId: task.UserID, Message: fmt.Sprintf("Task '%s' was moved to you.", task.Title), Type: pb.NotificationType_NOTIFICATION_TYPE_TASK},
+	}
+	for _, req := range messages {
+		if _, err := s.notifications.SendNotification(ctx, req); err != nil {
+			log.Printf("Failed to notify user %s of task %s moving: %v", req.UserId, task.ID.Hex(), err)
+		}
+	}
+}
+
+// warnIfStandalone logs a warning when MongoDB cannot run transactions, which
+// MoveTask needs. A member started with --replSet but not yet initiated
+// answers isreplicaset instead of a set name.
+func warnIfStandalone(ctx context.Context, client *mongo.Client) {
+	var hello struct {
+		SetName      string `bson:"setName"`
+		IsReplicaSet bool   `bson:"isreplicaset"`
+		Msg          string `bson:"msg"`
+	}
+	if err := client.Database("admin").RunCommand(ctx, bson.M{"hello": 1}).Decode(&hello); err != nil {
+		log.Printf("Failed to check whether MongoDB supports transactions: %v", err)
+		return
+	}
+	switch {
+	case hello.IsReplicaSet:
+		log.Printf("Warning: MongoDB's replica set is not initiated yet; moving tasks fails until it is")
+	case hello.SetName == "" && hello.Msg != "isdbgrid":
+		log.Printf("Warning: MongoDB is a standalone server; moving tasks needs transactions, so it fails unless MongoDB runs as a replica set")
+	}
+}
@@ -0,0 +1,146 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// supportsTransactions reports whether the test server's MongoDB is a replica
+// set or sharded cluster.
+func supportsTransactions(t *testing.T, s *server) bool {
+	t.Helper()
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	err := s.collection.Database().Client().Database("admin").RunCommand(context.Background(), bson.M{"hello": 1}).Decode(&hello)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hello.SetName != "" || hello.Msg == "isdbgrid"
+}
+
+func TestMoveTask(t *testing.T) {
+	notifications := &fakeNotificationClient{ch: make(chan *pb.NotificationRequest, 2)}
+	s := newTestServer(t, notifications)
+	ctx := context.Background()
+
+	created, err := s.CreateTask(ctx, &pb.CreateTaskRequest{UserId: "u1", Title: "Write report"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	before, err := s.SyncTasks(ctx, &pb.SyncTasksRequest{UserId: "u1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := s.MoveTask(ctx, &pb.MoveTaskRequest{Id: created.Task.Id, ToUserId: "u2"})
+	if !supportsTransactions(t, s) {
+		if code := status.Code(err); code != codes.FailedPrecondition {
+			t.Fatalf("move on a standalone server: code = %v, want FailedPrecondition (%v)", code, err)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Task.UserId != "u2" {
+		t.Errorf("owner = %s, want u2", resp.Task.UserId)
+	}
+
+	// The previous owner's clients drop the task
+	synced, err := s.SyncTasks(ctx, &pb.SyncTasksRequest{UserId: "u1", Since: before.ServerTime})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(synced.DeletedIds) != 1 || synced.DeletedIds[0] != created.Task.Id {
+		t.Errorf("u1 sync deleted %v, want [%s]", synced.DeletedIds, created.Task.Id)
+	}
+
+	notified := make(map[string]bool)
+	for i := 0; i < 2; i++ {
+		select {
+		case req := <-notifications.ch:
+			notified[req.UserId] = true
+		case <-time.After(2 * time.Second):
+			t.Fatalf("notified %v, want both owners", notified)
+		}
+	}
+	if !notified["u1"] || !notified["u2"] {
+		t.Errorf("notified %v, want u1 and u2", notified)
+	}
+
+	if _, err := s.MoveTask(ctx, &pb.MoveTaskRequest{Id: created.Task.Id, ToUserId: "u2"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("moving to the current owner: %v, want InvalidArgument", err)
+	}
+}
+
+var errSimulatedAbort = errors.New("simulated failure before commit")
+
+// abortingTransactor runs fn in a real transaction, then fails it so the
+// transaction aborts after fn's writes.
+type abortingTransactor struct {
+	mongoTransactor
+}
+
+func (t abortingTransactor) WithTransaction(ctx context.Context, fn func(ctx mongo.SessionContext) error) error {
+	return t.mongoTransactor.WithTransaction(ctx, func(ctx mongo.SessionContext) error {
+		if err := fn(ctx); err != nil {
+			return err
+		}
+		return errSimulatedAbort
+	})
+}
+
+func TestMoveTaskAbortLeavesTaskAndNotifiesNoOne(t *testing.T) {
+	notifications := &fakeNotificationClient{}
+	s := newTestServer(t, notifications)
+	if !supportsTransactions(t, s) {
+		t.Skip("MongoDB is not a replica set, so it cannot run transactions")
+	}
+	s.transactions = abortingTransactor{mongoTransactor{client: s.collection.Database().Client()}}
+	ctx := context.Background()
+
+	created, err := s.CreateTask(ctx, &pb.CreateTaskRequest{UserId: "u1", Title: "Write report"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.MoveTask(ctx, &pb.MoveTaskRequest{Id: created.Task.Id, ToUserId: "u2"}); !errors.Is(err, errSimulatedAbort) {
+		t.Fatalf("err = %v, want the simulated failure", err)
+	}
+
+	got, err := s.GetTask(ctx, &pb.GetTaskRequest{Id: created.Task.Id, SkipView: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Task.UserId != "u1" || got.Task.UpdatedAt != created.Task.UpdatedAt {
+		t.Errorf("task = owner %s updated %s, want it untouched", got.Task.UserId, got.Task.UpdatedAt)
+	}
+	tombstones, err := s.deletedCollection.CountDocuments(ctx, bson.M{"user_id": "u1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tombstones != 0 {
+		t.Errorf("aborted move left %d tombstones for u1", tombstones)
+	}
+	// Notifications are sent in the background, so give a wrongly sent one
+	// time to arrive
+	time.Sleep(100 * time.Millisecond)
+	notifications.mu.Lock()
+	defer notifications.mu.Unlock()
+	if len(notifications.sent) != 0 {
+		t.Errorf("sent %d notifications for an aborted move", len(notifications.sent))
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/grpc/codes"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// withoutNotes projects listed tasks, which leave out their notes: notes
+// run to 100 KB a task, and are read one task at a time with GetTaskNotes.
+var withoutNotes = bson.M{"notes": 0}
+
+// GetTaskNotes returns a task's notes, without the rest of the task.
+func (s *server) GetTaskNotes(ctx context.Context, req *pb.GetTaskNotesRequest) (*pb.GetTaskNotesResponse, error) {
+	match, err := taskFilter(req.Id)
+	if err != nil {
+		return nil, err
+	}
+	match["is_deleted"] = notDeleted
+
+	var task Task
+	opts := options.FindOne().SetProjection(bson.M{"notes": 1, "updated_at": 1, "public_id": 1})
+	err = s.collection.FindOne(ctx, match, opts).Decode(&task)
+	if err == mongo.ErrNoDocuments {
+		return nil, statusError(codes.NotFound, "TASK_NOT_FOUND", map[string]string{"task_id": req.Id}, "task %s not found", req.Id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &pb.GetTaskNotesResponse{TaskId: task.publicID(), Notes: task.Notes, UpdatedAt: task.UpdatedAt}, nil
+}
+
+// UpdateTaskNotes replaces a task's notes, and only them, so clients editing
+// notes need not send the whole task back. Like UpdateTask, it is refused
+// while someone else holds the task's lock.
+func (s *server) UpdateTaskNotes(ctx context.Context, req *pb.UpdateTaskNotesRequest) (*pb.TaskResponse, error) {
+	current, err := s.writableTask(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	editor := requestingUser(ctx, "")
+	lockNow := lockTime(time.Now())
+	if current.lockedAgainst(editor, lockNow) {
+		return nil, taskLockedError(current)
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	update := bson.M{"$set": bson.M{"notes": req.Notes, "updated_at": now}}
+	if s.outbox != nil {
+		snapshot := current
+		snapshot.Notes, snapshot.UpdatedAt = req.Notes, now
+		if err := s.stageEvent(update, "task.updated", &snapshot, now); err != nil {
+			return nil, err
+		}
+	}
+
+	// The lock is checked again by the update itself, in case someone locked
+	// the task since it was read
+	filter := editableBy(editor, lockNow)
+	filter["_id"], filter["is_deleted"] = current.ID, notDeleted
+	var updated Task
+	err = s.collection.FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&updated)
+	if err == mongo.ErrNoDocuments {
+		id := current.publicID()
+		return nil, statusError(codes.Aborted, "TASK_CONFLICT", map[string]string{"task_id": id}, "task %s was changed concurrently, retry the update", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	go s.trackTaskEvent("task.updated", updated)
+	return &pb.TaskResponse{Task: updated.toProto()}, nil
+}
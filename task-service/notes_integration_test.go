@@ -0,0 +1,86 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+func TestTaskNotes(t *testing.T) {
+	s := newTestServer(t, nil)
+	ctx := context.Background()
+	created, err := s.CreateTask(ctx, &pb.CreateTaskRequest{UserId: "u1", Title: "Write report", Description: "Quarterly numbers"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := created.Task.Id
+
+	notes := "# Outline\n\n- revenue\n- costs\n\n" + strings.Repeat("Lorem ipsum dolor sit amet. ", 3000)
+	updated, err := s.UpdateTaskNotes(ctx, &pb.UpdateTaskNotesRequest{Id: id, Notes: notes})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Task.Notes != notes || updated.Task.Description != "Quarterly numbers" || updated.Task.UpdatedAt == "" {
+		t.Errorf("updated task has %d bytes of notes and description %q, want the notes alongside the description", len(updated.Task.Notes), updated.Task.Description)
+	}
+
+	got, err := s.GetTaskNotes(ctx, &pb.GetTaskNotesRequest{Id: id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Notes != notes || got.TaskId != id || got.UpdatedAt != updated.Task.UpdatedAt {
+		t.Errorf("GetTaskNotes = task %s, %d bytes at %s; want task %s with the notes", got.TaskId, len(got.Notes), got.UpdatedAt, id)
+	}
+	task, err := s.GetTask(ctx, &pb.GetTaskRequest{Id: id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.Task.Notes != notes {
+		t.Error("GetTask left out the notes")
+	}
+
+	// Listed tasks leave the notes out
+	list, err := s.ListTasks(ctx, &pb.ListTasksRequest{UserId: "u1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Tasks) != 1 || list.Tasks[0].Notes != "" || list.Tasks[0].Description != "Quarterly numbers" {
+		t.Errorf("listed %v, want the task without its notes", list.Tasks)
+	}
+
+	// Empty notes clear them
+	if _, err := s.UpdateTaskNotes(ctx, &pb.UpdateTaskNotesRequest{Id: id}); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := s.GetTaskNotes(ctx, &pb.GetTaskNotesRequest{Id: id}); err != nil || got.Notes != "" {
+		t.Errorf("notes after clearing = %q, %v; want none", got.GetNotes(), err)
+	}
+
+	if _, err := s.GetTaskNotes(ctx, &pb.GetTaskNotesRequest{Id: "000000000000000000000000"}); status.Code(err) != codes.NotFound {
+		t.Errorf("notes of a missing task: %v, want NotFound", err)
+	}
+}
+
+func TestUpdateTaskNotesRespectsLock(t *testing.T) {
+	s := newTestServer(t, nil)
+	ctx := context.Background()
+	created, err := s.CreateTask(ctx, &pb.CreateTaskRequest{UserId: "u1", Title: "Shared"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.LockTask(ctx, &pb.LockTaskRequest{Id: created.Task.Id, UserId: "u2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s.UpdateTaskNotes(ctx, &pb.UpdateTaskNotesRequest{Id: created.Task.Id, Notes: "Mine now"})
+	if reason, _ := errorInfo(err); status.Code(err) != codes.Aborted || reason != "TASK_LOCKED" {
+		t.Errorf("notes of a task locked by someone else: %v, want Aborted TASK_LOCKED", err)
+	}
+}
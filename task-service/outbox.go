@@ -0,0 +1,497 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/nats-io/nuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsonrw"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/technonext/todo-app/proto/mongoutil"
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// Task changes are published to the broker through an outbox. Each change
+// pushes a pending event onto the task in the same write that makes it, so a
+// change is never stored without its event. The relay then moves pending
+// events into the outbox collection, numbering them per task, and publishes
+// the outbox in order, marking entries sent once the broker has stored them.
+// A crash at any point leaves the event pending or unsent, and the relay
+// picks it up again, so delivery is at least once; consumers dedupe on the
+// event id, which is also the message id.
+
+// Pending events and outbox entries handled per relay pass
+const outboxBatchSize = 100
+
+// pendingEvent is an event waiting on its task to be moved to the outbox.
+type pendingEvent struct {
+	ID         string `bson:"id"`
+	Type       string `bson:"type"`
+	OccurredAt string `bson:"occurred_at"`
+	// The task as the change left it, protobuf-encoded. Deletions leave it
+	// empty, as trashed tasks no longer change, and the relay takes the task
+	// as it finds it
+	Task []byte `bson:"task,omitempty"`
+}
+
+// outboxEntry is an event in the outbox collection, keyed by its id.
+type outboxEntry struct {
+	ID string `bson:"_id"`
+	// The task's ObjectID hex, which unlike the public id in the event never
+	// changes
+	TaskID   string `bson:"task_id"`
+	Sequence int64  `bson:"sequence"`
+	// The TaskEvent, protobuf-encoded
+	Payload   []byte    `bson:"payload"`
+	CreatedAt time.Time `bson:"created_at"`
+	// Unset until the broker has stored the event. Sent entries are kept
+	// until expires_at so they can be replayed
+	SentAt    *time.Time `bson:"sent_at,omitempty"`
+	ExpiresAt *time.Time `bson:"expires_at,omitempty"`
+	// Replays counts the times the entry was queued again by ReplayTaskEvents,
+	// so replays are not dropped as duplicates by the broker
+	Replays int32 `bson:"replays,omitempty"`
+}
+
+// messageID is the broker message id of the entry.
+func (e outboxEntry) messageID() string {
+	if e.Replays == 0 {
+		return e.ID
+	}
+	return fmt.Sprintf("%s-replay-%d", e.ID, e.Replays)
+}
+
+// taskEventBroker stores published events; it is satisfied by JetStream.
+type taskEventBroker interface {
+	Publish(ctx context.Context, subject, msgID string, data []byte) error
+}
+
+type outbox struct {
+	collection *mongo.Collection
+	broker     taskEventBroker
+	// Events are published on subject.<change>, as in tasks.events.created
+	subject   string
+	interval  time.Duration
+	retention time.Duration
+}
+
+type outboxConfig struct {
+	url       string
+	stream    string
+	subject   string
+	interval  time.Duration
+	retention time.Duration
+}
+
+// loadOutboxConfig reads the outbox settings, returning nil when
+// EVENT_BROKER is unset.
+func loadOutboxConfig() (*outboxConfig, error) {
+	switch broker := os.Getenv("EVENT_BROKER"); broker {
+	case "":
+		return nil, nil
+	case "nats":
+	default:
+		return nil, fmt.Errorf("unsupported EVENT_BROKER %q: only nats is supported", broker)
+	}
+
+	interval, err := time.ParseDuration(getEnv("TASK_OUTBOX_INTERVAL", "1s"))
+	if err != nil || interval <= 0 {
+		return nil, fmt.Errorf("invalid TASK_OUTBOX_INTERVAL %q", os.Getenv("TASK_OUTBOX_INTERVAL"))
+	}
+	retention, err := time.ParseDuration(getEnv("TASK_OUTBOX_RETENTION", "168h"))
+	if err != nil || retention <= 0 {
+		return nil, fmt.Errorf("invalid TASK_OUTBOX_RETENTION %q", os.Getenv("TASK_OUTBOX_RETENTION"))
+	}
+	cfg := &outboxConfig{
+		url:       getEnv("NATS_URL", nats.DefaultURL),
+		stream:    getEnv("TASK_EVENT_STREAM", "TASK_EVENTS"),
+		subject:   getEnv("TASK_EVENT_SUBJECT", "tasks.events"),
+		interval:  interval,
+		retention: retention,
+	}
+	if cfg.subject == getEnv("EVENT_SUBJECT", "analytics.events") {
+		return nil, fmt.Errorf("TASK_EVENT_SUBJECT must differ from EVENT_SUBJECT")
+	}
+	return cfg, nil
+}
+
+// jetStreamBroker publishes to a JetStream stream it creates on first use.
+// The connection is retried in the background, so a broker that is not up
+// yet only holds events back in the outbox.
+type jetStreamBroker struct {
+	js      jetstream.JetStream
+	stream  string
+	subject string
+	ready   bool
+}
+
+func newJetStreamBroker(cfg *outboxConfig) (*jetStreamBroker, error) {
+	nc, err := nats.Connect(cfg.url,
+		nats.RetryOnFailedConnect(true),
+		nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, err
+	}
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return nil, err
+	}
+	return &jetStreamBroker{js: js, stream: cfg.stream, subject: cfg.subject}, nil
+}
+
+// newTaskOutbox indexes the outbox and the pending events of tasks, and
+// connects to the broker.
+func newTaskOutbox(ctx context.Context, collection, tasks *mongo.Collection, cfg *outboxConfig) (*outbox, error) {
+	// Serves the relay's search for tasks with pending events
+	err := mongoutil.EnsureIndexes(ctx, tasks, mongo.IndexModel{
+		Keys:    bson.D{{Key: "pending_events.id", Value: 1}},
+		Options: options.Index().SetSparse(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+	// The first serves publishing unsent entries in order; expired sent
+	// entries are removed by MongoDB
+	err = mongoutil.EnsureIndexes(ctx, collection,
+		mongo.IndexModel{Keys: bson.D{{Key: "sent_at", Value: 1}, {Key: "task_id", Value: 1}, {Key: "sequence", Value: 1}}},
+		mongo.IndexModel{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	broker, err := newJetStreamBroker(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &outbox{
+		collection: collection,
+		broker:     broker,
+		subject:    cfg.subject,
+		interval:   cfg.interval,
+		retention:  cfg.retention,
+	}, nil
+}
+
+// Publish waits for the stream to store the message. The relay publishes
+// from one goroutine, so ready needs no lock.
+func (b *jetStreamBroker) Publish(ctx context.Context, subject, msgID string, data []byte) error {
+	if !b.ready {
+		_, err := b.js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+			Name:     b.stream,
+			Subjects: []string{b.subject + ".>"},
+		})
+		if err != nil {
+			return err
+		}
+		b.ready = true
+	}
+
+	msg := nats.NewMsg(subject)
+	msg.Data = data
+	msg.Header.Set(jetstream.MsgIDHeader, msgID)
+	_, err := b.js.PublishMsg(ctx, msg)
+	return err
+}
+
+// newPendingEvent returns a pending eventType event for the task as the
+// change leaves it, or without the task when snapshot is nil.
+func newPendingEvent(eventType string, snapshot *Task, now string) (pendingEvent, error) {
+	event := pendingEvent{ID: nuid.Next(), Type: eventType, OccurredAt: now}
+	if snapshot != nil {
+		data, err := proto.Marshal(snapshot.toProto())
+		if err != nil {
+			return event, err
+		}
+		event.Task = data
+	}
+	return event, nil
+}
+
+// stageEvent adds pushing an eventType event onto the task to update,
+// numbering it with event_seq. It does nothing when there is no outbox.
+func (s *server) stageEvent(update bson.M, eventType string, snapshot *Task, now string) error {
+	return s.outbox.stage(update, eventType, snapshot, now)
+}
+
+// stage is stageEvent for a nil or configured outbox.
+func (o *outbox) stage(update bson.M, eventType string, snapshot *Task, now string) error {
+	if o == nil {
+		return nil
+	}
+	event, err := newPendingEvent(eventType, snapshot, now)
+	if err != nil {
+		return err
+	}
+
+	push, _ := update["$push"].(bson.M)
+	if push == nil {
+		push = bson.M{}
+		update["$push"] = push
+	}
+	push["pending_events"] = event
+
+	inc, _ := update["$inc"].(bson.M)
+	if inc == nil {
+		inc = bson.M{}
+		update["$inc"] = inc
+	}
+	inc["event_seq"] = 1
+	return nil
+}
+
+// projectUpdate returns the task as update leaves it. Fields moved with $inc
+// count from the values read, so they can lag behind concurrent updates.
+func projectUpdate(task Task, update bson.M) (Task, error) {
+	if set, ok := update["$set"].(bson.M); ok {
+		// Decoding into the task keeps the fields the update does not set
+		data, err := bson.Marshal(set)
+		if err != nil {
+			return task, err
+		}
+		// Dates are set as dates, read back as the strings tasks hold
+		decoder, err := bson.NewDecoder(bsonrw.NewBSONDocumentReader(data))
+		if err != nil {
+			return task, err
+		}
+		if err := decoder.SetRegistry(mongoutil.DatesAsStrings().Registry); err != nil {
+			return task, err
+		}
+		if err := decoder.Decode(&task); err != nil {
+			return task, err
+		}
+	}
+	if unset, ok := update["$unset"].(bson.M); ok {
+		if _, ok := unset["completed_at"]; ok {
+			task.CompletedAt = ""
+		}
+	}
+	if push, ok := update["$push"].(bson.M); ok {
+		if change, ok := push["status_history"].(StatusChange); ok {
+			task.StatusHistory = append(task.StatusHistory, change)
+		}
+		if extension, ok := push["due_date_extensions"].(DueDateExtension); ok {
+			task.DueDateExtensions = append(task.DueDateExtensions, extension)
+		}
+	}
+	if inc, ok := update["$inc"].(bson.M); ok {
+		if minutes, ok := inc["time_spent_minutes"].(int32); ok {
+			task.TimeSpentMinutes += minutes
+		}
+		if count, ok := inc["extension_count"].(int32); ok {
+			task.ExtensionCount += count
+		}
+	}
+	return task, nil
+}
+
+// firstPendingSequence is the sequence of the oldest pending event of the
+// task. Every pending event is pushed with an increment of event_seq and the
+// relay removes them oldest first, so the pending ones are the last numbered.
+func firstPendingSequence(task Task) int64 {
+	return task.EventSeq - int64(len(task.PendingEvents)) + 1
+}
+
+// eventSubject is the subject the event is published on.
+func (o *outbox) eventSubject(eventType string) string {
+	return o.subject + "." + strings.TrimPrefix(eventType, "task.")
+}
+
+// startOutboxRelay publishes task events every interval until ctx ends.
+func (s *server) startOutboxRelay(ctx context.Context) {
+	ticker := time.NewTicker(s.outbox.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.relayOutbox(ctx); err != nil {
+			log.Printf("Failed to relay task events: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// relayOutbox moves pending events to the outbox and publishes what is
+// unsent, a batch at a time until both are empty or the broker fails.
+func (s *server) relayOutbox(ctx context.Context) error {
+	for {
+		moved, err := s.drainPendingEvents(ctx)
+		if err != nil {
+			return err
+		}
+		published, err := s.publishOutbox(ctx)
+		if err != nil {
+			return err
+		}
+		if moved < outboxBatchSize && published < outboxBatchSize {
+			return nil
+		}
+	}
+}
+
+// drainPendingEvents moves the pending events of up to a batch of tasks to
+// the outbox, returning how many tasks it drained. Entries are inserted
+// before their events are removed from the task, so a crash in between only
+// has them inserted again, which the unique ids turn into no-ops.
+func (s *server) drainPendingEvents(ctx context.Context) (int, error) {
+	cursor, err := s.collection.Find(ctx,
+		bson.M{"pending_events.id": bson.M{"$exists": true}},
+		options.Find().SetLimit(outboxBatchSize))
+	if err != nil {
+		return 0, err
+	}
+	var tasks []Task
+	if err := cursor.All(ctx, &tasks); err != nil {
+		return 0, err
+	}
+
+	for _, task := range tasks {
+		first := firstPendingSequence(task)
+		ids := make([]string, 0, len(task.PendingEvents))
+		for i, pending := range task.PendingEvents {
+			entry, err := newOutboxEntry(task, pending, first+int64(i))
+			if err != nil {
+				return 0, err
+			}
+			_, err = s.outbox.collection.InsertOne(ctx, entry)
+			if err != nil && !mongo.IsDuplicateKeyError(err) {
+				return 0, err
+			}
+			ids = append(ids, pending.ID)
+		}
+		_, err := s.collection.UpdateOne(ctx,
+			bson.M{"_id": task.ID},
+			bson.M{"$pull": bson.M{"pending_events": bson.M{"id": bson.M{"$in": ids}}}})
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(tasks), nil
+}
+
+// newOutboxEntry is the outbox entry for a pending event of the task.
+func newOutboxEntry(task Task, pending pendingEvent, sequence int64) (outboxEntry, error) {
+	event := &pb.TaskEvent{
+		EventId:    pending.ID,
+		Type:       pending.Type,
+		TaskId:     task.publicID(),
+		UserId:     task.UserID,
+		Sequence:   sequence,
+		OccurredAt: pending.OccurredAt,
+		Task:       &pb.Task{},
+	}
+	if pending.Task == nil {
+		event.Task = task.toProto()
+	} else if err := proto.Unmarshal(pending.Task, event.Task); err != nil {
+		return outboxEntry{}, err
+	}
+
+	payload, err := proto.Marshal(event)
+	if err != nil {
+		return outboxEntry{}, err
+	}
+	return outboxEntry{
+		ID:        pending.ID,
+		TaskID:    task.ID.Hex(),
+		Sequence:  sequence,
+		Payload:   payload,
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+// publishOutbox publishes up to a batch of unsent entries, returning how many
+// it read. Entries go out in sequence order per task; once one of a task's
+// entries fails, the rest of that task's wait for the next pass.
+func (s *server) publishOutbox(ctx context.Context) (int, error) {
+	cursor, err := s.outbox.collection.Find(ctx,
+		bson.M{"sent_at": nil},
+		options.Find().
+			SetSort(bson.D{{Key: "task_id", Value: 1}, {Key: "sequence", Value: 1}}).
+			SetLimit(outboxBatchSize))
+	if err != nil {
+		return 0, err
+	}
+	var entries []outboxEntry
+	if err := cursor.All(ctx, &entries); err != nil {
+		return 0, err
+	}
+
+	blocked := make(map[string]bool)
+	var failure error
+	for _, entry := range entries {
+		if blocked[entry.TaskID] {
+			continue
+		}
+		var event pb.TaskEvent
+		if err := proto.Unmarshal(entry.Payload, &event); err != nil {
+			return 0, err
+		}
+		if err := s.outbox.broker.Publish(ctx, s.outbox.eventSubject(event.Type), entry.messageID(), entry.Payload); err != nil {
+			blocked[entry.TaskID] = true
+			failure = err
+			continue
+		}
+
+		now := time.Now()
+		expires := now.Add(s.outbox.retention)
+		_, err := s.outbox.collection.UpdateOne(ctx,
+			bson.M{"_id": entry.ID},
+			bson.M{"$set": bson.M{"sent_at": now, "expires_at": expires}})
+		if err != nil {
+			return 0, err
+		}
+	}
+	if failure != nil {
+		return len(entries), fmt.Errorf("publishing task events: %w", failure)
+	}
+	return len(entries), nil
+}
+
+// ReplayTaskEvents marks sent outbox entries unsent again, so the relay
+// publishes them once more in order. since is matched against the time
+// entries entered the outbox, moments after their events occurred.
+func (s *server) ReplayTaskEvents(ctx context.Context, req *pb.ReplayTaskEventsRequest) (*pb.ReplayTaskEventsResponse, error) {
+	if s.outbox == nil {
+		return nil, statusError(codes.FailedPrecondition, "OUTBOX_DISABLED", nil, "task events are not published: set EVENT_BROKER")
+	}
+
+	filter := bson.M{"sent_at": bson.M{"$ne": nil}}
+	if req.TaskId != "" {
+		oid, err := s.taskObjectID(ctx, req.TaskId)
+		if err != nil {
+			return nil, err
+		}
+		filter["task_id"] = oid.Hex()
+	}
+	if req.Since != "" {
+		since, err := time.Parse(time.RFC3339, req.Since)
+		if err != nil {
+			return nil, statusError(codes.InvalidArgument, "INVALID_SINCE", map[string]string{"since": req.Since}, "invalid since %q: use RFC3339", req.Since)
+		}
+		filter["created_at"] = bson.M{"$gte": since}
+	}
+
+	result, err := s.outbox.collection.UpdateMany(ctx, filter, bson.M{
+		"$unset": bson.M{"sent_at": "", "expires_at": ""},
+		"$inc":   bson.M{"replays": 1},
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Queued %d task events for replay", result.ModifiedCount)
+	return &pb.ReplayTaskEventsResponse{Replayed: int32(result.ModifiedCount)}, nil
+}
@@ -0,0 +1,229 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// newOutboxTestServer returns a test server publishing task events to broker.
+func newOutboxTestServer(t *testing.T, broker taskEventBroker) *server {
+	t.Helper()
+	s := newTestServer(t, nil)
+	s.outbox = &outbox{
+		collection: s.collection.Database().Collection("task_outbox"),
+		broker:     broker,
+		subject:    "tasks.events",
+		retention:  time.Hour,
+	}
+	return s
+}
+
+func TestOutboxRecoversEventsAfterCrash(t *testing.T) {
+	broker := &fakeBroker{}
+	s := newOutboxTestServer(t, broker)
+	ctx := context.Background()
+
+	// The service stops after writing the changes, before the relay runs
+	created, err := s.CreateTask(ctx, &pb.CreateTaskRequest{UserId: "u1", Title: "Write report"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := created.Task.Id
+	if _, err := s.UpdateTaskStatus(ctx, &pb.UpdateTaskStatusRequest{Id: id, Status: pb.TaskStatus_TASK_STATUS_IN_PROGRESS}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Then stops again after copying the events to the outbox, before
+	// removing them from the task
+	oid, _ := primitive.ObjectIDFromHex(id)
+	var task Task
+	if err := s.collection.FindOne(ctx, bson.M{"_id": oid}).Decode(&task); err != nil {
+		t.Fatal(err)
+	}
+	entry, err := newOutboxEntry(task, task.PendingEvents[0], firstPendingSequence(task))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.outbox.collection.InsertOne(ctx, entry); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new instance over the same data publishes every event once
+	restarted := &server{collection: s.collection, outbox: s.outbox}
+	if err := restarted.relayOutbox(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	published := broker.events()
+	if len(published) != 2 {
+		t.Fatalf("published %d events, want 2", len(published))
+	}
+	wantTypes := []string{"task.created", "task.status_changed"}
+	for i, p := range published {
+		if p.event.Type != wantTypes[i] || p.event.Sequence != int64(i+1) || p.event.TaskId != id || p.event.UserId != "u1" {
+			t.Errorf("event %d = %s #%d of task %s", i, p.event.Type, p.event.Sequence, p.event.TaskId)
+		}
+		if p.msgID != p.event.EventId {
+			t.Errorf("event %d message id %q, want the event id %q", i, p.msgID, p.event.EventId)
+		}
+	}
+	if got := published[1].event.Task.Status; got != pb.TaskStatus_TASK_STATUS_IN_PROGRESS {
+		t.Errorf("status change event has the task %v", got)
+	}
+
+	if err := s.collection.FindOne(ctx, bson.M{"_id": oid}).Decode(&task); err != nil {
+		t.Fatal(err)
+	}
+	if len(task.PendingEvents) != 0 {
+		t.Errorf("%d events still pending", len(task.PendingEvents))
+	}
+	unsent, err := s.outbox.collection.CountDocuments(ctx, bson.M{"sent_at": nil})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unsent != 0 {
+		t.Errorf("%d entries unsent", unsent)
+	}
+
+	// Publishing again finds nothing left to send
+	if err := restarted.relayOutbox(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(broker.events()); got != 2 {
+		t.Errorf("published %d events after another pass, want 2", got)
+	}
+}
+
+func TestOutboxKeepsTaskOrder(t *testing.T) {
+	var mu sync.Mutex
+	down := true
+	broker := &fakeBroker{fail: func(event *pb.TaskEvent) error {
+		mu.Lock()
+		defer mu.Unlock()
+		// The broker goes down partway through the first task's events
+		if down && event.Sequence == 3 {
+			return errors.New("broker unavailable")
+		}
+		return nil
+	}}
+	s := newOutboxTestServer(t, broker)
+	ctx := context.Background()
+
+	var ids []string
+	for _, title := range []string{"First", "Second"} {
+		created, err := s.CreateTask(ctx, &pb.CreateTaskRequest{UserId: "u1", Title: title})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, created.Task.Id)
+	}
+
+	// Concurrent updates each get their own number
+	const updates = 5
+	var wg sync.WaitGroup
+	for i := 0; i < updates; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := s.UpdateTask(ctx, &pb.UpdateTaskRequest{Id: ids[0], Title: "First", AddTimeSpentMinutes: 5}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if _, err := s.DeleteTask(ctx, &pb.DeleteTaskRequest{Id: ids[0]}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.UpdateTask(ctx, &pb.UpdateTaskRequest{Id: ids[1], Title: "Second, renamed"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.relayOutbox(ctx); err == nil {
+		t.Fatal("relay succeeded with the broker down")
+	}
+	// The failure holds back the rest of its task, but not the other task
+	byTask := make(map[string][]int64)
+	for _, p := range broker.events() {
+		byTask[p.event.TaskId] = append(byTask[p.event.TaskId], p.event.Sequence)
+	}
+	if got := byTask[ids[0]]; len(got) != 2 {
+		t.Errorf("published %v of the first task with the broker down, want [1 2]", got)
+	}
+	if got := byTask[ids[1]]; len(got) != 2 {
+		t.Errorf("published %v of the second task, want [1 2]", got)
+	}
+
+	mu.Lock()
+	down = false
+	mu.Unlock()
+	if err := s.relayOutbox(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var seqs []int64
+	var last *pb.TaskEvent
+	for _, p := range broker.events() {
+		if p.event.TaskId == ids[0] {
+			seqs = append(seqs, p.event.Sequence)
+			last = p.event
+		}
+	}
+	if len(seqs) != updates+2 {
+		t.Fatalf("published %d events of the first task, want %d", len(seqs), updates+2)
+	}
+	for i, seq := range seqs {
+		if seq != int64(i+1) {
+			t.Fatalf("first task events published as %v, want 1 to %d in order", seqs, updates+2)
+		}
+	}
+	if last.Type != "task.deleted" || last.Task.TimeSpentMinutes != updates*5 {
+		t.Errorf("last event = %s with %d minutes spent", last.Type, last.Task.TimeSpentMinutes)
+	}
+}
+
+func TestReplayTaskEvents(t *testing.T) {
+	broker := &fakeBroker{}
+	s := newOutboxTestServer(t, broker)
+	ctx := context.Background()
+
+	created, err := s.CreateTask(ctx, &pb.CreateTaskRequest{UserId: "u1", Title: "Write report"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CreateTask(ctx, &pb.CreateTaskRequest{UserId: "u1", Title: "Other"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.relayOutbox(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := s.ReplayTaskEvents(ctx, &pb.ReplayTaskEventsRequest{TaskId: created.Task.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Replayed != 1 {
+		t.Fatalf("replayed %d events, want 1", resp.Replayed)
+	}
+	if err := s.relayOutbox(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	published := broker.events()
+	if len(published) != 3 {
+		t.Fatalf("published %d events, want 3", len(published))
+	}
+	replay := published[2]
+	if replay.event.TaskId != created.Task.Id || replay.msgID != replay.event.EventId+"-replay-1" {
+		t.Errorf("replayed event %s of task %s as message %s", replay.event.EventId, replay.event.TaskId, replay.msgID)
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+func TestProjectUpdate(t *testing.T) {
+	current := Task{
+		Title:            "Old title",
+		Description:      "Kept",
+		UserID:           "u1",
+		Status:           pb.TaskStatus_TASK_STATUS_COMPLETED.String(),
+		Completed:        true,
+		CompletedAt:      "2026-03-01T10:00:00Z",
+		TimeSpentMinutes: 30,
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"title":     "New title",
+			"status":    pb.TaskStatus_TASK_STATUS_NOT_STARTED.String(),
+			"completed": false,
+		},
+		"$unset": bson.M{"completed_at": ""},
+		"$push": bson.M{"status_history": StatusChange{
+			From: pb.TaskStatus_TASK_STATUS_COMPLETED.String(),
+			To:   pb.TaskStatus_TASK_STATUS_NOT_STARTED.String(),
+		}},
+		"$inc": bson.M{"edit_count": 1, "time_spent_minutes": int32(15)},
+	}
+
+	got, err := projectUpdate(current, update)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Title != "New title" || got.Description != "Kept" || got.UserID != "u1" {
+		t.Errorf("title %q, description %q, user %q", got.Title, got.Description, got.UserID)
+	}
+	if got.status() != pb.TaskStatus_TASK_STATUS_NOT_STARTED || got.Completed || got.CompletedAt != "" {
+		t.Errorf("status %s, completed %v at %q", got.Status, got.Completed, got.CompletedAt)
+	}
+	if len(got.StatusHistory) != 1 || got.TimeSpentMinutes != 45 {
+		t.Errorf("%d status changes, %d minutes spent", len(got.StatusHistory), got.TimeSpentMinutes)
+	}
+	if current.Title != "Old title" {
+		t.Errorf("current task changed to %q", current.Title)
+	}
+}
+
+func TestProjectUpdateDueDate(t *testing.T) {
+	update := bson.M{"$set": bson.M{"due_date": taskDate("2026-03-04T12:00:00Z")}}
+	got, err := projectUpdate(Task{DueDate: "2026-03-01T12:00:00Z"}, update)
+	if err != nil {
+		t.Fatal(err)
+	}
+	due, err := time.Parse(time.RFC3339, got.DueDate)
+	if err != nil || !due.Equal(time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("due date = %q, want 2026-03-04T12:00:00Z", got.DueDate)
+	}
+}
+
+func TestStageEvent(t *testing.T) {
+	update := bson.M{
+		"$set":  bson.M{"updated_at": "now"},
+		"$push": bson.M{"status_history": StatusChange{}},
+		"$inc":  bson.M{"edit_count": 1},
+	}
+	if err := (&server{}).stageEvent(update, "task.updated", nil, "now"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := update["$push"].(bson.M)["pending_events"]; ok {
+		t.Error("staged an event without an outbox")
+	}
+
+	s := &server{outbox: &outbox{}}
+	if err := s.stageEvent(update, "task.updated", &Task{Title: "Write report"}, "now"); err != nil {
+		t.Fatal(err)
+	}
+	push, inc := update["$push"].(bson.M), update["$inc"].(bson.M)
+	event, ok := push["pending_events"].(pendingEvent)
+	if !ok || event.ID == "" || event.Type != "task.updated" || len(event.Task) == 0 {
+		t.Errorf("pending event = %+v", push["pending_events"])
+	}
+	if _, ok := push["status_history"]; !ok || inc["edit_count"] != 1 || inc["event_seq"] != 1 {
+		t.Errorf("update = %v", update)
+	}
+}
+
+func TestFirstPendingSequence(t *testing.T) {
+	tests := []struct {
+		name     string
+		eventSeq int64
+		pending  int
+		want     int64
+	}{
+		{"created", 1, 1, 1},
+		{"all pending", 3, 3, 1},
+		{"older events sent", 5, 2, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := Task{EventSeq: tt.eventSeq, PendingEvents: make([]pendingEvent, tt.pending)}
+			if got := firstPendingSequence(task); got != tt.want {
+				t.Errorf("firstPendingSequence = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOutboxNames(t *testing.T) {
+	o := &outbox{subject: "tasks.events"}
+	if got := o.eventSubject("task.status_changed"); got != "tasks.events.status_changed" {
+		t.Errorf("subject = %q", got)
+	}
+	if got := (outboxEntry{ID: "abc"}).messageID(); got != "abc" {
+		t.Errorf("message id = %q", got)
+	}
+	if got := (outboxEntry{ID: "abc", Replays: 2}).messageID(); got != "abc-replay-2" {
+		t.Errorf("replayed message id = %q", got)
+	}
+}
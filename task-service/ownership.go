@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/technonext/todo-app/proto/logging"
+	pb "github.com/technonext/todo-app/proto/proto"
+	"github.com/technonext/todo-app/proto/publicid"
+	"github.com/technonext/todo-app/proto/tenant"
+)
+
+// MigrateTaskOwnership moves every task of one user to another with a single
+// update, for merging two accounts: the trash moves too, and each task
+// remembers its previous owner in migrated_from. Like BulkUpdateTasks it
+// records no task.updated events, only one task.ownership_migrated event for
+// the whole move, from which analytics moves the user's counters. The previous
+// owner gets a tombstone for each task, so their SyncTasks drops them. The
+// gateway only lets admins call it.
+func (s *server) MigrateTaskOwnership(ctx context.Context, req *pb.MigrateOwnershipRequest) (*pb.MigrateOwnershipResponse, error) {
+	if req.FromUserId == "" || req.ToUserId == "" {
+		return nil, statusError(codes.InvalidArgument, "USER_ID_REQUIRED", nil, "from_user_id and to_user_id are required")
+	}
+	if req.FromUserId == req.ToUserId {
+		return nil, statusError(codes.InvalidArgument, "SAME_USER", map[string]string{"user_id": req.ToUserId}, "cannot migrate the tasks of %s to themselves", req.ToUserId)
+	}
+	_, err := s.users.GetUser(ctx, &pb.GetUserRequest{Id: req.ToUserId})
+	if status.Code(err) == codes.NotFound {
+		return nil, statusError(codes.NotFound, "USER_NOT_FOUND", map[string]string{"user_id": req.ToUserId}, "user %s not found", req.ToUserId)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The tasks are looked up first and only those are moved, so each task
+	// moved gets a tombstone, even one created while the move runs
+	cursor, err := s.collection.Find(ctx, bson.M{"user_id": req.FromUserId},
+		options.Find().SetProjection(bson.M{"_id": 1, publicid.Field: 1, "tenant_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	var tasks []Task
+	if err := cursor.All(ctx, &tasks); err != nil {
+		return nil, err
+	}
+	ids := make([]primitive.ObjectID, len(tasks))
+	for i, task := range tasks {
+		ids[i] = task.ID
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	result, err := s.collection.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": ids}, "user_id": req.FromUserId}, bson.M{"$set": bson.M{
+		"user_id":       req.ToUserId,
+		"migrated_from": req.FromUserId,
+		"updated_at":    now,
+	}})
+	if err != nil {
+		return nil, err
+	}
+	logger := logging.FromContext(ctx)
+	logger.Info("Migrated tasks", "count", result.ModifiedCount, "from_user_id", req.FromUserId, "to_user_id", req.ToUserId)
+
+	if len(tasks) > 0 {
+		tombstones := make([]interface{}, len(tasks))
+		for i, task := range tasks {
+			tombstones[i] = DeletedTask{TaskID: task.publicID(), UserID: req.FromUserId, DeletedAt: now, TenantID: task.TenantID}
+		}
+		// Like recordDeletion, a lost tombstone only costs the previous
+		// owner's clients a stale copy, so it does not fail the move
+		if _, err := s.deletedCollection.InsertMany(ctx, tombstones); err != nil {
+			logger.Error("Failed to record migrated tasks for their previous owner", "from_user_id", req.FromUserId, "error", err)
+		}
+	}
+
+	tenantID, _ := tenant.Scope(ctx)
+	go s.trackEvent(tenantID, &pb.TrackEventRequest{
+		UserId:     req.ToUserId,
+		EventType:  "task.ownership_migrated",
+		ResourceId: req.FromUserId,
+		Metadata: &structpb.Struct{Fields: map[string]*structpb.Value{
+			"from":  structpb.NewStringValue(req.FromUserId),
+			"to":    structpb.NewStringValue(req.ToUserId),
+			"count": structpb.NewNumberValue(float64(result.ModifiedCount)),
+		}},
+	})
+	return &pb.MigrateOwnershipResponse{MigratedCount: result.ModifiedCount}, nil
+}
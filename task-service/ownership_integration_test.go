@@ -0,0 +1,104 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+func TestMigrateTaskOwnership(t *testing.T) {
+	s := newTestServer(t, nil)
+	s.users = &knownUserClient{ids: map[string]bool{"primary": true}}
+	analytics := &fakeAnalyticsClient{tracked: make(chan *pb.TrackEventRequest, 128)}
+	s.analytics = analytics
+	ctx := context.Background()
+
+	for i := 0; i < 50; i++ {
+		created, err := s.CreateTask(ctx, &pb.CreateTaskRequest{UserId: "secondary", Title: fmt.Sprintf("Task %d", i)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Tasks in the trash move too
+		if i%10 == 0 {
+			if _, err := s.DeleteTask(ctx, &pb.DeleteTaskRequest{Id: created.Task.Id}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if _, err := s.CreateTask(ctx, &pb.CreateTaskRequest{UserId: "bystander", Title: "Stays put"}); err != nil {
+		t.Fatal(err)
+	}
+
+	before, err := s.SyncTasks(ctx, &pb.SyncTasksRequest{UserId: "secondary"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := s.MigrateTaskOwnership(ctx, &pb.MigrateOwnershipRequest{FromUserId: "secondary", ToUserId: "primary"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.MigratedCount != 50 {
+		t.Errorf("migrated %d tasks, want 50", resp.MigratedCount)
+	}
+
+	var tasks []Task
+	cursor, err := s.collection.Find(ctx, map[string]interface{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cursor.All(ctx, &tasks); err != nil {
+		t.Fatal(err)
+	}
+	moved := 0
+	for _, task := range tasks {
+		switch task.UserID {
+		case "primary":
+			moved++
+			if task.MigratedFrom != "secondary" {
+				t.Errorf("task %s migrated_from = %q, want secondary", task.Title, task.MigratedFrom)
+			}
+		case "secondary":
+			t.Errorf("task %s was left with the secondary account", task.Title)
+		case "bystander":
+			if task.MigratedFrom != "" {
+				t.Errorf("another user's task has migrated_from %q", task.MigratedFrom)
+			}
+		}
+	}
+	if moved != 50 {
+		t.Errorf("primary owns %d tasks, want 50", moved)
+	}
+
+	// The secondary account's clients learn the tasks are no longer theirs
+	synced, err := s.SyncTasks(ctx, &pb.SyncTasksRequest{UserId: "secondary", Since: before.ServerTime})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(synced.DeletedIds) != 50 {
+		t.Errorf("secondary sync reports %d deleted tasks, want 50", len(synced.DeletedIds))
+	}
+
+	// One event for the whole move, among those of the tasks' creation
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case event := <-analytics.tracked:
+			if event.EventType != "task.ownership_migrated" {
+				continue
+			}
+			fields := event.Metadata.GetFields()
+			if fields["from"].GetStringValue() != "secondary" || fields["to"].GetStringValue() != "primary" || fields["count"].GetNumberValue() != 50 {
+				t.Errorf("event metadata = %v, want from secondary to primary, count 50", fields)
+			}
+			return
+		case <-timeout:
+			t.Fatal("no task.ownership_migrated event tracked")
+		}
+	}
+}
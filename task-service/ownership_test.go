@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// knownUserClient knows the users in ids, none of them frozen.
+type knownUserClient struct {
+	pb.UserServiceClient
+	ids map[string]bool
+}
+
+func (c *knownUserClient) GetUser(ctx context.Context, req *pb.GetUserRequest, opts ...grpc.CallOption) (*pb.UserResponse, error) {
+	if !c.ids[req.Id] {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+	return &pb.UserResponse{User: &pb.User{Id: req.Id}}, nil
+}
+
+func TestMigrateTaskOwnershipValidation(t *testing.T) {
+	s := &server{users: &knownUserClient{ids: map[string]bool{"u1": true}}}
+	tests := []struct {
+		name   string
+		req    *pb.MigrateOwnershipRequest
+		code   codes.Code
+		reason string
+	}{
+		{"no from", &pb.MigrateOwnershipRequest{ToUserId: "u1"}, codes.InvalidArgument, "USER_ID_REQUIRED"},
+		{"no to", &pb.MigrateOwnershipRequest{FromUserId: "u2"}, codes.InvalidArgument, "USER_ID_REQUIRED"},
+		{"same user", &pb.MigrateOwnershipRequest{FromUserId: "u1", ToUserId: "u1"}, codes.InvalidArgument, "SAME_USER"},
+		{"unknown to", &pb.MigrateOwnershipRequest{FromUserId: "u1", ToUserId: "u9"}, codes.NotFound, "USER_NOT_FOUND"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.MigrateTaskOwnership(context.Background(), tt.req)
+			if reason, _ := errorInfo(err); status.Code(err) != tt.code || reason != tt.reason {
+				t.Errorf("MigrateTaskOwnership() = %v, want %v %s", err, tt.code, tt.reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"google.golang.org/grpc/codes"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// GetPendingTaskCount buckets a user's incomplete tasks by due date in a
+// single $facet aggregation for the dashboard widgets.
+func (s *server) GetPendingTaskCount(ctx context.Context, req *pb.GetPendingTaskCountRequest) (*pb.GetPendingTaskCountResponse, error) {
+	if req.UserId == "" {
+		return nil, statusError(codes.InvalidArgument, "USER_ID_REQUIRED", nil, "user_id is required")
+	}
+
+	now := time.Now().UTC()
+	today := now.Truncate(24 * time.Hour)
+	tomorrow := today.AddDate(0, 0, 1)
+	weekEnd := today.AddDate(0, 0, 7)
+
+	count := func(filter bson.M) bson.A {
+		return bson.A{
+			bson.D{{Key: "$match", Value: filter}},
+			bson.D{{Key: "$count", Value: "n"}},
+		}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"user_id": req.UserId, "completed": false, "is_deleted": notDeleted}}},
+		// due_date is a date or an RFC3339 string; anything unparseable has no due date
+		{{Key: "$addFields", Value: bson.M{"due": toDate("$due_date")}}},
+		{{Key: "$facet", Value: bson.M{
+			"due_today":     count(bson.M{"due": bson.M{"$gte": today, "$lt": tomorrow}}),
+			"due_this_week": count(bson.M{"due": bson.M{"$gte": today, "$lt": weekEnd}}),
+			"overdue":       count(bson.M{"due": bson.M{"$lt": now}}),
+			"no_due_date":   count(bson.M{"due": nil}),
+		}}},
+	}
+
+	cursor, err := s.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	type bucket []struct {
+		N int32 `bson:"n"`
+	}
+	var result struct {
+		DueToday    bucket `bson:"due_today"`
+		DueThisWeek bucket `bson:"due_this_week"`
+		Overdue     bucket `bson:"overdue"`
+		NoDueDate   bucket `bson:"no_due_date"`
+	}
+	if cursor.Next(ctx) {
+		if err := cursor.Decode(&result); err != nil {
+			return nil, err
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	// $count emits nothing for an empty bucket
+	total := func(b bucket) int32 {
+		if len(b) == 0 {
+			return 0
+		}
+		return b[0].N
+	}
+
+	return &pb.GetPendingTaskCountResponse{
+		DueToday:    total(result.DueToday),
+		DueThisWeek: total(result.DueThisWeek),
+		Overdue:     total(result.Overdue),
+		NoDueDate:   total(result.NoDueDate),
+	}, nil
+}
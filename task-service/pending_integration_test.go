@@ -0,0 +1,93 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+func TestGetPendingTaskCountBoundaries(t *testing.T) {
+	s := newTestServer(t, nil)
+	ctx := context.Background()
+
+	// Buckets start at midnight UTC, so keep the test within one day
+	now := time.Now().UTC()
+	if left := now.Truncate(24 * time.Hour).Add(24 * time.Hour).Sub(now); left < 10*time.Second {
+		time.Sleep(left)
+	}
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+
+	// today and week are the buckets each due date falls in besides overdue,
+	// which depends on the time of day
+	east := time.FixedZone("UTC+6", 6*60*60)
+	dueDates := []struct {
+		due         time.Time
+		today, week bool
+	}{
+		{today.Add(-time.Second), false, false},
+		{today, true, true},
+		// Already tomorrow in the zone it is given in, but today in UTC
+		{today.Add(20 * time.Hour).In(east), true, true},
+		{today.AddDate(0, 0, 1), false, true},
+		{today.AddDate(0, 0, 7).Add(-time.Second), false, true},
+		{today.AddDate(0, 0, 7), false, false},
+	}
+	want := pb.GetPendingTaskCountResponse{NoDueDate: 1}
+	if _, err := s.CreateTask(ctx, &pb.CreateTaskRequest{UserId: "u1", Title: "Someday"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range dueDates {
+		due := d.due.Format(time.RFC3339)
+		if _, err := s.CreateTask(ctx, &pb.CreateTaskRequest{UserId: "u1", Title: "Due " + due, DueDate: due}); err != nil {
+			t.Fatal(err)
+		}
+		if d.today {
+			want.DueToday++
+		}
+		if d.week {
+			want.DueThisWeek++
+		}
+		if d.due.Before(time.Now()) {
+			want.Overdue++
+		}
+	}
+	// Due dates from before the dates migration are strings
+	_, err := s.collection.InsertOne(ctx, bson.M{"user_id": "u1", "title": "Unmigrated", "completed": false,
+		"due_date": today.Add(12 * time.Hour).Format(time.RFC3339)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want.DueToday++
+	want.DueThisWeek++
+	if today.Add(12 * time.Hour).Before(time.Now()) {
+		want.Overdue++
+	}
+
+	// Completed tasks and other users' tasks are not pending
+	done, err := s.CreateTask(ctx, &pb.CreateTaskRequest{UserId: "u1", Title: "Done", DueDate: today.Format(time.RFC3339)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.UpdateTask(ctx, &pb.UpdateTaskRequest{Id: done.Task.Id, Title: "Done", DueDate: done.Task.DueDate, Completed: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CreateTask(ctx, &pb.CreateTaskRequest{UserId: "u2", Title: "Not mine", DueDate: today.Format(time.RFC3339)}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.GetPendingTaskCount(ctx, &pb.GetPendingTaskCountRequest{UserId: "u1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.DueToday != want.DueToday || got.DueThisWeek != want.DueThisWeek || got.Overdue != want.Overdue || got.NoDueDate != want.NoDueDate {
+		t.Errorf("today %d, this week %d, overdue %d, no due date %d; want %d, %d, %d and %d",
+			got.DueToday, got.DueThisWeek, got.Overdue, got.NoDueDate,
+			want.DueToday, want.DueThisWeek, want.Overdue, want.NoDueDate)
+	}
+}
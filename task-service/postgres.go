@@ -0,0 +1,382 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/technonext/todo-app/proto/pagination"
+	"github.com/technonext/todo-app/proto/tenant"
+)
+
+// postgresMigrations build the schema of the tasks table, applied in order
+// at startup; version n is postgresMigrations[n-1]. Released ones are never
+// changed or reordered.
+//
+// Tasks keep their ObjectID ids, so ids look the same on either store.
+// created_at is a timestamptz so created ranges compare as times; the other
+// times are RFC3339 text, as they are in MongoDB.
+var postgresMigrations = []string{
+	`CREATE TABLE tasks (
+		id text PRIMARY KEY,
+		tenant_id text NOT NULL DEFAULT '',
+		user_id text NOT NULL,
+		title text NOT NULL,
+		description text NOT NULL DEFAULT '',
+		completed boolean NOT NULL DEFAULT false,
+		status text NOT NULL DEFAULT '',
+		status_history jsonb NOT NULL DEFAULT '[]',
+		priority text NOT NULL DEFAULT '',
+		labels text[] NOT NULL DEFAULT '{}',
+		due_date text NOT NULL DEFAULT '',
+		estimated_minutes integer NOT NULL DEFAULT 0,
+		time_spent_minutes integer NOT NULL DEFAULT 0,
+		deadline_alert_sent boolean NOT NULL DEFAULT false,
+		view_count bigint NOT NULL DEFAULT 0,
+		edit_count bigint NOT NULL DEFAULT 0,
+		last_viewed_at text NOT NULL DEFAULT '',
+		locked_by text NOT NULL DEFAULT '',
+		locked_until text NOT NULL DEFAULT '',
+		created_at timestamptz NOT NULL,
+		updated_at text NOT NULL DEFAULT '',
+		completed_at text NOT NULL DEFAULT '',
+		is_deleted boolean NOT NULL DEFAULT false,
+		deleted_at text NOT NULL DEFAULT ''
+	);
+	CREATE INDEX tasks_tenant_user ON tasks (tenant_id, user_id, created_at);
+	CREATE INDEX tasks_title_search ON tasks USING gin (to_tsvector('english', title));`,
+	// Rows from before public ids have none, and are known by id alone
+	`ALTER TABLE tasks ADD COLUMN public_id text CONSTRAINT tasks_public_id_key UNIQUE;`,
+}
+
+// postgresMigrationLock serializes migrations between replicas starting at
+// once; it is an arbitrary advisory lock key.
+const postgresMigrationLock = 7305117
+
+// openPostgres connects to the database at url.
+func openPostgres(ctx context.Context, url string) (*pgxpool.Pool, error) {
+	pool, err := pgxpool.New(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, err
+	}
+	return pool, nil
+}
+
+// migratePostgres applies the pending postgresMigrations in one
+// transaction, or with dryRun only lists them on out.
+func migratePostgres(ctx context.Context, pool *pgxpool.Pool, dryRun bool, out io.Writer) error {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, postgresMigrationLock); err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version integer PRIMARY KEY,
+		applied_at timestamptz NOT NULL DEFAULT now()
+	)`)
+	if err != nil {
+		return err
+	}
+	var applied int
+	if err := tx.QueryRow(ctx, `SELECT coalesce(max(version), 0) FROM schema_migrations`).Scan(&applied); err != nil {
+		return err
+	}
+
+	for version := applied + 1; version <= len(postgresMigrations); version++ {
+		if dryRun {
+			fmt.Fprintf(out, "Pending schema migration %d\n", version)
+			continue
+		}
+		if _, err := tx.Exec(ctx, postgresMigrations[version-1]); err != nil {
+			return fmt.Errorf("schema migration %d: %w", version, err)
+		}
+		if _, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version) VALUES ($1)`, version); err != nil {
+			return err
+		}
+		slog.Info("Applied schema migration", "version", version)
+	}
+	if dryRun {
+		return nil
+	}
+	return tx.Commit(ctx)
+}
+
+// postgresTasks stores tasks as rows of the tasks table. Search uses a
+// full-text index of titles with English stemming, as MongoDB's text index
+// does; SEARCH_PROVIDER does not apply. Changes stage no events, as there is
+// no outbox, but are still tracked in analytics.
+type postgresTasks struct {
+	pool *pgxpool.Pool
+}
+
+const taskColumns = `id, tenant_id, user_id, title, description, completed, status, status_history,
+	priority, labels, due_date, estimated_minutes, time_spent_minutes, deadline_alert_sent,
+	view_count, edit_count, last_viewed_at, locked_by, locked_until, created_at, updated_at,
+	completed_at, is_deleted, deleted_at, public_id`
+
+// scanTask reads a row of taskColumns.
+func scanTask(row pgx.Row) (Task, error) {
+	var task Task
+	var id string
+	var history []byte
+	var createdAt time.Time
+	var publicID *string
+	err := row.Scan(&id, &task.TenantID, &task.UserID, &task.Title, &task.Description, &task.Completed, &task.Status, &history,
+		&task.Priority, &task.Labels, &task.DueDate, &task.EstimatedMinutes, &task.TimeSpentMinutes, &task.DeadlineAlertSent,
+		&task.ViewCount, &task.EditCount, &task.LastViewedAt, &task.LockedBy, &task.LockedUntil, &createdAt, &task.UpdatedAt,
+		&task.CompletedAt, &task.IsDeleted, &task.DeletedAt, &publicID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return task, errNoTask
+	}
+	if err != nil {
+		return task, err
+	}
+	if task.ID, err = primitive.ObjectIDFromHex(id); err != nil {
+		return task, err
+	}
+	if err := json.Unmarshal(history, &task.StatusHistory); err != nil {
+		return task, err
+	}
+	if publicID != nil {
+		task.PublicID = *publicID
+	}
+	// Stored in the server's zone, as MongoDB's are
+	task.CreatedAt = createdAt.Local().Format(time.RFC3339)
+	return task, nil
+}
+
+// conditions are the WHERE clause of a query and its arguments.
+type conditions struct {
+	clauses []string
+	args    []interface{}
+}
+
+// scopedConditions starts the conditions of a query limited to ctx's tenant.
+func scopedConditions(ctx context.Context) *conditions {
+	c := &conditions{}
+	if id, ok := tenant.Scope(ctx); ok {
+		c.add("tenant_id = " + c.arg(id))
+	}
+	return c
+}
+
+// arg adds an argument, returning its placeholder.
+func (c *conditions) arg(value interface{}) string {
+	c.args = append(c.args, value)
+	return fmt.Sprintf("$%d", len(c.args))
+}
+
+func (c *conditions) add(clause string) {
+	c.clauses = append(c.clauses, clause)
+}
+
+func (c *conditions) String() string {
+	if len(c.clauses) == 0 {
+		return "true"
+	}
+	return strings.Join(c.clauses, " AND ")
+}
+
+// match adds the conditions of filter.
+func (c *conditions) match(filter SearchFilter) {
+	c.add("user_id = " + c.arg(filter.UserID))
+	c.add("NOT is_deleted")
+	if filter.Completed {
+		c.add("completed")
+	}
+	if !filter.CreatedAfter.IsZero() {
+		c.add("created_at >= " + c.arg(filter.CreatedAfter))
+	}
+	if !filter.CreatedBefore.IsZero() {
+		c.add("created_at < " + c.arg(filter.CreatedBefore))
+	}
+}
+
+// nonNil returns labels, empty rather than nil, as the column is not null.
+func nonNil(labels []string) []string {
+	if labels == nil {
+		return []string{}
+	}
+	return labels
+}
+
+func (p postgresTasks) Insert(ctx context.Context, task Task) error {
+	if id, ok := tenant.Scope(ctx); ok {
+		task.TenantID = id
+	}
+	createdAt, err := time.Parse(time.RFC3339, task.CreatedAt)
+	if err != nil {
+		return err
+	}
+	history, err := json.Marshal(task.StatusHistory)
+	if err != nil {
+		return err
+	}
+	if task.StatusHistory == nil {
+		history = []byte("[]")
+	}
+	var publicID *string
+	if task.PublicID != "" {
+		publicID = &task.PublicID
+	}
+	_, err = p.pool.Exec(ctx, `INSERT INTO tasks (`+taskColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25)`,
+		task.ID.Hex(), task.TenantID, task.UserID, task.Title, task.Description, task.Completed, task.Status, history,
+		task.Priority, nonNil(task.Labels), task.DueDate, task.EstimatedMinutes, task.TimeSpentMinutes, task.DeadlineAlertSent,
+		task.ViewCount, task.EditCount, task.LastViewedAt, task.LockedBy, task.LockedUntil, createdAt, task.UpdatedAt,
+		task.CompletedAt, task.IsDeleted, task.DeletedAt, publicID)
+	// 23505 is unique_violation
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "tasks_public_id_key" {
+		return errPublicIDTaken
+	}
+	return err
+}
+
+func (p postgresTasks) Resolve(ctx context.Context, publicID string) (primitive.ObjectID, error) {
+	c := scopedConditions(ctx)
+	c.add("public_id = " + c.arg(publicID))
+	var id string
+	err := p.pool.QueryRow(ctx, `SELECT id FROM tasks WHERE `+c.String(), c.args...).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return primitive.NilObjectID, errNoTask
+	}
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	return primitive.ObjectIDFromHex(id)
+}
+
+func (p postgresTasks) Get(ctx context.Context, id primitive.ObjectID, skipView bool, viewedAt string) (Task, error) {
+	c := scopedConditions(ctx)
+	c.add("id = " + c.arg(id.Hex()))
+	c.add("NOT is_deleted")
+	if skipView {
+		return scanTask(p.pool.QueryRow(ctx, `SELECT `+taskColumns+` FROM tasks WHERE `+c.String(), c.args...))
+	}
+	viewed := c.arg(viewedAt)
+	return scanTask(p.pool.QueryRow(ctx, `UPDATE tasks SET view_count = view_count + 1, last_viewed_at = `+viewed+`
+		WHERE `+c.String()+` RETURNING `+taskColumns, c.args...))
+}
+
+// Update locks the task's row, so concurrent updates apply one after the
+// other, each to the task as the previous one left it.
+func (p postgresTasks) Update(ctx context.Context, current Task, change taskChange) (before, updated Task, err error) {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return before, updated, err
+	}
+	defer tx.Rollback(ctx)
+
+	c := scopedConditions(ctx)
+	c.add("id = " + c.arg(current.ID.Hex()))
+	c.add("NOT is_deleted")
+	before, err = scanTask(tx.QueryRow(ctx, `SELECT `+taskColumns+` FROM tasks WHERE `+c.String()+` FOR UPDATE`, c.args...))
+	if err == errNoTask {
+		return before, updated, errTaskChanged
+	}
+	if err != nil {
+		return before, updated, err
+	}
+	if before.status() != current.status() || (change.LockNow != "" && before.lockedAgainst(change.Editor, change.LockNow)) {
+		return before, updated, errTaskChanged
+	}
+
+	updated = change.apply(before)
+	history, err := json.Marshal(updated.StatusHistory)
+	if err != nil {
+		return before, updated, err
+	}
+	_, err = tx.Exec(ctx, `UPDATE tasks SET title = $2, description = $3, completed = $4, status = $5, status_history = $6,
+		priority = $7, labels = $8, due_date = $9, estimated_minutes = $10, time_spent_minutes = $11,
+		deadline_alert_sent = $12, edit_count = $13, updated_at = $14, completed_at = $15
+		WHERE id = $1`,
+		updated.ID.Hex(), updated.Title, updated.Description, updated.Completed, updated.Status, history,
+		updated.Priority, nonNil(updated.Labels), updated.DueDate, updated.EstimatedMinutes, updated.TimeSpentMinutes,
+		updated.DeadlineAlertSent, updated.EditCount, updated.UpdatedAt, updated.CompletedAt)
+	if err != nil {
+		return before, updated, err
+	}
+	return before, updated, tx.Commit(ctx)
+}
+
+func (p postgresTasks) Delete(ctx context.Context, id primitive.ObjectID, deletedAt string) (Task, error) {
+	c := scopedConditions(ctx)
+	c.add("id = " + c.arg(id.Hex()))
+	c.add("NOT is_deleted")
+	deleted := c.arg(deletedAt)
+	return scanTask(p.pool.QueryRow(ctx, `UPDATE tasks SET is_deleted = true, deleted_at = `+deleted+`
+		WHERE `+c.String()+` RETURNING `+taskColumns, c.args...))
+}
+
+func (p postgresTasks) Purge(ctx context.Context, id primitive.ObjectID) (Task, error) {
+	c := scopedConditions(ctx)
+	c.add("id = " + c.arg(id.Hex()))
+	c.add("NOT is_deleted")
+	return scanTask(p.pool.QueryRow(ctx, `DELETE FROM tasks WHERE `+c.String()+` RETURNING `+taskColumns, c.args...))
+}
+
+// List returns tasks oldest first, as MongoDB returns them in the order they
+// were stored.
+func (p postgresTasks) List(ctx context.Context, filter SearchFilter, page pagination.Page) ([]Task, int64, error) {
+	c := scopedConditions(ctx)
+	c.match(filter)
+	return p.page(ctx, c, "created_at, id", page)
+}
+
+func (p postgresTasks) Search(ctx context.Context, filter SearchFilter, query string, page pagination.Page) ([]Task, int64, error) {
+	words := queryWords(query)
+	if len(words) == 0 {
+		return nil, 0, nil
+	}
+	c := scopedConditions(ctx)
+	c.match(filter)
+	// Any of the words matches, as with $text, and they are only letters and
+	// digits, so joining them is a valid tsquery
+	tsquery := "to_tsquery('english', " + c.arg(strings.Join(words, " | ")) + ")"
+	c.add("to_tsvector('english', title) @@ " + tsquery)
+	if filter.MinWordShare > 0 {
+		c.add(`(SELECT count(*) FROM unnest(` + c.arg(words) + `::text[]) AS word
+			WHERE word = ANY(regexp_split_to_array(lower(title), '[^[:alnum:]]+'))) >= ` + c.arg(filter.requiredWords(words)))
+	}
+	return p.page(ctx, c, "ts_rank(to_tsvector('english', title), "+tsquery+") DESC, id", page)
+}
+
+// page returns the page of tasks matching c in order, and how many match.
+func (p postgresTasks) page(ctx context.Context, c *conditions, order string, page pagination.Page) ([]Task, int64, error) {
+	var total int64
+	if err := p.pool.QueryRow(ctx, `SELECT count(*) FROM tasks WHERE `+c.String(), c.args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT ` + taskColumns + ` FROM tasks WHERE ` + c.String() + ` ORDER BY ` + order + ` OFFSET ` + c.arg(page.Offset)
+	if page.Limit > 0 {
+		query += ` LIMIT ` + c.arg(page.Limit)
+	}
+	rows, err := p.pool.Query(ctx, query, c.args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	tasks, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (Task, error) {
+		return scanTask(row)
+	})
+	return tasks, total, err
+}
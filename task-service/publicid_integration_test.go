@@ -0,0 +1,154 @@
+//go:build integration
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+	"github.com/technonext/todo-app/proto/publicid"
+)
+
+// newPublicIDTestServer is newTestServer with the public id index, as main
+// creates it.
+func newPublicIDTestServer(t *testing.T) *server {
+	t.Helper()
+	s := newTestServer(t, nil)
+	if err := ensurePublicIDIndex(context.Background(), s.collection.Database().Collection("tasks")); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestTaskLookupByEitherID(t *testing.T) {
+	s := newPublicIDTestServer(t)
+	ctx := context.Background()
+
+	created, err := s.CreateTask(ctx, &pb.CreateTaskRequest{UserId: "u1", Title: "Write report"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicID := created.Task.Id
+	if !publicid.Valid(publicid.Task, publicID) {
+		t.Fatalf("created task id = %q, want a public id", publicID)
+	}
+	var stored Task
+	if err := s.collection.FindOne(ctx, bson.M{publicid.Field: publicID}).Decode(&stored); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []string{publicID, stored.ID.Hex()} {
+		got, err := s.GetTask(ctx, &pb.GetTaskRequest{Id: id})
+		if err != nil {
+			t.Fatalf("GetTask(%s): %v", id, err)
+		}
+		if got.Task.Id != publicID {
+			t.Errorf("GetTask(%s).Id = %q, want only the public id %q", id, got.Task.Id, publicID)
+		}
+	}
+
+	updated, err := s.UpdateTask(ctx, &pb.UpdateTaskRequest{Id: stored.ID.Hex(), Title: "Write the report"})
+	if err != nil || updated.Task.Id != publicID {
+		t.Fatalf("UpdateTask by ObjectID = %v, %v", updated, err)
+	}
+	locked, err := s.LockTask(ctx, &pb.LockTaskRequest{Id: stored.ID.Hex(), UserId: "u1"})
+	if err != nil || locked.TaskId != publicID {
+		t.Fatalf("LockTask by ObjectID = %v, %v; want the public id", locked, err)
+	}
+
+	for _, id := range []string{"nope", publicid.New(publicid.User)} {
+		if _, err := s.GetTask(ctx, &pb.GetTaskRequest{Id: id}); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("GetTask(%q) code = %v, want InvalidArgument", id, status.Code(err))
+		}
+	}
+	if _, err := s.GetTask(ctx, &pb.GetTaskRequest{Id: publicid.New(publicid.Task)}); status.Code(err) != codes.NotFound {
+		t.Errorf("GetTask of an unknown public id: code = %v, want NotFound", status.Code(err))
+	}
+
+	deleted, err := s.DeleteTask(ctx, &pb.DeleteTaskRequest{Id: publicID})
+	if err != nil || deleted.DeletedTask.Id != publicID {
+		t.Fatalf("DeleteTask by public id = %v, %v", deleted, err)
+	}
+	if _, err := s.GetTask(ctx, &pb.GetTaskRequest{Id: stored.ID.Hex()}); status.Code(err) != codes.NotFound {
+		t.Errorf("GetTask after delete: code = %v, want NotFound", status.Code(err))
+	}
+}
+
+func TestCreateTaskRetriesTakenPublicID(t *testing.T) {
+	s := newPublicIDTestServer(t)
+	ctx := context.Background()
+
+	taken := publicid.New(publicid.Task)
+	var generated []string
+	next := func(ids ...string) {
+		generated = ids
+		newTaskPublicID = func() string {
+			id := generated[0]
+			if len(generated) > 1 {
+				generated = generated[1:]
+			}
+			return id
+		}
+	}
+	t.Cleanup(func() { newTaskPublicID = func() string { return publicid.New(publicid.Task) } })
+
+	next(taken)
+	if _, err := s.CreateTask(ctx, &pb.CreateTaskRequest{UserId: "u1", Title: "First"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A collision is retried with a new id
+	fresh := publicid.New(publicid.Task)
+	next(taken, fresh)
+	created, err := s.CreateTask(ctx, &pb.CreateTaskRequest{UserId: "u1", Title: "Second"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.Task.Id != fresh {
+		t.Errorf("task created with %q, want the id generated after the collision", created.Task.Id)
+	}
+
+	// Colliding every time fails rather than storing a duplicate
+	next(taken)
+	if _, err := s.CreateTask(ctx, &pb.CreateTaskRequest{UserId: "u1", Title: "Third"}); err == nil {
+		t.Error("CreateTask succeeded though every public id was taken")
+	}
+	if n, _ := s.collection.CountDocuments(ctx, bson.M{publicid.Field: taken}); n != 1 {
+		t.Errorf("%d tasks with public id %s, want 1", n, taken)
+	}
+}
+
+func TestPublicIDBackfill(t *testing.T) {
+	s := newPublicIDTestServer(t)
+	ctx := context.Background()
+	db := s.collection.Database()
+
+	// A task stored before public ids
+	oid := primitive.NewObjectID()
+	if _, err := s.collection.InsertOne(ctx, Task{ID: oid, UserID: "u1", Title: "Old", CreatedAt: "2026-03-01T09:00:00Z"}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.GetTask(ctx, &pb.GetTaskRequest{Id: oid.Hex()})
+	if err != nil || got.Task.Id != oid.Hex() {
+		t.Fatalf("GetTask before the backfill = %v, %v; want the ObjectID hex", got, err)
+	}
+
+	var out bytes.Buffer
+	if err := runMigrations(ctx, db, false, &out); err != nil {
+		t.Fatal(err)
+	}
+	got, err = s.GetTask(ctx, &pb.GetTaskRequest{Id: oid.Hex()})
+	if err != nil || !publicid.Valid(publicid.Task, got.Task.Id) {
+		t.Fatalf("GetTask after the backfill = %v, %v; want a public id", got, err)
+	}
+	if byPublic, err := s.GetTask(ctx, &pb.GetTaskRequest{Id: got.Task.Id}); err != nil || byPublic.Task.Title != "Old" {
+		t.Errorf("GetTask(%s) = %v, %v", got.Task.Id, byPublic, err)
+	}
+}
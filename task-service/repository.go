@@ -0,0 +1,384 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	"github.com/technonext/todo-app/proto/pagination"
+	pb "github.com/technonext/todo-app/proto/proto"
+	"github.com/technonext/todo-app/proto/publicid"
+	"github.com/technonext/todo-app/proto/settings"
+	"github.com/technonext/todo-app/proto/tenant"
+)
+
+// Storage drivers, chosen with STORAGE_DRIVER
+const (
+	storageMongo    = settings.StorageMongo
+	storagePostgres = "postgres"
+	// Tasks held in memory, for local development
+	storageMemory = "memory"
+)
+
+// Errors of a TaskRepository, which the RPCs turn into status errors
+var (
+	// errNoTask is returned for a task that does not exist, is in the trash,
+	// or belongs to another tenant
+	errNoTask = errors.New("task not found")
+	// errTaskChanged is returned by Update once the task no longer has the
+	// status it was read with, or no longer meets the change's conditions
+	errTaskChanged = errors.New("task changed concurrently")
+	// errPublicIDTaken is returned by Insert when another task already has
+	// the new task's public id
+	errPublicIDTaken = errors.New("public id taken")
+)
+
+// TaskRepository stores the tasks behind the core RPCs: CreateTask, GetTask,
+// UpdateTask, UpdateTaskStatus, DeleteTask and ListTasks. STORAGE_DRIVER
+// picks the implementation, mongoTasks, postgresTasks or memoryTasks. The
+// other RPCs and the background jobs work on the MongoDB collection
+// directly, so on the other stores only these RPCs are served; see
+// repositoryMethods.
+//
+// Every method is limited to the tenant of its context, as a
+// tenant.Collection is.
+type TaskRepository interface {
+	// Insert stores a new task.
+	Insert(ctx context.Context, task Task) error
+	// Resolve returns the ObjectID of the task with publicID, in the trash
+	// or not.
+	Resolve(ctx context.Context, publicID string) (primitive.ObjectID, error)
+	// Get returns the task with id outside the trash. Unless skipView, it
+	// counts a view at viewedAt in the same round-trip.
+	Get(ctx context.Context, id primitive.ObjectID, skipView bool, viewedAt string) (Task, error)
+	// Update makes change to the task as read in current, and returns the
+	// task as it was just before the change and as it is after. The change
+	// only applies while the task still has the status it was read with, so
+	// concurrent changes cannot skip the state machine.
+	Update(ctx context.Context, current Task, change taskChange) (before, updated Task, err error)
+	// Delete moves the task with id to the trash, returning it.
+	Delete(ctx context.Context, id primitive.ObjectID, deletedAt string) (Task, error)
+	// Purge removes the task with id outside the trash for good, returning
+	// it as it was.
+	Purge(ctx context.Context, id primitive.ObjectID) (Task, error)
+	// List returns a page of the tasks matching filter, and how many match
+	// in all.
+	List(ctx context.Context, filter SearchFilter, page pagination.Page) ([]Task, int64, error)
+	// Search is List for the tasks also matching query, best matches first.
+	Search(ctx context.Context, filter SearchFilter, query string, page pagination.Page) ([]Task, int64, error)
+}
+
+// taskChange is a change to one task, moving it to Status, which may be the
+// status it already has.
+type taskChange struct {
+	Status pb.TaskStatus
+	// Fields, when set, replaces the fields UpdateTask edits and counts an
+	// edit
+	Fields *taskFields
+	// Added to the time spent, so concurrent changes each count
+	AddTimeSpentMinutes int32
+	UpdatedAt           string
+	// The type of the event recorded for the change
+	EventType string
+	// The authenticated user making the change, kept in the status history
+	ChangedBy string
+	// When LockNow is set the change only applies while the task is not
+	// locked against Editor at LockNow
+	Editor, LockNow string
+}
+
+// taskFields are the fields of a task UpdateTask replaces.
+type taskFields struct {
+	Title            string
+	Description      string
+	DueDate          string
+	Labels           []string
+	EstimatedMinutes int32
+	// An unspecified priority leaves the task's alone
+	Priority pb.TaskPriority
+}
+
+// apply returns task as change leaves it.
+func (c taskChange) apply(task Task) Task {
+	from, to := task.status(), c.Status
+	task.UpdatedAt = c.UpdatedAt
+	if f := c.Fields; f != nil {
+		// A new due date gets an alert of its own
+		if f.DueDate != task.DueDate {
+			task.DeadlineAlertSent = false
+		}
+		task.Title = f.Title
+		task.Description = f.Description
+		task.DueDate = f.DueDate
+		task.Labels = f.Labels
+		task.EstimatedMinutes = f.EstimatedMinutes
+		if f.Priority != pb.TaskPriority_TASK_PRIORITY_UNSPECIFIED {
+			task.Priority = f.Priority.String()
+		}
+		task.EditCount++
+	}
+	task.TimeSpentMinutes += c.AddTimeSpentMinutes
+	task.Status = to.String()
+	task.Completed = to == pb.TaskStatus_TASK_STATUS_COMPLETED
+	if to != from {
+		task.StatusHistory = append(task.StatusHistory, StatusChange{From: from.String(), To: to.String(), ChangedAt: c.UpdatedAt, ChangedBy: c.ChangedBy})
+		if to == pb.TaskStatus_TASK_STATUS_COMPLETED {
+			task.CompletedAt = c.UpdatedAt
+		} else if from == pb.TaskStatus_TASK_STATUS_COMPLETED {
+			task.CompletedAt = ""
+		}
+	}
+	return task
+}
+
+// repositoryMethods are the TaskService methods served on stores other than
+// MongoDB, those TaskRepository covers. The rest rely on MongoDB: locks, the
+// trash and its reaper, sync tombstones, the event outbox, due date
+// extensions, moves, bulk changes, checklists, notes and the aggregations
+// behind metrics and the calendar.
+var repositoryMethods = map[string]bool{
+	pb.TaskService_CreateTask_FullMethodName:       true,
+	pb.TaskService_GetTask_FullMethodName:          true,
+	pb.TaskService_UpdateTask_FullMethodName:       true,
+	pb.TaskService_UpdateTaskStatus_FullMethodName: true,
+	pb.TaskService_DeleteTask_FullMethodName:       true,
+	pb.TaskService_ListTasks_FullMethodName:        true,
+}
+
+// checkRepositoryMethod refuses the TaskService methods not in
+// repositoryMethods when tasks are stored by driver.
+func checkRepositoryMethod(method, driver string) error {
+	if !strings.HasPrefix(method, "/todo.TaskService/") || repositoryMethods[method] {
+		return nil
+	}
+	return statusError(codes.Unimplemented, "UNSUPPORTED_BY_STORAGE", map[string]string{"method": method, "storage_driver": driver},
+		"%s needs STORAGE_DRIVER=%s", method, storageMongo)
+}
+
+// repositoryInterceptors refuse the methods checkRepositoryMethod does.
+func repositoryInterceptors(driver string) (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
+	unary := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := checkRepositoryMethod(info.FullMethod, driver); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+	stream := func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := checkRepositoryMethod(info.FullMethod, driver); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+	return unary, stream
+}
+
+// repository returns the store of the core RPCs: tasks when set, or else
+// the MongoDB collection.
+func (s *server) repository() TaskRepository {
+	if s.tasks != nil {
+		return s.tasks
+	}
+	return mongoTasks{collection: s.collection, search: s.search, outbox: s.outbox}
+}
+
+// mongoTasks stores tasks as documents of a collection, searched by search.
+// Changes stage their events in the task for the outbox, when there is one.
+type mongoTasks struct {
+	collection *tenant.Collection
+	search     SearchBackend
+	outbox     *outbox
+}
+
+func (m mongoTasks) Insert(ctx context.Context, task Task) error {
+	task.SchemaVersion = taskSchema.Version()
+	_, err := m.collection.InsertOne(ctx, newStoredTask(task))
+	if mongo.IsDuplicateKeyError(err) && strings.Contains(err.Error(), publicid.Field) {
+		return errPublicIDTaken
+	}
+	return err
+}
+
+func (m mongoTasks) Resolve(ctx context.Context, publicID string) (primitive.ObjectID, error) {
+	var task Task
+	opts := options.FindOne().SetProjection(bson.M{"_id": 1})
+	err := m.collection.FindOne(ctx, bson.M{publicid.Field: publicID}, opts).Decode(&task)
+	if err == mongo.ErrNoDocuments {
+		return task.ID, errNoTask
+	}
+	return task.ID, err
+}
+
+func (m mongoTasks) Get(ctx context.Context, id primitive.ObjectID, skipView bool, viewedAt string) (Task, error) {
+	var task Task
+	var err error
+	if skipView {
+		err = m.collection.FindOne(ctx, bson.M{"_id": id, "is_deleted": notDeleted}).Decode(&task)
+	} else {
+		err = m.collection.FindOneAndUpdate(ctx,
+			bson.M{"_id": id, "is_deleted": notDeleted},
+			bson.M{
+				"$inc": bson.M{"view_count": 1},
+				"$set": bson.M{"last_viewed_at": viewedAt},
+			},
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(&task)
+	}
+	if err == mongo.ErrNoDocuments {
+		return task, errNoTask
+	}
+	return task, err
+}
+
+// update returns change to current as a MongoDB update.
+func (m mongoTasks) update(current Task, change taskChange) (bson.M, error) {
+	from, to := current.status(), change.Status
+	set := bson.M{
+		"updated_at": change.UpdatedAt,
+		"status":     to.String(),
+		"completed":  to == pb.TaskStatus_TASK_STATUS_COMPLETED,
+	}
+	update := bson.M{"$set": set}
+	inc := bson.M{}
+	if f := change.Fields; f != nil {
+		set["title"] = f.Title
+		set["description"] = f.Description
+		set["due_date"] = taskDate(f.DueDate)
+		set["labels"] = f.Labels
+		set["estimated_minutes"] = f.EstimatedMinutes
+		if f.Priority != pb.TaskPriority_TASK_PRIORITY_UNSPECIFIED {
+			set["priority"] = f.Priority.String()
+		}
+		if f.DueDate != current.DueDate {
+			set["deadline_alert_sent"] = false
+		}
+		inc["edit_count"] = 1
+	}
+	// Time spent is added with $inc, so concurrent updates each count
+	if change.AddTimeSpentMinutes > 0 {
+		inc["time_spent_minutes"] = change.AddTimeSpentMinutes
+	}
+	if len(inc) > 0 {
+		update["$inc"] = inc
+	}
+	if to != from {
+		update["$push"] = bson.M{"status_history": StatusChange{
+			From:      from.String(),
+			To:        to.String(),
+			ChangedAt: change.UpdatedAt,
+			ChangedBy: change.ChangedBy,
+		}}
+		if to == pb.TaskStatus_TASK_STATUS_COMPLETED {
+			set["completed_at"] = change.UpdatedAt
+		} else if from == pb.TaskStatus_TASK_STATUS_COMPLETED {
+			update["$unset"] = bson.M{"completed_at": ""}
+		}
+	}
+	if m.outbox != nil {
+		snapshot, err := projectUpdate(current, update)
+		if err != nil {
+			return nil, err
+		}
+		if err := m.outbox.stage(update, change.EventType, &snapshot, change.UpdatedAt); err != nil {
+			return nil, err
+		}
+	}
+	return update, nil
+}
+
+func (m mongoTasks) Update(ctx context.Context, current Task, change taskChange) (before, updated Task, err error) {
+	update, err := m.update(current, change)
+	if err != nil {
+		return before, updated, err
+	}
+
+	status := current.status()
+	matchStatus := bson.M{"status": status.String()}
+	if status == legacyStatus(current.Completed) {
+		// Tasks stored before statuses read with the status their completed
+		// flag implies until migration 3 stores it
+		matchStatus = bson.M{"$or": bson.A{
+			matchStatus,
+			bson.M{"status": bson.M{"$exists": false}, "completed": current.Completed},
+		}}
+	}
+	// Under $and, so the lock's own $or can join the filter
+	filter := bson.M{"_id": current.ID, "is_deleted": notDeleted, "$and": bson.A{matchStatus}}
+	if change.LockNow != "" {
+		for key, value := range editableBy(change.Editor, change.LockNow) {
+			filter[key] = value
+		}
+	}
+
+	// The task as it was before the update tells callers exactly what the
+	// update changed, including fields it moved with $inc
+	err = m.collection.FindOneAndUpdate(ctx, filter, update,
+		options.FindOneAndUpdate().SetReturnDocument(options.Before),
+	).Decode(&before)
+	if err == mongo.ErrNoDocuments {
+		return before, updated, errTaskChanged
+	}
+	if err != nil {
+		return before, updated, err
+	}
+
+	err = m.collection.FindOne(ctx, bson.M{"_id": current.ID}).Decode(&updated)
+	return before, updated, err
+}
+
+func (m mongoTasks) Delete(ctx context.Context, id primitive.ObjectID, deletedAt string) (Task, error) {
+	update := bson.M{"$set": bson.M{"is_deleted": true, "deleted_at": deletedAt}}
+	if err := m.outbox.stage(update, "task.deleted", nil, deletedAt); err != nil {
+		return Task{}, err
+	}
+	var task Task
+	err := m.collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": id, "is_deleted": notDeleted},
+		update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&task)
+	if err == mongo.ErrNoDocuments {
+		return task, errNoTask
+	}
+	return task, err
+}
+
+// Purge stages no event: the pending events ride on the task's document,
+// which is gone, so like the trash reaper it is not published to the
+// broker.
+func (m mongoTasks) Purge(ctx context.Context, id primitive.ObjectID) (Task, error) {
+	var task Task
+	err := m.collection.FindOneAndDelete(ctx, bson.M{"_id": id, "is_deleted": notDeleted}).Decode(&task)
+	if err == mongo.ErrNoDocuments {
+		return task, errNoTask
+	}
+	return task, err
+}
+
+func (m mongoTasks) List(ctx context.Context, filter SearchFilter, page pagination.Page) ([]Task, int64, error) {
+	match := filter.match()
+	cursor, err := m.collection.Find(ctx, match, page.FindOptions().SetProjection(withoutNotes))
+	if err != nil {
+		return nil, 0, err
+	}
+	var tasks []Task
+	if err := cursor.All(ctx, &tasks); err != nil {
+		return nil, 0, err
+	}
+
+	count, err := m.collection.CountDocuments(ctx, match)
+	if err != nil {
+		return nil, 0, err
+	}
+	return tasks, count, nil
+}
+
+func (m mongoTasks) Search(ctx context.Context, filter SearchFilter, query string, page pagination.Page) ([]Task, int64, error) {
+	return m.search.Search(ctx, m.collection, filter, query, page.Offset, int64(page.Limit))
+}
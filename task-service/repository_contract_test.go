@@ -0,0 +1,343 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/technonext/todo-app/proto/pagination"
+	pb "github.com/technonext/todo-app/proto/proto"
+	"github.com/technonext/todo-app/proto/publicid"
+	"github.com/technonext/todo-app/proto/tenant"
+)
+
+// contractTask returns a new, unstored task of userID's.
+func contractTask(userID, title string, created time.Time) Task {
+	return Task{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		Title:     title,
+		CreatedAt: created.Format(time.RFC3339),
+		UpdatedAt: created.Format(time.RFC3339),
+		Status:    pb.TaskStatus_TASK_STATUS_NOT_STARTED.String(),
+	}
+}
+
+func insertTasks(t *testing.T, ctx context.Context, repo TaskRepository, tasks ...Task) {
+	t.Helper()
+	for _, task := range tasks {
+		if err := repo.Insert(ctx, task); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func taskTitles(tasks []Task) []string {
+	titles := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		titles = append(titles, task.Title)
+	}
+	return titles
+}
+
+func runRepositoryContract(t *testing.T, newRepository func(*testing.T) TaskRepository) {
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second)
+
+	t.Run("get counts views", func(t *testing.T) {
+		repo := newRepository(t)
+		task := contractTask("u1", "Write report", now)
+		task.Labels = []string{"work"}
+		insertTasks(t, ctx, repo, task)
+
+		got, err := repo.Get(ctx, task.ID, true, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Title != task.Title || got.UserID != "u1" || len(got.Labels) != 1 || got.ViewCount != 0 {
+			t.Fatalf("Get() = %+v, want the task unviewed", got)
+		}
+		created, err := time.Parse(time.RFC3339, got.CreatedAt)
+		if err != nil || !created.Equal(now) {
+			t.Errorf("created_at = %q, want %v", got.CreatedAt, now)
+		}
+
+		viewed := now.Format(time.RFC3339)
+		got, err = repo.Get(ctx, task.ID, false, viewed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.ViewCount != 1 || got.LastViewedAt != viewed {
+			t.Errorf("viewed task has %d views, last at %q; want 1 at %q", got.ViewCount, got.LastViewedAt, viewed)
+		}
+		if _, err := repo.Get(ctx, primitive.NewObjectID(), true, ""); err != errNoTask {
+			t.Errorf("Get() of a missing task = %v, want errNoTask", err)
+		}
+	})
+
+	t.Run("public ids", func(t *testing.T) {
+		repo := newRepository(t)
+		task := contractTask("u1", "Write report", now)
+		task.PublicID = publicid.New(publicid.Task)
+		// Tasks from before public ids have none, and do not collide
+		insertTasks(t, ctx, repo, task, contractTask("u1", "Old", now), contractTask("u1", "Older", now))
+
+		if oid, err := repo.Resolve(ctx, task.PublicID); err != nil || oid != task.ID {
+			t.Errorf("Resolve() = %v, %v; want %v", oid, err, task.ID)
+		}
+		if got, err := repo.Get(ctx, task.ID, true, ""); err != nil || got.PublicID != task.PublicID {
+			t.Errorf("Get() = %+v, %v; want the public id stored", got, err)
+		}
+		if _, err := repo.Resolve(ctx, publicid.New(publicid.Task)); err != errNoTask {
+			t.Errorf("Resolve() of an unknown public id = %v, want errNoTask", err)
+		}
+		if _, err := repo.Resolve(tenant.WithID(ctx, "b"), task.PublicID); err != errNoTask {
+			t.Errorf("Resolve() for another tenant = %v, want errNoTask", err)
+		}
+
+		taken := contractTask("u1", "Copy", now)
+		taken.PublicID = task.PublicID
+		if err := repo.Insert(ctx, taken); err != errPublicIDTaken {
+			t.Errorf("Insert() with a taken public id = %v, want errPublicIDTaken", err)
+		}
+	})
+
+	t.Run("tenants", func(t *testing.T) {
+		repo := newRepository(t)
+		tenantA := tenant.WithID(ctx, "a")
+		task := contractTask("u1", "Quarterly report", now)
+		insertTasks(t, tenantA, repo, task)
+
+		for name, ctx := range map[string]context.Context{"tenant b": tenant.WithID(ctx, "b"), "default tenant": tenant.WithID(ctx, "")} {
+			if _, err := repo.Get(ctx, task.ID, true, ""); err != errNoTask {
+				t.Errorf("Get() for %s = %v, want errNoTask", name, err)
+			}
+			if tasks, total, err := repo.List(ctx, SearchFilter{UserID: "u1"}, pagination.Page{}); err != nil || total != 0 {
+				t.Errorf("List() for %s = %v (total %d, %v), want none", name, taskTitles(tasks), total, err)
+			}
+		}
+		got, err := repo.Get(tenantA, task.ID, true, "")
+		if err != nil || got.TenantID != "a" {
+			t.Errorf("Get() for tenant a = %+v, %v; want the task in tenant a", got, err)
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		repo := newRepository(t)
+		task := contractTask("u1", "Write report", now)
+		task.DueDate = "2026-01-10"
+		task.DeadlineAlertSent = true
+		insertTasks(t, ctx, repo, task)
+
+		change := taskChange{
+			Status:              pb.TaskStatus_TASK_STATUS_COMPLETED,
+			Fields:              &taskFields{Title: "Write the report", DueDate: "2026-02-01", Priority: pb.TaskPriority_TASK_PRIORITY_HIGH},
+			AddTimeSpentMinutes: 20,
+			UpdatedAt:           "later",
+			EventType:           "task.updated",
+		}
+		before, updated, err := repo.Update(ctx, task, change)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if before.Title != "Write report" || before.Completed {
+			t.Errorf("before = %+v, want the task as inserted", before)
+		}
+		if want := change.apply(before); updated.Title != want.Title || updated.Status != want.Status || !updated.Completed ||
+			updated.CompletedAt != "later" || updated.Priority != want.Priority || updated.DeadlineAlertSent ||
+			updated.TimeSpentMinutes != 20 || updated.EditCount != 1 || len(updated.StatusHistory) != 1 {
+			t.Errorf("updated = %+v, want %+v", updated, want)
+		}
+
+		// The task read before the update no longer has its status
+		if _, _, err := repo.Update(ctx, task, change); err != errTaskChanged {
+			t.Errorf("Update() of a stale task = %v, want errTaskChanged", err)
+		}
+
+		reopen := taskChange{Status: pb.TaskStatus_TASK_STATUS_NOT_STARTED, UpdatedAt: "reopened"}
+		if _, updated, err = repo.Update(ctx, updated, reopen); err != nil {
+			t.Fatal(err)
+		}
+		if updated.Completed || updated.CompletedAt != "" || len(updated.StatusHistory) != 2 || updated.EditCount != 1 {
+			t.Errorf("reopened = %+v, want it incomplete with two status changes", updated)
+		}
+	})
+
+	t.Run("concurrent updates all count", func(t *testing.T) {
+		repo := newRepository(t)
+		task := contractTask("u1", "Write report", now)
+		insertTasks(t, ctx, repo, task)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				change := taskChange{Status: pb.TaskStatus_TASK_STATUS_NOT_STARTED, AddTimeSpentMinutes: 15, UpdatedAt: "later"}
+				if _, _, err := repo.Update(ctx, task, change); err != nil {
+					t.Error(err)
+				}
+			}()
+		}
+		wg.Wait()
+
+		got, err := repo.Get(ctx, task.ID, true, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.TimeSpentMinutes != 150 {
+			t.Errorf("time spent = %d, want 150", got.TimeSpentMinutes)
+		}
+	})
+
+	t.Run("locked tasks", func(t *testing.T) {
+		repo := newRepository(t)
+		lockNow := lockTime(now)
+		task := contractTask("u1", "Write report", now)
+		task.LockedBy, task.LockedUntil = "u2", lockTime(now.Add(time.Minute))
+		insertTasks(t, ctx, repo, task)
+
+		change := taskChange{Status: pb.TaskStatus_TASK_STATUS_NOT_STARTED, UpdatedAt: "later", Editor: "u1", LockNow: lockNow}
+		if _, _, err := repo.Update(ctx, task, change); err != errTaskChanged {
+			t.Errorf("Update() by another editor = %v, want errTaskChanged", err)
+		}
+		change.Editor = "u2"
+		if _, _, err := repo.Update(ctx, task, change); err != nil {
+			t.Errorf("Update() by the lock holder = %v", err)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		repo := newRepository(t)
+		task := contractTask("u1", "Write report", now)
+		insertTasks(t, ctx, repo, task)
+
+		deleted, err := repo.Delete(ctx, task.ID, "later")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !deleted.IsDeleted || deleted.DeletedAt != "later" || deleted.Title != task.Title {
+			t.Errorf("Delete() = %+v, want the task in the trash", deleted)
+		}
+		if _, err := repo.Get(ctx, task.ID, true, ""); err != errNoTask {
+			t.Errorf("Get() of a deleted task = %v, want errNoTask", err)
+		}
+		if _, err := repo.Delete(ctx, task.ID, "later"); err != errNoTask {
+			t.Errorf("Delete() of a deleted task = %v, want errNoTask", err)
+		}
+	})
+
+	t.Run("purge", func(t *testing.T) {
+		repo := newRepository(t)
+		task := contractTask("u1", "Write report", now)
+		task.PublicID = "tsk_purged"
+		trashed := contractTask("u1", "Trashed", now)
+		trashed.IsDeleted = true
+		insertTasks(t, ctx, repo, task, trashed)
+
+		purged, err := repo.Purge(ctx, task.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if purged.IsDeleted || purged.Title != task.Title {
+			t.Errorf("Purge() = %+v, want the task as it was", purged)
+		}
+		if _, err := repo.Resolve(ctx, task.PublicID); err != errNoTask {
+			t.Errorf("Resolve() of a purged task = %v, want errNoTask, as it is not in the trash", err)
+		}
+		if _, err := repo.Purge(ctx, task.ID); err != errNoTask {
+			t.Errorf("Purge() of a purged task = %v, want errNoTask", err)
+		}
+		if _, err := repo.Purge(ctx, trashed.ID); err != errNoTask {
+			t.Errorf("Purge() of a task in the trash = %v, want errNoTask", err)
+		}
+	})
+
+	t.Run("list", func(t *testing.T) {
+		repo := newRepository(t)
+		done := contractTask("u1", "Pay rent", now.Add(-time.Hour))
+		done.Completed, done.Status = true, pb.TaskStatus_TASK_STATUS_COMPLETED.String()
+		trashed := contractTask("u1", "Trashed", now)
+		trashed.IsDeleted = true
+		insertTasks(t, ctx, repo,
+			contractTask("u1", "Write report", now.Add(-2*time.Hour)),
+			done,
+			contractTask("u1", "Walk the dog", now),
+			trashed,
+			contractTask("u2", "Other user's", now),
+		)
+
+		tests := []struct {
+			name   string
+			filter SearchFilter
+			page   pagination.Page
+			want   []string
+			total  int64
+		}{
+			{"all", SearchFilter{UserID: "u1"}, pagination.Page{}, []string{"Write report", "Pay rent", "Walk the dog"}, 3},
+			{"page", SearchFilter{UserID: "u1"}, pagination.Page{Offset: 1, Limit: 1}, []string{"Pay rent"}, 3},
+			{"completed", SearchFilter{UserID: "u1", Completed: true}, pagination.Page{}, []string{"Pay rent"}, 1},
+			{"created range", SearchFilter{UserID: "u1", CreatedAfter: now.Add(-90 * time.Minute), CreatedBefore: now}, pagination.Page{}, []string{"Pay rent"}, 1},
+			{"other user", SearchFilter{UserID: "u3"}, pagination.Page{}, []string{}, 0},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				tasks, total, err := repo.List(ctx, tt.filter, tt.page)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := taskTitles(tasks); fmt.Sprint(got) != fmt.Sprint(tt.want) || total != tt.total {
+					t.Errorf("List() = %q (total %d), want %q (total %d)", got, total, tt.want, tt.total)
+				}
+			})
+		}
+	})
+
+	t.Run("search", func(t *testing.T) {
+		repo := newRepository(t)
+		done := contractTask("u1", "Buy milk and eggs", now)
+		done.Completed = true
+		trashed := contractTask("u1", "Buy milk", now)
+		trashed.IsDeleted = true
+		insertTasks(t, ctx, repo,
+			contractTask("u1", "Buy oat milk", now),
+			done,
+			contractTask("u1", "Walk the dog", now),
+			trashed,
+			contractTask("u2", "Buy milk", now),
+		)
+
+		tests := []struct {
+			name   string
+			filter SearchFilter
+			query  string
+			want   map[string]bool
+		}{
+			{"any word", SearchFilter{UserID: "u1"}, "milk", map[string]bool{"Buy oat milk": true, "Buy milk and eggs": true}},
+			{"stemmed", SearchFilter{UserID: "u1"}, "walking dogs", map[string]bool{"Walk the dog": true}},
+			{"completed", SearchFilter{UserID: "u1", Completed: true}, "milk", map[string]bool{"Buy milk and eggs": true}},
+			{"every word", SearchFilter{UserID: "u1", MinWordShare: 1}, "buy oat milk", map[string]bool{"Buy oat milk": true}},
+			{"no match", SearchFilter{UserID: "u1"}, "bread", map[string]bool{}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				tasks, total, err := repo.Search(ctx, tt.filter, tt.query, pagination.Page{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(tasks) != len(tt.want) || total != int64(len(tt.want)) {
+					t.Fatalf("Search() = %q (total %d), want %v", taskTitles(tasks), total, tt.want)
+				}
+				for _, task := range tasks {
+					if !tt.want[task.Title] {
+						t.Errorf("Search() = %q, want %v", taskTitles(tasks), tt.want)
+					}
+				}
+			})
+		}
+	})
+}
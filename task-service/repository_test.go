@@ -0,0 +1,93 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+func TestTaskChangeApply(t *testing.T) {
+	current := Task{
+		Title:             "Write report",
+		DueDate:           "2026-01-10",
+		Priority:          pb.TaskPriority_TASK_PRIORITY_HIGH.String(),
+		Status:            pb.TaskStatus_TASK_STATUS_IN_PROGRESS.String(),
+		TimeSpentMinutes:  30,
+		DeadlineAlertSent: true,
+	}
+	tests := []struct {
+		name   string
+		change taskChange
+		want   func(Task) Task
+	}{
+		{"status only", taskChange{Status: pb.TaskStatus_TASK_STATUS_IN_PROGRESS, UpdatedAt: "now"}, func(t Task) Task {
+			t.UpdatedAt = "now"
+			return t
+		}},
+		{"completing", taskChange{Status: pb.TaskStatus_TASK_STATUS_COMPLETED, UpdatedAt: "now"}, func(t Task) Task {
+			t.UpdatedAt, t.CompletedAt = "now", "now"
+			t.Status, t.Completed = pb.TaskStatus_TASK_STATUS_COMPLETED.String(), true
+			t.StatusHistory = []StatusChange{{From: "TASK_STATUS_IN_PROGRESS", To: "TASK_STATUS_COMPLETED", ChangedAt: "now"}}
+			return t
+		}},
+		{"fields with the same due date", taskChange{
+			Status:              pb.TaskStatus_TASK_STATUS_IN_PROGRESS,
+			Fields:              &taskFields{Title: "Write the report", DueDate: "2026-01-10", Labels: []string{"work"}},
+			AddTimeSpentMinutes: 15,
+			UpdatedAt:           "now",
+		}, func(t Task) Task {
+			t.UpdatedAt, t.Title, t.Labels = "now", "Write the report", []string{"work"}
+			t.TimeSpentMinutes, t.EditCount = 45, 1
+			return t
+		}},
+		{"fields with a new due date and priority", taskChange{
+			Status:    pb.TaskStatus_TASK_STATUS_IN_PROGRESS,
+			Fields:    &taskFields{Title: "Write report", DueDate: "2026-02-01", Priority: pb.TaskPriority_TASK_PRIORITY_LOW},
+			UpdatedAt: "now",
+		}, func(t Task) Task {
+			t.UpdatedAt, t.DueDate, t.Priority = "now", "2026-02-01", pb.TaskPriority_TASK_PRIORITY_LOW.String()
+			t.DeadlineAlertSent, t.EditCount = false, 1
+			return t
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.change.apply(current)
+			if want := tt.want(current); !reflect.DeepEqual(got, want) {
+				t.Errorf("apply() = %+v, want %+v", got, want)
+			}
+
+			// MongoDB's update leaves the task as apply does
+			update, err := mongoTasks{}.update(current, tt.change)
+			if err != nil {
+				t.Fatal(err)
+			}
+			projected, err := projectUpdate(current, update)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// projectUpdate does not follow the engagement counters
+			projected.EditCount = got.EditCount
+			if !reflect.DeepEqual(projected, got) {
+				t.Errorf("MongoDB update leaves %+v, want %+v", projected, got)
+			}
+		})
+	}
+}
+
+func TestCheckRepositoryMethod(t *testing.T) {
+	for method, want := range map[string]codes.Code{
+		pb.TaskService_ListTasks_FullMethodName:   codes.OK,
+		pb.TaskService_LockTask_FullMethodName:    codes.Unimplemented,
+		pb.TaskService_ExportTasks_FullMethodName: codes.Unimplemented,
+		"/todo.FaultService/SetFaults":            codes.OK,
+	} {
+		if got := status.Code(checkRepositoryMethod(method, storageMemory)); got != want {
+			t.Errorf("checkRepositoryMethod(%s) = %v, want %v", method, got, want)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"math"
+	"strings"
+	"time"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/technonext/todo-app/proto/tenant"
+)
+
+// SearchBackend finds tasks by free text. SEARCH_PROVIDER picks the
+// implementation: "text" (the default) uses the title text index, and
+// "atlas_search" uses an Atlas Search index for fuzzy matching.
+type SearchBackend interface {
+	// Search returns the tasks matching both filter and query, best matches
+	// first, along with how many match in total. A zero limit returns all.
+	Search(ctx context.Context, collection *tenant.Collection, filter SearchFilter, query string, skip, limit int64) ([]Task, int64, error)
+}
+
+// SearchFilter narrows a search to one user's tasks outside the trash.
+type SearchFilter struct {
+	UserID string
+	// Only completed tasks
+	Completed bool
+	// Only tasks created in [CreatedAfter, CreatedBefore); a zero time
+	// leaves that end open
+	CreatedAfter, CreatedBefore time.Time
+	// Share of the query's words a task's title must match, from 0 (any of
+	// them) to 1 (all of them)
+	MinWordShare float64
+}
+
+// match is the filter as a query, without the search itself.
+func (f SearchFilter) match() bson.M {
+	filter := bson.M{"user_id": f.UserID, "is_deleted": notDeleted}
+	if f.Completed {
+		filter["completed"] = true
+	}
+	if bounds := createdBounds(f.CreatedAfter, f.CreatedBefore); len(bounds) > 0 {
+		filter["$expr"] = bson.M{"$and": bounds}
+	}
+	return filter
+}
+
+// requiredWords is how many of words a match must contain for MinWordShare,
+// at least one.
+func (f SearchFilter) requiredWords(words []string) int {
+	return int(math.Max(1, math.Ceil(f.MinWordShare*float64(len(words)))))
+}
+
+// queryWords returns the distinct lowercased words of a query.
+func queryWords(query string) []string {
+	seen := make(map[string]bool)
+	var words []string
+	for _, w := range strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}) {
+		if !seen[w] {
+			seen[w] = true
+			words = append(words, w)
+		}
+	}
+	return words
+}
+
+// NewSearchBackend returns the backend for provider, or nil if there is none
+// by that name.
+func NewSearchBackend(provider string) SearchBackend {
+	switch provider {
+	case "", "text":
+		return textSearch{}
+	case "atlas_search":
+		return atlasSearch{index: getEnv("ATLAS_SEARCH_INDEX", "default")}
+	}
+	return nil
+}
+
+// textSearch matches whole words of the title through the $text index.
+type textSearch struct{}
+
+func (textSearch) Search(ctx context.Context, collection *tenant.Collection, filter SearchFilter, query string, skip, limit int64) ([]Task, int64, error) {
+	matching := filter.match()
+	matching["$text"] = bson.M{"$search": query}
+	if filter.MinWordShare > 0 {
+		// Count the query's words among the title's, as $text matches any one
+		words := queryWords(query)
+		titleWords := bson.M{"$map": bson.M{
+			"input": bson.M{"$regexFindAll": bson.M{"input": bson.M{"$toLower": "$title"}, "regex": `[\p{L}\p{N}]+`}},
+			"in":    "$$this.match",
+		}}
+		shared := bson.M{"$gte": bson.A{
+			bson.M{"$size": bson.M{"$setIntersection": bson.A{titleWords, words}}},
+			filter.requiredWords(words),
+		}}
+		if expr, ok := matching["$expr"].(bson.M); ok {
+			matching["$expr"] = bson.M{"$and": bson.A{expr, shared}}
+		} else {
+			matching["$expr"] = shared
+		}
+	}
+
+	score := bson.M{"$meta": "textScore"}
+	opts := options.Find().
+		SetProjection(bson.M{"score": score, "notes": 0}).
+		SetSort(bson.M{"score": score}).
+		SetSkip(skip).
+		SetLimit(limit)
+	cursor, err := collection.Find(ctx, matching, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	var tasks []Task
+	if err := cursor.All(ctx, &tasks); err != nil {
+		return nil, 0, err
+	}
+
+	total, err := collection.CountDocuments(ctx, matching)
+	if err != nil {
+		return nil, 0, err
+	}
+	return tasks, total, nil
+}
+
+// atlasSearch fuzzy-matches title and description through an Atlas Search
+// index. The index must cover both as strings, user_id as a token, and
+// completed and is_deleted as booleans.
+type atlasSearch struct {
+	index string
+}
+
+// operator is the $search compound operator for filter and query: the
+// query's words match fuzzily, and the rest of filter narrows the results
+// without scoring them.
+func (a atlasSearch) operator(filter SearchFilter, query string) bson.M {
+	fuzzy := bson.M{"maxEdits": 1}
+	should := bson.A{bson.M{"text": bson.M{"query": query, "path": bson.A{"title", "description"}, "fuzzy": fuzzy}}}
+	minimum := 1
+	if filter.MinWordShare > 0 {
+		words := queryWords(query)
+		should = bson.A{}
+		for _, w := range words {
+			should = append(should, bson.M{"text": bson.M{"query": w, "path": "title", "fuzzy": fuzzy}})
+		}
+		minimum = filter.requiredWords(words)
+	}
+
+	narrow := bson.A{bson.M{"equals": bson.M{"path": "user_id", "value": filter.UserID}}}
+	if filter.Completed {
+		narrow = append(narrow, bson.M{"equals": bson.M{"path": "completed", "value": true}})
+	}
+	return bson.M{
+		"index": a.index,
+		"compound": bson.M{
+			"should":             should,
+			"minimumShouldMatch": minimum,
+			"filter":             narrow,
+			"mustNot":            bson.A{bson.M{"equals": bson.M{"path": "is_deleted", "value": true}}},
+		},
+	}
+}
+
+func (a atlasSearch) Search(ctx context.Context, collection *tenant.Collection, filter SearchFilter, query string, skip, limit int64) ([]Task, int64, error) {
+	page := bson.A{bson.M{"$skip": skip}}
+	if limit > 0 {
+		page = append(page, bson.M{"$limit": limit})
+	}
+
+	pipeline := []bson.M{{"$search": a.operator(filter, query)}}
+	// created_at is a string in the server's zone, which Atlas Search cannot
+	// compare as a date
+	if bounds := createdBounds(filter.CreatedAfter, filter.CreatedBefore); len(bounds) > 0 {
+		pipeline = append(pipeline, bson.M{"$match": bson.M{"$expr": bson.M{"$and": bounds}}})
+	}
+	pipeline = append(pipeline, bson.M{"$project": withoutNotes}, bson.M{"$facet": bson.M{
+		"tasks": page,
+		"total": bson.A{bson.M{"$count": "n"}},
+	}})
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var result struct {
+		Tasks []Task `bson:"tasks"`
+		Total []struct {
+			N int64 `bson:"n"`
+		} `bson:"total"`
+	}
+	if cursor.Next(ctx) {
+		if err := cursor.Decode(&result); err != nil {
+			return nil, 0, err
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	var total int64
+	if len(result.Total) > 0 {
+		total = result.Total[0].N
+	}
+	return result.Tasks, total, nil
+}
@@ -0,0 +1,221 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+	"github.com/technonext/todo-app/proto/tenant"
+)
+
+// seedSearchTasks stores u1's tasks for the search tests, one of them in the
+// trash, and one of u2's.
+func seedSearchTasks(t *testing.T, collection *tenant.Collection) {
+	t.Helper()
+	now := time.Now().Format(time.RFC3339)
+	_, err := collection.InsertMany(context.Background(), []interface{}{
+		Task{UserID: "u1", Title: "Buy oat milk", CreatedAt: now},
+		Task{UserID: "u1", Title: "Buy milk and eggs", CreatedAt: now, Completed: true},
+		Task{UserID: "u1", Title: "Walk the dog", CreatedAt: now},
+		Task{UserID: "u1", Title: "Buy milk", CreatedAt: now, IsDeleted: true},
+		Task{UserID: "u2", Title: "Buy milk", CreatedAt: now},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func searchTitles(tasks []*pb.Task) map[string]bool {
+	titles := make(map[string]bool)
+	for _, task := range tasks {
+		titles[task.Title] = true
+	}
+	return titles
+}
+
+// runSearchTests checks s's search backend through the RPCs that use it.
+func runSearchTests(t *testing.T, s *server, fuzzy bool) {
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		call func() (*pb.ListTasksResponse, error)
+		want []string
+	}{
+		{"list by query", func() (*pb.ListTasksResponse, error) {
+			return s.ListTasks(ctx, &pb.ListTasksRequest{UserId: "u1", Query: "milk"})
+		}, []string{"Buy oat milk", "Buy milk and eggs"}},
+		{"list completed", func() (*pb.ListTasksResponse, error) {
+			return s.ListTasks(ctx, &pb.ListTasksRequest{UserId: "u1", Query: "milk", Completed: true})
+		}, []string{"Buy milk and eggs"}},
+		{"list created before", func() (*pb.ListTasksResponse, error) {
+			return s.ListTasks(ctx, &pb.ListTasksRequest{UserId: "u1", Query: "milk", CreatedBefore: "2020-01-01T00:00:00Z"})
+		}, nil},
+		{"similar at half", func() (*pb.ListTasksResponse, error) {
+			return s.FindSimilarTasks(ctx, &pb.FindSimilarTasksRequest{UserId: "u1", Title: "buy milk"})
+		}, []string{"Buy oat milk", "Buy milk and eggs"}},
+		{"similar needing every word", func() (*pb.ListTasksResponse, error) {
+			return s.FindSimilarTasks(ctx, &pb.FindSimilarTasksRequest{UserId: "u1", Title: "buy oat milk", Threshold: 1})
+		}, []string{"Buy oat milk"}},
+	}
+	if fuzzy {
+		tests = append(tests, struct {
+			name string
+			call func() (*pb.ListTasksResponse, error)
+			want []string
+		}{"similar with a typo", func() (*pb.ListTasksResponse, error) {
+			return s.FindSimilarTasks(ctx, &pb.FindSimilarTasksRequest{UserId: "u1", Title: "walk the dgo", Threshold: 1})
+		}, []string{"Walk the dog"}})
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := tt.call()
+			if err != nil {
+				t.Fatal(err)
+			}
+			titles := searchTitles(resp.Tasks)
+			if len(titles) != len(tt.want) || int(resp.Total) != len(tt.want) {
+				t.Fatalf("found %v (total %d), want %q", titles, resp.Total, tt.want)
+			}
+			for _, title := range tt.want {
+				if !titles[title] {
+					t.Errorf("found %v, want %q", titles, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestTextSearch(t *testing.T) {
+	s := newTestServer(t, nil)
+	s.search = textSearch{}
+	_, err := s.collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys: bson.D{{Key: "title", Value: "text"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	seedSearchTasks(t, s.collection)
+	runSearchTests(t, s, false)
+}
+
+// TestAtlasSearch needs an Atlas cluster, named by ATLAS_TEST_URI, where it
+// creates a search index; building one takes a while, so -short skips it.
+func TestAtlasSearch(t *testing.T) {
+	if testing.Short() {
+		t.Skip("building an Atlas Search index is slow")
+	}
+	uri := os.Getenv("ATLAS_TEST_URI")
+	if uri == "" {
+		t.Skip("ATLAS_TEST_URI is not set")
+	}
+	t.Setenv("MONGO_TEST_URI", uri)
+	s := newTestServer(t, nil)
+	s.search = atlasSearch{index: "tasks_test"}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	_, err := s.collection.SearchIndexes().CreateOne(ctx, mongo.SearchIndexModel{
+		Options: options.SearchIndexes().SetName("tasks_test"),
+		Definition: bson.M{"mappings": bson.M{
+			"dynamic": false,
+			"fields": bson.M{
+				"title":       bson.M{"type": "string"},
+				"description": bson.M{"type": "string"},
+				"user_id":     bson.M{"type": "token"},
+				"completed":   bson.M{"type": "boolean"},
+				"is_deleted":  bson.M{"type": "boolean"},
+			},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	seedSearchTasks(t, s.collection)
+
+	for {
+		cursor, err := s.collection.SearchIndexes().List(ctx, options.SearchIndexes().SetName("tasks_test"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var indexes []struct {
+			Queryable bool `bson:"queryable"`
+		}
+		if err := cursor.All(ctx, &indexes); err != nil {
+			t.Fatal(err)
+		}
+		if len(indexes) > 0 && indexes[0].Queryable {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatal("search index did not become queryable")
+		case <-time.After(5 * time.Second):
+		}
+	}
+	// The index catches up with writes asynchronously
+	time.Sleep(5 * time.Second)
+
+	runSearchTests(t, s, true)
+}
+
+func TestFindSimilarTasksDuplicateTitles(t *testing.T) {
+	s := newTestServer(t, nil)
+	s.search = textSearch{}
+	ctx := context.Background()
+	_, err := s.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "title", Value: "text"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, task := range []struct{ userID, title string }{
+		{"u1", "Buy milk"},
+		{"u1", "buy MILK!"},
+		{"u1", "Milk: buy"},
+		{"u1", "Buy milk tomorrow"},
+		{"u1", "Buy bread"},
+		{"u1", "Call mom"},
+		{"u2", "Buy milk"},
+	} {
+		if _, err := s.CreateTask(ctx, &pb.CreateTaskRequest{UserId: task.userID, Title: task.title}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		title     string
+		threshold float32
+		want      []string
+	}{
+		{"same words in any case, order and punctuation", "Buy milk", 1,
+			[]string{"Buy milk", "Buy milk tomorrow", "Milk: buy", "buy MILK!"}},
+		{"half the words", "Buy milk", 0.5,
+			[]string{"Buy bread", "Buy milk", "Buy milk tomorrow", "Milk: buy", "buy MILK!"}},
+		{"a longer title", "Buy milk tomorrow", 1, []string{"Buy milk tomorrow"}},
+		{"most of a longer title", "Buy milk tomorrow", 0.6,
+			[]string{"Buy milk", "Buy milk tomorrow", "Milk: buy", "buy MILK!"}},
+		{"no duplicates", "Water the plants", 0.5, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := s.FindSimilarTasks(ctx, &pb.FindSimilarTasksRequest{UserId: "u1", Title: tt.title, Threshold: tt.threshold})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := titles(resp.Tasks); !slices.Equal(got, tt.want) || int(resp.Total) != len(tt.want) {
+				t.Errorf("similar to %q = %q (total %d), want %q", tt.title, got, resp.Total, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+func TestFindSimilarTasks(t *testing.T) {
+	tests := []struct {
+		name      string
+		req       *pb.FindSimilarTasksRequest
+		wantCode  codes.Code
+		wantShare float64
+	}{
+		{"default threshold", &pb.FindSimilarTasksRequest{UserId: "u1", Title: "Buy milk"}, codes.OK, 0.5},
+		{"threshold", &pb.FindSimilarTasksRequest{UserId: "u1", Title: "Buy milk", Threshold: 1}, codes.OK, 1},
+		{"no user", &pb.FindSimilarTasksRequest{Title: "Buy milk"}, codes.InvalidArgument, 0},
+		{"no words", &pb.FindSimilarTasksRequest{UserId: "u1", Title: " -- "}, codes.InvalidArgument, 0},
+		{"threshold too high", &pb.FindSimilarTasksRequest{UserId: "u1", Title: "Buy milk", Threshold: 1.5}, codes.InvalidArgument, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			search := &fakeSearch{tasks: []Task{{Title: "Buy milk"}, {Title: "Buy oat milk"}}}
+			s := &server{search: search}
+			resp, err := s.FindSimilarTasks(context.Background(), tt.req)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("code = %v, want %v", code, tt.wantCode)
+			}
+			if err != nil {
+				return
+			}
+			// Every match the backend returns is kept
+			if len(resp.Tasks) != 2 || resp.Total != 2 {
+				t.Errorf("returned %d tasks, want 2", len(resp.Tasks))
+			}
+			want := SearchFilter{UserID: "u1", MinWordShare: tt.wantShare}
+			if search.filter != want || search.query != tt.req.Title || search.limit != maxSimilarTasks {
+				t.Errorf("searched %+v for %q, limit %d", search.filter, search.query, search.limit)
+			}
+		})
+	}
+}
+
+func TestListTasksSearchFilter(t *testing.T) {
+	search := &fakeSearch{}
+	s := &server{search: search}
+	_, err := s.ListTasks(context.Background(), &pb.ListTasksRequest{
+		UserId: "u1", Completed: true, Query: "milk", CreatedAfter: "2026-01-01T00:00:00Z",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f := search.filter; f.UserID != "u1" || !f.Completed || f.CreatedAfter.IsZero() || !f.CreatedBefore.IsZero() || f.MinWordShare != 0 {
+		t.Errorf("searched %+v", f)
+	}
+
+	_, err = s.ListTasks(context.Background(), &pb.ListTasksRequest{UserId: "u1", Query: "milk", CreatedBefore: "yesterday"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("code = %v, want InvalidArgument", status.Code(err))
+	}
+}
+
+func TestRequiredWords(t *testing.T) {
+	tests := []struct {
+		share float64
+		words int
+		want  int
+	}{
+		{0, 3, 1},
+		{0.5, 3, 2},
+		{0.5, 4, 2},
+		{1, 3, 3},
+		{0.1, 1, 1},
+	}
+	for _, tt := range tests {
+		words := make([]string, tt.words)
+		if got := (SearchFilter{MinWordShare: tt.share}).requiredWords(words); got != tt.want {
+			t.Errorf("share %v of %d words = %d, want %d", tt.share, tt.words, got, tt.want)
+		}
+	}
+}
+
+func TestQueryWords(t *testing.T) {
+	got := queryWords("Buy MILK, buy eggs & 2 loaves")
+	want := []string{"buy", "milk", "eggs", "2", "loaves"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("queryWords = %q, want %q", got, want)
+	}
+}
+
+func TestAtlasSearchOperator(t *testing.T) {
+	a := atlasSearch{index: "tasks"}
+	tests := []struct {
+		name        string
+		filter      SearchFilter
+		wantShould  int
+		wantMinimum int
+		wantFilters int
+	}{
+		{"list", SearchFilter{UserID: "u1"}, 1, 1, 1},
+		{"completed", SearchFilter{UserID: "u1", Completed: true}, 1, 1, 2},
+		{"similar", SearchFilter{UserID: "u1", MinWordShare: 0.5}, 3, 2, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			op := a.operator(tt.filter, "buy oat milk")
+			if op["index"] != "tasks" {
+				t.Errorf("index = %v", op["index"])
+			}
+			compound := op["compound"].(bson.M)
+			if n := len(compound["should"].(bson.A)); n != tt.wantShould {
+				t.Errorf("%d should clauses, want %d", n, tt.wantShould)
+			}
+			if compound["minimumShouldMatch"] != tt.wantMinimum {
+				t.Errorf("minimumShouldMatch = %v, want %d", compound["minimumShouldMatch"], tt.wantMinimum)
+			}
+			filters := compound["filter"].(bson.A)
+			if len(filters) != tt.wantFilters {
+				t.Errorf("%d filter clauses, want %d", len(filters), tt.wantFilters)
+			}
+			user := filters[0].(bson.M)["equals"].(bson.M)
+			if user["path"] != "user_id" || user["value"] != tt.filter.UserID {
+				t.Errorf("user filter = %v", user)
+			}
+		})
+	}
+}
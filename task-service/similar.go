@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+const (
+	defaultSimilarityThreshold = 0.5
+	maxSimilarTasks            = 10
+)
+
+// FindSimilarTasks returns the user's tasks whose titles look like a
+// duplicate of req.Title: those matching at least the threshold's share of
+// its words, best matches first. The search backend applies the threshold,
+// so with Atlas Search the words may match fuzzily.
+func (s *server) FindSimilarTasks(ctx context.Context, req *pb.FindSimilarTasksRequest) (*pb.ListTasksResponse, error) {
+	if req.UserId == "" {
+		return nil, statusError(codes.InvalidArgument, "USER_ID_REQUIRED", nil, "user_id is required")
+	}
+	if len(queryWords(req.Title)) == 0 {
+		return nil, statusError(codes.InvalidArgument, "TITLE_REQUIRED", nil, "title is required")
+	}
+	threshold := float64(req.Threshold)
+	if threshold < 0 || threshold > 1 {
+		return nil, statusError(codes.InvalidArgument, "INVALID_THRESHOLD", nil, "threshold must be between 0 and 1, got %v", req.Threshold)
+	}
+	if threshold == 0 {
+		threshold = defaultSimilarityThreshold
+	}
+
+	filter := SearchFilter{UserID: req.UserId, MinWordShare: threshold}
+	found, _, err := s.search.Search(ctx, s.collection, filter, req.Title, 0, maxSimilarTasks)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks := make([]*pb.Task, 0, len(found))
+	for _, task := range found {
+		tasks = append(tasks, task.toProto())
+	}
+	return &pb.ListTasksResponse{Tasks: tasks, Total: int32(len(tasks))}, nil
+}
@@ -0,0 +1,137 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+func TestUpdateTaskStatus(t *testing.T) {
+	s := newTestServer(t, nil)
+	ctx := context.Background()
+
+	created, err := s.CreateTask(ctx, &pb.CreateTaskRequest{UserId: "u1", Title: "Write report"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := created.Task.Id
+	if created.Task.Status != pb.TaskStatus_TASK_STATUS_NOT_STARTED {
+		t.Fatalf("new task status = %v, want NOT_STARTED", created.Task.Status)
+	}
+
+	steps := []struct {
+		to       pb.TaskStatus
+		wantCode codes.Code
+	}{
+		{pb.TaskStatus_TASK_STATUS_ON_HOLD, codes.FailedPrecondition},
+		{pb.TaskStatus_TASK_STATUS_IN_PROGRESS, codes.OK},
+		{pb.TaskStatus_TASK_STATUS_ON_HOLD, codes.OK},
+		{pb.TaskStatus_TASK_STATUS_COMPLETED, codes.FailedPrecondition},
+		{pb.TaskStatus_TASK_STATUS_IN_PROGRESS, codes.OK},
+		// Setting the current status again changes nothing
+		{pb.TaskStatus_TASK_STATUS_IN_PROGRESS, codes.OK},
+		{pb.TaskStatus_TASK_STATUS_COMPLETED, codes.OK},
+		{pb.TaskStatus_TASK_STATUS_IN_PROGRESS, codes.FailedPrecondition},
+		{pb.TaskStatus_TASK_STATUS_NOT_STARTED, codes.OK},
+		{pb.TaskStatus_TASK_STATUS_COMPLETED, codes.OK},
+	}
+	var history []pb.TaskStatus
+	current := pb.TaskStatus_TASK_STATUS_NOT_STARTED
+	for _, step := range steps {
+		resp, err := s.UpdateTaskStatus(ctx, &pb.UpdateTaskStatusRequest{Id: id, Status: step.to})
+		if code := status.Code(err); code != step.wantCode {
+			t.Fatalf("%v to %v: code = %v, want %v", current, step.to, code, step.wantCode)
+		}
+		if err != nil {
+			continue
+		}
+		if resp.Task.Status != step.to || resp.Task.Completed != (step.to == pb.TaskStatus_TASK_STATUS_COMPLETED) {
+			t.Fatalf("%v to %v: task is %v, completed %v", current, step.to, resp.Task.Status, resp.Task.Completed)
+		}
+		if step.to != current {
+			history = append(history, current, step.to)
+			current = step.to
+		}
+	}
+
+	got, err := s.GetTask(ctx, &pb.GetTaskRequest{Id: id, SkipView: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Task.StatusHistory) != len(history)/2 {
+		t.Fatalf("history has %d changes, want %d: %v", len(got.Task.StatusHistory), len(history)/2, got.Task.StatusHistory)
+	}
+	for i, change := range got.Task.StatusHistory {
+		if change.From != history[2*i] || change.To != history[2*i+1] || change.ChangedAt == "" {
+			t.Errorf("change %d = %v, want %v to %v", i, change, history[2*i], history[2*i+1])
+		}
+	}
+}
+
+func TestUpdateTaskStatusReopenClearsCompletion(t *testing.T) {
+	s := newTestServer(t, nil)
+	ctx := context.Background()
+
+	created, err := s.CreateTask(ctx, &pb.CreateTaskRequest{UserId: "u1", Title: "Write report"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := created.Task.Id
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.UpdateTaskStatus(ctx, &pb.UpdateTaskStatusRequest{Id: id, Status: pb.TaskStatus_TASK_STATUS_COMPLETED}); err != nil {
+		t.Fatal(err)
+	}
+
+	var task Task
+	if err := s.collection.FindOne(ctx, bson.M{"_id": oid}).Decode(&task); err != nil {
+		t.Fatal(err)
+	}
+	if task.CompletedAt == "" {
+		t.Error("completing the task did not set completed_at")
+	}
+
+	if _, err := s.UpdateTaskStatus(ctx, &pb.UpdateTaskStatusRequest{Id: id, Status: pb.TaskStatus_TASK_STATUS_NOT_STARTED}); err != nil {
+		t.Fatal(err)
+	}
+	task = Task{}
+	if err := s.collection.FindOne(ctx, bson.M{"_id": oid}).Decode(&task); err != nil {
+		t.Fatal(err)
+	}
+	if task.CompletedAt != "" || task.Completed {
+		t.Errorf("reopened task still completed: completed %v at %q", task.Completed, task.CompletedAt)
+	}
+}
+
+func TestUpdateTaskStatusValidation(t *testing.T) {
+	s := newTestServer(t, nil)
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		req      *pb.UpdateTaskStatusRequest
+		wantCode codes.Code
+	}{
+		{"bad id", &pb.UpdateTaskStatusRequest{Id: "nope", Status: pb.TaskStatus_TASK_STATUS_IN_PROGRESS}, codes.InvalidArgument},
+		{"no status", &pb.UpdateTaskStatusRequest{Id: "000000000000000000000000"}, codes.InvalidArgument},
+		{"missing task", &pb.UpdateTaskStatusRequest{Id: "000000000000000000000000", Status: pb.TaskStatus_TASK_STATUS_IN_PROGRESS}, codes.NotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.UpdateTaskStatus(ctx, tt.req)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Errorf("code = %v, want %v", code, tt.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+func TestTaskStateMachineTransition(t *testing.T) {
+	const (
+		notStarted = pb.TaskStatus_TASK_STATUS_NOT_STARTED
+		inProgress = pb.TaskStatus_TASK_STATUS_IN_PROGRESS
+		onHold     = pb.TaskStatus_TASK_STATUS_ON_HOLD
+		completed  = pb.TaskStatus_TASK_STATUS_COMPLETED
+	)
+	tests := []struct {
+		from, to pb.TaskStatus
+		allowed  bool
+	}{
+		{notStarted, inProgress, true},
+		{notStarted, completed, true},
+		{notStarted, onHold, false},
+		{inProgress, onHold, true},
+		{inProgress, completed, true},
+		{inProgress, notStarted, false},
+		{onHold, inProgress, true},
+		{onHold, completed, false},
+		{onHold, notStarted, false},
+		{completed, notStarted, true},
+		{completed, inProgress, false},
+		{completed, onHold, false},
+		{notStarted, pb.TaskStatus_TASK_STATUS_UNSPECIFIED, false},
+		{pb.TaskStatus_TASK_STATUS_UNSPECIFIED, inProgress, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.from.String()+"_to_"+tt.to.String(), func(t *testing.T) {
+			err := taskStates.Transition(tt.from, tt.to)
+			if tt.allowed {
+				if err != nil {
+					t.Fatalf("Transition() = %v, want allowed", err)
+				}
+				return
+			}
+
+			if code := status.Code(err); code != codes.FailedPrecondition {
+				t.Fatalf("code = %v, want FailedPrecondition", code)
+			}
+			reason, metadata := errorInfo(err)
+			if reason != "INVALID_STATUS_TRANSITION" {
+				t.Fatalf("reason = %q, want INVALID_STATUS_TRANSITION", reason)
+			}
+			if metadata["from"] != tt.from.String() || metadata["to"] != tt.to.String() {
+				t.Errorf("metadata = %v, want from %s to %s", metadata, tt.from, tt.to)
+			}
+		})
+	}
+}
+
+func TestTaskStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		task Task
+		want pb.TaskStatus
+	}{
+		{"stored", Task{Status: "TASK_STATUS_ON_HOLD"}, pb.TaskStatus_TASK_STATUS_ON_HOLD},
+		{"stored over completed", Task{Status: "TASK_STATUS_IN_PROGRESS", Completed: true}, pb.TaskStatus_TASK_STATUS_IN_PROGRESS},
+		{"legacy open", Task{}, pb.TaskStatus_TASK_STATUS_NOT_STARTED},
+		{"legacy completed", Task{Completed: true}, pb.TaskStatus_TASK_STATUS_COMPLETED},
+		{"unspecified", Task{Status: "TASK_STATUS_UNSPECIFIED", Completed: true}, pb.TaskStatus_TASK_STATUS_COMPLETED},
+		{"unknown", Task{Status: "DONE"}, pb.TaskStatus_TASK_STATUS_NOT_STARTED},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.task.status(); got != tt.want {
+				t.Errorf("status() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"google.golang.org/grpc/codes"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// recordDeletion writes a tombstone for a deleted task. A missing tombstone
+// only means sync clients keep a stale copy, so failures are logged.
+func (s *server) recordDeletion(ctx context.Context, task Task) {
+	_, err := s.deletedCollection.InsertOne(ctx, DeletedTask{
+		TaskID:    task.publicID(),
+		UserID:    task.UserID,
+		DeletedAt: time.Now().Format(time.RFC3339),
+		TenantID:  task.TenantID,
+	})
+	if err != nil {
+		log.Printf("Failed to record deletion of task %s: %v", task.ID.Hex(), err)
+	}
+}
+
+// SyncTasks returns the changes to a user's tasks since the previous sync.
+// Timestamps have second precision, so the since boundary is inclusive:
+// clients may see a change twice but never miss one.
+func (s *server) SyncTasks(ctx context.Context, req *pb.SyncTasksRequest) (*pb.SyncTasksResponse, error) {
+	if req.UserId == "" {
+		return nil, statusError(codes.InvalidArgument, "USER_ID_REQUIRED", nil, "user_id is required")
+	}
+
+	serverTime := time.Now().Format(time.RFC3339)
+
+	// Timestamps are stored as RFC3339 strings in server local time, so the
+	// cursor is normalized the same way before comparing. created_at may
+	// have been migrated to a date, so it is compared as one.
+	since := ""
+	var sinceTime time.Time
+	if req.Since != "" {
+		t, err := time.Parse(time.RFC3339, req.Since)
+		if err != nil {
+			return nil, statusError(codes.InvalidArgument, "INVALID_SINCE", map[string]string{"since": req.Since}, "since must be an RFC3339 timestamp: %v", err)
+		}
+		since = t.In(time.Local).Format(time.RFC3339)
+		sinceTime = t
+	}
+
+	createdFilter := bson.M{"user_id": req.UserId}
+	if since != "" {
+		createdFilter["$expr"] = bson.M{"$gte": bson.A{toDate("$created_at"), sinceTime}}
+	}
+	created, err := s.findTasks(ctx, createdFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &pb.SyncTasksResponse{
+		Created:    created,
+		ServerTime: serverTime,
+	}
+
+	// A full sync has nothing to report beyond the current tasks
+	if since == "" {
+		return resp, nil
+	}
+
+	resp.Updated, err = s.findTasks(ctx, bson.M{
+		"user_id":    req.UserId,
+		"$expr":      bson.M{"$lt": bson.A{toDate("$created_at"), sinceTime}},
+		"updated_at": bson.M{"$gte": since},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := s.deletedCollection.Find(ctx, bson.M{
+		"user_id":    req.UserId,
+		"deleted_at": bson.M{"$gte": since},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var deleted DeletedTask
+		if err := cursor.Decode(&deleted); err != nil {
+			return nil, err
+		}
+		resp.DeletedIds = append(resp.DeletedIds, deleted.TaskID)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// findTasks lists the tasks matching filter that are not in the trash.
+func (s *server) findTasks(ctx context.Context, filter bson.M) ([]*pb.Task, error) {
+	filter["is_deleted"] = notDeleted
+	cursor, err := s.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var tasks []*pb.Task
+	for cursor.Next(ctx) {
+		var task Task
+		if err := cursor.Decode(&task); err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task.toProto())
+	}
+
+	return tasks, cursor.Err()
+}
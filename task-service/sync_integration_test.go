@@ -0,0 +1,117 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// nextSecond waits for the next second to start. Sync cursors have second
+// precision and are inclusive, so changes a second apart from a sync are
+// reported by one round only.
+func nextSecond() {
+	now := time.Now()
+	time.Sleep(now.Truncate(time.Second).Add(time.Second).Sub(now))
+}
+
+// titles returns the titles of tasks, sorted.
+func titles(tasks []*pb.Task) []string {
+	var titles []string
+	for _, task := range tasks {
+		titles = append(titles, task.Title)
+	}
+	slices.Sort(titles)
+	return titles
+}
+
+func TestSyncTasksRounds(t *testing.T) {
+	s := newTestServer(t, nil)
+	ctx := context.Background()
+	ids := make(map[string]string)
+	create := func(title string) {
+		t.Helper()
+		created, err := s.CreateTask(ctx, &pb.CreateTaskRequest{UserId: "u1", Title: title})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[title] = created.Task.Id
+	}
+	update := func(title string) {
+		t.Helper()
+		if _, err := s.UpdateTask(ctx, &pb.UpdateTaskRequest{Id: ids[title], Title: title, Description: "Changed"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	remove := func(title string) {
+		t.Helper()
+		if _, err := s.DeleteTask(ctx, &pb.DeleteTaskRequest{Id: ids[title]}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	create("A")
+	create("B")
+	nextSecond()
+	full, err := s.SyncTasks(ctx, &pb.SyncTasksRequest{UserId: "u1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := titles(full.Created); !slices.Equal(got, []string{"A", "B"}) {
+		t.Fatalf("full sync returned %v, want [A B]", got)
+	}
+	since := full.ServerTime
+
+	rounds := []struct {
+		name        string
+		change      func()
+		wantCreated []string
+		wantUpdated []string
+		wantDeleted []string
+	}{
+		{"create and update", func() { create("C"); update("A") }, []string{"C"}, []string{"A"}, nil},
+		{"update and delete", func() { update("C"); remove("B") }, nil, []string{"C"}, []string{"B"}},
+		{"create and delete", func() { create("D"); remove("A") }, []string{"D"}, nil, []string{"A"}},
+	}
+	for _, round := range rounds {
+		t.Run(round.name, func(t *testing.T) {
+			nextSecond()
+			round.change()
+			nextSecond()
+
+			resp, err := s.SyncTasks(ctx, &pb.SyncTasksRequest{UserId: "u1", Since: since})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := titles(resp.Created); !slices.Equal(got, round.wantCreated) {
+				t.Errorf("created %v, want %v", got, round.wantCreated)
+			}
+			if got := titles(resp.Updated); !slices.Equal(got, round.wantUpdated) {
+				t.Errorf("updated %v, want %v", got, round.wantUpdated)
+			}
+			var wantDeleted []string
+			for _, title := range round.wantDeleted {
+				wantDeleted = append(wantDeleted, ids[title])
+			}
+			if !slices.Equal(resp.DeletedIds, wantDeleted) {
+				t.Errorf("deleted %v, want %v", resp.DeletedIds, wantDeleted)
+			}
+			if resp.ServerTime <= since {
+				t.Errorf("server time %s does not advance past %s", resp.ServerTime, since)
+			}
+			since = resp.ServerTime
+		})
+	}
+
+	resp, err := s.SyncTasks(ctx, &pb.SyncTasksRequest{UserId: "u1", Since: since})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Created)+len(resp.Updated)+len(resp.DeletedIds) != 0 {
+		t.Errorf("sync with nothing changed returned %v", resp)
+	}
+}
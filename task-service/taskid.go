@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/grpc/codes"
+
+	"github.com/technonext/todo-app/proto/mongoutil"
+	"github.com/technonext/todo-app/proto/publicid"
+)
+
+// Tasks are known outside the service by their public ids. Until clients
+// have all moved over, the RPCs taking a task id also accept its ObjectID
+// hex.
+
+// newTaskPublicID returns the public id of a new task; tests replace it to
+// force collisions.
+var newTaskPublicID = func() string { return publicid.New(publicid.Task) }
+
+// publicIDAttempts is how many public ids CreateTask tries before giving
+// up. ULIDs made in the same millisecond still differ in 80 random bits, so
+// a second attempt is already unheard of.
+const publicIDAttempts = 3
+
+// publicID returns the id the API shows for the task: its ObjectID hex until
+// the backfill migration gives it a public id.
+func (t Task) publicID() string {
+	return publicid.Or(t.PublicID, t.ID)
+}
+
+func invalidTaskID(id string) error {
+	return statusError(codes.InvalidArgument, "INVALID_TASK_ID", map[string]string{"task_id": id}, "invalid task id %q", id)
+}
+
+// ensurePublicIDIndex creates the unique index of public ids, which makes
+// Insert refuse a taken one. Tasks created before public ids have none
+// until the backfill migration runs.
+func ensurePublicIDIndex(ctx context.Context, collection *mongo.Collection) error {
+	return mongoutil.EnsureIndexes(ctx, collection, mongo.IndexModel{
+		Keys: bson.D{{Key: publicid.Field, Value: 1}},
+		Options: options.Index().SetUnique(true).
+			SetPartialFilterExpression(bson.M{publicid.Field: bson.M{"$exists": true}}),
+	})
+}
+
+// taskFilter returns the query for the task id names, in either form.
+func taskFilter(id string) (bson.M, error) {
+	filter, err := publicid.Filter(publicid.Task, id)
+	if err != nil {
+		return nil, invalidTaskID(id)
+	}
+	return filter, nil
+}
+
+// taskObjectID returns the ObjectID of the task id names, in either form,
+// for the TaskRepository methods, which take ObjectIDs. Public ids cost a
+// lookup.
+func (s *server) taskObjectID(ctx context.Context, id string) (primitive.ObjectID, error) {
+	if oid, err := primitive.ObjectIDFromHex(id); err == nil {
+		return oid, nil
+	}
+	if !publicid.Valid(publicid.Task, id) {
+		return primitive.NilObjectID, invalidTaskID(id)
+	}
+	oid, err := s.repository().Resolve(ctx, id)
+	if err == errNoTask {
+		return oid, statusError(codes.NotFound, "TASK_NOT_FOUND", map[string]string{"task_id": id}, "task %s not found", id)
+	}
+	return oid, err
+}
@@ -0,0 +1,72 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+	"github.com/technonext/todo-app/proto/tenant"
+)
+
+func TestTenantsCannotSeeEachOthersTasks(t *testing.T) {
+	s := newTestServer(t, nil)
+	tenantA := tenant.WithID(context.Background(), "a")
+	tenantB := tenant.WithID(context.Background(), "b")
+
+	// Both tenants have a user u1; only tenant A's has a task
+	created, err := s.CreateTask(tenantA, &pb.CreateTaskRequest{UserId: "u1", Title: "Quarterly report"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := created.Task.Id
+
+	var stored bson.M
+	if err := s.collection.Collection.FindOne(context.Background(), bson.M{"title": "Quarterly report"}).Decode(&stored); err != nil {
+		t.Fatal(err)
+	}
+	if stored[tenant.Field] != "a" {
+		t.Fatalf("stored tenant_id = %v, want a", stored[tenant.Field])
+	}
+
+	for name, ctx := range map[string]context.Context{"tenant b": tenantB, "default tenant": tenant.WithID(context.Background(), "")} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := s.GetTask(ctx, &pb.GetTaskRequest{Id: id}); status.Code(err) != codes.NotFound {
+				t.Errorf("GetTask err = %v, want NotFound", err)
+			}
+			listed, err := s.ListTasks(ctx, &pb.ListTasksRequest{UserId: "u1"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(listed.Tasks) != 0 {
+				t.Errorf("ListTasks returned %d tasks of another tenant", len(listed.Tasks))
+			}
+			if _, err := s.UpdateTask(ctx, &pb.UpdateTaskRequest{Id: id, Title: "Taken over"}); err == nil {
+				t.Error("UpdateTask changed another tenant's task")
+			}
+			if _, err := s.DeleteTask(ctx, &pb.DeleteTaskRequest{Id: id}); status.Code(err) != codes.NotFound {
+				t.Errorf("DeleteTask err = %v, want NotFound", err)
+			}
+		})
+	}
+
+	got, err := s.GetTask(tenantA, &pb.GetTaskRequest{Id: id, SkipView: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Task.Title != "Quarterly report" {
+		t.Errorf("tenant A's task is now titled %q", got.Task.Title)
+	}
+	listed, err := s.ListTasks(tenantA, &pb.ListTasksRequest{UserId: "u1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listed.Tasks) != 1 {
+		t.Errorf("tenant A lists %d tasks, want 1", len(listed.Tasks))
+	}
+}
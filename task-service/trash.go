@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"log"
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"google.golang.org/grpc/codes"
+
+	"github.com/technonext/todo-app/proto/pagination"
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// Deleted tasks are kept in the trash, flagged is_deleted, for trashRetention
+// before the reaper removes them for good. Every other read filters them out
+// with notDeleted.
+const (
+	trashRetention = 30 * 24 * time.Hour
+	// How often the reaper looks for tasks past their retention
+	trashReapInterval = time.Hour
+	// Layout of deleted_at_display
+	deletedAtDisplayLayout = "Jan 2, 2006 at 3:04 PM MST"
+)
+
+// notDeleted matches tasks that are not in the trash, including those stored
+// before deletion was soft.
+var notDeleted = bson.M{"$ne": true}
+
+// ListDeletedTasks lists the user's trash, most recently deleted first.
+func (s *server) ListDeletedTasks(ctx context.Context, req *pb.ListDeletedTasksRequest) (*pb.ListTasksResponse, error) {
+	if req.UserId == "" {
+		return nil, statusError(codes.InvalidArgument, "USER_ID_REQUIRED", nil, "user_id is required")
+	}
+	filter := bson.M{"user_id": req.UserId, "is_deleted": true}
+
+	page, err := pagination.Resolve(req.Pagination, req.Page, req.Limit, pagination.DefaultLimits)
+	if err != nil {
+		return nil, err
+	}
+	findOptions := page.FindOptions()
+	findOptions.SetSort(bson.D{{Key: "deleted_at", Value: -1}, {Key: "_id", Value: -1}})
+
+	cursor, err := s.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	now := time.Now()
+	var tasks []*pb.Task
+	for cursor.Next(ctx) {
+		var task Task
+		if err := cursor.Decode(&task); err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task.trashProto(now))
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	count, err := s.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.ListTasksResponse{
+		Tasks:      tasks,
+		Total:      int32(count),
+		Pagination: page.Response(count, len(tasks)),
+	}, nil
+}
+
+// trashProto is toProto with the deletion details filled in.
+func (t Task) trashProto(now time.Time) *pb.Task {
+	task := t.toProto()
+	task.DeletedAt = t.DeletedAt
+	deletedAt, err := time.Parse(time.RFC3339, t.DeletedAt)
+	if err != nil {
+		return task
+	}
+	task.DeletedAtDisplay = deletedAt.Format(deletedAtDisplayLayout)
+	task.DaysUntilPermanentDeletion = daysUntilPermanentDeletion(deletedAt, now)
+	return task
+}
+
+// daysUntilPermanentDeletion counts the days, rounded up, until the reaper
+// may remove a task deleted at deletedAt.
+func daysUntilPermanentDeletion(deletedAt, now time.Time) int32 {
+	left := deletedAt.Add(trashRetention).Sub(now)
+	if left <= 0 {
+		return 0
+	}
+	return int32(math.Ceil(left.Hours() / 24))
+}
+
+// startTrashReaper removes tasks whose trash retention is over, once at
+// startup and then every trashReapInterval, until ctx ends.
+func (s *server) startTrashReaper(ctx context.Context) {
+	ticker := time.NewTicker(trashReapInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.reapTrash(ctx); err != nil {
+			log.Printf("Failed to empty task trash: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// reapTrash deletes tasks deleted before the retention cutoff. deleted_at is
+// an RFC3339 string in the server's zone, so the cutoff is formatted the same
+// way and compared as text.
+func (s *server) reapTrash(ctx context.Context) error {
+	cutoff := time.Now().Add(-trashRetention).Format(time.RFC3339)
+	result, err := s.collection.DeleteMany(ctx, bson.M{
+		"is_deleted": true,
+		"deleted_at": bson.M{"$lt": cutoff},
+	})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount > 0 {
+		log.Printf("Removed %d tasks from the trash", result.DeletedCount)
+	}
+	return nil
+}
+
+// BatchDeleteTasks permanently deletes all of the user's tasks, trashed ones
+// included, along with the tombstones of earlier deletions. It is meant for
+// erasing a user; sync clients are not told about these deletions.
+func (s *server) BatchDeleteTasks(ctx context.Context, req *pb.BatchDeleteTasksRequest) (*pb.BatchDeleteTasksResponse, error) {
+	if req.UserId == "" {
+		return nil, statusError(codes.InvalidArgument, "USER_ID_REQUIRED", nil, "user_id is required")
+	}
+
+	result, err := s.collection.DeleteMany(ctx, bson.M{"user_id": req.UserId})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.deletedCollection.DeleteMany(ctx, bson.M{"user_id": req.UserId}); err != nil {
+		return nil, err
+	}
+	log.Printf("Permanently deleted %d tasks of user %s", result.DeletedCount, req.UserId)
+	return &pb.BatchDeleteTasksResponse{DeletedCount: int32(result.DeletedCount)}, nil
+}
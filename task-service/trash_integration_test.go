@@ -0,0 +1,107 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+func TestListDeletedTasks(t *testing.T) {
+	s := newTestServer(t, nil)
+	ctx := context.Background()
+
+	var ids []string
+	for _, title := range []string{"First", "Second", "Kept"} {
+		created, err := s.CreateTask(ctx, &pb.CreateTaskRequest{UserId: "u1", Title: title})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, created.Task.Id)
+	}
+	other, err := s.CreateTask(ctx, &pb.CreateTaskRequest{UserId: "u2", Title: "Someone else's"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{ids[0], ids[1], other.Task.Id} {
+		if _, err := s.DeleteTask(ctx, &pb.DeleteTaskRequest{Id: id}); err != nil {
+			t.Fatal(err)
+		}
+		// deleted_at has whole seconds, so space the deletions to order them
+		time.Sleep(1100 * time.Millisecond)
+	}
+
+	tests := []struct {
+		name      string
+		req       *pb.ListDeletedTasksRequest
+		wantIDs   []string
+		wantTotal int32
+	}{
+		{"whole trash, latest first", &pb.ListDeletedTasksRequest{UserId: "u1"}, []string{ids[1], ids[0]}, 2},
+		{"first page", &pb.ListDeletedTasksRequest{UserId: "u1", Limit: 1}, []string{ids[1]}, 2},
+		{"second page", &pb.ListDeletedTasksRequest{UserId: "u1", Page: 1, Limit: 1}, []string{ids[0]}, 2},
+		{"other user", &pb.ListDeletedTasksRequest{UserId: "u2"}, []string{other.Task.Id}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := s.ListDeletedTasks(ctx, tt.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.Total != tt.wantTotal || len(resp.Tasks) != len(tt.wantIDs) {
+				t.Fatalf("total, listed = %d, %d, want %d, %d", resp.Total, len(resp.Tasks), tt.wantTotal, len(tt.wantIDs))
+			}
+			for i, task := range resp.Tasks {
+				if task.Id != tt.wantIDs[i] {
+					t.Errorf("task %d = %s, want %s", i, task.Id, tt.wantIDs[i])
+				}
+				if task.DaysUntilPermanentDeletion != 30 || task.DeletedAtDisplay == "" {
+					t.Errorf("task %s: days left, display = %d, %q", task.Id, task.DaysUntilPermanentDeletion, task.DeletedAtDisplay)
+				}
+			}
+		})
+	}
+
+	// The trash is not listed with the live tasks
+	live, err := s.ListTasks(ctx, &pb.ListTasksRequest{UserId: "u1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(live.Tasks) != 1 || live.Tasks[0].Id != ids[2] {
+		t.Errorf("live tasks = %v, want only %s", live.Tasks, ids[2])
+	}
+}
+
+func TestReapTrash(t *testing.T) {
+	s := newTestServer(t, nil)
+	ctx := context.Background()
+
+	deleted := func(age time.Duration) bson.M {
+		return bson.M{"user_id": "u1", "title": age.String(), "is_deleted": true, "deleted_at": time.Now().Add(-age).Format(time.RFC3339)}
+	}
+	_, err := s.collection.InsertMany(ctx, []interface{}{
+		deleted(trashRetention + time.Hour),
+		deleted(trashRetention - time.Hour),
+		deleted(time.Hour),
+		bson.M{"user_id": "u1", "title": "live"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.reapTrash(ctx); err != nil {
+		t.Fatal(err)
+	}
+	left, err := s.collection.CountDocuments(ctx, bson.M{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if left != 3 {
+		t.Errorf("%d tasks left, want 3: only the one past retention is removed", left)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDaysUntilPermanentDeletion(t *testing.T) {
+	deletedAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		now  time.Time
+		want int32
+	}{
+		{"just deleted", deletedAt, 30},
+		{"an hour later", deletedAt.Add(time.Hour), 30},
+		{"a day later", deletedAt.AddDate(0, 0, 1), 29},
+		{"a day and a minute later", deletedAt.AddDate(0, 0, 1).Add(time.Minute), 29},
+		{"a minute left", deletedAt.Add(trashRetention - time.Minute), 1},
+		{"retention over", deletedAt.Add(trashRetention), 0},
+		{"long past", deletedAt.AddDate(0, 2, 0), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := daysUntilPermanentDeletion(deletedAt, tt.now); got != tt.want {
+				t.Errorf("days left = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrashProto(t *testing.T) {
+	now := time.Date(2026, 3, 11, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		deletedAt   string
+		wantDisplay string
+		wantDays    int32
+	}{
+		{"utc", "2026-03-01T12:00:00Z", "Mar 1, 2026 at 12:00 PM UTC", 20},
+		{"offset kept", "2026-03-01T09:30:00+06:00", "Mar 1, 2026 at 9:30 AM +0600", 20},
+		{"unparseable", "yesterday", "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := Task{Title: "Old", IsDeleted: true, DeletedAt: tt.deletedAt}.trashProto(now)
+			if task.DeletedAt != tt.deletedAt {
+				t.Errorf("deleted_at = %q, want %q", task.DeletedAt, tt.deletedAt)
+			}
+			if task.DeletedAtDisplay != tt.wantDisplay || task.DaysUntilPermanentDeletion != tt.wantDays {
+				t.Errorf("display, days = %q, %d, want %q, %d", task.DeletedAtDisplay, task.DaysUntilPermanentDeletion, tt.wantDisplay, tt.wantDays)
+			}
+		})
+	}
+}
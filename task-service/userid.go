@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/technonext/todo-app/proto/callctx"
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// creationMethods are the calls creating something a user owns, whose
+// request names the owner in user_id.
+var creationMethods = map[string]bool{
+	pb.TaskService_CreateTask_FullMethodName: true,
+}
+
+// loadStrictUserID reads STRICT_USER_ID, false unless set.
+func loadStrictUserID() (bool, error) {
+	value := getEnv("STRICT_USER_ID", "false")
+	strict, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid STRICT_USER_ID %q", os.Getenv("STRICT_USER_ID"))
+	}
+	return strict, nil
+}
+
+// userIDEnforcementInterceptor makes the authenticated user, sent by the
+// gateway in the user-id metadata, the owner of what creation calls create,
+// whatever user_id the request names; clients can leave it out. With strict
+// set a request naming someone else is refused instead. Calls without the
+// metadata, such as those from other services, are left alone.
+func userIDEnforcementInterceptor(strict bool) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		userID := callctx.CallerFrom(ctx).UserID
+		msg, ok := req.(proto.Message)
+		if userID == "" || !ok || !creationMethods[info.FullMethod] {
+			return handler(ctx, req)
+		}
+		m := msg.ProtoReflect()
+		field := m.Descriptor().Fields().ByName("user_id")
+		if field == nil || field.Kind() != protoreflect.StringKind {
+			return handler(ctx, req)
+		}
+
+		if requested := m.Get(field).String(); strict && requested != "" && requested != userID {
+			return nil, statusError(codes.PermissionDenied, "USER_ID_MISMATCH", map[string]string{"user_id": requested},
+				"user_id %s is not the authenticated user", requested)
+		}
+		m.Set(field, protoreflect.ValueOfString(userID))
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/technonext/todo-app/proto/callctx"
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+func TestUserIDEnforcementInterceptor(t *testing.T) {
+	createTask := &grpc.UnaryServerInfo{FullMethod: pb.TaskService_CreateTask_FullMethodName}
+	tests := []struct {
+		name      string
+		strict    bool
+		metadata  string
+		requested string
+		info      *grpc.UnaryServerInfo
+		want      string
+		code      codes.Code
+	}{
+		{name: "missing user_id is filled in", metadata: "u1", want: "u1"},
+		{name: "matching user_id", metadata: "u1", requested: "u1", want: "u1"},
+		{name: "matching user_id when strict", strict: true, metadata: "u1", requested: "u1", want: "u1"},
+		{name: "other user_id is overridden", metadata: "u1", requested: "u2", want: "u1"},
+		{name: "other user_id is refused when strict", strict: true, metadata: "u1", requested: "u2", code: codes.PermissionDenied},
+		{name: "missing user_id is filled in when strict", strict: true, metadata: "u1", want: "u1"},
+		{name: "calls without metadata are left alone", strict: true, requested: "u2", want: "u2"},
+		{
+			name:      "other calls are left alone",
+			strict:    true,
+			metadata:  "u1",
+			requested: "u2",
+			info:      &grpc.UnaryServerInfo{FullMethod: pb.TaskService_ListTasks_FullMethodName},
+			want:      "u2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.metadata != "" {
+				ctx = callctx.WithCaller(ctx, callctx.Caller{UserID: tt.metadata})
+			}
+			info := tt.info
+			if info == nil {
+				info = createTask
+			}
+			req := &pb.CreateTaskRequest{Title: "Report", UserId: tt.requested}
+
+			var got string
+			_, err := userIDEnforcementInterceptor(tt.strict)(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+				got = req.(*pb.CreateTaskRequest).UserId
+				return nil, nil
+			})
+			if status.Code(err) != tt.code {
+				t.Fatalf("got %v, want %v", err, tt.code)
+			}
+			if got != tt.want {
+				t.Errorf("handler saw user_id %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
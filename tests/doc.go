@@ -0,0 +1,13 @@
+// Package tests runs the whole application end to end: the four gRPC
+// services and the gateway are built from this repository and started as
+// processes against MongoDB, and the tests talk to the gateway over HTTP.
+//
+// The suite needs Docker to start a MongoDB container, or MONGO_TEST_URI
+// pointing at a server to use instead; without either it is skipped. Run it
+// from this directory with
+//
+//	go test -tags integration ./...
+//
+// Every test starts its own stack on free ports with a database of its own,
+// so tests run in parallel without seeing each other's data.
+package tests
@@ -0,0 +1,32 @@
+//go:build integration
+
+package tests
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestGatewayRetriesInjectedFaults(t *testing.T) {
+	t.Parallel()
+	// Half the GetTask calls fail as if the task service were unreachable
+	s := startStack(t,
+		"FAULTS_ENABLED=true",
+		`FAULTS=[{"method":"/todo.TaskService/GetTask","percent":50,"code":"UNAVAILABLE"}]`,
+	)
+
+	owner := s.signUp(t, "chaos", "flaky network")
+	id := s.createTask(t, owner.ID, "Survive the faults").ID
+
+	const requests = 40
+	ok := 0
+	for i := 0; i < requests; i++ {
+		if s.call(t, http.MethodGet, "/api/tasks/"+id, nil, nil) == http.StatusOK {
+			ok++
+		}
+	}
+	// The gateway tries each call three times, so about one in eight fails
+	if ok < requests*3/4 {
+		t.Errorf("%d of %d requests succeeded, want most of them", ok, requests)
+	}
+}
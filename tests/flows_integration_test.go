@@ -0,0 +1,229 @@
+//go:build integration
+
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// The gateway answers with the gRPC messages encoded as JSON; these hold the
+// fields the tests look at.
+type user struct {
+	ID    string `json:"id"`
+	Email string `json:"email"`
+}
+
+type task struct {
+	ID        string `json:"id"`
+	Title     string `json:"title"`
+	Completed bool   `json:"completed"`
+}
+
+type taskList struct {
+	Tasks []task `json:"tasks"`
+	Total int32  `json:"total"`
+}
+
+// signUp creates a user through the gateway, with password as its password.
+func (s *stack) signUp(t *testing.T, username, password string) user {
+	t.Helper()
+	var resp struct {
+		User user `json:"user"`
+	}
+	body := map[string]string{"username": username, "email": username + "@example.com", "password": password}
+	if code := s.call(t, http.MethodPost, "/api/users", body, &resp); code != http.StatusCreated {
+		t.Fatalf("sign up %s: status %d", username, code)
+	}
+	return resp.User
+}
+
+// createTask creates a task for the user through the gateway.
+func (s *stack) createTask(t *testing.T, userID, title string) task {
+	t.Helper()
+	var resp struct {
+		Task task `json:"task"`
+	}
+	body := map[string]string{"user_id": userID, "title": title}
+	if code := s.call(t, http.MethodPost, "/api/tasks", body, &resp); code != http.StatusCreated {
+		t.Fatalf("create task %q: status %d", title, code)
+	}
+	return resp.Task
+}
+
+// seedTasks creates n tasks for the user and returns their ids.
+func (s *stack) seedTasks(t *testing.T, userID string, n int) []string {
+	t.Helper()
+	ids := make([]string, 0, n)
+	for i := 1; i <= n; i++ {
+		ids = append(ids, s.createTask(t, userID, fmt.Sprintf("Seeded task %d", i)).ID)
+	}
+	return ids
+}
+
+// listTasks lists tasks through the gateway, which matches query keys
+// against the request's Go field names.
+func (s *stack) listTasks(t *testing.T, query url.Values) taskList {
+	t.Helper()
+	var list taskList
+	if code := s.call(t, http.MethodGet, "/api/tasks?"+query.Encode(), nil, &list); code != http.StatusOK {
+		t.Fatalf("list tasks %v: status %d", query, code)
+	}
+	return list
+}
+
+func TestCoreFlow(t *testing.T) {
+	t.Parallel()
+	s := startStack(t)
+
+	alice := s.signUp(t, "alice", "correct horse")
+
+	var auth struct {
+		Token string `json:"token"`
+		User  user   `json:"user"`
+	}
+	code := s.call(t, http.MethodPost, "/api/auth", map[string]string{"email": alice.Email, "password": "correct horse"}, &auth)
+	if code != http.StatusOK || auth.Token == "" || auth.User.ID != alice.ID {
+		t.Fatalf("auth: status %d, token %q, user %q", code, auth.Token, auth.User.ID)
+	}
+	if code := s.call(t, http.MethodPost, "/api/auth", map[string]string{"email": alice.Email, "password": "wrong"}, nil); code != http.StatusUnauthorized {
+		t.Errorf("auth with a wrong password: status %d, want 401", code)
+	}
+
+	report := s.createTask(t, alice.ID, "Write report")
+	s.createTask(t, alice.ID, "Buy milk")
+	// Another user's tasks never show up in alice's lists
+	bob := s.signUp(t, "bob", "battery staple")
+	s.createTask(t, bob.ID, "Bob's task")
+
+	if list := s.listTasks(t, url.Values{"UserId": {alice.ID}, "Limit": {"10"}}); list.Total != 2 || len(list.Tasks) != 2 {
+		t.Fatalf("alice has %d tasks listed of %d, want 2", len(list.Tasks), list.Total)
+	}
+
+	var updated struct {
+		Task task `json:"task"`
+	}
+	if code := s.call(t, http.MethodPut, "/api/tasks/"+report.ID+"/status", map[string]string{"status": "completed"}, &updated); code != http.StatusOK {
+		t.Fatalf("complete task: status %d", code)
+	}
+	if !updated.Task.Completed {
+		t.Error("completed task is not marked completed")
+	}
+
+	done := s.listTasks(t, url.Values{"UserId": {alice.ID}, "Completed": {"true"}, "Limit": {"10"}})
+	if done.Total != 1 || len(done.Tasks) != 1 || done.Tasks[0].ID != report.ID {
+		t.Errorf("completed tasks = %+v, want only %s", done, report.ID)
+	}
+
+	// Analytics events are tracked in the background
+	eventually(t, 15*time.Second, func() error {
+		var resp struct {
+			Stats struct {
+				TotalTasks     int32 `json:"total_tasks"`
+				CompletedTasks int32 `json:"completed_tasks"`
+			} `json:"stats"`
+		}
+		if code := s.call(t, http.MethodGet, "/api/analytics/users/"+alice.ID+"/stats?Fresh=true", nil, &resp); code != http.StatusOK {
+			return fmt.Errorf("stats: status %d", code)
+		}
+		if resp.Stats.TotalTasks != 2 || resp.Stats.CompletedTasks != 1 {
+			return fmt.Errorf("stats = %+v, want 2 tasks with 1 completed", resp.Stats)
+		}
+		return nil
+	})
+
+	if code := s.call(t, http.MethodPost, "/api/notifications", map[string]string{"user_id": alice.ID, "message": "Report sent"}, nil); code >= 300 {
+		t.Fatalf("send notification: status %d", code)
+	}
+	var notifications struct {
+		Notifications []struct {
+			Message string `json:"message"`
+			Read    bool   `json:"read"`
+		} `json:"notifications"`
+		Total int32 `json:"total"`
+	}
+	if code := s.call(t, http.MethodGet, "/api/notifications?UserId="+alice.ID+"&Limit=10", nil, &notifications); code != http.StatusOK {
+		t.Fatalf("get notifications: status %d", code)
+	}
+	if notifications.Total != 1 || notifications.Notifications[0].Message != "Report sent" || notifications.Notifications[0].Read {
+		t.Errorf("notifications = %+v, want the unread one sent", notifications)
+	}
+}
+
+func TestListTasksPagination(t *testing.T) {
+	t.Parallel()
+	s := startStack(t)
+
+	owner := s.signUp(t, "pager", "page by page")
+	seeded := s.seedTasks(t, owner.ID, 7)
+	want := make(map[string]bool)
+	for _, id := range seeded {
+		want[id] = true
+	}
+
+	// Pages start at 0; every task is on exactly one page
+	seen := make(map[string]bool)
+	for page, wantLen := range []int{3, 3, 1, 0} {
+		list := s.listTasks(t, url.Values{"UserId": {owner.ID}, "Page": {fmt.Sprint(page)}, "Limit": {"3"}})
+		if list.Total != 7 {
+			t.Errorf("page %d total = %d, want 7", page, list.Total)
+		}
+		if len(list.Tasks) != wantLen {
+			t.Fatalf("page %d has %d tasks, want %d", page, len(list.Tasks), wantLen)
+		}
+		for _, task := range list.Tasks {
+			if !want[task.ID] {
+				t.Errorf("page %d lists %s, which was not seeded", page, task.ID)
+			}
+			if seen[task.ID] {
+				t.Errorf("page %d repeats %s", page, task.ID)
+			}
+			seen[task.ID] = true
+		}
+	}
+	if len(seen) != len(seeded) {
+		t.Errorf("pages list %d of the %d tasks", len(seen), len(seeded))
+	}
+}
+
+func TestCompletingTaskRecordsEvent(t *testing.T) {
+	t.Parallel()
+	s := startStack(t)
+
+	owner := s.signUp(t, "finisher", "done and dusted")
+	report := s.createTask(t, owner.ID, "Write report")
+
+	var updated struct {
+		Task task `json:"task"`
+	}
+	body := map[string]interface{}{"title": report.Title, "completed": true}
+	if code := s.call(t, http.MethodPut, "/api/tasks/"+report.ID, body, &updated); code != http.StatusOK {
+		t.Fatalf("complete task: status %d", code)
+	}
+	if !updated.Task.Completed {
+		t.Fatal("updated task is not marked completed")
+	}
+
+	// The task service tracks the event in the background; analytics
+	// stores it in its events collection
+	events := s.db.Collection("events")
+	eventually(t, 15*time.Second, func() error {
+		var event struct {
+			UserID string `bson:"user_id"`
+		}
+		err := events.FindOne(context.Background(), bson.M{"event_type": "task.completed", "resource_id": report.ID}).Decode(&event)
+		if err != nil {
+			return fmt.Errorf("find task.completed event for %s: %v", report.ID, err)
+		}
+		if event.UserID != owner.ID {
+			return fmt.Errorf("task.completed event is for user %s, want %s", event.UserID, owner.ID)
+		}
+		return nil
+	})
+}
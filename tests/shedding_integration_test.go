@@ -0,0 +1,79 @@
+//go:build integration
+
+package tests
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSaturatedServiceSheds429s(t *testing.T) {
+	t.Parallel()
+	// Each service handles two calls at a time, and every GetTask takes half
+	// a second, so a burst of reads saturates the task service
+	s := startStack(t,
+		"GRPC_MAX_IN_FLIGHT=2",
+		"FAULTS_ENABLED=true",
+		`FAULTS=[{"method":"/todo.TaskService/GetTask","percent":100,"latency_ms":500}]`,
+	)
+
+	owner := s.signUp(t, "burst", "load spike")
+	id := s.createTask(t, owner.ID, "Weather the spike").ID
+
+	const requests = 30
+	client := &http.Client{Timeout: 10 * time.Second}
+	var (
+		mu         sync.Mutex
+		codes      = make(map[int]int)
+		retryAfter = true
+		slowest429 time.Duration
+		failures   []error
+	)
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			resp, err := client.Get(s.gateway + "/api/tasks/" + id)
+			took := time.Since(start)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				failures = append(failures, err)
+				return
+			}
+			resp.Body.Close()
+			codes[resp.StatusCode]++
+			if resp.StatusCode == http.StatusTooManyRequests {
+				retryAfter = retryAfter && resp.Header.Get("Retry-After") != ""
+				if took > slowest429 {
+					slowest429 = took
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Every request is answered: the ones over the cap at once with 429,
+	// rather than queueing until they time out
+	if len(failures) > 0 {
+		t.Fatalf("%d requests failed, the first with %v", len(failures), failures[0])
+	}
+	if codes[http.StatusOK] == 0 || codes[http.StatusTooManyRequests] == 0 {
+		t.Errorf("status codes = %v, want both 200s and 429s", codes)
+	}
+	if codes[http.StatusOK]+codes[http.StatusTooManyRequests] != requests {
+		t.Errorf("status codes = %v, want only 200s and 429s", codes)
+	}
+	if !retryAfter {
+		t.Error("a 429 came without Retry-After")
+	}
+	// A refused call never waits for a slot, so it is answered before the
+	// calls holding the slots are
+	if slowest429 >= 500*time.Millisecond {
+		t.Errorf("slowest 429 took %s, want it answered without waiting for a slot", slowest429)
+	}
+}
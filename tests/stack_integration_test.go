@@ -0,0 +1,328 @@
+//go:build integration
+
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// The image docker-compose.yml runs
+const mongoImage = "mongo:5.0"
+
+// How long a stack gets to start answering
+const startTimeout = time.Minute
+
+// The gRPC services, each started with PORT set and the others' addresses
+// in <NAME>_SERVICE_ADDR
+var services = []string{"task-service", "user-service", "notification-service", "analytics-service"}
+
+var (
+	// mongoURI is the server every stack uses, each with its own database
+	mongoURI    string
+	mongoClient *mongo.Client
+	// binaries holds the built services and gateway by name
+	binaries = make(map[string]string)
+	// skipReason is set when there is no MongoDB to run against
+	skipReason string
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(run(m))
+}
+
+func run(m *testing.M) int {
+	uri, stop, err := startMongo()
+	if err != nil {
+		skipReason = err.Error()
+		return m.Run()
+	}
+	defer stop()
+	mongoURI = uri
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	mongoClient, err = mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		log.Printf("Failed to connect to MongoDB at %s: %v", uri, err)
+		return 1
+	}
+	defer mongoClient.Disconnect(context.Background())
+
+	dir, err := os.MkdirTemp("", "todo-e2e")
+	if err != nil {
+		log.Print(err)
+		return 1
+	}
+	defer os.RemoveAll(dir)
+	for _, name := range append([]string{"api-gateway"}, services...) {
+		binary := filepath.Join(dir, name)
+		build := exec.Command("go", "build", "-o", binary, ".")
+		build.Dir = filepath.Join("..", name)
+		if out, err := build.CombinedOutput(); err != nil {
+			log.Printf("Failed to build %s: %v\n%s", name, err, out)
+			return 1
+		}
+		binaries[name] = binary
+	}
+
+	return m.Run()
+}
+
+// startMongo returns MONGO_TEST_URI when it is set, and otherwise starts a
+// MongoDB container on a free local port, removed by stop.
+func startMongo() (uri string, stop func(), err error) {
+	if uri := os.Getenv("MONGO_TEST_URI"); uri != "" {
+		return uri, func() {}, nil
+	}
+	if _, err := exec.LookPath("docker"); err != nil {
+		return "", nil, errors.New("MONGO_TEST_URI is not set and docker is not available")
+	}
+
+	out, err := exec.Command("docker", "run", "-d", "--rm", "-p", "127.0.0.1::27017", mongoImage).Output()
+	if err != nil {
+		return "", nil, fmt.Errorf("start %s container: %w", mongoImage, err)
+	}
+	id := strings.TrimSpace(string(out))
+	stop = func() { exec.Command("docker", "rm", "-f", id).Run() }
+
+	out, err = exec.Command("docker", "port", id, "27017/tcp").Output()
+	if err != nil {
+		stop()
+		return "", nil, fmt.Errorf("find the MongoDB container's port: %w", err)
+	}
+	addr := strings.TrimSpace(strings.SplitN(string(out), "\n", 2)[0])
+	uri = "mongodb://" + addr
+
+	// The port is open before mongod accepts connections
+	ctx, cancel := context.WithTimeout(context.Background(), startTimeout)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		stop()
+		return "", nil, err
+	}
+	defer client.Disconnect(context.Background())
+	for {
+		if err = client.Ping(ctx, nil); err == nil {
+			return uri, stop, nil
+		}
+		select {
+		case <-ctx.Done():
+			stop()
+			return "", nil, fmt.Errorf("MongoDB container did not start: %w", err)
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
+
+// stack is the application started for one test.
+type stack struct {
+	// gateway is the base URL of the API gateway
+	gateway string
+	// db is the test's own database, dropped when it ends
+	db *mongo.Database
+}
+
+var unsafeDBChars = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+// databaseName returns a database name unique to the test.
+func databaseName(t *testing.T) string {
+	name := unsafeDBChars.ReplaceAllString(t.Name(), "_")
+	if len(name) > 40 {
+		name = name[:40]
+	}
+	return fmt.Sprintf("e2e_%s_%d", name, time.Now().UnixNano())
+}
+
+// startStack starts the services and the gateway for t, against a database
+// of its own, and stops them when t ends. Every process also gets extraEnv.
+// The output of every process is logged when t fails.
+func startStack(t *testing.T, extraEnv ...string) *stack {
+	t.Helper()
+	if skipReason != "" {
+		t.Skip(skipReason)
+	}
+
+	dbName := databaseName(t)
+	db := mongoClient.Database(dbName)
+	t.Cleanup(func() { db.Drop(context.Background()) })
+
+	ports := make(map[string]string)
+	for _, name := range append([]string{"api-gateway"}, services...) {
+		ports[name] = freePort(t)
+	}
+	env := append(os.Environ(),
+		"MONGO_URI="+mongoURI,
+		"MONGO_DATABASE="+dbName,
+		"MONGO_CONNECT_ATTEMPTS=3",
+		"EVENT_BROKER=",
+	)
+	env = append(env, extraEnv...)
+	for _, name := range services {
+		key := strings.ToUpper(strings.TrimSuffix(name, "-service")) + "_SERVICE_ADDR"
+		env = append(env, key+"=localhost:"+ports[name])
+	}
+
+	for _, name := range append(services, "api-gateway") {
+		startProcess(t, name, append(env, "PORT="+ports[name]))
+	}
+
+	s := &stack{gateway: "http://localhost:" + ports["api-gateway"], db: db}
+	deadline := time.Now().Add(startTimeout)
+	for _, name := range services {
+		waitFor(t, deadline, name, func() error {
+			conn, err := net.DialTimeout("tcp", "localhost:"+ports[name], time.Second)
+			if err == nil {
+				conn.Close()
+			}
+			return err
+		})
+	}
+	waitFor(t, deadline, "api-gateway", func() error {
+		resp, err := http.Get(s.gateway + "/health")
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("health check returned %s", resp.Status)
+		}
+		return nil
+	})
+	return s
+}
+
+// freePort returns a local port nothing is listening on.
+func freePort(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+	return port
+}
+
+// syncBuffer collects a process's output while it runs.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func startProcess(t *testing.T, name string, env []string) {
+	t.Helper()
+	var output syncBuffer
+	cmd := exec.Command(binaries[name])
+	cmd.Env = env
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("start %s: %v", name, err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+		if t.Failed() {
+			t.Logf("%s output:\n%s", name, output.String())
+		}
+	})
+}
+
+// waitFor retries ready until it succeeds, failing t at deadline.
+func waitFor(t *testing.T, deadline time.Time, name string, ready func() error) {
+	t.Helper()
+	for {
+		err := ready()
+		if err == nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s did not start: %v", name, err)
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
+
+// eventually retries check until it passes or timeout runs out, for results
+// the services produce in the background.
+func eventually(t *testing.T, timeout time.Duration, check func() error) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for {
+		err := check()
+		if err == nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal(err)
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+}
+
+// call sends body as JSON to the gateway and decodes the response into out
+// when it is not nil, returning the status code.
+func (s *stack) call(t *testing.T, method, path string, body, out interface{}) int {
+	t.Helper()
+	var reqBody bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&reqBody).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req, err := http.NewRequest(method, s.gateway+path, &reqBody)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	var raw bytes.Buffer
+	if _, err := raw.ReadFrom(resp.Body); err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	if out != nil && resp.StatusCode < 300 {
+		if err := json.Unmarshal(raw.Bytes(), out); err != nil {
+			t.Fatalf("%s %s: decode %q: %v", method, path, raw.String(), err)
+		}
+	}
+	if resp.StatusCode >= 300 {
+		t.Logf("%s %s: %s %s", method, path, resp.Status, raw.String())
+	}
+	return resp.StatusCode
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/grpc/codes"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+const defaultActivityDays = 30
+
+// GetUserActivityStats summarises how often the user logged in recently.
+func (s *server) GetUserActivityStats(ctx context.Context, req *pb.GetUserActivityStatsRequest) (*pb.GetUserActivityStatsResponse, error) {
+	objectID, err := primitive.ObjectIDFromHex(req.UserId)
+	if err != nil {
+		return nil, statusError(codes.InvalidArgument, "INVALID_USER_ID", map[string]string{"user_id": req.UserId}, "invalid user_id")
+	}
+	days := req.Days
+	if days <= 0 {
+		days = defaultActivityDays
+	}
+
+	var user User
+	err = s.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return nil, statusError(codes.NotFound, "USER_NOT_FOUND", map[string]string{"user_id": req.UserId}, "user %s not found", req.UserId)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	since := time.Now().AddDate(0, 0, -int(days))
+	cursor, err := s.sessions.Find(ctx,
+		bson.M{"user_id": req.UserId, "created_at": bson.M{"$gte": createdAtLowerBound(since)}},
+		options.Find().SetProjection(bson.M{"created_at": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	resp := &pb.GetUserActivityStatsResponse{LastSeenAt: user.LastSeenAt}
+	activeDays := make(map[string]bool)
+	for cursor.Next(ctx) {
+		var session Session
+		if err := cursor.Decode(&session); err != nil {
+			return nil, err
+		}
+		createdAt, err := time.Parse(time.RFC3339, session.CreatedAt)
+		if err != nil || createdAt.Before(since) {
+			continue
+		}
+		resp.Sessions++
+		activeDays[createdAt.Local().Format("2006-01-02")] = true
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	resp.ActiveDays = int32(len(activeDays))
+	return resp, nil
+}
+
+// createdAtLowerBound is a string no later than how any time at or after
+// since is written in created_at. Sessions are stamped in the server's local
+// offset, which shifts the written time by at most 12 hours back, so
+// comparing as strings against since less 12 hours keeps every session in the
+// window and lets the (user_id, created_at) index skip older ones. The exact
+// cutoff is applied after decoding.
+func createdAtLowerBound(since time.Time) string {
+	return since.UTC().Add(-12 * time.Hour).Format("2006-01-02T15:04:05")
+}
+
+// GetUserCounts counts all users and those who signed up since req.Since,
+// or all of them when it is empty.
+func (s *server) GetUserCounts(ctx context.Context, req *pb.GetUserCountsRequest) (*pb.GetUserCountsResponse, error) {
+	filter := bson.M{}
+	if req.Since != "" {
+		since, err := time.Parse(time.RFC3339, req.Since)
+		if err != nil {
+			return nil, statusError(codes.InvalidArgument, "INVALID_SINCE", map[string]string{"since": req.Since}, "invalid since %q: use RFC3339", req.Since)
+		}
+		createdAt := bson.M{"$dateFromString": bson.M{"dateString": "$created_at", "onError": nil, "onNull": nil}}
+		filter["$expr"] = bson.M{"$gte": bson.A{createdAt, since}}
+	}
+
+	// Counted rather than estimated, so only the caller's tenant is counted
+	total, err := s.collection.CountDocuments(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	signups, err := s.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.GetUserCountsResponse{Total: total, Signups: signups}, nil
+}
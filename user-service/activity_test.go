@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCreatedAtLowerBound(t *testing.T) {
+	since := time.Date(2026, 3, 10, 6, 0, 0, 0, time.UTC)
+	bound := createdAtLowerBound(since)
+	tests := []struct {
+		name    string
+		at      time.Time
+		inRange bool
+	}{
+		{"since in UTC", since, true},
+		{"since at UTC-12", since.In(time.FixedZone("", -12*3600)), true},
+		{"since at UTC+14", since.In(time.FixedZone("", 14*3600)), true},
+		{"a day before", since.AddDate(0, 0, -1), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			createdAt := tt.at.Format(time.RFC3339)
+			if got := createdAt >= bound; got != tt.inRange {
+				t.Errorf("%q >= %q = %v, want %v", createdAt, bound, got, tt.inRange)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/technonext/todo-app/proto/mongoutil"
+	"github.com/technonext/todo-app/proto/settings"
+)
+
+// Bounds of BCRYPT_COST: below 10 hashes are quick to crack, above 14 each
+// login takes a second or more
+const (
+	minBcryptCost = 10
+	maxBcryptCost = 14
+)
+
+// Storage drivers, chosen with STORAGE_DRIVER
+const (
+	storageMongo = settings.StorageMongo
+	// Users held in memory, for local development
+	storageMemory = "memory"
+)
+
+// minJWTSecretLen is the shortest JWT_SECRET accepted, 256 bits for HS256.
+const minJWTSecretLen = 32
+
+// Config holds the settings validateConfig checks at startup: the shared
+// server settings and how users' passwords and sessions are secured.
+type Config struct {
+	settings.Server
+	// BcryptCost hashes passwords; higher is slower to hash and to crack
+	BcryptCost string
+	// JWTSecret signs session tokens; when empty a random one is used
+	JWTSecret string
+}
+
+func loadConfig(mongo mongoutil.Config) Config {
+	return Config{
+		Server:     settings.LoadServer("50052", mongo),
+		BcryptCost: getEnv("BCRYPT_COST", "10"),
+		JWTSecret:  os.Getenv("JWT_SECRET"),
+	}
+}
+
+// validateConfig reports every invalid setting in cfg, each named by its
+// environment variable.
+func validateConfig(cfg Config) error {
+	errs := cfg.Errors()
+	switch cfg.StorageDriver {
+	case storageMongo, storageMemory:
+	default:
+		errs = append(errs, fmt.Errorf("STORAGE_DRIVER: %q is not %s or %s", cfg.StorageDriver, storageMongo, storageMemory))
+	}
+	if cost, err := strconv.Atoi(cfg.BcryptCost); err != nil || cost < minBcryptCost || cost > maxBcryptCost {
+		errs = append(errs, fmt.Errorf("BCRYPT_COST: %q is not a cost between %d and %d", cfg.BcryptCost, minBcryptCost, maxBcryptCost))
+	}
+	if cfg.JWTSecret != "" {
+		if err := settings.Secret(cfg.JWTSecret, minJWTSecretLen); err != nil {
+			errs = append(errs, fmt.Errorf("JWT_SECRET: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// bcryptCost returns BcryptCost, once validated.
+func (c Config) bcryptCost() int {
+	cost, _ := strconv.Atoi(c.BcryptCost)
+	return cost
+}
+
+// info adds BCRYPT_COST to the shared settings for the InfoService.
+// JWTSecret is left out.
+func (c Config) info() map[string]string {
+	values := c.Info()
+	values["BCRYPT_COST"] = c.BcryptCost
+	return values
+}
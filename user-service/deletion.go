@@ -0,0 +1,355 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/grpc/codes"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+	"github.com/technonext/todo-app/proto/tenant"
+)
+
+const (
+	deletionPending   = "pending"
+	deletionCompleted = "completed"
+	deletionFailed    = "failed"
+
+	// deletionClaimLease covers a pass over every step, each of which is
+	// bounded by purgeStepTimeout
+	deletionClaimLease = 5 * time.Minute
+	// Retries back off exponentially up to this wait
+	maxDeletionBackoff = time.Hour
+)
+
+// deletionSteps names the steps of a user deletion, in the order they run.
+var deletionSteps = []string{"sessions", "tasks", "notifications", "analytics"}
+
+// instanceID identifies this replica as the holder of deletion job claims.
+var instanceID = fmt.Sprintf("%s-%d", hostname(), os.Getpid())
+
+func hostname() string {
+	name, err := os.Hostname()
+	if err != nil {
+		return "unknown"
+	}
+	return name
+}
+
+// deletionJobsFailed counts jobs that used up a step's attempts; each needs
+// someone to look at why the step keeps failing.
+var deletionJobsFailed = prometheus.NewCounter(prometheus.CounterOpts{
+	Name: "user_deletion_jobs_failed_total",
+	Help: "User deletion jobs that failed after retrying a step.",
+})
+
+func init() {
+	prometheus.MustRegister(deletionJobsFailed)
+}
+
+type DeletionStep struct {
+	Name        string `bson:"name"`
+	Status      string `bson:"status"`
+	Attempts    int32  `bson:"attempts"`
+	LastError   string `bson:"last_error,omitempty"`
+	CompletedAt string `bson:"completed_at,omitempty"`
+}
+
+// DeletionJob is the saga erasing a deleted user's data in the other
+// services. Jobs are claimed by one replica at a time, and a claim left
+// behind by a crashed replica expires.
+type DeletionJob struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty"`
+	UserID         string             `bson:"user_id"`
+	Status         string             `bson:"status"`
+	Steps          []DeletionStep     `bson:"steps"`
+	CreatedAt      string             `bson:"created_at"`
+	UpdatedAt      string             `bson:"updated_at"`
+	NextAttemptAt  time.Time          `bson:"next_attempt_at"`
+	ClaimedBy      string             `bson:"claimed_by,omitempty"`
+	ClaimExpiresAt *time.Time         `bson:"claim_expires_at,omitempty"`
+}
+
+func (j DeletionJob) toProto() *pb.DeletionJob {
+	job := &pb.DeletionJob{
+		Id:        j.ID.Hex(),
+		UserId:    j.UserID,
+		Status:    j.Status,
+		CreatedAt: j.CreatedAt,
+		UpdatedAt: j.UpdatedAt,
+	}
+	if j.Status == deletionPending {
+		job.NextAttemptAt = j.NextAttemptAt.UTC().Format(time.RFC3339)
+	}
+	for _, step := range j.Steps {
+		job.Steps = append(job.Steps, &pb.DeletionStep{
+			Name:        step.Name,
+			Status:      step.Status,
+			Attempts:    step.Attempts,
+			LastError:   step.LastError,
+			CompletedAt: step.CompletedAt,
+		})
+	}
+	return job
+}
+
+// deletionWorker runs deletion jobs. Every step can be repeated, so a job a
+// replica crashed partway through is safely run again by the next claim.
+type deletionWorker struct {
+	jobs        *mongo.Collection
+	steps       map[string]func(ctx context.Context, userID string) error
+	maxAttempts int32
+	backoff     time.Duration
+	interval    time.Duration
+	wake        chan struct{}
+}
+
+type deletionConfig struct {
+	maxAttempts int32
+	backoff     time.Duration
+	interval    time.Duration
+}
+
+func loadDeletionConfig() (deletionConfig, error) {
+	maxAttempts, err := strconv.Atoi(getEnv("USER_DELETION_MAX_ATTEMPTS", "8"))
+	if err != nil || maxAttempts < 1 {
+		return deletionConfig{}, fmt.Errorf("invalid USER_DELETION_MAX_ATTEMPTS: %q", os.Getenv("USER_DELETION_MAX_ATTEMPTS"))
+	}
+	backoff, err := time.ParseDuration(getEnv("USER_DELETION_RETRY_BACKOFF", "30s"))
+	if err != nil || backoff <= 0 {
+		return deletionConfig{}, fmt.Errorf("invalid USER_DELETION_RETRY_BACKOFF: %q", os.Getenv("USER_DELETION_RETRY_BACKOFF"))
+	}
+	interval, err := time.ParseDuration(getEnv("USER_DELETION_POLL_INTERVAL", "30s"))
+	if err != nil || interval <= 0 {
+		return deletionConfig{}, fmt.Errorf("invalid USER_DELETION_POLL_INTERVAL: %q", os.Getenv("USER_DELETION_POLL_INTERVAL"))
+	}
+	return deletionConfig{maxAttempts: int32(maxAttempts), backoff: backoff, interval: interval}, nil
+}
+
+// newDeletionWorker returns a worker erasing users through s's clients of
+// the other services.
+func newDeletionWorker(jobs *mongo.Collection, s *server, cfg deletionConfig) *deletionWorker {
+	return &deletionWorker{
+		jobs: jobs,
+		steps: map[string]func(ctx context.Context, userID string) error{
+			"sessions": func(ctx context.Context, userID string) error {
+				_, err := s.sessions.DeleteMany(ctx, bson.M{"user_id": userID})
+				return err
+			},
+			"tasks": func(ctx context.Context, userID string) error {
+				_, err := s.tasks.BatchDeleteTasks(ctx, &pb.BatchDeleteTasksRequest{UserId: userID})
+				return err
+			},
+			"notifications": func(ctx context.Context, userID string) error {
+				_, err := s.notifications.DeleteNotificationsByUser(ctx, &pb.DeleteNotificationsByUserRequest{UserId: userID})
+				return err
+			},
+			// Analytics keeps the user's events for aggregate history, under a
+			// pseudonym; PurgeUserData deletes them outright
+			"analytics": func(ctx context.Context, userID string) error {
+				_, err := s.analytics.AnonymizeUserEvents(ctx, &pb.AnonymizeUserEventsRequest{UserId: userID})
+				return err
+			},
+		},
+		maxAttempts: cfg.maxAttempts,
+		backoff:     cfg.backoff,
+		interval:    cfg.interval,
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// start runs due jobs now, every interval and whenever a job is added.
+func (w *deletionWorker) start() {
+	go func() {
+		for {
+			// Jobs erase users of every tenant
+			w.runDue(tenant.WithID(context.Background(), tenant.All))
+			select {
+			case <-w.wake:
+			case <-time.After(w.interval):
+			}
+		}
+	}()
+}
+
+// wakeUp runs due jobs without waiting for the interval.
+func (w *deletionWorker) wakeUp() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// begin records the deletion job for a user, or returns the one already
+// recorded, so a DeleteUser retried after a failure reuses it.
+func (w *deletionWorker) begin(ctx context.Context, userID string, now time.Time) (DeletionJob, error) {
+	job := DeletionJob{
+		UserID:        userID,
+		Status:        deletionPending,
+		CreatedAt:     now.Format(time.RFC3339),
+		UpdatedAt:     now.Format(time.RFC3339),
+		NextAttemptAt: now,
+	}
+	for _, name := range deletionSteps {
+		job.Steps = append(job.Steps, DeletionStep{Name: name, Status: deletionPending})
+	}
+
+	result, err := w.jobs.InsertOne(ctx, job)
+	if mongo.IsDuplicateKeyError(err) {
+		err = w.jobs.FindOne(ctx, bson.M{"user_id": userID}).Decode(&job)
+		return job, err
+	}
+	if err != nil {
+		return DeletionJob{}, err
+	}
+	job.ID = result.InsertedID.(primitive.ObjectID)
+	return job, nil
+}
+
+// runDue runs every pending job whose next attempt is due.
+func (w *deletionWorker) runDue(ctx context.Context) {
+	for {
+		job, err := w.claim(ctx, time.Now())
+		if err == mongo.ErrNoDocuments {
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to claim user deletion job: %v", err)
+			return
+		}
+		w.advance(ctx, &job, time.Now())
+		if err := w.save(ctx, job); err != nil {
+			log.Printf("Failed to save user deletion job %s: %v", job.ID.Hex(), err)
+		}
+	}
+}
+
+// claim takes the next due job that no live replica holds.
+func (w *deletionWorker) claim(ctx context.Context, now time.Time) (DeletionJob, error) {
+	var job DeletionJob
+	err := w.jobs.FindOneAndUpdate(ctx,
+		bson.M{
+			"status":          deletionPending,
+			"next_attempt_at": bson.M{"$lte": now},
+			"$or": bson.A{
+				bson.M{"claim_expires_at": bson.M{"$exists": false}},
+				bson.M{"claim_expires_at": bson.M{"$lt": now}},
+			},
+		},
+		bson.M{"$set": bson.M{"claimed_by": instanceID, "claim_expires_at": now.Add(deletionClaimLease)}},
+		options.FindOneAndUpdate().
+			SetSort(bson.D{{Key: "next_attempt_at", Value: 1}}).
+			SetReturnDocument(options.After),
+	).Decode(&job)
+	return job, err
+}
+
+// save writes back a job this replica claimed, releasing the claim. A claim
+// that expired and was taken over is left to its new holder.
+func (w *deletionWorker) save(ctx context.Context, job DeletionJob) error {
+	job.ClaimedBy = ""
+	job.ClaimExpiresAt = nil
+	result, err := w.jobs.ReplaceOne(ctx, bson.M{"_id": job.ID, "claimed_by": instanceID}, job)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		log.Printf("Lost the claim on user deletion job %s", job.ID.Hex())
+	}
+	return nil
+}
+
+// advance tries each pending step of job once. A failed step is retried
+// after a backoff that doubles with each attempt, until it has used
+// maxAttempts; the job fails once no step is left pending and one of them
+// failed.
+func (w *deletionWorker) advance(ctx context.Context, job *DeletionJob, now time.Time) {
+	var pending, failed int
+	var wait time.Duration
+	for i := range job.Steps {
+		step := &job.Steps[i]
+		if step.Status != deletionPending {
+			continue
+		}
+
+		err := fmt.Errorf("unknown deletion step %q", step.Name)
+		if run, ok := w.steps[step.Name]; ok {
+			stepCtx, cancel := context.WithTimeout(ctx, purgeStepTimeout)
+			err = run(stepCtx, job.UserID)
+			cancel()
+		}
+		step.Attempts++
+		if err == nil {
+			step.Status = deletionCompleted
+			step.LastError = ""
+			step.CompletedAt = now.Format(time.RFC3339)
+			continue
+		}
+
+		log.Printf("Deletion of user %s: %s step failed (attempt %d of %d): %v", job.UserID, step.Name, step.Attempts, w.maxAttempts, err)
+		step.LastError = err.Error()
+		if step.Attempts >= w.maxAttempts {
+			step.Status = deletionFailed
+			continue
+		}
+		pending++
+		if backoff := w.backoffAfter(step.Attempts); backoff > wait {
+			wait = backoff
+		}
+	}
+	for _, step := range job.Steps {
+		if step.Status == deletionFailed {
+			failed++
+		}
+	}
+
+	job.UpdatedAt = now.Format(time.RFC3339)
+	switch {
+	case pending > 0:
+		job.NextAttemptAt = now.Add(wait)
+	case failed > 0:
+		job.Status = deletionFailed
+		deletionJobsFailed.Inc()
+		log.Printf("ALERT: deletion of user %s failed; %d step(s) used up their attempts", job.UserID, failed)
+	default:
+		job.Status = deletionCompleted
+	}
+}
+
+// backoffAfter returns the wait after a step's attempts-th failure.
+func (w *deletionWorker) backoffAfter(attempts int32) time.Duration {
+	wait := w.backoff
+	for i := int32(1); i < attempts && wait < maxDeletionBackoff; i++ {
+		wait *= 2
+	}
+	if wait > maxDeletionBackoff {
+		wait = maxDeletionBackoff
+	}
+	return wait
+}
+
+// GetDeletionStatus returns the job erasing a deleted user's data.
+func (s *server) GetDeletionStatus(ctx context.Context, req *pb.GetDeletionStatusRequest) (*pb.DeletionJobResponse, error) {
+	if _, err := primitive.ObjectIDFromHex(req.UserId); err != nil {
+		return nil, statusError(codes.InvalidArgument, "INVALID_USER_ID", map[string]string{"user_id": req.UserId}, "invalid user id %q", req.UserId)
+	}
+
+	var job DeletionJob
+	err := s.deletions.jobs.FindOne(ctx, bson.M{"user_id": req.UserId}).Decode(&job)
+	if err == mongo.ErrNoDocuments {
+		return nil, statusError(codes.NotFound, "DELETION_JOB_NOT_FOUND", map[string]string{"user_id": req.UserId}, "no deletion job for user %s", req.UserId)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &pb.DeletionJobResponse{Job: job.toProto()}, nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// newTestDeletionJob returns a pending job over the steps of w.
+func newTestDeletionJob(w *deletionWorker) *DeletionJob {
+	job := &DeletionJob{UserID: "u1", Status: deletionPending}
+	for _, name := range deletionSteps {
+		if _, ok := w.steps[name]; ok {
+			job.Steps = append(job.Steps, DeletionStep{Name: name, Status: deletionPending})
+		}
+	}
+	return job
+}
+
+func TestDeletionStepRecoversAfterFailures(t *testing.T) {
+	var taskCalls, sessionCalls int
+	w := &deletionWorker{
+		steps: map[string]func(context.Context, string) error{
+			"sessions": func(context.Context, string) error { sessionCalls++; return nil },
+			"tasks": func(context.Context, string) error {
+				taskCalls++
+				if taskCalls <= 2 {
+					return errors.New("task service unavailable")
+				}
+				return nil
+			},
+		},
+		maxAttempts: 5,
+		backoff:     time.Second,
+	}
+	job := newTestDeletionJob(w)
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	for i, wantWait := range []time.Duration{time.Second, 2 * time.Second} {
+		w.advance(context.Background(), job, now)
+		if job.Status != deletionPending {
+			t.Fatalf("after failure %d job is %s, want pending", i+1, job.Status)
+		}
+		if got := job.NextAttemptAt.Sub(now); got != wantWait {
+			t.Errorf("after failure %d next attempt in %v, want %v", i+1, got, wantWait)
+		}
+		if job.Steps[1].LastError == "" {
+			t.Errorf("after failure %d the tasks step has no error", i+1)
+		}
+		now = job.NextAttemptAt
+	}
+
+	w.advance(context.Background(), job, now)
+	if job.Status != deletionCompleted {
+		t.Fatalf("job is %s, want completed", job.Status)
+	}
+	tasks := job.Steps[1]
+	if tasks.Status != deletionCompleted || tasks.Attempts != 3 || tasks.LastError != "" {
+		t.Errorf("tasks step = %+v, want completed on the third attempt", tasks)
+	}
+	// Completed steps are not repeated by later passes
+	if sessionCalls != 1 {
+		t.Errorf("sessions step ran %d times, want 1", sessionCalls)
+	}
+}
+
+func TestDeletionFailsWhenServiceStaysDown(t *testing.T) {
+	var notificationCalls int
+	w := &deletionWorker{
+		steps: map[string]func(context.Context, string) error{
+			"sessions":      func(context.Context, string) error { return nil },
+			"tasks":         func(context.Context, string) error { return nil },
+			"notifications": func(context.Context, string) error { notificationCalls++; return errors.New("connection refused") },
+		},
+		maxAttempts: 3,
+		backoff:     time.Second,
+	}
+	job := newTestDeletionJob(w)
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	failedBefore := testutil.ToFloat64(deletionJobsFailed)
+	for i := 0; i < 5 && job.Status == deletionPending; i++ {
+		w.advance(context.Background(), job, now)
+		now = now.Add(time.Hour)
+	}
+
+	if job.Status != deletionFailed {
+		t.Fatalf("job is %s, want failed", job.Status)
+	}
+	if notificationCalls != 3 {
+		t.Errorf("notifications step ran %d times, want 3", notificationCalls)
+	}
+	want := map[string]string{"sessions": deletionCompleted, "tasks": deletionCompleted, "notifications": deletionFailed}
+	for _, step := range job.Steps {
+		if step.Status != want[step.Name] {
+			t.Errorf("%s step is %s, want %s", step.Name, step.Status, want[step.Name])
+		}
+	}
+	if got := testutil.ToFloat64(deletionJobsFailed) - failedBefore; got != 1 {
+		t.Errorf("failed job counter rose by %v, want 1", got)
+	}
+}
+
+func TestDeletionBackoffIsCapped(t *testing.T) {
+	w := &deletionWorker{backoff: time.Minute}
+	if got := w.backoffAfter(3); got != 4*time.Minute {
+		t.Errorf("backoff after 3 attempts = %v, want 4m", got)
+	}
+	if got := w.backoffAfter(20); got != maxDeletionBackoff {
+		t.Errorf("backoff after 20 attempts = %v, want %v", got, maxDeletionBackoff)
+	}
+}
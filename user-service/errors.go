@@ -0,0 +1,6 @@
+package main
+
+import "github.com/technonext/todo-app/proto/grpcerror"
+
+// statusError returns a gRPC error whose ErrorInfo names this service.
+var statusError = grpcerror.Domain("user.service").Errorf
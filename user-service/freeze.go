@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/grpc/codes"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// FreezeUser stops the user from creating or changing tasks until they are
+// unfrozen. Freezing a frozen user keeps the original frozen_at.
+func (s *server) FreezeUser(ctx context.Context, req *pb.FreezeUserRequest) (*pb.UserResponse, error) {
+	now := time.Now().Format(time.RFC3339)
+	return s.setFrozen(ctx, req.Id, true,
+		bson.M{"$set": bson.M{"is_frozen": true, "frozen_at": now, "updated_at": now}})
+}
+
+// UnfreezeUser lifts a freeze. Tokens issued while the user was frozen still
+// say so until they expire, so the user has to log in again before the
+// gateway lets their writes through.
+func (s *server) UnfreezeUser(ctx context.Context, req *pb.UnfreezeUserRequest) (*pb.UserResponse, error) {
+	return s.setFrozen(ctx, req.Id, false,
+		bson.M{
+			"$set":   bson.M{"updated_at": time.Now().Format(time.RFC3339)},
+			"$unset": bson.M{"is_frozen": "", "frozen_at": ""},
+		})
+}
+
+// setFrozen applies update to the user unless they are already frozen or
+// unfrozen as asked, and returns the user either way.
+func (s *server) setFrozen(ctx context.Context, id string, frozen bool, update bson.M) (*pb.UserResponse, error) {
+	match, err := userFilter(id)
+	if err != nil {
+		return nil, err
+	}
+	filter := bson.M{"is_frozen": true}
+	if frozen {
+		filter["is_frozen"] = bson.M{"$ne": true}
+	}
+	for k, v := range match {
+		filter[k] = v
+	}
+
+	var user User
+	err = s.collection.FindOneAndUpdate(ctx, filter, update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		// Already in the requested state, or no such user
+		err = s.collection.FindOne(ctx, match).Decode(&user)
+	}
+	if err == mongo.ErrNoDocuments {
+		return nil, statusError(codes.NotFound, "USER_NOT_FOUND", map[string]string{"user_id": id}, "user %s not found", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &pb.UserResponse{User: user.toProto()}, nil
+}
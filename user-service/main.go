@@ -2,9 +2,10 @@ package main
 
 import (
 	"context"
-	"fmt"
-	"log"
+	"flag"
+	"log/slog"
 	"net"
+	"net/http"
 	"os"
 	"time"
 
@@ -14,27 +15,71 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"golang.org/x/crypto/bcrypt"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/reflection"
-
+	"google.golang.org/grpc/codes"
+
+	"github.com/technonext/todo-app/proto/backup"
+	"github.com/technonext/todo-app/proto/buildinfo"
+	"github.com/technonext/todo-app/proto/diagnostics"
+	"github.com/technonext/todo-app/proto/faults"
+	"github.com/technonext/todo-app/proto/grpcmiddleware"
+	"github.com/technonext/todo-app/proto/logging"
+	"github.com/technonext/todo-app/proto/mongoutil"
 	pb "github.com/technonext/todo-app/proto/proto"
+	"github.com/technonext/todo-app/proto/serviceauth"
+	"github.com/technonext/todo-app/proto/tenant"
 )
 
 type server struct {
 	pb.UnimplementedUserServiceServer
-	collection *mongo.Collection
+	collection *tenant.Collection
+	sessions   *tenant.Collection
+	// Stores the users of the core RPCs; nil uses collection and sessions
+	users      UserRepository
+	jwtSecret  []byte
+	sessionTTL time.Duration
+	// bcryptCost hashes new passwords; bcrypt's default when 0
+	bcryptCost int
+	// The services PurgeUserData erases the user from
+	tasks         pb.TaskServiceClient
+	notifications pb.NotificationServiceClient
+	analytics     pb.AnalyticsServiceClient
+	// Erases the data of users removed by DeleteUser
+	deletions *deletionWorker
 }
 
 type User struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty"`
-	Username  string             `bson:"username"`
-	Email     string             `bson:"email"`
-	Password  string             `bson:"password"`
-	CreatedAt string             `bson:"created_at"`
-	UpdatedAt string             `bson:"updated_at"`
+	ID         primitive.ObjectID `bson:"_id,omitempty"`
+	Username   string             `bson:"username"`
+	Email      string             `bson:"email"`
+	Password   string             `bson:"password"`
+	CreatedAt  string             `bson:"created_at"`
+	UpdatedAt  string             `bson:"updated_at"`
+	LastSeenAt string             `bson:"last_seen_at,omitempty"`
+	IsFrozen   bool               `bson:"is_frozen,omitempty"`
+	FrozenAt   string             `bson:"frozen_at,omitempty"`
+	// The tenant the user belongs to, empty for the default tenant
+	TenantID string `bson:"tenant_id,omitempty"`
+	// Set at creation; see userid.go
+	PublicID string `bson:"public_id,omitempty"`
+	// The version of the document's layout; see userSchema
+	SchemaVersion int `bson:"schema_version,omitempty"`
+}
+
+// toProto shows the user's ObjectID hex as their id, for now; see userid.go.
+func (u User) toProto() *pb.User {
+	return &pb.User{
+		Id:        u.ID.Hex(),
+		Username:  u.Username,
+		Email:     u.Email,
+		CreatedAt: u.CreatedAt,
+		UpdatedAt: u.UpdatedAt,
+		IsFrozen:  u.IsFrozen,
+		FrozenAt:  u.FrozenAt,
+	}
 }
 
 func (s *server) CreateUser(ctx context.Context, req *pb.CreateUserRequest) (*pb.UserResponse, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), s.bcryptCost)
 	if err != nil {
 		return nil, err
 	}
@@ -48,181 +93,318 @@ func (s *server) CreateUser(ctx context.Context, req *pb.CreateUserRequest) (*pb
 		UpdatedAt: now,
 	}
 
-	result, err := s.collection.InsertOne(ctx, user)
-	if err != nil {
-		log.Printf("Failed to create user: %v", err)
-		return nil, err
+	for attempt := 0; attempt < publicIDAttempts; attempt++ {
+		user.PublicID = newUserPublicID()
+		if user.ID, err = s.repository().Insert(ctx, user); err != errPublicIDTaken {
+			break
+		}
+		logging.FromContext(ctx).Warn("User public id taken, retrying", "public_id", user.PublicID)
 	}
-
-	oid, ok := result.InsertedID.(primitive.ObjectID)
-	if !ok {
-		log.Printf("Failed to convert ObjectID")
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to create user", "error", err)
 		return nil, err
 	}
 
-	return &pb.UserResponse{
-		User: &pb.User{
-			Id:        oid.Hex(),
-			Username:  user.Username,
-			Email:     user.Email,
-			CreatedAt: user.CreatedAt,
-			UpdatedAt: user.UpdatedAt,
-		},
-	}, nil
+	return &pb.UserResponse{User: user.toProto()}, nil
 }
 
 func (s *server) GetUser(ctx context.Context, req *pb.GetUserRequest) (*pb.UserResponse, error) {
-	oid, err := primitive.ObjectIDFromHex(req.Id)
-	if err != nil {
-		return nil, err
+	user, err := s.repository().Get(ctx, req.Id)
+	if err == errNoUser {
+		return nil, statusError(codes.NotFound, "USER_NOT_FOUND", map[string]string{"user_id": req.Id}, "user %s not found", req.Id)
 	}
-
-	var user User
-	err = s.collection.FindOne(ctx, bson.M{"_id": oid}).Decode(&user)
 	if err != nil {
 		return nil, err
 	}
 
-	return &pb.UserResponse{
-		User: &pb.User{
-			Id:        user.ID.Hex(),
-			Username:  user.Username,
-			Email:     user.Email,
-			CreatedAt: user.CreatedAt,
-			UpdatedAt: user.UpdatedAt,
-		},
-	}, nil
+	return &pb.UserResponse{User: user.toProto()}, nil
 }
 
 func (s *server) UpdateUser(ctx context.Context, req *pb.UpdateUserRequest) (*pb.UserResponse, error) {
-	oid, err := primitive.ObjectIDFromHex(req.Id)
-	if err != nil {
+	if _, err := userFilter(req.Id); err != nil {
 		return nil, err
 	}
 
-	update := bson.M{
-		"$set": bson.M{
-			"username":   req.Username,
-			"email":      req.Email,
-			"updated_at": time.Now().Format(time.RFC3339),
-		},
+	change := userChange{
+		Username:  req.Username,
+		Email:     req.Email,
+		UpdatedAt: time.Now().Format(time.RFC3339),
 	}
 
 	if req.Password != "" {
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), s.bcryptCost)
 		if err != nil {
 			return nil, err
 		}
-		update["$set"].(bson.M)["password"] = string(hashedPassword)
+		change.Password = string(hashedPassword)
 	}
 
-	_, err = s.collection.UpdateOne(ctx, bson.M{"_id": oid}, update)
-	if err != nil {
-		return nil, err
+	updatedUser, err := s.repository().Update(ctx, req.Id, change)
+	if err == errNoUser {
+		return nil, statusError(codes.NotFound, "USER_NOT_FOUND", map[string]string{"user_id": req.Id}, "user %s not found", req.Id)
 	}
-
-	var updatedUser User
-	err = s.collection.FindOne(ctx, bson.M{"_id": oid}).Decode(&updatedUser)
 	if err != nil {
 		return nil, err
 	}
 
-	return &pb.UserResponse{
-		User: &pb.User{
-			Id:        updatedUser.ID.Hex(),
-			Username:  updatedUser.Username,
-			Email:     updatedUser.Email,
-			CreatedAt: updatedUser.CreatedAt,
-			UpdatedAt: updatedUser.UpdatedAt,
-		},
-	}, nil
+	return &pb.UserResponse{User: updatedUser.toProto()}, nil
 }
 
+// DeleteUser removes the user and starts a deletion job erasing their data
+// in the other services. The job is recorded before the user is removed, so
+// a DeleteUser that fails in between is completed by retrying it.
 func (s *server) DeleteUser(ctx context.Context, req *pb.DeleteUserRequest) (*pb.DeleteUserResponse, error) {
-	oid, err := primitive.ObjectIDFromHex(req.Id)
+	filter, err := userFilter(req.Id)
 	if err != nil {
 		return nil, err
 	}
 
-	_, err = s.collection.DeleteOne(ctx, bson.M{"_id": oid})
+	// The other services know the user by ObjectID, so the job erases that
+	var user User
+	err = s.collection.FindOne(ctx, filter, options.FindOne().SetProjection(bson.M{"_id": 1})).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return nil, statusError(codes.NotFound, "USER_NOT_FOUND", map[string]string{"user_id": req.Id}, "user %s not found", req.Id)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	return &pb.DeleteUserResponse{Success: true}, nil
+	job, err := s.deletions.begin(ctx, user.ID.Hex(), time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.collection.DeleteOne(ctx, bson.M{"_id": user.ID}); err != nil {
+		return nil, err
+	}
+	s.deletions.wakeUp()
+
+	return &pb.DeleteUserResponse{Success: true, DeletionJobId: job.ID.Hex()}, nil
 }
 
 func (s *server) AuthenticateUser(ctx context.Context, req *pb.AuthRequest) (*pb.AuthResponse, error) {
-	var user User
-	err := s.collection.FindOne(ctx, bson.M{"email": req.Email}).Decode(&user)
+	// Callers sign in before they have a tenant, so the email is looked up
+	// in every tenant, and the rest of the sign-in acts for the user's
+	user, err := s.repository().FindByEmail(tenant.WithID(ctx, tenant.All), req.Email)
+	if err == errNoUser {
+		// Same error as a wrong password, so emails cannot be probed
+		return nil, statusError(codes.Unauthenticated, "INVALID_CREDENTIALS", nil, "invalid email or password")
+	}
 	if err != nil {
 		return nil, err
 	}
 
 	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password))
 	if err != nil {
-		return nil, err
+		return nil, statusError(codes.Unauthenticated, "INVALID_CREDENTIALS", nil, "invalid email or password")
 	}
+	ctx = tenant.WithID(ctx, user.TenantID)
 
-	// In a real application, you would generate a JWT token here
-	token := "sample-jwt-token"
+	token, err := s.createSession(ctx, user)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to create session", "error", err)
+		return nil, err
+	}
+	if err := s.repository().TouchLastSeen(ctx, user.ID, time.Now()); err != nil {
+		logging.FromContext(ctx).Error("Failed to record last login", "user_id", user.ID.Hex(), "error", err)
+	}
 
 	return &pb.AuthResponse{
 		Token: token,
-		User: &pb.User{
-			Id:        user.ID.Hex(),
-			Username:  user.Username,
-			Email:     user.Email,
-			CreatedAt: user.CreatedAt,
-			UpdatedAt: user.UpdatedAt,
-		},
+		User:  user.toProto(),
 	}, nil
 }
 
 func main() {
-	// Read the environment variables
-	mongoUser := os.Getenv("MONGO_USERNAME")
-	mongoPass := os.Getenv("MONGO_PASSWORD")
-	mongoHost := os.Getenv("MONGO_HOST")
-	if mongoUser == "" || mongoPass == "" || mongoHost == "" {
-		log.Fatal("Error: MONGO_USERNAME, MONGO_PASSWORD, and MONGO_HOST must be set")
+	migrateOnly := flag.Bool("migrate", false, "apply pending data migrations and exit")
+	dryRun := flag.Bool("dry-run", false, "with -migrate, report what the pending migrations would change without applying them")
+	flag.Parse()
+	logging.Setup("user-service")
+
+	mongoConfig, err := mongoutil.ConfigFromEnv()
+	if err != nil {
+		logging.Fatal("Invalid MongoDB settings", "error", err)
+	}
+	cfg := loadConfig(mongoConfig)
+	if err := validateConfig(cfg); err != nil {
+		logging.Fatal("Invalid configuration", "error", err)
 	}
-	// Build the connection string
-	mongoURI := fmt.Sprintf("mongodb://%s:%s@%s/todo_app?authSource=admin", mongoUser, mongoPass, mongoHost)
+	backupStore, err := backup.FromEnv(context.Background())
+	if err != nil {
+		logging.Fatal("Invalid configuration", "error", err)
+	}
+	srv := &server{}
+	var diagnosticsHandler http.Handler
+	var backups *backup.Server
+	var deletionJobs *mongo.Collection
+	if cfg.StorageDriver == storageMemory {
+		if *migrateOnly {
+			return
+		}
+		slog.Warn("Users are kept in memory and lost when the service stops")
+		srv.users = newMemoryUsers()
+	} else {
+		slog.Info("Connecting to MongoDB")
+		client, err := mongoutil.Connect(context.Background(), mongoConfig)
+		if err != nil {
+			logging.Fatal("Failed to connect to MongoDB", "error", err)
+		}
+		defer client.Disconnect(context.Background())
+
+		if *migrateOnly {
+			if err := runMigrations(context.Background(), client.Database(mongoConfig.DatabaseName()), *dryRun, os.Stdout); err != nil {
+				logging.Fatal("Migration failed", "error", err)
+			}
+			return
+		}
+		warnPendingMigrations(context.Background(), client.Database(mongoConfig.DatabaseName()))
+		if backupStore != nil {
+			backups = backup.NewServer(client.Database(mongoConfig.DatabaseName()), backupStore)
+		}
 
-	log.Printf("Connecting to MongoDB at %s...", mongoHost)
-	// Connect to MongoDB
-	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI(mongoURI))
+		collection := client.Database(mongoConfig.DatabaseName()).Collection("users", userSchema.Decoding(nil, User{}))
+		if err := userSchema.Check(context.Background(), collection); err != nil {
+			logging.Fatal("Database is newer than this release", "error", err)
+		}
+		sessions := client.Database(mongoConfig.DatabaseName()).Collection("sessions")
+
+		if err := ensurePublicIDIndex(context.Background(), collection); err != nil {
+			logging.Fatal("Failed to create user public id index", "error", err)
+		}
+
+		err = mongoutil.EnsureIndexes(context.Background(), sessions, mongo.IndexModel{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}},
+		})
+		if err != nil {
+			logging.Fatal("Failed to create session indexes", "error", err)
+		}
+
+		// One deletion job per user, found by the worker once its next attempt is due
+		deletionJobs = client.Database(mongoConfig.DatabaseName()).Collection("user_deletions")
+		err = mongoutil.EnsureIndexes(context.Background(), deletionJobs,
+			mongo.IndexModel{
+				Keys:    bson.D{{Key: "user_id", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			},
+			mongo.IndexModel{Keys: bson.D{{Key: "status", Value: 1}, {Key: "next_attempt_at", Value: 1}}},
+		)
+		if err != nil {
+			logging.Fatal("Failed to create deletion job indexes", "error", err)
+		}
+
+		srv.collection = tenant.Scoped(collection)
+		srv.sessions = tenant.Scoped(sessions)
+		diagnosticsHandler = diagnostics.NewServer(collection)
+	}
+
+	deletionConfig, err := loadDeletionConfig()
 	if err != nil {
-		log.Fatalf("Failed to connect to MongoDB: %v", err)
+		logging.Fatal("Invalid configuration", "error", err)
+	}
+
+	jwtSecret, err := loadJWTSecret()
+	if err != nil {
+		logging.Fatal("Failed to generate a JWT secret", "error", err)
+	}
+	sessionTTL, err := time.ParseDuration(getEnv("SESSION_TTL", "24h"))
+	if err != nil || sessionTTL <= 0 {
+		logging.Fatal("Invalid SESSION_TTL", "value", os.Getenv("SESSION_TTL"))
 	}
-	defer client.Disconnect(context.Background())
 
-	// Check the connection
-	err = client.Ping(context.Background(), nil)
+	// Purging a user erases their data in the other services
+	taskConn, err := grpcmiddleware.Dial(getEnv("TASK_SERVICE_ADDR", "localhost:50051"), "TASK_SERVICE_HMAC_SECRET")
 	if err != nil {
-		log.Fatalf("Failed to ping MongoDB: %v", err)
+		logging.Fatal("Failed to connect to task service", "error", err)
 	}
+	defer taskConn.Close()
 
-	collection := client.Database("todo_app").Collection("users")
+	notificationConn, err := grpcmiddleware.Dial(getEnv("NOTIFICATION_SERVICE_ADDR", "localhost:50053"), "NOTIFICATION_SERVICE_HMAC_SECRET")
+	if err != nil {
+		logging.Fatal("Failed to connect to notification service", "error", err)
+	}
+	defer notificationConn.Close()
 
-	// Get port from environment variable
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "50052"
+	analyticsConn, err := grpcmiddleware.Dial(getEnv("ANALYTICS_SERVICE_ADDR", "localhost:50054"), "ANALYTICS_SERVICE_HMAC_SECRET")
+	if err != nil {
+		logging.Fatal("Failed to connect to analytics service", "error", err)
 	}
+	defer analyticsConn.Close()
+
+	port := cfg.Port
 
 	lis, err := net.Listen("tcp", ":"+port)
 	if err != nil {
-		log.Fatalf("Failed to listen: %v", err)
+		logging.Fatal("Failed to listen", "error", err)
+	}
+
+	auth, err := serviceauth.FromEnv("USER_SERVICE_HMAC_SECRET")
+	if err != nil {
+		logging.Fatal("Invalid configuration", "error", err)
+	}
+	reflection, err := grpcmiddleware.ReflectionFromEnv()
+	if err != nil {
+		logging.Fatal("Invalid configuration", "error", err)
+	}
+
+	srv.jwtSecret = jwtSecret
+	srv.bcryptCost = cfg.bcryptCost()
+	srv.sessionTTL = sessionTTL
+	srv.tasks = pb.NewTaskServiceClient(taskConn)
+	srv.notifications = pb.NewNotificationServiceClient(notificationConn)
+	srv.analytics = pb.NewAnalyticsServiceClient(analyticsConn)
+	// Deletion jobs are kept in MongoDB, so DeleteUser is only served there
+	if deletionJobs != nil {
+		srv.deletions = newDeletionWorker(deletionJobs, srv, deletionConfig)
+		srv.deletions.start()
 	}
 
-	s := grpc.NewServer()
-	pb.RegisterUserServiceServer(s, &server{collection: collection})
-	reflection.Register(s)
+	requireTenant, err := tenant.RequiredFromEnv()
+	if err != nil {
+		logging.Fatal("Invalid configuration", "error", err)
+	}
+	injector, err := faults.FromEnv()
+	if err != nil {
+		logging.Fatal("Invalid configuration", "error", err)
+	}
+	pprof, err := grpcmiddleware.PprofFromEnv()
+	if err != nil {
+		logging.Fatal("Invalid configuration", "error", err)
+	}
+	info := buildinfo.NewServer("user-service", cfg.info())
+
+	var unary []grpc.UnaryServerInterceptor
+	if cfg.StorageDriver == storageMemory {
+		unary = append(unary, memoryUnaryInterceptor)
+	}
+	s := grpcmiddleware.NewServer(grpcmiddleware.ServerConfig{
+		Auth:                 auth,
+		Reflection:           reflection,
+		RequireTenant:        requireTenant,
+		Faults:               injector,
+		Info:                 info,
+		MaxRecvMsgSize:       cfg.RecvMsgSize(),
+		MaxConcurrentStreams: cfg.StreamLimit(),
+		MaxInFlight:          cfg.InFlightLimit(),
+		Unary:                unary,
+	})
+	pb.RegisterUserServiceServer(s, srv)
+	backups.Register(s)
+
+	grpcmiddleware.ServeMetrics(grpcmiddleware.MetricsConfig{
+		Port:        os.Getenv("METRICS_PORT"),
+		Diagnostics: diagnosticsHandler,
+		Info:        info,
+		Pprof:       pprof,
+	})
+
+	slog.Info("User service listening", "port", port, "version", buildinfo.Version, "storage", cfg.StorageDriver)
+	if err := s.Serve(injector.Listen(lis)); err != nil {
+		logging.Fatal("Failed to serve", "error", err)
+	}
+}
 
-	log.Printf("User service listening on port %s", port)
-	if err := s.Serve(lis); err != nil {
-		log.Fatalf("Failed to serve: %v", err)
+func getEnv(key, fallback string) string {
+	if value, exists := os.LookupEnv(key); exists {
+		return value
 	}
+	return fallback
 }
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+	"github.com/technonext/todo-app/proto/publicid"
+)
+
+func TestInvalidUserIDIsInvalidArgument(t *testing.T) {
+	s := &server{}
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{"GetUser", func() error { _, err := s.GetUser(ctx, &pb.GetUserRequest{Id: "nope"}); return err }},
+		{"UpdateUser", func() error { _, err := s.UpdateUser(ctx, &pb.UpdateUserRequest{Id: "nope"}); return err }},
+		{"DeleteUser", func() error { _, err := s.DeleteUser(ctx, &pb.DeleteUserRequest{Id: "nope"}); return err }},
+		{"FreezeUser", func() error { _, err := s.FreezeUser(ctx, &pb.FreezeUserRequest{Id: "nope"}); return err }},
+		// The public id of something else
+		{"GetUser with a task id", func() error {
+			_, err := s.GetUser(ctx, &pb.GetUserRequest{Id: publicid.New(publicid.Task)})
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := status.Code(tt.call()); code != codes.InvalidArgument {
+				t.Errorf("code = %v, want InvalidArgument", code)
+			}
+		})
+	}
+}
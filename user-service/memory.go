@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+	"github.com/technonext/todo-app/proto/publicid"
+	"github.com/technonext/todo-app/proto/tenant"
+)
+
+// memoryUsers keeps users and sessions in the process's memory, for local
+// development without a database; they are gone once the service stops.
+type memoryUsers struct {
+	mu       sync.Mutex
+	users    map[primitive.ObjectID]User
+	sessions []Session
+}
+
+func newMemoryUsers() *memoryUsers {
+	return &memoryUsers{users: make(map[primitive.ObjectID]User)}
+}
+
+// inScope reports whether user belongs to ctx's tenant.
+func inScope(ctx context.Context, user User) bool {
+	id, ok := tenant.Scope(ctx)
+	return !ok || user.TenantID == id
+}
+
+// find returns the user id names in ctx's tenant. The caller holds mu.
+func (m *memoryUsers) find(ctx context.Context, id string) (User, error) {
+	filter, err := userFilter(id)
+	if err != nil {
+		return User{}, err
+	}
+	for _, user := range m.users {
+		if !inScope(ctx, user) {
+			continue
+		}
+		if oid, ok := filter["_id"].(primitive.ObjectID); ok && user.ID == oid {
+			return user, nil
+		}
+		if user.PublicID != "" && filter[publicid.Field] == user.PublicID {
+			return user, nil
+		}
+	}
+	return User{}, errNoUser
+}
+
+func (m *memoryUsers) Insert(ctx context.Context, user User) (primitive.ObjectID, error) {
+	if id, ok := tenant.Scope(ctx); ok {
+		user.TenantID = id
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, other := range m.users {
+		if user.PublicID != "" && other.PublicID == user.PublicID {
+			return primitive.NilObjectID, errPublicIDTaken
+		}
+	}
+	if user.ID.IsZero() {
+		user.ID = primitive.NewObjectID()
+	}
+	m.users[user.ID] = user
+	return user.ID, nil
+}
+
+func (m *memoryUsers) Get(ctx context.Context, id string) (User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.find(ctx, id)
+}
+
+func (m *memoryUsers) FindByEmail(ctx context.Context, email string) (User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, user := range m.users {
+		if user.Email == email && inScope(ctx, user) {
+			return user, nil
+		}
+	}
+	return User{}, errNoUser
+}
+
+func (m *memoryUsers) Update(ctx context.Context, id string, change userChange) (User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	user, err := m.find(ctx, id)
+	if err != nil {
+		return user, err
+	}
+	user.Username, user.Email, user.UpdatedAt = change.Username, change.Email, change.UpdatedAt
+	if change.Password != "" {
+		user.Password = change.Password
+	}
+	m.users[user.ID] = user
+	return user, nil
+}
+
+func (m *memoryUsers) TouchLastSeen(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if user, ok := m.users[id]; ok && inScope(ctx, user) {
+		user.LastSeenAt = at.Format(time.RFC3339)
+		m.users[id] = user
+	}
+	return nil
+}
+
+func (m *memoryUsers) InsertSession(ctx context.Context, session Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sessions = append(m.sessions, session)
+	return nil
+}
+
+// memoryMethods are the UserService methods served with
+// STORAGE_DRIVER=memory, those UserRepository covers.
+var memoryMethods = map[string]bool{
+	pb.UserService_CreateUser_FullMethodName:       true,
+	pb.UserService_GetUser_FullMethodName:          true,
+	pb.UserService_UpdateUser_FullMethodName:       true,
+	pb.UserService_AuthenticateUser_FullMethodName: true,
+}
+
+// checkMemoryMethod refuses the UserService methods not in memoryMethods.
+func checkMemoryMethod(method string) error {
+	if !strings.HasPrefix(method, "/todo.UserService/") || memoryMethods[method] {
+		return nil
+	}
+	return statusError(codes.Unimplemented, "UNSUPPORTED_BY_STORAGE", map[string]string{"method": method, "storage_driver": storageMemory},
+		"%s needs STORAGE_DRIVER=%s", method, storageMongo)
+}
+
+func memoryUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := checkMemoryMethod(info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
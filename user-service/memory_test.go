@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+	"github.com/technonext/todo-app/proto/tenant"
+)
+
+func TestCoreRPCsInMemory(t *testing.T) {
+	s := &server{
+		users:      newMemoryUsers(),
+		jwtSecret:  []byte(strings.Repeat("s", minJWTSecretLen)),
+		sessionTTL: time.Hour,
+		bcryptCost: bcrypt.MinCost,
+	}
+	ctx := tenant.WithID(context.Background(), "acme")
+
+	created, err := s.CreateUser(ctx, &pb.CreateUserRequest{Username: "ada", Email: "ada@example.com", Password: "correct horse"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.GetUser(ctx, &pb.GetUserRequest{Id: created.User.Id})
+	if err != nil || got.User.Email != "ada@example.com" {
+		t.Fatalf("GetUser() = %v, %v; want the new user", got, err)
+	}
+	if _, err := s.GetUser(tenant.WithID(context.Background(), "other"), &pb.GetUserRequest{Id: created.User.Id}); status.Code(err) != codes.NotFound {
+		t.Errorf("GetUser() for another tenant = %v, want NotFound", err)
+	}
+
+	updated, err := s.UpdateUser(ctx, &pb.UpdateUserRequest{Id: created.User.Id, Username: "ada", Email: "ada@example.org", Password: "battery staple"})
+	if err != nil || updated.User.Email != "ada@example.org" {
+		t.Fatalf("UpdateUser() = %v, %v; want the new email", updated, err)
+	}
+
+	// Sign-in finds the user in any tenant, with the new password only
+	if _, err := s.AuthenticateUser(context.Background(), &pb.AuthRequest{Email: "ada@example.org", Password: "correct horse"}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("AuthenticateUser() with the old password = %v, want Unauthenticated", err)
+	}
+	auth, err := s.AuthenticateUser(context.Background(), &pb.AuthRequest{Email: "ada@example.org", Password: "battery staple"})
+	if err != nil || auth.Token == "" || auth.User.Id != created.User.Id {
+		t.Fatalf("AuthenticateUser() = %v, %v; want a token for the user", auth, err)
+	}
+	if user, _ := s.users.Get(ctx, created.User.Id); user.LastSeenAt == "" {
+		t.Error("sign-in did not record when the user was last seen")
+	}
+}
+
+func TestCheckMemoryMethod(t *testing.T) {
+	for method, want := range map[string]codes.Code{
+		pb.UserService_GetUser_FullMethodName:      codes.OK,
+		pb.UserService_FreezeUser_FullMethodName:   codes.Unimplemented,
+		pb.UserService_ListSessions_FullMethodName: codes.Unimplemented,
+		"/todo.FaultService/SetFaults":             codes.OK,
+	} {
+		if got := status.Code(checkMemoryMethod(method)); got != want {
+			t.Errorf("checkMemoryMethod(%s) = %v, want %v", method, got, want)
+		}
+	}
+}
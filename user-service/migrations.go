@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"text/tabwriter"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/technonext/todo-app/proto/logging"
+	"github.com/technonext/todo-app/proto/migrate"
+	"github.com/technonext/todo-app/proto/publicid"
+	"github.com/technonext/todo-app/proto/schema"
+	"github.com/technonext/todo-app/proto/tenant"
+)
+
+// userMigrations are the user service's data migrations over db, applied
+// with -migrate. Versions are never reused or reordered once released.
+func userMigrations(db *mongo.Database) []migrate.Migration {
+	return []migrate.Migration{
+		{
+			Version:       1,
+			Description:   "backfill public_id",
+			Collection:    "users",
+			Filter:        bson.M{publicid.Field: bson.M{"$exists": false}},
+			Update:        publicid.Backfill(publicid.User),
+			SchemaVersion: 1,
+		},
+		{
+			Version:     2,
+			Description: "sessions carry their user's tenant",
+			Collection:  "sessions",
+			Filter:      bson.M{tenant.Field: bson.M{"$exists": false}},
+			Update:      userTenants(db.Collection("users")),
+		},
+	}
+}
+
+// userTenants sets a document's tenant_id to the tenant of the user in its
+// user_id. Documents of the default tenant's users, and of users since
+// deleted, are left alone.
+func userTenants(users *mongo.Collection) func(bson.Raw) (bson.M, error) {
+	return func(doc bson.Raw) (bson.M, error) {
+		userID, ok := doc.Lookup("user_id").StringValueOK()
+		if !ok {
+			return nil, nil
+		}
+		filter, err := publicid.Filter(publicid.User, userID)
+		if err != nil {
+			return nil, nil
+		}
+		var user User
+		opts := options.FindOne().SetProjection(bson.M{tenant.Field: 1})
+		err = users.FindOne(context.Background(), filter, opts).Decode(&user)
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		if err != nil || user.TenantID == "" {
+			return nil, err
+		}
+		return bson.M{"$set": bson.M{tenant.Field: user.TenantID}}, nil
+	}
+}
+
+// userSchema is the history of the layout of users, a version per migration
+// changing it; see package schema.
+var userSchema = schema.Schema{
+	Collection: "users",
+	Upgrades: []schema.Upgrade{
+		// Public ids, which publicid.Or stands in for
+		nil,
+	},
+}
+
+// runMigrations applies the pending migrations, or with dryRun reports what
+// they would change, and writes a line per migration to w.
+func runMigrations(ctx context.Context, db *mongo.Database, dryRun bool, w io.Writer) error {
+	runner, err := migrate.New(db, "user-service", userMigrations(db)...)
+	if err != nil {
+		return err
+	}
+	run := runner.Run
+	if dryRun {
+		run = runner.DryRun
+	}
+	results, err := run(ctx)
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tDESCRIPTION\tSCANNED\tCHANGED")
+	for _, r := range results {
+		fmt.Fprintf(tw, "%d\t%s\t%d\t%d\n", r.Version, r.Description, r.Scanned, r.Changed)
+	}
+	tw.Flush()
+	if len(results) == 0 && err == nil {
+		fmt.Fprintln(w, "No pending migrations")
+	}
+	return err
+}
+
+// warnPendingMigrations logs the migrations the database still needs.
+func warnPendingMigrations(ctx context.Context, db *mongo.Database) {
+	runner, err := migrate.New(db, "user-service", userMigrations(db)...)
+	if err != nil {
+		logging.Fatal("Invalid migrations", "error", err)
+	}
+	pending, err := runner.Pending(ctx)
+	if err != nil {
+		slog.Warn("Failed to check for pending migrations", "error", err)
+		return
+	}
+	for _, m := range pending {
+		slog.Warn("Data migration pending, apply it with -migrate", "version", m.Version, "description", m.Description)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/technonext/todo-app/proto/publicid"
+)
+
+func TestDecodeUserVersions(t *testing.T) {
+	oid := primitive.NewObjectID()
+	// A user as each release stored them
+	tests := []struct {
+		name     string
+		doc      bson.M
+		publicID string
+		version  int
+	}{
+		{"version 0, before public ids",
+			bson.M{"_id": oid, "username": "ada", "email": "ada@example.com", "password": "$2a$10$hash", "created_at": "2026-03-01T09:00:00Z"},
+			oid.Hex(), 1},
+		{"version 1, public ids",
+			bson.M{"_id": oid, "username": "ada", "email": "ada@example.com", "password": "$2a$10$hash", "created_at": "2026-03-01T09:00:00Z", "public_id": "user_01HV4Z3K9Q8W5N2M7R6T1Y0XCB", "schema_version": 1},
+			"user_01HV4Z3K9Q8W5N2M7R6T1Y0XCB", 1},
+		{"version 2, from the next release",
+			bson.M{"_id": oid, "username": "ada", "email": "ada@example.com", "password": "$2a$10$hash", "created_at": "2026-03-01T09:00:00Z", "public_id": "user_01HV4Z3K9Q8W5N2M7R6T1Y0XCB", "display_name": "Ada", "schema_version": 2},
+			"user_01HV4Z3K9Q8W5N2M7R6T1Y0XCB", 2},
+	}
+	registry := userSchema.Decoding(nil, User{}).Registry
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cursor, err := mongo.NewCursorFromDocuments([]interface{}{tt.doc}, nil, registry)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var users []User
+			if err := cursor.All(context.Background(), &users); err != nil {
+				t.Fatal(err)
+			}
+			u := users[0]
+			if u.ID != oid || u.Username != "ada" || u.Email != "ada@example.com" || u.Password != "$2a$10$hash" {
+				t.Errorf("user = %+v, want ada", u)
+			}
+			if got := publicid.Or(u.PublicID, u.ID); got != tt.publicID {
+				t.Errorf("public id = %q, want %q", got, tt.publicID)
+			}
+			if u.SchemaVersion != tt.version {
+				t.Errorf("schema version = %d, want %d", u.SchemaVersion, tt.version)
+			}
+		})
+	}
+}
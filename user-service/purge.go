@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc/codes"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// purgeStepTimeout bounds each step of a purge, so one unreachable service
+// does not hold up the rest.
+const purgeStepTimeout = 30 * time.Second
+
+// PurgeUserData erases a user everywhere, for right-to-erasure requests. A
+// step that fails is logged and reported, and the remaining steps still run;
+// every step can be repeated, so a purge that failed partway is finished by
+// running it again.
+func (s *server) PurgeUserData(ctx context.Context, req *pb.PurgeUserDataRequest) (*pb.PurgeUserDataResponse, error) {
+	oid, err := primitive.ObjectIDFromHex(req.UserId)
+	if err != nil {
+		return nil, statusError(codes.InvalidArgument, "INVALID_USER_ID", map[string]string{"user_id": req.UserId}, "invalid user id %q", req.UserId)
+	}
+	if req.ConfirmUserId != req.UserId {
+		return nil, statusError(codes.InvalidArgument, "PURGE_NOT_CONFIRMED", map[string]string{"user_id": req.UserId}, "confirm_user_id must repeat user_id")
+	}
+	userID := req.UserId
+
+	steps := []purgeStep{
+		{"user", func(ctx context.Context) error {
+			_, err := s.collection.DeleteOne(ctx, bson.M{"_id": oid})
+			return err
+		}},
+		{"tasks", func(ctx context.Context) error {
+			_, err := s.tasks.BatchDeleteTasks(ctx, &pb.BatchDeleteTasksRequest{UserId: userID})
+			return err
+		}},
+		{"notifications", func(ctx context.Context) error {
+			_, err := s.notifications.DeleteNotificationsByUser(ctx, &pb.DeleteNotificationsByUserRequest{UserId: userID})
+			return err
+		}},
+		{"analytics", func(ctx context.Context) error {
+			_, err := s.analytics.DeleteUserEvents(ctx, &pb.DeleteUserEventsRequest{UserId: userID})
+			return err
+		}},
+		{"sessions", func(ctx context.Context) error {
+			_, err := s.sessions.DeleteMany(ctx, bson.M{"user_id": userID})
+			return err
+		}},
+	}
+
+	report := runPurgeSteps(ctx, userID, steps)
+	log.Printf("Purged user %s: completed %v, failed %v", userID, report.StepsCompleted, report.StepsFailed)
+	return &pb.PurgeUserDataResponse{Report: report}, nil
+}
+
+// purgeStep erases one part of a user's data.
+type purgeStep struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+// runPurgeSteps runs every step in order, each under purgeStepTimeout, and
+// reports which of them completed and which failed.
+func runPurgeSteps(ctx context.Context, userID string, steps []purgeStep) *pb.PurgeReport {
+	report := &pb.PurgeReport{}
+	for _, step := range steps {
+		stepCtx, cancel := context.WithTimeout(ctx, purgeStepTimeout)
+		err := step.run(stepCtx)
+		cancel()
+		if err != nil {
+			log.Printf("Purge of user %s: %s step failed: %v", userID, step.name, err)
+			report.StepsFailed = append(report.StepsFailed, step.name)
+			continue
+		}
+		report.StepsCompleted = append(report.StepsCompleted, step.name)
+	}
+	return report
+}
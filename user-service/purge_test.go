@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+func TestRunPurgeStepsReportsPartialCompletion(t *testing.T) {
+	ok := func(context.Context) error { return nil }
+	fail := func(context.Context) error { return errors.New("unavailable") }
+
+	tests := []struct {
+		name          string
+		steps         []purgeStep
+		wantCompleted []string
+		wantFailed    []string
+	}{
+		{
+			name:          "all steps complete",
+			steps:         []purgeStep{{"user", ok}, {"tasks", ok}, {"sessions", ok}},
+			wantCompleted: []string{"user", "tasks", "sessions"},
+		},
+		{
+			name:          "failure does not abort later steps",
+			steps:         []purgeStep{{"user", ok}, {"tasks", fail}, {"notifications", ok}, {"analytics", fail}, {"sessions", ok}},
+			wantCompleted: []string{"user", "notifications", "sessions"},
+			wantFailed:    []string{"tasks", "analytics"},
+		},
+		{
+			name:       "every step fails",
+			steps:      []purgeStep{{"user", fail}, {"tasks", fail}},
+			wantFailed: []string{"user", "tasks"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := runPurgeSteps(context.Background(), "u1", tt.steps)
+			if !reflect.DeepEqual(report.StepsCompleted, tt.wantCompleted) {
+				t.Errorf("completed = %v, want %v", report.StepsCompleted, tt.wantCompleted)
+			}
+			if !reflect.DeepEqual(report.StepsFailed, tt.wantFailed) {
+				t.Errorf("failed = %v, want %v", report.StepsFailed, tt.wantFailed)
+			}
+		})
+	}
+}
+
+func TestRunPurgeStepsBoundsEachStep(t *testing.T) {
+	var deadlines int
+	step := func(ctx context.Context) error {
+		if _, ok := ctx.Deadline(); ok {
+			deadlines++
+		}
+		return nil
+	}
+	runPurgeSteps(context.Background(), "u1", []purgeStep{{"user", step}, {"tasks", step}})
+	if deadlines != 2 {
+		t.Errorf("%d of 2 steps ran with a deadline", deadlines)
+	}
+}
+
+func TestPurgeUserDataRequiresConfirmation(t *testing.T) {
+	s := &server{}
+	tests := []struct {
+		name string
+		req  *pb.PurgeUserDataRequest
+	}{
+		{"invalid user id", &pb.PurgeUserDataRequest{UserId: "nope", ConfirmUserId: "nope"}},
+		{"missing confirmation", &pb.PurgeUserDataRequest{UserId: "65f000000000000000000001"}},
+		{"mismatched confirmation", &pb.PurgeUserDataRequest{UserId: "65f000000000000000000001", ConfirmUserId: "65f000000000000000000002"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.PurgeUserData(context.Background(), tt.req)
+			if status.Code(err) != codes.InvalidArgument {
+				t.Errorf("code = %v, want InvalidArgument", status.Code(err))
+			}
+		})
+	}
+}
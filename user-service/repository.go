@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/technonext/todo-app/proto/tenant"
+)
+
+// Errors of a UserRepository, which the RPCs turn into status errors
+var (
+	// errNoUser is returned for a user that does not exist or belongs to
+	// another tenant
+	errNoUser = errors.New("user not found")
+	// errPublicIDTaken is returned by Insert when another user already has
+	// the new user's public id
+	errPublicIDTaken = errors.New("public id taken")
+)
+
+// UserRepository stores the users behind the core RPCs: CreateUser, GetUser,
+// UpdateUser and AuthenticateUser, with the sessions sign-in records.
+// STORAGE_DRIVER picks the implementation, mongoUsers or memoryUsers. The
+// other RPCs and the deletion worker work on the MongoDB collections
+// directly, so in memory only these RPCs are served; see memoryMethods.
+//
+// Every method is limited to the tenant of its context, as a
+// tenant.Collection is. Methods taking a user's id accept either form, and
+// fail with userFilter's error for one that is neither.
+type UserRepository interface {
+	// Insert stores a new user and returns their ObjectID.
+	Insert(ctx context.Context, user User) (primitive.ObjectID, error)
+	Get(ctx context.Context, id string) (User, error)
+	// FindByEmail returns the user with email.
+	FindByEmail(ctx context.Context, email string) (User, error)
+	// Update applies change to the user with id, returning them as updated.
+	Update(ctx context.Context, id string, change userChange) (User, error)
+	// TouchLastSeen records that the user with id logged in at at.
+	TouchLastSeen(ctx context.Context, id primitive.ObjectID, at time.Time) error
+	InsertSession(ctx context.Context, session Session) error
+}
+
+// userChange is a change UpdateUser makes to a user.
+type userChange struct {
+	Username string
+	Email    string
+	// Password is the new hash, or empty to keep the user's
+	Password  string
+	UpdatedAt string
+}
+
+// repository returns the store of the core RPCs: users when set, or else
+// the MongoDB collections.
+func (s *server) repository() UserRepository {
+	if s.users != nil {
+		return s.users
+	}
+	return mongoUsers{collection: s.collection, sessions: s.sessions}
+}
+
+// mongoUsers stores users and sessions as documents of two collections.
+type mongoUsers struct {
+	collection *tenant.Collection
+	sessions   *tenant.Collection
+}
+
+func (m mongoUsers) Insert(ctx context.Context, user User) (primitive.ObjectID, error) {
+	user.SchemaVersion = userSchema.Version()
+	result, err := m.collection.InsertOne(ctx, user)
+	if isPublicIDTaken(err) {
+		return primitive.NilObjectID, errPublicIDTaken
+	}
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	oid, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return primitive.NilObjectID, errors.New("inserted user id is not an ObjectID")
+	}
+	return oid, nil
+}
+
+func (m mongoUsers) Get(ctx context.Context, id string) (User, error) {
+	var user User
+	filter, err := userFilter(id)
+	if err != nil {
+		return user, err
+	}
+	err = m.collection.FindOne(ctx, filter).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return user, errNoUser
+	}
+	return user, err
+}
+
+func (m mongoUsers) FindByEmail(ctx context.Context, email string) (User, error) {
+	var user User
+	err := m.collection.FindOne(ctx, bson.M{"email": email}).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return user, errNoUser
+	}
+	return user, err
+}
+
+func (m mongoUsers) Update(ctx context.Context, id string, change userChange) (User, error) {
+	var user User
+	filter, err := userFilter(id)
+	if err != nil {
+		return user, err
+	}
+	set := bson.M{
+		"username":   change.Username,
+		"email":      change.Email,
+		"updated_at": change.UpdatedAt,
+	}
+	if change.Password != "" {
+		set["password"] = change.Password
+	}
+	err = m.collection.FindOneAndUpdate(ctx, filter, bson.M{"$set": set},
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return user, errNoUser
+	}
+	return user, err
+}
+
+func (m mongoUsers) TouchLastSeen(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	_, err := m.collection.UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{"last_seen_at": at.Format(time.RFC3339)}})
+	return err
+}
+
+func (m mongoUsers) InsertSession(ctx context.Context, session Session) error {
+	_, err := m.sessions.InsertOne(ctx, session)
+	return err
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"log"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/technonext/todo-app/proto/pagination"
+	pb "github.com/technonext/todo-app/proto/proto"
+)
+
+// Metadata keys the gateway uses to describe the client behind a request.
+const (
+	mdClientUserAgent = "x-client-user-agent"
+	mdClientIP        = "x-client-ip"
+	mdDeviceID        = "x-device-fingerprint"
+)
+
+// loadJWTSecret returns JWT_SECRET, or when it is unset a random secret for
+// this process alone, so tokens stop verifying when it restarts.
+func loadJWTSecret() ([]byte, error) {
+	if secret := os.Getenv("JWT_SECRET"); secret != "" {
+		return []byte(secret), nil
+	}
+	log.Println("Warning: JWT_SECRET is not set; signing session tokens with a random secret")
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+type Session struct {
+	ID                primitive.ObjectID `bson:"_id,omitempty"`
+	UserID            string             `bson:"user_id"`
+	UserAgent         string             `bson:"user_agent"`
+	IPAddress         string             `bson:"ip_address"`
+	DeviceFingerprint string             `bson:"device_fingerprint"`
+	CreatedAt         string             `bson:"created_at"`
+	ExpiresAt         string             `bson:"expires_at"`
+}
+
+func (s Session) toProto() *pb.Session {
+	return &pb.Session{
+		Id:                s.ID.Hex(),
+		UserId:            s.UserID,
+		UserAgent:         s.UserAgent,
+		IpAddress:         s.IPAddress,
+		DeviceFingerprint: s.DeviceFingerprint,
+		CreatedAt:         s.CreatedAt,
+		ExpiresAt:         s.ExpiresAt,
+	}
+}
+
+// firstMetadata returns the first value of an incoming metadata key.
+func firstMetadata(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// createSession records a login and returns the signed token for it. The
+// token carries the session id so it can be tied back to this record, and
+// whether the account is frozen so the gateway can turn away its writes early.
+func (s *server) createSession(ctx context.Context, user User) (string, error) {
+	userID := user.ID.Hex()
+	md, _ := metadata.FromIncomingContext(ctx)
+	now := time.Now()
+	expires := now.Add(s.sessionTTL)
+
+	session := Session{
+		ID:                primitive.NewObjectID(),
+		UserID:            userID,
+		UserAgent:         firstMetadata(md, mdClientUserAgent),
+		IPAddress:         firstMetadata(md, mdClientIP),
+		DeviceFingerprint: firstMetadata(md, mdDeviceID),
+		CreatedAt:         now.Format(time.RFC3339),
+		ExpiresAt:         expires.Format(time.RFC3339),
+	}
+	if err := s.repository().InsertSession(ctx, session); err != nil {
+		return "", err
+	}
+
+	claims := jwt.MapClaims{
+		"sub":       userID,
+		"sid":       session.ID.Hex(),
+		"is_frozen": user.IsFrozen,
+		"iat":       now.Unix(),
+		"exp":       expires.Unix(),
+	}
+	// The gateway scopes the user's calls to this tenant
+	if user.TenantID != "" {
+		claims["tenant"] = user.TenantID
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(s.jwtSecret)
+}
+
+func (s *server) ListSessions(ctx context.Context, req *pb.ListSessionsRequest) (*pb.ListSessionsResponse, error) {
+	if req.UserId == "" {
+		return nil, statusError(codes.InvalidArgument, "USER_ID_REQUIRED", nil, "user_id is required")
+	}
+	filter := bson.M{"user_id": req.UserId}
+
+	page, err := pagination.Resolve(req.Pagination, req.Page, req.Limit, pagination.DefaultLimits)
+	if err != nil {
+		return nil, err
+	}
+	findOptions := page.FindOptions()
+	findOptions.SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	cursor, err := s.sessions.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var sessions []*pb.Session
+	for cursor.Next(ctx) {
+		var session Session
+		if err := cursor.Decode(&session); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session.toProto())
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	count, err := s.sessions.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.ListSessionsResponse{
+		Sessions:   sessions,
+		Total:      int32(count),
+		Pagination: page.Response(count, len(sessions)),
+	}, nil
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestLoadJWTSecret(t *testing.T) {
+	t.Setenv("JWT_SECRET", "configured-secret")
+	secret, err := loadJWTSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(secret) != "configured-secret" {
+		t.Errorf("secret = %q, want JWT_SECRET", secret)
+	}
+
+	t.Setenv("JWT_SECRET", "")
+	first, err := loadJWTSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _ := loadJWTSecret()
+	if len(first) != 32 || bytes.Equal(first, second) {
+		t.Errorf("unset JWT_SECRET should give a fresh 32-byte secret, got %x and %x", first, second)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/grpc/codes"
+
+	"github.com/technonext/todo-app/proto/mongoutil"
+	"github.com/technonext/todo-app/proto/publicid"
+)
+
+// Users get public ids like tasks and notifications, and the RPCs taking a
+// user's id accept either form. Responses still show the ObjectID hex, as
+// the other services store it in user_id and sign-in tokens carry it; the
+// public id takes over once those references have moved.
+
+// newUserPublicID returns the public id of a new user; tests replace it to
+// force collisions.
+var newUserPublicID = func() string { return publicid.New(publicid.User) }
+
+// publicIDAttempts is how many public ids CreateUser tries before giving up.
+const publicIDAttempts = 3
+
+// isPublicIDTaken reports whether err is a write refused for reusing a
+// public id.
+func isPublicIDTaken(err error) bool {
+	return mongo.IsDuplicateKeyError(err) && strings.Contains(err.Error(), publicid.Field)
+}
+
+// ensurePublicIDIndex creates the unique index of public ids. Users created
+// before public ids have none until the backfill migration runs.
+func ensurePublicIDIndex(ctx context.Context, collection *mongo.Collection) error {
+	return mongoutil.EnsureIndexes(ctx, collection, mongo.IndexModel{
+		Keys: bson.D{{Key: publicid.Field, Value: 1}},
+		Options: options.Index().SetUnique(true).
+			SetPartialFilterExpression(bson.M{publicid.Field: bson.M{"$exists": true}}),
+	})
+}
+
+// userFilter returns the query for the user id names, in either form.
+func userFilter(id string) (bson.M, error) {
+	filter, err := publicid.Filter(publicid.User, id)
+	if err != nil {
+		return nil, statusError(codes.InvalidArgument, "INVALID_USER_ID", map[string]string{"user_id": id}, "invalid user id %q", id)
+	}
+	return filter, nil
+}